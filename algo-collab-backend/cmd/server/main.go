@@ -1,17 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/is-Xiaoen/algo-collab/internal/config"
 	"github.com/is-Xiaoen/algo-collab/internal/database"
+	"github.com/is-Xiaoen/algo-collab/internal/job"
+	"github.com/is-Xiaoen/algo-collab/internal/lsp"
 	"github.com/is-Xiaoen/algo-collab/internal/middleware"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
 	"github.com/is-Xiaoen/algo-collab/internal/repository"
 	"github.com/is-Xiaoen/algo-collab/internal/router"
+	"github.com/is-Xiaoen/algo-collab/internal/sandbox"
 	"github.com/is-Xiaoen/algo-collab/internal/service"
 	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/ratelimit"
+	"github.com/is-Xiaoen/algo-collab/pkg/wordfilter"
 	"go.uber.org/zap"
 )
 
@@ -49,7 +62,110 @@ func main() {
 	// 4. 初始化 Gin
 	// 初始化服务层
 	userRepo := repository.NewUserRepository(database.DB)
+	roomRepo := repository.NewRoomRepository(database.DB)
+	templateRepo := repository.NewTemplateRepository(database.DB)
+	orgRepo := repository.NewOrganizationRepository(database.DB)
+	tagRepo := repository.NewTagRepository(database.DB)
+	roomEventRepo := repository.NewRoomEventRepository(database.DB)
+	documentRepo := repository.NewDocumentRepository(database.DB)
+	messageRepo := repository.NewRoomMessageRepository(database.DB)
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+	reportRepo := repository.NewRoomMessageReportRepository(database.DB)
+	versionRepo := repository.NewDocumentVersionRepository(database.DB)
+	sessionRepo := repository.NewRoomSessionRepository(database.DB)
+	authorRepo := repository.NewDocumentAuthorRepository(database.DB)
+	fileRepo := repository.NewRoomFileRepository(database.DB)
+	commentRepo := repository.NewRoomCommentRepository(database.DB)
+	lockRepo := repository.NewRoomLockRepository(database.DB)
+	whiteboardRepo := repository.NewRoomWhiteboardRepository(database.DB)
+	whiteboardDocRepo := repository.NewWhiteboardDocumentRepository(database.DB)
 	authService := service.NewAuthService(userRepo, &config.GlobalConfig.JWT)
+	tagService := service.NewTagService(tagRepo)
+	chatService := service.NewChatService(messageRepo, roomRepo, notificationRepo,
+		ratelimit.NewRedisLimiter(database.RedisClient), loadWordFilter(&config.GlobalConfig.Chat.WordFilter), &config.GlobalConfig.Chat)
+	notificationService := service.NewNotificationService(notificationRepo)
+	moderationService := service.NewModerationService(roomRepo, messageRepo, reportRepo, roomEventRepo)
+	replayService := service.NewReplayService(roomRepo, sessionRepo)
+
+	// 启动后台任务
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// 协作房间在多个节点之间同步：广播模式或房间亲和模式
+	hubOptions := realtime.Options{
+		AllowOrigins:   config.GlobalConfig.CORS.AllowOrigins,
+		Documents:      documentRepo,
+		Versions:       versionRepo,
+		Authors:        authorRepo,
+		Locks:          lockRepo,
+		Activity:       roomRepo,
+		Events:         roomEventRepo,
+		Document:       config.GlobalConfig.Document,
+		Whiteboards:    whiteboardDocRepo,
+		Whiteboard:     config.GlobalConfig.Whiteboard,
+		Locker:         realtime.NewRedisLocker(database.RedisClient),
+		WebSocket:      config.GlobalConfig.WebSocket,
+		Chat:           chatService,
+		ChatConfig:     config.GlobalConfig.Chat,
+		RTC:            config.GlobalConfig.RTC,
+		Replay:         config.GlobalConfig.Replay,
+		TerminalConfig: config.GlobalConfig.Terminal,
+	}
+	if terminalCfg := config.GlobalConfig.Terminal; terminalCfg.Enabled {
+		if len(terminalCfg.Command) > 0 {
+			hubOptions.Terminal = sandbox.NewTerminal(terminalCfg.Command)
+		} else {
+			logger.Warn("共享终端已开启但没有配置沙箱命令，终端不可用")
+		}
+	}
+	if config.GlobalConfig.Replay.Enabled {
+		hubOptions.Sessions = sessionRepo
+	}
+	if clusterCfg := config.GlobalConfig.Cluster; clusterCfg.Mode == config.ClusterModeAffinity {
+		nodeAddr := clusterCfg.NodeAddr
+		if nodeAddr == "" {
+			hostname, _ := os.Hostname()
+			nodeAddr = fmt.Sprintf("%s:%d", hostname, config.GlobalConfig.App.Port)
+		}
+		hubOptions.Cluster = realtime.NewCluster(database.RedisClient, nodeAddr, clusterCfg)
+		logger.Info("协作房间使用亲和模式", zap.String("node_addr", nodeAddr))
+	} else {
+		hubOptions.Broker = realtime.NewRedisBroker(jobCtx, database.RedisClient)
+		if limit := config.GlobalConfig.WebSocket.MaxConnectionsPerRoom; limit > 0 {
+			// 广播模式下连接分散在各个节点上，房间连接数上限只能按节点计数
+			logger.Info("广播模式下房间连接数上限按节点计数，全局上限为节点数乘以该值",
+				zap.Int("max_connections_per_room", limit))
+		}
+	}
+	hub := realtime.NewHub(hubOptions)
+	roomService := service.NewRoomService(roomRepo, tagRepo, roomEventRepo, hub)
+	templateService := service.NewTemplateService(templateRepo, orgRepo, roomRepo, roomService)
+	orgService := service.NewOrganizationService(orgRepo, templateRepo, roomService)
+	versionService := service.NewVersionService(roomRepo, versionRepo, authorRepo, roomEventRepo, hub)
+	workspaceService := service.NewWorkspaceService(roomRepo, fileRepo, roomEventRepo, hub, &config.GlobalConfig.Workspace)
+	commentService := service.NewCommentService(roomRepo, fileRepo, commentRepo, roomEventRepo, hub)
+	lockService := service.NewLockService(roomRepo, fileRepo, lockRepo, roomEventRepo, hub)
+	whiteboardService := service.NewWhiteboardService(roomRepo, whiteboardRepo, whiteboardDocRepo, commentRepo, roomEventRepo, hub, &config.GlobalConfig.Whiteboard)
+	if hubOptions.Cluster != nil {
+		hubOptions.Cluster.Start(jobCtx)
+	}
+	job.NewRoomCleanupJob(roomRepo, hub, &config.GlobalConfig.Room).Start(jobCtx)
+	job.NewReplayCleanupJob(sessionRepo, &config.GlobalConfig.Replay).Start(jobCtx)
+	hub.Start(jobCtx)
+	lspOptions := lsp.Options{
+		AllowOrigins: config.GlobalConfig.CORS.AllowOrigins,
+		Workspaces:   workspaceService,
+		Config:       config.GlobalConfig.LSP,
+	}
+	if lspCfg := config.GlobalConfig.LSP; lspCfg.Enabled {
+		if len(lspCfg.Commands) > 0 {
+			lspOptions.Launcher = sandbox.NewLauncher(lspCfg.Commands)
+		} else {
+			logger.Warn("语言服务器网关已开启但没有配置沙箱命令，网关不可用")
+		}
+	}
+	lspGateway := lsp.NewGateway(lspOptions)
+	lspGateway.Start(jobCtx)
 
 	if config.GlobalConfig.App.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -62,15 +178,69 @@ func main() {
 	r.Use(middleware.CORSMiddleware(&config.GlobalConfig.CORS))
 
 	// 设置路由
-	newRouter := router.NewRouter(authService)
+	newRouter := router.NewRouter(&router.Services{
+		Auth:         authService,
+		Room:         roomService,
+		Template:     templateService,
+		Organization: orgService,
+		Tag:          tagService,
+		Chat:         chatService,
+		Notification: notificationService,
+		Moderation:   moderationService,
+		Version:      versionService,
+		Replay:       replayService,
+		Workspace:    workspaceService,
+		Comment:      commentService,
+		Lock:         lockService,
+		Whiteboard:   whiteboardService,
+		Hub:          hub,
+		LSP:          lspGateway,
+	})
 	newRouter.Setup(r)
 
 	// 7. 启动服务器
 	addr := fmt.Sprintf(":%d", config.GlobalConfig.App.Port)
 	logger.Info("✅ 服务器启动成功", zap.String("address", addr))
 
-	if err := r.Run(addr); err != nil {
-		logger.Fatal("服务器启动失败", zap.Error(err))
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("服务器启动失败", zap.Error(err))
+		}
+	}()
+
+	// 8. 收到退出信号后优雅关闭：先断开协作连接（带重连提示），再关闭 HTTP 服务
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Info("服务关闭中...")
+
+	timeout := time.Duration(config.GlobalConfig.WebSocket.ShutdownTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	hub.Shutdown(ctx)
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP 服务关闭超时", zap.Error(err))
 	}
+	stopJobs()
+	logger.Info("服务已关闭")
+}
 
+// loadWordFilter 加载聊天敏感词表，词表读取失败时不过滤，不影响服务启动
+func loadWordFilter(cfg *config.WordFilterConfig) *wordfilter.Filter {
+	if cfg.Mode == "" {
+		return nil
+	}
+	words, err := wordfilter.LoadFiles(cfg.WordLists...)
+	if err != nil {
+		logger.Error("加载敏感词表失败，聊天不过滤敏感词", zap.Error(err))
+		return nil
+	}
+	words = append(words, cfg.Words...)
+	logger.Info("敏感词表已加载", zap.String("mode", cfg.Mode), zap.Int("words", len(words)))
+	return wordfilter.New(words)
 }
@@ -10,12 +10,23 @@ import (
 // Config 主配置结构体
 // 这个结构体的字段需要与 YAML 文件对应
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
-	CORS     CORSConfig     `mapstructure:"cors"`
+	App        AppConfig        `mapstructure:"app"`
+	WebSocket  WebSocketConfig  `mapstructure:"websocket"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Log        LogConfig        `mapstructure:"log"`
+	CORS       CORSConfig       `mapstructure:"cors"`
+	Room       RoomConfig       `mapstructure:"room"`
+	Document   DocumentConfig   `mapstructure:"document"`
+	Chat       ChatConfig       `mapstructure:"chat"`
+	RTC        RTCConfig        `mapstructure:"rtc"`
+	Replay     ReplayConfig     `mapstructure:"replay"`
+	Workspace  WorkspaceConfig  `mapstructure:"workspace"`
+	Whiteboard WhiteboardConfig `mapstructure:"whiteboard"`
+	Terminal   TerminalConfig   `mapstructure:"terminal"`
+	LSP        LSPConfig        `mapstructure:"lsp"`
+	Cluster    ClusterConfig    `mapstructure:"cluster"`
 }
 
 // AppConfig 应用配置
@@ -26,6 +37,19 @@ type AppConfig struct {
 	Debug bool   `mapstructure:"debug"`
 }
 
+// WebSocketConfig 实时协作连接的心跳、限额和关闭配置
+type WebSocketConfig struct {
+	PingIntervalSeconds    int `mapstructure:"ping_interval_seconds"`    // 服务端发送 ping 的间隔
+	IdleTimeoutSeconds     int `mapstructure:"idle_timeout_seconds"`     // 多久收不到任何消息（包括 pong）就断开，必须大于 ping 间隔
+	WriteTimeoutSeconds    int `mapstructure:"write_timeout_seconds"`    // 单次写操作的超时时间
+	MaxMessageKB           int `mapstructure:"max_message_kb"`           // 单条消息的最大大小，首次同步可能包含整个文档
+	SendBufferSize         int `mapstructure:"send_buffer_size"`         // 每个连接的发送队列长度
+	MaxConnectionsPerUser  int `mapstructure:"max_connections_per_user"` // 单个用户在本节点的最大连接数，0 表示不限制
+	MaxConnectionsPerRoom  int `mapstructure:"max_connections_per_room"` // 单个房间在本节点的最大连接数，0 表示不限制；只有亲和模式下才是房间的全局上限
+	ShutdownTimeoutSeconds int `mapstructure:"shutdown_timeout_seconds"` // 关闭服务时等待连接断开的最长时间
+	ReconnectDelaySeconds  int `mapstructure:"reconnect_delay_seconds"`  // 关闭服务时建议客户端等待多久再重连
+}
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	Driver          string `mapstructure:"driver"`
@@ -73,6 +97,115 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// RoomConfig 房间生命周期配置
+type RoomConfig struct {
+	ArchiveAfterDays       int `mapstructure:"archive_after_days"`       // 无活动多少天后自动归档
+	PurgeAfterDays         int `mapstructure:"purge_after_days"`         // 归档多少天后彻底删除
+	CleanupIntervalMinutes int `mapstructure:"cleanup_interval_minutes"` // 清理任务执行间隔
+}
+
+// DocumentConfig 协作文档持久化配置
+type DocumentConfig struct {
+	MaxSizeKB              int  `mapstructure:"max_size_kb"`              // 单个文档快照的最大大小，0 表示不限制
+	GC                     bool `mapstructure:"gc"`                       // 是否回收已删除内容（墓碑）
+	CompactEveryUpdates    int  `mapstructure:"compact_every_updates"`    // 累计多少条增量后合并为快照
+	CompactIntervalSeconds int  `mapstructure:"compact_interval_seconds"` // 定期合并的间隔
+	VersionIntervalSeconds int  `mapstructure:"version_interval_seconds"` // 自动保存历史版本的最小间隔，0 表示不自动保存
+	MaxAutoVersions        int  `mapstructure:"max_auto_versions"`        // 每个房间保留的自动版本数
+}
+
+// ChatConfig 房间聊天配置
+type ChatConfig struct {
+	MaxLength              int              `mapstructure:"max_length"`                // 单条消息的最大字符数
+	PresenceGraceSeconds   int              `mapstructure:"presence_grace_seconds"`    // 断开多久后才发送离开消息，期间重连不产生加入/离开消息
+	RateLimitMessages      int              `mapstructure:"rate_limit_messages"`       // 每个成员在每个房间的窗口内最多发送的消息数，0 表示不限制
+	RateLimitWindowSeconds int              `mapstructure:"rate_limit_window_seconds"` // 限流窗口
+	WordFilter             WordFilterConfig `mapstructure:"word_filter"`
+}
+
+// 敏感词处理方式
+const (
+	WordFilterMask   = "mask"   // 替换为 *
+	WordFilterReject = "reject" // 拒绝发送
+)
+
+// WordFilterConfig 聊天敏感词过滤配置
+type WordFilterConfig struct {
+	Mode      string   `mapstructure:"mode"`       // mask 或 reject，为空表示不过滤
+	WordLists []string `mapstructure:"word_lists"` // 词表文件，每行一个词
+	Words     []string `mapstructure:"words"`      // 额外的敏感词
+}
+
+// RTCConfig 房间语音和屏幕共享配置
+// 服务端只负责 WebRTC 信令，音视频在成员之间点对点传输，无法直连时经 TURN 中继
+type RTCConfig struct {
+	ICEServers []ICEServerConfig `mapstructure:"ice_servers"`
+	// TURNSecret 与 coturn 的 static-auth-secret 一致，设置后为没有配置用户名的 TURN 服务器生成临时凭证
+	TURNSecret               string `mapstructure:"turn_secret"`
+	TURNCredentialTTLSeconds int    `mapstructure:"turn_credential_ttl_seconds"` // 临时凭证有效期
+	MaxParticipants          int    `mapstructure:"max_participants"`            // 语音频道人数上限，点对点连接数随人数平方增长
+}
+
+// ICEServerConfig 一个 STUN/TURN 服务器，对应浏览器的 RTCIceServer
+type ICEServerConfig struct {
+	URLs       []string `mapstructure:"urls"`
+	Username   string   `mapstructure:"username"`
+	Credential string   `mapstructure:"credential"`
+}
+
+// ReplayConfig 协作过程回放配置
+type ReplayConfig struct {
+	Enabled                 bool `mapstructure:"enabled"`                   // 是否记录回放
+	SessionGapSeconds       int  `mapstructure:"session_gap_seconds"`       // 房间多久没有活动后，下一次活动开始新的会话
+	KeyframeIntervalSeconds int  `mapstructure:"keyframe_interval_seconds"` // 有修改时每隔多久记录一次完整文档，决定跳转时需要重放的事件数
+	RetentionDays           int  `mapstructure:"retention_days"`            // 会话保留天数，0 表示一直保留到房间被删除
+}
+
+// WorkspaceConfig 房间多文件工作区配置
+type WorkspaceConfig struct {
+	MaxFiles int `mapstructure:"max_files"` // 每个房间最多的文件数（包括主文件）
+}
+
+// WhiteboardConfig 房间协作白板配置
+type WhiteboardConfig struct {
+	MaxBoards     int `mapstructure:"max_boards"`      // 每个房间最多的白板数
+	MaxSizeKB     int `mapstructure:"max_size_kb"`     // 每块白板文档的大小上限，0 表示不限制；不计入代码文档的大小
+	ExportMaxSize int `mapstructure:"export_max_size"` // 导出 PNG 时较长一边的像素上限，超出时整体缩小
+}
+
+// TerminalConfig 房间共享终端配置
+// 终端运行在代码执行沙箱中：Command 为在沙箱中启动 shell 的命令，由服务端分配 PTY 运行，
+// 没有配置命令时即使开启也不可用
+type TerminalConfig struct {
+	Enabled            bool     `mapstructure:"enabled"`              // 是否允许成员开启共享终端
+	IdleTimeoutSeconds int      `mapstructure:"idle_timeout_seconds"` // 多久没有输入输出后自动关闭终端
+	Command            []string `mapstructure:"command"`              // 启动 shell 的命令及参数，{room} 替换为房间 UUID
+}
+
+// LSPConfig 语言服务器网关配置
+// 语言服务器运行在代码执行沙箱中：Commands 为每个语言服务器在沙箱中的启动命令，通过标准输入输出通信，
+// 没有配置命令的语言服务器不可用
+type LSPConfig struct {
+	Enabled            bool                `mapstructure:"enabled"`              // 是否开启语言服务器网关
+	MaxServers         int                 `mapstructure:"max_servers"`          // 本节点同时运行的语言服务器上限
+	IdleTimeoutSeconds int                 `mapstructure:"idle_timeout_seconds"` // 没有连接的语言服务器多久后关闭
+	Commands           map[string][]string `mapstructure:"commands"`             // 语言服务器名到启动命令及参数，{room} 替换为房间 UUID
+}
+
+// 多节点部署模式
+const (
+	ClusterModeBroadcast = "broadcast" // 每个节点都持有房间文档，通过 Redis pub/sub 同步
+	ClusterModeAffinity  = "affinity"  // 房间由一致性哈希选出的节点独占，其他节点转发连接
+)
+
+// ClusterConfig 多节点部署配置
+type ClusterConfig struct {
+	Mode             string `mapstructure:"mode"`              // broadcast 或 affinity，默认 broadcast
+	NodeAddr         string `mapstructure:"node_addr"`         // 其他节点访问本节点的地址，为空时使用主机名和端口
+	HeartbeatSeconds int    `mapstructure:"heartbeat_seconds"` // 成员心跳间隔
+	NodeTTLSeconds   int    `mapstructure:"node_ttl_seconds"`  // 多久没有心跳的节点被剔除
+}
+
 // 全局配置变量
 var GlobalConfig *Config
 
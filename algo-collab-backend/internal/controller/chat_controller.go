@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// ChatController 房间聊天控制器
+// 发送消息走协作 WebSocket，这里只提供历史记录
+type ChatController struct {
+	chatService service.ChatService
+}
+
+// NewChatController 创建聊天控制器实例
+func NewChatController(chatService service.ChatService) *ChatController {
+	return &ChatController{
+		chatService: chatService,
+	}
+}
+
+// ListMessages 聊天记录（房间成员），用 before 向前翻页
+func (c *ChatController) ListMessages(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListRoomMessagesQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.chatService.ListMessages(ctx.Request.Context(), ctx.Param("uuid"), userID, &query)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// CollaborationController 实时协作（y-websocket）控制器
+type CollaborationController struct {
+	roomService       service.RoomService
+	whiteboardService service.WhiteboardService
+	hub               *realtime.Hub
+}
+
+// NewCollaborationController 创建协作控制器实例
+func NewCollaborationController(roomService service.RoomService, whiteboardService service.WhiteboardService, hub *realtime.Hub) *CollaborationController {
+	return &CollaborationController{
+		roomService:       roomService,
+		whiteboardService: whiteboardService,
+		hub:               hub,
+	}
+}
+
+// Connect 建立协作 WebSocket 连接
+// y-websocket 会把房间名拼在 URL 末尾：/collaboration/<roomUUID>?token=...
+func (c *CollaborationController) Connect(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	roomUUID := ctx.Param("roomId")
+
+	// 1. 校验成员身份
+	room, member, err := c.roomService.CheckMember(ctx.Request.Context(), roomUUID, userID)
+	if err != nil {
+		logger.BusinessWarn("协作连接被拒绝",
+			zap.String("room_uuid", roomUUID),
+			zap.Uint("user_id", userID),
+			zap.String("error", err.Error()))
+		writeRoomError(ctx, err)
+		return
+	}
+
+	// 2. 升级连接，归档房间只读（归档和恢复时会断开连接，重连后按新状态连接）；
+	// 修改角色后由 Hub.UpdateMemberRole 更新在线连接上的角色，不需要重连
+	user := realtime.User{ID: userID, Username: ctx.GetString("username"), Role: member.Role}
+	if err := c.hub.Serve(ctx.Writer, ctx.Request, room, user, room.IsArchived()); err != nil {
+		logger.Warn("WebSocket 升级失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+	}
+}
+
+// ConnectWhiteboard 建立白板协作 WebSocket 连接，每块白板是一份独立的文档
+// 前端以 <roomUUID>/whiteboards/<boardID> 为 y-websocket 的房间名：/collaboration/<roomUUID>/whiteboards/<boardID>?token=...
+func (c *CollaborationController) ConnectWhiteboard(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	roomUUID := ctx.Param("roomId")
+	boardID, ok := parseIDParam(ctx, "boardId")
+	if !ok {
+		response.BadRequest(ctx, "无效的白板ID")
+		return
+	}
+
+	// 1. 校验成员身份和白板
+	room, member, err := c.roomService.CheckMember(ctx.Request.Context(), roomUUID, userID)
+	if err != nil {
+		logger.BusinessWarn("白板协作连接被拒绝",
+			zap.String("room_uuid", roomUUID),
+			zap.Uint("user_id", userID),
+			zap.String("error", err.Error()))
+		writeRoomError(ctx, err)
+		return
+	}
+	board, err := c.whiteboardService.FindWhiteboard(ctx.Request.Context(), room, boardID)
+	if err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	// 2. 升级连接，和代码的协作连接一样归档房间只读
+	user := realtime.User{ID: userID, Username: ctx.GetString("username"), Role: member.Role}
+	if err := c.hub.ServeWhiteboard(ctx.Writer, ctx.Request, room, board.ID, user, room.IsArchived()); err != nil {
+		logger.Warn("WebSocket 升级失败",
+			zap.String("room_uuid", roomUUID),
+			zap.Uint("whiteboard_id", board.ID),
+			zap.Error(err))
+	}
+}
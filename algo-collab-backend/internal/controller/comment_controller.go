@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// CommentController 代码评论控制器
+// 评论的位置由房间的协作文档解析，亲和模式下所有接口都由房间所在节点处理
+type CommentController struct {
+	commentService service.CommentService
+	hub            *realtime.Hub
+}
+
+// NewCommentController 创建代码评论控制器实例
+func NewCommentController(commentService service.CommentService, hub *realtime.Hub) *CommentController {
+	return &CommentController{
+		commentService: commentService,
+		hub:            hub,
+	}
+}
+
+// writeCommentError 评论相关的错误，其余交给 writeWorkspaceError
+func writeCommentError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCommentThreadNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrCommentEmpty),
+		errors.Is(err, service.ErrCommentTooLong),
+		errors.Is(err, service.ErrCommentAnchor):
+		response.BadRequest(ctx, err.Error())
+	default:
+		writeWorkspaceError(ctx, err)
+	}
+}
+
+// ListThreads 评论列表，可按文件和状态筛选（房间成员）
+func (c *CommentController) ListThreads(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListCommentThreadsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	threads, err := c.commentService.ListThreads(ctx.Request.Context(), roomUUID, userID, &query)
+	if err != nil {
+		writeCommentError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", threads)
+}
+
+// CreateThread 对一段代码发起评论（房间成员）
+func (c *CommentController) CreateThread(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateCommentThreadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	thread, err := c.commentService.CreateThread(ctx.Request.Context(), roomUUID, userID, &req)
+	if err != nil {
+		writeCommentError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "评论已发布", thread)
+}
+
+// Reply 回复评论（房间成员）
+func (c *CommentController) Reply(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	threadID, ok := parseIDParam(ctx, "threadId")
+	if !ok {
+		response.BadRequest(ctx, "无效的评论ID")
+		return
+	}
+
+	var req service.ReplyCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	thread, err := c.commentService.Reply(ctx.Request.Context(), roomUUID, userID, threadID, &req)
+	if err != nil {
+		writeCommentError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "回复成功", thread)
+}
+
+// ResolveThread 标记评论已解决（房间成员）
+func (c *CommentController) ResolveThread(ctx *gin.Context) {
+	c.setResolved(ctx, true, "评论已解决")
+}
+
+// ReopenThread 重新打开已解决的评论（房间成员）
+func (c *CommentController) ReopenThread(ctx *gin.Context) {
+	c.setResolved(ctx, false, "评论已重新打开")
+}
+
+func (c *CommentController) setResolved(ctx *gin.Context, resolved bool, message string) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	threadID, ok := parseIDParam(ctx, "threadId")
+	if !ok {
+		response.BadRequest(ctx, "无效的评论ID")
+		return
+	}
+
+	thread, err := c.commentService.SetResolved(ctx.Request.Context(), roomUUID, userID, threadID, resolved)
+	if err != nil {
+		writeCommentError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, message, thread)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/lsp"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// LanguageServerController 语言服务器（LSP）控制器
+type LanguageServerController struct {
+	roomService service.RoomService
+	gateway     *lsp.Gateway
+}
+
+// NewLanguageServerController 创建语言服务器控制器实例
+func NewLanguageServerController(roomService service.RoomService, gateway *lsp.Gateway) *LanguageServerController {
+	return &LanguageServerController{
+		roomService: roomService,
+		gateway:     gateway,
+	}
+}
+
+// writeLanguageServerError 语言服务器错误转 HTTP 响应
+func writeLanguageServerError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, lsp.ErrUnsupportedLanguage):
+		response.BadRequest(ctx, err.Error())
+	case errors.Is(err, lsp.ErrUnavailable),
+		errors.Is(err, lsp.ErrTooManyServers),
+		errors.Is(err, lsp.ErrLaunchFailed):
+		response.Error(ctx, 503, 5003, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// Connect 建立语言服务器 WebSocket 连接
+// /lsp/<roomUUID>?token=...&language=go，language 默认为房间语言
+func (c *LanguageServerController) Connect(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	roomUUID := ctx.Param("roomId")
+
+	// 1. 校验成员身份，只读成员也可以使用补全、跳转等功能
+	room, _, err := c.roomService.CheckMember(ctx.Request.Context(), roomUUID, userID)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+	language := ctx.DefaultQuery("language", room.Language)
+	if err := c.gateway.Check(language); err != nil {
+		writeLanguageServerError(ctx, err)
+		return
+	}
+
+	// 2. 获取语言服务器并升级连接，升级前的错误直接返回给客户端
+	if err := c.gateway.Serve(ctx.Writer, ctx.Request, room, language); err != nil {
+		if ctx.Writer.Written() {
+			logger.Warn("语言服务器连接失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+			return
+		}
+		writeLanguageServerError(ctx, err)
+	}
+}
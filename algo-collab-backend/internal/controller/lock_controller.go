@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// LockController 锁定区域控制器
+type LockController struct {
+	lockService service.LockService
+	hub         *realtime.Hub
+}
+
+// NewLockController 创建锁定区域控制器实例
+func NewLockController(lockService service.LockService, hub *realtime.Hub) *LockController {
+	return &LockController{
+		lockService: lockService,
+		hub:         hub,
+	}
+}
+
+// writeLockError 锁定区域相关的错误，其余交给 writeWorkspaceError
+func writeLockError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoomLockNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrRoomLockAnchor),
+		errors.Is(err, service.ErrTooManyRoomLocks):
+		response.BadRequest(ctx, err.Error())
+	default:
+		writeWorkspaceError(ctx, err)
+	}
+}
+
+// ListLocks 锁定区域列表（房间成员）
+func (c *LockController) ListLocks(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	locks, err := c.lockService.ListLocks(ctx.Request.Context(), ctx.Param("uuid"), userID)
+	if err != nil {
+		writeLockError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", locks)
+}
+
+// CreateLock 锁定一段代码（管理员及以上），亲和模式下由房间所在节点处理
+func (c *LockController) CreateLock(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateRoomLockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	lock, err := c.lockService.CreateLock(ctx.Request.Context(), roomUUID, userID, &req)
+	if err != nil {
+		writeLockError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "区域已锁定", lock)
+}
+
+// DeleteLock 解除锁定（管理员及以上），亲和模式下由房间所在节点处理
+func (c *LockController) DeleteLock(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	lockID, ok := parseIDParam(ctx, "lockId")
+	if !ok {
+		response.BadRequest(ctx, "无效的锁定区域ID")
+		return
+	}
+
+	if err := c.lockService.DeleteLock(ctx.Request.Context(), roomUUID, userID, lockID); err != nil {
+		writeLockError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已解除锁定", nil)
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// ModerationController 房间聊天管理控制器：禁言和举报
+type ModerationController struct {
+	moderationService service.ModerationService
+}
+
+// NewModerationController 创建聊天管理控制器实例
+func NewModerationController(moderationService service.ModerationService) *ModerationController {
+	return &ModerationController{
+		moderationService: moderationService,
+	}
+}
+
+// writeModerationError 举报相关的错误，其余交给 writeRoomError
+func writeModerationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrReportNotFound),
+		errors.Is(err, service.ErrChatMessageNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrReportDuplicate),
+		errors.Is(err, service.ErrReportHandled):
+		response.Error(ctx, 409, 4009, err.Error())
+	case errors.Is(err, service.ErrReportOwnMessage),
+		errors.Is(err, service.ErrChatMessageDeleted):
+		response.BadRequest(ctx, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// parseIDParam 解析路径中的 ID 参数
+func parseIDParam(ctx *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// MuteMember 禁言成员（房间管理员）
+func (c *ModerationController) MuteMember(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	targetID, ok := parseIDParam(ctx, "userId")
+	if !ok {
+		response.BadRequest(ctx, "无效的用户ID")
+		return
+	}
+
+	var req service.MuteMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	member, err := c.moderationService.MuteMember(ctx.Request.Context(), ctx.Param("uuid"), userID, targetID, &req)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已禁言", member)
+}
+
+// UnmuteMember 解除禁言（房间管理员）
+func (c *ModerationController) UnmuteMember(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	targetID, ok := parseIDParam(ctx, "userId")
+	if !ok {
+		response.BadRequest(ctx, "无效的用户ID")
+		return
+	}
+
+	if err := c.moderationService.UnmuteMember(ctx.Request.Context(), ctx.Param("uuid"), userID, targetID); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已解除禁言", nil)
+}
+
+// ReportMessage 举报聊天消息（房间成员）
+func (c *ModerationController) ReportMessage(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	messageID, ok := parseIDParam(ctx, "messageId")
+	if !ok {
+		response.BadRequest(ctx, "无效的消息ID")
+		return
+	}
+
+	var req service.ReportMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	report, err := c.moderationService.ReportMessage(ctx.Request.Context(), ctx.Param("uuid"), userID, messageID, &req)
+	if err != nil {
+		writeModerationError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "举报已提交", report)
+}
+
+// ListReports 举报审核队列（房间管理员）
+func (c *ModerationController) ListReports(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListReportsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.moderationService.ListReports(ctx.Request.Context(), ctx.Param("uuid"), userID, &query)
+	if err != nil {
+		writeModerationError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
+
+// HandleReport 处理举报（房间管理员）
+func (c *ModerationController) HandleReport(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	reportID, ok := parseIDParam(ctx, "reportId")
+	if !ok {
+		response.BadRequest(ctx, "无效的举报ID")
+		return
+	}
+
+	var req service.HandleReportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	report, err := c.moderationService.HandleReport(ctx.Request.Context(), ctx.Param("uuid"), userID, reportID, &req)
+	if err != nil {
+		writeModerationError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "处理成功", report)
+}
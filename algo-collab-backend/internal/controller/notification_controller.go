@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// NotificationController 站内通知控制器
+type NotificationController struct {
+	notificationService service.NotificationService
+}
+
+// NewNotificationController 创建通知控制器实例
+func NewNotificationController(notificationService service.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// ListNotifications 当前用户的通知
+func (c *NotificationController) ListNotifications(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListNotificationsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.notificationService.ListNotifications(ctx.Request.Context(), userID, &query)
+	if err != nil {
+		response.InternalError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
+
+// MarkRead 标记通知为已读
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.MarkNotificationsReadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	if err := c.notificationService.MarkRead(ctx.Request.Context(), userID, &req); err != nil {
+		response.InternalError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "已标记为已读", nil)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// OrganizationController 组织控制器
+type OrganizationController struct {
+	orgService service.OrganizationService
+}
+
+// NewOrganizationController 创建组织控制器实例
+func NewOrganizationController(orgService service.OrganizationService) *OrganizationController {
+	return &OrganizationController{
+		orgService: orgService,
+	}
+}
+
+// CreateOrganization 创建组织
+func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateOrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	org, err := c.orgService.CreateOrganization(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.SuccessWithCode(ctx, 201, "创建成功", org)
+}
+
+// AddMember 添加组织成员
+func (c *OrganizationController) AddMember(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.AddOrganizationMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	member, err := c.orgService.AddMember(ctx.Request.Context(), userID, ctx.Param("uuid"), &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "添加成功", member)
+}
+
+// SetDefaultTemplate 设置组织默认模板
+func (c *OrganizationController) SetDefaultTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.SetDefaultTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	org, err := c.orgService.SetDefaultTemplate(ctx.Request.Context(), userID, ctx.Param("uuid"), &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "设置成功", org)
+}
+
+// CreateRoom 在组织下创建房间（受组织默认/强制模板约束）
+func (c *OrganizationController) CreateRoom(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateOrganizationRoomRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	room, err := c.orgService.CreateRoom(ctx.Request.Context(), userID, ctx.Param("uuid"), &req)
+	if err != nil {
+		logger.BusinessWarn("组织创建房间失败",
+			zap.String("org_uuid", ctx.Param("uuid")),
+			zap.Uint("user_id", userID),
+			zap.String("error", err.Error()))
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.SuccessWithCode(ctx, 201, "创建成功", room)
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// ReplayController 协作过程回放控制器
+type ReplayController struct {
+	replayService service.ReplayService
+}
+
+// NewReplayController 创建回放控制器实例
+func NewReplayController(replayService service.ReplayService) *ReplayController {
+	return &ReplayController{
+		replayService: replayService,
+	}
+}
+
+// writeReplayError 回放相关的错误，其余交给 writeRoomError
+func writeReplayError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoomSessionNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// ListSessions 房间的回放会话列表（房间成员）
+func (c *ReplayController) ListSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListSessionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.replayService.ListSessions(ctx.Request.Context(), ctx.Param("uuid"), userID, &query)
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
+
+// GetSession 回放会话详情（房间成员）
+func (c *ReplayController) GetSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	sessionID, ok := parseIDParam(ctx, "sessionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的会话ID")
+		return
+	}
+
+	session, err := c.replayService.GetSession(ctx.Request.Context(), ctx.Param("uuid"), userID, sessionID)
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", session)
+}
+
+// StreamSession 以 SSE 流回放会话（房间成员）
+// ?from= 跳转位置（毫秒），?speed= 播放速度，调整时客户端重新打开
+func (c *ReplayController) StreamSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	sessionID, ok := parseIDParam(ctx, "sessionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的会话ID")
+		return
+	}
+
+	var opts service.ReplayOptions
+	if err := ctx.ShouldBindQuery(&opts); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	session, err := c.replayService.GetSession(ctx.Request.Context(), ctx.Param("uuid"), userID, sessionID)
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	c.stream(ctx, session, &opts)
+}
+
+// ShareSession 生成回放的分享链接（房间管理员）
+func (c *ReplayController) ShareSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	sessionID, ok := parseIDParam(ctx, "sessionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的会话ID")
+		return
+	}
+
+	session, err := c.replayService.ShareSession(ctx.Request.Context(), ctx.Param("uuid"), userID, sessionID)
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已分享", session)
+}
+
+// UnshareSession 使回放的分享链接失效（房间管理员）
+func (c *ReplayController) UnshareSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	sessionID, ok := parseIDParam(ctx, "sessionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的会话ID")
+		return
+	}
+
+	if err := c.replayService.UnshareSession(ctx.Request.Context(), ctx.Param("uuid"), userID, sessionID); err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已取消分享", nil)
+}
+
+// GetShared 通过分享链接查看回放信息（不需要登录）
+func (c *ReplayController) GetShared(ctx *gin.Context) {
+	shared, err := c.replayService.SharedSession(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", shared)
+}
+
+// StreamShared 通过分享链接回放（不需要登录），参数同 StreamSession
+func (c *ReplayController) StreamShared(ctx *gin.Context) {
+	var opts service.ReplayOptions
+	if err := ctx.ShouldBindQuery(&opts); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	shared, err := c.replayService.SharedSession(ctx.Request.Context(), ctx.Param("token"))
+	if err != nil {
+		writeReplayError(ctx, err)
+		return
+	}
+
+	c.stream(ctx, shared.Session, &opts)
+}
+
+// stream 每一帧作为一条 SSE 事件发送，事件名为帧类型，客户端断开后停止
+func (c *ReplayController) stream(ctx *gin.Context, session *models.RoomSession, opts *service.ReplayOptions) {
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	err := c.replayService.Play(ctx.Request.Context(), session, opts, func(frame *service.ReplayFrame) error {
+		ctx.SSEvent(frame.Type, frame)
+		ctx.Writer.Flush()
+		return ctx.Request.Context().Err()
+	})
+	if err != nil && ctx.Request.Context().Err() == nil {
+		logger.Warn("回放中断", zap.Uint("session_id", session.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,371 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// RoomController 房间控制器
+type RoomController struct {
+	roomService service.RoomService
+	orgService  service.OrganizationService
+	hub         *realtime.Hub
+}
+
+// NewRoomController 创建房间控制器实例
+func NewRoomController(roomService service.RoomService, orgService service.OrganizationService, hub *realtime.Hub) *RoomController {
+	return &RoomController{
+		roomService: roomService,
+		orgService:  orgService,
+		hub:         hub,
+	}
+}
+
+// currentUserID 从 Context 中读取 JWT 中间件写入的用户ID
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	value, exists := ctx.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok
+}
+
+// writeRoomError 把房间相关的业务错误映射为 HTTP 响应
+func writeRoomError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoomNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrRoomFull):
+		response.Error(ctx, 409, 4009, err.Error())
+	case errors.Is(err, service.ErrRoomPassword):
+		response.Forbidden(ctx, err.Error())
+	case errors.Is(err, service.ErrOwnerLeave):
+		response.BadRequest(ctx, err.Error())
+	case errors.Is(err, service.ErrNotRoomMember),
+		errors.Is(err, service.ErrRoomForbidden),
+		errors.Is(err, service.ErrRoomArchived):
+		response.Forbidden(ctx, err.Error())
+	default:
+		response.InternalError(ctx, err.Error())
+	}
+}
+
+// CreateRoom 创建房间
+func (c *RoomController) CreateRoom(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateRoomRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logger.Warn("创建房间参数验证失败", zap.Error(err))
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	// 组织房间统一走组织流程，保证默认/强制模板生效
+	if req.OrganizationUUID != "" {
+		room, err := c.orgService.CreateRoom(ctx.Request.Context(), userID, req.OrganizationUUID, service.OrganizationRoomRequest(&req))
+		if err != nil {
+			writeTemplateError(ctx, err)
+			return
+		}
+		response.SuccessWithCode(ctx, 201, "创建成功", room)
+		return
+	}
+
+	room, err := c.roomService.CreateRoom(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	logger.Info("房间创建成功",
+		zap.String("room_uuid", room.UUID),
+		zap.Uint("creator_id", userID))
+
+	response.SuccessWithCode(ctx, 201, "创建成功", room)
+}
+
+// ListRooms 房间列表
+func (c *RoomController) ListRooms(ctx *gin.Context) {
+	var query service.ListRoomsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	rooms, err := c.roomService.ListRooms(ctx.Request.Context(), &query)
+	if err != nil {
+		logger.Error("获取房间列表失败", zap.Error(err))
+		response.InternalError(ctx, "获取房间列表失败")
+		return
+	}
+
+	response.Success(ctx, "获取成功", rooms)
+}
+
+// GetRoom 房间详情
+func (c *RoomController) GetRoom(ctx *gin.Context) {
+	room, err := c.roomService.GetRoom(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", room)
+}
+
+// JoinRoom 加入房间
+func (c *RoomController) JoinRoom(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	// 公开房间可以不传 body
+	var req service.JoinRoomRequest
+	_ = ctx.ShouldBindJSON(&req)
+
+	member, err := c.roomService.JoinRoom(ctx.Request.Context(), ctx.Param("uuid"), userID, req.Password)
+	if err != nil {
+		logger.BusinessWarn("加入房间失败",
+			zap.String("room_uuid", ctx.Param("uuid")),
+			zap.Uint("user_id", userID),
+			zap.String("error", err.Error()))
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "加入成功", member)
+}
+
+// LeaveRoom 退出房间
+func (c *RoomController) LeaveRoom(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	if err := c.roomService.LeaveRoom(ctx.Request.Context(), ctx.Param("uuid"), userID); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已退出房间", nil)
+}
+
+// GetMembers 成员列表
+func (c *RoomController) GetMembers(ctx *gin.Context) {
+	members, err := c.roomService.GetMembers(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", members)
+}
+
+// UpdateMetadata 修改房间难度、主题和标签
+func (c *RoomController) UpdateMetadata(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.UpdateRoomMetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	room, err := c.roomService.UpdateMetadata(ctx.Request.Context(), ctx.Param("uuid"), userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrRoomNotFound) || errors.Is(err, service.ErrRoomForbidden) ||
+			errors.Is(err, service.ErrNotRoomMember) || errors.Is(err, service.ErrRoomArchived) {
+			writeRoomError(ctx, err)
+			return
+		}
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "修改成功", room)
+}
+
+// UpdateProblem 切换房间题目
+func (c *RoomController) UpdateProblem(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.UpdateRoomProblemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	room, err := c.roomService.UpdateProblem(ctx.Request.Context(), ctx.Param("uuid"), userID, &req)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "修改成功", room)
+}
+
+// UpdateMemberRole 修改成员角色（房主），同时更新其协作连接上的角色，亲和模式下由房间所在节点处理
+func (c *RoomController) UpdateMemberRole(ctx *gin.Context) {
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, ctx.Param("uuid")) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(ctx.Param("userId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的用户ID")
+		return
+	}
+
+	var req service.UpdateMemberRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	if err := c.roomService.UpdateMemberRole(ctx.Request.Context(), ctx.Param("uuid"), userID, uint(targetID), req.Role); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "修改成功", nil)
+}
+
+// KickMember 移出成员（房间管理员），同时断开其协作连接，亲和模式下由房间所在节点处理
+func (c *RoomController) KickMember(ctx *gin.Context) {
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, ctx.Param("uuid")) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(ctx.Param("userId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "无效的用户ID")
+		return
+	}
+
+	if err := c.roomService.KickMember(ctx.Request.Context(), ctx.Param("uuid"), userID, uint(targetID)); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已移出房间", nil)
+}
+
+// ListEvents 房间审计日志（房间管理员）
+func (c *RoomController) ListEvents(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListRoomEventsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.roomService.ListEvents(ctx.Request.Context(), ctx.Param("uuid"), userID, &query)
+	if err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
+
+// RecordActivity 成员活跃心跳
+func (c *RoomController) RecordActivity(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	if err := c.roomService.RecordActivity(ctx.Request.Context(), ctx.Param("uuid"), userID); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "ok", nil)
+}
+
+// ArchiveRoom 归档房间，同时断开在线的协作连接（重连后只读），亲和模式下由房间所在节点处理
+func (c *RoomController) ArchiveRoom(ctx *gin.Context) {
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, ctx.Param("uuid")) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	if err := c.roomService.ArchiveRoom(ctx.Request.Context(), ctx.Param("uuid"), userID); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "房间已归档", nil)
+}
+
+// UnarchiveRoom 恢复已归档的房间，同时断开在线的只读连接（重连后可以编辑），亲和模式下由房间所在节点处理
+func (c *RoomController) UnarchiveRoom(ctx *gin.Context) {
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, ctx.Param("uuid")) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	if err := c.roomService.UnarchiveRoom(ctx.Request.Context(), ctx.Param("uuid"), userID); err != nil {
+		writeRoomError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "房间已恢复", nil)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// TagController 标签控制器
+type TagController struct {
+	tagService service.TagService
+}
+
+// NewTagController 创建标签控制器实例
+func NewTagController(tagService service.TagService) *TagController {
+	return &TagController{
+		tagService: tagService,
+	}
+}
+
+// ListTags 标签列表（带使用次数，用于自动补全）
+func (c *TagController) ListTags(ctx *gin.Context) {
+	var query service.ListTagsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	tags, err := c.tagService.ListTags(ctx.Request.Context(), &query)
+	if err != nil {
+		logger.Error("获取标签列表失败", zap.Error(err))
+		response.InternalError(ctx, "获取标签列表失败")
+		return
+	}
+
+	response.Success(ctx, "获取成功", tags)
+}
+
+// CreateTag 新增标签（管理员）
+func (c *TagController) CreateTag(ctx *gin.Context) {
+	var req service.CreateTagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	tag, err := c.tagService.CreateTag(ctx.Request.Context(), &req)
+	if err != nil {
+		response.Error(ctx, 409, 4009, err.Error())
+		return
+	}
+
+	response.SuccessWithCode(ctx, 201, "创建成功", tag)
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// TemplateController 房间模板控制器
+type TemplateController struct {
+	templateService service.TemplateService
+}
+
+// NewTemplateController 创建模板控制器实例
+func NewTemplateController(templateService service.TemplateService) *TemplateController {
+	return &TemplateController{
+		templateService: templateService,
+	}
+}
+
+// writeTemplateError 模板/组织相关错误映射，其余交给 writeRoomError
+func writeTemplateError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound),
+		errors.Is(err, service.ErrOrganizationNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrTemplateForbidden),
+		errors.Is(err, service.ErrOrganizationForbidden):
+		response.Forbidden(ctx, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// CreateTemplate 创建模板
+func (c *TemplateController) CreateTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.SaveTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logger.Warn("创建模板参数验证失败", zap.Error(err))
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	template, err := c.templateService.CreateTemplate(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.SuccessWithCode(ctx, 201, "创建成功", template)
+}
+
+// SaveRoomAsTemplate 把房间配置另存为模板
+func (c *TemplateController) SaveRoomAsTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.SaveTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logger.Warn("保存模板参数验证失败", zap.Error(err))
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	template, err := c.templateService.SaveRoomAsTemplate(ctx.Request.Context(), userID, ctx.Param("uuid"), &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.SuccessWithCode(ctx, 201, "保存成功", template)
+}
+
+// ListTemplates 可见模板列表
+func (c *TemplateController) ListTemplates(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	templates, err := c.templateService.ListTemplates(ctx.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		logger.Error("获取模板列表失败", zap.Error(err))
+		response.InternalError(ctx, "获取模板列表失败")
+		return
+	}
+
+	response.Success(ctx, "获取成功", templates)
+}
+
+// GetTemplate 模板详情
+func (c *TemplateController) GetTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	template, err := c.templateService.GetTemplate(ctx.Request.Context(), userID, ctx.Param("uuid"))
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", template)
+}
+
+// DeleteTemplate 删除模板
+func (c *TemplateController) DeleteTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	if err := c.templateService.DeleteTemplate(ctx.Request.Context(), userID, ctx.Param("uuid")); err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "删除成功", nil)
+}
+
+// CreateRoomFromTemplate 从模板创建房间
+func (c *TemplateController) CreateRoomFromTemplate(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateRoomFromTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	room, err := c.templateService.CreateRoomFromTemplate(ctx.Request.Context(), userID, ctx.Param("uuid"), &req)
+	if err != nil {
+		writeTemplateError(ctx, err)
+		return
+	}
+
+	logger.Info("从模板创建房间成功",
+		zap.String("template_uuid", ctx.Param("uuid")),
+		zap.String("room_uuid", room.UUID),
+		zap.Uint("creator_id", userID))
+
+	response.SuccessWithCode(ctx, 201, "创建成功", room)
+}
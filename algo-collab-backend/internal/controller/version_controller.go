@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+	"go.uber.org/zap"
+)
+
+// VersionController 房间代码历史版本控制器
+type VersionController struct {
+	versionService service.VersionService
+	hub            *realtime.Hub
+}
+
+// NewVersionController 创建历史版本控制器实例
+func NewVersionController(versionService service.VersionService, hub *realtime.Hub) *VersionController {
+	return &VersionController{
+		versionService: versionService,
+		hub:            hub,
+	}
+}
+
+// writeVersionError 历史版本相关的错误，其余交给 writeRoomError
+func writeVersionError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentVersionNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, realtime.ErrHubClosed),
+		errors.Is(err, realtime.ErrNotRoomOwner):
+		response.Error(ctx, 503, 5003, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// ListVersions 历史版本列表（房间成员）
+func (c *VersionController) ListVersions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var query service.ListVersionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	page, err := c.versionService.ListVersions(ctx.Request.Context(), ctx.Param("uuid"), userID, &query)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", page)
+}
+
+// GetVersion 历史版本的内容（房间成员）
+func (c *VersionController) GetVersion(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	versionID, ok := parseIDParam(ctx, "versionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的版本ID")
+		return
+	}
+
+	version, err := c.versionService.GetVersion(ctx.Request.Context(), ctx.Param("uuid"), userID, versionID)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", version)
+}
+
+// CreateCheckpoint 把当前代码保存为命名检查点（房间成员）
+func (c *VersionController) CreateCheckpoint(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateCheckpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	version, err := c.versionService.CreateCheckpoint(ctx.Request.Context(), ctx.Param("uuid"), userID, &req)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "检查点已保存", version)
+}
+
+// DiffVersion 对比历史版本，?against= 指定另一个版本，默认和当前代码对比（房间成员）
+func (c *VersionController) DiffVersion(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	versionID, ok := parseIDParam(ctx, "versionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的版本ID")
+		return
+	}
+
+	var query service.DiffVersionQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	diff, err := c.versionService.DiffVersion(ctx.Request.Context(), ctx.Param("uuid"), userID, versionID, &query)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", diff)
+}
+
+// Blame 当前代码每一行的作者和贡献占比（房间成员）
+func (c *VersionController) Blame(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	blame, err := c.versionService.Blame(ctx.Request.Context(), ctx.Param("uuid"), userID, 0)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", blame)
+}
+
+// VersionBlame 历史版本每一行的作者和贡献占比（房间成员）
+func (c *VersionController) VersionBlame(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	versionID, ok := parseIDParam(ctx, "versionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的版本ID")
+		return
+	}
+
+	blame, err := c.versionService.Blame(ctx.Request.Context(), ctx.Param("uuid"), userID, versionID)
+	if err != nil {
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", blame)
+}
+
+// RestoreVersion 恢复历史版本（房间管理员及以上），只恢复主文件，返回恢复前自动保存的版本
+// 亲和模式下由房间所在节点修改文档
+func (c *VersionController) RestoreVersion(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	versionID, ok := parseIDParam(ctx, "versionId")
+	if !ok {
+		response.BadRequest(ctx, "无效的版本ID")
+		return
+	}
+
+	backup, err := c.versionService.RestoreVersion(ctx.Request.Context(), roomUUID, userID, versionID)
+	if err != nil {
+		logger.BusinessWarn("恢复历史版本失败",
+			zap.String("room_uuid", roomUUID),
+			zap.Uint("version_id", versionID),
+			zap.String("error", err.Error()))
+		writeVersionError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "已恢复", backup)
+}
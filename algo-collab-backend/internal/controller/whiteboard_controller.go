@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// WhiteboardController 房间协作白板控制器
+type WhiteboardController struct {
+	whiteboardService service.WhiteboardService
+	hub               *realtime.Hub
+}
+
+// NewWhiteboardController 创建白板控制器实例
+func NewWhiteboardController(whiteboardService service.WhiteboardService, hub *realtime.Hub) *WhiteboardController {
+	return &WhiteboardController{
+		whiteboardService: whiteboardService,
+		hub:               hub,
+	}
+}
+
+// writeWhiteboardError 白板相关的错误，其余交给 writeCommentError
+func writeWhiteboardError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWhiteboardNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrWhiteboardTitle),
+		errors.Is(err, service.ErrTooManyWhiteboards),
+		errors.Is(err, service.ErrWhiteboardFormat):
+		response.BadRequest(ctx, err.Error())
+	default:
+		writeCommentError(ctx, err)
+	}
+}
+
+// ListWhiteboards 白板列表（房间成员）
+func (c *WhiteboardController) ListWhiteboards(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	boards, err := c.whiteboardService.ListWhiteboards(ctx.Request.Context(), ctx.Param("uuid"), userID)
+	if err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", boards)
+}
+
+// CreateWhiteboard 新建白板（房间成员），亲和模式下由房间所在节点处理
+func (c *WhiteboardController) CreateWhiteboard(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateWhiteboardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	board, err := c.whiteboardService.CreateWhiteboard(ctx.Request.Context(), roomUUID, userID, &req)
+	if err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "白板已创建", board)
+}
+
+// UpdateWhiteboard 修改标题或关联的讨论（房间成员），亲和模式下由房间所在节点处理
+func (c *WhiteboardController) UpdateWhiteboard(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	boardID, ok := parseIDParam(ctx, "boardId")
+	if !ok {
+		response.BadRequest(ctx, "无效的白板ID")
+		return
+	}
+
+	var req service.UpdateWhiteboardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	board, err := c.whiteboardService.UpdateWhiteboard(ctx.Request.Context(), roomUUID, userID, boardID, &req)
+	if err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "白板已更新", board)
+}
+
+// DeleteWhiteboard 删除白板（房间成员），亲和模式下由房间所在节点处理
+func (c *WhiteboardController) DeleteWhiteboard(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	boardID, ok := parseIDParam(ctx, "boardId")
+	if !ok {
+		response.BadRequest(ctx, "无效的白板ID")
+		return
+	}
+
+	if err := c.whiteboardService.DeleteWhiteboard(ctx.Request.Context(), roomUUID, userID, boardID); err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "白板已删除", nil)
+}
+
+// ExportWhiteboard 导出白板为图片（房间成员），?format=svg（默认）或 png
+func (c *WhiteboardController) ExportWhiteboard(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	boardID, ok := parseIDParam(ctx, "boardId")
+	if !ok {
+		response.BadRequest(ctx, "无效的白板ID")
+		return
+	}
+
+	format := ctx.DefaultQuery("format", service.WhiteboardFormatSVG)
+	export, err := c.whiteboardService.ExportWhiteboard(ctx.Request.Context(), ctx.Param("uuid"), userID, boardID, format)
+	if err != nil {
+		writeWhiteboardError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	ctx.Data(200, export.ContentType, export.Data)
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/response"
+)
+
+// WorkspaceController 房间多文件工作区控制器
+type WorkspaceController struct {
+	workspaceService service.WorkspaceService
+	hub              *realtime.Hub
+}
+
+// NewWorkspaceController 创建工作区控制器实例
+func NewWorkspaceController(workspaceService service.WorkspaceService, hub *realtime.Hub) *WorkspaceController {
+	return &WorkspaceController{
+		workspaceService: workspaceService,
+		hub:              hub,
+	}
+}
+
+// writeWorkspaceError 工作区相关的错误，其余交给 writeRoomError
+func writeWorkspaceError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoomFileNotFound):
+		response.Error(ctx, 404, 4004, err.Error())
+	case errors.Is(err, service.ErrRoomFileExists),
+		errors.Is(err, service.ErrRoomFileConflict):
+		response.Error(ctx, 409, 4009, err.Error())
+	case errors.Is(err, service.ErrRoomFilePath),
+		errors.Is(err, service.ErrRoomFileMain),
+		errors.Is(err, service.ErrTooManyRoomFiles):
+		response.BadRequest(ctx, err.Error())
+	case errors.Is(err, service.ErrRoomRegionLocked):
+		response.Forbidden(ctx, err.Error())
+	case errors.Is(err, realtime.ErrHubClosed),
+		errors.Is(err, realtime.ErrNotRoomOwner):
+		response.Error(ctx, 503, 5003, err.Error())
+	default:
+		writeRoomError(ctx, err)
+	}
+}
+
+// ListFiles 工作区文件列表（房间成员）
+func (c *WorkspaceController) ListFiles(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	files, err := c.workspaceService.ListFiles(ctx.Request.Context(), ctx.Param("uuid"), userID)
+	if err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", files)
+}
+
+// CreateFile 新建文件（房间成员），亲和模式下由房间所在节点处理
+func (c *WorkspaceController) CreateFile(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req service.CreateRoomFileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	file, err := c.workspaceService.CreateFile(ctx.Request.Context(), roomUUID, userID, &req)
+	if err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "文件已创建", file)
+}
+
+// UpdateFile 重命名或移动文件（房间成员），亲和模式下由房间所在节点处理
+func (c *WorkspaceController) UpdateFile(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	fileID, ok := parseIDParam(ctx, "fileId")
+	if !ok {
+		response.BadRequest(ctx, "无效的文件ID")
+		return
+	}
+
+	var req service.UpdateRoomFileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "参数验证失败: "+err.Error())
+		return
+	}
+
+	file, err := c.workspaceService.UpdateFile(ctx.Request.Context(), roomUUID, userID, fileID, &req)
+	if err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "文件已更新", file)
+}
+
+// DeleteFile 删除文件（房间成员），亲和模式下由房间所在节点处理
+func (c *WorkspaceController) DeleteFile(ctx *gin.Context) {
+	roomUUID := ctx.Param("uuid")
+	if c.hub.ForwardToOwner(ctx.Writer, ctx.Request, roomUUID) {
+		ctx.Abort()
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	fileID, ok := parseIDParam(ctx, "fileId")
+	if !ok {
+		response.BadRequest(ctx, "无效的文件ID")
+		return
+	}
+
+	if err := c.workspaceService.DeleteFile(ctx.Request.Context(), roomUUID, userID, fileID); err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "文件已删除", nil)
+}
+
+// GetWorkspace 所有文件及其内容，供执行代码使用（房间成员）
+func (c *WorkspaceController) GetWorkspace(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	workspace, err := c.workspaceService.Workspace(ctx.Request.Context(), ctx.Param("uuid"), userID)
+	if err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", workspace)
+}
+
+// GetFileContent 按路径读取一个文件的当前内容（房间成员）
+// 路径编码后作为一个路径段，其中的 / 编码为 %2F，例如 /rooms/:uuid/files/src%2Futils.py/content
+func (c *WorkspaceController) GetFileContent(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+	path, ok := filePathParam(ctx)
+	if !ok {
+		response.BadRequest(ctx, "无效的文件路径")
+		return
+	}
+
+	file, err := c.workspaceService.FileContent(ctx.Request.Context(), ctx.Param("uuid"), userID, path)
+	if err != nil {
+		writeWorkspaceError(ctx, err)
+		return
+	}
+
+	response.Success(ctx, "获取成功", file)
+}
+
+// filePathParam 解码路由参数 :path（/content 之前的路径段）
+// 从原始路径中取出再解码：请求路径中没有 %2F 时 gin 按已解码的路径匹配，参数可能已经解码过一次
+func filePathParam(ctx *gin.Context) (string, bool) {
+	segments := strings.Split(ctx.Request.URL.EscapedPath(), "/")
+	if len(segments) < 2 {
+		return "", false
+	}
+	path, err := url.PathUnescape(segments[len(segments)-2])
+	return path, err == nil && path != ""
+}
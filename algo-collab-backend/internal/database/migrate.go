@@ -4,21 +4,64 @@ import (
 	"log"
 
 	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
 )
 
+// Models 需要自动迁移的所有模型
+// 按 room_id 归属于房间的模型还要加入 repository 中的 roomOwnedTables，彻底删除房间时才会清理
+var Models = []interface{}{
+	&models.User{},
+	&models.Room{},
+	&models.RoomMember{},
+	&models.RoomTemplate{},
+	&models.Organization{},
+	&models.OrganizationMember{},
+	&models.Tag{},
+	&models.RoomEvent{},
+	&models.Document{},
+	&models.DocumentUpdate{},
+	&models.DocumentVersion{},
+	&models.RoomMessage{},
+	&models.RoomMessageReaction{},
+	&models.Notification{},
+	&models.RoomMessageReport{},
+	&models.RoomSession{},
+	&models.RoomSessionEvent{},
+	&models.DocumentAuthor{},
+	&models.RoomFile{},
+	&models.RoomCommentThread{},
+	&models.RoomComment{},
+	&models.RoomLock{},
+	&models.RoomWhiteboard{},
+	&models.WhiteboardDocument{},
+	&models.WhiteboardUpdate{},
+	// 后续添加更多模型...
+}
+
 // AutoMigrate 自动迁移数据库表
 func AutoMigrate() error {
 	// 自动创建或更新表结构
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Room{},
-		// 后续添加更多模型...
-	)
+	err := DB.AutoMigrate(Models...)
+	if err != nil {
+		return err
+	}
 
+	// 加入活跃时间之前创建的房间没有 last_active_at，按创建时间补齐，否则永远不会被自动归档
+	err = DB.Model(&models.Room{}).
+		Where("last_active_at IS NULL").
+		UpdateColumn("last_active_at", gorm.Expr("created_at")).Error
 	if err != nil {
 		return err
 	}
 
+	// 白板改为独立文档之前的图形保存在代码文档的 Y.Map 中，map_name 不再使用，
+	// 它是非空列，不删除的话新建白板会失败（索引随列一起删除）
+	if DB.Migrator().HasColumn(&models.RoomWhiteboard{}, "map_name") {
+		if err := DB.Migrator().DropColumn(&models.RoomWhiteboard{}, "map_name"); err != nil {
+			return err
+		}
+	}
+
 	log.Println("✅ 数据库表迁移完成")
 	return nil
 }
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// replayCleanupInterval 清理过期回放的间隔
+const replayCleanupInterval = time.Hour
+
+// ReplayCleanupJob 删除超过保留天数的回放会话及其事件
+type ReplayCleanupJob struct {
+	sessionRepo repository.RoomSessionRepository
+	cfg         *config.ReplayConfig
+}
+
+// NewReplayCleanupJob 创建回放清理任务
+func NewReplayCleanupJob(sessionRepo repository.RoomSessionRepository, cfg *config.ReplayConfig) *ReplayCleanupJob {
+	return &ReplayCleanupJob{
+		sessionRepo: sessionRepo,
+		cfg:         cfg,
+	}
+}
+
+// Start 在后台每小时运行一次，ctx 取消后退出；未配置保留天数时不启动
+func (j *ReplayCleanupJob) Start(ctx context.Context) {
+	if j.cfg.RetentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(replayCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			j.RunOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce 分批删除过期的回放，直到没有剩余
+func (j *ReplayCleanupJob) RunOnce(ctx context.Context) {
+	before := time.Now().AddDate(0, 0, -j.cfg.RetentionDays)
+
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := j.sessionRepo.DeleteBefore(ctx, before, purgeBatchSize)
+		if err != nil {
+			logger.Error("清理过期回放失败", zap.Error(err))
+			break
+		}
+		total += deleted
+		if deleted < purgeBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		logger.Info("已清理过期回放", zap.Int64("count", total))
+	}
+}
@@ -0,0 +1,126 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// purgeBatchSize 每轮最多彻底删除的房间数，避免一次清理时间过长
+const purgeBatchSize = 100
+
+// RoomCloser 断开本节点上协作房间的所有连接，由 realtime.Hub 实现
+type RoomCloser interface {
+	// LoadedRooms 本节点正在协作的房间 UUID
+	LoadedRooms() []string
+	CloseRoom(ctx context.Context, room *models.Room) error
+}
+
+// RoomCleanupJob 房间清理任务
+// 1. 长时间无活动的房间 → 归档（只读、不出现在列表中）
+// 2. 归档时间过长的房间 → 物理删除（连同成员和文档）
+// 3. 本节点上已归档或删除的房间 → 断开协作连接，重连后只读或被拒绝
+type RoomCleanupJob struct {
+	roomRepo repository.RoomRepository
+	rooms    RoomCloser
+	cfg      *config.RoomConfig
+}
+
+// NewRoomCleanupJob 创建房间清理任务
+// 每个节点都运行清理任务：归档和删除是幂等的，断开连接只处理本节点的房间
+func NewRoomCleanupJob(roomRepo repository.RoomRepository, rooms RoomCloser, cfg *config.RoomConfig) *RoomCleanupJob {
+	return &RoomCleanupJob{
+		roomRepo: roomRepo,
+		rooms:    rooms,
+		cfg:      cfg,
+	}
+}
+
+// Start 在后台按配置的间隔运行，ctx 取消后退出
+func (j *RoomCleanupJob) Start(ctx context.Context) {
+	interval := time.Duration(j.cfg.CleanupIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			j.RunOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce 执行一轮归档和清理
+func (j *RoomCleanupJob) RunOnce(ctx context.Context) {
+	now := time.Now()
+
+	// 1. 归档长时间无活动的房间
+	if j.cfg.ArchiveAfterDays > 0 {
+		inactiveBefore := now.AddDate(0, 0, -j.cfg.ArchiveAfterDays)
+		archived, err := j.roomRepo.ArchiveIdleRooms(ctx, inactiveBefore)
+		if err != nil {
+			logger.Error("归档闲置房间失败", zap.Error(err))
+		} else if archived > 0 {
+			logger.Info("已归档闲置房间", zap.Int64("count", archived))
+		}
+	}
+
+	// 2. 彻底删除归档过久的房间
+	if j.cfg.PurgeAfterDays > 0 {
+		archivedBefore := now.AddDate(0, 0, -j.cfg.PurgeAfterDays)
+		rooms, err := j.roomRepo.FindPurgeableRooms(ctx, archivedBefore, purgeBatchSize)
+		if err != nil {
+			logger.Error("查询待清理房间失败", zap.Error(err))
+		}
+
+		for _, room := range rooms {
+			if err := j.roomRepo.PurgeRoom(ctx, room.ID); err != nil {
+				logger.Error("清理房间失败", zap.String("room_uuid", room.UUID), zap.Error(err))
+				continue
+			}
+			logger.Info("已彻底删除归档房间", zap.String("room_uuid", room.UUID))
+		}
+	}
+
+	// 3. 断开本节点上已归档或删除的房间，包括其他节点归档的房间
+	j.closeInactiveRooms(ctx)
+}
+
+// closeInactiveRooms 本节点加载的房间已归档或不存在时断开所有连接
+func (j *RoomCleanupJob) closeInactiveRooms(ctx context.Context) {
+	if j.rooms == nil {
+		return
+	}
+	for _, roomUUID := range j.rooms.LoadedRooms() {
+		room, err := j.roomRepo.FindByUUID(ctx, roomUUID)
+		switch {
+		case errors.Is(err, repository.ErrRoomNotFound):
+			room = &models.Room{UUID: roomUUID}
+		case err != nil:
+			logger.Error("查询协作房间状态失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+			continue
+		case !room.IsArchived():
+			continue
+		}
+		if err := j.rooms.CloseRoom(ctx, room); err != nil {
+			logger.Warn("断开房间连接失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+			continue
+		}
+		logger.Info("已断开归档或删除房间的协作连接", zap.String("room_uuid", roomUUID))
+	}
+}
@@ -0,0 +1,115 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// cleanupRoomRepo 记录清理任务的调用，其他方法不会被用到
+type cleanupRoomRepo struct {
+	repository.RoomRepository
+
+	inactiveBefore time.Time
+	archivedBefore time.Time
+	purgeable      []*models.Room
+	failPurge      uint
+	purged         []uint
+	archived       map[string]bool
+}
+
+func (r *cleanupRoomRepo) FindByUUID(_ context.Context, uuid string) (*models.Room, error) {
+	for _, purged := range r.purged {
+		if r.purgeable[purged-1].UUID == uuid {
+			return nil, repository.ErrRoomNotFound
+		}
+	}
+	room := &models.Room{UUID: uuid, Status: models.RoomStatusActive}
+	if r.archived[uuid] {
+		room.Status = models.RoomStatusArchived
+	}
+	return room, nil
+}
+
+// fakeRoomCloser 本节点加载的房间，记录被断开的房间
+type fakeRoomCloser struct {
+	loaded []string
+	closed []string
+}
+
+func (c *fakeRoomCloser) LoadedRooms() []string {
+	return c.loaded
+}
+
+func (c *fakeRoomCloser) CloseRoom(_ context.Context, room *models.Room) error {
+	c.closed = append(c.closed, room.UUID)
+	return nil
+}
+
+func (r *cleanupRoomRepo) ArchiveIdleRooms(_ context.Context, inactiveBefore time.Time) (int64, error) {
+	r.inactiveBefore = inactiveBefore
+	return 2, nil
+}
+
+func (r *cleanupRoomRepo) FindPurgeableRooms(_ context.Context, archivedBefore time.Time, limit int) ([]*models.Room, error) {
+	r.archivedBefore = archivedBefore
+	if len(r.purgeable) > limit {
+		return r.purgeable[:limit], nil
+	}
+	return r.purgeable, nil
+}
+
+func (r *cleanupRoomRepo) PurgeRoom(_ context.Context, roomID uint) error {
+	if roomID == r.failPurge {
+		return errors.New("数据库不可用")
+	}
+	r.purged = append(r.purged, roomID)
+	return nil
+}
+
+func TestRoomCleanupJob_RunOnce(t *testing.T) {
+	repo := &cleanupRoomRepo{
+		purgeable: []*models.Room{
+			{BaseModel: models.BaseModel{ID: 1}, UUID: "a"},
+			{BaseModel: models.BaseModel{ID: 2}, UUID: "b"},
+			{BaseModel: models.BaseModel{ID: 3}, UUID: "c"},
+		},
+		failPurge: 2,
+		archived:  map[string]bool{"b": true, "idle": true},
+	}
+	rooms := &fakeRoomCloser{loaded: []string{"a", "b", "active", "idle"}}
+	job := NewRoomCleanupJob(repo, rooms, &config.RoomConfig{ArchiveAfterDays: 30, PurgeAfterDays: 90})
+
+	before := time.Now()
+	job.RunOnce(context.Background())
+
+	assert.WithinDuration(t, before.AddDate(0, 0, -30), repo.inactiveBefore, time.Second)
+	assert.WithinDuration(t, before.AddDate(0, 0, -90), repo.archivedBefore, time.Second)
+	// 一个房间清理失败不影响其他房间
+	assert.Equal(t, []uint{1, 3}, repo.purged)
+	// 本节点上已删除和已归档（包括其他节点归档）的房间断开连接，活跃的房间不受影响
+	assert.ElementsMatch(t, []string{"a", "b", "idle"}, rooms.closed)
+}
+
+func TestRoomCleanupJob_Disabled(t *testing.T) {
+	repo := &cleanupRoomRepo{purgeable: []*models.Room{{BaseModel: models.BaseModel{ID: 1}}}}
+	NewRoomCleanupJob(repo, nil, &config.RoomConfig{}).RunOnce(context.Background())
+
+	assert.True(t, repo.inactiveBefore.IsZero())
+	assert.True(t, repo.archivedBefore.IsZero())
+	assert.Empty(t, repo.purged)
+}
@@ -0,0 +1,233 @@
+// Package lsp 实现语言服务器网关
+//
+// 编辑器通过 WebSocket 发送 JSON-RPC 消息（每条 WebSocket 消息一条），网关转发给
+// 沙箱中为房间启动的语言服务器（gopls、pyright、jdtls、typescript-language-server）。
+// 同一房间同一语言的连接共享一个语言服务器进程，文件内容以协作文档为准，由网关同步。
+// 进程池按节点管理，限制同时运行的进程数，并回收空闲的进程。
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+)
+
+var (
+	// ErrUnavailable 没有开启语言服务器网关或没有可用的沙箱
+	ErrUnavailable = errors.New("语言服务器未开启")
+	// ErrUnsupportedLanguage 没有这种语言的语言服务器
+	ErrUnsupportedLanguage = errors.New("不支持该语言的语言服务器")
+	// ErrTooManyServers 本节点运行的语言服务器已达上限，且都在使用中
+	ErrTooManyServers = errors.New("语言服务器繁忙，请稍后再试")
+	// ErrLaunchFailed 沙箱启动语言服务器失败
+	ErrLaunchFailed = errors.New("语言服务器启动失败")
+)
+
+// languageServers 房间文件的语言对应的语言服务器，JavaScript 和 TypeScript 共用一个
+var languageServers = map[string]string{
+	"go":         "gopls",
+	"python":     "pyright",
+	"java":       "jdtls",
+	"javascript": "typescript-language-server",
+	"typescript": "typescript-language-server",
+}
+
+const (
+	clientSendBufferSize = 256
+	clientWriteTimeout   = 10 * time.Second
+	clientPingInterval   = 30 * time.Second
+)
+
+// Launcher 在代码执行沙箱中启动语言服务器，返回它的标准输入输出
+// 工作区挂载在沙箱的 /workspace 下（可以是空目录，文件内容由网关通过 LSP 同步），
+// Close 结束进程。实现需要自行限制 CPU、内存和网络；sandbox.Launcher 按配置的命令（例如 docker run）启动
+type Launcher interface {
+	Launch(ctx context.Context, roomUUID, server string) (io.ReadWriteCloser, error)
+}
+
+// Workspaces 读取房间工作区的文件和内容，由 service.WorkspaceService 实现
+type Workspaces interface {
+	Snapshot(ctx context.Context, room *models.Room) (*service.Workspace, error)
+}
+
+// Options Gateway 配置
+type Options struct {
+	AllowOrigins []string
+	// Launcher 为空或 Config 未开启时网关不可用
+	Launcher   Launcher
+	Workspaces Workspaces
+	Config     config.LSPConfig
+}
+
+// Gateway 语言服务器网关
+type Gateway struct {
+	opts     Options
+	pool     *pool // 为空表示不可用
+	upgrader websocket.Upgrader
+}
+
+// NewGateway 创建 Gateway
+func NewGateway(opts Options) *Gateway {
+	g := &Gateway{opts: opts}
+	if opts.Config.Enabled && opts.Launcher != nil {
+		idle := time.Duration(opts.Config.IdleTimeoutSeconds) * time.Second
+		g.pool = newPool(opts.Launcher, opts.Workspaces, opts.Config.MaxServers, idle)
+	}
+	g.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     g.checkOrigin,
+	}
+	return g
+}
+
+// Start 启动回收空闲语言服务器的后台任务，ctx 取消后关闭所有语言服务器
+func (g *Gateway) Start(ctx context.Context) {
+	if g.pool != nil {
+		go g.pool.reap(ctx)
+	}
+}
+
+// Check 检查能否为 language 提供语言服务器，在升级连接前调用，方便返回 HTTP 错误
+func (g *Gateway) Check(language string) error {
+	if g.pool == nil {
+		return ErrUnavailable
+	}
+	if _, ok := languageServers[language]; !ok {
+		return ErrUnsupportedLanguage
+	}
+	return nil
+}
+
+// Serve 获取房间的语言服务器并把 HTTP 连接升级为 WebSocket，连接断开后返回
+// 调用方负责校验成员身份；升级之前的错误调用方可以直接返回给客户端
+func (g *Gateway) Serve(w http.ResponseWriter, r *http.Request, room *models.Room, language string) error {
+	if err := g.Check(language); err != nil {
+		return err
+	}
+	server, err := g.pool.acquire(room, languageServers[language])
+	if err != nil {
+		return err
+	}
+	defer g.pool.release(server)
+
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	c := newClient(conn)
+	if !server.addClient(c) {
+		c.close()
+		return nil
+	}
+	defer server.removeClient(c)
+
+	go c.writePump()
+	c.readPump(server)
+	return nil
+}
+
+// checkOrigin 按 CORS 白名单校验 Origin；非浏览器客户端没有 Origin，直接放行
+func (g *Gateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range g.opts.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// client 一个编辑器连接
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(conn *websocket.Conn) *client {
+	conn.SetReadLimit(maxMessageSize)
+	return &client{
+		conn: conn,
+		send: make(chan []byte, clientSendBufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// enqueue 加入发送队列，队列满说明客户端跟不上，断开让它重新连接
+func (c *client) enqueue(data []byte) {
+	select {
+	case <-c.done:
+	case c.send <- data:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) reply(id, result json.RawMessage) {
+	c.write(&message{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (c *client) replyError(id json.RawMessage, code int, text string) {
+	c.write(&message{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (c *client) write(msg *message) {
+	if data, err := json.Marshal(msg); err == nil {
+		c.enqueue(data)
+	}
+}
+
+func (c *client) readPump(server *languageServer) {
+	defer c.close()
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		server.handleClient(c, data)
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(clientPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(clientWriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// fakeServer 一个假的语言服务器：记录打开的文档，hover 返回文档的当前内容
+type fakeServer struct {
+	in  *io.PipeReader // 网关写入的消息
+	out *io.PipeWriter // 发给网关的消息
+
+	mu          sync.Mutex
+	docs        map[string]string
+	initialized int
+	rootURI     string
+	config      json.RawMessage
+	closed      bool
+}
+
+func (f *fakeServer) serve() {
+	r := bufio.NewReader(f.in)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		var params struct {
+			RootURI      string `json:"rootUri"`
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		uri := params.TextDocument.URI
+
+		f.mu.Lock()
+		switch msg.Method {
+		case "initialize":
+			f.initialized++
+			f.rootURI = params.RootURI
+			f.reply(msg.ID, `{"capabilities":{"hoverProvider":true}}`)
+			f.write(&message{JSONRPC: "2.0", ID: json.RawMessage(`"cfg"`), Method: "workspace/configuration", Params: json.RawMessage(`{"items":[{"section":"python"}]}`)})
+		case "textDocument/didOpen":
+			f.docs[uri] = params.TextDocument.Text
+		case "textDocument/didChange":
+			f.docs[uri] = params.ContentChanges[0].Text
+		case "textDocument/didClose":
+			delete(f.docs, uri)
+		case "textDocument/hover":
+			contents, _ := json.Marshal(f.docs[uri])
+			f.reply(msg.ID, `{"contents":`+string(contents)+`}`)
+		case "":
+			if string(msg.ID) == `"cfg"` {
+				f.config = msg.Result
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeServer) reply(id json.RawMessage, result string) {
+	f.write(&message{JSONRPC: "2.0", ID: id, Result: json.RawMessage(result)})
+}
+
+func (f *fakeServer) write(msg *message) {
+	data, _ := json.Marshal(msg)
+	_ = writeMessage(f.out, data)
+}
+
+func (f *fakeServer) doc(uri string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	text, ok := f.docs[uri]
+	return text, ok
+}
+
+// fakeConn 网关看到的语言服务器标准输入输出
+type fakeConn struct {
+	io.Reader
+	io.Writer
+	server *fakeServer
+}
+
+func (c *fakeConn) Close() error {
+	c.server.mu.Lock()
+	c.server.closed = true
+	c.server.mu.Unlock()
+	_ = c.server.in.Close()
+	return c.server.out.Close()
+}
+
+type fakeLauncher struct {
+	mu      sync.Mutex
+	servers []*fakeServer
+}
+
+func (l *fakeLauncher) Launch(_ context.Context, _, _ string) (io.ReadWriteCloser, error) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	server := &fakeServer{in: inR, out: outW, docs: make(map[string]string)}
+	go server.serve()
+
+	l.mu.Lock()
+	l.servers = append(l.servers, server)
+	l.mu.Unlock()
+	return &fakeConn{Reader: outR, Writer: inW, server: server}, nil
+}
+
+func (l *fakeLauncher) launched() []*fakeServer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*fakeServer(nil), l.servers...)
+}
+
+// fakeWorkspaces 房间的工作区，内容可以在测试中修改
+type fakeWorkspaces struct {
+	mu    sync.Mutex
+	files map[string]*service.WorkspaceFile
+}
+
+func (w *fakeWorkspaces) Snapshot(_ context.Context, _ *models.Room) (*service.Workspace, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	workspace := &service.Workspace{Entry: "main.py"}
+	for _, file := range w.files {
+		copied := *file
+		workspace.Files = append(workspace.Files, &copied)
+	}
+	return workspace, nil
+}
+
+func (w *fakeWorkspaces) set(path, language, content string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[path] = &service.WorkspaceFile{Path: path, Language: language, Content: content}
+}
+
+func (w *fakeWorkspaces) remove(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.files, path)
+}
+
+func newTestGateway(t *testing.T, cfg config.LSPConfig) (string, *fakeLauncher, *fakeWorkspaces) {
+	launcher := &fakeLauncher{}
+	workspaces := &fakeWorkspaces{files: make(map[string]*service.WorkspaceFile)}
+	gateway := NewGateway(Options{Launcher: launcher, Workspaces: workspaces, Config: cfg})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		room := &models.Room{UUID: r.URL.Query().Get("room")}
+		if err := gateway.Serve(w, r, room, r.URL.Query().Get("language")); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), launcher, workspaces
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func sendRPC(t *testing.T, conn *websocket.Conn, id any, method string, params any) {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != nil {
+		msg["id"] = id
+	}
+	require.NoError(t, conn.WriteJSON(msg))
+}
+
+// readResponse 跳过通知，读取下一条响应
+func readResponse(t *testing.T, conn *websocket.Conn) *message {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg message
+		require.NoError(t, conn.ReadJSON(&msg))
+		if msg.isResponse() {
+			return &msg
+		}
+	}
+}
+
+func hover(t *testing.T, conn *websocket.Conn, id int, path string) string {
+	sendRPC(t, conn, id, "textDocument/hover", map[string]any{
+		"textDocument": map[string]string{"uri": documentURI(path)},
+		"position":     map[string]int{"line": 0, "character": 0},
+	})
+	resp := readResponse(t, conn)
+	assert.JSONEq(t, itoa(id), string(resp.ID))
+	var result struct {
+		Contents string `json:"contents"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	return result.Contents
+}
+
+func itoa(id int) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+func TestGateway_ProxiesAndSyncsDocuments(t *testing.T) {
+	url, launcher, workspaces := newTestGateway(t, config.LSPConfig{Enabled: true, MaxServers: 1})
+	workspaces.set("main.py", "python", "print(1)")
+	workspaces.set("notes.md", "markdown", "# notes")
+
+	// 1. 第一个连接的 initialize 初始化语言服务器，工作区改为沙箱中的目录
+	alice := dial(t, url+"?room=r1&language=python")
+	sendRPC(t, alice, 1, "initialize", map[string]any{"rootUri": "file:///home/alice", "capabilities": map[string]any{}})
+	resp := readResponse(t, alice)
+	assert.JSONEq(t, `{"capabilities":{"hoverProvider":true}}`, string(resp.Result))
+
+	require.Len(t, launcher.launched(), 1)
+	server := launcher.launched()[0]
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.config != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "file:///workspace", server.rootURI)
+	assert.JSONEq(t, `[null]`, string(server.config))
+
+	// 2. 只同步这种语言的文件，客户端自己的 didOpen 被丢弃
+	sendRPC(t, alice, nil, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": documentURI("main.py"), "text": "stale"},
+	})
+	assert.Equal(t, "print(1)", hover(t, alice, 2, "main.py"))
+	_, ok := server.doc(documentURI("notes.md"))
+	assert.False(t, ok)
+
+	// 3. 第二个连接共享语言服务器，相同的请求 id 各自收到自己的响应
+	bob := dial(t, url+"?room=r1&language=python")
+	sendRPC(t, bob, 1, "initialize", map[string]any{})
+	assert.JSONEq(t, `{"capabilities":{"hoverProvider":true}}`, string(readResponse(t, bob).Result))
+	assert.Len(t, launcher.launched(), 1)
+	server.mu.Lock()
+	assert.Equal(t, 1, server.initialized)
+	server.mu.Unlock()
+	assert.Equal(t, "print(1)", hover(t, bob, 2, "main.py"))
+
+	// 4. 协作文档的修改、新增和删除同步给语言服务器
+	workspaces.set("main.py", "python", "print(2)")
+	workspaces.set("util.py", "python", "def f(): pass")
+	time.Sleep(syncMinInterval)
+	assert.Equal(t, "print(2)", hover(t, alice, 3, "main.py"))
+	assert.Equal(t, "def f(): pass", hover(t, bob, 3, "util.py"))
+	workspaces.remove("util.py")
+	require.Eventually(t, func() bool {
+		_, ok := server.doc(documentURI("util.py"))
+		return !ok
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// 5. 进程数已达上限且都在使用中，其他房间无法启动新的语言服务器
+	_, resp2, err := websocket.DefaultDialer.Dial(url+"?room=r2&language=python", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode)
+
+	// 6. 所有连接断开后，空闲的语言服务器让位给其他房间
+	alice.Close()
+	bob.Close()
+	time.Sleep(100 * time.Millisecond)
+	dial(t, url+"?room=r2&language=python")
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.closed
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Len(t, launcher.launched(), 2)
+}
+
+func TestGateway_RejectsUnavailableLanguages(t *testing.T) {
+	assert.ErrorIs(t, NewGateway(Options{Config: config.LSPConfig{Enabled: true}}).Check("python"), ErrUnavailable)
+	assert.ErrorIs(t, NewGateway(Options{Launcher: &fakeLauncher{}}).Check("python"), ErrUnavailable)
+
+	gateway := NewGateway(Options{Launcher: &fakeLauncher{}, Config: config.LSPConfig{Enabled: true}})
+	assert.NoError(t, gateway.Check("go"))
+	assert.ErrorIs(t, gateway.Check("cobol"), ErrUnsupportedLanguage)
+}
+
+func TestPool_ReapsIdleServers(t *testing.T) {
+	launcher := &fakeLauncher{}
+	p := newPool(launcher, &fakeWorkspaces{files: make(map[string]*service.WorkspaceFile)}, 2, 50*time.Millisecond)
+
+	inUse, err := p.acquire(&models.Room{UUID: "r1"}, "gopls")
+	require.NoError(t, err)
+	idle, err := p.acquire(&models.Room{UUID: "r2"}, "gopls")
+	require.NoError(t, err)
+	p.release(idle)
+
+	time.Sleep(60 * time.Millisecond)
+	p.reapIdle()
+	assert.False(t, inUse.isClosed())
+	assert.True(t, idle.isClosed())
+
+	// 同一房间再次获取时启动新的进程
+	again, err := p.acquire(&models.Room{UUID: "r2"}, "gopls")
+	require.NoError(t, err)
+	assert.NotSame(t, idle, again)
+	assert.Len(t, launcher.launched(), 3)
+}
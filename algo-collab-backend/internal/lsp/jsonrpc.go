@@ -0,0 +1,87 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxMessageSize 单条 JSON-RPC 消息的上限，补全列表等结果可能比较大
+const maxMessageSize = 16 << 20
+
+// JSON-RPC 错误码
+const (
+	codeServerNotInitialized = -32002
+	codeRequestFailed        = -32803
+)
+
+var errInvalidHeader = errors.New("lsp: 无效的消息头")
+
+// message 一条 JSON-RPC 2.0 消息：
+// 有 method 和 id 的是请求，只有 method 的是通知，只有 id 的是响应
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (m *message) isResponse() bool {
+	return m.Method == "" && m.ID != nil
+}
+
+func (m *message) isRequest() bool {
+	return m.Method != "" && m.ID != nil
+}
+
+// readMessage 读取一条 LSP base protocol 消息：Content-Length 头、空行和 JSON 内容
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, errInvalidHeader
+			}
+		}
+	}
+	if length < 0 || length > maxMessageSize {
+		return nil, errInvalidHeader
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeMessage 按 LSP base protocol 写入一条消息
+func writeMessage(w io.Writer, data []byte) error {
+	_, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// notification 编码一条通知
+func notification(method string, params any) *message {
+	data, _ := json.Marshal(params)
+	return &message{JSONRPC: "2.0", Method: method, Params: data}
+}
@@ -0,0 +1,186 @@
+package lsp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxServers  = 8
+	defaultIdleTimeout = 5 * time.Minute
+	// launchTimeout 沙箱启动语言服务器的超时时间
+	launchTimeout = 30 * time.Second
+)
+
+// poolEntry 进程池中的一个语言服务器，server 为空表示正在启动，启动完成后关闭 ready
+type poolEntry struct {
+	server   *languageServer
+	ready    chan struct{}
+	err      error
+	refs     int
+	lastUsed time.Time
+}
+
+// pool 本节点的语言服务器进程池
+// 限制同时运行的进程数，满了以后关闭最久没有使用的空闲进程；没有连接的进程超时后关闭
+type pool struct {
+	launcher   Launcher
+	workspaces Workspaces
+	max        int
+	idle       time.Duration
+
+	mu      sync.Mutex
+	entries map[serverKey]*poolEntry
+}
+
+func newPool(launcher Launcher, workspaces Workspaces, max int, idle time.Duration) *pool {
+	if max <= 0 {
+		max = defaultMaxServers
+	}
+	if idle <= 0 {
+		idle = defaultIdleTimeout
+	}
+	return &pool{
+		launcher:   launcher,
+		workspaces: workspaces,
+		max:        max,
+		idle:       idle,
+		entries:    make(map[serverKey]*poolEntry),
+	}
+}
+
+// acquire 返回房间的语言服务器，没有时启动一个，用完后调用 release
+func (p *pool) acquire(room *models.Room, server string) (*languageServer, error) {
+	key := serverKey{roomUUID: room.UUID, server: server}
+
+	p.mu.Lock()
+	entry := p.entries[key]
+	if entry != nil && entry.server != nil && entry.server.isClosed() {
+		delete(p.entries, key)
+		entry = nil
+	}
+	if entry != nil {
+		entry.refs++
+		p.mu.Unlock()
+
+		<-entry.ready
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return entry.server, nil
+	}
+
+	if !p.reserveLocked() {
+		p.mu.Unlock()
+		return nil, ErrTooManyServers
+	}
+	entry = &poolEntry{ready: make(chan struct{}), refs: 1}
+	p.entries[key] = entry
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), launchTimeout)
+	conn, err := p.launcher.Launch(ctx, room.UUID, server)
+	cancel()
+
+	p.mu.Lock()
+	if err != nil {
+		logger.Error("启动语言服务器失败", zap.String("room_uuid", room.UUID), zap.String("server", server), zap.Error(err))
+		entry.err = ErrLaunchFailed
+		delete(p.entries, key)
+	} else {
+		entry.server = newLanguageServer(key, room, conn, p.workspaces)
+		logger.Info("语言服务器已启动", zap.String("room_uuid", room.UUID), zap.String("server", server))
+	}
+	close(entry.ready)
+	p.mu.Unlock()
+	return entry.server, entry.err
+}
+
+// release 连接断开后归还语言服务器
+func (p *pool) release(server *languageServer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry := p.entries[server.key]; entry != nil && entry.server == server {
+		entry.refs--
+		entry.lastUsed = time.Now()
+	}
+}
+
+// reserveLocked 为新的语言服务器腾出位置，必要时关闭最久没有使用的空闲进程
+func (p *pool) reserveLocked() bool {
+	if len(p.entries) < p.max {
+		return true
+	}
+	var oldestKey serverKey
+	var oldest *poolEntry
+	for key, entry := range p.entries {
+		if entry.server == nil || entry.refs > 0 {
+			continue
+		}
+		if oldest == nil || entry.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(p.entries, oldestKey)
+	go oldest.server.close()
+	return true
+}
+
+// reap 定期关闭空闲超时和已经退出的语言服务器，ctx 取消后关闭所有进程
+func (p *pool) reap(ctx context.Context) {
+	ticker := time.NewTicker(min(p.idle/2, 30*time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeAll()
+			return
+		case <-ticker.C:
+			p.reapIdle()
+		}
+	}
+}
+
+func (p *pool) reapIdle() {
+	p.mu.Lock()
+	var idle []*languageServer
+	for key, entry := range p.entries {
+		if entry.server == nil {
+			continue
+		}
+		if entry.server.isClosed() || (entry.refs == 0 && time.Since(entry.lastUsed) >= p.idle) {
+			delete(p.entries, key)
+			idle = append(idle, entry.server)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, server := range idle {
+		server.close()
+		logger.Debug("语言服务器已回收", zap.String("room_uuid", server.key.roomUUID), zap.String("server", server.key.server))
+	}
+}
+
+func (p *pool) closeAll() {
+	p.mu.Lock()
+	servers := make([]*languageServer, 0, len(p.entries))
+	for key, entry := range p.entries {
+		if entry.server != nil {
+			servers = append(servers, entry.server)
+		}
+		delete(p.entries, key)
+	}
+	p.mu.Unlock()
+
+	for _, server := range servers {
+		server.close()
+	}
+}
@@ -0,0 +1,497 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// workspaceRoot 语言服务器看到的工作区根目录，房间的文件路径相对于它
+	workspaceRoot = "/workspace"
+	// initializeTimeout 语言服务器初始化的超时时间，jdtls 冷启动比较慢
+	initializeTimeout = time.Minute
+	// syncInterval 有连接时每隔多久把协作文档同步给语言服务器，让诊断跟上其他人的修改
+	syncInterval = time.Second
+	// syncMinInterval 转发请求前同步文档的最小间隔，避免补全时每次按键都读取文档
+	syncMinInterval = 200 * time.Millisecond
+	syncTimeout     = 5 * time.Second
+)
+
+var errServerClosed = errors.New("语言服务器已退出")
+
+// serverKey 每个房间的每种语言服务器最多一个进程
+type serverKey struct {
+	roomUUID string
+	server   string
+}
+
+// pendingCall 已转发给语言服务器、等待响应的请求
+// client 为空表示网关自己发出的请求，响应发到 reply
+type pendingCall struct {
+	client *client
+	id     json.RawMessage
+	reply  chan *message
+}
+
+// openDocument 已经通知语言服务器打开的文件
+type openDocument struct {
+	version int
+	text    string
+}
+
+// languageServer 一个语言服务器进程，房间内同一语言的所有连接共享
+//
+// 网关代替客户端完成初始化和文档同步：第一个连接的 initialize 请求用来初始化语言服务器，
+// 之后的连接直接返回缓存的结果；文件内容以协作文档为准，客户端的 didOpen、didChange 等通知被丢弃。
+// 客户端的请求改写 id 后转发，响应再改回原来的 id；语言服务器的通知（诊断等）发给所有连接。
+type languageServer struct {
+	key        serverKey
+	room       *models.Room
+	conn       io.ReadWriteCloser
+	workspaces Workspaces
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	nextID      int64
+	pending     map[int64]*pendingCall
+	clients     map[*client]struct{}
+	initStarted bool
+	initResult  json.RawMessage
+	initErr     error
+	closed      bool
+
+	initDone  chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	syncMu   sync.Mutex
+	docs     map[string]*openDocument
+	lastSync time.Time
+}
+
+func newLanguageServer(key serverKey, room *models.Room, conn io.ReadWriteCloser, workspaces Workspaces) *languageServer {
+	s := &languageServer{
+		key:        key,
+		room:       room,
+		conn:       conn,
+		workspaces: workspaces,
+		pending:    make(map[int64]*pendingCall),
+		clients:    make(map[*client]struct{}),
+		initDone:   make(chan struct{}),
+		done:       make(chan struct{}),
+		docs:       make(map[string]*openDocument),
+	}
+	go s.readLoop()
+	return s
+}
+
+// close 结束语言服务器进程并断开所有连接，可以重复调用
+func (s *languageServer) close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		clients := make([]*client, 0, len(s.clients))
+		for c := range s.clients {
+			clients = append(clients, c)
+		}
+		s.mu.Unlock()
+
+		close(s.done)
+		if err := s.conn.Close(); err != nil {
+			logger.Debug("关闭语言服务器失败", zap.String("room_uuid", s.key.roomUUID), zap.Error(err))
+		}
+		for _, c := range clients {
+			c.close()
+		}
+	})
+}
+
+func (s *languageServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *languageServer) addClient(c *client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.clients[c] = struct{}{}
+	return true
+}
+
+// removeClient 移除连接，丢弃它还没有收到响应的请求
+func (s *languageServer) removeClient(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, c)
+	for id, call := range s.pending {
+		if call.client == c {
+			delete(s.pending, id)
+		}
+	}
+}
+
+// send 写入一条消息，语言服务器的标准输入不能并发写
+func (s *languageServer) send(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return writeMessage(s.conn, data)
+}
+
+// call 网关自己发出请求并等待响应
+func (s *languageServer) call(ctx context.Context, method string, params json.RawMessage) (*message, error) {
+	reply := make(chan *message, 1)
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = &pendingCall{reply: reply}
+	s.mu.Unlock()
+
+	err := s.send(&message{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return nil, errors.New(msg.Error.Message)
+		}
+		return msg, nil
+	case <-s.done:
+		return nil, errServerClosed
+	case <-ctx.Done():
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// initialize 用第一个连接的 initialize 参数初始化语言服务器，返回语言服务器的能力
+func (s *languageServer) initialize(params json.RawMessage) (json.RawMessage, error) {
+	s.mu.Lock()
+	first := !s.initStarted
+	s.initStarted = true
+	s.mu.Unlock()
+
+	if first {
+		s.runInitialize(params)
+	}
+	select {
+	case <-s.initDone:
+	case <-s.done:
+		return nil, errServerClosed
+	}
+	return s.initResult, s.initErr
+}
+
+func (s *languageServer) runInitialize(params json.RawMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), initializeTimeout)
+	defer cancel()
+
+	msg, err := s.call(ctx, "initialize", workspaceParams(params))
+	if err == nil {
+		err = s.send(notification("initialized", struct{}{}))
+	}
+	if err != nil {
+		logger.Error("初始化语言服务器失败",
+			zap.String("room_uuid", s.key.roomUUID),
+			zap.String("server", s.key.server),
+			zap.Error(err))
+		s.initErr = err
+		close(s.initDone)
+		s.close()
+		return
+	}
+	s.initResult = msg.Result
+	close(s.initDone)
+
+	s.syncDocuments(true)
+	go s.syncLoop()
+}
+
+func (s *languageServer) initialized() bool {
+	select {
+	case <-s.initDone:
+		return s.initErr == nil
+	default:
+		return false
+	}
+}
+
+// handleClient 处理连接发来的一条消息
+func (s *languageServer) handleClient(c *client, data []byte) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+
+	switch {
+	case msg.Method == "":
+		// 语言服务器的请求由网关直接回复，客户端的响应不再转发
+	case msg.Method == "initialize":
+		result, err := s.initialize(msg.Params)
+		if err != nil {
+			c.replyError(msg.ID, codeRequestFailed, err.Error())
+			return
+		}
+		c.reply(msg.ID, result)
+	case msg.Method == "initialized", strings.HasPrefix(msg.Method, "textDocument/did"):
+		// 文档同步由网关根据协作文档完成
+	case msg.Method == "shutdown":
+		// 语言服务器由其他连接共享，空闲后由进程池关闭
+		c.reply(msg.ID, json.RawMessage("null"))
+	case msg.Method == "exit":
+		c.close()
+	case !s.initialized():
+		if msg.isRequest() {
+			c.replyError(msg.ID, codeServerNotInitialized, "语言服务器尚未初始化")
+		}
+	case msg.Method == "$/cancelRequest":
+		s.forwardCancel(c, &msg)
+	case msg.isRequest():
+		s.syncDocuments(false)
+		s.forwardRequest(c, &msg)
+	default:
+		s.sendOrLog(&msg)
+	}
+}
+
+// forwardRequest 改写 id 后转发，不同连接的 id 可能相同
+func (s *languageServer) forwardRequest(c *client, msg *message) {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = &pendingCall{client: c, id: msg.ID}
+	s.mu.Unlock()
+
+	msg.ID = json.RawMessage(strconv.FormatInt(id, 10))
+	s.sendOrLog(msg)
+}
+
+// forwardCancel 把取消请求中客户端的 id 改为转发时的 id
+func (s *languageServer) forwardCancel(c *client, msg *message) {
+	var params struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+	s.mu.Lock()
+	var found int64
+	for id, call := range s.pending {
+		if call.client == c && string(call.id) == string(params.ID) {
+			found = id
+			break
+		}
+	}
+	s.mu.Unlock()
+	if found != 0 {
+		s.sendOrLog(notification("$/cancelRequest", map[string]int64{"id": found}))
+	}
+}
+
+func (s *languageServer) sendOrLog(msg *message) {
+	if err := s.send(msg); err != nil {
+		logger.Debug("写入语言服务器失败", zap.String("room_uuid", s.key.roomUUID), zap.Error(err))
+	}
+}
+
+// readLoop 读取语言服务器的输出，进程退出后关闭
+func (s *languageServer) readLoop() {
+	defer s.close()
+
+	r := bufio.NewReader(s.conn)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			if !s.isClosed() {
+				logger.Warn("语言服务器已退出",
+					zap.String("room_uuid", s.key.roomUUID),
+					zap.String("server", s.key.server),
+					zap.Error(err))
+			}
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		switch {
+		case msg.isResponse():
+			s.handleResponse(&msg)
+		case msg.isRequest():
+			s.handleServerRequest(&msg)
+		default:
+			s.broadcast(data)
+		}
+	}
+}
+
+// handleResponse 把响应交给发出请求的一方
+func (s *languageServer) handleResponse(msg *message) {
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	call := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+	if call == nil {
+		return
+	}
+
+	if msg.Result == nil && msg.Error == nil {
+		msg.Result = json.RawMessage("null")
+	}
+	if call.reply != nil {
+		call.reply <- msg
+		return
+	}
+	msg.ID = call.id
+	if data, err := json.Marshal(msg); err == nil {
+		call.client.enqueue(data)
+	}
+}
+
+// handleServerRequest 语言服务器发给客户端的请求由网关回复：
+// 配置项都使用默认值；网关无法代替客户端修改协作文档，拒绝 applyEdit。
+// 在读取输出的 goroutine 之外回复，语言服务器阻塞在输出上时不会互相等待
+func (s *languageServer) handleServerRequest(msg *message) {
+	result := json.RawMessage("null")
+	switch msg.Method {
+	case "workspace/configuration":
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		result, _ = json.Marshal(make([]any, len(params.Items)))
+	case "workspace/applyEdit":
+		result = json.RawMessage(`{"applied":false}`)
+	}
+	go s.sendOrLog(&message{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *languageServer) broadcast(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		c.enqueue(data)
+	}
+}
+
+// syncLoop 有连接时定期同步协作文档
+func (s *languageServer) syncLoop() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			idle := len(s.clients) == 0
+			s.mu.Unlock()
+			if !idle {
+				s.syncDocuments(false)
+			}
+		}
+	}
+}
+
+// syncDocuments 把协作文档中这种语言的文件同步给语言服务器：
+// 新文件 didOpen，修改过的 didChange（整个文件），删除或重命名的 didClose
+func (s *languageServer) syncDocuments(force bool) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if !force && time.Since(s.lastSync) < syncMinInterval {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	workspace, err := s.workspaces.Snapshot(ctx, s.room)
+	if err != nil {
+		logger.Warn("读取工作区失败", zap.String("room_uuid", s.key.roomUUID), zap.Error(err))
+		return
+	}
+	s.lastSync = time.Now()
+
+	seen := make(map[string]bool, len(workspace.Files))
+	for _, file := range workspace.Files {
+		if languageServers[file.Language] != s.key.server {
+			continue
+		}
+		uri := documentURI(file.Path)
+		seen[uri] = true
+
+		doc := s.docs[uri]
+		switch {
+		case doc == nil:
+			s.docs[uri] = &openDocument{version: 1, text: file.Content}
+			s.sendOrLog(notification("textDocument/didOpen", map[string]any{
+				"textDocument": map[string]any{
+					"uri":        uri,
+					"languageId": file.Language,
+					"version":    1,
+					"text":       file.Content,
+				},
+			}))
+		case doc.text != file.Content:
+			doc.version++
+			doc.text = file.Content
+			s.sendOrLog(notification("textDocument/didChange", map[string]any{
+				"textDocument":   map[string]any{"uri": uri, "version": doc.version},
+				"contentChanges": []map[string]string{{"text": file.Content}},
+			}))
+		}
+	}
+	for uri := range s.docs {
+		if !seen[uri] {
+			delete(s.docs, uri)
+			s.sendOrLog(notification("textDocument/didClose", map[string]any{
+				"textDocument": map[string]string{"uri": uri},
+			}))
+		}
+	}
+}
+
+// documentURI 工作区文件对应的 URI，客户端打开模型时必须使用相同的 URI
+func documentURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: workspaceRoot + "/" + path}).String()
+}
+
+// workspaceParams 把 initialize 参数中的工作区改为沙箱中的目录
+func workspaceParams(params json.RawMessage) json.RawMessage {
+	fields := make(map[string]any)
+	_ = json.Unmarshal(params, &fields)
+	root := documentURI("")
+	root = strings.TrimSuffix(root, "/")
+	fields["processId"] = nil
+	fields["rootUri"] = root
+	fields["rootPath"] = workspaceRoot
+	fields["workspaceFolders"] = []map[string]string{{"uri": root, "name": "workspace"}}
+	data, _ := json.Marshal(fields)
+	return data
+}
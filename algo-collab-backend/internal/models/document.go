@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Document 房间协作文档的压缩快照（Yjs v1 更新格式）
+// 每个房间一份，快照之后的修改追加在 DocumentUpdate 中，定期合并回快照
+type Document struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	RoomID   uint   `gorm:"not null;uniqueIndex" json:"room_id"`
+	Snapshot []byte `gorm:"type:bytea" json:"-"`
+	// Size 快照字节数，便于统计而不必读取整个快照
+	Size      int       `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
+
+// DocumentUpdate 快照之后追加的一条增量更新，只追加不修改
+type DocumentUpdate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	Data      []byte    `gorm:"type:bytea;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (DocumentUpdate) TableName() string {
+	return "document_updates"
+}
+
+// 文档版本的来源
+const (
+	DocumentVersionAuto       = "auto"       // 定期自动保存
+	DocumentVersionCheckpoint = "checkpoint" // 成员手动创建的命名检查点
+	DocumentVersionRestore    = "restore"    // 恢复历史版本前自动保存的当前内容
+)
+
+// DocumentVersion 房间代码的历史版本，保存纯文本便于查看、对比和恢复
+// 自动保存的版本只保留最近的若干个，检查点和恢复前的版本不自动清理。
+// 版本只包含房间主文件（TextName 固定为 MainTextName），工作区的其他文件不在版本中，恢复时也不会改动
+type DocumentVersion struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	RoomID   uint   `gorm:"not null;index:idx_document_versions_room_kind,priority:1" json:"room_id"`
+	Kind     string `gorm:"type:varchar(20);not null;index:idx_document_versions_room_kind,priority:2" json:"kind"`
+	Name     string `gorm:"type:varchar(100)" json:"name"`
+	TextName string `gorm:"type:varchar(100);not null;default:'monaco'" json:"text_name"`
+	Content  string `gorm:"type:text" json:"content,omitempty"`
+	Size     int    `json:"size"`
+	// Authorship 内容中每段文本由哪个 Yjs 客户端写入，通过 DocumentAuthor 对应到用户
+	Authorship AuthorRuns `gorm:"type:jsonb" json:"-"`
+	CreatedBy  *uint      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// 关联
+	Creator *User `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
+}
+
+func (DocumentVersion) TableName() string {
+	return "document_versions"
+}
+
+// DocumentAuthor 房间文档中 Yjs 客户端ID对应的登录用户
+// 客户端ID由浏览器随机生成，第一次提交该客户端内容的连接的用户即为作者，之后不再修改
+type DocumentAuthor struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoomID    uint      `gorm:"not null;uniqueIndex:idx_document_authors_room_client,priority:1" json:"room_id"`
+	ClientID  uint64    `gorm:"not null;uniqueIndex:idx_document_authors_room_client,priority:2" json:"client_id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (DocumentAuthor) TableName() string {
+	return "document_authors"
+}
+
+// AuthorRun 由同一个 Yjs 客户端写入的连续 Length 个字符（按 rune 计）
+type AuthorRun struct {
+	Client uint64 `json:"c"`
+	Length int    `json:"n"`
+}
+
+// AuthorRuns 依次覆盖整段文本的作者信息（postgres 中为 jsonb）
+type AuthorRuns []AuthorRun
+
+// Value 实现 driver.Valuer
+func (r AuthorRuns) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *AuthorRuns) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将 %T 解析为 AuthorRuns", value)
+	}
+	return json.Unmarshal(data, r)
+}
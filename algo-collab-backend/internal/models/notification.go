@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationMention = "mention" // 在房间聊天中被 @
+)
+
+// Notification 站内通知
+// ActorID 是触发通知的用户；Data 保存展示所需的快照（例如房间 UUID、消息摘要），避免列表查询时关联多张表
+type Notification struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notifications_user_read,priority:1" json:"user_id"`
+	ActorID   *uint      `json:"actor_id"`
+	Type      string     `gorm:"type:varchar(30);not null" json:"type"`
+	RoomID    *uint      `gorm:"index" json:"room_id"`
+	MessageID *uint      `json:"message_id"`
+	Data      JSONMap    `gorm:"type:jsonb" json:"data"`
+	ReadAt    *time.Time `gorm:"index:idx_notifications_user_read,priority:2" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// 关联
+	Actor *User `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 组织成员角色
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization 组织（团队）
+// DefaultTemplateID 是组织的默认房间模板；EnforceTemplate 为 true 时组织内创建房间必须使用该模板
+type Organization struct {
+	BaseModel
+	UUID              string `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	Name              string `gorm:"type:varchar(100);not null" json:"name"`
+	OwnerID           uint   `gorm:"index;not null" json:"owner_id"`
+	DefaultTemplateID *uint  `json:"default_template_id"`
+	EnforceTemplate   bool   `gorm:"default:false" json:"enforce_template"`
+}
+
+// OrganizationMember 组织成员
+type OrganizationMember struct {
+	BaseModel
+	OrganizationID uint   `gorm:"not null;uniqueIndex:idx_org_members_org_user" json:"organization_id"`
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_org_members_org_user" json:"user_id"`
+	Role           string `gorm:"size:20;default:'member'" json:"role"` // owner/admin/member
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// BeforeCreate GORM 钩子：创建前生成 UUID
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.UUID == "" {
+		o.UUID = uuid.New().String()
+	}
+	return nil
+}
+
+// CanManage 是否可以管理组织设置（模板、成员）
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
@@ -1,6 +1,38 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 房间成员角色（权限从高到低）
+const (
+	RoomRoleOwner  = "owner"
+	RoomRoleAdmin  = "admin"
+	RoomRoleMember = "member"
+)
+
+// roomRoleLevel 角色等级，数值越大权限越高
+var roomRoleLevel = map[string]int{
+	RoomRoleMember: 1,
+	RoomRoleAdmin:  2,
+	RoomRoleOwner:  3,
+}
+
+// 房间难度
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// 房间状态
+const (
+	RoomStatusActive   = "active"
+	RoomStatusArchived = "archived" // 只读，不出现在房间列表中
+)
 
 // Room 房间模型
 type Room struct {
@@ -13,19 +45,38 @@ type Room struct {
 	MaxMembers  int    `gorm:"default:10" json:"max_members"`
 	IsPublic    bool   `gorm:"default:true" json:"is_public"`
 	Password    string `gorm:"type:varchar(255)" json:"-"`
-	Status      string `gorm:"type:varchar(20);default:'active'" json:"status"`
+	Status      string `gorm:"type:varchar(20);default:'active';index" json:"status"`
+
+	// 题目与初始代码（可来自模板）
+	OrganizationID *uint  `gorm:"index" json:"organization_id"`
+	TemplateID     *uint  `json:"template_id"`
+	ProblemTitle   string `gorm:"type:varchar(200)" json:"problem_title"`
+	ProblemContent string `gorm:"type:text" json:"problem_content"`
+	StarterCode    string `gorm:"type:text" json:"starter_code"`
+
+	// 分类信息：难度、主题（topic 类标签名）和标签
+	Difficulty string `gorm:"type:varchar(20);index" json:"difficulty"`
+	Topic      string `gorm:"type:varchar(50);index" json:"topic"`
+	Tags       []Tag  `gorm:"many2many:room_tags;" json:"tags"`
+
+	// 活跃度与归档
+	LastActiveAt time.Time  `gorm:"index" json:"last_active_at"`
+	ArchivedAt   *time.Time `gorm:"index" json:"archived_at"`
 
 	// 关联
 	Creator User `gorm:"foreignKey:CreatorID" json:"creator"`
 }
 
+// RoomMember 房间成员
+// (room_id, user_id) 唯一：退出房间是软删除，重新加入时恢复原记录而不是插入新行
 type RoomMember struct {
 	BaseModel
-	RoomID       uint      `json:"room_id" gorm:"not null"`
-	UserID       uint      `json:"user_id" gorm:" not null"`
-	Role         string    `json:"role" gorm:"size:20;default:'member'"` // owner/admin/member
-	JoinedAt     time.Time `json:"joined_at" gorm:"autoCreateTime"`
-	LastActiveAt time.Time `json:"last_active_at" gorm:"autoCreateTime"`
+	RoomID       uint       `json:"room_id" gorm:"not null;uniqueIndex:idx_room_members_room_user"`
+	UserID       uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_room_members_room_user"`
+	Role         string     `json:"role" gorm:"size:20;default:'member'"` // owner/admin/member
+	JoinedAt     time.Time  `json:"joined_at" gorm:"autoCreateTime"`
+	LastActiveAt time.Time  `json:"last_active_at" gorm:"autoCreateTime"`
+	MutedUntil   *time.Time `json:"muted_until"` // 禁言截止时间，为空或已过期表示可以发言
 
 	// 关联
 	Room  Room `json:"room" gorm:"foreignKey:RoomID"`
@@ -35,3 +86,39 @@ type RoomMember struct {
 func (Room) TableName() string {
 	return "rooms"
 }
+
+// BeforeCreate GORM 钩子：创建前生成 UUID
+func (r *Room) BeforeCreate(tx *gorm.DB) error {
+	if r.UUID == "" {
+		r.UUID = uuid.New().String()
+	}
+	if r.LastActiveAt.IsZero() {
+		r.LastActiveAt = time.Now()
+	}
+	return nil
+}
+
+// IsArchived 房间是否已归档
+func (r *Room) IsArchived() bool {
+	return r.Status == RoomStatusArchived
+}
+
+// IsFull 按当前有效成员数判断房间是否已满
+func (r *Room) IsFull(memberCount int64) bool {
+	return r.MaxMembers > 0 && memberCount >= int64(r.MaxMembers)
+}
+
+// IsMuted 成员在 now 时是否处于禁言中
+func (m *RoomMember) IsMuted(now time.Time) bool {
+	return m.MutedUntil != nil && now.Before(*m.MutedUntil)
+}
+
+// HasRole 成员角色是否不低于 role（owner > admin > member）
+func (m *RoomMember) HasRole(role string) bool {
+	return RoomRoleAtLeast(m.Role, role)
+}
+
+// RoomRoleAtLeast 角色 role 是否不低于 min
+func RoomRoleAtLeast(role, min string) bool {
+	return roomRoleLevel[role] >= roomRoleLevel[min]
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// RoomCommentThread 挂在房间文件中一段代码上的评论
+// 锚点是 Yjs 相对位置（Y.encodeRelativePosition 的结果），其他成员编辑后仍然指向同一段代码；
+// Quote 是创建时选中的代码，锚定的代码被删除后仍然能看到评论的上下文
+type RoomCommentThread struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	RoomID      uint       `gorm:"not null;index" json:"room_id"`
+	FileID      uint       `gorm:"not null;index" json:"file_id"`
+	AnchorStart []byte     `gorm:"type:bytea;not null" json:"anchor_start"`
+	AnchorEnd   []byte     `gorm:"type:bytea;not null" json:"anchor_end"`
+	Quote       string     `gorm:"type:text;not null" json:"quote"`
+	Resolved    bool       `gorm:"not null;default:false" json:"resolved"`
+	ResolvedBy  *uint      `json:"resolved_by"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	CreatedBy   uint       `gorm:"not null" json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Range 锚点在文件当前内容中的位置，查询时由协作文档解析，不存储
+	Range *CommentRange `gorm:"-" json:"range"`
+
+	// 关联
+	Comments []*RoomComment `gorm:"foreignKey:ThreadID" json:"comments"`
+	// Whiteboards 关联到这条讨论的白板
+	Whiteboards []*RoomWhiteboard `gorm:"foreignKey:ThreadID" json:"whiteboards"`
+}
+
+func (RoomCommentThread) TableName() string {
+	return "room_comment_threads"
+}
+
+// CommentRange 评论对应的代码范围 [Start, End)，以 UTF-16 码元计算，和 Monaco 的 getPositionAt 一致
+type CommentRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// RoomComment 评论中的一条回复，第一条是发起评论时的内容
+type RoomComment struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	ThreadID  uint      `gorm:"not null;index" json:"thread_id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (RoomComment) TableName() string {
+	return "room_comments"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// 房间事件类型
+const (
+	RoomEventJoin           = "join"
+	RoomEventLeave          = "leave"
+	RoomEventRoleChange     = "role_change"
+	RoomEventKick           = "kick"
+	RoomEventSettingsChange = "settings_change"
+	RoomEventProblemSwitch  = "problem_switch"
+	RoomEventCodeRun        = "code_run"
+	RoomEventSnapshot       = "snapshot"
+	RoomEventMute           = "mute"
+	RoomEventUnmute         = "unmute"
+	RoomEventReportHandled  = "report_handled"
+	RoomEventRestore        = "restore"
+	RoomEventFileCreate     = "file_create"
+	RoomEventFileMove       = "file_move" // 重命名或移动
+	RoomEventFileDelete     = "file_delete"
+	RoomEventCommentCreate  = "comment_create"
+	RoomEventCommentReply   = "comment_reply"
+	RoomEventCommentResolve = "comment_resolve"
+	RoomEventCommentReopen  = "comment_reopen"
+	RoomEventLockCreate     = "lock_create"
+	RoomEventLockDelete     = "lock_delete"
+	RoomEventBoardCreate    = "whiteboard_create"
+	RoomEventBoardUpdate    = "whiteboard_update" // 修改标题或关联的讨论
+	RoomEventBoardDelete    = "whiteboard_delete"
+)
+
+// RoomEvent 房间审计日志，只追加不修改
+// ActorID 为空表示系统行为（例如自动归档）；TargetUserID 是被操作的成员（踢人、改角色）
+type RoomEvent struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	RoomID       uint      `gorm:"not null;index:idx_room_events_room_created" json:"room_id"`
+	ActorID      *uint     `gorm:"index" json:"actor_id"`
+	TargetUserID *uint     `json:"target_user_id"`
+	Type         string    `gorm:"type:varchar(30);not null;index" json:"type"`
+	Data         JSONMap   `gorm:"type:jsonb" json:"data"`
+	CreatedAt    time.Time `gorm:"index:idx_room_events_room_created" json:"created_at"`
+
+	// 关联
+	Actor *User `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+}
+
+func (RoomEvent) TableName() string {
+	return "room_events"
+}
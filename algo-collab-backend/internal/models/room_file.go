@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MainTextName 房间主文件使用的 Y.Text，和前端 ydoc.getText('monaco') 一致
+const MainTextName = "monaco"
+
+// RoomFile 房间工作区中的一个文件
+// 文件内容保存在房间协作文档中名为 TextName 的 Y.Text 里，这里只保存文件树信息；
+// 目录不单独保存，由路径推导
+type RoomFile struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	RoomID uint   `gorm:"not null;uniqueIndex:idx_room_files_room_path,priority:1;uniqueIndex:idx_room_files_room_text,priority:1" json:"room_id"`
+	Path   string `gorm:"type:varchar(255);not null;uniqueIndex:idx_room_files_room_path,priority:2" json:"path"` // 例如 src/utils.py
+	// Language 由扩展名推断，用于编辑器高亮和执行
+	Language string `gorm:"type:varchar(20);not null" json:"language"`
+	// TextName 文件内容对应的 Y.Text 名称，创建后不再修改，重命名和移动不影响协作中的内容
+	TextName string `gorm:"type:varchar(64);not null;uniqueIndex:idx_room_files_room_text,priority:2" json:"text_name"`
+	// IsMain 主文件即房间原有的代码（Y.Text "monaco"），不能删除，执行时作为入口
+	IsMain    bool      `gorm:"not null;default:false" json:"is_main"`
+	CreatedBy *uint     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (RoomFile) TableName() string {
+	return "room_files"
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RoomLock 房间文件中的只读区域，例如面试中的题目描述和测试代码
+// 管理员及以上的成员可以编辑锁定区域，其他成员修改锁定区域的编辑会被服务端拒绝。
+// 区域两端是 Yjs 相对位置：AnchorStart 指向区域的第一个字符（assoc 0），
+// AnchorEnd 指向最后一个字符（assoc -1），在区域边界外输入不受影响
+type RoomLock struct {
+	ID     uint `gorm:"primarykey" json:"id"`
+	RoomID uint `gorm:"not null;index" json:"room_id"`
+	FileID uint `gorm:"not null" json:"file_id"`
+	// TextName 文件对应的 Y.Text 名称，协作服务按它检查编辑
+	TextName    string    `gorm:"type:varchar(64);not null" json:"text_name"`
+	AnchorStart []byte    `gorm:"type:bytea;not null" json:"anchor_start"`
+	AnchorEnd   []byte    `gorm:"type:bytea;not null" json:"anchor_end"`
+	Label       string    `gorm:"type:varchar(100);not null;default:''" json:"label"` // 例如 "题目描述"
+	CreatedBy   uint      `gorm:"not null" json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (RoomLock) TableName() string {
+	return "room_locks"
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// 聊天消息类型
+const (
+	RoomMessageText   = "text"   // 成员发送的 Markdown 文本
+	RoomMessageCode   = "code"   // 引用房间文档中一段代码，Content 是对代码的说明
+	RoomMessageSystem = "system" // 系统生成的消息，例如成员加入/离开
+)
+
+// RoomMessage 房间聊天消息
+// UserID 为空表示系统消息；Content 在写入前已经过 Markdown 清理
+// ReplyToID 不为空表示是某条消息的回复，回复只挂在根消息下，不会嵌套
+// 删除的消息保留记录（内容清空），让回复和引用仍然有上下文
+type RoomMessage struct {
+	ID        uint         `gorm:"primarykey;index:idx_room_messages_room_id_id,priority:2" json:"id"`
+	RoomID    uint         `gorm:"not null;index:idx_room_messages_room_id_id,priority:1" json:"room_id"`
+	UserID    *uint        `gorm:"index" json:"user_id"`
+	Type      string       `gorm:"type:varchar(20);not null;default:'text'" json:"type"`
+	Content   string       `gorm:"type:text;not null" json:"content"`
+	Snippet   *CodeSnippet `gorm:"type:jsonb;serializer:json" json:"snippet,omitempty"`
+	ReplyToID *uint        `gorm:"index" json:"reply_to_id"`
+	CreatedAt time.Time    `json:"created_at"`
+	EditedAt  *time.Time   `json:"edited_at"`
+	DeletedAt *time.Time   `json:"deleted_at"`
+	DeletedBy *uint        `json:"deleted_by"`
+
+	// 查询时按需聚合，不存储在消息表中
+	ReplyCount int               `gorm:"-" json:"reply_count"`
+	Reactions  []ReactionSummary `gorm:"-" json:"reactions"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (RoomMessage) TableName() string {
+	return "room_messages"
+}
+
+// IsDeleted 消息是否已被删除
+func (m *RoomMessage) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// CodeSnippet 代码片段：发送时从房间文档中截取的行，文档之后的修改不影响已发送的片段
+type CodeSnippet struct {
+	Language  string `json:"language"`
+	StartLine int    `json:"start_line"` // 从 1 开始，包含
+	EndLine   int    `json:"end_line"`   // 包含
+	Code      string `json:"code"`
+}
+
+// RoomMessageReaction 某个用户对消息的一个表情回应
+type RoomMessageReaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	MessageID uint      `gorm:"not null;uniqueIndex:idx_room_message_reactions_unique" json:"message_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_room_message_reactions_unique" json:"user_id"`
+	Emoji     string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_room_message_reactions_unique" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (RoomMessageReaction) TableName() string {
+	return "room_message_reactions"
+}
+
+// ReactionSummary 消息上某个表情的聚合结果
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	UserIDs []uint `json:"user_ids"` // 按回应时间排序
+}
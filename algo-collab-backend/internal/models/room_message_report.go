@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// 举报处理状态
+const (
+	ReportStatusPending   = "pending"   // 待处理
+	ReportStatusResolved  = "resolved"  // 已处理（例如删除消息、禁言）
+	ReportStatusDismissed = "dismissed" // 驳回
+)
+
+// RoomMessageReport 成员对聊天消息的举报，进入房间管理员的审核队列
+// 同一成员对同一条消息只能举报一次
+type RoomMessageReport struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	RoomID     uint       `gorm:"not null;index:idx_room_message_reports_room_status,priority:1" json:"room_id"`
+	MessageID  uint       `gorm:"not null;uniqueIndex:idx_room_message_reports_unique" json:"message_id"`
+	ReporterID uint       `gorm:"not null;uniqueIndex:idx_room_message_reports_unique" json:"reporter_id"`
+	Reason     string     `gorm:"type:varchar(500)" json:"reason"`
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_room_message_reports_room_status,priority:2" json:"status"`
+	HandledBy  *uint      `json:"handled_by"`
+	HandledAt  *time.Time `json:"handled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// 关联
+	Message  *RoomMessage `gorm:"foreignKey:MessageID" json:"message,omitempty"`
+	Reporter *User        `gorm:"foreignKey:ReporterID" json:"reporter,omitempty"`
+}
+
+func (RoomMessageReport) TableName() string {
+	return "room_message_reports"
+}
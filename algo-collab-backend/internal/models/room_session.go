@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 会话事件类型
+const (
+	SessionEventKeyframe  = "keyframe"  // 完整文档状态，回放时从最近的关键帧开始跳转
+	SessionEventUpdate    = "update"    // 文档增量
+	SessionEventAwareness = "awareness" // 光标、选区等 awareness 更新
+	SessionEventChat      = "chat"      // 聊天消息（聊天协议的 JSON 消息体）
+	SessionEventTerminal  = "terminal"  // 共享终端的输出和状态（终端协议的 JSON 消息体）
+)
+
+// RoomSession 房间的一次协作过程，用于回放
+// 房间有活动时自动开始，超过一段时间没有活动后，下一次活动开始新的会话
+type RoomSession struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	RoomID      uint      `gorm:"not null;index:idx_room_sessions_room_last,priority:1" json:"room_id"`
+	StartedAt   time.Time `gorm:"not null" json:"started_at"`
+	LastEventAt time.Time `gorm:"not null;index:idx_room_sessions_room_last,priority:2" json:"last_event_at"`
+	DurationMS  int64     `gorm:"not null;default:0" json:"duration_ms"`
+	EventCount  int       `gorm:"not null;default:0" json:"event_count"`
+	// ShareToken 分享链接的令牌，持有链接的人不需要是房间成员也可以观看回放
+	ShareToken *string    `gorm:"type:varchar(64);uniqueIndex" json:"share_token,omitempty"`
+	SharedBy   *uint      `json:"shared_by,omitempty"`
+	SharedAt   *time.Time `json:"shared_at,omitempty"`
+}
+
+func (RoomSession) TableName() string {
+	return "room_sessions"
+}
+
+// RoomSessionEvent 会话中的一条事件，OffsetMS 为相对会话开始的毫秒数
+// 按 (offset_ms, id) 排序回放，多个节点写入的事件以各自的时间为准
+type RoomSessionEvent struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	SessionID uint   `gorm:"not null;index:idx_room_session_events_session_offset,priority:1" json:"session_id"`
+	RoomID    uint   `gorm:"not null;index" json:"room_id"`
+	Kind      string `gorm:"type:varchar(20);not null" json:"kind"`
+	OffsetMS  int64  `gorm:"not null;index:idx_room_session_events_session_offset,priority:2" json:"offset_ms"`
+	UserID    *uint  `json:"user_id"`
+	Data      []byte `gorm:"type:bytea;not null" json:"-"`
+}
+
+func (RoomSessionEvent) TableName() string {
+	return "room_session_events"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 模板可见范围
+const (
+	TemplateVisibilityPrivate = "private" // 仅创建者
+	TemplateVisibilityTeam    = "team"    // 所属组织成员
+	TemplateVisibilityPublic  = "public"  // 所有用户
+)
+
+// RoomTemplate 房间模板：保存一套房间配置，用于一键创建房间
+type RoomTemplate struct {
+	BaseModel
+	UUID           string `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	Name           string `gorm:"type:varchar(100);not null" json:"name"`
+	Description    string `gorm:"type:text" json:"description"`
+	OwnerID        uint   `gorm:"index;not null" json:"owner_id"`
+	OrganizationID *uint  `gorm:"index" json:"organization_id"` // team 可见时必填
+	Visibility     string `gorm:"type:varchar(20);default:'private';index" json:"visibility"`
+
+	// 房间配置
+	Language       string `gorm:"type:varchar(20);not null" json:"language"`
+	MaxMembers     int    `gorm:"default:10" json:"max_members"`
+	IsPublic       bool   `gorm:"default:true" json:"is_public"`
+	ProblemTitle   string `gorm:"type:varchar(200)" json:"problem_title"`
+	ProblemContent string `gorm:"type:text" json:"problem_content"`
+	StarterCode    string `gorm:"type:text" json:"starter_code"`
+}
+
+func (RoomTemplate) TableName() string {
+	return "room_templates"
+}
+
+// BeforeCreate GORM 钩子：创建前生成 UUID
+func (t *RoomTemplate) BeforeCreate(tx *gorm.DB) error {
+	if t.UUID == "" {
+		t.UUID = uuid.New().String()
+	}
+	return nil
+}
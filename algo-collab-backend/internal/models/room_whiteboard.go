@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// WhiteboardMapName 白板文档中保存图形的 Y.Map 名称（键为图形 ID，值为图形对象）
+const WhiteboardMapName = "shapes"
+
+// RoomWhiteboard 房间中的一块协作白板
+// 每块白板是一份独立的 Yjs 文档（WhiteboardDocument），通过自己的协作连接实时同步，
+// 不计入代码文档的大小，删除白板时一起删除。这里只保存白板的标题和关联信息
+type RoomWhiteboard struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	RoomID uint   `gorm:"not null;index" json:"room_id"`
+	Title  string `gorm:"type:varchar(100);not null" json:"title"`
+	// ThreadID 关联的评论讨论，为空表示没有关联；同一个讨论可以关联多块白板
+	ThreadID  *uint     `gorm:"index" json:"thread_id"`
+	CreatedBy *uint     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (RoomWhiteboard) TableName() string {
+	return "room_whiteboards"
+}
+
+// WhiteboardDocument 白板文档的压缩快照，和 Document 一样，快照之后的修改追加在 WhiteboardUpdate 中
+// RoomID 用于彻底删除房间时一起清理
+type WhiteboardDocument struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	RoomID       uint      `gorm:"not null;index" json:"room_id"`
+	WhiteboardID uint      `gorm:"not null;uniqueIndex" json:"whiteboard_id"`
+	Snapshot     []byte    `gorm:"type:bytea" json:"-"`
+	Size         int       `json:"size"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (WhiteboardDocument) TableName() string {
+	return "whiteboard_documents"
+}
+
+// WhiteboardUpdate 白板快照之后追加的一条增量更新，只追加不修改
+type WhiteboardUpdate struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	RoomID       uint      `gorm:"not null;index" json:"room_id"`
+	WhiteboardID uint      `gorm:"not null;index" json:"whiteboard_id"`
+	Data         []byte    `gorm:"type:bytea;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (WhiteboardUpdate) TableName() string {
+	return "whiteboard_document_updates"
+}
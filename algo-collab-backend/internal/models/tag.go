@@ -0,0 +1,25 @@
+package models
+
+// 标签分类
+const (
+	TagCategoryTopic     = "topic"     // 题目主题：数组、图、动态规划...
+	TagCategoryTechnique = "technique" // 解题技巧：双指针、滑动窗口...
+	TagCategoryOther     = "other"
+)
+
+// Tag 标签（全站共享的标签体系，由管理员维护）
+type Tag struct {
+	BaseModel
+	Name     string `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	Category string `gorm:"type:varchar(20);default:'other';index" json:"category"`
+}
+
+// TagUsage 标签及其使用次数（用于自动补全）
+type TagUsage struct {
+	Tag
+	UsageCount int64 `json:"usage_count"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// activityInterval 同一成员在同一房间内两次刷新活跃时间的最小间隔
+const activityInterval = time.Minute
+
+// ActivityRecorder 刷新成员和房间的最后活跃时间，由 repository.RoomRepository 实现
+// 房间长时间没有活动会被自动归档，协作编辑和聊天都算活动
+type ActivityRecorder interface {
+	TouchActivity(ctx context.Context, roomID, userID uint) error
+}
+
+// activityThrottle 按成员限制刷新频率，避免每次按键都写库
+type activityThrottle struct {
+	mu       sync.Mutex
+	touched  map[uint]time.Time
+	interval time.Duration
+}
+
+func newActivityThrottle(interval time.Duration) *activityThrottle {
+	return &activityThrottle{touched: make(map[uint]time.Time), interval: interval}
+}
+
+// allow 距离该成员上次刷新已超过间隔时返回 true 并记下本次时间
+func (t *activityThrottle) allow(userID uint, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.touched[userID]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	t.touched[userID] = now
+	return true
+}
+
+// touchActivity 成员在房间内编辑或聊天，刷新活跃时间；写库在后台进行，不阻塞文档同步
+func (r *Room) touchActivity(userID uint) {
+	if r.activity == nil || !r.activityThrottle.allow(userID, time.Now()) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		if err := r.activity.TouchActivity(ctx, r.id, userID); err != nil {
+			logger.Warn("刷新房间活跃时间失败", zap.String("room_uuid", r.uuid), zap.Uint("user_id", userID), zap.Error(err))
+		}
+	}()
+}
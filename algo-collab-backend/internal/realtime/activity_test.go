@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingActivity 记录每个成员刷新活跃时间的次数
+type countingActivity struct {
+	mu      sync.Mutex
+	touches map[uint]int
+}
+
+func (a *countingActivity) TouchActivity(_ context.Context, _, userID uint) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.touches[userID]++
+	return nil
+}
+
+func (a *countingActivity) count(userID uint) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.touches[userID]
+}
+
+func TestHub_EditsRefreshActivity(t *testing.T) {
+	activity := &countingActivity{touches: make(map[uint]int)}
+	url := newTestServer(t, NewHub(Options{Activity: activity}))
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	doc := yjs.NewDoc(yjs.Options{})
+	for _, s := range []string{"a", "b", "c"} {
+		update := doc.GetText(codeTextName).Insert(0, s)
+		require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+
+	// 连续编辑在间隔内只刷新一次
+	waitFor(t, func() bool { return activity.count(1) == 1 })
+	bob := dial(t, url+"?user=2")
+	readMessage(t, bob)
+	assert.Equal(t, "cba", syncText(t, bob))
+	assert.Equal(t, 1, activity.count(1))
+	assert.Zero(t, activity.count(2))
+}
+
+func TestActivityThrottle(t *testing.T) {
+	throttle := newActivityThrottle(activityInterval)
+	now := time.Now()
+	assert.True(t, throttle.allow(1, now))
+	assert.False(t, throttle.allow(1, now.Add(activityInterval/2)))
+	assert.True(t, throttle.allow(2, now))
+	assert.True(t, throttle.allow(1, now.Add(activityInterval)))
+}
@@ -0,0 +1,85 @@
+package realtime
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+// CodeAuthorship 读取房间当前的代码，以及每段代码由哪个 Yjs 客户端写入
+// 客户端对应的用户见 DocumentAuthorRepository，服务端写入的内容（初始代码、恢复版本）没有作者
+func (h *Hub) CodeAuthorship(_ context.Context, roomModel *models.Room) (string, models.AuthorRuns, error) {
+	h.mu.Lock()
+	room := h.rooms[roomModel.UUID]
+	h.mu.Unlock()
+	if room == nil {
+		room = newRoom(roomModel, h)
+		room.broker = nil
+	}
+	return room.currentAuthorship()
+}
+
+func (r *Room) currentAuthorship() (string, models.AuthorRuns, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		return "", nil, err
+	}
+	text, runs := r.authorshipLocked()
+	return text, runs, nil
+}
+
+// authorshipLocked 当前代码及其作者信息，长度按 rune 计，和保存的纯文本一致
+func (r *Room) authorshipLocked() (string, models.AuthorRuns) {
+	var text []byte
+	runs := models.AuthorRuns{}
+	for _, run := range r.doc.GetText(codeTextName).Runs() {
+		text = append(text, run.Text...)
+		runs = append(runs, models.AuthorRun{Client: run.Client, Length: utf8.RuneCountInString(run.Text)})
+	}
+	return string(text), runs
+}
+
+// clientStatesLocked 更新中包含插入的客户端及其在合入前的时钟，配合 claimAuthorsLocked 使用
+func (r *Room) clientStatesLocked(update []byte) map[uint64]uint64 {
+	if r.authors == nil {
+		return nil
+	}
+	clients, err := yjs.UpdateClients(update)
+	if err != nil {
+		return nil
+	}
+	states := make(map[uint64]uint64, len(clients))
+	for _, id := range clients {
+		if !r.claimed[id] {
+			states[id] = r.doc.ClientState(id)
+		}
+	}
+	return states
+}
+
+// claimAuthorsLocked 把这条更新新增内容的客户端记为连接用户所写
+// 只认领时钟确实前进了的客户端：重连时客户端会带上它见过的其他人的内容，这些内容服务端已经有了
+func (r *Room) claimAuthorsLocked(client *Client, before map[uint64]uint64) {
+	for id, state := range before {
+		if id == r.doc.ClientID() || r.doc.ClientState(id) <= state {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		err := r.authors.Claim(ctx, r.id, id, client.user.ID)
+		cancel()
+		if err != nil {
+			logger.Error("记录文档作者失败",
+				zap.String("room_uuid", r.uuid),
+				zap.Uint64("client_id", id),
+				zap.Error(err))
+			continue
+		}
+		r.claimed[id] = true
+	}
+}
@@ -0,0 +1,82 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAuthorRepo 内存中的文档作者，先记录的为准
+type memoryAuthorRepo struct {
+	mu      sync.Mutex
+	authors map[uint64]uint
+}
+
+func (r *memoryAuthorRepo) Claim(_ context.Context, _ uint, clientID uint64, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.authors == nil {
+		r.authors = make(map[uint64]uint)
+	}
+	if _, ok := r.authors[clientID]; !ok {
+		r.authors[clientID] = userID
+	}
+	return nil
+}
+
+func (r *memoryAuthorRepo) ListByRoom(context.Context, uint) ([]*models.DocumentAuthor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var authors []*models.DocumentAuthor
+	for clientID, userID := range r.authors {
+		authors = append(authors, &models.DocumentAuthor{ClientID: clientID, UserID: userID})
+	}
+	return authors, nil
+}
+
+func (r *memoryAuthorRepo) snapshot() map[uint64]uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := make(map[uint64]uint, len(r.authors))
+	for clientID, userID := range r.authors {
+		copied[clientID] = userID
+	}
+	return copied
+}
+
+func TestHub_ClaimsAuthorsPerConnection(t *testing.T) {
+	authors := &memoryAuthorRepo{}
+	hub := NewHub(Options{Authors: authors})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url+"?user=1")
+	readMessage(t, alice)
+	bob := dial(t, url+"?user=2")
+	readMessage(t, bob)
+
+	aliceDoc := yjs.NewDoc(yjs.Options{ClientID: 11})
+	bobDoc := yjs.NewDoc(yjs.Options{ClientID: 22})
+
+	update := aliceDoc.GetText(codeTextName).Insert(0, "ab")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	require.NoError(t, bobDoc.ApplyUpdate(readMessage(t, bob).Payload))
+
+	// bob 在 alice 的内容之后输入，并把包含 alice 内容的完整状态发回（例如重连）
+	bobDoc.GetText(codeTextName).Insert(2, "c")
+	require.NoError(t, bob.WriteMessage(websocket.BinaryMessage, yjs.EncodeSyncStep2(bobDoc.EncodeStateAsUpdate(nil))))
+	readMessage(t, alice)
+
+	// alice 的客户端不会被 bob 认领
+	assert.Equal(t, map[uint64]uint{11: 1, 22: 2}, authors.snapshot())
+
+	text, runs, err := hub.CodeAuthorship(context.Background(), testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", text)
+	assert.Equal(t, models.AuthorRuns{{Client: 11, Length: 2}, {Client: 22, Length: 1}}, runs)
+}
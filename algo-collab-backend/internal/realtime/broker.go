@@ -0,0 +1,216 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Broker 多节点部署时在节点之间转发房间消息
+// 转发最多一次：消息可能丢失，可能丢失时调用订阅者的 resync，由订阅者从存储中追上
+type Broker interface {
+	// Publish 把消息发布给订阅了该房间的所有节点（包括自己）
+	Publish(ctx context.Context, roomUUID string, data []byte) error
+	// Subscribe 开始接收房间消息，返回的函数取消本次订阅
+	// 每个订阅按顺序调用 handler，不同订阅之间互不阻塞；resync 可以为空
+	Subscribe(ctx context.Context, roomUUID string, handler func(data []byte), resync func()) (func(), error)
+}
+
+const roomChannelPrefix = "collab:room:"
+
+// subscriptionQueueSize 每个订阅等待处理的消息上限，房间处理不过来时丢弃之后的消息
+const subscriptionQueueSize = 256
+
+// subscription 一个订阅：有自己的消息队列和处理协程，一个房间处理得慢不会拖住其他房间
+type subscription struct {
+	handler func(data []byte)
+	resync  func()
+	queue   chan []byte
+	// gap 有消息被丢弃或断线重连过，需要调用 resync；多次标记只调用一次
+	gap  chan struct{}
+	done chan struct{}
+}
+
+func newSubscription(handler func(data []byte), resync func()) *subscription {
+	sub := &subscription{
+		handler: handler,
+		resync:  resync,
+		queue:   make(chan []byte, subscriptionQueueSize),
+		gap:     make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go sub.run()
+	return sub
+}
+
+func (s *subscription) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case data := <-s.queue:
+			s.handler(data)
+		case <-s.gap:
+			if s.resync != nil {
+				s.resync()
+			}
+		}
+	}
+}
+
+// deliver 把消息放入队列，不阻塞接收协程；队列已满时丢弃并标记需要重新同步
+func (s *subscription) deliver(data []byte) {
+	select {
+	case s.queue <- data:
+	default:
+		s.markGap()
+	}
+}
+
+func (s *subscription) markGap() {
+	select {
+	case s.gap <- struct{}{}:
+	default:
+	}
+}
+
+// redisBroker 基于 Redis pub/sub 的 Broker
+// 每个节点只使用一个订阅连接，按房间动态订阅/退订频道
+type redisBroker struct {
+	rdb    *redis.Client
+	pubsub *redis.PubSub
+
+	mu       sync.Mutex
+	handlers map[string][]*subscription
+	// confirmed 已收到 Redis 订阅确认的频道，再次收到确认说明连接断开后重新订阅过，期间的消息已经丢失
+	confirmed map[string]bool
+}
+
+// NewRedisBroker 创建 Broker，ctx 取消后停止接收消息
+func NewRedisBroker(ctx context.Context, rdb *redis.Client) Broker {
+	b := &redisBroker{
+		rdb:       rdb,
+		pubsub:    rdb.Subscribe(ctx),
+		handlers:  make(map[string][]*subscription),
+		confirmed: make(map[string]bool),
+	}
+	go b.receive(ctx)
+	return b
+}
+
+func (b *redisBroker) receive(ctx context.Context) {
+	defer b.pubsub.Close()
+
+	ch := b.pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch msg := msg.(type) {
+			case *redis.Message:
+				b.mu.Lock()
+				subs := b.handlers[msg.Channel]
+				b.mu.Unlock()
+				for _, sub := range subs {
+					sub.deliver([]byte(msg.Payload))
+				}
+			case *redis.Subscription:
+				if msg.Kind == "subscribe" {
+					b.confirm(msg.Channel)
+				}
+			}
+		}
+	}
+}
+
+// confirm 记录频道的订阅确认，重新订阅时通知频道上的所有订阅者重新同步
+func (b *redisBroker) confirm(channel string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.confirmed[channel] {
+		b.confirmed[channel] = true
+		return
+	}
+	logger.Warn("房间频道已重新订阅，从存储中重新同步", zap.String("channel", channel))
+	for _, sub := range b.handlers[channel] {
+		sub.markGap()
+	}
+}
+
+func (b *redisBroker) Publish(ctx context.Context, roomUUID string, data []byte) error {
+	return b.rdb.Publish(ctx, roomChannelPrefix+roomUUID, data).Err()
+}
+
+func (b *redisBroker) Subscribe(ctx context.Context, roomUUID string, handler func(data []byte), resync func()) (func(), error) {
+	channel := roomChannelPrefix + roomUUID
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 同一频道只向 Redis 订阅一次
+	if len(b.handlers[channel]) == 0 {
+		if err := b.pubsub.Subscribe(ctx, channel); err != nil {
+			return nil, err
+		}
+	}
+	sub := newSubscription(handler, resync)
+	b.handlers[channel] = append(b.handlers[channel], sub)
+
+	return func() { b.unsubscribe(channel, sub) }, nil
+}
+
+// unsubscribe 移除订阅，频道上没有订阅者时向 Redis 退订
+func (b *redisBroker) unsubscribe(channel string, sub *subscription) {
+	close(sub.done)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.handlers[channel]
+	for i, s := range subs {
+		if s == sub {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) > 0 {
+		b.handlers[channel] = subs
+		return
+	}
+
+	delete(b.handlers, channel)
+	delete(b.confirmed, channel)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := b.pubsub.Unsubscribe(ctx, channel); err != nil {
+		logger.Warn("退订房间频道失败", zap.String("channel", channel), zap.Error(err))
+	}
+}
+
+// encodeEnvelope 节点间转发的消息：来源节点 ID + y-websocket 原始消息
+func encodeEnvelope(nodeID string, frame []byte) []byte {
+	e := yjs.NewEncoder()
+	e.WriteVarString(nodeID)
+	e.WriteVarBytes(frame)
+	return e.Bytes()
+}
+
+func decodeEnvelope(data []byte) (nodeID string, frame []byte, err error) {
+	d := yjs.NewDecoder(data)
+	if nodeID, err = d.ReadVarString(); err != nil {
+		return "", nil, err
+	}
+	if frame, err = d.ReadVarBytes(); err != nil {
+		return "", nil, err
+	}
+	return nodeID, frame, nil
+}
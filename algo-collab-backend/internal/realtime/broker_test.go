@@ -0,0 +1,197 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestNode 模拟一个后端节点：独立的 Hub 和 Redis 连接，共享同一个 Redis 和文档存储
+func newTestNode(t *testing.T, mr *miniredis.Miniredis, store *memoryDocumentStore) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	hub := NewHub(Options{Documents: store, Broker: NewRedisBroker(ctx, rdb)})
+	return newTestServer(t, hub)
+}
+
+// expectSyncDone 发送 SyncStep1 并断言下一条消息是 SyncStep2，确认之前没有多余的消息
+func expectSyncDone(t *testing.T, conn *websocket.Conn) {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, yjs.EncodeSyncStep1(yjs.EmptyStateVector)))
+	msg := readMessage(t, conn)
+	assert.Equal(t, uint64(yjs.MessageSync), msg.Type)
+	assert.Equal(t, uint64(yjs.SyncStep2), msg.SubType)
+}
+
+func TestHub_FansOutAcrossNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	nodeA := newTestNode(t, mr, store)
+	nodeB := newTestNode(t, mr, store)
+	channel := roomChannelPrefix + testRoom.UUID
+
+	alice := dial(t, nodeA)
+	readMessage(t, alice)
+	bob := dial(t, nodeB)
+	readMessage(t, bob)
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 2 })
+
+	// 文档更新：另一个节点上的 bob 只收到一次，alice 不会收到自己的回送
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, "hello")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+
+	msg := readMessage(t, bob)
+	assert.Equal(t, uint64(yjs.SyncUpdate), msg.SubType)
+	assert.Equal(t, update, msg.Payload)
+	expectSyncDone(t, bob)
+	expectSyncDone(t, alice)
+
+	// awareness 同样跨节点转发
+	awareness := yjs.EncodeAwarenessUpdate([]yjs.AwarenessEntry{{ClientID: 7, Clock: 1, State: []byte(`{"user":"bob"}`)}})
+	require.NoError(t, bob.WriteMessage(websocket.BinaryMessage, yjs.EncodeAwarenessMessage(awareness)))
+	msg = readMessage(t, alice)
+	assert.Equal(t, uint64(yjs.MessageAwareness), msg.Type)
+	assert.Equal(t, awareness, msg.Payload)
+
+	// bob 断开后：alice 收到其 awareness 被移除，节点 B 退订频道
+	bob.Close()
+	msg = readMessage(t, alice)
+	entries, err := yjs.DecodeAwarenessUpdate(msg.Payload)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].IsRemoved())
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 1 })
+
+	// 节点 B 上新加入的连接从共享存储中拿到完整文档
+	carol := dial(t, nodeB)
+	readMessage(t, carol)
+	assert.Equal(t, "hello", syncText(t, carol))
+}
+
+func TestRedisBroker_DispatchesPerRoom(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	broker := NewRedisBroker(ctx, rdb)
+
+	block := make(chan struct{})
+	resynced := make(chan struct{}, 1)
+	_, err := broker.Subscribe(ctx, "slow", func([]byte) { <-block }, func() { resynced <- struct{}{} })
+	require.NoError(t, err)
+	fast := make(chan []byte, 1)
+	_, err = broker.Subscribe(ctx, "fast", func(data []byte) { fast <- data }, nil)
+	require.NoError(t, err)
+	waitFor(t, func() bool { return len(mr.PubSubChannels("")) == 2 })
+
+	// 1. 一个房间处理得慢，队列满了之后丢弃消息，其他房间照常收到
+	for i := 0; i < subscriptionQueueSize+2; i++ {
+		require.NoError(t, broker.Publish(ctx, "slow", []byte("x")))
+	}
+	require.NoError(t, broker.Publish(ctx, "fast", []byte("hello")))
+	select {
+	case data := <-fast:
+		assert.Equal(t, []byte("hello"), data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("慢房间阻塞了其他房间")
+	}
+
+	// 2. 丢弃过消息的房间恢复后从存储中重新同步
+	close(block)
+	select {
+	case <-resynced:
+	case <-time.After(2 * time.Second):
+		t.Fatal("丢弃消息后没有重新同步")
+	}
+}
+
+func TestRedisBroker_ResyncsAfterReconnect(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	broker := NewRedisBroker(ctx, rdb)
+
+	resynced := make(chan struct{}, 1)
+	_, err := broker.Subscribe(ctx, "room", func([]byte) {}, func() { resynced <- struct{}{} })
+	require.NoError(t, err)
+	channel := roomChannelPrefix + "room"
+	waitFor(t, func() bool {
+		b := broker.(*redisBroker)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.confirmed[channel]
+	})
+
+	// 断线期间发布的消息已经丢失，重新订阅后通知订阅者
+	mr.Close()
+	require.NoError(t, mr.Restart())
+	select {
+	case <-resynced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("重新订阅后没有重新同步")
+	}
+}
+
+func TestHub_ResyncFromStore(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	hub.mu.Lock()
+	room := hub.rooms[testRoom.UUID]
+	hub.mu.Unlock()
+
+	// 其他节点已经写库、但转发的消息丢失了：重新同步后把缺少的部分下发给本节点的连接
+	update := yjs.NewDoc(yjs.Options{ClientID: 9}).GetText(codeTextName).Insert(0, "hello")
+	_, err := store.AppendUpdate(context.Background(), testRoom.ID, update)
+	require.NoError(t, err)
+	room.resync()
+
+	msg := readMessage(t, alice)
+	require.Equal(t, uint64(yjs.SyncUpdate), msg.SubType)
+	doc := yjs.NewDoc(yjs.Options{})
+	require.NoError(t, doc.ApplyUpdate(msg.Payload))
+	assert.Equal(t, "hello", doc.GetText(codeTextName).String())
+
+	// 没有漏掉的内容时不下发
+	room.resync()
+	expectSyncDone(t, alice)
+	assert.Equal(t, "hello", syncText(t, alice))
+}
+
+func TestRedisLocker_TryLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	locker := NewRedisLocker(rdb)
+
+	unlock, ok, err := locker.TryLock(ctx, "room-1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = locker.TryLock(ctx, "room-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	unlock()
+	_, ok, err = locker.TryLock(ctx, "room-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
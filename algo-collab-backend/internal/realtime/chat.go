@@ -0,0 +1,295 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// chatFrame 的类型
+const (
+	chatFrameSend   = "send"   // 客户端 → 服务端：发送消息，可以带代码片段或回复某条消息
+	chatFrameEdit   = "edit"   // 客户端 → 服务端：编辑自己的消息
+	chatFrameDelete = "delete" // 客户端 → 服务端：删除消息
+	chatFrameReact  = "react"  // 客户端 → 服务端：添加或取消表情回应
+
+	chatFrameMessage  = "message"  // 服务端 → 客户端：一条新消息（包括系统消息和回复）
+	chatFrameUpdated  = "updated"  // 服务端 → 客户端：消息被编辑或删除，客户端整条替换
+	chatFrameReaction = "reaction" // 服务端 → 客户端：消息的表情回应变化
+	chatFrameError    = "error"    // 服务端 → 客户端：操作失败
+)
+
+var (
+	// errChatReadOnly 只读连接（归档房间）不能发送消息
+	errChatReadOnly = errors.New("房间已归档，不能发送消息")
+	// errChatSnippetUnavailable 文档加载失败，无法截取代码片段
+	errChatSnippetUnavailable = errors.New("读取代码失败，请稍后重试")
+)
+
+// ChatService 聊天消息的校验、权限和持久化，由 service 层实现
+// 返回的错误会原样展示给用户
+type ChatService interface {
+	PostMessage(ctx context.Context, roomID, userID uint, req *service.PostChatMessageRequest) (*models.RoomMessage, error)
+	PostSystemMessage(ctx context.Context, roomID uint, content string) (*models.RoomMessage, error)
+	EditMessage(ctx context.Context, roomID, userID, messageID uint, content string) (*models.RoomMessage, error)
+	DeleteMessage(ctx context.Context, roomID, userID, messageID uint) (*models.RoomMessage, error)
+	ToggleReaction(ctx context.Context, roomID, userID, messageID uint, emoji string) ([]models.ReactionSummary, error)
+}
+
+// chatFrame 聊天消息的 JSON 结构
+// Nonce 由客户端生成，服务端在回送的消息或错误中原样带回，用于替换本地的待发送消息
+type chatFrame struct {
+	Type    string `json:"type"`
+	Nonce   string `json:"nonce,omitempty"`
+	Content string `json:"content,omitempty"`
+	// MessageID 编辑、删除、表情回应的目标消息
+	MessageID uint `json:"message_id,omitempty"`
+	ReplyToID uint `json:"reply_to_id,omitempty"`
+	// Snippet 客户端只需要给出语言和行号，代码由服务端从房间文档中截取
+	Snippet *models.CodeSnippet `json:"snippet,omitempty"`
+	Emoji   string              `json:"emoji,omitempty"`
+
+	Message   *models.RoomMessage      `json:"message,omitempty"`
+	Reactions []models.ReactionSummary `json:"reactions,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+// isChatFrame 判断二进制消息是否为聊天消息
+func isChatFrame(data []byte) bool {
+	return isMessageType(data, messageChat)
+}
+
+func encodeChatFrame(frame *chatFrame) []byte {
+	return encodeJSONMessage(messageChat, frame)
+}
+
+func decodeChatFrame(data []byte) (*chatFrame, error) {
+	var frame chatFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return nil, err
+	}
+	return &frame, nil
+}
+
+// handleChat 处理客户端发来的聊天消息
+// 写库在房间锁外进行，不阻塞文档同步
+func (r *Room) handleChat(client *Client, data []byte) {
+	frame, err := decodeChatFrame(data)
+	if err != nil || r.chat == nil {
+		return
+	}
+	if client.readOnly {
+		r.replyChatError(client, frame.Nonce, errChatReadOnly)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	userID := client.user.ID
+
+	var reply *chatFrame
+	switch frame.Type {
+	case chatFrameSend:
+		req := &service.PostChatMessageRequest{
+			Content:   frame.Content,
+			ReplyToID: frame.ReplyToID,
+			Snippet:   frame.Snippet,
+		}
+		if req.Snippet != nil {
+			if req.Source, err = r.codeText(); err != nil {
+				break
+			}
+		}
+		var message *models.RoomMessage
+		if message, err = r.chat.PostMessage(ctx, r.id, userID, req); err == nil {
+			reply = &chatFrame{Type: chatFrameMessage, Nonce: frame.Nonce, Message: message}
+		}
+	case chatFrameEdit:
+		var message *models.RoomMessage
+		if message, err = r.chat.EditMessage(ctx, r.id, userID, frame.MessageID, frame.Content); err == nil {
+			reply = &chatFrame{Type: chatFrameUpdated, Nonce: frame.Nonce, Message: message}
+		}
+	case chatFrameDelete:
+		var message *models.RoomMessage
+		if message, err = r.chat.DeleteMessage(ctx, r.id, userID, frame.MessageID); err == nil {
+			reply = &chatFrame{Type: chatFrameUpdated, Nonce: frame.Nonce, Message: message}
+		}
+	case chatFrameReact:
+		var reactions []models.ReactionSummary
+		if reactions, err = r.chat.ToggleReaction(ctx, r.id, userID, frame.MessageID, frame.Emoji); err == nil {
+			reply = &chatFrame{Type: chatFrameReaction, Nonce: frame.Nonce, MessageID: frame.MessageID, Reactions: reactions}
+		}
+	default:
+		return
+	}
+	if err != nil {
+		r.replyChatError(client, frame.Nonce, err)
+		return
+	}
+	r.touchActivity(userID)
+	r.deliverChat(reply)
+}
+
+// codeText 读取当前的代码文本，用于截取代码片段
+func (r *Room) codeText() (string, error) {
+	text, err := r.currentCode()
+	if err != nil {
+		logger.Error("加载协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return "", errChatSnippetUnavailable
+	}
+	return text, nil
+}
+
+// deliverChat 把已保存的消息发给房间内所有人（包括发送者）和其他节点
+func (r *Room) deliverChat(frame *chatFrame) {
+	data := encodeChatFrame(frame)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.broadcastLocked(data, nil)
+	r.publishLocked(data)
+	r.recordChatLocked(frame)
+}
+
+func (r *Room) replyChatError(client *Client, nonce string, err error) {
+	data := encodeChatFrame(&chatFrame{Type: chatFrameError, Nonce: nonce, Error: err.Error()})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sendLocked(client, data)
+}
+
+// postSystemMessage 保存一条系统消息并推送给房间内的所有人
+// 房间已回收时仍然写库，其他节点上的连接通过 broker 收到
+func (h *Hub) postSystemMessage(roomID uint, roomUUID, content string) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	message, err := h.opts.Chat.PostSystemMessage(ctx, roomID, content)
+	if err != nil {
+		logger.Warn("发送系统消息失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+		return
+	}
+	frame := &chatFrame{Type: chatFrameMessage, Message: message}
+
+	h.mu.Lock()
+	room := h.rooms[roomUUID]
+	h.mu.Unlock()
+	if room != nil {
+		room.deliverChat(frame)
+		return
+	}
+	if h.opts.Broker != nil {
+		if err := h.opts.Broker.Publish(ctx, roomUUID, encodeEnvelope(h.nodeID, encodeChatFrame(frame))); err != nil {
+			logger.Warn("转发系统消息失败", zap.String("room_uuid", roomUUID), zap.Error(err))
+		}
+	}
+}
+
+// presenceKey 某个用户在某个房间
+type presenceKey struct {
+	room string
+	user uint
+}
+
+// chatPresence 按用户（而不是连接）跟踪房间内的成员，生成加入/离开的系统消息
+//
+// 同一用户的多个标签页只算一次；用户最后一个连接断开后等待 grace 再发送离开消息，
+// 期间重连（刷新页面、网络抖动）不产生任何消息。
+// 只统计本节点的连接：多节点部署时同一用户的连接分布在不同节点上，可能产生重复的消息。
+type chatPresence struct {
+	grace time.Duration
+
+	mu      sync.Mutex
+	conns   map[presenceKey]int
+	leaving map[presenceKey]*time.Timer
+	stopped bool
+}
+
+func newChatPresence(grace time.Duration) *chatPresence {
+	return &chatPresence{
+		grace:   grace,
+		conns:   make(map[presenceKey]int),
+		leaving: make(map[presenceKey]*time.Timer),
+	}
+}
+
+// enter 记录一个连接，返回是否需要发送加入消息
+func (p *chatPresence) enter(key presenceKey) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return false
+	}
+	p.conns[key]++
+	if p.conns[key] > 1 {
+		return false
+	}
+	if timer, ok := p.leaving[key]; ok {
+		// 在宽限期内重连
+		timer.Stop()
+		delete(p.leaving, key)
+		return false
+	}
+	return true
+}
+
+// exit 移除一个连接，用户的最后一个连接断开并超过宽限期后调用 announce
+// announce 为空表示不发送离开消息（例如房间迁移、服务关闭，用户会立即重连）
+func (p *chatPresence) exit(key presenceKey, announce func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns[key]--; p.conns[key] > 0 {
+		return
+	}
+	delete(p.conns, key)
+	if p.stopped || announce == nil {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(p.grace, func() {
+		p.mu.Lock()
+		// 宽限期内重连过，这个定时器已经作废
+		current := p.leaving[key] == timer
+		if current {
+			delete(p.leaving, key)
+		}
+		p.mu.Unlock()
+
+		if current {
+			announce()
+		}
+	})
+	p.leaving[key] = timer
+}
+
+// stop 服务关闭：取消尚未发送的离开消息，之后不再产生任何消息
+func (p *chatPresence) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	for key, timer := range p.leaving {
+		timer.Stop()
+		delete(p.leaving, key)
+	}
+}
+
+// joinedMessage 和 leftMessage 系统消息的内容
+func joinedMessage(username string) string {
+	return fmt.Sprintf("%s 加入了房间", username)
+}
+
+func leftMessage(username string) string {
+	return fmt.Sprintf("%s 离开了房间", username)
+}
@@ -0,0 +1,223 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryChatService 内存中的聊天存储
+type memoryChatService struct {
+	mu        sync.Mutex
+	messages  []*models.RoomMessage
+	reactions map[uint][]models.ReactionSummary
+}
+
+func (s *memoryChatService) PostMessage(_ context.Context, roomID, userID uint, req *service.PostChatMessageRequest) (*models.RoomMessage, error) {
+	message := &models.RoomMessage{RoomID: roomID, UserID: &userID, Type: models.RoomMessageText, Content: req.Content}
+	if req.Snippet != nil {
+		lines := strings.Split(req.Source, "\n")
+		message.Type = models.RoomMessageCode
+		message.Snippet = &models.CodeSnippet{
+			StartLine: req.Snippet.StartLine,
+			EndLine:   req.Snippet.EndLine,
+			Code:      strings.Join(lines[req.Snippet.StartLine-1:req.Snippet.EndLine], "\n"),
+		}
+	} else if req.Content == "" {
+		return nil, errors.New("消息不能为空")
+	}
+	return s.save(message), nil
+}
+
+func (s *memoryChatService) EditMessage(_ context.Context, _, userID, messageID uint, content string) (*models.RoomMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message := s.messages[messageID-1]
+	if message.UserID == nil || *message.UserID != userID {
+		return nil, errors.New("只能编辑自己的消息")
+	}
+	now := time.Now()
+	message.Content = content
+	message.EditedAt = &now
+	return message, nil
+}
+
+func (s *memoryChatService) DeleteMessage(_ context.Context, _, userID, messageID uint) (*models.RoomMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message := s.messages[messageID-1]
+	now := time.Now()
+	message.Content = ""
+	message.DeletedAt = &now
+	message.DeletedBy = &userID
+	return message, nil
+}
+
+func (s *memoryChatService) ToggleReaction(_ context.Context, _, userID, messageID uint, emoji string) ([]models.ReactionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reactions == nil {
+		s.reactions = make(map[uint][]models.ReactionSummary)
+	}
+	s.reactions[messageID] = append(s.reactions[messageID], models.ReactionSummary{Emoji: emoji, Count: 1, UserIDs: []uint{userID}})
+	return s.reactions[messageID], nil
+}
+
+func (s *memoryChatService) PostSystemMessage(_ context.Context, roomID uint, content string) (*models.RoomMessage, error) {
+	return s.save(&models.RoomMessage{RoomID: roomID, Type: models.RoomMessageSystem, Content: content}), nil
+}
+
+func (s *memoryChatService) save(message *models.RoomMessage) *models.RoomMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message.ID = uint(len(s.messages) + 1)
+	s.messages = append(s.messages, message)
+	return message
+}
+
+// readChat 跳过文档同步等其他消息，返回下一条聊天消息
+func readChat(t *testing.T, conn *websocket.Conn) *chatFrame {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		if isChatFrame(data) {
+			frame, err := decodeChatFrame(data)
+			require.NoError(t, err)
+			return frame
+		}
+	}
+}
+
+func sendChat(t *testing.T, conn *websocket.Conn, nonce, content string) {
+	frame := encodeChatFrame(&chatFrame{Type: chatFrameSend, Nonce: nonce, Content: content})
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, frame))
+}
+
+func TestHub_ChatOverRoomSocket(t *testing.T) {
+	chat := &memoryChatService{}
+	url := newTestServer(t, NewHub(Options{Chat: chat}))
+
+	alice := dial(t, url+"?user=1")
+	assert.Equal(t, "user-1 加入了房间", readChat(t, alice).Message.Content)
+	bob := dial(t, url+"?user=2")
+	assert.Equal(t, "user-2 加入了房间", readChat(t, alice).Message.Content)
+	assert.Equal(t, "user-2 加入了房间", readChat(t, bob).Message.Content)
+
+	// 同一用户的第二个标签页不产生加入消息
+	dial(t, url+"?user=2")
+
+	// 消息广播给所有人，发送者收到带 nonce 的回送
+	sendChat(t, alice, "n1", "用哈希表")
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		frame := readChat(t, conn)
+		assert.Equal(t, chatFrameMessage, frame.Type)
+		assert.Equal(t, "n1", frame.Nonce)
+		assert.Equal(t, "用哈希表", frame.Message.Content)
+		assert.Equal(t, uint(1), *frame.Message.UserID)
+	}
+
+	// 错误只返回给发送者
+	sendChat(t, alice, "n2", "")
+	frame := readChat(t, alice)
+	assert.Equal(t, chatFrameError, frame.Type)
+	assert.Equal(t, "n2", frame.Nonce)
+	assert.Equal(t, "消息不能为空", frame.Error)
+
+	// 只读连接不能发送
+	viewer := dial(t, url+"?user=3&readonly=1")
+	sendChat(t, viewer, "n3", "hi")
+	frame = readChat(t, viewer)
+	assert.Equal(t, chatFrameError, frame.Type)
+	assert.Equal(t, errChatReadOnly.Error(), frame.Error)
+
+	// alice 断开后其他人收到离开消息
+	alice.Close()
+	assert.Equal(t, "user-1 离开了房间", readChat(t, bob).Message.Content)
+}
+
+func TestHub_ChatSnippetEditDeleteAndReact(t *testing.T) {
+	chat := &memoryChatService{}
+	room := &models.Room{BaseModel: models.BaseModel{ID: 2}, UUID: "room-2", StarterCode: "line 1\nline 2\nline 3"}
+	url := newTestServerForRoom(t, NewHub(Options{Chat: chat}), room)
+
+	alice := dial(t, url+"?user=1")
+	readChat(t, alice) // 加入消息，ID 为 1
+
+	send := func(frame *chatFrame) *chatFrame {
+		require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, encodeChatFrame(frame)))
+		return readChat(t, alice)
+	}
+
+	// 代码由服务端从房间文档中截取
+	frame := send(&chatFrame{Type: chatFrameSend, Nonce: "n1", Snippet: &models.CodeSnippet{StartLine: 2, EndLine: 3}})
+	require.Equal(t, chatFrameMessage, frame.Type)
+	assert.Equal(t, "line 2\nline 3", frame.Message.Snippet.Code)
+	id := frame.Message.ID
+
+	frame = send(&chatFrame{Type: chatFrameEdit, Nonce: "n2", MessageID: id, Content: "看这里"})
+	assert.Equal(t, chatFrameUpdated, frame.Type)
+	assert.Equal(t, "看这里", frame.Message.Content)
+	assert.NotNil(t, frame.Message.EditedAt)
+
+	frame = send(&chatFrame{Type: chatFrameReact, MessageID: id, Emoji: "👍"})
+	assert.Equal(t, chatFrameReaction, frame.Type)
+	assert.Equal(t, id, frame.MessageID)
+	assert.Equal(t, []models.ReactionSummary{{Emoji: "👍", Count: 1, UserIDs: []uint{1}}}, frame.Reactions)
+
+	frame = send(&chatFrame{Type: chatFrameDelete, MessageID: id})
+	assert.Equal(t, chatFrameUpdated, frame.Type)
+	assert.True(t, frame.Message.IsDeleted())
+
+	// 服务层的错误只返回给发送者
+	frame = send(&chatFrame{Type: chatFrameEdit, Nonce: "n3", MessageID: 1, Content: "改系统消息"})
+	assert.Equal(t, chatFrameError, frame.Type)
+	assert.Equal(t, "n3", frame.Nonce)
+}
+
+func TestChatPresence_GracePeriod(t *testing.T) {
+	presence := newChatPresence(50 * time.Millisecond)
+	key := presenceKey{room: "room-1", user: 1}
+	left := make(chan struct{}, 1)
+	announce := func() { left <- struct{}{} }
+
+	require.True(t, presence.enter(key))
+	require.False(t, presence.enter(key))
+	presence.exit(key, announce)
+
+	// 宽限期内重连：不产生离开和加入消息
+	presence.exit(key, announce)
+	require.False(t, presence.enter(key))
+	select {
+	case <-left:
+		t.Fatal("宽限期内重连不应发送离开消息")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 超过宽限期才发送离开消息，之后再进入是新的加入
+	presence.exit(key, announce)
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("没有发送离开消息")
+	}
+	assert.True(t, presence.enter(key))
+
+	// 服务关闭后取消尚未发送的离开消息
+	presence.exit(key, announce)
+	presence.stop()
+	select {
+	case <-left:
+		t.Fatal("服务关闭后不应发送离开消息")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
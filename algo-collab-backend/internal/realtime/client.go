@@ -0,0 +1,170 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+)
+
+// 连接参数的默认值，配置为 0 时使用
+const (
+	defaultPingInterval   = 25 * time.Second
+	defaultIdleTimeout    = 60 * time.Second
+	defaultWriteTimeout   = 10 * time.Second
+	defaultMaxMessageSize = 8 << 20
+	defaultSendBufferSize = 256
+)
+
+// connLimits 单个连接的心跳、超时和缓冲参数
+type connLimits struct {
+	pingInterval   time.Duration // 发送 ping 的间隔，必须小于 idleTimeout
+	idleTimeout    time.Duration // 多久收不到任何消息（包括 pong）就认为连接已断开
+	writeTimeout   time.Duration
+	maxMessageSize int64
+	// sendBufferSize 发送队列长度，队列满说明客户端跟不上：
+	// 光标等临时消息直接丢弃，文档更新不能丢，只能断开让客户端重新同步
+	sendBufferSize int
+}
+
+func newConnLimits(cfg config.WebSocketConfig) connLimits {
+	limits := connLimits{
+		pingInterval:   time.Duration(cfg.PingIntervalSeconds) * time.Second,
+		idleTimeout:    time.Duration(cfg.IdleTimeoutSeconds) * time.Second,
+		writeTimeout:   time.Duration(cfg.WriteTimeoutSeconds) * time.Second,
+		maxMessageSize: int64(cfg.MaxMessageKB) << 10,
+		sendBufferSize: cfg.SendBufferSize,
+	}
+	if limits.pingInterval <= 0 {
+		limits.pingInterval = defaultPingInterval
+	}
+	if limits.idleTimeout <= limits.pingInterval {
+		limits.idleTimeout = max(defaultIdleTimeout, limits.pingInterval*2)
+	}
+	if limits.writeTimeout <= 0 {
+		limits.writeTimeout = defaultWriteTimeout
+	}
+	if limits.maxMessageSize <= 0 {
+		limits.maxMessageSize = defaultMaxMessageSize
+	}
+	if limits.sendBufferSize <= 0 {
+		limits.sendBufferSize = defaultSendBufferSize
+	}
+	return limits
+}
+
+// Client 一个 WebSocket 连接
+type Client struct {
+	conn     *websocket.Conn
+	user     User
+	readOnly bool
+	limits   connLimits
+	// peerID 语音频道中标识该连接，信令按它转发
+	peerID string
+
+	send      chan []byte
+	closeOnce sync.Once
+	// closeMessage 断开时发送的关闭帧，为空表示正常关闭
+	closeMessage []byte
+
+	// awareness 中由该连接控制的 clientID 及其最新 clock，断开时统一清除
+	awarenessIDs map[uint64]uint64
+	// lockRejected 编辑锁定区域被拒绝过，连接上的文档已经和服务端不一致，见 checkLocksLocked
+	lockRejected bool
+}
+
+func newClient(conn *websocket.Conn, user User, readOnly bool, limits connLimits) *Client {
+	return &Client{
+		conn:         conn,
+		user:         user,
+		readOnly:     readOnly,
+		limits:       limits,
+		peerID:       uuid.NewString(),
+		send:         make(chan []byte, limits.sendBufferSize),
+		awarenessIDs: make(map[uint64]uint64),
+	}
+}
+
+// enqueue 非阻塞地把消息放入发送队列，队列已满返回 false
+// 只能在持有房间锁时调用，保证不会和 close 并发
+func (c *Client) enqueue(data []byte) bool {
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// close 关闭发送队列，writePump 随之退出并关闭连接
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.send)
+	})
+}
+
+// closeWith 带关闭码断开连接，只能在持有房间锁时调用
+func (c *Client) closeWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeMessage = websocket.FormatCloseMessage(code, reason)
+		close(c.send)
+	})
+}
+
+// readPump 持续读取消息，连接断开后调用 onClose
+func (c *Client) readPump(onMessage func([]byte), onClose func()) {
+	defer func() {
+		onClose()
+		c.conn.Close()
+	}()
+
+	// 超过限制时 gorilla 会以 1009 关闭连接
+	c.conn.SetReadLimit(c.limits.maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.limits.idleTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.limits.idleTimeout))
+	})
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.limits.idleTimeout))
+		// y-protocols 只使用二进制消息
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		onMessage(data)
+	}
+}
+
+// writePump 把发送队列中的消息写到连接上，并定时发送 ping
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.limits.pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.limits.writeTimeout))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage)
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.limits.writeTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
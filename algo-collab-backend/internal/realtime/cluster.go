@@ -0,0 +1,202 @@
+package realtime
+
+import (
+	"context"
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// clusterNodesKey 节点成员表：有序集合，成员是节点地址，分数是最近一次心跳的时间（毫秒）
+	clusterNodesKey = "collab:nodes"
+	// ringReplicas 每个节点在哈希环上的虚拟节点数，越多分布越均匀
+	ringReplicas = 128
+)
+
+// hashRing 一致性哈希环：节点增减时只有相邻区间的房间会换节点
+type hashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+func newHashRing(nodes []string) *hashRing {
+	ring := &hashRing{owners: make(map[uint32]string, len(nodes)*ringReplicas)}
+	for _, node := range nodes {
+		for i := 0; i < ringReplicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(node + "#" + strconv.Itoa(i)))
+			// 极少数哈希冲突时保留字典序较小的节点，保证所有节点算出同一个环
+			if existing, ok := ring.owners[point]; ok && existing < node {
+				continue
+			}
+			ring.owners[point] = node
+		}
+	}
+	for point := range ring.owners {
+		ring.points = append(ring.points, point)
+	}
+	slices.Sort(ring.points)
+	return ring
+}
+
+// get 顺时针找到 key 之后的第一个虚拟节点
+func (r *hashRing) get(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// Cluster 房间亲和模式下的节点成员表
+//
+// 每个节点定期把自己的地址写入 Redis 有序集合作为心跳，超过 TTL 没有心跳的节点被剔除。
+// 所有节点按同一份成员表构建一致性哈希环，房间由环上对应的节点独占，其他节点只转发连接。
+type Cluster struct {
+	rdb      *redis.Client
+	self     string
+	interval time.Duration
+	ttl      time.Duration
+
+	mu       sync.RWMutex
+	nodes    []string
+	ring     *hashRing
+	onChange []func()
+	left     bool // 已主动退出，不再发送心跳
+}
+
+// NewCluster 创建成员表，self 是其他节点访问本节点的地址（host:port）
+func NewCluster(rdb *redis.Client, self string, cfg config.ClusterConfig) *Cluster {
+	interval := time.Duration(cfg.HeartbeatSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ttl := time.Duration(cfg.NodeTTLSeconds) * time.Second
+	if ttl <= interval {
+		ttl = 3 * interval
+	}
+	return &Cluster{
+		rdb:      rdb,
+		self:     self,
+		interval: interval,
+		ttl:      ttl,
+		nodes:    []string{self},
+		ring:     newHashRing([]string{self}),
+	}
+}
+
+// Self 本节点地址
+func (c *Cluster) Self() string {
+	return c.self
+}
+
+// Owner 房间当前归属的节点
+func (c *Cluster) Owner(roomUUID string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ring.get(roomUUID)
+}
+
+// IsOwner 房间是否归本节点所有
+func (c *Cluster) IsOwner(roomUUID string) bool {
+	return c.Owner(roomUUID) == c.self
+}
+
+// Nodes 当前存活的节点
+func (c *Cluster) Nodes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.nodes)
+}
+
+// OnChange 注册成员变化的回调（在心跳协程中调用）
+func (c *Cluster) OnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, fn)
+}
+
+// Start 立即注册本节点，然后定期发送心跳、刷新成员表，ctx 取消后停止
+func (c *Cluster) Start(ctx context.Context) {
+	c.refresh(ctx)
+
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Leave 主动退出成员表并停止心跳
+// 本节点立即按新的成员表迁出所有房间，其他节点下一次刷新时接管
+func (c *Cluster) Leave(ctx context.Context) error {
+	c.mu.Lock()
+	c.left = true
+	c.mu.Unlock()
+
+	if err := c.rdb.ZRem(ctx, clusterNodesKey, c.self).Err(); err != nil {
+		return err
+	}
+	c.refresh(ctx)
+	return nil
+}
+
+// refresh 发送心跳、剔除超时节点并重建哈希环
+// Redis 不可用时保留上一次的成员表，避免所有房间来回迁移
+func (c *Cluster) refresh(ctx context.Context) {
+	now := time.Now()
+	expired := strconv.FormatInt(now.Add(-c.ttl).UnixMilli(), 10)
+
+	c.mu.RLock()
+	left := c.left
+	c.mu.RUnlock()
+
+	pipe := c.rdb.TxPipeline()
+	if !left {
+		pipe.ZAdd(ctx, clusterNodesKey, redis.Z{Score: float64(now.UnixMilli()), Member: c.self})
+	}
+	pipe.ZRemRangeByScore(ctx, clusterNodesKey, "-inf", "("+expired)
+	members := pipe.ZRange(ctx, clusterNodesKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warn("刷新集群成员失败", zap.Error(err))
+		return
+	}
+
+	nodes := members.Val()
+	sort.Strings(nodes)
+
+	c.mu.Lock()
+	if slices.Equal(nodes, c.nodes) {
+		c.mu.Unlock()
+		return
+	}
+	c.nodes = nodes
+	c.ring = newHashRing(nodes)
+	callbacks := slices.Clone(c.onChange)
+	c.mu.Unlock()
+
+	logger.Info("集群成员变化", zap.Strings("nodes", nodes))
+	for _, fn := range callbacks {
+		fn()
+	}
+}
@@ -0,0 +1,156 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRing_StableAndMinimalMovement(t *testing.T) {
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = uuid.NewString()
+	}
+
+	before := newHashRing([]string{"node-a:8080", "node-b:8080", "node-c:8080"})
+	// 成员顺序不影响结果
+	same := newHashRing([]string{"node-c:8080", "node-a:8080", "node-b:8080"})
+	after := newHashRing([]string{"node-a:8080", "node-b:8080", "node-c:8080", "node-d:8080"})
+
+	moved := 0
+	for _, key := range keys {
+		assert.Equal(t, before.get(key), same.get(key))
+		if owner := after.get(key); owner != before.get(key) {
+			// 只会迁到新节点，不会在老节点之间来回移动
+			assert.Equal(t, "node-d:8080", owner)
+			moved++
+		}
+	}
+	// 理想情况下迁移 1/4
+	assert.InDelta(t, len(keys)/4, moved, float64(len(keys))/10)
+}
+
+func TestCluster_Membership(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	a := NewCluster(rdb, "node-a:8080", config.ClusterConfig{})
+	b := NewCluster(rdb, "node-b:8080", config.ClusterConfig{})
+	a.refresh(ctx)
+	b.refresh(ctx)
+	a.refresh(ctx)
+	assert.Equal(t, []string{"node-a:8080", "node-b:8080"}, a.Nodes())
+	assert.Equal(t, a.Nodes(), b.Nodes())
+
+	// 心跳超时的节点被剔除
+	mr.ZAdd(clusterNodesKey, 0, "node-dead:8080")
+	a.refresh(ctx)
+	assert.Equal(t, []string{"node-a:8080", "node-b:8080"}, a.Nodes())
+
+	// 主动退出后不再发送心跳
+	changed := make(chan struct{}, 1)
+	b.OnChange(func() { changed <- struct{}{} })
+	require.NoError(t, b.Leave(ctx))
+	<-changed
+	assert.Equal(t, []string{"node-a:8080"}, b.Nodes())
+	b.refresh(ctx)
+	a.refresh(ctx)
+	assert.Equal(t, []string{"node-a:8080"}, a.Nodes())
+}
+
+// newAffinityNode 启动一个亲和模式的节点，返回 WebSocket 地址和成员表
+func newAffinityNode(t *testing.T, mr *miniredis.Miniredis, store *memoryDocumentStore, room *models.Room) (string, *Cluster) {
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	var hub *Hub
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = hub.Serve(w, r, room, User{ID: 1}, false)
+	}))
+	t.Cleanup(server.Close)
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	cluster := NewCluster(rdb, addr, config.ClusterConfig{})
+	hub = NewHub(Options{Documents: store, Cluster: cluster, Locker: NewRedisLocker(rdb)})
+	return "ws://" + addr, cluster
+}
+
+func TestHub_AffinityProxiesToOwnerAndHandsOff(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	ctx := context.Background()
+
+	// 找一个归节点 B 所有的房间
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}}
+	urlA, clusterA := newAffinityNode(t, mr, store, room)
+	urlB, clusterB := newAffinityNode(t, mr, store, room)
+	clusterA.refresh(ctx)
+	clusterB.refresh(ctx)
+	clusterA.refresh(ctx)
+	for i := 0; room.UUID == "" || clusterA.Owner(room.UUID) != clusterB.Self(); i++ {
+		room.UUID = fmt.Sprintf("room-%d", i)
+	}
+
+	// alice 连到节点 A，被转发到节点 B；bob 直接连到节点 B
+	alice := dial(t, urlA)
+	readMessage(t, alice)
+	bob := dial(t, urlB)
+	readMessage(t, bob)
+
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, "owned by b")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	msg := readMessage(t, bob)
+	assert.Equal(t, update, msg.Payload)
+
+	// 节点 B 退出：现有连接收到 1012，经过转发的连接同样如此
+	require.NoError(t, clusterB.Leave(ctx))
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+	}
+
+	// 节点 A 接管后从存储中恢复文档
+	clusterA.refresh(ctx)
+	require.True(t, clusterA.IsOwner(room.UUID))
+	carol := dial(t, urlA)
+	readMessage(t, carol)
+	assert.Equal(t, "owned by b", syncText(t, carol))
+}
+
+func TestHub_AffinityRejectsForwardedConnectionForOtherOwner(t *testing.T) {
+	mr := miniredis.RunT(t)
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1"}
+	urlA, clusterA := newAffinityNode(t, mr, &memoryDocumentStore{}, room)
+	_, clusterB := newAffinityNode(t, mr, &memoryDocumentStore{}, room)
+	ctx := context.Background()
+	clusterA.refresh(ctx)
+	clusterB.refresh(ctx)
+	clusterA.refresh(ctx)
+	for i := 0; clusterA.IsOwner(room.UUID); i++ {
+		room.UUID = fmt.Sprintf("room-%d", i)
+	}
+
+	header := http.Header{}
+	header.Set(forwardedHeader, "other:8080")
+	_, resp, err := websocket.DefaultDialer.Dial(urlA, header)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
@@ -0,0 +1,77 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+)
+
+var _ service.CommentEditor = (*Hub)(nil)
+
+// commentsFrameThread 服务端 → 客户端：一条评论的最新状态（新建、回复、解决、重新打开）
+const commentsFrameThread = "thread"
+
+// commentsFrame 代码评论消息的 JSON 结构
+// 评论由 HTTP 接口修改，修改后推送给房间内的所有连接；客户端打开文件时先通过接口获取评论列表
+type commentsFrame struct {
+	Type   string                    `json:"type"`
+	Thread *models.RoomCommentThread `json:"thread"`
+}
+
+func isCommentsFrame(data []byte) bool {
+	return isMessageType(data, messageComments)
+}
+
+// BroadcastComment 把评论推送给房间内所有节点上的连接
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) BroadcastComment(_ context.Context, roomModel *models.Room, thread *models.RoomCommentThread) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+
+	frame := encodeJSONMessage(messageComments, &commentsFrame{Type: commentsFrameThread, Thread: thread})
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.broadcastLocked(frame, nil)
+	room.publishLocked(frame)
+	return nil
+}
+
+// ResolveAnchors 把 Y.Text name 中的相对位置解析为当前下标，无法解析的为 -1
+// 和 FileTexts 一样，房间不在本节点时从存储中加载
+func (h *Hub) ResolveAnchors(_ context.Context, roomModel *models.Room, name string, anchors [][]byte) ([]int, error) {
+	h.mu.Lock()
+	room := h.rooms[roomModel.UUID]
+	h.mu.Unlock()
+	if room == nil {
+		room = newRoom(roomModel, h)
+		room.broker = nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if err := room.loadLocked(); err != nil {
+		return nil, err
+	}
+	text := room.doc.GetText(name)
+	positions := make([]int, len(anchors))
+	for i, anchor := range anchors {
+		positions[i] = -1
+		pos, err := yjs.DecodeRelativePosition(anchor)
+		if err != nil {
+			continue
+		}
+		if index, ok := text.Resolve(pos); ok {
+			positions[i] = int(index)
+		}
+	}
+	return positions, nil
+}
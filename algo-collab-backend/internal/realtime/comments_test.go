@@ -0,0 +1,49 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_CommentAnchorsFollowEdits(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	doc := yjs.NewDoc(yjs.Options{ClientID: 7})
+	update := doc.GetText(codeTextName).Insert(0, "hello world")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	waitFor(t, func() bool {
+		_, updates := store.counts()
+		return updates == 1
+	})
+
+	// 评论 "world"，之后在前面插入内容
+	text := doc.GetText(codeTextName)
+	anchors := [][]byte{
+		text.RelativePosition(6, 0).Encode(),
+		text.RelativePosition(11, -1).Encode(),
+		{0xff},
+	}
+	require.NoError(t, hub.ReplaceCode(context.Background(), testRoom, "say hello world"))
+
+	positions, err := hub.ResolveAnchors(context.Background(), testRoom, codeTextName, anchors)
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 15, -1}, positions)
+
+	// 评论的变化推送给在线成员
+	thread := &models.RoomCommentThread{ID: 1, RoomID: testRoom.ID, Quote: "world"}
+	require.NoError(t, hub.BroadcastComment(context.Background(), testRoom, thread))
+	var frame commentsFrame
+	readExtension(t, alice, messageComments, &frame)
+	assert.Equal(t, commentsFrameThread, frame.Type)
+	assert.Equal(t, "world", frame.Thread.Quote)
+}
@@ -0,0 +1,251 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+	"unicode/utf16"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+// defaultMaxAutoVersions 每个房间默认保留的自动版本数
+const defaultMaxAutoVersions = 100
+
+var _ service.DocumentEditor = (*Hub)(nil)
+
+// CodeText 读取房间当前的代码
+// 房间不在本节点时从存储中加载：每条更新在广播前已经写库，读到的就是最新内容
+func (h *Hub) CodeText(_ context.Context, roomModel *models.Room) (string, error) {
+	h.mu.Lock()
+	room := h.rooms[roomModel.UUID]
+	h.mu.Unlock()
+	if room == nil {
+		// 临时房间只用于读取，不注册到 Hub
+		room = newRoom(roomModel, h)
+		room.broker = nil
+	}
+	return room.currentCode()
+}
+
+// ReplaceCode 把房间代码修改为 text，作为一次普通编辑下发给所有连接
+// 恢复版本只有管理员可以执行，和管理员的编辑一样不受锁定区域限制。
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) ReplaceCode(ctx context.Context, roomModel *models.Room, text string) error {
+	return h.ReplaceFileText(ctx, roomModel, codeTextName, text, models.RoomRoleAdmin)
+}
+
+// ReplaceFileText 以角色为 role 的成员身份把工作区文件（Y.Text name）的内容修改为 text，限制同 ReplaceCode
+// 和连接上的编辑一样检查锁定区域，管理员以下的成员修改到锁定区域时返回 service.ErrRoomRegionLocked
+func (h *Hub) ReplaceFileText(_ context.Context, roomModel *models.Room, name, text, role string) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+	return room.replaceText(name, text, role)
+}
+
+// acquire 获取房间，不存在时创建并注册，用完后调用 release
+func (h *Hub) acquire(roomModel *models.Room) (*Room, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return nil, ErrHubClosed
+	}
+	room, ok := h.rooms[roomModel.UUID]
+	if !ok {
+		room = newRoom(roomModel, h)
+		room.subscribe()
+		h.rooms[roomModel.UUID] = room
+	}
+	return room, nil
+}
+
+// release 房间没有连接时回收，和最后一个连接断开时一样
+func (h *Hub) release(room *Room) {
+	h.mu.Lock()
+	empty := h.rooms[room.uuid] == room && room.isEmpty()
+	if empty {
+		delete(h.rooms, room.uuid)
+	}
+	h.mu.Unlock()
+
+	if empty {
+		room.close()
+	}
+}
+
+// ForwardToOwner 亲和模式下把需要修改房间文档的 HTTP 请求转发到房间所在节点，返回 true 表示请求已处理
+// 和协作连接的转发一样原样带上鉴权信息，由房间所在节点重新校验
+func (h *Hub) ForwardToOwner(w http.ResponseWriter, r *http.Request, roomUUID string) bool {
+	cluster := h.opts.Cluster
+	if cluster == nil {
+		return false
+	}
+	owner := cluster.Owner(roomUUID)
+	if owner == cluster.Self() {
+		return false
+	}
+	if r.Header.Get(forwardedHeader) != "" {
+		http.Error(w, ErrNotRoomOwner.Error(), http.StatusConflict)
+		return true
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: owner})
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.Warn("请求转发到房间所在节点失败", zap.String("owner", owner), zap.Error(err))
+		http.Error(w, "房间所在节点不可用", http.StatusBadGateway)
+	}
+	r.Header.Set(forwardedHeader, cluster.Self())
+	proxy.ServeHTTP(w, r)
+	return true
+}
+
+func (r *Room) isEmpty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients) == 0
+}
+
+// currentCode 读取当前的代码文本
+func (r *Room) currentCode() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		return "", err
+	}
+	return r.textLocked(codeTextName), nil
+}
+
+// replaceText 以服务端身份修改 Y.Text name：只删除和插入首尾相同部分之间的内容，
+// 其他人光标所在的未修改部分保持不动。role 低于管理员时修改不能碰到锁定区域
+func (r *Room) replaceText(name, text, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		return err
+	}
+
+	code := r.doc.GetText(name)
+	// Y.Text 的位置以 UTF-16 码元计算
+	current := utf16.Encode([]rune(code.String()))
+	target := utf16.Encode([]rune(text))
+
+	prefix := 0
+	for prefix < len(current) && prefix < len(target) && current[prefix] == target[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(current)-prefix && suffix < len(target)-prefix &&
+		current[len(current)-1-suffix] == target[len(target)-1-suffix] {
+		suffix++
+	}
+	// 不能从代理对中间拆开
+	if prefix > 0 && isHighSurrogate(current[prefix-1]) {
+		prefix--
+	}
+	if suffix > 0 && isLowSurrogate(current[len(current)-suffix]) {
+		suffix--
+	}
+
+	deleted := len(current) - prefix - suffix
+	inserted := target[prefix : len(target)-suffix]
+	if !models.RoomRoleAtLeast(role, models.RoomRoleAdmin) {
+		var edits []yjs.TextEdit
+		if deleted > 0 {
+			edits = append(edits, yjs.TextEdit{From: uint64(prefix), To: uint64(prefix + deleted)})
+		}
+		if len(inserted) > 0 {
+			edits = append(edits, yjs.TextEdit{Insert: true, From: uint64(prefix), To: uint64(prefix)})
+		}
+		if r.editTouchesLockLocked(name, edits) {
+			return service.ErrRoomRegionLocked
+		}
+	}
+
+	r.keyframeLocked()
+	var updates [][]byte
+	if deleted > 0 {
+		updates = append(updates, code.Delete(uint64(prefix), uint64(deleted)))
+	}
+	if len(inserted) > 0 {
+		updates = append(updates, code.Insert(uint64(prefix), string(utf16.Decode(inserted))))
+	}
+	for _, update := range updates {
+		r.commitServerUpdateLocked(update)
+	}
+	r.refreshTextsLocked()
+	return nil
+}
+
+// commitServerUpdateLocked 下发服务端对文档的修改：广播、写库、转发给其他节点并记录回放
+func (r *Room) commitServerUpdateLocked(update []byte) {
+	frame := yjs.EncodeUpdate(update)
+	r.broadcastLocked(frame, nil)
+	r.persistLocked(update)
+	r.publishLocked(frame)
+	r.recordLocked(models.SessionEventUpdate, nil, update)
+}
+
+// autoVersionLocked 合并快照后按间隔自动保存历史版本，内容没有变化时不保存
+// 多节点共享存储时在合并锁内执行，以存储中最近的自动版本为准，不会重复保存
+func (r *Room) autoVersionLocked(ctx context.Context) {
+	interval := time.Duration(r.cfg.VersionIntervalSeconds) * time.Second
+	if r.versions == nil || interval <= 0 || time.Since(r.lastVersionAt) < interval {
+		return
+	}
+
+	latest, err := r.versions.Latest(ctx, r.id, models.DocumentVersionAuto)
+	if err != nil {
+		logger.Error("读取历史版本失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < interval {
+		r.lastVersionAt = latest.CreatedAt
+		return
+	}
+	text, authorship := r.authorshipLocked()
+	if latest != nil && latest.Content == text || latest == nil && text == "" {
+		r.lastVersionAt = time.Now()
+		return
+	}
+
+	version := &models.DocumentVersion{
+		RoomID:     r.id,
+		Kind:       models.DocumentVersionAuto,
+		TextName:   codeTextName,
+		Content:    text,
+		Size:       len(text),
+		Authorship: authorship,
+	}
+	if err := r.versions.Create(ctx, version); err != nil {
+		logger.Error("自动保存历史版本失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	r.lastVersionAt = time.Now()
+
+	keep := r.cfg.MaxAutoVersions
+	if keep <= 0 {
+		keep = defaultMaxAutoVersions
+	}
+	if err := r.versions.PruneAuto(ctx, r.id, keep); err != nil {
+		logger.Warn("清理自动版本失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+	}
+}
+
+func isHighSurrogate(c uint16) bool { return c >= 0xd800 && c < 0xdc00 }
+func isLowSurrogate(c uint16) bool  { return c >= 0xdc00 && c < 0xe000 }
@@ -0,0 +1,119 @@
+package realtime
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_ReplaceCodeBroadcastsEdit(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, "a = 1 😀\nprint(a)")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	waitFor(t, func() bool {
+		_, updates := store.counts()
+		return updates == 1
+	})
+
+	require.NoError(t, hub.ReplaceCode(context.Background(), testRoom, "a = 2 😀\nprint(a)"))
+
+	// 在线成员作为普通编辑收到修改：删除 "1"，插入 "2"
+	for i := 0; i < 2; i++ {
+		msg := readMessage(t, alice)
+		assert.Equal(t, uint64(yjs.SyncUpdate), msg.SubType)
+	}
+	assert.Equal(t, "a = 2 😀\nprint(a)", syncText(t, alice))
+
+	text, err := hub.CodeText(context.Background(), testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, "a = 2 😀\nprint(a)", text)
+}
+
+func TestHub_ReplaceCodeWithoutConnections(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store})
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", StarterCode: "x"}
+
+	require.NoError(t, hub.ReplaceCode(context.Background(), room, "y"))
+
+	// 修改已经写入存储，房间用完后回收
+	waitFor(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.rooms) == 0
+	})
+	text, err := hub.CodeText(context.Background(), room)
+	require.NoError(t, err)
+	assert.Equal(t, "y", text)
+}
+
+func TestHub_FileTextsFollowsEdits(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+	ctx := context.Background()
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	client := yjs.NewDoc(yjs.Options{})
+	send := func(update []byte) {
+		require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+	send(client.GetText(codeTextName).Insert(0, "import util"))
+	send(client.GetText("file-1").Insert(0, "def f(): pass"))
+
+	names := []string{codeTextName, "file-1"}
+	waitFor(t, func() bool {
+		texts, err := hub.FileTexts(ctx, testRoom, names)
+		require.NoError(t, err)
+		return texts["file-1"] == "def f(): pass"
+	})
+
+	// 之后的修改随更新直接应用到缓存上，不需要读取
+	hub.mu.Lock()
+	room := hub.rooms[testRoom.UUID]
+	hub.mu.Unlock()
+	cached := func() map[string]string {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return maps.Clone(room.texts)
+	}
+	send(client.GetText("file-1").Insert(13, "\n"))
+	send(client.GetText(codeTextName).Insert(0, "😀 "))
+	send(client.GetText(codeTextName).Delete(3, 1))
+	waitFor(t, func() bool {
+		return cached()[codeTextName] == "😀 mport util"
+	})
+	assert.Equal(t, map[string]string{codeTextName: "😀 mport util", "file-1": "def f(): pass\n"}, cached())
+
+	// 服务端的修改同样刷新视图
+	require.NoError(t, hub.ReplaceFileText(ctx, testRoom, "file-1", "def g(): pass", models.RoomRoleMember))
+	texts, err := hub.FileTexts(ctx, testRoom, names)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{codeTextName: "😀 mport util", "file-1": "def g(): pass"}, texts)
+}
+
+func TestApplyTextDelta(t *testing.T) {
+	text, ok := applyTextDelta("a😀b", yjs.TextDelta{Index: 3, Insert: "é"})
+	require.True(t, ok)
+	assert.Equal(t, "a😀éb", text)
+	text, ok = applyTextDelta(text, yjs.TextDelta{Index: 1, Delete: 2})
+	require.True(t, ok)
+	assert.Equal(t, "aéb", text)
+
+	// 代理对中间、超出范围时无法应用，调用方重新解码
+	_, ok = applyTextDelta("a😀b", yjs.TextDelta{Index: 2, Insert: "x"})
+	assert.False(t, ok)
+	_, ok = applyTextDelta("ab", yjs.TextDelta{Index: 1, Delete: 2})
+	assert.False(t, ok)
+}
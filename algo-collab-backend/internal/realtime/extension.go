@@ -0,0 +1,53 @@
+package realtime
+
+import (
+	"encoding/json"
+
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+)
+
+// y-websocket 的消息类型是可扩展的：前端在 provider.messageHandlers[type] 注册处理函数。
+// 协作连接上除了文档同步和 awareness，还承载以下扩展消息，
+// 消息体都是一个变长字符串，内容为 JSON
+const (
+	messageChat = 100 // 聊天，见 chatFrame
+	messageRTC  = 101 // 语音和屏幕共享的 WebRTC 信令，见 rtcFrame
+	// 演示者：控制消息和视图分开，视图是可以丢弃的临时状态
+	messagePresenter     = 102 // 见 presenterFrame
+	messagePresenterView = 103 // 见 presenterView
+	messageFiles         = 104 // 工作区文件树变化，见 filesFrame
+	messageComments      = 105 // 代码评论变化，见 commentsFrame
+	messageLocks         = 106 // 锁定区域变化和编辑被拒绝的通知，见 locksFrame
+	// 共享终端：控制消息和输出分开，输出和视图一样在发送队列满时丢弃
+	messageTerminal       = 107 // 见 terminalFrame
+	messageTerminalOutput = 108 // 终端输出，也是 terminalFrame
+	messageWhiteboards    = 109 // 白板列表变化，见 whiteboardsFrame
+	messageMembers        = 110 // 成员变化，只在节点之间转发，见 membersFrame
+)
+
+// isMessageType 判断二进制消息是否为 msgType 类型
+func isMessageType(data []byte, msgType uint64) bool {
+	t, err := yjs.NewDecoder(data).ReadVarUint()
+	return err == nil && t == msgType
+}
+
+func encodeJSONMessage(msgType uint64, v any) []byte {
+	payload, _ := json.Marshal(v)
+	e := yjs.NewEncoder()
+	e.WriteVarUint(msgType)
+	e.WriteVarString(string(payload))
+	return e.Bytes()
+}
+
+// decodeJSONMessage 跳过消息类型，把 JSON 消息体解析到 v
+func decodeJSONMessage(data []byte, v any) error {
+	d := yjs.NewDecoder(data)
+	if _, err := d.ReadVarUint(); err != nil {
+		return err
+	}
+	payload, err := d.ReadVarString()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(payload), v)
+}
@@ -0,0 +1,45 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+)
+
+var _ service.WorkspaceEditor = (*Hub)(nil)
+
+// filesFrameTree 服务端 → 客户端：文件树变化后的完整文件列表
+const filesFrameTree = "tree"
+
+// filesFrame 工作区文件树消息的 JSON 结构
+// 文件树由 HTTP 接口修改，修改后推送给房间内的所有连接；客户端连接后先通过接口获取一次文件列表
+type filesFrame struct {
+	Type  string             `json:"type"`
+	Files []*models.RoomFile `json:"files"`
+}
+
+func isFilesFrame(data []byte) bool {
+	return isMessageType(data, messageFiles)
+}
+
+// BroadcastFiles 把新的文件列表推送给房间内所有节点上的连接
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) BroadcastFiles(_ context.Context, roomModel *models.Room, files []*models.RoomFile) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+
+	frame := encodeJSONMessage(messageFiles, &filesFrame{Type: filesFrameTree, Files: files})
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.broadcastLocked(frame, nil)
+	room.publishLocked(frame)
+	return nil
+}
@@ -0,0 +1,436 @@
+// Package realtime 实现 y-websocket 兼容的实时协作服务
+//
+// 每个协作房间对应一个 Room，Room 内的所有连接共享同一份文档和 awareness 状态。
+// Hub 负责按房间 UUID 创建/回收 Room、定期持久化文档，以及把 HTTP 连接升级为 WebSocket。
+// 房间的每块白板也是一份独立的文档，由键为 <房间 UUID>/whiteboards/<白板 ID> 的 Room 承载。
+// 多节点部署时，同一房间的连接可能分布在不同节点上，各节点通过 Broker（Redis pub/sub）互相转发消息。
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrHubClosed 服务正在关闭，不再接受新连接
+	ErrHubClosed = errors.New("协作服务正在关闭")
+	// ErrTooManyConnections 用户或房间的连接数超出限制
+	ErrTooManyConnections = errors.New("连接数超出限制")
+)
+
+// tooManyConnectionsRetry 连接数超限时建议客户端等待的时间
+const tooManyConnectionsRetry = 10 * time.Second
+
+// Options Hub 配置
+type Options struct {
+	AllowOrigins []string // 允许的 Origin，包含 "*" 时不校验
+	// WebSocket 心跳、缓冲、连接数限制和关闭参数
+	WebSocket config.WebSocketConfig
+	// Documents 文档存储，为空时文档只保存在内存中，房间回收后丢失
+	Documents repository.DocumentRepository
+	Document  config.DocumentConfig
+	// Whiteboards 白板文档存储，为空时白板只保存在内存中；Whiteboard 中的 MaxSizeKB 是每块白板的大小限制
+	Whiteboards repository.WhiteboardDocumentRepository
+	Whiteboard  config.WhiteboardConfig
+	// 多节点部署有两种模式：
+	//   Broker 广播模式，每个节点都持有房间文档，通过 Redis pub/sub 互相转发消息
+	//   Cluster 亲和模式，房间由一致性哈希选出的节点独占，其他节点只转发连接
+	// 都为空表示单节点
+	Broker  Broker
+	Cluster *Cluster
+	// Locker 多节点共享文档存储时合并快照用的分布式锁
+	Locker Locker
+	// Versions 历史版本存储，为空时不自动保存版本
+	Versions repository.DocumentVersionRepository
+	// Authors 文档作者（Yjs 客户端对应的用户），为空时不记录作者
+	Authors repository.DocumentAuthorRepository
+	// Locks 锁定区域存储，为空时不限制编辑
+	Locks repository.RoomLockRepository
+	// Chat 聊天消息的校验和持久化，为空时不处理聊天消息
+	Chat       ChatService
+	ChatConfig config.ChatConfig
+	// RTC 语音和屏幕共享的 ICE 服务器和人数限制
+	RTC config.RTCConfig
+	// Terminal 运行共享终端的代码执行沙箱，为空或 TerminalConfig 未开启时不能开启终端
+	Terminal       TerminalSandbox
+	TerminalConfig config.TerminalConfig
+	// Activity 编辑和聊天时刷新房间的活跃时间，为空时不刷新
+	Activity ActivityRecorder
+	// Events 房间审计日志，开启共享终端（运行代码）时记录 code_run，为空时不记录
+	Events repository.RoomEventRepository
+	// Sessions 回放会话存储，为空时不记录回放
+	Sessions repository.RoomSessionRepository
+	Replay   config.ReplayConfig
+}
+
+// User 连接对应的登录用户
+type User struct {
+	ID       uint
+	Username string
+	Role     string // 房间角色，决定能否接管演示者
+}
+
+// Hub 管理所有协作房间
+type Hub struct {
+	opts     Options
+	limits   connLimits
+	upgrader websocket.Upgrader
+	// nodeID 当前节点的标识，用于忽略自己发布到 Redis 的消息
+	nodeID string
+
+	mu        sync.Mutex
+	rooms     map[string]*Room
+	userConns map[uint]int                 // 每个用户在本节点的连接数
+	proxies   map[*websocket.Conn]struct{} // 转发到其他节点的客户端连接
+	closing   bool
+
+	// presence 生成成员加入/离开的系统消息
+	presence *chatPresence
+	// recorder 记录回放事件，为空表示不记录
+	recorder         *recorder
+	keyframeInterval time.Duration
+
+	// writers 仍在运行的 writePump，关闭服务时等待关闭帧发送完
+	writers sync.WaitGroup
+}
+
+// NewHub 创建 Hub
+func NewHub(opts Options) *Hub {
+	h := &Hub{
+		opts:      opts,
+		limits:    newConnLimits(opts.WebSocket),
+		nodeID:    uuid.NewString(),
+		rooms:     make(map[string]*Room),
+		userConns: make(map[uint]int),
+		proxies:   make(map[*websocket.Conn]struct{}),
+		presence:  newChatPresence(time.Duration(opts.ChatConfig.PresenceGraceSeconds) * time.Second),
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     h.checkOrigin,
+	}
+	if opts.Sessions != nil {
+		gap := time.Duration(opts.Replay.SessionGapSeconds) * time.Second
+		if gap <= 0 {
+			gap = defaultSessionGap
+		}
+		h.keyframeInterval = time.Duration(opts.Replay.KeyframeIntervalSeconds) * time.Second
+		if h.keyframeInterval <= 0 {
+			h.keyframeInterval = defaultKeyframeInterval
+		}
+		h.recorder = newRecorder(opts.Sessions, gap)
+	}
+	if opts.Cluster != nil {
+		opts.Cluster.OnChange(h.rebalance)
+	}
+	return h
+}
+
+// checkOrigin 按 CORS 白名单校验 Origin；非浏览器客户端没有 Origin，直接放行
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.opts.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Start 启动定期合并文档快照的后台任务，ctx 取消后停止
+func (h *Hub) Start(ctx context.Context) {
+	interval := time.Duration(h.opts.Document.CompactIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.compactAll()
+			}
+		}
+	}()
+}
+
+// compactAll 合并所有在线房间中有修改的文档
+func (h *Hub) compactAll() {
+	h.mu.Lock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.compact()
+	}
+}
+
+// rebalance 成员变化后，把不再归本节点所有的房间交给新节点
+// 先从 Hub 中摘除，之后的连接会被转发到新节点；再断开现有连接并把文档合并写库
+func (h *Hub) rebalance() {
+	h.mu.Lock()
+	var moved []*Room
+	for key, room := range h.rooms {
+		// 白板跟随所属房间
+		if !h.opts.Cluster.IsOwner(roomOf(key)) {
+			moved = append(moved, room)
+			delete(h.rooms, key)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, room := range moved {
+		room.drain(websocket.CloseServiceRestart, reconnectHint("room_moved", 0))
+		logger.Info("协作房间已迁出",
+			zap.String("room_uuid", room.uuid),
+			zap.String("owner", h.opts.Cluster.Owner(roomOf(room.uuid))))
+	}
+}
+
+// Serve 升级连接并加入房间，readOnly 的连接只能接收更新
+// 亲和模式下房间不归本节点所有时转发到房间所在节点
+// 升级失败时 upgrader 已经写回了 HTTP 错误
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, roomModel *models.Room, user User, readOnly bool) error {
+	return h.serve(w, r, roomModel.UUID, func() *Room { return newRoom(roomModel, h) }, user, readOnly)
+}
+
+// serve 把连接加入 Hub 中键为 key 的房间或白板文档，不存在时用 create 创建
+func (h *Hub) serve(w http.ResponseWriter, r *http.Request, key string, create func() *Room, user User, readOnly bool) error {
+	if h.isClosing() {
+		http.Error(w, ErrHubClosed.Error(), http.StatusServiceUnavailable)
+		return ErrHubClosed
+	}
+	if cluster := h.opts.Cluster; cluster != nil {
+		if owner := cluster.Owner(roomOf(key)); owner != cluster.Self() {
+			if r.Header.Get(forwardedHeader) != "" {
+				http.Error(w, ErrNotRoomOwner.Error(), http.StatusConflict)
+				return ErrNotRoomOwner
+			}
+			return h.proxy(w, r, owner)
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(conn, user, readOnly, h.limits)
+	h.writers.Add(1)
+	go func() {
+		defer h.writers.Done()
+		client.writePump()
+	}()
+
+	room, err := h.join(key, create, client)
+	if err != nil {
+		// 客户端还没加入任何房间，可以直接关闭
+		if errors.Is(err, ErrHubClosed) {
+			client.closeWith(websocket.CloseServiceRestart, h.shutdownHint())
+		} else {
+			client.closeWith(websocket.CloseTryAgainLater, reconnectHint("too_many_connections", tooManyConnectionsRetry))
+		}
+		logger.BusinessWarn("协作连接被拒绝",
+			zap.String("room_uuid", key),
+			zap.Uint("user_id", user.ID),
+			zap.String("error", err.Error()))
+		return err
+	}
+
+	logger.Info("协作连接建立",
+		zap.String("room_uuid", key),
+		zap.Uint("user_id", user.ID),
+		zap.Bool("read_only", readOnly))
+
+	go client.readPump(func(data []byte) {
+		room.handleMessage(client, data)
+	}, func() {
+		h.leave(room, client)
+	})
+
+	room.greet(client)
+
+	// 只读连接（归档房间）和白板连接不产生加入/离开消息
+	if room.chat != nil && !readOnly && h.presence.enter(presenceKey{room.uuid, user.ID}) {
+		h.postSystemMessage(room.id, room.uuid, joinedMessage(user.Username))
+	}
+	return nil
+}
+
+// join 把客户端加入房间，房间不存在时创建
+// 连接数限制只统计本节点：亲和模式下房间的连接都在同一个节点上，房间上限就是全局上限；
+// 广播模式下每个节点各自计数，一个房间最多可以有 节点数 × MaxConnectionsPerRoom 个连接
+func (h *Hub) join(key string, create func() *Room, client *Client) (*Room, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return nil, ErrHubClosed
+	}
+	userID := client.user.ID
+	if max := h.opts.WebSocket.MaxConnectionsPerUser; max > 0 && h.userConns[userID] >= max {
+		return nil, ErrTooManyConnections
+	}
+
+	room, ok := h.rooms[key]
+	if !ok {
+		room = create()
+		room.subscribe()
+		h.rooms[key] = room
+	}
+	if !room.tryAdd(client, h.opts.WebSocket.MaxConnectionsPerRoom) {
+		return nil, ErrTooManyConnections
+	}
+	h.userConns[userID]++
+	return room, nil
+}
+
+// leave 客户端断开后移出房间，房间空了就合并文档并回收
+// 合并在 Hub 锁外进行：期间重新加入的客户端会创建新 Room，
+// 它从数据库加载时增量日志中已经有全部修改
+func (h *Hub) leave(room *Room, client *Client) {
+	h.mu.Lock()
+	if h.userConns[client.user.ID]--; h.userConns[client.user.ID] <= 0 {
+		delete(h.userConns, client.user.ID)
+	}
+	// 迁出的房间已经从 Hub 摘除，同名的可能是之后新建的房间
+	empty := room.remove(client) && h.rooms[room.uuid] == room
+	if empty {
+		delete(h.rooms, room.uuid)
+	}
+	// 不在 Hub 中说明房间已迁出或服务正在关闭，用户会立即重连，不发送离开消息
+	drained := !empty && h.rooms[room.uuid] != room
+	h.mu.Unlock()
+
+	if room.chat != nil && !client.readOnly {
+		var announce func()
+		if !drained {
+			announce = func() {
+				h.postSystemMessage(room.id, room.uuid, leftMessage(client.user.Username))
+			}
+		}
+		h.presence.exit(presenceKey{room.uuid, client.user.ID}, announce)
+	}
+
+	if empty {
+		room.close()
+		logger.Debug("协作房间已回收", zap.String("room_uuid", room.uuid))
+	}
+}
+
+func (h *Hub) isClosing() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closing
+}
+
+// trackProxy 记录转发连接，关闭服务时统一断开；服务正在关闭时返回 false
+func (h *Hub) trackProxy(conn *websocket.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return false
+	}
+	h.proxies[conn] = struct{}{}
+	return true
+}
+
+func (h *Hub) untrackProxy(conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.proxies, conn)
+}
+
+// Shutdown 关闭服务：不再接受新连接，用 1012 和重连提示断开所有连接，并把文档合并写库
+// 等待关闭帧发送完或 ctx 超时后返回
+func (h *Hub) Shutdown(ctx context.Context) {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+	h.presence.stop()
+
+	// 1. 亲和模式先退出成员表，房间由其他节点接管
+	if h.opts.Cluster != nil {
+		if err := h.opts.Cluster.Leave(ctx); err != nil {
+			logger.Warn("退出集群成员表失败", zap.Error(err))
+		}
+	}
+
+	// 2. 断开剩余的连接
+	h.mu.Lock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.rooms = make(map[string]*Room)
+	proxies := make([]*websocket.Conn, 0, len(h.proxies))
+	for conn := range h.proxies {
+		proxies = append(proxies, conn)
+	}
+	h.mu.Unlock()
+
+	hint := h.shutdownHint()
+	for _, room := range rooms {
+		room.drain(websocket.CloseServiceRestart, hint)
+	}
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseServiceRestart, hint)
+	for _, conn := range proxies {
+		_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(h.limits.writeTimeout))
+		conn.Close()
+	}
+
+	// 3. 写入剩余的回放事件，等待发送队列中的关闭帧写完
+	if h.recorder != nil {
+		h.recorder.stop(ctx)
+	}
+	done := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("等待协作连接关闭超时")
+	}
+	logger.Info("协作服务已关闭", zap.Int("rooms", len(rooms)), zap.Int("proxies", len(proxies)))
+}
+
+// shutdownHint 服务关闭时的重连提示
+func (h *Hub) shutdownHint() string {
+	return reconnectHint("shutdown", time.Duration(h.opts.WebSocket.ReconnectDelaySeconds)*time.Second)
+}
+
+func (h *Hub) shutdownCloseMessage() []byte {
+	return websocket.FormatCloseMessage(websocket.CloseServiceRestart, h.shutdownHint())
+}
+
+// reconnectHint 关闭帧的原因：机器可读的 JSON，说明断开原因和建议的重连等待时间
+// 关闭帧的原因最长 123 字节，这里的内容远小于上限
+func reconnectHint(reason string, retryAfter time.Duration) string {
+	return fmt.Sprintf(`{"reason":%q,"retry_after_ms":%d}`, reason, retryAfter.Milliseconds())
+}
@@ -0,0 +1,368 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// 上一个用例的连接可能还在后台退出，日志只在这里设置一次
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// memoryDocumentStore 内存中的文档存储
+type memoryDocumentStore struct {
+	mu       sync.Mutex
+	snapshot []byte
+	updates  []*models.DocumentUpdate
+	nextID   uint
+}
+
+func (s *memoryDocumentStore) Load(_ context.Context, roomID uint) (*models.Document, []*models.DocumentUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updates := append([]*models.DocumentUpdate(nil), s.updates...)
+	if s.snapshot == nil {
+		return nil, updates, nil
+	}
+	return &models.Document{RoomID: roomID, Snapshot: s.snapshot}, updates, nil
+}
+
+func (s *memoryDocumentStore) AppendUpdate(_ context.Context, roomID uint, data []byte) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(roomID, data), nil
+}
+
+func (s *memoryDocumentStore) appendLocked(roomID uint, data []byte) uint {
+	s.nextID++
+	s.updates = append(s.updates, &models.DocumentUpdate{ID: s.nextID, RoomID: roomID, Data: data})
+	return s.nextID
+}
+
+func (s *memoryDocumentStore) Compact(_ context.Context, roomID uint, snapshot []byte, uptoID uint, pending [][]byte) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot = snapshot
+	kept := s.updates[:0]
+	for _, update := range s.updates {
+		if update.ID > uptoID {
+			kept = append(kept, update)
+		}
+	}
+	s.updates = kept
+
+	lastID := uptoID
+	for _, data := range pending {
+		lastID = s.appendLocked(roomID, data)
+	}
+	return lastID, nil
+}
+
+func (s *memoryDocumentStore) counts() (hasSnapshot bool, updates int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot != nil, len(s.updates)
+}
+
+var testRoom = &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1"}
+
+func newTestServer(t *testing.T, hub *Hub) string {
+	return newTestServerForRoom(t, hub, testRoom)
+}
+
+func newTestServerForRoom(t *testing.T, hub *Hub, room *models.Room) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readOnly := r.URL.Query().Get("readonly") == "1"
+		user := User{ID: 1}
+		if id, err := strconv.Atoi(r.URL.Query().Get("user")); err == nil {
+			user.ID = uint(id)
+		}
+		user.Username = fmt.Sprintf("user-%d", user.ID)
+		user.Role = r.URL.Query().Get("role")
+		_ = hub.Serve(w, r, room, user, readOnly)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readMessage 读取下一条消息并解析
+func readMessage(t *testing.T, conn *websocket.Conn) *yjs.Message {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	msg, err := yjs.ParseMessage(data)
+	require.NoError(t, err)
+	return msg
+}
+
+// syncText 以空状态向量请求完整文档，返回其中的代码文本
+func syncText(t *testing.T, conn *websocket.Conn) string {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, yjs.EncodeSyncStep1(yjs.EmptyStateVector)))
+	msg := readMessage(t, conn)
+	require.Equal(t, uint64(yjs.SyncStep2), msg.SubType)
+
+	doc := yjs.NewDoc(yjs.Options{})
+	require.NoError(t, doc.ApplyUpdate(msg.Payload))
+	return doc.GetText(codeTextName).String()
+}
+
+// waitFor 等待异步条件成立（例如断开连接后的合并）
+func waitFor(t *testing.T, cond func() bool) {
+	require.Eventually(t, cond, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHub_RelaysUpdatesAndSyncsLateJoiners(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{}))
+
+	alice := dial(t, url)
+	greeting := readMessage(t, alice)
+	assert.Equal(t, uint64(yjs.SyncStep1), greeting.SubType)
+
+	bob := dial(t, url)
+	readMessage(t, bob) // SyncStep1
+
+	// alice 的更新转发给 bob
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, "a")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+
+	msg := readMessage(t, bob)
+	assert.Equal(t, uint64(yjs.SyncUpdate), msg.SubType)
+	assert.Equal(t, update, msg.Payload)
+
+	// 后加入的 carol 同步时在 SyncStep2 中收到完整文档
+	carol := dial(t, url)
+	readMessage(t, carol)
+	assert.Equal(t, "a", syncText(t, carol))
+}
+
+func TestHub_PersistsDocumentAcrossRoomReload(t *testing.T) {
+	store := &memoryDocumentStore{}
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", StarterCode: "def solve():"}
+	url := newTestServerForRoom(t, NewHub(Options{Documents: store}), room)
+
+	// 全新房间用初始代码初始化
+	alice := dial(t, url)
+	readMessage(t, alice)
+	assert.Equal(t, "def solve():", syncText(t, alice))
+
+	local := yjs.NewDoc(yjs.Options{})
+	update := local.GetText(codeTextName).Insert(0, "# hi\n")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	waitFor(t, func() bool {
+		_, updates := store.counts()
+		return updates == 2
+	})
+
+	// 最后一个人离开后合并为快照并清空增量
+	alice.Close()
+	waitFor(t, func() bool {
+		hasSnapshot, updates := store.counts()
+		return hasSnapshot && updates == 0
+	})
+
+	// 重新加入时从存储恢复，且不会重复写入初始代码
+	bob := dial(t, url)
+	readMessage(t, bob)
+	text := syncText(t, bob)
+	assert.Contains(t, text, "# hi\n")
+	assert.Contains(t, text, "def solve():")
+	assert.Len(t, text, len("# hi\ndef solve():"))
+}
+
+func TestHub_CompactsEveryNUpdates(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store, Document: config.DocumentConfig{CompactEveryUpdates: 3}})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+
+	text := yjs.NewDoc(yjs.Options{}).GetText(codeTextName)
+	for i := 0; i < 4; i++ {
+		update := text.Insert(text.Length(), "x")
+		require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+
+	waitFor(t, func() bool {
+		hasSnapshot, updates := store.counts()
+		return hasSnapshot && updates == 1
+	})
+	assert.Equal(t, "xxxx", syncText(t, alice))
+}
+
+func TestHub_RejectsUpdatesOverSizeLimit(t *testing.T) {
+	hub := NewHub(Options{Document: config.DocumentConfig{MaxSizeKB: 1}})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, strings.Repeat("x", 2048))
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+
+	_ = alice.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := alice.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseMessageTooBig, closeErr.Code)
+}
+
+func TestHub_ReadOnlyClientCannotWrite(t *testing.T) {
+	hub := NewHub(Options{})
+	url := newTestServer(t, hub)
+
+	viewer := dial(t, url+"?readonly=1")
+	readMessage(t, viewer)
+	require.NoError(t, viewer.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate([]byte{0, 0})))
+
+	// 同步请求在更新之后处理，如果更新被接受会先收到它
+	require.NoError(t, viewer.WriteMessage(websocket.BinaryMessage, yjs.EncodeSyncStep1(yjs.EmptyStateVector)))
+	msg := readMessage(t, viewer)
+	assert.Equal(t, uint64(yjs.SyncStep2), msg.SubType)
+}
+
+func TestHub_IgnoresSpoofedAwareness(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	bob := dial(t, url)
+	readMessage(t, bob)
+	carol := dial(t, url)
+	readMessage(t, carol)
+	sendAwareness := func(conn *websocket.Conn, entries ...yjs.AwarenessEntry) []byte {
+		update := yjs.EncodeAwarenessUpdate(entries)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, yjs.EncodeAwarenessMessage(update)))
+		return update
+	}
+	readAwareness := func() []yjs.AwarenessEntry {
+		msg := readMessage(t, carol)
+		require.Equal(t, uint64(yjs.MessageAwareness), msg.Type)
+		entries, err := yjs.DecodeAwarenessUpdate(msg.Payload)
+		require.NoError(t, err)
+		return entries
+	}
+
+	alice7 := yjs.AwarenessEntry{ClientID: 7, Clock: 1, State: []byte(`{"user":"alice"}`)}
+	sendAwareness(alice, alice7)
+	assert.Equal(t, []yjs.AwarenessEntry{alice7}, readAwareness())
+
+	// 1. bob 冒用 alice 的 clientID：只转发他自己的条目
+	bob8 := yjs.AwarenessEntry{ClientID: 8, Clock: 1, State: []byte(`{"user":"bob"}`)}
+	sendAwareness(bob, yjs.AwarenessEntry{ClientID: 7, Clock: 2, State: []byte(`{"user":"mallory"}`)}, bob8)
+	assert.Equal(t, []yjs.AwarenessEntry{bob8}, readAwareness())
+
+	// 2. 全部是伪造的条目（包括移除 alice）时整条消息丢弃，carol 下一条收到的是 alice 的更新
+	sendAwareness(bob, yjs.AwarenessEntry{ClientID: 7, Clock: 3, State: []byte(`null`)})
+	alice7.Clock = 2
+	sendAwareness(alice, alice7)
+	assert.Equal(t, []yjs.AwarenessEntry{alice7}, readAwareness())
+}
+
+// expectClose 读取到关闭帧并返回关闭码和原因
+func expectClose(t *testing.T, conn *websocket.Conn) *websocket.CloseError {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	return closeErr
+}
+
+func TestHub_EnforcesConnectionLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.WebSocketConfig
+		allowed int
+	}{
+		{name: "每个房间的连接数", cfg: config.WebSocketConfig{MaxConnectionsPerRoom: 1}, allowed: 1},
+		{name: "每个用户的连接数", cfg: config.WebSocketConfig{MaxConnectionsPerUser: 2}, allowed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newTestServer(t, NewHub(Options{WebSocket: tt.cfg}))
+			conns := make([]*websocket.Conn, tt.allowed)
+			for i := range conns {
+				conns[i] = dial(t, url)
+				readMessage(t, conns[i])
+			}
+
+			// 超出限制的连接收到 1013 和重连提示
+			closeErr := expectClose(t, dial(t, url))
+			assert.Equal(t, websocket.CloseTryAgainLater, closeErr.Code)
+			assert.JSONEq(t, `{"reason":"too_many_connections","retry_after_ms":10000}`, closeErr.Text)
+
+			// 已有连接断开后名额释放
+			conns[0].Close()
+			waitFor(t, func() bool {
+				conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+				if err != nil {
+					return false
+				}
+				defer conn.Close()
+				_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+				_, _, err = conn.ReadMessage()
+				return err == nil
+			})
+		})
+	}
+}
+
+func TestHub_ShutdownClosesWithReconnectHint(t *testing.T) {
+	store := &memoryDocumentStore{}
+	hub := NewHub(Options{Documents: store, WebSocket: config.WebSocketConfig{ReconnectDelaySeconds: 3}})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	update := yjs.NewDoc(yjs.Options{}).GetText(codeTextName).Insert(0, "saved")
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	expectSyncDone(t, alice)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	hub.Shutdown(ctx)
+
+	closeErr := expectClose(t, alice)
+	assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+	assert.JSONEq(t, `{"reason":"shutdown","retry_after_ms":3000}`, closeErr.Text)
+
+	// 关闭时文档已合并写入快照
+	hasSnapshot, _ := store.counts()
+	assert.True(t, hasSnapshot)
+
+	// 之后的新连接直接被拒绝
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Locker 房间级的分布式锁
+// 多个节点共享同一份文档存储时，合并快照必须互斥，否则后写入的快照会覆盖其他节点的修改
+type Locker interface {
+	// TryLock 获取锁，已被其他节点持有时返回 false
+	TryLock(ctx context.Context, roomUUID string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+const roomLockPrefix = "collab:lock:"
+
+// unlockScript 只释放自己持有的锁，避免锁过期后误删其他节点的锁
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type redisLocker struct {
+	rdb *redis.Client
+}
+
+// NewRedisLocker 创建基于 Redis SET NX 的 Locker
+func NewRedisLocker(rdb *redis.Client) Locker {
+	return &redisLocker{rdb: rdb}
+}
+
+func (l *redisLocker) TryLock(ctx context.Context, roomUUID string, ttl time.Duration) (func(), bool, error) {
+	key := roomLockPrefix + roomUUID
+	token := uuid.NewString()
+
+	ok, err := l.rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		if err := unlockScript.Run(ctx, l.rdb, []string{key}, token).Err(); err != nil && !errors.Is(err, redis.Nil) {
+			logger.Warn("释放房间锁失败", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return unlock, true, nil
+}
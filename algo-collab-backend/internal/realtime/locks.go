@@ -0,0 +1,217 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+var _ service.LockEditor = (*Hub)(nil)
+
+// 锁定区域消息的 type 字段，只由服务端发送
+const (
+	locksFrameList     = "locks"    // 锁定区域变化后的完整列表
+	locksFrameRejected = "rejected" // 发给编辑被拒绝的连接
+)
+
+var (
+	errLockedRegion = service.ErrRoomRegionLocked
+	// errLockResync 之前的编辑已被拒绝，客户端的文档和服务端不再一致
+	errLockResync = errors.New("编辑已被拒绝，请重新同步文档")
+)
+
+// locksFrame 锁定区域消息的 JSON 结构
+// 收到 rejected 后客户端本地的文档已经包含被拒绝的编辑，需要丢弃本地文档重新连接同步
+type locksFrame struct {
+	Type   string             `json:"type"`
+	Locks  []*models.RoomLock `json:"locks,omitempty"`
+	LockID uint               `json:"lock_id,omitempty"`
+	Label  string             `json:"label,omitempty"`
+	Error  string             `json:"error,omitempty"`
+}
+
+func isLocksFrame(data []byte) bool {
+	return isMessageType(data, messageLocks)
+}
+
+// SetLocks 更新房间的锁定区域，并推送给房间内所有节点上的连接
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) SetLocks(_ context.Context, roomModel *models.Room, locks []*models.RoomLock) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+
+	frame := encodeJSONMessage(messageLocks, &locksFrame{Type: locksFrameList, Locks: locks})
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.locks = locks
+	room.broadcastLocked(frame, nil)
+	room.publishLocked(frame)
+	return nil
+}
+
+// loadLocksLocked 加载房间时读取锁定区域，读取失败时不限制编辑
+func (r *Room) loadLocksLocked() {
+	if r.lockRepo == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	locks, err := r.lockRepo.ListByRoom(ctx, r.id)
+	if err != nil {
+		logger.Error("读取锁定区域失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	r.locks = locks
+}
+
+// handleRemoteLocks 其他节点修改了锁定区域：更新本节点的列表并推送给本节点的连接
+func (r *Room) handleRemoteLocks(frame []byte) {
+	var msg locksFrame
+	if err := decodeJSONMessage(frame, &msg); err != nil || msg.Type != locksFrameList {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locks = msg.Locks
+	r.broadcastLocked(frame, nil)
+}
+
+// checkLocksLocked 管理员以下的成员不能修改锁定区域，返回编辑是否被拒绝及被修改的区域
+// 只解析更新本身：删除的区间与锁定区域重叠、或插入位置落在区域内部即拒绝，区域边界外的输入不受影响。
+// 被拒绝后客户端本地仍保留这次编辑，之后的编辑可能依赖它，在重新连接前一并拒绝
+func (r *Room) checkLocksLocked(client *Client, update []byte) (*models.RoomLock, bool) {
+	if len(r.locks) == 0 || models.RoomRoleAtLeast(client.user.Role, models.RoomRoleAdmin) {
+		return nil, false
+	}
+	if client.lockRejected {
+		return nil, true
+	}
+	lock, rejected := r.touchedLockLocked(update)
+	client.lockRejected = rejected
+	return lock, rejected
+}
+
+// touchedLockLocked 更新是否修改锁定区域，返回被修改的区域
+// 缺少依赖的更新暂时无法定位，之后合入时会绕过检查，直接拒绝（此时返回的区域为空）。
+// 文档中已有其他人等待依赖的更新时也一样，否则可以先发送越过锁定区域的乱序更新，再用无害的更新补齐
+func (r *Room) touchedLockLocked(update []byte) (*models.RoomLock, bool) {
+	edits := make(map[string][]yjs.TextEdit)
+	for _, lock := range r.locks {
+		if _, ok := edits[lock.TextName]; ok {
+			continue
+		}
+		textEdits, ok, err := r.doc.PreviewTextEdits(update, lock.TextName)
+		if err != nil {
+			// 无效的更新由 ApplyUpdate 丢弃
+			return nil, false
+		}
+		if !ok {
+			return nil, true
+		}
+		edits[lock.TextName] = textEdits
+	}
+
+	for _, lock := range r.locks {
+		if lockTouched(r.doc, lock, edits[lock.TextName]) {
+			return lock, true
+		}
+	}
+	return nil, false
+}
+
+// editTouchesLockLocked 服务端对 Y.Text name 的修改是否落在锁定区域内
+func (r *Room) editTouchesLockLocked(name string, edits []yjs.TextEdit) bool {
+	for _, lock := range r.locks {
+		if lock.TextName == name && lockTouched(r.doc, lock, edits) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockTouched 修改中是否有落在锁定区域内的，锚点无法解析的区域不限制
+func lockTouched(doc *yjs.Doc, lock *models.RoomLock, edits []yjs.TextEdit) bool {
+	region, ok := resolveLockedRegion(doc, lock)
+	if !ok {
+		return false
+	}
+	for _, edit := range edits {
+		if region.touches(edit) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectEditLocked 通知连接编辑被拒绝
+func (r *Room) rejectEditLocked(client *Client, lock *models.RoomLock) {
+	frame := &locksFrame{Type: locksFrameRejected, Error: errLockResync.Error()}
+	if lock != nil {
+		frame.LockID, frame.Label, frame.Error = lock.ID, lock.Label, errLockedRegion.Error()
+	}
+	logger.BusinessWarn("编辑锁定区域被拒绝",
+		zap.String("room_uuid", r.uuid),
+		zap.Uint("user_id", client.user.ID),
+		zap.Uint("lock_id", frame.LockID))
+	r.sendLocked(client, encodeJSONMessage(messageLocks, frame))
+}
+
+// lockedRegion 锁定区域当前的位置 [from, to)
+// 两端锚点的 assoc 决定恰好在边界上的插入算不算区域内：
+// 起点关联右侧字符（assoc >= 0）时在起点插入的内容落在区域外，终点关联左侧字符（assoc < 0）时同理
+type lockedRegion struct {
+	from, to uint64
+	// 插入位置在 [insertFrom, insertTo] 之内时落在区域内部
+	insertFrom, insertTo int64
+}
+
+// resolveLockedRegion 解析锁定区域当前的位置，锚点无法解析时返回 false
+func resolveLockedRegion(doc *yjs.Doc, lock *models.RoomLock) (lockedRegion, bool) {
+	start, err := yjs.DecodeRelativePosition(lock.AnchorStart)
+	if err != nil {
+		return lockedRegion{}, false
+	}
+	end, err := yjs.DecodeRelativePosition(lock.AnchorEnd)
+	if err != nil {
+		return lockedRegion{}, false
+	}
+	text := doc.GetText(lock.TextName)
+	from, ok := text.Resolve(start)
+	if !ok {
+		return lockedRegion{}, false
+	}
+	to, ok := text.Resolve(end)
+	if !ok {
+		return lockedRegion{}, false
+	}
+	to = max(from, to)
+
+	region := lockedRegion{from: from, to: to, insertFrom: int64(from), insertTo: int64(to)}
+	if start.Assoc >= 0 {
+		region.insertFrom++
+	}
+	if end.Assoc < 0 {
+		region.insertTo--
+	}
+	return region, true
+}
+
+// touches 修改是否落在区域内
+func (l lockedRegion) touches(edit yjs.TextEdit) bool {
+	if edit.Insert {
+		return int64(edit.From) <= l.insertTo && int64(edit.To) >= l.insertFrom && l.insertFrom <= l.insertTo
+	}
+	return edit.From < l.to && edit.To > l.from
+}
@@ -0,0 +1,205 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLocks(t *testing.T, conn *websocket.Conn) *locksFrame {
+	var frame locksFrame
+	readExtension(t, conn, messageLocks, &frame)
+	return &frame
+}
+
+func TestHub_LocksRejectMemberEdits(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+
+	interviewer := dial(t, url+"?user=1&role=owner")
+	readMessage(t, interviewer)
+	interviewerDoc := yjs.NewDoc(yjs.Options{ClientID: 11})
+	setup := interviewerDoc.GetText(codeTextName).Insert(0, "PROBLEM\nanswer")
+	require.NoError(t, interviewer.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(setup)))
+
+	candidate := dial(t, url+"?user=2&role=member")
+	readMessage(t, candidate)
+	candidateDoc := yjs.NewDoc(yjs.Options{ClientID: 22})
+	require.NoError(t, candidateDoc.ApplyUpdate(setup))
+
+	// 1. 锁定 "PROBLEM"，推送给所有连接
+	text := interviewerDoc.GetText(codeTextName)
+	lock := &models.RoomLock{
+		ID:          1,
+		TextName:    codeTextName,
+		AnchorStart: text.RelativePosition(0, 0).Encode(),
+		AnchorEnd:   text.RelativePosition(7, -1).Encode(),
+		Label:       "题目",
+	}
+	require.NoError(t, hub.SetLocks(context.Background(), testRoom, []*models.RoomLock{lock}))
+	assert.Len(t, readLocks(t, interviewer).Locks, 1)
+	assert.Len(t, readLocks(t, candidate).Locks, 1)
+
+	// 2. 候选人在区域边界和区域外输入不受影响
+	send := func(conn *websocket.Conn, update []byte) {
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+	candidateText := candidateDoc.GetText(codeTextName)
+	send(candidate, candidateText.Insert(0, ">"))
+	send(candidate, candidateText.Insert(8, "!"))
+	send(candidate, candidateText.Insert(candidateText.Length(), " = 42"))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint64(yjs.SyncUpdate), readMessage(t, interviewer).SubType)
+	}
+
+	// 3. 修改区域内的内容被拒绝，之后同一客户端的编辑也被拒绝
+	send(candidate, candidateText.Delete(2, 1))
+	rejected := readLocks(t, candidate)
+	assert.Equal(t, locksFrameRejected, rejected.Type)
+	assert.Equal(t, uint(1), rejected.LockID)
+	assert.Equal(t, "题目", rejected.Label)
+	send(candidate, candidateText.Insert(candidateText.Length(), "!"))
+	rejected = readLocks(t, candidate)
+	assert.Zero(t, rejected.LockID)
+	assert.Equal(t, errLockResync.Error(), rejected.Error)
+
+	// 4. 面试官不受限制
+	send(interviewer, text.Insert(3, "-"))
+	assert.Equal(t, uint64(yjs.SyncUpdate), readMessage(t, candidate).SubType)
+	code, err := hub.CodeText(context.Background(), testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, ">PRO-BLEM!\nanswer = 42", code)
+}
+
+func TestHub_LocksRejectOutOfOrderUpdates(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+
+	owner := dial(t, url+"?user=1&role=owner")
+	readMessage(t, owner)
+	ownerDoc := yjs.NewDoc(yjs.Options{ClientID: 11})
+	text := ownerDoc.GetText(codeTextName)
+	setup := text.Insert(0, "PROBLEM\nanswer")
+	send := func(conn *websocket.Conn, update []byte) {
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+	send(owner, setup)
+
+	candidate := dial(t, url+"?user=2&role=member")
+	readMessage(t, candidate)
+	candidateDoc := yjs.NewDoc(yjs.Options{ClientID: 22})
+	require.NoError(t, candidateDoc.ApplyUpdate(setup))
+
+	lock := &models.RoomLock{
+		ID:          1,
+		TextName:    codeTextName,
+		AnchorStart: text.RelativePosition(0, 0).Encode(),
+		AnchorEnd:   text.RelativePosition(7, -1).Encode(),
+	}
+	require.NoError(t, hub.SetLocks(context.Background(), testRoom, []*models.RoomLock{lock}))
+	readLocks(t, owner)
+	readLocks(t, candidate)
+
+	// 1. 面试官的乱序更新让文档中留有等待依赖的结构
+	text.Insert(text.Length(), "?")
+	send(owner, text.Insert(text.Length(), "?"))
+	hub.mu.Lock()
+	room := hub.rooms[testRoom.UUID]
+	hub.mu.Unlock()
+	waitFor(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return room.doc.HasPending()
+	})
+
+	// 2. 候选人先发送修改锁定区域的乱序更新，再发送补齐它的无害更新，两条都被拒绝
+	candidateText := candidateDoc.GetText(codeTextName)
+	filler := candidateText.Insert(candidateText.Length(), "!")
+	parked := candidateText.Insert(3, "x")
+	send(candidate, parked)
+	rejected := readLocks(t, candidate)
+	assert.Equal(t, locksFrameRejected, rejected.Type)
+	send(candidate, filler)
+	rejected = readLocks(t, candidate)
+	assert.Equal(t, errLockResync.Error(), rejected.Error)
+
+	code, err := hub.CodeText(context.Background(), testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, "PROBLEM\nanswer", code)
+}
+
+func TestHub_LocksFollowRoleChanges(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+
+	admin := dial(t, url+"?user=1&role=admin")
+	readMessage(t, admin)
+	adminDoc := yjs.NewDoc(yjs.Options{ClientID: 11})
+	text := adminDoc.GetText(codeTextName)
+	send := func(update []byte) {
+		require.NoError(t, admin.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	}
+	send(text.Insert(0, "PROBLEM\nanswer"))
+
+	lock := &models.RoomLock{
+		ID:          1,
+		TextName:    codeTextName,
+		AnchorStart: text.RelativePosition(0, 0).Encode(),
+		AnchorEnd:   text.RelativePosition(7, -1).Encode(),
+	}
+	require.NoError(t, hub.SetLocks(context.Background(), testRoom, []*models.RoomLock{lock}))
+	readLocks(t, admin)
+
+	// 降级后连接上的角色立即更新，修改锁定区域被拒绝
+	require.NoError(t, hub.UpdateMemberRole(context.Background(), testRoom, 1, models.RoomRoleMember))
+	send(text.Delete(0, 1))
+	rejected := readLocks(t, admin)
+	assert.Equal(t, locksFrameRejected, rejected.Type)
+	assert.Equal(t, uint(1), rejected.LockID)
+}
+
+func TestHub_ReplaceFileTextRespectsLocks(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+	ctx := context.Background()
+
+	owner := dial(t, url+"?user=1&role=owner")
+	readMessage(t, owner)
+	ownerDoc := yjs.NewDoc(yjs.Options{ClientID: 11})
+	text := ownerDoc.GetText(codeTextName)
+	setup := text.Insert(0, "PROBLEM\nanswer")
+	require.NoError(t, owner.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(setup)))
+	waitFor(t, func() bool {
+		code, err := hub.CodeText(ctx, testRoom)
+		return err == nil && code == "PROBLEM\nanswer"
+	})
+
+	lock := &models.RoomLock{
+		ID:          1,
+		TextName:    codeTextName,
+		AnchorStart: text.RelativePosition(0, 0).Encode(),
+		AnchorEnd:   text.RelativePosition(7, -1).Encode(),
+	}
+	require.NoError(t, hub.SetLocks(ctx, testRoom, []*models.RoomLock{lock}))
+
+	// 1. 普通成员通过接口修改：区域外和区域边界可以，区域内被拒绝且文档不变
+	require.NoError(t, hub.ReplaceFileText(ctx, testRoom, codeTextName, "PROBLEM!\nanswer = 1", models.RoomRoleMember))
+	err := hub.ReplaceFileText(ctx, testRoom, codeTextName, "PROBLEx!\nanswer = 1", models.RoomRoleMember)
+	assert.ErrorIs(t, err, service.ErrRoomRegionLocked)
+	assert.ErrorIs(t, hub.ReplaceFileText(ctx, testRoom, codeTextName, "", models.RoomRoleMember), service.ErrRoomRegionLocked)
+	code, err := hub.CodeText(ctx, testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, "PROBLEM!\nanswer = 1", code)
+
+	// 2. 管理员不受限制
+	require.NoError(t, hub.ReplaceFileText(ctx, testRoom, codeTextName, "", models.RoomRoleAdmin))
+	code, err = hub.CodeText(ctx, testRoom)
+	require.NoError(t, err)
+	assert.Empty(t, code)
+}
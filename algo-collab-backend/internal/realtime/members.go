@@ -0,0 +1,164 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+)
+
+var _ service.MemberNotifier = (*Hub)(nil)
+
+// 成员变化消息的 type 字段，只在节点之间转发，不发给客户端
+const (
+	membersFrameKick  = "kick"  // 成员被移出房间，断开其所有连接
+	membersFrameRole  = "role"  // 成员角色变化，更新其连接上的角色
+	membersFrameClose = "close" // 房间被归档、恢复或删除，断开所有连接
+	// 白板被删除，断开白板的所有连接并丢弃文档，只在白板的频道上发送
+	membersFrameDelete = "delete"
+)
+
+// membersFrame 成员变化消息的 JSON 结构
+type membersFrame struct {
+	Type   string `json:"type"`
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+}
+
+func isMembersFrame(data []byte) bool {
+	return isMessageType(data, messageMembers)
+}
+
+// DisconnectMember 断开成员在房间内的所有连接，成员被移出房间后调用
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) DisconnectMember(_ context.Context, roomModel *models.Room, userID uint) error {
+	return h.applyMember(roomModel, &membersFrame{Type: membersFrameKick, UserID: userID})
+}
+
+// UpdateMemberRole 更新成员在房间内所有连接上的角色，锁定区域、演示者等权限检查立即按新角色执行
+// 限制同 DisconnectMember
+func (h *Hub) UpdateMemberRole(_ context.Context, roomModel *models.Room, userID uint, role string) error {
+	return h.applyMember(roomModel, &membersFrame{Type: membersFrameRole, UserID: userID, Role: role})
+}
+
+// CloseRoom 房间被归档、恢复或删除后断开房间内的所有连接，客户端重连时按房间的新状态连接（只读或不存在）
+// 不需要加载房间：本节点没有该房间时只通知其他节点。亲和模式下限制同 DisconnectMember
+func (h *Hub) CloseRoom(ctx context.Context, roomModel *models.Room) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	h.closeRoom(roomModel.UUID)
+	if broker := h.opts.Broker; broker != nil {
+		frame := encodeJSONMessage(messageMembers, &membersFrame{Type: membersFrameClose})
+		return broker.Publish(ctx, roomModel.UUID, encodeEnvelope(h.nodeID, frame))
+	}
+	return nil
+}
+
+// closeRoom 和迁出房间一样，先从 Hub 摘除本节点的房间和它的白板，再断开所有连接并把文档合并写库
+func (h *Hub) closeRoom(roomUUID string) {
+	h.mu.Lock()
+	rooms := h.whiteboardRoomsLocked(roomUUID)
+	if room := h.rooms[roomUUID]; room != nil {
+		rooms = append(rooms, room)
+	}
+	for _, room := range rooms {
+		delete(h.rooms, room.uuid)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.drain(websocket.CloseServiceRestart, reconnectHint("room_closed", 0))
+	}
+}
+
+// LoadedRooms 本节点正在协作的房间 UUID，只有白板连接的房间也包括在内
+func (h *Hub) LoadedRooms() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	seen := make(map[string]bool, len(h.rooms))
+	uuids := make([]string, 0, len(h.rooms))
+	for key := range h.rooms {
+		if roomUUID := roomOf(key); !seen[roomUUID] {
+			seen[roomUUID] = true
+			uuids = append(uuids, roomUUID)
+		}
+	}
+	return uuids
+}
+
+// applyMember 在本节点执行成员变化并转发给其他节点
+func (h *Hub) applyMember(roomModel *models.Room, frame *membersFrame) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+
+	// 白板连接同样受影响，其他节点上的白板文档订阅了房间的频道
+	h.mu.Lock()
+	boards := h.whiteboardRoomsLocked(roomModel.UUID)
+	h.mu.Unlock()
+	for _, board := range boards {
+		board.mu.Lock()
+		board.applyMemberLocked(frame)
+		board.mu.Unlock()
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.applyMemberLocked(frame)
+	room.publishLocked(encodeJSONMessage(messageMembers, frame))
+	return nil
+}
+
+// handleRemoteMembers 其他节点上的成员变化
+func (r *Room) handleRemoteMembers(data []byte) {
+	var frame membersFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+	switch frame.Type {
+	case membersFrameClose:
+		r.closeRoom()
+		return
+	case membersFrameDelete:
+		if r.discard != nil {
+			r.discard()
+		}
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applyMemberLocked(&frame)
+}
+
+// applyMemberLocked 处理本节点上该成员的连接
+// 被移出的成员用 ClosePolicyViolation 断开，客户端据此提示而不是自动重连。
+// 连接上的角色只在持有 r.mu 时读写
+func (r *Room) applyMemberLocked(frame *membersFrame) {
+	switch frame.Type {
+	case membersFrameKick:
+		for client := range r.clients {
+			if client.user.ID == frame.UserID {
+				client.closeWith(websocket.ClosePolicyViolation, `{"reason":"kicked"}`)
+				r.dropLocked(client)
+			}
+		}
+	case membersFrameRole:
+		for client := range r.clients {
+			if client.user.ID == frame.UserID {
+				client.user.Role = frame.Role
+			}
+		}
+		if r.presenter != nil && r.presenter.UserID == frame.UserID {
+			r.presenter.Role = frame.Role
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_DisconnectMember(t *testing.T) {
+	hub := NewHub(Options{})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url+"?user=1")
+	readMessage(t, alice)
+	bob := dial(t, url+"?user=2")
+	readMessage(t, bob)
+	bobAgain := dial(t, url+"?user=2")
+	readMessage(t, bobAgain)
+
+	// 被移出的成员在房间内的所有连接都被断开，其他成员不受影响
+	require.NoError(t, hub.DisconnectMember(context.Background(), testRoom, 2))
+	for _, conn := range []*websocket.Conn{bob, bobAgain} {
+		closeErr := expectClose(t, conn)
+		assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+		assert.Contains(t, closeErr.Text, "kicked")
+	}
+	expectSyncDone(t, alice)
+}
+
+func TestHub_DisconnectMemberAcrossNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	newNode := func() (*Hub, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		hub := NewHub(Options{Documents: store, Broker: NewRedisBroker(ctx, rdb)})
+		return hub, newTestServer(t, hub)
+	}
+	hubA, nodeA := newNode()
+	_, nodeB := newNode()
+	channel := roomChannelPrefix + testRoom.UUID
+
+	alice := dial(t, nodeA+"?user=1")
+	readMessage(t, alice)
+	bob := dial(t, nodeB+"?user=2")
+	readMessage(t, bob)
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 2 })
+
+	// 在 A 节点移出成员，B 节点上的连接也被断开
+	require.NoError(t, hubA.DisconnectMember(context.Background(), testRoom, 2))
+	assert.Equal(t, websocket.ClosePolicyViolation, expectClose(t, bob).Code)
+	expectSyncDone(t, alice)
+}
+
+func TestHub_CloseRoom(t *testing.T) {
+	hub := NewHub(Options{Documents: &memoryDocumentStore{}})
+	url := newTestServer(t, hub)
+	ctx := context.Background()
+
+	alice := dial(t, url+"?user=1")
+	readMessage(t, alice)
+	editor := yjs.NewDoc(yjs.Options{ClientID: 11})
+	text := editor.GetText(codeTextName)
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(text.Insert(0, "a"))))
+	waitFor(t, func() bool {
+		code, err := hub.CodeText(ctx, testRoom)
+		return err == nil && code == "a"
+	})
+
+	// 1. 归档后在线连接被断开，带重连提示，房间从 Hub 摘除
+	require.NoError(t, hub.CloseRoom(ctx, testRoom))
+	closeErr := expectClose(t, alice)
+	assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+	assert.Contains(t, closeErr.Text, "room_closed")
+	assert.Empty(t, hub.LoadedRooms())
+
+	// 2. 重连时房间已归档，连接只读，编辑不会被接受
+	viewer := dial(t, url+"?user=1&readonly=1")
+	readMessage(t, viewer)
+	require.NoError(t, viewer.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(text.Insert(1, "b"))))
+	expectSyncDone(t, viewer)
+	code, err := hub.CodeText(ctx, testRoom)
+	require.NoError(t, err)
+	assert.Equal(t, "a", code)
+}
+
+func TestHub_CloseRoomAcrossNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	newNode := func() (*Hub, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		hub := NewHub(Options{Documents: store, Broker: NewRedisBroker(ctx, rdb)})
+		return hub, newTestServer(t, hub)
+	}
+	hubA, _ := newNode()
+	hubB, nodeB := newNode()
+	channel := roomChannelPrefix + testRoom.UUID
+
+	bob := dial(t, nodeB+"?user=2")
+	readMessage(t, bob)
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 1 })
+
+	// A 节点没有加载房间，也能通知 B 节点断开连接
+	require.NoError(t, hubA.CloseRoom(context.Background(), testRoom))
+	assert.Equal(t, websocket.CloseServiceRestart, expectClose(t, bob).Code)
+	waitFor(t, func() bool { return len(hubB.LoadedRooms()) == 0 })
+	assert.Empty(t, hubA.LoadedRooms())
+}
@@ -0,0 +1,320 @@
+package realtime
+
+import (
+	"errors"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+)
+
+// presenterFrame 的类型
+//
+// 房间同一时间最多一个演示者（按用户，同一用户的多个标签页都可以发送视图）。
+// 没有演示者时任何可编辑的成员都可以开始演示；已有演示者时只有管理员及以上
+// 可以接管，且不能接管角色比自己高的演示者。演示者断开所有连接后自动结束演示。
+const (
+	presenterFrameTake     = "take"     // 客户端 → 服务端：开始演示或接管
+	presenterFrameRelease  = "release"  // 客户端 → 服务端：结束演示（演示者本人或管理员）
+	presenterFrameHandover = "handover" // 客户端 → 服务端：把演示者交给 user_id；节点之间：由目标用户所在的节点接手
+
+	presenterFrameState = "state" // 服务端 → 客户端：当前演示者，presenter 为空表示没有演示者
+	presenterFrameError = "error" // 服务端 → 客户端：操作失败
+
+	// presenterFrameQuery 节点之间：新建的房间请求其他节点重新发布演示者
+	presenterFrameQuery = "query"
+)
+
+var (
+	errPresenterReadOnly  = errors.New("房间已归档，不能演示")
+	errPresenterTaken     = errors.New("已有其他成员在演示")
+	errPresenterForbidden = errors.New("没有权限修改演示者")
+	errPresenterNone      = errors.New("当前没有演示者")
+	errPresenterOffline   = errors.New("对方不在房间中")
+)
+
+// presenterState 当前演示者
+type presenterState struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	Since    time.Time `json:"since"`
+}
+
+// presenterFrame 演示者控制消息的 JSON 结构
+type presenterFrame struct {
+	Type string `json:"type"`
+	// UserID handover 的目标
+	UserID    uint            `json:"user_id,omitempty"`
+	Presenter *presenterState `json:"presenter"`
+	// At 状态修改的时间，多节点同时修改时以最新的为准
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+// presenterView 演示者的视图，跟随的客户端据此切换文件、滚动和显示光标
+// 视图是高频的临时状态，使用单独的消息类型，发送队列满时和 awareness 一样直接丢弃
+type presenterView struct {
+	UserID    uint               `json:"user_id"` // 由服务端填写
+	File      string             `json:"file"`
+	StartLine int                `json:"start_line"` // 可见区域
+	EndLine   int                `json:"end_line"`
+	Cursor    *presenterPosition `json:"cursor,omitempty"`
+	Selection *presenterRange    `json:"selection,omitempty"`
+}
+
+type presenterPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type presenterRange struct {
+	Start presenterPosition `json:"start"`
+	End   presenterPosition `json:"end"`
+}
+
+func isPresenterFrame(data []byte) bool {
+	return isMessageType(data, messagePresenter)
+}
+
+func isPresenterView(data []byte) bool {
+	return isMessageType(data, messagePresenterView)
+}
+
+func encodePresenterFrame(frame *presenterFrame) []byte {
+	return encodeJSONMessage(messagePresenter, frame)
+}
+
+// handlePresenter 处理客户端发来的演示者控制消息
+func (r *Room) handlePresenter(client *Client, data []byte) {
+	var frame presenterFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[client]; !ok {
+		return
+	}
+	var err error
+	switch frame.Type {
+	case presenterFrameTake:
+		err = r.takePresenterLocked(client)
+	case presenterFrameRelease:
+		err = r.releasePresenterLocked(client)
+	case presenterFrameHandover:
+		err = r.handoverPresenterLocked(client, frame.UserID)
+	}
+	if err != nil {
+		r.sendLocked(client, encodePresenterFrame(&presenterFrame{Type: presenterFrameError, Presenter: r.presenter, Error: err.Error()}))
+	}
+}
+
+func (r *Room) takePresenterLocked(client *Client) error {
+	if client.readOnly {
+		return errPresenterReadOnly
+	}
+	if current := r.presenter; current != nil {
+		if current.UserID == client.user.ID {
+			return nil
+		}
+		if !canOverridePresenter(client.user, current) {
+			return errPresenterTaken
+		}
+	}
+	r.setPresenterLocked(presenterOf(client.user), time.Now())
+	return nil
+}
+
+func (r *Room) releasePresenterLocked(client *Client) error {
+	current := r.presenter
+	if current == nil {
+		return errPresenterNone
+	}
+	if current.UserID != client.user.ID && !canOverridePresenter(client.user, current) {
+		return errPresenterForbidden
+	}
+	r.setPresenterLocked(nil, time.Now())
+	return nil
+}
+
+// handoverPresenterLocked 演示者本人或可以接管的管理员把演示者交给其他成员
+// 目标不在本节点时交给其他节点处理，目标不在任何节点上时不会有回应
+func (r *Room) handoverPresenterLocked(client *Client, targetID uint) error {
+	if client.readOnly {
+		return errPresenterReadOnly
+	}
+	current := r.presenter
+	isPresenter := current != nil && current.UserID == client.user.ID
+	if !isPresenter && (!models.RoomRoleAtLeast(client.user.Role, models.RoomRoleAdmin) ||
+		(current != nil && !canOverridePresenter(client.user, current))) {
+		return errPresenterForbidden
+	}
+	if targetID == client.user.ID {
+		return r.takePresenterLocked(client)
+	}
+
+	if target := r.writableClientLocked(targetID); target != nil {
+		r.setPresenterLocked(presenterOf(target.user), time.Now())
+		return nil
+	}
+	if r.broker == nil {
+		return errPresenterOffline
+	}
+	r.publishLocked(encodePresenterFrame(&presenterFrame{Type: presenterFrameHandover, UserID: targetID, At: time.Now()}))
+	return nil
+}
+
+// setPresenterLocked 修改演示者并通知本节点的连接和其他节点
+func (r *Room) setPresenterLocked(state *presenterState, at time.Time) {
+	r.presenter = state
+	r.presenterAt = at
+	r.presenterView = nil
+
+	data := encodePresenterFrame(&presenterFrame{Type: presenterFrameState, Presenter: state, At: at})
+	r.broadcastLocked(data, nil)
+	r.publishLocked(data)
+}
+
+// releaseIfPresenterGoneLocked 演示者在本节点的最后一个连接断开时结束演示
+func (r *Room) releaseIfPresenterGoneLocked(client *Client) {
+	if r.presenter == nil || r.presenter.UserID != client.user.ID {
+		return
+	}
+	if r.writableClientLocked(client.user.ID) != nil {
+		return
+	}
+	r.setPresenterLocked(nil, time.Now())
+}
+
+// writableClientLocked 返回用户在本节点的一个可编辑连接
+func (r *Room) writableClientLocked(userID uint) *Client {
+	for client := range r.clients {
+		if client.user.ID == userID && !client.readOnly {
+			return client
+		}
+	}
+	return nil
+}
+
+// handlePresenterView 转发演示者的视图，其他人发送的直接忽略
+func (r *Room) handlePresenterView(client *Client, data []byte) {
+	var view presenterView
+	if err := decodeJSONMessage(data, &view); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.presenter == nil || r.presenter.UserID != client.user.ID || client.readOnly {
+		return
+	}
+	view.UserID = client.user.ID
+	frame := encodeJSONMessage(messagePresenterView, &view)
+	r.presenterView = frame
+	r.broadcastLocked(frame, client)
+	r.publishLocked(frame)
+}
+
+// sendPresenterLocked 新连接建立时下发当前演示者和最近的视图
+func (r *Room) sendPresenterLocked(client *Client) {
+	if r.presenter == nil {
+		return
+	}
+	r.sendLocked(client, encodePresenterFrame(&presenterFrame{Type: presenterFrameState, Presenter: r.presenter, At: r.presenterAt}))
+	if r.presenterView != nil {
+		r.sendLocked(client, r.presenterView)
+	}
+}
+
+// queryRemotePresenter 新建的房间向其他节点请求当前演示者
+func (r *Room) queryRemotePresenter() {
+	if r.broker == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publishLocked(encodePresenterFrame(&presenterFrame{Type: presenterFrameQuery}))
+}
+
+// releaseLocalPresenterLocked 房间被清空时，如果演示者连接在本节点上，通知其他节点结束演示
+func (r *Room) releaseLocalPresenterLocked() {
+	if r.presenter != nil {
+		for client := range r.clients {
+			if client.user.ID == r.presenter.UserID {
+				r.publishLocked(encodePresenterFrame(&presenterFrame{Type: presenterFrameState, At: time.Now()}))
+				break
+			}
+		}
+	}
+	r.presenter = nil
+	r.presenterView = nil
+}
+
+// handleRemotePresenter 处理其他节点转发的演示者消息
+func (r *Room) handleRemotePresenter(data []byte) {
+	var frame presenterFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch frame.Type {
+	case presenterFrameState:
+		if frame.At.Before(r.presenterAt) {
+			return
+		}
+		if r.presenter == nil && frame.Presenter == nil {
+			return
+		}
+		r.presenter = frame.Presenter
+		r.presenterAt = frame.At
+		r.presenterView = nil
+		r.broadcastLocked(data, nil)
+	case presenterFrameHandover:
+		// 权限已经由发起的节点校验
+		if target := r.writableClientLocked(frame.UserID); target != nil && !frame.At.Before(r.presenterAt) {
+			r.setPresenterLocked(presenterOf(target.user), frame.At)
+		}
+	case presenterFrameQuery:
+		if r.presenter != nil {
+			r.publishLocked(encodePresenterFrame(&presenterFrame{Type: presenterFrameState, Presenter: r.presenter, At: r.presenterAt}))
+		}
+	}
+}
+
+// handleRemotePresenterView 其他节点转发的视图，只接受当前演示者的
+func (r *Room) handleRemotePresenterView(data []byte) {
+	var view presenterView
+	if err := decodeJSONMessage(data, &view); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.presenter == nil || r.presenter.UserID != view.UserID {
+		return
+	}
+	r.presenterView = data
+	r.broadcastLocked(data, nil)
+}
+
+// canOverridePresenter 管理员及以上可以接管或结束角色不高于自己的演示者
+func canOverridePresenter(user User, current *presenterState) bool {
+	return models.RoomRoleAtLeast(user.Role, models.RoomRoleAdmin) &&
+		models.RoomRoleAtLeast(user.Role, current.Role)
+}
+
+func presenterOf(user User) *presenterState {
+	return &presenterState{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Since:    time.Now(),
+	}
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readExtension 跳过其他消息，把下一条 msgType 类型的消息解析到 v
+func readExtension(t *testing.T, conn *websocket.Conn, msgType uint64, v any) {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		if isMessageType(data, msgType) {
+			require.NoError(t, decodeJSONMessage(data, v))
+			return
+		}
+	}
+}
+
+func readPresenter(t *testing.T, conn *websocket.Conn) *presenterFrame {
+	var frame presenterFrame
+	readExtension(t, conn, messagePresenter, &frame)
+	return &frame
+}
+
+func readPresenterView(t *testing.T, conn *websocket.Conn) *presenterView {
+	var view presenterView
+	readExtension(t, conn, messagePresenterView, &view)
+	return &view
+}
+
+func sendPresenter(t *testing.T, conn *websocket.Conn, frame *presenterFrame) {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, encodePresenterFrame(frame)))
+}
+
+func sendPresenterView(t *testing.T, conn *websocket.Conn, view *presenterView) {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, encodeJSONMessage(messagePresenterView, view)))
+}
+
+func TestHub_Presenter(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{}))
+
+	alice := dial(t, url+"?user=1&role=member")
+	bob := dial(t, url+"?user=2&role=member")
+	carol := dial(t, url+"?user=3&role=admin")
+
+	// 没有演示者时成员可以开始演示
+	sendPresenter(t, alice, &presenterFrame{Type: presenterFrameTake})
+	for _, conn := range []*websocket.Conn{alice, bob, carol} {
+		frame := readPresenter(t, conn)
+		assert.Equal(t, presenterFrameState, frame.Type)
+		require.NotNil(t, frame.Presenter)
+		assert.Equal(t, uint(1), frame.Presenter.UserID)
+	}
+
+	// 普通成员不能接管，也不能结束别人的演示
+	sendPresenter(t, bob, &presenterFrame{Type: presenterFrameTake})
+	assert.Equal(t, errPresenterTaken.Error(), readPresenter(t, bob).Error)
+	sendPresenter(t, bob, &presenterFrame{Type: presenterFrameRelease})
+	assert.Equal(t, errPresenterForbidden.Error(), readPresenter(t, bob).Error)
+
+	// 只转发演示者的视图，并填上演示者
+	sendPresenterView(t, bob, &presenterView{File: "bob.go"})
+	sendPresenterView(t, alice, &presenterView{UserID: 2, File: "main.go", StartLine: 10, EndLine: 40, Cursor: &presenterPosition{Line: 12, Column: 4}})
+	view := readPresenterView(t, bob)
+	assert.Equal(t, uint(1), view.UserID)
+	assert.Equal(t, "main.go", view.File)
+	assert.Equal(t, 12, view.Cursor.Line)
+
+	// 新连接收到当前演示者和最近的视图
+	dave := dial(t, url+"?user=4&role=member")
+	assert.Equal(t, uint(1), readPresenter(t, dave).Presenter.UserID)
+	assert.Equal(t, "main.go", readPresenterView(t, dave).File)
+
+	// 管理员可以接管
+	sendPresenter(t, carol, &presenterFrame{Type: presenterFrameTake})
+	assert.Equal(t, uint(3), readPresenter(t, alice).Presenter.UserID)
+	assert.Equal(t, uint(3), readPresenter(t, carol).Presenter.UserID)
+
+	// 演示者交给其他成员，不在房间中的成员不能接手
+	sendPresenter(t, carol, &presenterFrame{Type: presenterFrameHandover, UserID: 99})
+	assert.Equal(t, errPresenterOffline.Error(), readPresenter(t, carol).Error)
+	sendPresenter(t, carol, &presenterFrame{Type: presenterFrameHandover, UserID: 2})
+	assert.Equal(t, uint(3), readPresenter(t, bob).Presenter.UserID)
+	assert.Equal(t, uint(2), readPresenter(t, bob).Presenter.UserID)
+	assert.Equal(t, uint(2), readPresenter(t, carol).Presenter.UserID)
+
+	// 演示者断开后自动结束
+	bob.Close()
+	frame := readPresenter(t, carol)
+	assert.Equal(t, presenterFrameState, frame.Type)
+	assert.Nil(t, frame.Presenter)
+}
+
+func TestHub_PresenterRoleRules(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{}))
+
+	owner := dial(t, url+"?user=1&role=owner")
+	admin := dial(t, url+"?user=2&role=admin")
+	viewer := dial(t, url+"?user=3&role=member&readonly=1")
+
+	sendPresenter(t, viewer, &presenterFrame{Type: presenterFrameTake})
+	assert.Equal(t, errPresenterReadOnly.Error(), readPresenter(t, viewer).Error)
+
+	// 管理员不能接管房主的演示
+	sendPresenter(t, owner, &presenterFrame{Type: presenterFrameTake})
+	readPresenter(t, admin)
+	sendPresenter(t, admin, &presenterFrame{Type: presenterFrameTake})
+	assert.Equal(t, errPresenterTaken.Error(), readPresenter(t, admin).Error)
+	sendPresenter(t, admin, &presenterFrame{Type: presenterFrameHandover, UserID: 2})
+	assert.Equal(t, errPresenterForbidden.Error(), readPresenter(t, admin).Error)
+
+	sendPresenter(t, owner, &presenterFrame{Type: presenterFrameRelease})
+	assert.Nil(t, readPresenter(t, admin).Presenter)
+	sendPresenter(t, admin, &presenterFrame{Type: presenterFrameRelease})
+	assert.Equal(t, errPresenterNone.Error(), readPresenter(t, admin).Error)
+}
+
+func TestHub_PresenterAcrossNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	nodeA := newTestNode(t, mr, store)
+	nodeB := newTestNode(t, mr, store)
+	channel := roomChannelPrefix + testRoom.UUID
+
+	alice := dial(t, nodeA+"?user=1&role=member")
+	sendPresenter(t, alice, &presenterFrame{Type: presenterFrameTake})
+	readPresenter(t, alice)
+
+	// 后创建的房间向其他节点查询当前演示者
+	bob := dial(t, nodeB+"?user=2&role=admin")
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 2 })
+	assert.Equal(t, uint(1), readPresenter(t, bob).Presenter.UserID)
+
+	sendPresenterView(t, alice, &presenterView{File: "main.go", StartLine: 1, EndLine: 30})
+	assert.Equal(t, "main.go", readPresenterView(t, bob).File)
+
+	// 把演示者交给其他节点上的成员
+	sendPresenter(t, alice, &presenterFrame{Type: presenterFrameHandover, UserID: 2})
+	frame := readPresenter(t, bob)
+	for frame.Presenter == nil || frame.Presenter.UserID != 2 {
+		frame = readPresenter(t, bob)
+	}
+	frame = readPresenter(t, alice)
+	for frame.Presenter == nil || frame.Presenter.UserID != 2 {
+		frame = readPresenter(t, alice)
+	}
+	assert.Equal(t, "user-2", frame.Presenter.Username)
+}
@@ -0,0 +1,129 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// forwardedHeader 标记由其他节点转发来的连接，值为转发节点的地址
+// 收到转发连接的节点只在本地处理，不再继续转发，避免成员表不一致时来回转发
+const forwardedHeader = "X-Collab-Forwarded-By"
+
+// ErrNotRoomOwner 转发来的连接对应的房间不归本节点所有（成员表尚未收敛）
+var ErrNotRoomOwner = errors.New("房间不属于本节点")
+
+// proxy 把客户端连接转发到房间所在的节点
+// 原样转发鉴权信息，由房间所在节点重新校验身份和成员资格
+func (h *Hub) proxy(w http.ResponseWriter, r *http.Request, owner string) error {
+	// 1. 先连上房间所在节点，失败时返回 HTTP 错误，客户端会稍后重连
+	header := http.Header{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		header.Set("Authorization", auth)
+	}
+	header.Set(forwardedHeader, h.opts.Cluster.Self())
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.limits.writeTimeout)
+	upstream, resp, err := websocket.DefaultDialer.DialContext(ctx, "ws://"+owner+r.URL.RequestURI(), header)
+	cancel()
+	if err != nil {
+		status := http.StatusBadGateway
+		if resp != nil {
+			// 鉴权失败等错误原样返回给客户端
+			status = resp.StatusCode
+		}
+		http.Error(w, "房间所在节点不可用", status)
+		return err
+	}
+
+	// 2. 升级客户端连接并双向转发
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		upstream.Close()
+		return err
+	}
+
+	if !h.trackProxy(conn) {
+		_ = conn.WriteControl(websocket.CloseMessage, h.shutdownCloseMessage(), time.Now().Add(h.limits.writeTimeout))
+		conn.Close()
+		upstream.Close()
+		return ErrHubClosed
+	}
+
+	logger.Info("协作连接转发到房间所在节点",
+		zap.String("path", r.URL.Path),
+		zap.String("owner", owner))
+	pipeConnections(conn, upstream, h.limits, func() { h.untrackProxy(conn) })
+	return nil
+}
+
+// pipeConnections 在客户端和上游节点之间双向转发消息
+// 任一方断开时把关闭码转给另一方，例如房间迁移时的 1012 会原样到达客户端
+func pipeConnections(client, upstream *websocket.Conn, limits connLimits, onClose func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			close(done)
+			client.Close()
+			upstream.Close()
+			onClose()
+		})
+	}
+
+	client.SetReadLimit(limits.maxMessageSize)
+	upstream.SetReadLimit(limits.maxMessageSize)
+
+	// 上游节点会给转发连接发 ping，客户端这一侧由转发节点负责心跳
+	_ = client.SetReadDeadline(time.Now().Add(limits.idleTimeout))
+	client.SetPongHandler(func(string) error {
+		return client.SetReadDeadline(time.Now().Add(limits.idleTimeout))
+	})
+	go func() {
+		ticker := time.NewTicker(limits.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := client.WriteControl(websocket.PingMessage, nil, time.Now().Add(limits.writeTimeout)); err != nil {
+					closeBoth()
+					return
+				}
+			}
+		}
+	}()
+
+	go copyMessages(upstream, client, limits, closeBoth)
+	go copyMessages(client, upstream, limits, closeBoth)
+}
+
+// copyMessages 把 src 收到的消息写到 dst，直到任一方出错
+func copyMessages(dst, src *websocket.Conn, limits connLimits, closeBoth func()) {
+	defer closeBoth()
+
+	for {
+		messageType, data, err := src.ReadMessage()
+		if err != nil {
+			code, text := websocket.CloseServiceRestart, ""
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code != websocket.CloseAbnormalClosure {
+				code, text = closeErr.Code, closeErr.Text
+			}
+			_ = dst.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(limits.writeTimeout))
+			return
+		}
+
+		_ = dst.SetWriteDeadline(time.Now().Add(limits.writeTimeout))
+		if err := dst.WriteMessage(messageType, data); err != nil {
+			return
+		}
+	}
+}
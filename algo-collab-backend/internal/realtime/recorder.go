@@ -0,0 +1,222 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSessionGap 房间多久没有活动后开始新的回放会话
+	defaultSessionGap = 30 * time.Minute
+	// defaultKeyframeInterval 有修改时每隔多久记录一次完整文档
+	defaultKeyframeInterval = time.Minute
+
+	recorderQueueSize     = 4096
+	recorderBatchSize     = 200
+	recorderFlushInterval = time.Second
+)
+
+// recordedEvent 等待写入的会话事件
+type recordedEvent struct {
+	roomID uint
+	kind   string
+	userID *uint
+	data   []byte
+	at     time.Time
+}
+
+// activeSession 房间当前的会话，lastAt 为本节点最后一次写入事件的时间
+type activeSession struct {
+	session *models.RoomSession
+	lastAt  time.Time
+}
+
+// recorder 把房间内的文档更新、awareness 和聊天按时间记录到回放会话
+//
+// 事件由收到它的节点记录（其他节点转发来的不再记录），在后台批量写库，不阻塞文档同步；
+// 队列满时丢弃事件，回放在下一个关键帧处恢复完整的文档。
+type recorder struct {
+	repo   repository.RoomSessionRepository
+	gap    time.Duration
+	events chan recordedEvent
+
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// sessions 只在后台 goroutine 中访问
+	sessions map[uint]*activeSession
+}
+
+func newRecorder(repo repository.RoomSessionRepository, gap time.Duration) *recorder {
+	rec := &recorder{
+		repo:     repo,
+		gap:      gap,
+		events:   make(chan recordedEvent, recorderQueueSize),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		sessions: make(map[uint]*activeSession),
+	}
+	go rec.run()
+	return rec
+}
+
+// record 加入写入队列，队列满或已停止时丢弃
+func (rec *recorder) record(event recordedEvent) {
+	select {
+	case <-rec.quit:
+		return
+	default:
+	}
+	select {
+	case rec.events <- event:
+	default:
+		logger.Warn("回放事件队列已满，丢弃事件", zap.Uint("room_id", event.roomID), zap.String("kind", event.kind))
+	}
+}
+
+// stop 写入队列中剩余的事件后停止，等待到 ctx 超时为止
+func (rec *recorder) stop(ctx context.Context) {
+	rec.stopOnce.Do(func() { close(rec.quit) })
+	select {
+	case <-rec.done:
+	case <-ctx.Done():
+		logger.Warn("等待回放事件写入超时")
+	}
+}
+
+func (rec *recorder) run() {
+	defer close(rec.done)
+	ticker := time.NewTicker(recorderFlushInterval)
+	defer ticker.Stop()
+
+	var batch []recordedEvent
+	for {
+		select {
+		case event := <-rec.events:
+			if batch = append(batch, event); len(batch) >= recorderBatchSize {
+				rec.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			rec.flush(batch)
+			batch = nil
+		case <-rec.quit:
+			for {
+				select {
+				case event := <-rec.events:
+					batch = append(batch, event)
+				default:
+					rec.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 为每个事件找到所属的会话并批量写库
+func (rec *recorder) flush(batch []recordedEvent) {
+	now := time.Now()
+	for roomID, active := range rec.sessions {
+		if now.Sub(active.lastAt) > rec.gap {
+			delete(rec.sessions, roomID)
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*storeTimeout)
+	defer cancel()
+
+	failed := make(map[uint]bool)
+	events := make([]*models.RoomSessionEvent, 0, len(batch))
+	for _, event := range batch {
+		if failed[event.roomID] {
+			continue
+		}
+		active := rec.sessions[event.roomID]
+		if active == nil || event.at.Sub(active.lastAt) > rec.gap {
+			session, err := rec.repo.Begin(ctx, event.roomID, event.at, rec.gap)
+			if err != nil {
+				logger.Error("开始回放会话失败", zap.Uint("room_id", event.roomID), zap.Error(err))
+				failed[event.roomID] = true
+				continue
+			}
+			active = &activeSession{session: session}
+			rec.sessions[event.roomID] = active
+		}
+		if event.at.After(active.lastAt) {
+			active.lastAt = event.at
+		}
+
+		events = append(events, &models.RoomSessionEvent{
+			SessionID: active.session.ID,
+			RoomID:    event.roomID,
+			Kind:      event.kind,
+			OffsetMS:  max(event.at.Sub(active.session.StartedAt).Milliseconds(), 0),
+			UserID:    event.userID,
+			Data:      event.data,
+		})
+	}
+
+	if err := rec.repo.AppendEvents(ctx, events); err != nil {
+		logger.Error("写入回放事件失败", zap.Int("count", len(events)), zap.Error(err))
+	}
+}
+
+// recordLocked 记录房间内的一条事件，见 keyframeLocked
+func (r *Room) recordLocked(kind string, client *Client, data []byte) {
+	if r.recorder == nil {
+		return
+	}
+	r.keyframeLocked()
+
+	var userID *uint
+	if client != nil {
+		id := client.user.ID
+		userID = &id
+	}
+	now := time.Now()
+	r.lastRecordAt = now
+	r.recorder.record(recordedEvent{roomID: r.id, kind: kind, userID: userID, data: data, at: now})
+}
+
+// keyframeLocked 按需记录完整文档和所有人的 awareness 状态，回放从最近的关键帧开始跳转
+// 距离上次记录超过会话间隔时（可能开始了新会话）也记录一次，作为会话的初始状态。
+// 修改文档前调用，关键帧是这次修改之前的状态
+func (r *Room) keyframeLocked() {
+	if r.recorder == nil || r.doc == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(r.lastRecordAt) <= r.recorder.gap && now.Sub(r.lastKeyframeAt) < r.keyframeInterval {
+		return
+	}
+	r.lastKeyframeAt = now
+	r.lastRecordAt = now
+	r.recorder.record(recordedEvent{roomID: r.id, kind: models.SessionEventKeyframe, data: r.doc.EncodeStateAsUpdate(nil), at: now})
+	if len(r.awareness) > 0 {
+		r.recorder.record(recordedEvent{roomID: r.id, kind: models.SessionEventAwareness, data: r.awarenessSnapshotLocked(), at: now})
+	}
+}
+
+// recordChatLocked 记录发给房间的聊天消息，只保存 JSON 消息体
+func (r *Room) recordChatLocked(frame *chatFrame) {
+	if r.recorder == nil {
+		return
+	}
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	r.recordLocked(models.SessionEventChat, nil, data)
+}
@@ -0,0 +1,105 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySessionRepo 内存中的回放会话，只实现记录用到的方法
+type memorySessionRepo struct {
+	repository.RoomSessionRepository
+
+	mu       sync.Mutex
+	sessions []*models.RoomSession
+	events   []*models.RoomSessionEvent
+}
+
+func (r *memorySessionRepo) Begin(_ context.Context, roomID uint, at time.Time, gap time.Duration) (*models.RoomSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, session := range r.sessions {
+		if session.RoomID == roomID && !session.LastEventAt.Before(at.Add(-gap)) {
+			return session, nil
+		}
+	}
+	session := &models.RoomSession{ID: uint(len(r.sessions) + 1), RoomID: roomID, StartedAt: at, LastEventAt: at}
+	r.sessions = append(r.sessions, session)
+	return session, nil
+}
+
+func (r *memorySessionRepo) AppendEvents(_ context.Context, events []*models.RoomSessionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *memorySessionRepo) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		kinds = append(kinds, event.Kind)
+	}
+	return kinds
+}
+
+func TestHub_RecordsSessionEvents(t *testing.T) {
+	sessions := &memorySessionRepo{}
+	hub := NewHub(Options{Sessions: sessions})
+	url := newTestServer(t, hub)
+
+	alice := dial(t, url)
+	readMessage(t, alice)
+	bob := dial(t, url)
+	readMessage(t, bob)
+
+	source := yjs.NewDoc(yjs.Options{})
+	for _, s := range []string{"a", "b"} {
+		update := source.GetText(codeTextName).Insert(source.GetText(codeTextName).Length(), s)
+		require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+		readMessage(t, bob)
+	}
+
+	// 关闭时写入队列中剩余的事件
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	hub.Shutdown(ctx)
+
+	// 第一次修改前记录一次关键帧作为会话的初始状态，之后只记录增量
+	kinds := sessions.kinds()
+	require.NotEmpty(t, kinds)
+	assert.Equal(t, models.SessionEventKeyframe, kinds[0])
+	assert.Equal(t, []string{models.SessionEventUpdate, models.SessionEventUpdate}, filterKinds(kinds, models.SessionEventUpdate))
+	assert.Len(t, filterKinds(kinds, models.SessionEventKeyframe), 1)
+	require.Len(t, sessions.sessions, 1)
+
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	for _, event := range sessions.events {
+		assert.Equal(t, sessions.sessions[0].ID, event.SessionID)
+		if event.Kind == models.SessionEventUpdate {
+			require.NotNil(t, event.UserID)
+			assert.Equal(t, uint(1), *event.UserID)
+		}
+	}
+}
+
+func filterKinds(kinds []string, kind string) []string {
+	var matched []string
+	for _, k := range kinds {
+		if k == kind {
+			matched = append(matched, k)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,771 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+const (
+	// codeTextName 编辑器代码对应的 Y.Text 名称，与前端 y-monaco 绑定的一致
+	codeTextName = models.MainTextName
+	// storeTimeout 单次读写文档存储的超时时间
+	storeTimeout = 5 * time.Second
+	// seedClientID 生成初始代码时使用的 Yjs clientID
+	seedClientID = 1
+	// remoteAwarenessTimeout 其他节点的 awareness 状态多久没有刷新就视为离线（与 y-protocols 一致）
+	remoteAwarenessTimeout = 30 * time.Second
+)
+
+// awarenessState 房间内某个 Yjs clientID 的最新 awareness 状态
+// owner 为空表示该状态来自其他节点上的连接
+type awarenessState struct {
+	clock     uint64
+	state     []byte
+	owner     *Client
+	updatedAt time.Time
+}
+
+// Room 一个协作房间
+//
+// 服务端维护一份完整的 Yjs 文档：首次使用时从数据库加载（快照 + 增量），
+// 收到的每条更新先合入文档再追加到增量日志，累计一定数量、定期或房间空闲时合并为新快照。
+// 多节点部署时通过 broker 和其他节点上的同一房间互相转发更新和 awareness。
+type Room struct {
+	uuid        string
+	id          uint
+	starterCode string
+	store       repository.DocumentRepository
+	cfg         config.DocumentConfig
+
+	nodeID      string
+	broker      Broker
+	locker      Locker
+	chat        ChatService
+	rtc         config.RTCConfig
+	versions    repository.DocumentVersionRepository
+	recorder    *recorder
+	authors     repository.DocumentAuthorRepository
+	lockRepo    repository.RoomLockRepository
+	activity    ActivityRecorder
+	events      repository.RoomEventRepository
+	unsubscribe func()
+	closeOnce   sync.Once
+	// closeRoom 从 Hub 摘除房间并断开所有连接，其他节点通知房间已归档或删除时调用
+	closeRoom func()
+	// whiteboard 是否为白板文档，见 newWhiteboardRoom；discard 其他节点通知白板已删除时调用
+	whiteboard bool
+	discard    func()
+
+	// terminalSandbox 为空表示本节点不能开启共享终端，terminalIdle 终端的空闲超时
+	terminalSandbox TerminalSandbox
+	terminalIdle    time.Duration
+
+	// activityThrottle 限制刷新活跃时间的频率，有自己的锁
+	activityThrottle *activityThrottle
+
+	mu        sync.Mutex
+	clients   map[*Client]struct{}
+	awareness map[uint64]*awarenessState
+	// voice 语音频道的参与者，按 peerID 索引，包括其他节点上的连接
+	voice map[string]*voicePeer
+	// presenter 当前演示者，presenterAt 最后一次修改的时间，presenterView 最近一次视图消息
+	presenter     *presenterState
+	presenterAt   time.Time
+	presenterView []byte
+
+	doc *yjs.Doc // 为空表示尚未加载
+	// texts 文档的纯文本视图，按 Y.Text 名称缓存，随每次更新增量维护，见 textLocked
+	texts map[string]string
+	// lastUpdateID 已知的最后一条增量 ID，合并时删除它及之前的增量
+	lastUpdateID uint
+	// dirty 上次合并后新增的增量条数
+	dirty int
+	// size 快照和增量的总字节数，增量中可能有已被覆盖的内容，合并后才是真实大小
+	size int
+	// lastVersionAt 最近一次检查或保存自动版本的时间
+	lastVersionAt time.Time
+	// keyframeInterval 回放关键帧的间隔，lastRecordAt、lastKeyframeAt 最近一次记录回放事件、关键帧的时间
+	keyframeInterval time.Duration
+	lastRecordAt     time.Time
+	lastKeyframeAt   time.Time
+	// claimed 已记录作者的 Yjs 客户端
+	claimed map[uint64]bool
+	// locks 锁定区域，和文档一起加载，修改后由 SetLocks 或其他节点的消息更新
+	locks []*models.RoomLock
+	// terminal 本节点运行的共享终端；terminalState 房间当前的终端，可能运行在其他节点上；
+	// terminalOutput 终端最近的输出
+	terminal       *terminalSession
+	terminalState  *terminalState
+	terminalOutput []byte
+}
+
+func newRoom(room *models.Room, h *Hub) *Room {
+	r := &Room{
+		uuid:        room.UUID,
+		id:          room.ID,
+		starterCode: room.StarterCode,
+		store:       h.opts.Documents,
+		cfg:         h.opts.Document,
+		nodeID:      h.nodeID,
+		broker:      h.opts.Broker,
+		locker:      h.opts.Locker,
+		chat:        h.opts.Chat,
+		rtc:         h.opts.RTC,
+		versions:    h.opts.Versions,
+		recorder:    h.recorder,
+		authors:     h.opts.Authors,
+		lockRepo:    h.opts.Locks,
+		activity:    h.opts.Activity,
+		events:      h.opts.Events,
+		clients:     make(map[*Client]struct{}),
+		awareness:   make(map[uint64]*awarenessState),
+		voice:       make(map[string]*voicePeer),
+		claimed:     make(map[uint64]bool),
+		texts:       make(map[string]string),
+
+		keyframeInterval: h.keyframeInterval,
+		activityThrottle: newActivityThrottle(activityInterval),
+		terminalIdle:     time.Duration(h.opts.TerminalConfig.IdleTimeoutSeconds) * time.Second,
+	}
+	r.closeRoom = func() { h.closeRoom(r.uuid) }
+	if h.opts.TerminalConfig.Enabled {
+		r.terminalSandbox = h.opts.Terminal
+	}
+	if r.terminalIdle <= 0 {
+		r.terminalIdle = defaultTerminalIdleTimeout
+	}
+	return r
+}
+
+// subscribe 订阅其他节点转发的房间消息
+// 必须在加载文档之前订阅：其他节点先写库再发布，订阅前发布的更新一定能从数据库读到
+func (r *Room) subscribe() {
+	if r.broker == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	unsubscribe, err := r.broker.Subscribe(ctx, r.uuid, r.handleRemote, r.resync)
+	if err != nil {
+		logger.Error("订阅协作房间失败，仅同步本节点的连接", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	r.unsubscribe = unsubscribe
+	if r.whiteboard {
+		r.subscribeParent(ctx)
+		return
+	}
+	r.queryRemoteVoice()
+	r.queryRemotePresenter()
+	r.queryRemoteTerminal()
+}
+
+// subscribeParent 白板文档订阅所属房间的频道，接收成员被移出和房间关闭的通知
+func (r *Room) subscribeParent(ctx context.Context) {
+	unsubscribe, err := r.broker.Subscribe(ctx, roomOf(r.uuid), r.handleParentRemote, nil)
+	if err != nil {
+		logger.Error("订阅白板所属房间失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	unsubscribeBoard := r.unsubscribe
+	r.unsubscribe = func() {
+		unsubscribeBoard()
+		unsubscribe()
+	}
+}
+
+// close 房间回收：关闭共享终端、退订并把文档合并为快照，可以重复调用
+func (r *Room) close() {
+	r.closeOnce.Do(func() {
+		r.closeTerminal()
+		if r.unsubscribe != nil {
+			r.unsubscribe()
+		}
+		r.compact()
+	})
+}
+
+// drain 用关闭码断开所有连接并回收房间，客户端重连后由新的节点接管
+// 每条更新在广播前已经写库，断开前未处理的修改会在客户端重连同步时重新发送
+func (r *Room) drain(code int, reason string) {
+	r.mu.Lock()
+	r.releaseLocalPresenterLocked()
+	for client := range r.clients {
+		client.closeWith(code, reason)
+		delete(r.clients, client)
+	}
+	r.awareness = make(map[uint64]*awarenessState)
+	r.clearVoiceLocked()
+	r.mu.Unlock()
+
+	r.close()
+}
+
+// tryAdd 加入客户端，房间连接数已达上限（max > 0）时返回 false
+func (r *Room) tryAdd(client *Client, max int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if max > 0 && len(r.clients) >= max {
+		return false
+	}
+	r.clients[client] = struct{}{}
+	return true
+}
+
+// remove 移除客户端并清除它的 awareness 状态，返回房间是否已空
+func (r *Room) remove(client *Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dropLocked(client)
+	return len(r.clients) == 0
+}
+
+// dropLocked 断开客户端，并通知其他人它的光标等状态已失效
+func (r *Room) dropLocked(client *Client) {
+	if _, ok := r.clients[client]; !ok {
+		return
+	}
+	delete(r.clients, client)
+	client.close()
+	r.leaveVoiceLocked(client)
+	r.releaseIfPresenterGoneLocked(client)
+
+	if len(client.awarenessIDs) == 0 {
+		return
+	}
+	removed := make([]yjs.AwarenessEntry, 0, len(client.awarenessIDs))
+	for clientID, clock := range client.awarenessIDs {
+		if current, ok := r.awareness[clientID]; ok && current.owner == client {
+			delete(r.awareness, clientID)
+		}
+		removed = append(removed, yjs.AwarenessEntry{ClientID: clientID, Clock: clock + 1})
+	}
+	update := yjs.EncodeAwarenessUpdate(removed)
+	frame := yjs.EncodeAwarenessMessage(update)
+	r.broadcastLocked(frame, nil)
+	r.publishLocked(frame)
+	r.recordLocked(models.SessionEventAwareness, client, update)
+}
+
+// loadLocked 首次使用时加载文档，全新的房间用题目的初始代码初始化
+func (r *Room) loadLocked() error {
+	if r.doc != nil {
+		return nil
+	}
+	doc := yjs.NewDoc(yjs.Options{GC: r.cfg.GC})
+
+	found, updates := false, 0
+	if r.store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+
+		var err error
+		if found, updates, err = r.readStoreLocked(ctx, doc); err != nil {
+			return err
+		}
+	}
+	r.dirty = updates
+	r.doc = doc
+	r.loadLocksLocked()
+
+	if !found && r.starterCode != "" {
+		r.seedLocked()
+	}
+
+	logger.Debug("协作文档已加载",
+		zap.String("room_uuid", r.uuid),
+		zap.Int("size", r.size),
+		zap.Int("updates", updates))
+	return nil
+}
+
+// readStoreLocked 把存储中的快照和增量合入 doc，返回是否有已保存的内容及增量条数
+// 重复合入已有的内容是安全的，多节点合并快照前也用它追上其他节点的修改
+func (r *Room) readStoreLocked(ctx context.Context, doc *yjs.Doc) (bool, int, error) {
+	snapshot, updates, err := r.store.Load(ctx, r.id)
+	if err != nil {
+		return false, 0, err
+	}
+
+	r.size = 0
+	if snapshot != nil {
+		r.size = len(snapshot.Snapshot)
+		if err := doc.ApplyUpdate(snapshot.Snapshot); err != nil {
+			logger.Error("协作文档快照损坏", zap.String("room_uuid", r.uuid), zap.Error(err))
+		}
+	}
+	for _, update := range updates {
+		if err := doc.ApplyUpdate(update.Data); err != nil {
+			logger.Warn("跳过损坏的文档增量",
+				zap.String("room_uuid", r.uuid),
+				zap.Uint("update_id", update.ID),
+				zap.Error(err))
+		}
+		r.size += len(update.Data)
+		if update.ID > r.lastUpdateID {
+			r.lastUpdateID = update.ID
+		}
+	}
+	return snapshot != nil || len(updates) > 0, len(updates), nil
+}
+
+// resync 可能漏掉了其他节点转发的更新（Redis pub/sub 最多转发一次）：从存储中追上，
+// 把本节点缺少的部分下发给本地连接。其他节点先写库再发布，漏掉的更新一定已经在存储中；
+// awareness 等临时状态不补发，客户端下一次刷新时恢复
+func (r *Room) resync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.doc == nil || r.store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	before := r.doc.StateVector()
+	// 删除集总是完整编码，和追上之前的结果相同说明没有新内容
+	unchanged := r.doc.EncodeStateAsUpdate(before)
+	if _, _, err := r.readStoreLocked(ctx, r.doc); err != nil {
+		logger.Error("重新同步协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	missed := r.doc.EncodeStateAsUpdate(before)
+	if bytes.Equal(missed, unchanged) {
+		return
+	}
+	r.refreshTextsLocked()
+	r.broadcastLocked(yjs.EncodeUpdate(missed), nil)
+	logger.Info("协作文档已从存储中重新同步", zap.String("room_uuid", r.uuid), zap.Int("size", len(missed)))
+}
+
+// seedLocked 写入初始代码
+// 使用固定的 clientID 生成更新，多个节点同时初始化时产生的是同一条更新，合并后不会重复
+func (r *Room) seedLocked() {
+	update := yjs.NewDoc(yjs.Options{ClientID: seedClientID}).GetText(codeTextName).Insert(0, r.starterCode)
+	if err := r.doc.ApplyUpdate(update); err != nil {
+		logger.Error("写入初始代码失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+	r.persistLocked(update)
+}
+
+// ensureLoadedLocked 加载文档，失败时断开客户端
+func (r *Room) ensureLoadedLocked(client *Client) bool {
+	if err := r.loadLocked(); err != nil {
+		logger.Error("加载协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		client.closeWith(websocket.CloseInternalServerErr, "文档加载失败")
+		r.dropLocked(client)
+		return false
+	}
+	return true
+}
+
+// persistLocked 追加一条已合入文档的更新，写库失败时修改仍在内存中，下次合并时写入快照
+func (r *Room) persistLocked(update []byte) {
+	r.size += len(update)
+	r.dirty++
+	if r.store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		id, err := r.store.AppendUpdate(ctx, r.id, update)
+		cancel()
+		if err != nil {
+			logger.Error("保存文档增量失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		} else {
+			r.lastUpdateID = id
+		}
+	}
+
+	if r.cfg.CompactEveryUpdates > 0 && r.dirty >= r.cfg.CompactEveryUpdates {
+		r.compactLocked()
+	}
+}
+
+// publishLocked 把消息转发给其他节点上的同房间客户端
+func (r *Room) publishLocked(frame []byte) {
+	if r.broker == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := r.broker.Publish(ctx, r.uuid, encodeEnvelope(r.nodeID, frame)); err != nil {
+		logger.Error("转发协作消息失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+	}
+}
+
+// compact 把内存中的文档合并为新快照
+func (r *Room) compact() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compactLocked()
+}
+
+func (r *Room) compactLocked() {
+	if r.doc == nil || r.dirty == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*storeTimeout)
+	defer cancel()
+
+	if r.locker != nil && r.store != nil {
+		// 多个节点共享同一份快照：加锁后先追上其他节点已保存、但本节点还没收到的修改，
+		// 否则新快照会覆盖掉它们
+		unlock, ok, err := r.locker.TryLock(ctx, r.uuid, 2*storeTimeout)
+		if err != nil || !ok {
+			// 其他节点正在合并，保留 dirty 下次再试
+			return
+		}
+		defer unlock()
+		if _, _, err := r.readStoreLocked(ctx, r.doc); err != nil {
+			logger.Error("合并前读取协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+			return
+		}
+	}
+
+	snapshot := r.doc.EncodeStateAsUpdate(nil)
+	pending := r.doc.PendingUpdates()
+	size := len(snapshot)
+	for _, update := range pending {
+		size += len(update)
+	}
+
+	if r.store != nil {
+		lastID, err := r.store.Compact(ctx, r.id, snapshot, r.lastUpdateID, pending)
+		if err != nil {
+			logger.Error("合并协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+			return
+		}
+		r.lastUpdateID = lastID
+		r.autoVersionLocked(ctx)
+	}
+
+	logger.Debug("协作文档已合并",
+		zap.String("room_uuid", r.uuid),
+		zap.Int("merged_updates", r.dirty),
+		zap.Int("size", size))
+	r.size = size
+	r.dirty = 0
+}
+
+// exceedsLimitLocked 应用 update 后文档是否会超出大小限制
+// 只包含删除的更新总是允许，方便用户删减内容
+func (r *Room) exceedsLimitLocked(update []byte) bool {
+	limit := r.cfg.MaxSizeKB << 10
+	if limit <= 0 || r.size+len(update) <= limit {
+		return false
+	}
+	// 估算值包含已被覆盖的增量，先合并得到真实大小
+	r.compactLocked()
+	return r.size+len(update) > limit && yjs.UpdateHasStructs(update)
+}
+
+// greet 新连接建立后：请求客户端的离线修改，并下发当前在线用户
+func (r *Room) greet(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.ensureLoadedLocked(client) {
+		return
+	}
+	// 客户端回复服务端缺少的部分（例如离线期间的修改）
+	r.sendLocked(client, yjs.EncodeSyncStep1(r.doc.StateVector().Encode()))
+
+	if len(r.awareness) > 0 {
+		r.sendLocked(client, yjs.EncodeAwarenessMessage(r.awarenessSnapshotLocked()))
+	}
+	r.sendVoiceSnapshotLocked(client)
+	r.sendPresenterLocked(client)
+	r.sendTerminalLocked(client)
+}
+
+// handleMessage 处理客户端发来的一条消息，白板连接上只处理文档和 awareness
+func (r *Room) handleMessage(client *Client, data []byte) {
+	if !r.whiteboard && r.handleExtension(client, data) {
+		return
+	}
+	msg, err := yjs.ParseMessage(data)
+	if err != nil {
+		logger.Debug("无法解析的协作消息",
+			zap.String("room_uuid", r.uuid),
+			zap.Uint("user_id", client.user.ID),
+			zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.ensureLoadedLocked(client) {
+		return
+	}
+
+	switch msg.Type {
+	case yjs.MessageSync:
+		r.handleSyncLocked(client, msg)
+	case yjs.MessageAwareness:
+		r.handleAwarenessLocked(client, msg.Payload)
+	case yjs.MessageQueryAwareness:
+		r.sendLocked(client, yjs.EncodeAwarenessMessage(r.awarenessSnapshotLocked()))
+	}
+}
+
+// handleExtension 处理扩展消息，返回 false 表示不是扩展消息
+func (r *Room) handleExtension(client *Client, data []byte) bool {
+	switch {
+	case isChatFrame(data):
+		r.handleChat(client, data)
+	case isRTCFrame(data):
+		r.handleRTC(client, data)
+	case isPresenterFrame(data):
+		r.handlePresenter(client, data)
+	case isPresenterView(data):
+		r.handlePresenterView(client, data)
+	case isTerminalFrame(data):
+		r.handleTerminal(client, data)
+	default:
+		return false
+	}
+	return true
+}
+
+func (r *Room) handleSyncLocked(client *Client, msg *yjs.Message) {
+	switch msg.SubType {
+	case yjs.SyncStep1:
+		// 按客户端的状态向量只下发它缺少的部分
+		sv, err := yjs.DecodeStateVector(msg.Payload)
+		if err != nil {
+			return
+		}
+		r.sendLocked(client, yjs.EncodeSyncStep2(r.doc.EncodeStateAsUpdate(sv)))
+		// 缺少依赖、尚未合入的更新也一并下发，客户端可能已经有这些依赖
+		for _, update := range r.doc.PendingUpdates() {
+			r.sendLocked(client, yjs.EncodeUpdate(update))
+		}
+	case yjs.SyncStep2, yjs.SyncUpdate:
+		if client.readOnly || bytes.Equal(msg.Payload, yjs.EmptyUpdate) {
+			return
+		}
+		update := append([]byte(nil), msg.Payload...)
+		if lock, rejected := r.checkLocksLocked(client, update); rejected {
+			r.rejectEditLocked(client, lock)
+			return
+		}
+
+		if r.exceedsLimitLocked(update) {
+			logger.BusinessWarn("协作文档超出大小限制",
+				zap.String("room_uuid", r.uuid),
+				zap.Uint("user_id", client.user.ID),
+				zap.Int("size", r.size))
+			client.closeWith(websocket.CloseMessageTooBig, "文档大小超出限制")
+			r.dropLocked(client)
+			return
+		}
+		r.keyframeLocked()
+		states := r.clientStatesLocked(update)
+		if err := r.doc.ApplyUpdate(update); err != nil {
+			logger.Debug("丢弃无效的文档更新",
+				zap.String("room_uuid", r.uuid),
+				zap.Uint("user_id", client.user.ID),
+				zap.Error(err))
+			return
+		}
+		r.claimAuthorsLocked(client, states)
+		r.refreshTextsLocked()
+		frame := yjs.EncodeUpdate(update)
+		r.broadcastLocked(frame, client)
+		// 先写库再转发，其他节点新加载的房间不会漏掉这条更新
+		r.persistLocked(update)
+		r.publishLocked(frame)
+		r.recordLocked(models.SessionEventUpdate, client, update)
+		r.touchActivity(client.user.ID)
+	}
+}
+
+func (r *Room) handleAwarenessLocked(client *Client, payload []byte) {
+	entries, err := yjs.DecodeAwarenessUpdate(payload)
+	if err != nil {
+		return
+	}
+
+	// 只转发和记录接受的条目，伪造的状态不会到达其他连接、其他节点和回放
+	accepted := make([]yjs.AwarenessEntry, 0, len(entries))
+	for _, entry := range entries {
+		current, ok := r.awareness[entry.ClientID]
+		if ok && current.owner != client {
+			// clientID 由其他连接控制，忽略伪造的状态
+			continue
+		}
+		accepted = append(accepted, entry)
+		if entry.IsRemoved() {
+			delete(r.awareness, entry.ClientID)
+			delete(client.awarenessIDs, entry.ClientID)
+			continue
+		}
+		r.awareness[entry.ClientID] = &awarenessState{
+			clock:     entry.Clock,
+			state:     entry.State,
+			owner:     client,
+			updatedAt: time.Now(),
+		}
+		client.awarenessIDs[entry.ClientID] = entry.Clock
+	}
+	if len(accepted) == 0 {
+		return
+	}
+	if len(accepted) < len(entries) {
+		payload = yjs.EncodeAwarenessUpdate(accepted)
+	}
+
+	frame := yjs.EncodeAwarenessMessage(payload)
+	r.broadcastLocked(frame, client)
+	r.publishLocked(frame)
+	r.recordLocked(models.SessionEventAwareness, client, payload)
+}
+
+// handleRemote 处理其他节点转发的消息，自己发布的消息会被 Redis 回送，直接忽略
+func (r *Room) handleRemote(data []byte) {
+	nodeID, frame, err := decodeEnvelope(data)
+	if err != nil || nodeID == r.nodeID {
+		return
+	}
+	if isChatFrame(frame) || isFilesFrame(frame) || isCommentsFrame(frame) || isWhiteboardsFrame(frame) {
+		// 来源节点已经写库，原样推送给本节点的连接
+		r.mu.Lock()
+		r.broadcastLocked(frame, nil)
+		r.mu.Unlock()
+		return
+	}
+	if isRTCFrame(frame) {
+		r.handleRemoteRTC(frame)
+		return
+	}
+	if isPresenterFrame(frame) {
+		r.handleRemotePresenter(frame)
+		return
+	}
+	if isPresenterView(frame) {
+		r.handleRemotePresenterView(frame)
+		return
+	}
+	if isLocksFrame(frame) {
+		r.handleRemoteLocks(frame)
+		return
+	}
+	if isTerminalFrame(frame) {
+		r.handleRemoteTerminal(frame)
+		return
+	}
+	if isTerminalOutput(frame) {
+		r.handleRemoteTerminalOutput(frame)
+		return
+	}
+	if isMembersFrame(frame) {
+		r.handleRemoteMembers(frame)
+		return
+	}
+	msg, err := yjs.ParseMessage(frame)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		logger.Error("加载协作文档失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		return
+	}
+
+	switch msg.Type {
+	case yjs.MessageSync:
+		if msg.SubType == yjs.SyncStep1 {
+			return
+		}
+		// 来源节点已经写库，这里只合入内存文档
+		if err := r.doc.ApplyUpdate(msg.Payload); err != nil {
+			return
+		}
+		r.refreshTextsLocked()
+		r.size += len(msg.Payload)
+	case yjs.MessageAwareness:
+		r.applyRemoteAwarenessLocked(msg.Payload)
+	}
+	r.broadcastLocked(frame, nil)
+}
+
+// applyRemoteAwarenessLocked 记录其他节点上连接的 awareness 状态，供本节点新连接获取快照
+func (r *Room) applyRemoteAwarenessLocked(payload []byte) {
+	entries, err := yjs.DecodeAwarenessUpdate(payload)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if current, ok := r.awareness[entry.ClientID]; ok && current.owner != nil {
+			continue
+		}
+		if entry.IsRemoved() {
+			delete(r.awareness, entry.ClientID)
+			continue
+		}
+		r.awareness[entry.ClientID] = &awarenessState{
+			clock:     entry.Clock,
+			state:     entry.State,
+			updatedAt: time.Now(),
+		}
+	}
+}
+
+// awarenessSnapshotLocked 当前所有在线客户端的 awareness 状态
+func (r *Room) awarenessSnapshotLocked() []byte {
+	entries := make([]yjs.AwarenessEntry, 0, len(r.awareness))
+	for clientID, state := range r.awareness {
+		// 其他节点宕机时不会发送移除通知，超时的状态直接丢弃
+		if state.owner == nil && time.Since(state.updatedAt) > remoteAwarenessTimeout {
+			delete(r.awareness, clientID)
+			continue
+		}
+		entries = append(entries, yjs.AwarenessEntry{
+			ClientID: clientID,
+			Clock:    state.clock,
+			State:    state.state,
+		})
+	}
+	return yjs.EncodeAwarenessUpdate(entries)
+}
+
+// sendLocked 发送给单个客户端，发送队列满则断开该客户端
+func (r *Room) sendLocked(client *Client, data []byte) {
+	if _, ok := r.clients[client]; !ok {
+		return
+	}
+	if !client.enqueue(data) {
+		// 光标等临时状态丢了没关系，客户端下一次刷新会补上；文档更新不能丢，只能断开重新同步
+		if len(data) > 0 && (data[0] == yjs.MessageAwareness || data[0] == messagePresenterView || data[0] == messageTerminalOutput) {
+			return
+		}
+		logger.Warn("协作连接发送队列已满，断开连接",
+			zap.String("room_uuid", r.uuid),
+			zap.Uint("user_id", client.user.ID))
+		r.dropLocked(client)
+	}
+}
+
+// broadcastLocked 发送给房间内除 except 以外的所有客户端
+func (r *Room) broadcastLocked(data []byte, except *Client) {
+	for client := range r.clients {
+		if client != except {
+			r.sendLocked(client, data)
+		}
+	}
+}
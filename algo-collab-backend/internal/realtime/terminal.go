@@ -0,0 +1,614 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// TerminalSize 终端的列数和行数
+type TerminalSize struct {
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
+
+// TerminalSandbox 在代码执行沙箱中为房间创建 PTY 会话
+// 实现需要自行限制 CPU、内存、网络和文件系统；sandbox.Terminal 按配置的命令（例如 docker run）启动 shell
+type TerminalSandbox interface {
+	Open(ctx context.Context, roomUUID string, size TerminalSize) (TerminalProcess, error)
+}
+
+// TerminalProcess 一个 PTY 会话：读到的是终端输出，写入的是键盘输入
+// Close 结束会话，之后 Read 返回错误
+type TerminalProcess interface {
+	io.ReadWriteCloser
+	Resize(size TerminalSize) error
+}
+
+// 终端的控制模式
+const (
+	terminalModeOwner    = "owner"    // 只有开启终端的成员可以输入
+	terminalModeEveryone = "everyone" // 所有可编辑的成员都可以输入
+	terminalModeGrant    = "grant"    // 开启者和被授权的成员可以输入，其他人可以申请
+)
+
+// terminalFrame 的类型
+//
+// 房间同一时间最多一个共享终端，运行在开启它的节点上，其他节点转发控制消息和输出。
+// 开启者和管理员及以上可以修改控制模式、授权和关闭终端；只有开启者可以调整终端大小。
+const (
+	terminalFrameStart   = "start"   // 客户端 → 服务端：开启终端，mode 和 size 可选
+	terminalFrameInput   = "input"   // 客户端 → 服务端：键盘输入
+	terminalFrameResize  = "resize"  // 客户端 → 服务端：调整终端大小
+	terminalFrameMode    = "mode"    // 客户端 → 服务端：修改控制模式
+	terminalFrameRequest = "request" // 客户端 → 服务端：grant 模式下申请输入权限
+	terminalFrameGrant   = "grant"   // 客户端 → 服务端：授予 user_id 输入权限
+	terminalFrameRevoke  = "revoke"  // 客户端 → 服务端：收回 user_id 的输入权限或拒绝申请
+	terminalFrameStop    = "stop"    // 客户端 → 服务端：关闭终端
+
+	terminalFrameState  = "state"  // 服务端 → 客户端：当前终端，terminal 为空表示已关闭，reason 为关闭原因
+	terminalFrameOutput = "output" // 服务端 → 客户端：终端输出，见 messageTerminalOutput
+	terminalFrameError  = "error"  // 服务端 → 客户端：操作失败；节点之间按 user_id 投递
+
+	// terminalFrameQuery 节点之间：新建的房间请求运行终端的节点重新发布状态
+	terminalFrameQuery = "query"
+)
+
+// 终端关闭的原因
+const (
+	terminalStopUser   = "stopped" // 开启者或管理员关闭
+	terminalStopExited = "exited"  // shell 退出
+	terminalStopIdle   = "idle"    // 长时间没有输入输出
+	terminalStopClosed = "closed"  // 房间回收
+)
+
+const (
+	defaultTerminalIdleTimeout = 10 * time.Minute
+	// terminalOpenTimeout 沙箱创建 PTY 的超时时间
+	terminalOpenTimeout = 30 * time.Second
+	// terminalReadSize 每条输出消息最多的字节数
+	terminalReadSize = 4096
+	// terminalMaxInput 每条输入消息最多的字节数，超出的直接丢弃
+	terminalMaxInput = 4096
+	// terminalScrollback 保留最近多少字节的输出，新连接据此恢复屏幕
+	terminalScrollback = 64 << 10
+)
+
+var (
+	errTerminalUnavailable = errors.New("共享终端未开启")
+	errTerminalReadOnly    = errors.New("房间已归档，不能使用终端")
+	errTerminalRunning     = errors.New("终端已在运行")
+	errTerminalStarting    = errors.New("终端正在启动")
+	errTerminalNone        = errors.New("终端未开启")
+	errTerminalForbidden   = errors.New("没有权限操作终端")
+	errTerminalNoInput     = errors.New("没有终端的输入权限")
+	errTerminalMode        = errors.New("无效的控制模式")
+	errTerminalOpen        = errors.New("终端启动失败")
+)
+
+// terminalState 当前终端
+type terminalState struct {
+	OwnerID   uint   `json:"owner_id"`
+	OwnerName string `json:"owner_name"`
+	Mode      string `json:"mode"`
+	// Writers grant 模式下被授予输入权限的成员，Requests 正在申请的成员
+	Writers   []uint       `json:"writers"`
+	Requests  []uint       `json:"requests"`
+	Size      TerminalSize `json:"size"`
+	StartedAt time.Time    `json:"started_at"`
+}
+
+// terminalActor 发送控制消息的成员
+type terminalActor struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// terminalFrame 终端消息的 JSON 结构
+type terminalFrame struct {
+	Type string        `json:"type"`
+	Mode string        `json:"mode,omitempty"`
+	Size *TerminalSize `json:"size,omitempty"`
+	// Data input 为键盘输入，output 为终端输出（JSON 中为 base64）
+	Data []byte `json:"data,omitempty"`
+	// UserID grant、revoke 的对象，error 的接收者
+	UserID   uint           `json:"user_id,omitempty"`
+	Terminal *terminalState `json:"terminal,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	// From 发送控制消息的成员，由收到消息的节点填写，客户端填写的会被覆盖
+	From *terminalActor `json:"from,omitempty"`
+}
+
+// terminalSession 本节点运行的终端，process 为空表示沙箱正在创建
+type terminalSession struct {
+	process    TerminalProcess
+	state      *terminalState
+	lastActive time.Time
+	idle       *time.Timer
+}
+
+func isTerminalFrame(data []byte) bool {
+	return isMessageType(data, messageTerminal)
+}
+
+func isTerminalOutput(data []byte) bool {
+	return isMessageType(data, messageTerminalOutput)
+}
+
+func encodeTerminalFrame(frame *terminalFrame) []byte {
+	return encodeJSONMessage(messageTerminal, frame)
+}
+
+// handleTerminal 处理客户端发来的终端消息
+func (r *Room) handleTerminal(client *Client, data []byte) {
+	var frame terminalFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	if _, ok := r.clients[client]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	// 角色可能被修改，在锁内读取
+	frame.From = &terminalActor{
+		UserID:   client.user.ID,
+		Username: client.user.Username,
+		Role:     client.user.Role,
+		ReadOnly: client.readOnly,
+	}
+	after, err := r.applyTerminalLocked(&frame)
+	if err != nil {
+		r.sendLocked(client, encodeTerminalFrame(&terminalFrame{Type: terminalFrameError, Terminal: r.terminalState, Error: err.Error()}))
+	}
+	r.mu.Unlock()
+
+	if after != nil {
+		after()
+	}
+}
+
+// applyTerminalLocked 执行一条控制消息，返回需要在锁外执行的操作（写入沙箱可能阻塞）
+func (r *Room) applyTerminalLocked(frame *terminalFrame) (func(), error) {
+	if !isTerminalControl(frame.Type) {
+		return nil, nil
+	}
+	actor := frame.From
+	if r.terminal == nil && r.terminalState != nil {
+		// 终端运行在其他节点上，交给它处理
+		r.publishLocked(encodeTerminalFrame(frame))
+		return nil, nil
+	}
+	if frame.Type == terminalFrameStart {
+		return r.startTerminalLocked(actor, frame)
+	}
+
+	session := r.terminal
+	if session == nil {
+		return nil, errTerminalNone
+	}
+	state := session.state
+	var after func()
+	switch frame.Type {
+	case terminalFrameInput:
+		if !canTypeTerminal(state, actor) {
+			return nil, errTerminalNoInput
+		}
+		if session.process == nil {
+			return nil, errTerminalStarting
+		}
+		if len(frame.Data) == 0 || len(frame.Data) > terminalMaxInput {
+			return nil, nil
+		}
+		session.lastActive = time.Now()
+		process, input := session.process, frame.Data
+		return func() {
+			if _, err := process.Write(input); err != nil {
+				logger.Debug("写入共享终端失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+			}
+		}, nil
+	case terminalFrameResize:
+		if actor.UserID != state.OwnerID {
+			return nil, errTerminalForbidden
+		}
+		if session.process == nil || frame.Size == nil || frame.Size.Cols == 0 || frame.Size.Rows == 0 || *frame.Size == state.Size {
+			return nil, nil
+		}
+		state.Size = *frame.Size
+		process, size := session.process, state.Size
+		after = func() {
+			if err := process.Resize(size); err != nil {
+				logger.Debug("调整共享终端大小失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+			}
+		}
+	case terminalFrameMode:
+		if !canManageTerminal(state, actor) {
+			return nil, errTerminalForbidden
+		}
+		if !validTerminalMode(frame.Mode) {
+			return nil, errTerminalMode
+		}
+		state.Mode = frame.Mode
+		if state.Mode != terminalModeGrant {
+			state.Writers, state.Requests = nil, nil
+		}
+	case terminalFrameRequest:
+		if actor.ReadOnly {
+			return nil, errTerminalReadOnly
+		}
+		if state.Mode != terminalModeGrant || canTypeTerminal(state, actor) || slices.Contains(state.Requests, actor.UserID) {
+			return nil, nil
+		}
+		state.Requests = append(state.Requests, actor.UserID)
+	case terminalFrameGrant:
+		if !canManageTerminal(state, actor) {
+			return nil, errTerminalForbidden
+		}
+		if state.Mode != terminalModeGrant {
+			return nil, errTerminalMode
+		}
+		state.Requests = removeUserID(state.Requests, frame.UserID)
+		if frame.UserID != state.OwnerID && !slices.Contains(state.Writers, frame.UserID) {
+			state.Writers = append(state.Writers, frame.UserID)
+		}
+	case terminalFrameRevoke:
+		if !canManageTerminal(state, actor) {
+			return nil, errTerminalForbidden
+		}
+		state.Requests = removeUserID(state.Requests, frame.UserID)
+		state.Writers = removeUserID(state.Writers, frame.UserID)
+	case terminalFrameStop:
+		if !canManageTerminal(state, actor) {
+			return nil, errTerminalForbidden
+		}
+		r.stopTerminalLocked(terminalStopUser)
+		return nil, nil
+	}
+	if session.process != nil {
+		r.setTerminalStateLocked(state, "")
+	}
+	return after, nil
+}
+
+// startTerminalLocked 开启终端，沙箱在锁外异步创建，创建完成后才通知所有人
+// 广播模式下两个节点同时开启时会各自运行一个终端，以各节点本地的为准
+func (r *Room) startTerminalLocked(actor *terminalActor, frame *terminalFrame) (func(), error) {
+	if actor.ReadOnly {
+		return nil, errTerminalReadOnly
+	}
+	if r.terminalSandbox == nil {
+		return nil, errTerminalUnavailable
+	}
+	if r.terminal != nil {
+		if r.terminal.process == nil {
+			return nil, errTerminalStarting
+		}
+		return nil, errTerminalRunning
+	}
+	mode := frame.Mode
+	if mode == "" {
+		mode = terminalModeOwner
+	}
+	if !validTerminalMode(mode) {
+		return nil, errTerminalMode
+	}
+	size := TerminalSize{Cols: 80, Rows: 24}
+	if frame.Size != nil && frame.Size.Cols > 0 && frame.Size.Rows > 0 {
+		size = *frame.Size
+	}
+
+	session := &terminalSession{state: &terminalState{
+		OwnerID:   actor.UserID,
+		OwnerName: actor.Username,
+		Mode:      mode,
+		Size:      size,
+		StartedAt: time.Now(),
+	}}
+	r.terminal = session
+	sandbox := r.terminalSandbox
+	return func() { go r.openTerminal(sandbox, session) }, nil
+}
+
+// openTerminal 在沙箱中创建 PTY，成功后开始转发输出
+func (r *Room) openTerminal(sandbox TerminalSandbox, session *terminalSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), terminalOpenTimeout)
+	process, err := sandbox.Open(ctx, r.uuid, session.state.Size)
+	cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		logger.Error("开启共享终端失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		if r.terminal == session {
+			r.terminal = nil
+		}
+		r.notifyTerminalErrorLocked(session.state.OwnerID, errTerminalOpen)
+		return
+	}
+	if r.terminal != session {
+		// 创建期间房间已被回收
+		_ = process.Close()
+		return
+	}
+	session.process = process
+	session.lastActive = time.Now()
+	session.idle = time.AfterFunc(r.terminalIdle, func() { r.checkTerminalIdle(session) })
+	r.terminalOutput = nil
+	r.setTerminalStateLocked(session.state, "")
+	logger.Info("共享终端已开启",
+		zap.String("room_uuid", r.uuid),
+		zap.Uint("user_id", session.state.OwnerID),
+		zap.String("mode", session.state.Mode))
+	r.recordCodeRun(session.state)
+
+	go r.pumpTerminal(session)
+}
+
+// recordCodeRun 把开启终端记入房间审计日志，写库在后台进行
+func (r *Room) recordCodeRun(state *terminalState) {
+	if r.events == nil {
+		return
+	}
+	ownerID := state.OwnerID
+	event := &models.RoomEvent{
+		RoomID:  r.id,
+		ActorID: &ownerID,
+		Type:    models.RoomEventCodeRun,
+		Data:    models.JSONMap{"mode": state.Mode},
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		if err := r.events.Create(ctx, event); err != nil {
+			logger.Warn("写入房间事件失败", zap.String("room_uuid", r.uuid), zap.String("type", event.Type), zap.Error(err))
+		}
+	}()
+}
+
+// pumpTerminal 把终端输出转发给所有连接，shell 退出后关闭终端
+func (r *Room) pumpTerminal(session *terminalSession) {
+	buf := make([]byte, terminalReadSize)
+	for {
+		n, err := session.process.Read(buf)
+		if n > 0 {
+			r.mu.Lock()
+			if r.terminal != session {
+				r.mu.Unlock()
+				return
+			}
+			session.lastActive = time.Now()
+			output := bytes.Clone(buf[:n])
+			r.appendTerminalOutputLocked(output)
+			r.emitTerminalLocked(messageTerminalOutput, &terminalFrame{Type: terminalFrameOutput, Data: output})
+			r.mu.Unlock()
+		}
+		if err != nil {
+			r.mu.Lock()
+			if r.terminal == session {
+				r.stopTerminalLocked(terminalStopExited)
+			}
+			r.mu.Unlock()
+			return
+		}
+	}
+}
+
+// checkTerminalIdle 空闲超时后关闭终端，期间有输入输出则重新计时
+func (r *Room) checkTerminalIdle(session *terminalSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.terminal != session {
+		return
+	}
+	if idle := time.Since(session.lastActive); idle < r.terminalIdle {
+		session.idle.Reset(r.terminalIdle - idle)
+		return
+	}
+	r.stopTerminalLocked(terminalStopIdle)
+}
+
+// stopTerminalLocked 关闭本节点运行的终端并通知所有人
+func (r *Room) stopTerminalLocked(reason string) {
+	session := r.terminal
+	if session == nil {
+		return
+	}
+	r.terminal = nil
+	if session.idle != nil {
+		session.idle.Stop()
+	}
+	if session.process == nil {
+		// 还没有通知过其他人，创建完成后由 openTerminal 关闭
+		return
+	}
+	process := session.process
+	go func() {
+		if err := process.Close(); err != nil {
+			logger.Warn("关闭共享终端失败", zap.String("room_uuid", r.uuid), zap.Error(err))
+		}
+	}()
+	r.terminalOutput = nil
+	r.setTerminalStateLocked(nil, reason)
+	logger.Info("共享终端已关闭", zap.String("room_uuid", r.uuid), zap.String("reason", reason))
+}
+
+// setTerminalStateLocked 修改当前终端并通知本节点的连接和其他节点
+func (r *Room) setTerminalStateLocked(state *terminalState, reason string) {
+	r.terminalState = state
+	r.emitTerminalLocked(messageTerminal, &terminalFrame{Type: terminalFrameState, Terminal: state, Reason: reason})
+}
+
+// emitTerminalLocked 发送给所有节点上的连接，并记录到回放
+func (r *Room) emitTerminalLocked(msgType uint64, frame *terminalFrame) {
+	payload, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	data := encodeJSONMessage(msgType, json.RawMessage(payload))
+	r.broadcastLocked(data, nil)
+	r.publishLocked(data)
+	r.recordLocked(models.SessionEventTerminal, nil, payload)
+}
+
+// appendTerminalOutputLocked 保留最近的输出
+func (r *Room) appendTerminalOutputLocked(output []byte) {
+	r.terminalOutput = append(r.terminalOutput, output...)
+	if n := len(r.terminalOutput); n > terminalScrollback {
+		r.terminalOutput = bytes.Clone(r.terminalOutput[n-terminalScrollback:])
+	}
+}
+
+// notifyTerminalErrorLocked 通知 userID 在所有节点上的连接操作失败
+func (r *Room) notifyTerminalErrorLocked(userID uint, err error) {
+	data := encodeTerminalFrame(&terminalFrame{Type: terminalFrameError, UserID: userID, Terminal: r.terminalState, Error: err.Error()})
+	r.sendToUserLocked(userID, data)
+	r.publishLocked(data)
+}
+
+func (r *Room) sendToUserLocked(userID uint, data []byte) {
+	for client := range r.clients {
+		if client.user.ID == userID {
+			r.sendLocked(client, data)
+		}
+	}
+}
+
+// sendTerminalLocked 新连接建立时下发当前终端和最近的输出
+func (r *Room) sendTerminalLocked(client *Client) {
+	if r.terminalState == nil {
+		return
+	}
+	r.sendLocked(client, encodeTerminalFrame(&terminalFrame{Type: terminalFrameState, Terminal: r.terminalState}))
+	if len(r.terminalOutput) > 0 {
+		r.sendLocked(client, encodeJSONMessage(messageTerminalOutput, &terminalFrame{Type: terminalFrameOutput, Data: r.terminalOutput}))
+	}
+}
+
+// queryRemoteTerminal 新建的房间向其他节点请求当前终端
+func (r *Room) queryRemoteTerminal() {
+	if r.broker == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publishLocked(encodeTerminalFrame(&terminalFrame{Type: terminalFrameQuery}))
+}
+
+// closeTerminal 房间回收时关闭本节点运行的终端
+func (r *Room) closeTerminal() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTerminalLocked(terminalStopClosed)
+}
+
+// handleRemoteTerminal 处理其他节点转发的终端消息
+func (r *Room) handleRemoteTerminal(data []byte) {
+	var frame terminalFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	var after func()
+	switch frame.Type {
+	case terminalFrameState:
+		// 本节点运行着终端时以本节点的为准
+		if r.terminal != nil {
+			break
+		}
+		if frame.Terminal == nil || r.terminalState == nil || !frame.Terminal.StartedAt.Equal(r.terminalState.StartedAt) {
+			r.terminalOutput = nil
+		}
+		r.terminalState = frame.Terminal
+		r.broadcastLocked(data, nil)
+	case terminalFrameError:
+		r.sendToUserLocked(frame.UserID, data)
+	case terminalFrameQuery:
+		if r.terminal != nil && r.terminal.process != nil {
+			r.publishLocked(encodeTerminalFrame(&terminalFrame{Type: terminalFrameState, Terminal: r.terminalState}))
+		}
+	default:
+		// 其他节点上的成员发来的控制消息，权限按发起节点填写的成员校验
+		if r.terminal == nil || frame.From == nil || frame.Type == terminalFrameStart {
+			break
+		}
+		var err error
+		if after, err = r.applyTerminalLocked(&frame); err != nil {
+			r.notifyTerminalErrorLocked(frame.From.UserID, err)
+		}
+	}
+	r.mu.Unlock()
+
+	if after != nil {
+		after()
+	}
+}
+
+// handleRemoteTerminalOutput 其他节点上运行的终端的输出
+func (r *Room) handleRemoteTerminalOutput(data []byte) {
+	var frame terminalFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.terminal != nil || r.terminalState == nil {
+		return
+	}
+	r.appendTerminalOutputLocked(frame.Data)
+	r.broadcastLocked(data, nil)
+}
+
+// canTypeTerminal 成员能否向终端输入
+func canTypeTerminal(state *terminalState, actor *terminalActor) bool {
+	if actor.ReadOnly {
+		return false
+	}
+	switch state.Mode {
+	case terminalModeEveryone:
+		return true
+	case terminalModeGrant:
+		return actor.UserID == state.OwnerID || slices.Contains(state.Writers, actor.UserID)
+	default:
+		return actor.UserID == state.OwnerID
+	}
+}
+
+// canManageTerminal 开启者和管理员及以上可以修改控制模式、授权和关闭终端
+func canManageTerminal(state *terminalState, actor *terminalActor) bool {
+	return actor.UserID == state.OwnerID || models.RoomRoleAtLeast(actor.Role, models.RoomRoleAdmin)
+}
+
+// isTerminalControl 是否为客户端可以发送的控制消息
+func isTerminalControl(frameType string) bool {
+	switch frameType {
+	case terminalFrameStart, terminalFrameInput, terminalFrameResize, terminalFrameMode,
+		terminalFrameRequest, terminalFrameGrant, terminalFrameRevoke, terminalFrameStop:
+		return true
+	}
+	return false
+}
+
+func validTerminalMode(mode string) bool {
+	return mode == terminalModeOwner || mode == terminalModeEveryone || mode == terminalModeGrant
+}
+
+func removeUserID(ids []uint, id uint) []uint {
+	return slices.DeleteFunc(ids, func(v uint) bool { return v == id })
+}
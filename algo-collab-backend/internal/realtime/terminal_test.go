@@ -0,0 +1,241 @@
+package realtime
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoTerminal 把输入原样作为输出的终端
+type echoTerminal struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	mu   sync.Mutex
+	size TerminalSize
+}
+
+func (e *echoTerminal) Read(p []byte) (int, error)  { return e.reader.Read(p) }
+func (e *echoTerminal) Write(p []byte) (int, error) { return e.writer.Write(p) }
+func (e *echoTerminal) Close() error                { return e.writer.Close() }
+
+func (e *echoTerminal) Resize(size TerminalSize) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.size = size
+	return nil
+}
+
+type echoSandbox struct {
+	mu     sync.Mutex
+	opened []*echoTerminal
+}
+
+func (s *echoSandbox) Open(_ context.Context, _ string, size TerminalSize) (TerminalProcess, error) {
+	reader, writer := io.Pipe()
+	terminal := &echoTerminal{reader: reader, writer: writer, size: size}
+	s.mu.Lock()
+	s.opened = append(s.opened, terminal)
+	s.mu.Unlock()
+	return terminal, nil
+}
+
+func readTerminal(t *testing.T, conn *websocket.Conn) *terminalFrame {
+	var frame terminalFrame
+	readExtension(t, conn, messageTerminal, &frame)
+	return &frame
+}
+
+func readTerminalOutput(t *testing.T, conn *websocket.Conn) string {
+	var frame terminalFrame
+	readExtension(t, conn, messageTerminalOutput, &frame)
+	return string(frame.Data)
+}
+
+// readTerminalAll 每个连接读取下一条终端控制消息，返回第一个连接收到的
+func readTerminalAll(t *testing.T, conns ...*websocket.Conn) *terminalFrame {
+	frame := readTerminal(t, conns[0])
+	for _, conn := range conns[1:] {
+		assert.Equal(t, frame, readTerminal(t, conn))
+	}
+	return frame
+}
+
+func readTerminalOutputAll(t *testing.T, conns ...*websocket.Conn) string {
+	output := readTerminalOutput(t, conns[0])
+	for _, conn := range conns[1:] {
+		assert.Equal(t, output, readTerminalOutput(t, conn))
+	}
+	return output
+}
+
+func sendTerminal(t *testing.T, conn *websocket.Conn, frame *terminalFrame) {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, encodeTerminalFrame(frame)))
+}
+
+func TestHub_TerminalControlModes(t *testing.T) {
+	sandbox := &echoSandbox{}
+	url := newTestServer(t, NewHub(Options{Terminal: sandbox, TerminalConfig: config.TerminalConfig{Enabled: true}}))
+
+	alice := dial(t, url+"?user=1&role=member")
+	bob := dial(t, url+"?user=2&role=member")
+	carol := dial(t, url+"?user=3&role=admin")
+	viewer := dial(t, url+"?user=4&role=member&readonly=1")
+
+	// 1. 只读连接不能开启终端，成员开启后所有人收到状态
+	sendTerminal(t, viewer, &terminalFrame{Type: terminalFrameStart})
+	assert.Equal(t, errTerminalReadOnly.Error(), readTerminal(t, viewer).Error)
+
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameStart, Size: &TerminalSize{Cols: 120, Rows: 30}})
+	for _, conn := range []*websocket.Conn{alice, bob, carol, viewer} {
+		frame := readTerminal(t, conn)
+		assert.Equal(t, terminalFrameState, frame.Type)
+		require.NotNil(t, frame.Terminal)
+		assert.Equal(t, uint(1), frame.Terminal.OwnerID)
+		assert.Equal(t, terminalModeOwner, frame.Terminal.Mode)
+		assert.Equal(t, TerminalSize{Cols: 120, Rows: 30}, frame.Terminal.Size)
+	}
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameStart})
+	assert.Equal(t, errTerminalRunning.Error(), readTerminal(t, bob).Error)
+
+	// 2. owner 模式下只有开启者可以输入，输出推送给所有人
+	all := []*websocket.Conn{alice, bob, carol, viewer}
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameInput, Data: []byte("rm -rf /\n")})
+	assert.Equal(t, errTerminalNoInput.Error(), readTerminal(t, bob).Error)
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameInput, Data: []byte("go version\n")})
+	assert.Equal(t, "go version\n", readTerminalOutputAll(t, all...))
+
+	// 3. grant 模式：成员申请，开启者授权后可以输入，收回后不能
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameMode, Mode: terminalModeEveryone})
+	assert.Equal(t, errTerminalForbidden.Error(), readTerminal(t, bob).Error)
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameMode, Mode: terminalModeGrant})
+	assert.Equal(t, terminalModeGrant, readTerminalAll(t, all...).Terminal.Mode)
+
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameRequest})
+	assert.Equal(t, []uint{2}, readTerminalAll(t, all...).Terminal.Requests)
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameGrant, UserID: 2})
+	state := readTerminalAll(t, all...).Terminal
+	assert.Empty(t, state.Requests)
+	assert.Equal(t, []uint{2}, state.Writers)
+
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameInput, Data: []byte("time ./a.out\n")})
+	assert.Equal(t, "time ./a.out\n", readTerminalOutputAll(t, all...))
+
+	// 管理员可以管理别人开启的终端
+	sendTerminal(t, carol, &terminalFrame{Type: terminalFrameRevoke, UserID: 2})
+	assert.Empty(t, readTerminalAll(t, all...).Terminal.Writers)
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameInput, Data: []byte("ls\n")})
+	assert.Equal(t, errTerminalNoInput.Error(), readTerminal(t, bob).Error)
+
+	// 4. everyone 模式下可编辑的成员都可以输入，只读连接仍然不能
+	sendTerminal(t, carol, &terminalFrame{Type: terminalFrameMode, Mode: terminalModeEveryone})
+	assert.Equal(t, terminalModeEveryone, readTerminalAll(t, all...).Terminal.Mode)
+	sendTerminal(t, viewer, &terminalFrame{Type: terminalFrameInput, Data: []byte("whoami\n")})
+	assert.Equal(t, errTerminalNoInput.Error(), readTerminal(t, viewer).Error)
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameInput, Data: []byte("ls\n")})
+	assert.Equal(t, "ls\n", readTerminalOutputAll(t, all...))
+
+	// 5. 新连接收到当前终端和最近的输出
+	dave := dial(t, url+"?user=5&role=member")
+	all = append(all, dave)
+	assert.Equal(t, terminalModeEveryone, readTerminal(t, dave).Terminal.Mode)
+	assert.Equal(t, "go version\ntime ./a.out\nls\n", readTerminalOutput(t, dave))
+
+	// 6. 只有开启者可以调整大小
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameResize, Size: &TerminalSize{Cols: 40, Rows: 10}})
+	assert.Equal(t, errTerminalForbidden.Error(), readTerminal(t, bob).Error)
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameResize, Size: &TerminalSize{Cols: 100, Rows: 40}})
+	assert.Equal(t, TerminalSize{Cols: 100, Rows: 40}, readTerminalAll(t, all...).Terminal.Size)
+	opened := sandbox.opened[0]
+	waitFor(t, func() bool {
+		opened.mu.Lock()
+		defer opened.mu.Unlock()
+		return opened.size == TerminalSize{Cols: 100, Rows: 40}
+	})
+
+	// 7. 关闭后所有人收到空状态
+	sendTerminal(t, bob, &terminalFrame{Type: terminalFrameStop})
+	assert.Equal(t, errTerminalForbidden.Error(), readTerminal(t, bob).Error)
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameStop})
+	frame := readTerminalAll(t, all...)
+	assert.Nil(t, frame.Terminal)
+	assert.Equal(t, terminalStopUser, frame.Reason)
+}
+
+func TestHub_TerminalIdleTimeout(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{
+		Terminal:       &echoSandbox{},
+		TerminalConfig: config.TerminalConfig{Enabled: true, IdleTimeoutSeconds: 1},
+	}))
+	alice := dial(t, url+"?user=1&role=member")
+
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameStart})
+	require.NotNil(t, readTerminal(t, alice).Terminal)
+
+	start := time.Now()
+	frame := readTerminal(t, alice)
+	assert.Nil(t, frame.Terminal)
+	assert.Equal(t, terminalStopIdle, frame.Reason)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestHub_TerminalUnavailableWithoutSandbox(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{TerminalConfig: config.TerminalConfig{Enabled: true}}))
+	alice := dial(t, url+"?user=1&role=owner")
+
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameStart})
+	assert.Equal(t, errTerminalUnavailable.Error(), readTerminal(t, alice).Error)
+}
+
+// memoryEventRepo 记录写入的房间事件
+type memoryEventRepo struct {
+	mu     sync.Mutex
+	events []*models.RoomEvent
+}
+
+func (r *memoryEventRepo) Create(_ context.Context, event *models.RoomEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryEventRepo) List(_ context.Context, _ uint, _ *repository.RoomEventFilter, _, _ int) ([]*models.RoomEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events, int64(len(r.events)), nil
+}
+
+func TestHub_TerminalRecordsCodeRun(t *testing.T) {
+	events := &memoryEventRepo{}
+	url := newTestServer(t, NewHub(Options{
+		Terminal:       &echoSandbox{},
+		TerminalConfig: config.TerminalConfig{Enabled: true},
+		Events:         events,
+	}))
+	alice := dial(t, url+"?user=1&role=member")
+
+	sendTerminal(t, alice, &terminalFrame{Type: terminalFrameStart, Mode: terminalModeEveryone})
+	require.NotNil(t, readTerminal(t, alice).Terminal)
+
+	var recorded []*models.RoomEvent
+	waitFor(t, func() bool {
+		recorded, _, _ = events.List(context.Background(), testRoom.ID, nil, 10, 0)
+		return len(recorded) == 1
+	})
+	event := recorded[0]
+	assert.Equal(t, models.RoomEventCodeRun, event.Type)
+	assert.Equal(t, testRoom.ID, event.RoomID)
+	require.NotNil(t, event.ActorID)
+	assert.Equal(t, uint(1), *event.ActorID)
+	assert.Equal(t, terminalModeEveryone, event.Data["mode"])
+}
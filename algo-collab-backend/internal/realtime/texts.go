@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"context"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+)
+
+var _ service.DocumentReader = (*Hub)(nil)
+
+// FileTexts 读取工作区文件的内容，names 为文件对应的 Y.Text 名称
+// 房间在本节点时读取文本视图，已读取过的文件按每次更新的修改增量维护，不再重新解码；
+// 房间不在本节点时从存储中加载：每条更新在广播前已经写库，读到的就是最新内容
+func (h *Hub) FileTexts(_ context.Context, roomModel *models.Room, names []string) (map[string]string, error) {
+	h.mu.Lock()
+	room := h.rooms[roomModel.UUID]
+	h.mu.Unlock()
+	if room == nil {
+		// 临时房间只用于读取，不注册到 Hub
+		room = newRoom(roomModel, h)
+		room.broker = nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if err := room.loadLocked(); err != nil {
+		return nil, err
+	}
+	texts := make(map[string]string, len(names))
+	for _, name := range names {
+		texts[name] = room.textLocked(name)
+	}
+	return texts, nil
+}
+
+// textLocked 读取 Y.Text name 的纯文本
+// 第一次读取时解码并开始跟踪这段文本，之后文档每次合入更新（本地连接、其他节点、服务端编辑）
+// 记录的插入和删除由 refreshTextsLocked 直接应用到缓存上，不再遍历 Yjs 结构
+func (r *Room) textLocked(name string) string {
+	r.refreshTextsLocked()
+	text, ok := r.texts[name]
+	if !ok {
+		r.doc.WatchText(name)
+		text = r.doc.GetText(name).String()
+		r.texts[name] = text
+	}
+	return text
+}
+
+// refreshTextsLocked 把文档记录的修改应用到缓存的文本上，合入更新后调用
+// 修改位置落在代理对中间（无法对应到 UTF-8 字符串）时丢弃缓存，下次读取时重新解码
+func (r *Room) refreshTextsLocked() {
+	for name, deltas := range r.doc.TakeTextDeltas() {
+		text, ok := r.texts[name]
+		if !ok {
+			continue
+		}
+		for _, delta := range deltas {
+			if text, ok = applyTextDelta(text, delta); !ok {
+				break
+			}
+		}
+		if !ok {
+			delete(r.texts, name)
+			r.doc.UnwatchText(name)
+			continue
+		}
+		r.texts[name] = text
+	}
+}
+
+// applyTextDelta 在字符串上执行一次插入或删除，位置以 UTF-16 码元计算
+func applyTextDelta(text string, delta yjs.TextDelta) (string, bool) {
+	start, ok := utf16Advance(text, 0, delta.Index)
+	if !ok {
+		return "", false
+	}
+	if delta.Delete > 0 {
+		end, ok := utf16Advance(text, start, delta.Delete)
+		if !ok {
+			return "", false
+		}
+		return text[:start] + text[end:], true
+	}
+	return text[:start] + delta.Insert + text[start:], true
+}
+
+// utf16Advance 从字节位置 from 向后移动 units 个 UTF-16 码元，返回新的字节位置
+// 超出字符串或停在代理对中间时返回 false
+func utf16Advance(s string, from int, units uint64) (int, bool) {
+	i := from
+	for units > 0 {
+		if i >= len(s) {
+			return 0, false
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		n := uint64(utf16.RuneLen(c))
+		if n > units {
+			return 0, false
+		}
+		units -= n
+		i += size
+	}
+	return i, true
+}
@@ -0,0 +1,356 @@
+package realtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+)
+
+// rtcFrame 的类型
+//
+// 语音频道内每两个参与者之间建立一条点对点连接：新加入的一方收到 welcome 后
+// 向已有的每个参与者发送 offer，已有的参与者收到 joined 后等待对方的 offer 即可。
+// 屏幕共享作为同一条连接上的视频轨道，通过 state 的 sharing 告知其他人。
+const (
+	rtcFrameJoin   = "join"   // 客户端 → 服务端：加入语音频道，可以带初始的 muted
+	rtcFrameLeave  = "leave"  // 客户端 → 服务端：离开语音频道
+	rtcFrameState  = "state"  // 客户端 → 服务端：修改静音或屏幕共享状态
+	rtcFrameSignal = "signal" // 双向：SDP offer/answer 或 ICE candidate，服务端只转发给 to 指定的参与者
+
+	rtcFrameSnapshot = "snapshot" // 服务端 → 客户端：建立连接时语音频道里已有的参与者
+	rtcFrameWelcome  = "welcome"  // 服务端 → 客户端：加入成功，带自己的 peer_id、其他参与者和 ICE 服务器
+	rtcFrameJoined   = "joined"   // 服务端 → 客户端：有人加入
+	rtcFrameUpdated  = "updated"  // 服务端 → 客户端：有人修改了状态
+	rtcFrameLeft     = "left"     // 服务端 → 客户端：有人离开
+	rtcFrameError    = "error"    // 服务端 → 客户端：操作失败
+
+	// rtcFrameQuery 节点之间：新建的房间请求其他节点重新发布各自的参与者
+	rtcFrameQuery = "query"
+)
+
+// rtcSignalTypes 允许转发的信令类型
+var rtcSignalTypes = map[string]bool{
+	"offer":     true,
+	"answer":    true,
+	"candidate": true,
+}
+
+const (
+	defaultVoiceMaxParticipants = 8
+	defaultTURNCredentialTTL    = 12 * time.Hour
+)
+
+var (
+	errVoiceReadOnly  = errors.New("房间已归档，不能加入语音")
+	errVoiceFull      = errors.New("语音频道已满")
+	errVoiceNotJoined = errors.New("请先加入语音频道")
+	errVoicePeerGone  = errors.New("对方已离开语音频道")
+	errVoiceBadSignal = errors.New("无效的信令消息")
+)
+
+// voiceParticipant 语音频道中的一个连接，同一用户的多个标签页是不同的参与者
+type voiceParticipant struct {
+	PeerID   string    `json:"peer_id"`
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Muted    bool      `json:"muted"`
+	Sharing  bool      `json:"sharing"` // 正在共享屏幕
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// voicePeer 房间记录的参与者，owner 为空表示参与者连接在其他节点上
+//
+// 节点异常退出时来不及通知其他节点，它的参与者会残留到房间回收；
+// 客户端的点对点连接会断开，可以据此把对方显示为离线。
+type voicePeer struct {
+	voiceParticipant
+	owner *Client
+}
+
+// iceServer 下发给浏览器的 RTCIceServer
+type iceServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// rtcFrame 信令消息的 JSON 结构
+type rtcFrame struct {
+	Type string `json:"type"`
+
+	// 客户端 → 服务端：为空表示不修改
+	Muted   *bool `json:"muted,omitempty"`
+	Sharing *bool `json:"sharing,omitempty"`
+
+	// signal：客户端发送时填 To，服务端转发时补上 From
+	From       string          `json:"from,omitempty"`
+	To         string          `json:"to,omitempty"`
+	SignalType string          `json:"signal_type,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+
+	// 服务端 → 客户端
+	PeerID       string              `json:"peer_id,omitempty"`
+	Participant  *voiceParticipant   `json:"participant,omitempty"`
+	Participants []*voiceParticipant `json:"participants,omitempty"`
+	ICEServers   []iceServer         `json:"ice_servers,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// isRTCFrame 判断二进制消息是否为信令消息
+func isRTCFrame(data []byte) bool {
+	return isMessageType(data, messageRTC)
+}
+
+func encodeRTCFrame(frame *rtcFrame) []byte {
+	return encodeJSONMessage(messageRTC, frame)
+}
+
+// handleRTC 处理客户端发来的信令消息，全部是内存操作，直接在房间锁内完成
+func (r *Room) handleRTC(client *Client, data []byte) {
+	var frame rtcFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[client]; !ok {
+		return
+	}
+	var err error
+	switch frame.Type {
+	case rtcFrameJoin:
+		err = r.joinVoiceLocked(client, &frame)
+	case rtcFrameLeave:
+		r.leaveVoiceLocked(client)
+	case rtcFrameState:
+		err = r.updateVoiceLocked(client, &frame)
+	case rtcFrameSignal:
+		err = r.relaySignalLocked(client, &frame)
+	}
+	if err != nil {
+		r.sendLocked(client, encodeRTCFrame(&rtcFrame{Type: rtcFrameError, Error: err.Error()}))
+	}
+}
+
+// joinVoiceLocked 加入语音频道，重复加入时重新下发 welcome
+func (r *Room) joinVoiceLocked(client *Client, frame *rtcFrame) error {
+	if client.readOnly {
+		return errVoiceReadOnly
+	}
+
+	peer, joined := r.voice[client.peerID]
+	if !joined {
+		limit := r.rtc.MaxParticipants
+		if limit <= 0 {
+			limit = defaultVoiceMaxParticipants
+		}
+		if len(r.voice) >= limit {
+			return errVoiceFull
+		}
+		peer = &voicePeer{
+			voiceParticipant: voiceParticipant{
+				PeerID:   client.peerID,
+				UserID:   client.user.ID,
+				Username: client.user.Username,
+				Muted:    frame.Muted != nil && *frame.Muted,
+				JoinedAt: time.Now(),
+			},
+			owner: client,
+		}
+		r.voice[client.peerID] = peer
+	}
+
+	others := make([]*voiceParticipant, 0, len(r.voice))
+	for id, other := range r.voice {
+		if id != client.peerID {
+			others = append(others, &other.voiceParticipant)
+		}
+	}
+	r.sendLocked(client, encodeRTCFrame(&rtcFrame{
+		Type:         rtcFrameWelcome,
+		PeerID:       client.peerID,
+		Participants: others,
+		ICEServers:   iceServersFor(r.rtc, client.user.ID, time.Now()),
+	}))
+
+	if !joined {
+		r.broadcastVoiceLocked(&rtcFrame{Type: rtcFrameJoined, Participant: &peer.voiceParticipant}, client)
+	}
+	return nil
+}
+
+// leaveVoiceLocked 离开语音频道，连接断开时也会调用
+func (r *Room) leaveVoiceLocked(client *Client) {
+	peer, ok := r.voice[client.peerID]
+	if !ok || peer.owner != client {
+		return
+	}
+	delete(r.voice, client.peerID)
+	r.broadcastVoiceLocked(&rtcFrame{Type: rtcFrameLeft, PeerID: client.peerID}, client)
+}
+
+// clearVoiceLocked 房间被清空（迁移、服务关闭）时通知其他节点本节点的参与者已离开
+func (r *Room) clearVoiceLocked() {
+	for id, peer := range r.voice {
+		if peer.owner != nil {
+			r.publishLocked(encodeRTCFrame(&rtcFrame{Type: rtcFrameLeft, PeerID: id}))
+		}
+	}
+	r.voice = make(map[string]*voicePeer)
+}
+
+func (r *Room) updateVoiceLocked(client *Client, frame *rtcFrame) error {
+	peer, ok := r.voice[client.peerID]
+	if !ok {
+		return errVoiceNotJoined
+	}
+	if frame.Muted != nil {
+		peer.Muted = *frame.Muted
+	}
+	if frame.Sharing != nil {
+		peer.Sharing = *frame.Sharing
+	}
+	r.broadcastVoiceLocked(&rtcFrame{Type: rtcFrameUpdated, Participant: &peer.voiceParticipant}, client)
+	return nil
+}
+
+// relaySignalLocked 只在同一房间语音频道内的参与者之间转发，服务端不解析 SDP 和 candidate
+func (r *Room) relaySignalLocked(client *Client, frame *rtcFrame) error {
+	if _, ok := r.voice[client.peerID]; !ok {
+		return errVoiceNotJoined
+	}
+	if !rtcSignalTypes[frame.SignalType] || len(frame.Payload) == 0 || !json.Valid(frame.Payload) {
+		return errVoiceBadSignal
+	}
+	target, ok := r.voice[frame.To]
+	if !ok || frame.To == client.peerID {
+		return errVoicePeerGone
+	}
+
+	data := encodeRTCFrame(&rtcFrame{
+		Type:       rtcFrameSignal,
+		From:       client.peerID,
+		To:         frame.To,
+		SignalType: frame.SignalType,
+		Payload:    frame.Payload,
+	})
+	if target.owner != nil {
+		r.sendLocked(target.owner, data)
+	} else {
+		// 对方在其他节点上，由那个节点转给对应的连接
+		r.publishLocked(data)
+	}
+	return nil
+}
+
+// broadcastVoiceLocked 语音频道的变化发给房间内所有连接（不在语音频道的人也要显示谁在通话）和其他节点
+func (r *Room) broadcastVoiceLocked(frame *rtcFrame, except *Client) {
+	data := encodeRTCFrame(frame)
+	r.broadcastLocked(data, except)
+	r.publishLocked(data)
+}
+
+// sendVoiceSnapshotLocked 新连接建立时下发语音频道里已有的参与者
+func (r *Room) sendVoiceSnapshotLocked(client *Client) {
+	if len(r.voice) == 0 {
+		return
+	}
+	participants := make([]*voiceParticipant, 0, len(r.voice))
+	for _, peer := range r.voice {
+		participants = append(participants, &peer.voiceParticipant)
+	}
+	r.sendLocked(client, encodeRTCFrame(&rtcFrame{Type: rtcFrameSnapshot, Participants: participants}))
+}
+
+// queryRemoteVoice 新建的房间向其他节点请求已有的参与者
+func (r *Room) queryRemoteVoice() {
+	if r.broker == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publishLocked(encodeRTCFrame(&rtcFrame{Type: rtcFrameQuery}))
+}
+
+// handleRemoteRTC 处理其他节点转发的信令消息
+func (r *Room) handleRemoteRTC(data []byte) {
+	var frame rtcFrame
+	if err := decodeJSONMessage(data, &frame); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch frame.Type {
+	case rtcFrameJoined, rtcFrameUpdated:
+		if frame.Participant == nil {
+			return
+		}
+		if current, ok := r.voice[frame.Participant.PeerID]; ok && current.owner != nil {
+			return
+		}
+		r.voice[frame.Participant.PeerID] = &voicePeer{voiceParticipant: *frame.Participant}
+		r.broadcastLocked(data, nil)
+	case rtcFrameLeft:
+		if current, ok := r.voice[frame.PeerID]; ok && current.owner == nil {
+			delete(r.voice, frame.PeerID)
+			r.broadcastLocked(data, nil)
+		}
+	case rtcFrameSignal:
+		if target, ok := r.voice[frame.To]; ok && target.owner != nil {
+			r.sendLocked(target.owner, data)
+		}
+	case rtcFrameQuery:
+		for _, peer := range r.voice {
+			if peer.owner != nil {
+				r.publishLocked(encodeRTCFrame(&rtcFrame{Type: rtcFrameJoined, Participant: &peer.voiceParticipant}))
+			}
+		}
+	}
+}
+
+// iceServersFor 生成下发给用户的 ICE 服务器列表
+// 配置了 TURNSecret 时，为没有固定凭证的 TURN 服务器生成临时凭证（TURN REST API 约定）：
+// username 为 "过期时间戳:用户ID"，credential 为以共享密钥对 username 做 HMAC-SHA1 后的 base64
+func iceServersFor(cfg config.RTCConfig, userID uint, now time.Time) []iceServer {
+	ttl := time.Duration(cfg.TURNCredentialTTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultTURNCredentialTTL
+	}
+
+	servers := make([]iceServer, 0, len(cfg.ICEServers))
+	for _, server := range cfg.ICEServers {
+		s := iceServer{
+			URLs:       server.URLs,
+			Username:   server.Username,
+			Credential: server.Credential,
+		}
+		if s.Username == "" && cfg.TURNSecret != "" && isTURNServer(server.URLs) {
+			s.Username = fmt.Sprintf("%d:%d", now.Add(ttl).Unix(), userID)
+			mac := hmac.New(sha1.New, []byte(cfg.TURNSecret))
+			mac.Write([]byte(s.Username))
+			s.Credential = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+func isTURNServer(urls []string) bool {
+	for _, url := range urls {
+		if strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:") {
+			return true
+		}
+	}
+	return false
+}
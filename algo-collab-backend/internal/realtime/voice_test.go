@@ -0,0 +1,194 @@
+package realtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readRTC 跳过文档同步等其他消息，返回下一条信令消息
+func readRTC(t *testing.T, conn *websocket.Conn) *rtcFrame {
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		if isRTCFrame(data) {
+			var frame rtcFrame
+			require.NoError(t, decodeJSONMessage(data, &frame))
+			return &frame
+		}
+	}
+}
+
+func sendRTC(t *testing.T, conn *websocket.Conn, frame *rtcFrame) {
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, encodeRTCFrame(frame)))
+}
+
+func TestHub_VoiceSignaling(t *testing.T) {
+	url := newTestServer(t, NewHub(Options{RTC: config.RTCConfig{
+		ICEServers: []config.ICEServerConfig{
+			{URLs: []string{"stun:stun.example.com:3478"}},
+			{URLs: []string{"turn:turn.example.com:3478"}},
+		},
+		TURNSecret:      "secret",
+		MaxParticipants: 2,
+	}}))
+
+	alice := dial(t, url+"?user=1")
+	sendRTC(t, alice, &rtcFrame{Type: rtcFrameJoin})
+	welcome := readRTC(t, alice)
+	require.Equal(t, rtcFrameWelcome, welcome.Type)
+	assert.Empty(t, welcome.Participants)
+	require.Len(t, welcome.ICEServers, 2)
+	assert.Empty(t, welcome.ICEServers[0].Username)
+	// TURN 服务器带临时凭证
+	assert.Contains(t, welcome.ICEServers[1].Username, ":1")
+	assert.NotEmpty(t, welcome.ICEServers[1].Credential)
+	alicePeer := welcome.PeerID
+
+	// 新连接收到语音频道的快照
+	bob := dial(t, url+"?user=2")
+	snapshot := readRTC(t, bob)
+	require.Equal(t, rtcFrameSnapshot, snapshot.Type)
+	require.Len(t, snapshot.Participants, 1)
+	assert.Equal(t, alicePeer, snapshot.Participants[0].PeerID)
+
+	// 加入时可以带初始静音，已有的参与者收到 joined
+	muted := true
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameJoin, Muted: &muted})
+	welcome = readRTC(t, bob)
+	require.Equal(t, rtcFrameWelcome, welcome.Type)
+	require.Len(t, welcome.Participants, 1)
+	bobPeer := welcome.PeerID
+	joined := readRTC(t, alice)
+	assert.Equal(t, rtcFrameJoined, joined.Type)
+	assert.Equal(t, bobPeer, joined.Participant.PeerID)
+	assert.True(t, joined.Participant.Muted)
+
+	// 信令只转发给目标，并补上来源
+	payload := json.RawMessage(`{"sdp":"v=0"}`)
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameSignal, To: alicePeer, SignalType: "offer", Payload: payload})
+	signal := readRTC(t, alice)
+	assert.Equal(t, rtcFrameSignal, signal.Type)
+	assert.Equal(t, bobPeer, signal.From)
+	assert.Equal(t, "offer", signal.SignalType)
+	assert.JSONEq(t, string(payload), string(signal.Payload))
+
+	// 错误只返回给发送者
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameSignal, To: "unknown", SignalType: "offer", Payload: payload})
+	assert.Equal(t, errVoicePeerGone.Error(), readRTC(t, bob).Error)
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameSignal, To: alicePeer, SignalType: "bye", Payload: payload})
+	assert.Equal(t, errVoiceBadSignal.Error(), readRTC(t, bob).Error)
+
+	// 人数已满，只读连接不能加入
+	carol := dial(t, url+"?user=3")
+	readRTC(t, carol) // 快照
+	sendRTC(t, carol, &rtcFrame{Type: rtcFrameJoin})
+	assert.Equal(t, errVoiceFull.Error(), readRTC(t, carol).Error)
+	viewer := dial(t, url+"?user=4&readonly=1")
+	readRTC(t, viewer)
+	sendRTC(t, viewer, &rtcFrame{Type: rtcFrameJoin})
+	assert.Equal(t, errVoiceReadOnly.Error(), readRTC(t, viewer).Error)
+	sendRTC(t, carol, &rtcFrame{Type: rtcFrameSignal, To: alicePeer, SignalType: "offer", Payload: payload})
+	assert.Equal(t, errVoiceNotJoined.Error(), readRTC(t, carol).Error)
+
+	// 状态变化发给房间内所有连接
+	sharing := true
+	sendRTC(t, alice, &rtcFrame{Type: rtcFrameState, Sharing: &sharing})
+	for _, conn := range []*websocket.Conn{bob, carol} {
+		updated := readRTC(t, conn)
+		assert.Equal(t, rtcFrameUpdated, updated.Type)
+		assert.True(t, updated.Participant.Sharing)
+	}
+
+	// 断开连接等同于离开
+	alice.Close()
+	for _, conn := range []*websocket.Conn{bob, carol} {
+		left := readRTC(t, conn)
+		assert.Equal(t, rtcFrameLeft, left.Type)
+		assert.Equal(t, alicePeer, left.PeerID)
+	}
+}
+
+func TestHub_VoiceSignalingAcrossNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &memoryDocumentStore{}
+	nodeA := newTestNode(t, mr, store)
+	nodeB := newTestNode(t, mr, store)
+	channel := roomChannelPrefix + testRoom.UUID
+
+	alice := dial(t, nodeA+"?user=1")
+	sendRTC(t, alice, &rtcFrame{Type: rtcFrameJoin})
+	alicePeer := readRTC(t, alice).PeerID
+
+	// 后创建的房间向其他节点查询已有的参与者
+	bob := dial(t, nodeB+"?user=2")
+	waitFor(t, func() bool { return mr.PubSubNumSub(channel)[channel] == 2 })
+	frame := readRTC(t, bob)
+	require.Contains(t, []string{rtcFrameSnapshot, rtcFrameJoined}, frame.Type)
+	if frame.Type == rtcFrameJoined {
+		assert.Equal(t, alicePeer, frame.Participant.PeerID)
+	} else {
+		assert.Equal(t, alicePeer, frame.Participants[0].PeerID)
+	}
+
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameJoin})
+	welcome := readRTC(t, bob)
+	for welcome.Type != rtcFrameWelcome {
+		// 查询结果可能在快照之前和之后各推送一次
+		welcome = readRTC(t, bob)
+	}
+	require.Len(t, welcome.Participants, 1)
+	assert.Equal(t, alicePeer, welcome.Participants[0].PeerID)
+	assert.Equal(t, welcome.PeerID, readRTC(t, alice).Participant.PeerID)
+
+	// 信令经 Redis 转发给其他节点上的参与者
+	sendRTC(t, bob, &rtcFrame{Type: rtcFrameSignal, To: alicePeer, SignalType: "candidate", Payload: json.RawMessage(`{"candidate":""}`)})
+	signal := readRTC(t, alice)
+	assert.Equal(t, rtcFrameSignal, signal.Type)
+	assert.Equal(t, welcome.PeerID, signal.From)
+
+	bob.Close()
+	left := readRTC(t, alice)
+	assert.Equal(t, rtcFrameLeft, left.Type)
+	assert.Equal(t, welcome.PeerID, left.PeerID)
+}
+
+func TestICEServersFor(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cfg := config.RTCConfig{
+		ICEServers: []config.ICEServerConfig{
+			{URLs: []string{"stun:stun.example.com"}},
+			{URLs: []string{"turns:turn.example.com:443"}},
+			{URLs: []string{"turn:static.example.com"}, Username: "u", Credential: "p"},
+		},
+		TURNSecret:               "secret",
+		TURNCredentialTTLSeconds: 60,
+	}
+
+	servers := iceServersFor(cfg, 7, now)
+	require.Len(t, servers, 3)
+	assert.Empty(t, servers[0].Username)
+
+	assert.Equal(t, "1700000060:7", servers[1].Username)
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte("1700000060:7"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), servers[1].Credential)
+
+	// 配置了固定凭证的服务器原样下发
+	assert.Equal(t, "u", servers[2].Username)
+	assert.Equal(t, "p", servers[2].Credential)
+
+	// 没有共享密钥时不生成凭证
+	cfg.TURNSecret = ""
+	assert.Empty(t, iceServersFor(cfg, 7, now)[1].Credential)
+}
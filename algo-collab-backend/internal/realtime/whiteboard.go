@@ -0,0 +1,194 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/internal/service"
+)
+
+var _ service.WhiteboardEditor = (*Hub)(nil)
+
+// whiteboardsFrameList 服务端 → 客户端：白板列表变化后的完整列表
+const whiteboardsFrameList = "list"
+
+// whiteboardsFrame 白板列表消息的 JSON 结构
+// 白板的图形通过各自的白板连接同步，这里只推送新建、重命名、关联讨论和删除
+type whiteboardsFrame struct {
+	Type        string                   `json:"type"`
+	Whiteboards []*models.RoomWhiteboard `json:"whiteboards"`
+}
+
+func isWhiteboardsFrame(data []byte) bool {
+	return isMessageType(data, messageWhiteboards)
+}
+
+// whiteboardKey 白板文档在 Hub 中的键，也是节点之间转发消息的频道，
+// 和 y-websocket 的房间名一致：<房间 UUID>/whiteboards/<白板 ID>
+func whiteboardKey(roomUUID string, boardID uint) string {
+	return fmt.Sprintf("%s/whiteboards/%d", roomUUID, boardID)
+}
+
+// roomOf Hub 中的键所属的房间 UUID，房间本身的键就是 UUID
+func roomOf(key string) string {
+	roomUUID, _, _ := strings.Cut(key, "/")
+	return roomUUID
+}
+
+// newWhiteboardRoom 白板文档和代码文档一样同步、持久化和合并，但使用自己的存储和大小限制，
+// 连接上只有文档和 awareness，不处理聊天、语音、演示者和终端，也不记录作者、版本和回放
+func newWhiteboardRoom(roomModel *models.Room, boardID uint, h *Hub) *Room {
+	r := newRoom(&models.Room{BaseModel: models.BaseModel{ID: boardID}, UUID: whiteboardKey(roomModel.UUID, boardID)}, h)
+	r.whiteboard = true
+	r.store = nil
+	if h.opts.Whiteboards != nil {
+		r.store = &whiteboardStore{repo: h.opts.Whiteboards, roomID: roomModel.ID}
+	}
+	r.cfg.MaxSizeKB = h.opts.Whiteboard.MaxSizeKB
+	r.chat = nil
+	r.versions = nil
+	r.recorder = nil
+	r.authors = nil
+	r.lockRepo = nil
+	r.activity = nil
+	r.events = nil
+	r.terminalSandbox = nil
+	r.discard = func() { h.discardWhiteboard(r.uuid) }
+	return r
+}
+
+// whiteboardStore 把白板文档存储适配为 Room 使用的 DocumentRepository，文档 ID 即白板 ID
+type whiteboardStore struct {
+	repo   repository.WhiteboardDocumentRepository
+	roomID uint
+}
+
+func (s *whiteboardStore) Load(ctx context.Context, boardID uint) (*models.Document, []*models.DocumentUpdate, error) {
+	snapshot, updates, err := s.repo.Load(ctx, boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	converted := make([]*models.DocumentUpdate, 0, len(updates))
+	for _, update := range updates {
+		converted = append(converted, &models.DocumentUpdate{ID: update.ID, RoomID: s.roomID, Data: update.Data})
+	}
+	if snapshot == nil {
+		return nil, converted, nil
+	}
+	return &models.Document{RoomID: s.roomID, Snapshot: snapshot.Snapshot, Size: snapshot.Size}, converted, nil
+}
+
+func (s *whiteboardStore) AppendUpdate(ctx context.Context, boardID uint, data []byte) (uint, error) {
+	return s.repo.AppendUpdate(ctx, s.roomID, boardID, data)
+}
+
+func (s *whiteboardStore) Compact(ctx context.Context, boardID uint, snapshot []byte, uptoID uint, pending [][]byte) (uint, error) {
+	return s.repo.Compact(ctx, s.roomID, boardID, snapshot, uptoID, pending)
+}
+
+// ServeWhiteboard 升级连接并加入白板文档，限制和转发同 Serve
+// 白板连接按所属房间转发，亲和模式下和房间在同一个节点上
+func (h *Hub) ServeWhiteboard(w http.ResponseWriter, r *http.Request, roomModel *models.Room, boardID uint, user User, readOnly bool) error {
+	return h.serve(w, r, whiteboardKey(roomModel.UUID, boardID), func() *Room {
+		return newWhiteboardRoom(roomModel, boardID, h)
+	}, user, readOnly)
+}
+
+// BroadcastWhiteboards 把新的白板列表推送给房间内所有节点上的连接
+// 亲和模式下只能由房间所在节点执行，其他节点返回 ErrNotRoomOwner，见 ForwardToOwner
+func (h *Hub) BroadcastWhiteboards(_ context.Context, roomModel *models.Room, boards []*models.RoomWhiteboard) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	room, err := h.acquire(roomModel)
+	if err != nil {
+		return err
+	}
+	defer h.release(room)
+
+	frame := encodeJSONMessage(messageWhiteboards, &whiteboardsFrame{Type: whiteboardsFrameList, Whiteboards: boards})
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.broadcastLocked(frame, nil)
+	room.publishLocked(frame)
+	return nil
+}
+
+// WhiteboardShapes 读取白板中的所有图形，值为前端写入的普通对象
+// 和 FileTexts 一样，白板不在本节点时从存储中加载
+func (h *Hub) WhiteboardShapes(_ context.Context, roomModel *models.Room, boardID uint) (map[string]any, error) {
+	h.mu.Lock()
+	room := h.rooms[whiteboardKey(roomModel.UUID, boardID)]
+	h.mu.Unlock()
+	if room == nil {
+		room = newWhiteboardRoom(roomModel, boardID, h)
+		room.broker = nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if err := room.loadLocked(); err != nil {
+		return nil, err
+	}
+	return room.doc.GetMap(models.WhiteboardMapName).Entries(), nil
+}
+
+// CloseWhiteboard 白板被删除后断开所有节点上该白板的连接，丢弃内存中的文档而不再写库
+// 限制同 BroadcastWhiteboards
+func (h *Hub) CloseWhiteboard(ctx context.Context, roomModel *models.Room, boardID uint) error {
+	if cluster := h.opts.Cluster; cluster != nil && !cluster.IsOwner(roomModel.UUID) {
+		return ErrNotRoomOwner
+	}
+
+	key := whiteboardKey(roomModel.UUID, boardID)
+	h.discardWhiteboard(key)
+	if broker := h.opts.Broker; broker != nil {
+		frame := encodeJSONMessage(messageMembers, &membersFrame{Type: membersFrameDelete})
+		return broker.Publish(ctx, key, encodeEnvelope(h.nodeID, frame))
+	}
+	return nil
+}
+
+// discardWhiteboard 从 Hub 摘除本节点的白板文档并断开连接，断开前去掉存储，回收时不会把已删除的白板写回
+func (h *Hub) discardWhiteboard(key string) {
+	h.mu.Lock()
+	room := h.rooms[key]
+	delete(h.rooms, key)
+	h.mu.Unlock()
+
+	if room == nil {
+		return
+	}
+	room.mu.Lock()
+	room.store = nil
+	room.dirty = 0
+	room.mu.Unlock()
+	room.drain(websocket.CloseNormalClosure, reconnectHint("whiteboard_deleted", 0))
+}
+
+// whiteboardRoomsLocked 本节点上属于该房间的白板文档
+func (h *Hub) whiteboardRoomsLocked(roomUUID string) []*Room {
+	var rooms []*Room
+	for key, room := range h.rooms {
+		if key != roomUUID && roomOf(key) == roomUUID {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// handleParentRemote 白板文档也订阅所属房间的频道，只处理其中的成员变化：
+// 本节点可能只有白板连接而没有加载房间本身
+func (r *Room) handleParentRemote(data []byte) {
+	nodeID, frame, err := decodeEnvelope(data)
+	if err != nil || nodeID == r.nodeID || !isMembersFrame(frame) {
+		return
+	}
+	r.handleRemoteMembers(frame)
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWhiteboardStore 内存中的白板文档存储，每块白板一份 memoryDocumentStore
+type memoryWhiteboardStore struct {
+	mu     sync.Mutex
+	boards map[uint]*memoryDocumentStore
+}
+
+func (s *memoryWhiteboardStore) board(boardID uint) *memoryDocumentStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.boards == nil {
+		s.boards = make(map[uint]*memoryDocumentStore)
+	}
+	if s.boards[boardID] == nil {
+		s.boards[boardID] = &memoryDocumentStore{}
+	}
+	return s.boards[boardID]
+}
+
+func (s *memoryWhiteboardStore) Load(ctx context.Context, boardID uint) (*models.WhiteboardDocument, []*models.WhiteboardUpdate, error) {
+	doc, updates, _ := s.board(boardID).Load(ctx, boardID)
+	converted := make([]*models.WhiteboardUpdate, 0, len(updates))
+	for _, update := range updates {
+		converted = append(converted, &models.WhiteboardUpdate{ID: update.ID, WhiteboardID: boardID, Data: update.Data})
+	}
+	if doc == nil {
+		return nil, converted, nil
+	}
+	return &models.WhiteboardDocument{WhiteboardID: boardID, Snapshot: doc.Snapshot}, converted, nil
+}
+
+func (s *memoryWhiteboardStore) AppendUpdate(ctx context.Context, _, boardID uint, data []byte) (uint, error) {
+	return s.board(boardID).AppendUpdate(ctx, boardID, data)
+}
+
+func (s *memoryWhiteboardStore) Compact(ctx context.Context, _, boardID uint, snapshot []byte, uptoID uint, pending [][]byte) (uint, error) {
+	return s.board(boardID).Compact(ctx, boardID, snapshot, uptoID, pending)
+}
+
+func (s *memoryWhiteboardStore) Delete(_ context.Context, boardID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.boards, boardID)
+	return nil
+}
+
+// newWhiteboardServer 和 newTestServer 一样，路径为 /whiteboards/<白板 ID> 时连接到白板
+func newWhiteboardServer(t *testing.T, hub *Hub) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := User{ID: 1, Username: "user-1"}
+		if boardID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/whiteboards/")); err == nil {
+			_ = hub.ServeWhiteboard(w, r, testRoom, uint(boardID), user, false)
+			return
+		}
+		_ = hub.Serve(w, r, testRoom, user, false)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestHub_WhiteboardDocuments(t *testing.T) {
+	store := &memoryDocumentStore{}
+	boards := &memoryWhiteboardStore{}
+	hub := NewHub(Options{Documents: store, Whiteboards: boards, Whiteboard: config.WhiteboardConfig{MaxSizeKB: 1}})
+	url := newWhiteboardServer(t, hub)
+	ctx := context.Background()
+
+	code := dial(t, url)
+	readMessage(t, code)
+	alice := dial(t, url+"/whiteboards/1")
+	readMessage(t, alice)
+	bob := dial(t, url+"/whiteboards/1")
+	readMessage(t, bob)
+
+	// 1. 白板的图形只在白板连接之间同步，写入白板自己的文档，不占用代码文档
+	doc := yjs.NewDoc(yjs.Options{ClientID: 7})
+	update := doc.GetMap(models.WhiteboardMapName).Set("s1", map[string]any{"type": "rect", "x": 1, "y": 2, "w": 30, "h": 40})
+	require.NoError(t, alice.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(update)))
+	msg := readMessage(t, bob)
+	require.Equal(t, uint64(yjs.SyncUpdate), msg.SubType)
+	waitFor(t, func() bool {
+		_, updates := boards.board(1).counts()
+		return updates == 1
+	})
+	_, updates := store.counts()
+	assert.Equal(t, 0, updates)
+
+	shapes, err := hub.WhiteboardShapes(ctx, testRoom, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"s1": map[string]any{"type": "rect", "x": int64(1), "y": int64(2), "w": int64(30), "h": int64(40)},
+	}, shapes)
+	shapes, err = hub.WhiteboardShapes(ctx, testRoom, 2)
+	require.NoError(t, err)
+	assert.Empty(t, shapes)
+
+	// 2. 每块白板有自己的大小限制
+	carol := dial(t, url+"/whiteboards/2")
+	readMessage(t, carol)
+	big := yjs.NewDoc(yjs.Options{ClientID: 8}).GetMap(models.WhiteboardMapName).Set("s2", map[string]any{"type": "text", "text": strings.Repeat("x", 2048)})
+	require.NoError(t, carol.WriteMessage(websocket.BinaryMessage, yjs.EncodeUpdate(big)))
+	assert.Equal(t, websocket.CloseMessageTooBig, expectClose(t, carol).Code)
+
+	// 3. 删除白板时断开连接，已删除的白板不会在回收时写回
+	require.NoError(t, hub.CloseWhiteboard(ctx, testRoom, 1))
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		closeErr := expectClose(t, conn)
+		assert.Equal(t, websocket.CloseNormalClosure, closeErr.Code)
+		assert.Contains(t, closeErr.Text, "whiteboard_deleted")
+	}
+	hasSnapshot, _ := boards.board(1).counts()
+	assert.False(t, hasSnapshot)
+	assert.Equal(t, []string{testRoom.UUID}, hub.LoadedRooms())
+
+	// 4. 白板列表的变化推送给代码连接
+	list := []*models.RoomWhiteboard{{ID: 2, RoomID: testRoom.ID, Title: "BFS"}}
+	require.NoError(t, hub.BroadcastWhiteboards(ctx, testRoom, list))
+	var frame whiteboardsFrame
+	readExtension(t, code, messageWhiteboards, &frame)
+	assert.Equal(t, whiteboardsFrameList, frame.Type)
+	require.Len(t, frame.Whiteboards, 1)
+	assert.Equal(t, "BFS", frame.Whiteboards[0].Title)
+}
+
+func TestHub_CloseRoomClosesWhiteboards(t *testing.T) {
+	hub := NewHub(Options{Whiteboards: &memoryWhiteboardStore{}})
+	url := newWhiteboardServer(t, hub)
+
+	// 只有白板连接时房间同样算作在线，关闭房间时一起断开
+	alice := dial(t, url+"/whiteboards/1")
+	readMessage(t, alice)
+	assert.Equal(t, []string{testRoom.UUID}, hub.LoadedRooms())
+
+	require.NoError(t, hub.CloseRoom(context.Background(), testRoom))
+	assert.Equal(t, websocket.CloseServiceRestart, expectClose(t, alice).Code)
+	assert.Empty(t, hub.LoadedRooms())
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ DocumentAuthorRepository = (*documentAuthorRepository)(nil)
+
+type DocumentAuthorRepository interface {
+	// Claim 记录 Yjs 客户端的作者，客户端已有作者时不修改
+	Claim(ctx context.Context, roomID uint, clientID uint64, userID uint) error
+	// ListByRoom 房间文档的所有作者，附带用户的公开信息
+	ListByRoom(ctx context.Context, roomID uint) ([]*models.DocumentAuthor, error)
+}
+
+type documentAuthorRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentAuthorRepository(db *gorm.DB) DocumentAuthorRepository {
+	return &documentAuthorRepository{db: db}
+}
+
+// Claim 多个节点同时记录时以先写入的为准
+func (r *documentAuthorRepository) Claim(ctx context.Context, roomID uint, clientID uint64, userID uint) error {
+	author := &models.DocumentAuthor{RoomID: roomID, ClientID: clientID, UserID: userID}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Omit(clause.Associations).
+		Create(author).Error
+}
+
+func (r *documentAuthorRepository) ListByRoom(ctx context.Context, roomID uint) ([]*models.DocumentAuthor, error) {
+	var authors []*models.DocumentAuthor
+	err := r.db.WithContext(ctx).
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "uuid", "username", "avatar")
+		}).
+		Where("room_id = ?", roomID).
+		Find(&authors).Error
+	return authors, err
+}
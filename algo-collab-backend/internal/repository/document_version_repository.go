@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDocumentVersionNotFound 历史版本不存在
+var ErrDocumentVersionNotFound = errors.New("历史版本不存在")
+
+var _ DocumentVersionRepository = (*documentVersionRepository)(nil)
+
+type DocumentVersionRepository interface {
+	Create(ctx context.Context, version *models.DocumentVersion) error
+	// FindByID 包含版本内容
+	FindByID(ctx context.Context, id uint) (*models.DocumentVersion, error)
+	// List 按时间倒序分页返回房间的版本，不包含内容
+	List(ctx context.Context, roomID uint, limit, offset int) ([]*models.DocumentVersion, int64, error)
+	// Latest 房间最近一个 kind 类型的版本（包含内容），没有时返回 nil
+	Latest(ctx context.Context, roomID uint, kind string) (*models.DocumentVersion, error)
+	// PruneAuto 只保留最近 keep 个自动版本
+	PruneAuto(ctx context.Context, roomID uint, keep int) error
+}
+
+type documentVersionRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentVersionRepository(db *gorm.DB) DocumentVersionRepository {
+	return &documentVersionRepository{db: db}
+}
+
+func (r *documentVersionRepository) Create(ctx context.Context, version *models.DocumentVersion) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(version).Error
+}
+
+func (r *documentVersionRepository) FindByID(ctx context.Context, id uint) (*models.DocumentVersion, error) {
+	var version models.DocumentVersion
+	err := r.db.WithContext(ctx).
+		Preload("Creator", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "uuid", "username", "avatar")
+		}).
+		First(&version, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDocumentVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *documentVersionRepository) List(ctx context.Context, roomID uint, limit, offset int) ([]*models.DocumentVersion, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.DocumentVersion{}).Where("room_id = ?", roomID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var versions []*models.DocumentVersion
+	err := query.
+		Omit("content").
+		Preload("Creator", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "uuid", "username", "avatar")
+		}).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&versions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+func (r *documentVersionRepository) Latest(ctx context.Context, roomID uint, kind string) (*models.DocumentVersion, error) {
+	var version models.DocumentVersion
+	err := r.db.WithContext(ctx).
+		Where("room_id = ? AND kind = ?", roomID, kind).
+		Order("id DESC").
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *documentVersionRepository) PruneAuto(ctx context.Context, roomID uint, keep int) error {
+	// 找到第 keep 个自动版本，删除比它更早的
+	var boundary models.DocumentVersion
+	err := r.db.WithContext(ctx).
+		Select("id").
+		Where("room_id = ? AND kind = ?", roomID, models.DocumentVersionAuto).
+		Order("id DESC").
+		Offset(keep - 1).
+		First(&boundary).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Where("room_id = ? AND kind = ? AND id < ?", roomID, models.DocumentVersionAuto, boundary.ID).
+		Delete(&models.DocumentVersion{}).Error
+}
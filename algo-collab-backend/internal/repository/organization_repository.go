@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+)
+
+var _ OrganizationRepository = (*organizationRepository)(nil)
+
+// ErrOrganizationNotFound 组织不存在
+var ErrOrganizationNotFound = errors.New("组织不存在")
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *models.Organization) error
+	FindByID(ctx context.Context, id uint) (*models.Organization, error)
+	FindByUUID(ctx context.Context, uuid string) (*models.Organization, error)
+	Update(ctx context.Context, org *models.Organization) error
+	// ClearDefaultTemplate 取消所有以该模板为默认模板的组织设置（模板删除前调用）
+	ClearDefaultTemplate(ctx context.Context, templateID uint) error
+
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	GetMember(ctx context.Context, orgID, userID uint) (*models.OrganizationMember, error)
+	FindOrganizationIDsByUser(ctx context.Context, userID uint) ([]uint, error)
+}
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create 创建组织，创建者自动成为 owner
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         org.OwnerID,
+			Role:           models.OrgRoleOwner,
+		}).Error
+	})
+}
+
+func (r *organizationRepository) FindByID(ctx context.Context, id uint) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.WithContext(ctx).First(&org, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) FindByUUID(ctx context.Context, uuid string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.WithContext(ctx).Where("uuid = ?", uuid).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	return r.db.WithContext(ctx).Save(org).Error
+}
+
+func (r *organizationRepository) ClearDefaultTemplate(ctx context.Context, templateID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Organization{}).
+		Where("default_template_id = ?", templateID).
+		Updates(map[string]interface{}{
+			"default_template_id": nil,
+			"enforce_template":    false,
+		}).Error
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	return r.db.WithContext(ctx).Create(member).Error
+}
+
+func (r *organizationRepository) GetMember(ctx context.Context, orgID, userID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := r.db.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *organizationRepository) FindOrganizationIDsByUser(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.OrganizationMember{}).
+		Where("user_id = ?", userID).
+		Pluck("organization_id", &ids).Error
+	return ids, err
+}
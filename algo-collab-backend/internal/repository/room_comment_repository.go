@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCommentThreadNotFound 评论不存在
+var ErrCommentThreadNotFound = errors.New("评论不存在")
+
+var _ RoomCommentRepository = (*roomCommentRepository)(nil)
+
+// CommentThreadFilter 评论列表的筛选条件，零值表示不筛选
+type CommentThreadFilter struct {
+	FileID   uint
+	Resolved *bool
+}
+
+type RoomCommentRepository interface {
+	// CreateThread 创建评论及其第一条内容
+	CreateThread(ctx context.Context, thread *models.RoomCommentThread, first *models.RoomComment) error
+	AddComment(ctx context.Context, comment *models.RoomComment) error
+	// FindThread 返回评论及其所有回复
+	FindThread(ctx context.Context, id uint) (*models.RoomCommentThread, error)
+	// ListThreads 按创建时间返回房间的评论及其所有回复
+	ListThreads(ctx context.Context, roomID uint, filter CommentThreadFilter) ([]*models.RoomCommentThread, error)
+	// SetResolved 标记评论已解决，resolvedBy 为空表示重新打开
+	SetResolved(ctx context.Context, id uint, resolvedBy *uint) error
+}
+
+type roomCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomCommentRepository(db *gorm.DB) RoomCommentRepository {
+	return &roomCommentRepository{db: db}
+}
+
+// preloadComments 按时间顺序加载回复和关联的白板，只带出作者的公开字段
+func preloadComments(db *gorm.DB) *gorm.DB {
+	return db.Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Comments.User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "uuid", "username", "avatar")
+	}).Preload("Whiteboards", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
+
+func (r *roomCommentRepository) CreateThread(ctx context.Context, thread *models.RoomCommentThread, first *models.RoomComment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(thread).Error; err != nil {
+			return err
+		}
+		first.RoomID, first.ThreadID = thread.RoomID, thread.ID
+		return tx.Omit(clause.Associations).Create(first).Error
+	})
+}
+
+// AddComment 同时更新评论的修改时间
+func (r *roomCommentRepository) AddComment(ctx context.Context, comment *models.RoomComment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(comment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RoomCommentThread{}).
+			Where("id = ?", comment.ThreadID).
+			Update("updated_at", comment.CreatedAt).Error
+	})
+}
+
+func (r *roomCommentRepository) FindThread(ctx context.Context, id uint) (*models.RoomCommentThread, error) {
+	var thread models.RoomCommentThread
+	err := preloadComments(r.db.WithContext(ctx)).First(&thread, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCommentThreadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &thread, nil
+}
+
+func (r *roomCommentRepository) ListThreads(ctx context.Context, roomID uint, filter CommentThreadFilter) ([]*models.RoomCommentThread, error) {
+	query := preloadComments(r.db.WithContext(ctx)).Where("room_id = ?", roomID)
+	if filter.FileID != 0 {
+		query = query.Where("file_id = ?", filter.FileID)
+	}
+	if filter.Resolved != nil {
+		query = query.Where("resolved = ?", *filter.Resolved)
+	}
+
+	var threads []*models.RoomCommentThread
+	err := query.Order("id ASC").Find(&threads).Error
+	return threads, err
+}
+
+func (r *roomCommentRepository) SetResolved(ctx context.Context, id uint, resolvedBy *uint) error {
+	var resolvedAt *time.Time
+	if resolvedBy != nil {
+		now := time.Now()
+		resolvedAt = &now
+	}
+	return r.db.WithContext(ctx).
+		Model(&models.RoomCommentThread{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"resolved":    resolvedBy != nil,
+			"resolved_by": resolvedBy,
+			"resolved_at": resolvedAt,
+		}).Error
+}
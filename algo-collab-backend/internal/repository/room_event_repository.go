@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ RoomEventRepository = (*roomEventRepository)(nil)
+
+// RoomEventFilter 房间事件筛选条件，零值字段不参与筛选
+type RoomEventFilter struct {
+	Types   []string
+	ActorID uint
+}
+
+type RoomEventRepository interface {
+	Create(ctx context.Context, event *models.RoomEvent) error
+	// List 按时间倒序分页返回房间事件，同时返回符合条件的总数
+	List(ctx context.Context, roomID uint, filter *RoomEventFilter, limit, offset int) ([]*models.RoomEvent, int64, error)
+}
+
+type roomEventRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomEventRepository(db *gorm.DB) RoomEventRepository {
+	return &roomEventRepository{db: db}
+}
+
+func (r *roomEventRepository) Create(ctx context.Context, event *models.RoomEvent) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(event).Error
+}
+
+func (r *roomEventRepository) List(ctx context.Context, roomID uint, filter *RoomEventFilter, limit, offset int) ([]*models.RoomEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.RoomEvent{}).Where("room_id = ?", roomID)
+	if filter != nil {
+		if len(filter.Types) > 0 {
+			query = query.Where("type IN ?", filter.Types)
+		}
+		if filter.ActorID != 0 {
+			query = query.Where("actor_id = ?", filter.ActorID)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.RoomEvent
+	err := query.Preload("Actor").
+		Order("created_at DESC, id DESC").
+		Limit(limit).Offset(offset).
+		Find(&events).Error
+	return events, total, err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRoomFileNotFound 文件不存在
+var ErrRoomFileNotFound = errors.New("文件不存在")
+
+var _ RoomFileRepository = (*roomFileRepository)(nil)
+
+type RoomFileRepository interface {
+	Create(ctx context.Context, file *models.RoomFile) error
+	// EnsureMain 房间还没有主文件时创建 file，已有时不修改
+	EnsureMain(ctx context.Context, file *models.RoomFile) error
+	FindByID(ctx context.Context, id uint) (*models.RoomFile, error)
+	// ListByRoom 按路径排序返回房间的所有文件
+	ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomFile, error)
+	CountByRoom(ctx context.Context, roomID uint) (int64, error)
+	ExistsPath(ctx context.Context, roomID uint, path string) (bool, error)
+	// UpdatePath 重命名或移动文件
+	UpdatePath(ctx context.Context, id uint, path, language string) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type roomFileRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomFileRepository(db *gorm.DB) RoomFileRepository {
+	return &roomFileRepository{db: db}
+}
+
+func (r *roomFileRepository) Create(ctx context.Context, file *models.RoomFile) error {
+	return r.db.WithContext(ctx).Create(file).Error
+}
+
+// EnsureMain 主文件的 Y.Text 名称固定，多个请求同时创建时只有一个生效
+func (r *roomFileRepository) EnsureMain(ctx context.Context, file *models.RoomFile) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(file).Error
+}
+
+func (r *roomFileRepository) FindByID(ctx context.Context, id uint) (*models.RoomFile, error) {
+	var file models.RoomFile
+	err := r.db.WithContext(ctx).First(&file, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *roomFileRepository) ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomFile, error) {
+	var files []*models.RoomFile
+	err := r.db.WithContext(ctx).
+		Where("room_id = ?", roomID).
+		Order("path ASC").
+		Find(&files).Error
+	return files, err
+}
+
+func (r *roomFileRepository) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomFile{}).Where("room_id = ?", roomID).Count(&count).Error
+	return count, err
+}
+
+func (r *roomFileRepository) ExistsPath(ctx context.Context, roomID uint, path string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomFile{}).Where("room_id = ? AND path = ?", roomID, path).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roomFileRepository) UpdatePath(ctx context.Context, id uint, path, language string) error {
+	return r.db.WithContext(ctx).Model(&models.RoomFile{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"path":     path,
+		"language": language,
+	}).Error
+}
+
+func (r *roomFileRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.RoomFile{}, id).Error
+}
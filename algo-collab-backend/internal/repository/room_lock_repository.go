@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrRoomLockNotFound 锁定区域不存在
+var ErrRoomLockNotFound = errors.New("锁定区域不存在")
+
+var _ RoomLockRepository = (*roomLockRepository)(nil)
+
+type RoomLockRepository interface {
+	Create(ctx context.Context, lock *models.RoomLock) error
+	FindByID(ctx context.Context, id uint) (*models.RoomLock, error)
+	// ListByRoom 按创建顺序返回房间的锁定区域
+	ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomLock, error)
+	CountByRoom(ctx context.Context, roomID uint) (int64, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type roomLockRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomLockRepository(db *gorm.DB) RoomLockRepository {
+	return &roomLockRepository{db: db}
+}
+
+func (r *roomLockRepository) Create(ctx context.Context, lock *models.RoomLock) error {
+	return r.db.WithContext(ctx).Create(lock).Error
+}
+
+func (r *roomLockRepository) FindByID(ctx context.Context, id uint) (*models.RoomLock, error) {
+	var lock models.RoomLock
+	err := r.db.WithContext(ctx).First(&lock, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomLockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (r *roomLockRepository) ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomLock, error) {
+	var locks []*models.RoomLock
+	err := r.db.WithContext(ctx).
+		Where("room_id = ?", roomID).
+		Order("id ASC").
+		Find(&locks).Error
+	return locks, err
+}
+
+func (r *roomLockRepository) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomLock{}).Where("room_id = ?", roomID).Count(&count).Error
+	return count, err
+}
+
+func (r *roomLockRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.RoomLock{}, id).Error
+}
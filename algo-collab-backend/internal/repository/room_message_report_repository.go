@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrReportNotFound 举报不存在
+	ErrReportNotFound = errors.New("举报不存在")
+	// ErrReportDuplicate 已经举报过这条消息
+	ErrReportDuplicate = errors.New("已经举报过这条消息")
+)
+
+var _ RoomMessageReportRepository = (*roomMessageReportRepository)(nil)
+
+type RoomMessageReportRepository interface {
+	// Create 同一成员重复举报同一条消息时返回 ErrReportDuplicate
+	Create(ctx context.Context, report *models.RoomMessageReport) error
+	FindByID(ctx context.Context, id uint) (*models.RoomMessageReport, error)
+	// List 按时间倒序分页返回房间的举报，status 为空表示全部
+	List(ctx context.Context, roomID uint, status string, limit, offset int) ([]*models.RoomMessageReport, int64, error)
+	// UpdateStatus 保存处理结果（状态、处理人和处理时间）
+	UpdateStatus(ctx context.Context, report *models.RoomMessageReport) error
+}
+
+type roomMessageReportRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomMessageReportRepository(db *gorm.DB) RoomMessageReportRepository {
+	return &roomMessageReportRepository{db: db}
+}
+
+func (r *roomMessageReportRepository) Create(ctx context.Context, report *models.RoomMessageReport) error {
+	result := r.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(report)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportDuplicate
+	}
+	return nil
+}
+
+func (r *roomMessageReportRepository) FindByID(ctx context.Context, id uint) (*models.RoomMessageReport, error) {
+	var report models.RoomMessageReport
+	err := r.db.WithContext(ctx).First(&report, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List 带上被举报的消息（及其作者）和举报人，管理员不需要再逐条查询
+func (r *roomMessageReportRepository) List(ctx context.Context, roomID uint, status string, limit, offset int) ([]*models.RoomMessageReport, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.RoomMessageReport{}).Where("room_id = ?", roomID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	publicUser := func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "uuid", "username", "avatar")
+	}
+	var reports []*models.RoomMessageReport
+	err := query.
+		Preload("Message").
+		Preload("Message.User", publicUser).
+		Preload("Reporter", publicUser).
+		Order("id DESC").
+		Limit(limit).Offset(offset).
+		Find(&reports).Error
+	return reports, total, err
+}
+
+func (r *roomMessageReportRepository) UpdateStatus(ctx context.Context, report *models.RoomMessageReport) error {
+	return r.db.WithContext(ctx).
+		Model(report).
+		Select("status", "handled_by", "handled_at").
+		Updates(report).Error
+}
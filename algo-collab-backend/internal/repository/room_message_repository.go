@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRoomMessageNotFound 聊天消息不存在
+var ErrRoomMessageNotFound = errors.New("消息不存在")
+
+var _ RoomMessageRepository = (*roomMessageRepository)(nil)
+
+// RoomMessageFilter 聊天记录筛选条件
+// ThreadID 为 0 时只返回根消息（主时间线），否则返回该消息下的回复
+type RoomMessageFilter struct {
+	BeforeID uint
+	ThreadID uint
+}
+
+type RoomMessageRepository interface {
+	Create(ctx context.Context, message *models.RoomMessage) error
+	// Update 保存编辑和删除（内容、编辑时间、删除时间和删除人）
+	Update(ctx context.Context, message *models.RoomMessage) error
+	// FindByID 返回消息及发送者的公开信息
+	FindByID(ctx context.Context, id uint) (*models.RoomMessage, error)
+	// List 按 ID 倒序返回 filter.BeforeID 之前的最多 limit 条消息，BeforeID 为 0 表示从最新一条开始
+	List(ctx context.Context, roomID uint, filter *RoomMessageFilter, limit int) ([]*models.RoomMessage, error)
+	// CountReplies 统计每条消息的回复数
+	CountReplies(ctx context.Context, messageIDs []uint) (map[uint]int, error)
+
+	// 表情回应
+
+	// ToggleReaction 用户已回应过该表情时取消，否则添加，返回是否为添加
+	ToggleReaction(ctx context.Context, reaction *models.RoomMessageReaction) (bool, error)
+	// ListReactions 按回应时间返回这些消息上的所有回应
+	ListReactions(ctx context.Context, messageIDs []uint) ([]*models.RoomMessageReaction, error)
+}
+
+type roomMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomMessageRepository(db *gorm.DB) RoomMessageRepository {
+	return &roomMessageRepository{db: db}
+}
+
+// preloadAuthor 只加载发送者的公开字段，聊天记录对所有成员可见，不能带出邮箱等信息
+func preloadAuthor(db *gorm.DB) *gorm.DB {
+	return db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "uuid", "username", "avatar")
+	})
+}
+
+func (r *roomMessageRepository) Create(ctx context.Context, message *models.RoomMessage) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(message).Error
+}
+
+func (r *roomMessageRepository) Update(ctx context.Context, message *models.RoomMessage) error {
+	return r.db.WithContext(ctx).
+		Model(message).
+		Select("content", "snippet", "edited_at", "deleted_at", "deleted_by").
+		Updates(message).Error
+}
+
+func (r *roomMessageRepository) FindByID(ctx context.Context, id uint) (*models.RoomMessage, error) {
+	var message models.RoomMessage
+	err := preloadAuthor(r.db.WithContext(ctx)).First(&message, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *roomMessageRepository) List(ctx context.Context, roomID uint, filter *RoomMessageFilter, limit int) ([]*models.RoomMessage, error) {
+	query := r.db.WithContext(ctx).Where("room_id = ?", roomID)
+	if filter.ThreadID > 0 {
+		query = query.Where("reply_to_id = ?", filter.ThreadID)
+	} else {
+		query = query.Where("reply_to_id IS NULL")
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var messages []*models.RoomMessage
+	err := preloadAuthor(query).
+		Order("id DESC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *roomMessageRepository) CountReplies(ctx context.Context, messageIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ReplyToID uint
+		Count     int
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.RoomMessage{}).
+		Select("reply_to_id, COUNT(*) AS count").
+		Where("reply_to_id IN ? AND deleted_at IS NULL", messageIDs).
+		Group("reply_to_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ReplyToID] = row.Count
+	}
+	return counts, nil
+}
+
+// ToggleReaction 先尝试删除，没有删除任何记录说明还没回应过，再插入
+// 并发重复插入由唯一索引兜底，冲突时视为已添加
+func (r *roomMessageRepository) ToggleReaction(ctx context.Context, reaction *models.RoomMessageReaction) (bool, error) {
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("message_id = ? AND user_id = ? AND emoji = ?", reaction.MessageID, reaction.UserID, reaction.Emoji).
+			Delete(&models.RoomMessageReaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		added = true
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction).Error
+	})
+	return added, err
+}
+
+func (r *roomMessageRepository) ListReactions(ctx context.Context, messageIDs []uint) ([]*models.RoomMessageReaction, error) {
+	var reactions []*models.RoomMessageReaction
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("id ASC").
+		Find(&reactions).Error
+	return reactions, err
+}
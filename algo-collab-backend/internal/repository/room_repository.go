@@ -2,19 +2,39 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-//var _ RoomRepository = (*roomRepositoryImpl)(nil)
+var _ RoomRepository = (*roomRepositoryImpl)(nil)
+
+var (
+	// ErrRoomFull 房间有效成员数已达到 MaxMembers
+	ErrRoomFull = errors.New("房间人数已满")
+	// ErrRoomNotFound 房间不存在（或已删除）
+	ErrRoomNotFound = errors.New("房间不存在")
+)
+
+// RoomFilter 房间列表筛选条件，零值字段不参与筛选
+type RoomFilter struct {
+	Difficulty string
+	Topic      string
+	Tags       []string // 房间必须同时带有这些标签
+}
 
 type RoomRepository interface {
+	// Create 创建房间，并在同一事务中把创建者作为房主加入，不会留下没有房主的房间
 	Create(ctx context.Context, room *models.Room) error
 	FindByID(ctx context.Context, id uint) (*models.Room, error)
 	FindByUUID(ctx context.Context, uuid string) (*models.Room, error)
-	FindActiveRooms(ctx context.Context, limit, offset int) ([]*models.Room, error)
+	FindActiveRooms(ctx context.Context, filter *RoomFilter, limit, offset int) ([]*models.Room, error)
 	Update(ctx context.Context, room *models.Room) error
 	Delete(ctx context.Context, id uint) error
+	ReplaceTags(ctx context.Context, room *models.Room, tags []*models.Tag) error
 
 	// 成员相关操作
 
@@ -23,7 +43,326 @@ type RoomRepository interface {
 	GetMembers(ctx context.Context, roomID uint) ([]*models.RoomMember, error)
 	GetMemberCount(ctx context.Context, roomID uint) (int64, error)
 	IsMember(ctx context.Context, roomID, userID uint) (bool, error)
+	GetMember(ctx context.Context, roomID, userID uint) (*models.RoomMember, error)
+	UpdateMemberRole(ctx context.Context, roomID, userID uint, role string) error
+	// SetMutedUntil 设置成员的禁言截止时间，until 为空表示解除禁言
+	SetMutedUntil(ctx context.Context, roomID, userID uint, until *time.Time) error
+
+	// ReserveSeat 原子地占用一个席位，并发加入时不会超过 MaxMembers
+	ReserveSeat(ctx context.Context, roomID, userID uint, role string) (*models.RoomMember, error)
+
+	// 活跃度与归档
+
+	TouchActivity(ctx context.Context, roomID, userID uint) error
+	ArchiveIdleRooms(ctx context.Context, inactiveBefore time.Time) (int64, error)
+	FindPurgeableRooms(ctx context.Context, archivedBefore time.Time, limit int) ([]*models.Room, error)
+	PurgeRoom(ctx context.Context, roomID uint) error
 }
 
 type roomRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRoomRepository(db *gorm.DB) RoomRepository {
+	return &roomRepositoryImpl{db: db}
+}
+
+func (r *roomRepositoryImpl) Create(ctx context.Context, room *models.Room) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(room).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		owner := &models.RoomMember{
+			RoomID:       room.ID,
+			UserID:       room.CreatorID,
+			Role:         models.RoomRoleOwner,
+			JoinedAt:     now,
+			LastActiveAt: now,
+		}
+		return tx.Omit(clause.Associations).Create(owner).Error
+	})
+}
+
+func (r *roomRepositoryImpl) FindByID(ctx context.Context, id uint) (*models.Room, error) {
+	var room models.Room
+	err := r.db.WithContext(ctx).First(&room, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (r *roomRepositoryImpl) FindByUUID(ctx context.Context, uuid string) (*models.Room, error) {
+	var room models.Room
+	err := r.db.WithContext(ctx).Preload("Creator").Preload("Tags").Where("uuid = ?", uuid).First(&room).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (r *roomRepositoryImpl) FindActiveRooms(ctx context.Context, filter *RoomFilter, limit, offset int) ([]*models.Room, error) {
+	var rooms []*models.Room
+
+	query := r.db.WithContext(ctx).
+		Preload("Creator").
+		Preload("Tags").
+		Where("status = ? AND is_public = ?", models.RoomStatusActive, true)
+
+	if filter != nil {
+		if filter.Difficulty != "" {
+			query = query.Where("difficulty = ?", filter.Difficulty)
+		}
+		if filter.Topic != "" {
+			query = query.Where("topic = ?", filter.Topic)
+		}
+		if len(filter.Tags) > 0 {
+			// 子查询：同时带有全部指定标签的房间
+			tagged := r.db.Table("room_tags").
+				Select("room_tags.room_id").
+				Joins("JOIN tags ON tags.id = room_tags.tag_id").
+				Where("tags.name IN ?", filter.Tags).
+				Group("room_tags.room_id").
+				Having("COUNT(DISTINCT tags.id) = ?", len(filter.Tags))
+			query = query.Where("id IN (?)", tagged)
+		}
+	}
+
+	err := query.Order("created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&rooms).Error
+	return rooms, err
+}
+
+func (r *roomRepositoryImpl) Update(ctx context.Context, room *models.Room) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(room).Error
+}
+
+func (r *roomRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Room{}, id).Error
+}
+
+// ReplaceTags 用 tags 覆盖房间的标签
+func (r *roomRepositoryImpl) ReplaceTags(ctx context.Context, room *models.Room, tags []*models.Tag) error {
+	return r.db.WithContext(ctx).Model(room).Association("Tags").Replace(tags)
+}
+
+// AddMember 直接插入成员记录，不做人数校验
+// 用户加入房间请使用 ReserveSeat
+func (r *roomRepositoryImpl) AddMember(ctx context.Context, member *models.RoomMember) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(member).Error
+}
+
+// RemoveMember 软删除成员记录，释放席位
+func (r *roomRepositoryImpl) RemoveMember(ctx context.Context, roomID, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Delete(&models.RoomMember{}).Error
+}
+
+func (r *roomRepositoryImpl) GetMembers(ctx context.Context, roomID uint) ([]*models.RoomMember, error) {
+	var members []*models.RoomMember
+	err := r.db.WithContext(ctx).
+		Preload("Users").
+		Where("room_id = ?", roomID).
+		Order("joined_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// GetMemberCount 统计有效成员数（软删除的记录不计入）
+func (r *roomRepositoryImpl) GetMemberCount(ctx context.Context, roomID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomMember{}).Where("room_id = ?", roomID).Count(&count).Error
+	return count, err
+}
+
+func (r *roomRepositoryImpl) IsMember(ctx context.Context, roomID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roomRepositoryImpl) GetMember(ctx context.Context, roomID, userID uint) (*models.RoomMember, error) {
+	var member models.RoomMember
+	err := r.db.WithContext(ctx).Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *roomRepositoryImpl) UpdateMemberRole(ctx context.Context, roomID, userID uint, role string) error {
+	return r.db.WithContext(ctx).Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		UpdateColumn("role", role).Error
+}
+
+func (r *roomRepositoryImpl) SetMutedUntil(ctx context.Context, roomID, userID uint, until *time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		UpdateColumn("muted_until", until).Error
+}
+
+// ReserveSeat 在一个事务里完成"检查人数 + 写入成员"
+//
+//  1. SELECT ... FOR UPDATE 锁住房间行，同一房间的并发加入在这里排队
+//  2. 已经是有效成员：幂等返回，不占用新席位
+//  3. 统计有效成员数（不含软删除），达到 MaxMembers 返回 ErrRoomFull
+//  4. 有软删除的历史记录（之前退出过）就恢复它，否则插入新记录
+//
+// 因为 (room_id, user_id) 有唯一索引，恢复旧记录而不是重新插入
+func (r *roomRepositoryImpl) ReserveSeat(ctx context.Context, roomID, userID uint, role string) (*models.RoomMember, error) {
+	var member models.RoomMember
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 锁住房间行
+		var room models.Room
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&room, roomID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoomNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// 加入房间也算一次活动
+		if err := tx.Model(&room).UpdateColumn("last_active_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// 2. 查找历史记录（包含软删除的）
+		err = tx.Unscoped().Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if found && !member.DeletedAt.Valid {
+			return nil
+		}
+
+		// 3. 检查席位
+		var count int64
+		if err := tx.Model(&models.RoomMember{}).Where("room_id = ?", roomID).Count(&count).Error; err != nil {
+			return err
+		}
+		if room.IsFull(count) {
+			return ErrRoomFull
+		}
+
+		// 4. 恢复或插入
+		now := time.Now()
+		if found {
+			err := tx.Unscoped().Model(&member).Updates(map[string]interface{}{
+				"deleted_at":     nil,
+				"role":           role,
+				"joined_at":      now,
+				"last_active_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+			member.DeletedAt = gorm.DeletedAt{}
+			member.Role = role
+			member.JoinedAt = now
+			member.LastActiveAt = now
+			return nil
+		}
+
+		member = models.RoomMember{
+			RoomID:       roomID,
+			UserID:       userID,
+			Role:         role,
+			JoinedAt:     now,
+			LastActiveAt: now,
+		}
+		return tx.Omit(clause.Associations).Create(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// TouchActivity 刷新成员和房间的最后活跃时间
+// 只有有效成员的活动才算数：成员记录没有更新（不是成员或已退出）时不刷新房间
+func (r *roomRepositoryImpl) TouchActivity(ctx context.Context, roomID, userID uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RoomMember{}).
+			Where("room_id = ? AND user_id = ?", roomID, userID).
+			UpdateColumn("last_active_at", now)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		return tx.Model(&models.Room{}).
+			Where("id = ?", roomID).
+			UpdateColumn("last_active_at", now).Error
+	})
+}
+
+// ArchiveIdleRooms 把 inactiveBefore 之后没有任何活动的房间标记为归档
+// 加入活跃时间之前创建、从未刷新过的房间（last_active_at 为空）按创建时间计算
+func (r *roomRepositoryImpl) ArchiveIdleRooms(ctx context.Context, inactiveBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Room{}).
+		Where("status = ? AND COALESCE(last_active_at, created_at) < ?", models.RoomStatusActive, inactiveBefore).
+		Updates(map[string]interface{}{
+			"status":      models.RoomStatusArchived,
+			"archived_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// FindPurgeableRooms 查找归档时间早于 archivedBefore 的房间
+func (r *roomRepositoryImpl) FindPurgeableRooms(ctx context.Context, archivedBefore time.Time, limit int) ([]*models.Room, error) {
+	var rooms []*models.Room
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND archived_at < ?", models.RoomStatusArchived, archivedBefore).
+		Order("archived_at ASC").
+		Limit(limit).
+		Find(&rooms).Error
+	return rooms, err
+}
+
+// roomOwnedTables 按 room_id 归属于房间的表，彻底删除房间时一并清理
+var roomOwnedTables = []interface{}{
+	&models.RoomMember{},
+	&models.RoomEvent{},
+	&models.Document{},
+	&models.DocumentUpdate{},
+	&models.DocumentVersion{},
+	&models.RoomMessage{},
+	&models.RoomMessageReaction{},
+	&models.Notification{},
+	&models.RoomMessageReport{},
+	&models.RoomSessionEvent{},
+	&models.RoomSession{},
+	&models.DocumentAuthor{},
+	&models.RoomFile{},
+	&models.RoomComment{},
+	&models.RoomCommentThread{},
+	&models.RoomLock{},
+	&models.RoomWhiteboard{},
+	&models.WhiteboardDocument{},
+	&models.WhiteboardUpdate{},
+}
+
+// PurgeRoom 物理删除房间及其所有关联数据（不可恢复）
+func (r *roomRepositoryImpl) PurgeRoom(ctx context.Context, roomID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range roomOwnedTables {
+			if err := tx.Unscoped().Where("room_id = ?", roomID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM room_tags WHERE room_id = ?", roomID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Room{}, roomID).Error
+	})
 }
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/database"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordingPool 不连接数据库的 gorm 连接：记录执行的语句，按顺序返回预设的影响行数
+// 只支持 Exec，查询返回错误
+type recordingPool struct {
+	mu       sync.Mutex
+	execs    []string
+	affected []int64
+}
+
+var errQueryUnsupported = errors.New("recordingPool 不支持查询")
+
+func (p *recordingPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errQueryUnsupported
+}
+
+func (p *recordingPool) ExecContext(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.execs = append(p.execs, query)
+	rows := int64(1)
+	if len(p.affected) > 0 {
+		rows, p.affected = p.affected[0], p.affected[1:]
+	}
+	return driver.RowsAffected(rows), nil
+}
+
+func (p *recordingPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errQueryUnsupported
+}
+
+func (p *recordingPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+// BeginTx 事务中的语句同样记录，提交和回滚什么也不做
+func (p *recordingPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &recordingTx{p}, nil
+}
+
+type recordingTx struct{ *recordingPool }
+
+func (tx *recordingTx) Commit() error   { return nil }
+func (tx *recordingTx) Rollback() error { return nil }
+
+func (p *recordingPool) statements() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.execs...)
+}
+
+func newRecordingDB(t *testing.T, affected ...int64) (*gorm.DB, *recordingPool) {
+	pool := &recordingPool{affected: affected}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db, pool
+}
+
+func TestRoomRepository_TouchActivityRequiresMember(t *testing.T) {
+	// 成员记录没有更新：不是成员或已退出，不刷新房间
+	db, pool := newRecordingDB(t, 0)
+	require.NoError(t, NewRoomRepository(db).TouchActivity(context.Background(), 1, 7))
+	statements := pool.statements()
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `UPDATE "room_members"`)
+	assert.Contains(t, statements[0], `"deleted_at" IS NULL`)
+
+	db, pool = newRecordingDB(t, 1, 1)
+	require.NoError(t, NewRoomRepository(db).TouchActivity(context.Background(), 1, 7))
+	statements = pool.statements()
+	require.Len(t, statements, 2)
+	assert.Contains(t, statements[1], `UPDATE "rooms"`)
+}
+
+func TestRoomRepository_ArchiveIdleRooms(t *testing.T) {
+	db, pool := newRecordingDB(t, 3)
+	archived, err := NewRoomRepository(db).ArchiveIdleRooms(context.Background(), time.Now().AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), archived)
+
+	statements := pool.statements()
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], `UPDATE "rooms" SET "archived_at"`)
+	// 从未刷新过活跃时间的旧房间按创建时间计算
+	assert.Contains(t, statements[0], "COALESCE(last_active_at, created_at) <")
+	assert.Contains(t, statements[0], `"rooms"."deleted_at" IS NULL`)
+}
+
+func TestRoomRepository_PurgeRoom(t *testing.T) {
+	db, pool := newRecordingDB(t)
+	require.NoError(t, NewRoomRepository(db).PurgeRoom(context.Background(), 1))
+
+	statements := pool.statements()
+	require.Len(t, statements, len(roomOwnedTables)+2)
+	assert.Contains(t, statements[len(statements)-2], "DELETE FROM room_tags")
+	assert.True(t, strings.HasPrefix(statements[len(statements)-1], `DELETE FROM "rooms"`), statements[len(statements)-1])
+}
+
+// 新加的模型有 room_id 却忘了加入 roomOwnedTables 时，彻底删除房间会留下孤儿数据
+func TestRoomRepository_PurgeRoomCoversAllModels(t *testing.T) {
+	owned := make(map[reflect.Type]bool, len(roomOwnedTables))
+	for _, model := range roomOwnedTables {
+		owned[reflect.TypeOf(model)] = true
+	}
+
+	for _, model := range database.Models {
+		typ := reflect.TypeOf(model)
+		if _, ok := typ.Elem().FieldByName("RoomID"); !ok || typ == reflect.TypeOf(&models.Room{}) {
+			continue
+		}
+		assert.True(t, owned[typ], "%s 有 RoomID，需要加入 roomOwnedTables", typ.Elem().Name())
+	}
+}
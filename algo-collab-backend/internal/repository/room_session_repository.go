@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRoomSessionNotFound 会话不存在或分享链接已失效
+var ErrRoomSessionNotFound = errors.New("回放不存在")
+
+var _ RoomSessionRepository = (*roomSessionRepository)(nil)
+
+// SessionEventCursor 事件按 (offset_ms, id) 排序，游标指向上一页的最后一条
+type SessionEventCursor struct {
+	OffsetMS int64
+	ID       uint
+}
+
+// SessionStart 从头读取事件的游标
+var SessionStart = SessionEventCursor{OffsetMS: -1}
+
+type RoomSessionRepository interface {
+	// Begin 返回房间在 at 时仍在进行的会话（最后一个事件在 gap 之内），没有时创建新会话
+	Begin(ctx context.Context, roomID uint, at time.Time, gap time.Duration) (*models.RoomSession, error)
+	// AppendEvents 批量写入事件，并更新各会话的时长和事件数
+	AppendEvents(ctx context.Context, events []*models.RoomSessionEvent) error
+	FindByID(ctx context.Context, id uint) (*models.RoomSession, error)
+	FindByShareToken(ctx context.Context, token string) (*models.RoomSession, error)
+	// List 按开始时间倒序分页返回房间的会话
+	List(ctx context.Context, roomID uint, limit, offset int) ([]*models.RoomSession, int64, error)
+	// SetShare 设置或清除（token 为空）分享链接
+	SetShare(ctx context.Context, id uint, token *string, userID *uint) error
+	// LatestKeyframe 不晚于 offsetMS 的最后一个关键帧，没有时返回 nil
+	LatestKeyframe(ctx context.Context, sessionID uint, offsetMS int64) (*models.RoomSessionEvent, error)
+	// ListEvents 游标之后的最多 limit 条事件，kind 为空表示所有类型
+	ListEvents(ctx context.Context, sessionID uint, kind string, after SessionEventCursor, limit int) ([]*models.RoomSessionEvent, error)
+	// DeleteBefore 删除最后活动早于 before 的会话及其事件，返回删除的会话数
+	DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type roomSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomSessionRepository(db *gorm.DB) RoomSessionRepository {
+	return &roomSessionRepository{db: db}
+}
+
+// Begin 锁住房间行，多个节点同时开始会话时只会创建一个
+func (r *roomSessionRepository) Begin(ctx context.Context, roomID uint, at time.Time, gap time.Duration) (*models.RoomSession, error) {
+	var session models.RoomSession
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&room, roomID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("room_id = ? AND last_event_at >= ?", roomID, at.Add(-gap)).
+			Order("last_event_at DESC").
+			First(&session).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		session = models.RoomSession{RoomID: roomID, StartedAt: at, LastEventAt: at}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *roomSessionRepository) AppendEvents(ctx context.Context, events []*models.RoomSessionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// 每个会话本批的事件数和最晚的时间
+	type progress struct {
+		count int
+		last  int64
+	}
+	sessions := make(map[uint]*progress)
+	for _, event := range events {
+		p := sessions[event.SessionID]
+		if p == nil {
+			p = &progress{}
+			sessions[event.SessionID] = p
+		}
+		p.count++
+		p.last = max(p.last, event.OffsetMS)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(events, 200).Error; err != nil {
+			return err
+		}
+		for id, p := range sessions {
+			err := tx.Model(&models.RoomSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"duration_ms":   gorm.Expr("GREATEST(duration_ms, ?)", p.last),
+				"last_event_at": gorm.Expr("GREATEST(last_event_at, started_at + ? * INTERVAL '1 millisecond')", p.last),
+				"event_count":   gorm.Expr("event_count + ?", p.count),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *roomSessionRepository) FindByID(ctx context.Context, id uint) (*models.RoomSession, error) {
+	var session models.RoomSession
+	err := r.db.WithContext(ctx).First(&session, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *roomSessionRepository) FindByShareToken(ctx context.Context, token string) (*models.RoomSession, error) {
+	var session models.RoomSession
+	err := r.db.WithContext(ctx).Where("share_token = ?", token).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *roomSessionRepository) List(ctx context.Context, roomID uint, limit, offset int) ([]*models.RoomSession, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.RoomSession{}).Where("room_id = ?", roomID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []*models.RoomSession
+	err := query.
+		Order("started_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+func (r *roomSessionRepository) SetShare(ctx context.Context, id uint, token *string, userID *uint) error {
+	var sharedAt *time.Time
+	if token != nil {
+		now := time.Now()
+		sharedAt = &now
+	}
+	return r.db.WithContext(ctx).Model(&models.RoomSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"share_token": token,
+		"shared_by":   userID,
+		"shared_at":   sharedAt,
+	}).Error
+}
+
+func (r *roomSessionRepository) LatestKeyframe(ctx context.Context, sessionID uint, offsetMS int64) (*models.RoomSessionEvent, error) {
+	var event models.RoomSessionEvent
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND kind = ? AND offset_ms <= ?", sessionID, models.SessionEventKeyframe, offsetMS).
+		Order("offset_ms DESC, id DESC").
+		First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *roomSessionRepository) ListEvents(ctx context.Context, sessionID uint, kind string, after SessionEventCursor, limit int) ([]*models.RoomSessionEvent, error) {
+	query := r.db.WithContext(ctx).
+		Where("session_id = ?", sessionID).
+		Where("offset_ms > ? OR (offset_ms = ? AND id > ?)", after.OffsetMS, after.OffsetMS, after.ID)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var events []*models.RoomSessionEvent
+	err := query.Order("offset_ms ASC, id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (r *roomSessionRepository) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.RoomSession{}).
+		Where("last_event_at < ?", before).
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id IN ?", ids).Delete(&models.RoomSessionEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.RoomSession{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
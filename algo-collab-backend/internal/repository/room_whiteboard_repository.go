@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrWhiteboardNotFound 白板不存在
+var ErrWhiteboardNotFound = errors.New("白板不存在")
+
+var _ RoomWhiteboardRepository = (*roomWhiteboardRepository)(nil)
+
+type RoomWhiteboardRepository interface {
+	Create(ctx context.Context, board *models.RoomWhiteboard) error
+	FindByID(ctx context.Context, id uint) (*models.RoomWhiteboard, error)
+	// ListByRoom 按创建顺序返回房间的所有白板
+	ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomWhiteboard, error)
+	CountByRoom(ctx context.Context, roomID uint) (int64, error)
+	// Update 修改标题和关联的讨论，threadID 为空表示取消关联
+	Update(ctx context.Context, id uint, title string, threadID *uint) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type roomWhiteboardRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomWhiteboardRepository(db *gorm.DB) RoomWhiteboardRepository {
+	return &roomWhiteboardRepository{db: db}
+}
+
+func (r *roomWhiteboardRepository) Create(ctx context.Context, board *models.RoomWhiteboard) error {
+	return r.db.WithContext(ctx).Create(board).Error
+}
+
+func (r *roomWhiteboardRepository) FindByID(ctx context.Context, id uint) (*models.RoomWhiteboard, error) {
+	var board models.RoomWhiteboard
+	err := r.db.WithContext(ctx).First(&board, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWhiteboardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+func (r *roomWhiteboardRepository) ListByRoom(ctx context.Context, roomID uint) ([]*models.RoomWhiteboard, error) {
+	var boards []*models.RoomWhiteboard
+	err := r.db.WithContext(ctx).
+		Where("room_id = ?", roomID).
+		Order("id ASC").
+		Find(&boards).Error
+	return boards, err
+}
+
+func (r *roomWhiteboardRepository) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RoomWhiteboard{}).Where("room_id = ?", roomID).Count(&count).Error
+	return count, err
+}
+
+func (r *roomWhiteboardRepository) Update(ctx context.Context, id uint, title string, threadID *uint) error {
+	return r.db.WithContext(ctx).Model(&models.RoomWhiteboard{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"title":     title,
+		"thread_id": threadID,
+	}).Error
+}
+
+func (r *roomWhiteboardRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.RoomWhiteboard{}, id).Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+)
+
+var _ TagRepository = (*tagRepository)(nil)
+
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	FindByNames(ctx context.Context, names []string) ([]*models.Tag, error)
+	FindByName(ctx context.Context, name string) (*models.Tag, error)
+	// ListWithUsage 按使用次数倒序返回标签，prefix 为空时返回全部
+	ListWithUsage(ctx context.Context, prefix, category string, limit int) ([]*models.TagUsage, error)
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *tagRepository) FindByNames(ctx context.Context, names []string) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	if len(names) == 0 {
+		return tags, nil
+	}
+	err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) FindByName(ctx context.Context, name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ListWithUsage 统计每个标签被多少个未删除的房间使用
+func (r *tagRepository) ListWithUsage(ctx context.Context, prefix, category string, limit int) ([]*models.TagUsage, error) {
+	var usages []*models.TagUsage
+
+	query := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Select("tags.*, COUNT(rooms.id) AS usage_count").
+		Joins("LEFT JOIN room_tags ON room_tags.tag_id = tags.id").
+		Joins("LEFT JOIN rooms ON rooms.id = room_tags.room_id AND rooms.deleted_at IS NULL").
+		Group("tags.id")
+	if prefix != "" {
+		query = query.Where("tags.name ILIKE ?", prefix+"%")
+	}
+	if category != "" {
+		query = query.Where("tags.category = ?", category)
+	}
+
+	err := query.Order("usage_count DESC, tags.name ASC").Limit(limit).Scan(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+)
+
+var _ TemplateRepository = (*templateRepository)(nil)
+
+// ErrTemplateNotFound 模板不存在
+var ErrTemplateNotFound = errors.New("模板不存在")
+
+type TemplateRepository interface {
+	Create(ctx context.Context, template *models.RoomTemplate) error
+	FindByID(ctx context.Context, id uint) (*models.RoomTemplate, error)
+	FindByUUID(ctx context.Context, uuid string) (*models.RoomTemplate, error)
+	// FindVisible 返回用户可见的模板：自己的 + 所在组织共享的 + 公开的
+	FindVisible(ctx context.Context, userID uint, orgIDs []uint, limit, offset int) ([]*models.RoomTemplate, error)
+	Update(ctx context.Context, template *models.RoomTemplate) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type templateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func (r *templateRepository) Create(ctx context.Context, template *models.RoomTemplate) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+func (r *templateRepository) FindByID(ctx context.Context, id uint) (*models.RoomTemplate, error) {
+	var template models.RoomTemplate
+	err := r.db.WithContext(ctx).First(&template, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *templateRepository) FindByUUID(ctx context.Context, uuid string) (*models.RoomTemplate, error) {
+	var template models.RoomTemplate
+	err := r.db.WithContext(ctx).Where("uuid = ?", uuid).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *templateRepository) FindVisible(ctx context.Context, userID uint, orgIDs []uint, limit, offset int) ([]*models.RoomTemplate, error) {
+	var templates []*models.RoomTemplate
+
+	// 可见条件单独成组，避免 OR 和软删除条件混在一起
+	visible := r.db.Where("owner_id = ?", userID).
+		Or("visibility = ?", models.TemplateVisibilityPublic)
+	if len(orgIDs) > 0 {
+		visible = visible.Or("visibility = ? AND organization_id IN ?", models.TemplateVisibilityTeam, orgIDs)
+	}
+
+	err := r.db.WithContext(ctx).
+		Where(visible).
+		Order("updated_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&templates).Error
+	return templates, err
+}
+
+func (r *templateRepository) Update(ctx context.Context, template *models.RoomTemplate) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+func (r *templateRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.RoomTemplate{}, id).Error
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ WhiteboardDocumentRepository = (*whiteboardDocumentRepository)(nil)
+
+// WhiteboardDocumentRepository 白板文档的快照和增量，用法同 DocumentRepository，按白板 ID 区分
+type WhiteboardDocumentRepository interface {
+	// Load 读取白板的快照和增量更新，从未保存过时快照为 nil
+	Load(ctx context.Context, boardID uint) (*models.WhiteboardDocument, []*models.WhiteboardUpdate, error)
+	// AppendUpdate 追加一条增量更新，返回其 ID
+	AppendUpdate(ctx context.Context, roomID, boardID uint, data []byte) (uint, error)
+	// Compact 同 DocumentRepository.Compact
+	Compact(ctx context.Context, roomID, boardID uint, snapshot []byte, uptoID uint, pending [][]byte) (uint, error)
+	// Delete 删除白板的快照和所有增量
+	Delete(ctx context.Context, boardID uint) error
+}
+
+type whiteboardDocumentRepository struct {
+	db *gorm.DB
+}
+
+func NewWhiteboardDocumentRepository(db *gorm.DB) WhiteboardDocumentRepository {
+	return &whiteboardDocumentRepository{db: db}
+}
+
+// Load 和 documentRepository.Load 一样先读增量再读快照
+func (r *whiteboardDocumentRepository) Load(ctx context.Context, boardID uint) (*models.WhiteboardDocument, []*models.WhiteboardUpdate, error) {
+	var updates []*models.WhiteboardUpdate
+	err := r.db.WithContext(ctx).
+		Where("whiteboard_id = ?", boardID).
+		Order("id ASC").
+		Find(&updates).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc models.WhiteboardDocument
+	err = r.db.WithContext(ctx).Where("whiteboard_id = ?", boardID).First(&doc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, updates, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &doc, updates, nil
+}
+
+func (r *whiteboardDocumentRepository) AppendUpdate(ctx context.Context, roomID, boardID uint, data []byte) (uint, error) {
+	update := &models.WhiteboardUpdate{RoomID: roomID, WhiteboardID: boardID, Data: data}
+	if err := r.db.WithContext(ctx).Create(update).Error; err != nil {
+		return 0, err
+	}
+	return update.ID, nil
+}
+
+func (r *whiteboardDocumentRepository) Compact(ctx context.Context, roomID, boardID uint, snapshot []byte, uptoID uint, pending [][]byte) (uint, error) {
+	lastID := uptoID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		doc := &models.WhiteboardDocument{RoomID: roomID, WhiteboardID: boardID, Snapshot: snapshot, Size: len(snapshot)}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "whiteboard_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"snapshot", "size", "updated_at"}),
+		}).Create(doc).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("whiteboard_id = ? AND id <= ?", boardID, uptoID).Delete(&models.WhiteboardUpdate{}).Error; err != nil {
+			return err
+		}
+
+		for _, data := range pending {
+			update := &models.WhiteboardUpdate{RoomID: roomID, WhiteboardID: boardID, Data: data}
+			if err := tx.Create(update).Error; err != nil {
+				return err
+			}
+			lastID = update.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lastID, nil
+}
+
+func (r *whiteboardDocumentRepository) Delete(ctx context.Context, boardID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("whiteboard_id = ?", boardID).Delete(&models.WhiteboardUpdate{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("whiteboard_id = ?", boardID).Delete(&models.WhiteboardDocument{}).Error
+	})
+}
@@ -3,21 +3,76 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/is-Xiaoen/algo-collab/internal/controller"
+	"github.com/is-Xiaoen/algo-collab/internal/lsp"
 	"github.com/is-Xiaoen/algo-collab/internal/middleware"
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
 	"github.com/is-Xiaoen/algo-collab/internal/service"
 )
 
+// Services 路由依赖的服务集合
+type Services struct {
+	Auth         service.AuthService
+	Room         service.RoomService
+	Template     service.TemplateService
+	Organization service.OrganizationService
+	Tag          service.TagService
+	Chat         service.ChatService
+	Notification service.NotificationService
+	Moderation   service.ModerationService
+	Version      service.VersionService
+	Replay       service.ReplayService
+	Workspace    service.WorkspaceService
+	Comment      service.CommentService
+	Lock         service.LockService
+	Whiteboard   service.WhiteboardService
+
+	// Hub 实时协作
+	Hub *realtime.Hub
+	// LSP 语言服务器网关
+	LSP *lsp.Gateway
+}
+
 // Router 路由管理器
 type Router struct {
-	authController *controller.AuthController
-	authService    service.AuthService
+	authController         *controller.AuthController
+	roomController         *controller.RoomController
+	templateController     *controller.TemplateController
+	organizationController *controller.OrganizationController
+	tagController          *controller.TagController
+	chatController         *controller.ChatController
+	notificationController *controller.NotificationController
+	moderationController   *controller.ModerationController
+	versionController      *controller.VersionController
+	replayController       *controller.ReplayController
+	workspaceController    *controller.WorkspaceController
+	commentController      *controller.CommentController
+	lockController         *controller.LockController
+	whiteboardController   *controller.WhiteboardController
+	collabController       *controller.CollaborationController
+	lspController          *controller.LanguageServerController
+	authService            service.AuthService
 }
 
 // NewRouter 创建路由管理器
-func NewRouter(authService service.AuthService) *Router {
+func NewRouter(services *Services) *Router {
 	return &Router{
-		authController: controller.NewAuthController(authService),
-		authService:    authService,
+		authController:         controller.NewAuthController(services.Auth),
+		roomController:         controller.NewRoomController(services.Room, services.Organization, services.Hub),
+		templateController:     controller.NewTemplateController(services.Template),
+		organizationController: controller.NewOrganizationController(services.Organization),
+		tagController:          controller.NewTagController(services.Tag),
+		chatController:         controller.NewChatController(services.Chat),
+		notificationController: controller.NewNotificationController(services.Notification),
+		moderationController:   controller.NewModerationController(services.Moderation),
+		versionController:      controller.NewVersionController(services.Version, services.Hub),
+		replayController:       controller.NewReplayController(services.Replay),
+		workspaceController:    controller.NewWorkspaceController(services.Workspace, services.Hub),
+		commentController:      controller.NewCommentController(services.Comment, services.Hub),
+		lockController:         controller.NewLockController(services.Lock, services.Hub),
+		whiteboardController:   controller.NewWhiteboardController(services.Whiteboard, services.Hub),
+		collabController:       controller.NewCollaborationController(services.Room, services.Whiteboard, services.Hub),
+		lspController:          controller.NewLanguageServerController(services.Room, services.LSP),
+		authService:            services.Auth,
 	}
 }
 
@@ -26,7 +81,15 @@ func (r *Router) Setup(engine *gin.Engine) {
 	// 1. 全局中间件
 	// TODO: 添加全局中间件
 
-	// 2. API路由组
+	// 按原始路径匹配路由，文件路径中编码为 %2F 的 / 不会被拆成多个路径段，见 filePathParam
+	engine.UseRawPath = true
+
+	// 2. 实时协作（y-websocket 客户端直接连接，通过 ?token= 鉴权）
+	engine.GET("/collaboration/:roomId", middleware.AuthMiddleware(r.authService), r.collabController.Connect)
+	engine.GET("/collaboration/:roomId/whiteboards/:boardId", middleware.AuthMiddleware(r.authService), r.collabController.ConnectWhiteboard)
+	engine.GET("/lsp/:roomId", middleware.AuthMiddleware(r.authService), r.lspController.Connect)
+
+	// 3. API路由组
 	api := engine.Group("/api")
 	{
 		// 版本1的路由
@@ -40,12 +103,91 @@ func (r *Router) Setup(engine *gin.Engine) {
 				auth.POST("refresh", r.authController.RefreshToken)
 			}
 
+			// 回放分享链接（不需要登录）
+			v1.GET("/replays/:token", r.replayController.GetShared)
+			v1.GET("/replays/:token/stream", r.replayController.StreamShared)
+
 			// 需要认证的路由
 			protected := v1.Group("")
 			protected.Use(middleware.AuthMiddleware(r.authService))
 			{
 				protected.POST("/auth/logout", r.authController.Logout)
 				protected.GET("/auth/me", r.authController.GetCurrentUser)
+
+				// 房间
+				protected.POST("/rooms", r.roomController.CreateRoom)
+				protected.GET("/rooms", r.roomController.ListRooms)
+				protected.GET("/rooms/:uuid", r.roomController.GetRoom)
+				protected.POST("/rooms/:uuid/join", r.roomController.JoinRoom)
+				protected.POST("/rooms/:uuid/leave", r.roomController.LeaveRoom)
+				protected.GET("/rooms/:uuid/members", r.roomController.GetMembers)
+				protected.POST("/rooms/:uuid/activity", r.roomController.RecordActivity)
+				protected.POST("/rooms/:uuid/archive", r.roomController.ArchiveRoom)
+				protected.POST("/rooms/:uuid/unarchive", r.roomController.UnarchiveRoom)
+				protected.POST("/rooms/:uuid/template", r.templateController.SaveRoomAsTemplate)
+				protected.PUT("/rooms/:uuid/metadata", r.roomController.UpdateMetadata)
+				protected.PUT("/rooms/:uuid/problem", r.roomController.UpdateProblem)
+				protected.PUT("/rooms/:uuid/members/:userId/role", r.roomController.UpdateMemberRole)
+				protected.DELETE("/rooms/:uuid/members/:userId", r.roomController.KickMember)
+				protected.GET("/rooms/:uuid/events", r.roomController.ListEvents)
+				protected.GET("/rooms/:uuid/messages", r.chatController.ListMessages)
+				protected.POST("/rooms/:uuid/messages/:messageId/report", r.moderationController.ReportMessage)
+				protected.PUT("/rooms/:uuid/members/:userId/mute", r.moderationController.MuteMember)
+				protected.DELETE("/rooms/:uuid/members/:userId/mute", r.moderationController.UnmuteMember)
+				protected.GET("/rooms/:uuid/reports", r.moderationController.ListReports)
+				protected.PUT("/rooms/:uuid/reports/:reportId", r.moderationController.HandleReport)
+				protected.GET("/rooms/:uuid/versions", r.versionController.ListVersions)
+				protected.POST("/rooms/:uuid/versions", r.versionController.CreateCheckpoint)
+				protected.GET("/rooms/:uuid/versions/:versionId", r.versionController.GetVersion)
+				protected.GET("/rooms/:uuid/versions/:versionId/diff", r.versionController.DiffVersion)
+				protected.POST("/rooms/:uuid/versions/:versionId/restore", r.versionController.RestoreVersion)
+				protected.GET("/rooms/:uuid/versions/:versionId/blame", r.versionController.VersionBlame)
+				protected.GET("/rooms/:uuid/blame", r.versionController.Blame)
+				protected.GET("/rooms/:uuid/files", r.workspaceController.ListFiles)
+				protected.POST("/rooms/:uuid/files", r.workspaceController.CreateFile)
+				protected.PUT("/rooms/:uuid/files/:fileId", r.workspaceController.UpdateFile)
+				protected.DELETE("/rooms/:uuid/files/:fileId", r.workspaceController.DeleteFile)
+				protected.GET("/rooms/:uuid/files/:path/content", r.workspaceController.GetFileContent)
+				protected.GET("/rooms/:uuid/workspace", r.workspaceController.GetWorkspace)
+				protected.GET("/rooms/:uuid/comments", r.commentController.ListThreads)
+				protected.POST("/rooms/:uuid/comments", r.commentController.CreateThread)
+				protected.POST("/rooms/:uuid/comments/:threadId/replies", r.commentController.Reply)
+				protected.POST("/rooms/:uuid/comments/:threadId/resolve", r.commentController.ResolveThread)
+				protected.POST("/rooms/:uuid/comments/:threadId/reopen", r.commentController.ReopenThread)
+				protected.GET("/rooms/:uuid/locks", r.lockController.ListLocks)
+				protected.POST("/rooms/:uuid/locks", r.lockController.CreateLock)
+				protected.DELETE("/rooms/:uuid/locks/:lockId", r.lockController.DeleteLock)
+				protected.GET("/rooms/:uuid/whiteboards", r.whiteboardController.ListWhiteboards)
+				protected.POST("/rooms/:uuid/whiteboards", r.whiteboardController.CreateWhiteboard)
+				protected.PUT("/rooms/:uuid/whiteboards/:boardId", r.whiteboardController.UpdateWhiteboard)
+				protected.DELETE("/rooms/:uuid/whiteboards/:boardId", r.whiteboardController.DeleteWhiteboard)
+				protected.GET("/rooms/:uuid/whiteboards/:boardId/export", r.whiteboardController.ExportWhiteboard)
+				protected.GET("/rooms/:uuid/sessions", r.replayController.ListSessions)
+				protected.GET("/rooms/:uuid/sessions/:sessionId", r.replayController.GetSession)
+				protected.GET("/rooms/:uuid/sessions/:sessionId/stream", r.replayController.StreamSession)
+				protected.POST("/rooms/:uuid/sessions/:sessionId/share", r.replayController.ShareSession)
+				protected.DELETE("/rooms/:uuid/sessions/:sessionId/share", r.replayController.UnshareSession)
+
+				// 通知
+				protected.GET("/notifications", r.notificationController.ListNotifications)
+				protected.POST("/notifications/read", r.notificationController.MarkRead)
+
+				// 标签
+				protected.GET("/tags", r.tagController.ListTags)
+				protected.POST("/tags", middleware.RequireRole("admin"), r.tagController.CreateTag)
+
+				// 房间模板
+				protected.POST("/templates", r.templateController.CreateTemplate)
+				protected.GET("/templates", r.templateController.ListTemplates)
+				protected.GET("/templates/:uuid", r.templateController.GetTemplate)
+				protected.DELETE("/templates/:uuid", r.templateController.DeleteTemplate)
+				protected.POST("/templates/:uuid/rooms", r.templateController.CreateRoomFromTemplate)
+
+				// 组织
+				protected.POST("/organizations", r.organizationController.CreateOrganization)
+				protected.POST("/organizations/:uuid/members", r.organizationController.AddMember)
+				protected.PUT("/organizations/:uuid/default-template", r.organizationController.SetDefaultTemplate)
+				protected.POST("/organizations/:uuid/rooms", r.organizationController.CreateRoom)
 			}
 		}
 	}
@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+
+	"github.com/is-Xiaoen/algo-collab/internal/lsp"
+)
+
+var _ lsp.Launcher = (*Launcher)(nil)
+
+// Launcher 运行配置的命令作为房间的语言服务器，通过标准输入输出通信，实现 lsp.Launcher
+type Launcher struct {
+	commands map[string][]string
+}
+
+// NewLauncher commands 为每个语言服务器（gopls、pyright 等）的启动命令及参数，其中的 {room} 替换为房间 UUID
+func NewLauncher(commands map[string][]string) *Launcher {
+	return &Launcher{commands: commands}
+}
+
+// Launch 启动语言服务器，ctx 只用于启动阶段，进程的生命周期由 Close 控制
+func (l *Launcher) Launch(_ context.Context, roomUUID, server string) (io.ReadWriteCloser, error) {
+	command := l.commands[server]
+	if len(command) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoCommand, server)
+	}
+	args := expandArgs(command, roomUUID, server)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = commandEnv()
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, fmt.Errorf("sandbox: 启动命令失败: %w", err)
+	}
+	return &stdioProcess{stdin: stdin, stdout: stdout, cmd: cmd}, nil
+}
+
+// stdioProcess 通过管道通信的命令，标准错误丢弃
+type stdioProcess struct {
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	cmd       *exec.Cmd
+	closeOnce sync.Once
+}
+
+func (p *stdioProcess) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *stdioProcess) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close 结束命令启动的整个进程组，之后 Read 返回错误
+func (p *stdioProcess) Close() error {
+	p.closeOnce.Do(func() {
+		killProcessGroup(p.cmd)
+		_ = p.stdin.Close()
+		_ = p.cmd.Wait()
+	})
+	return nil
+}
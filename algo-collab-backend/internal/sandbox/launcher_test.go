@@ -0,0 +1,48 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLauncher_RunsServerOverStdio(t *testing.T) {
+	launcher := NewLauncher(map[string][]string{
+		"gopls": {"sh", "-c", "echo server={room}; cat"},
+	})
+	conn, err := launcher.Launch(context.Background(), "room-1", "gopls")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+
+	// 1. 参数替换
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "server=room-1\n", line)
+
+	// 2. 标准输入输出双向转发
+	_, err = conn.Write([]byte("Content-Length: 2\r\n\r\n{}\n"))
+	require.NoError(t, err)
+	for _, want := range []string{"Content-Length: 2\r\n", "\r\n", "{}\n"} {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+
+	// 3. Close 结束进程，之后读写都失败
+	require.NoError(t, conn.Close())
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+	_, err = conn.Write([]byte("x"))
+	assert.Error(t, err)
+}
+
+func TestLauncher_UnknownServer(t *testing.T) {
+	launcher := NewLauncher(map[string][]string{"gopls": {"cat"}})
+	_, err := launcher.Launch(context.Background(), "room-1", "pyright")
+	assert.ErrorIs(t, err, ErrNoCommand)
+}
@@ -0,0 +1,92 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+)
+
+// startPTY 分配 PTY，命令在新会话中运行并以 PTY 从设备作为控制终端，返回主设备
+func startPTY(cmd *exec.Cmd, size realtime.TerminalSize) (*os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: 分配 PTY 失败: %w", err)
+	}
+	slave, err := openSlave(master)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	defer slave.Close()
+
+	if err := resizePTY(master, size); err != nil {
+		master.Close()
+		return nil, err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("sandbox: 启动命令失败: %w", err)
+	}
+	return master, nil
+}
+
+// openSlave 解锁并打开主设备对应的从设备
+func openSlave(master *os.File) (*os.File, error) {
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		return nil, fmt.Errorf("sandbox: 解锁 PTY 失败: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		return nil, fmt.Errorf("sandbox: 读取 PTY 编号失败: %w", err)
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: 打开 PTY 从设备失败: %w", err)
+	}
+	return slave, nil
+}
+
+// resizePTY 修改终端大小，前台进程会收到 SIGWINCH
+func resizePTY(master *os.File, size realtime.TerminalSize) error {
+	ws := struct{ Row, Col, X, Y uint16 }{Row: size.Rows, Col: size.Cols}
+	return ioctl(master, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+}
+
+// setProcessGroup 命令在新的进程组中运行，Close 时可以结束它启动的所有进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup 结束命令所在的进程组（进程组 ID 即命令的 PID）
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// ioctl 通过 SyscallConn 调用，不会把文件切换为阻塞模式
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+)
+
+var errPTYUnsupported = errors.New("sandbox: 当前系统不支持 PTY 终端")
+
+func startPTY(*exec.Cmd, realtime.TerminalSize) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+func resizePTY(*os.File, realtime.TerminalSize) error {
+	return errPTYUnsupported
+}
+
+func setProcessGroup(*exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
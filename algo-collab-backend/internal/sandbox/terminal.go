@@ -0,0 +1,97 @@
+// Package sandbox 通过部署方配置的命令运行共享终端和语言服务器
+//
+// 协作服务本身不做隔离：命令负责把进程放进沙箱（例如 docker run --rm -i --network none ...），
+// 这里只负责启动命令、分配 PTY 并转发输入输出。命令按参数列表执行，不经过 shell。
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+)
+
+var _ realtime.TerminalSandbox = (*Terminal)(nil)
+
+// ErrNoCommand 没有配置命令
+var ErrNoCommand = errors.New("sandbox: 没有配置启动命令")
+
+// Terminal 在本地 PTY 中运行配置的命令作为房间的共享终端，实现 realtime.TerminalSandbox
+type Terminal struct {
+	command []string
+}
+
+// NewTerminal command 为启动 shell 的命令及参数，其中的 {room} 替换为房间 UUID
+func NewTerminal(command []string) *Terminal {
+	return &Terminal{command: command}
+}
+
+// Open 启动命令并分配 PTY，ctx 只用于启动阶段，终端的生命周期由 Close 控制
+func (t *Terminal) Open(_ context.Context, roomUUID string, size realtime.TerminalSize) (realtime.TerminalProcess, error) {
+	if len(t.command) == 0 {
+		return nil, ErrNoCommand
+	}
+	args := expandArgs(t.command, roomUUID, "")
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = commandEnv("TERM=xterm-256color")
+
+	master, err := startPTY(cmd, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ptyProcess{master: master, cmd: cmd}, nil
+}
+
+// ptyProcess PTY 中运行的命令：读写的是 PTY 主设备
+type ptyProcess struct {
+	master    *os.File
+	cmd       *exec.Cmd
+	closeOnce sync.Once
+}
+
+func (p *ptyProcess) Read(b []byte) (int, error) {
+	return p.master.Read(b)
+}
+
+func (p *ptyProcess) Write(b []byte) (int, error) {
+	return p.master.Write(b)
+}
+
+func (p *ptyProcess) Resize(size realtime.TerminalSize) error {
+	return resizePTY(p.master, size)
+}
+
+// Close 结束命令启动的整个进程组，之后 Read 返回错误
+func (p *ptyProcess) Close() error {
+	p.closeOnce.Do(func() {
+		killProcessGroup(p.cmd)
+		_ = p.master.Close()
+		_ = p.cmd.Wait()
+	})
+	return nil
+}
+
+// expandArgs 替换命令参数中的 {room} 和 {server}
+func expandArgs(command []string, roomUUID, server string) []string {
+	replacer := strings.NewReplacer("{room}", roomUUID, "{server}", server)
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+// commandEnv 命令的环境变量：只传递查找和运行命令需要的变量，数据库密码等配置不会泄露给沙箱
+func commandEnv(extra ...string) []string {
+	var env []string
+	for _, key := range []string{"PATH", "HOME", "DOCKER_HOST"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return append(env, extra...)
+}
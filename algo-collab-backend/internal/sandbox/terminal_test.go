@@ -0,0 +1,82 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/realtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// terminalOutput 在后台读取终端的全部输出
+type terminalOutput struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	done chan struct{}
+}
+
+func collectOutput(process realtime.TerminalProcess) *terminalOutput {
+	out := &terminalOutput{done: make(chan struct{})}
+	go func() {
+		defer close(out.done)
+		buf := make([]byte, 1024)
+		for {
+			n, err := process.Read(buf)
+			out.mu.Lock()
+			out.buf.Write(buf[:n])
+			out.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (o *terminalOutput) contains(want string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return strings.Contains(o.buf.String(), want)
+}
+
+func TestTerminal_RunsCommandInPTY(t *testing.T) {
+	terminal := NewTerminal([]string{"sh", "-c", "echo room={room}; stty size; read line; echo got:$line; stty size; sleep 10"})
+	process, err := terminal.Open(context.Background(), "room-1", realtime.TerminalSize{Cols: 100, Rows: 30})
+	require.NoError(t, err)
+	defer process.Close()
+	out := collectOutput(process)
+	waitOutput := func(want string) {
+		assert.Eventually(t, func() bool { return out.contains(want) }, 5*time.Second, 10*time.Millisecond, want)
+	}
+
+	// 1. 参数替换，命令运行在指定大小的终端中
+	waitOutput("room=room-1")
+	waitOutput("30 100")
+
+	// 2. 输入和调整大小
+	require.NoError(t, process.Resize(realtime.TerminalSize{Cols: 120, Rows: 40}))
+	_, err = process.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	waitOutput("got:hello")
+	waitOutput("40 120")
+
+	// 3. 关闭后进程结束，Read 返回错误
+	require.NoError(t, process.Close())
+	select {
+	case <-out.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("关闭后 Read 没有返回")
+	}
+}
+
+func TestTerminal_NoCommand(t *testing.T) {
+	_, err := NewTerminal(nil).Open(context.Background(), "room-1", realtime.TerminalSize{Cols: 80, Rows: 24})
+	assert.ErrorIs(t, err, ErrNoCommand)
+}
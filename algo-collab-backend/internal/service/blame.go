@@ -0,0 +1,160 @@
+package service
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+)
+
+// DocumentBlame 代码每一行的作者和每位作者的贡献占比
+// 只统计非空白字符，缩进和空行不计入任何人
+type DocumentBlame struct {
+	// Version 统计的历史版本，为空表示当前代码
+	Version    *models.DocumentVersion `json:"version"`
+	Content    string                  `json:"content"`
+	Lines      []BlameLine             `json:"lines"`
+	Authors    []*BlameAuthor          `json:"authors"`
+	TotalChars int                     `json:"total_chars"`
+}
+
+// BlameAuthor 一位作者写入的字符数，UserID 为空表示没有作者：
+// 初始代码、恢复的版本等由服务端写入的内容，以及开始记录作者之前的内容
+type BlameAuthor struct {
+	UserID  *uint        `json:"user_id"`
+	User    *models.User `json:"user,omitempty"`
+	Chars   int          `json:"chars"`
+	Percent float64      `json:"percent"`
+}
+
+// BlameLine 一行代码的作者，UserID 为写入字符最多的作者，Authors 按字符数从多到少排列
+type BlameLine struct {
+	Line    int               `json:"line"`
+	UserID  *uint             `json:"user_id"`
+	Authors []BlameLineAuthor `json:"authors"`
+}
+
+// BlameLineAuthor 一位作者在这一行写入的字符数
+type BlameLineAuthor struct {
+	UserID *uint `json:"user_id"`
+	Chars  int   `json:"chars"`
+}
+
+// buildBlame 按作者信息逐字符统计，authors 为 Yjs 客户端对应的用户
+// 作者信息和内容对不上时（例如开始记录作者之前保存的版本）全部视为没有作者
+func buildBlame(content string, runs models.AuthorRuns, authors []*models.DocumentAuthor) *DocumentBlame {
+	if authorRunsLength(runs) != utf8.RuneCountInString(content) {
+		runs = nil
+	}
+	owners := make(map[uint64]*models.DocumentAuthor, len(authors))
+	for _, author := range authors {
+		owners[author.ClientID] = author
+	}
+
+	blame := &DocumentBlame{Content: content}
+	totals := make(map[uint]*BlameAuthor) // 0 表示没有作者
+	var line map[uint]int
+
+	// authorAt 返回下一个字符所在段的作者，runIndex/runLeft 为当前段及其剩余字符数
+	runIndex, runLeft := -1, 0
+	authorAt := func() *models.DocumentAuthor {
+		for runLeft == 0 {
+			if runIndex+1 >= len(runs) {
+				return nil
+			}
+			runIndex++
+			runLeft = runs[runIndex].Length
+		}
+		runLeft--
+		return owners[runs[runIndex].Client]
+	}
+
+	endLine := func() {
+		blame.Lines = append(blame.Lines, newBlameLine(len(blame.Lines)+1, line))
+		line = nil
+	}
+	for _, r := range content {
+		author := authorAt()
+		if r == '\n' {
+			endLine()
+			continue
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		var userID uint
+		if author != nil {
+			userID = author.UserID
+		}
+		if line == nil {
+			line = make(map[uint]int)
+		}
+		line[userID]++
+		total := totals[userID]
+		if total == nil {
+			total = &BlameAuthor{}
+			if author != nil {
+				total.UserID, total.User = &author.UserID, author.User
+			}
+			totals[userID] = total
+		}
+		total.Chars++
+		blame.TotalChars++
+	}
+	// 和编辑器一致，最后一个换行之后还有一行
+	endLine()
+
+	for _, total := range totals {
+		total.Percent = float64(total.Chars) * 100 / float64(blame.TotalChars)
+		blame.Authors = append(blame.Authors, total)
+	}
+	sort.Slice(blame.Authors, func(i, j int) bool {
+		a, b := blame.Authors[i], blame.Authors[j]
+		if a.Chars != b.Chars {
+			return a.Chars > b.Chars
+		}
+		return blameUserID(a.UserID) < blameUserID(b.UserID)
+	})
+	return blame
+}
+
+func newBlameLine(number int, chars map[uint]int) BlameLine {
+	line := BlameLine{Line: number, Authors: make([]BlameLineAuthor, 0, len(chars))}
+	for userID, count := range chars {
+		author := BlameLineAuthor{Chars: count}
+		if userID != 0 {
+			id := userID
+			author.UserID = &id
+		}
+		line.Authors = append(line.Authors, author)
+	}
+	sort.Slice(line.Authors, func(i, j int) bool {
+		a, b := line.Authors[i], line.Authors[j]
+		if a.Chars != b.Chars {
+			return a.Chars > b.Chars
+		}
+		return blameUserID(a.UserID) < blameUserID(b.UserID)
+	})
+	if len(line.Authors) > 0 {
+		line.UserID = line.Authors[0].UserID
+	}
+	return line
+}
+
+func blameUserID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// authorRunsLength 作者信息覆盖的字符数
+func authorRunsLength(runs models.AuthorRuns) int {
+	n := 0
+	for _, run := range runs {
+		n += run.Length
+	}
+	return n
+}
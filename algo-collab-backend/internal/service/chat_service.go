@@ -0,0 +1,590 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/markdown"
+	"github.com/is-Xiaoen/algo-collab/pkg/ratelimit"
+	"github.com/is-Xiaoen/algo-collab/pkg/wordfilter"
+	"go.uber.org/zap"
+)
+
+// 聊天操作返回的错误都可以直接展示给用户
+var (
+	ErrChatMessageEmpty    = errors.New("消息不能为空")
+	ErrChatMessageTooLong  = errors.New("消息过长")
+	ErrChatSendFailed      = errors.New("消息发送失败，请稍后重试")
+	ErrChatMessageNotFound = repository.ErrRoomMessageNotFound
+	ErrChatMessageDeleted  = errors.New("消息已被删除")
+	ErrChatNotAuthor       = errors.New("只能编辑自己的消息")
+	ErrChatForbidden       = errors.New("没有权限删除该消息")
+	ErrChatInvalidSnippet  = errors.New("代码片段的行号超出范围")
+	ErrChatSnippetTooLong  = errors.New("代码片段过长")
+	ErrChatInvalidEmoji    = errors.New("不支持的表情")
+	ErrChatMuted           = errors.New("你已被禁言")
+	ErrChatRateLimited     = errors.New("发送太频繁")
+	ErrChatBlockedWord     = errors.New("消息包含敏感词，请修改后再发送")
+)
+
+const (
+	defaultChatMaxLength = 2000
+	// maxSnippetLines 单个代码片段最多引用的行数
+	maxSnippetLines = 200
+	// mentionExcerptLength 提及通知中消息摘要的长度
+	mentionExcerptLength = 100
+)
+
+// mentionPattern 消息中的 @用户名
+var mentionPattern = regexp.MustCompile(`@([\p{L}\p{N}_.-]+)`)
+
+// ChatService 房间聊天
+// 消息通过协作 WebSocket 收发（见 realtime 包），这里负责校验、清理、权限和持久化
+type ChatService interface {
+	// PostMessage 保存成员发送的消息，会检查禁言、限流和敏感词
+	PostMessage(ctx context.Context, roomID, userID uint, req *PostChatMessageRequest) (*models.RoomMessage, error)
+	// PostSystemMessage 保存系统消息
+	PostSystemMessage(ctx context.Context, roomID uint, content string) (*models.RoomMessage, error)
+	// EditMessage 编辑自己的消息
+	EditMessage(ctx context.Context, roomID, userID, messageID uint, content string) (*models.RoomMessage, error)
+	// DeleteMessage 删除自己的消息，房间管理员可以删除任何消息
+	DeleteMessage(ctx context.Context, roomID, userID, messageID uint) (*models.RoomMessage, error)
+	// ToggleReaction 添加或取消表情回应，返回该消息最新的回应汇总
+	ToggleReaction(ctx context.Context, roomID, userID, messageID uint, emoji string) ([]models.ReactionSummary, error)
+	// ListMessages 向前翻页查询聊天记录（房间成员）
+	ListMessages(ctx context.Context, uuid string, userID uint, query *ListRoomMessagesQuery) (*RoomMessagePage, error)
+}
+
+type chatService struct {
+	messageRepo      repository.RoomMessageRepository
+	roomRepo         repository.RoomRepository
+	notificationRepo repository.NotificationRepository
+	limiter          ratelimit.Limiter
+	filter           *wordfilter.Filter
+	cfg              config.ChatConfig
+	maxLength        int
+}
+
+// PostChatMessageRequest 发送消息
+type PostChatMessageRequest struct {
+	Content string
+	// ReplyToID 回复的消息，回复一条回复时归到同一个根消息下
+	ReplyToID uint
+	// Snippet 引用的代码行，只需要 Language、StartLine、EndLine，代码由服务端从 Source 截取
+	Snippet *models.CodeSnippet
+	// Source 发送时房间文档的全文，由实时协作服务填入
+	Source string
+}
+
+// ListRoomMessagesQuery 聊天记录查询参数
+// 新消息不断追加，按页码翻页会错位，所以用最早一条消息的 ID 作为游标
+type ListRoomMessagesQuery struct {
+	Before   uint `form:"before"`    // 返回 ID 小于它的消息，为空表示最新的消息
+	ThreadID uint `form:"thread_id"` // 查询某条消息下的回复，为空表示主时间线
+	Limit    int  `form:"limit"`
+}
+
+// RoomMessagePage 聊天记录，按时间正序
+type RoomMessagePage struct {
+	Messages []*models.RoomMessage `json:"messages"`
+	HasMore  bool                  `json:"has_more"`
+}
+
+// NewChatService 创建聊天服务，limiter 为空表示不限流，filter 为空表示不过滤敏感词
+func NewChatService(
+	messageRepo repository.RoomMessageRepository,
+	roomRepo repository.RoomRepository,
+	notificationRepo repository.NotificationRepository,
+	limiter ratelimit.Limiter,
+	filter *wordfilter.Filter,
+	cfg *config.ChatConfig,
+) ChatService {
+	maxLength := cfg.MaxLength
+	if maxLength <= 0 {
+		maxLength = defaultChatMaxLength
+	}
+	return &chatService{
+		messageRepo:      messageRepo,
+		roomRepo:         roomRepo,
+		notificationRepo: notificationRepo,
+		limiter:          limiter,
+		filter:           filter,
+		cfg:              *cfg,
+		maxLength:        maxLength,
+	}
+}
+
+// PostMessage 清理 Markdown 后保存，返回带发送者信息的消息，并通知被 @ 的成员
+func (s *chatService) PostMessage(ctx context.Context, roomID, userID uint, req *PostChatMessageRequest) (*models.RoomMessage, error) {
+	message := &models.RoomMessage{
+		RoomID: roomID,
+		UserID: &userID,
+		Type:   models.RoomMessageText,
+	}
+
+	// 1. 内容：代码片段可以不带说明
+	content, err := s.cleanContent(req.Content, req.Snippet != nil)
+	if err != nil {
+		return nil, err
+	}
+	message.Content = content
+
+	// 2. 代码片段
+	if req.Snippet != nil {
+		snippet, err := extractSnippet(req.Snippet, req.Source)
+		if err != nil {
+			return nil, err
+		}
+		message.Type = models.RoomMessageCode
+		message.Snippet = snippet
+	}
+
+	// 3. 禁言和限流
+	member, err := s.checkSpeaker(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRate(ctx, member); err != nil {
+		return nil, err
+	}
+
+	// 4. 回复：只挂在根消息下
+	if req.ReplyToID != 0 {
+		parent, err := s.findMessage(ctx, roomID, req.ReplyToID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.IsDeleted() {
+			return nil, ErrChatMessageDeleted
+		}
+		rootID := parent.ID
+		if parent.ReplyToID != nil {
+			rootID = *parent.ReplyToID
+		}
+		message.ReplyToID = &rootID
+	}
+
+	// 5. 保存
+	saved, err := s.create(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyMentions(ctx, saved)
+	return saved, nil
+}
+
+// PostSystemMessage 保存系统消息，内容由服务端生成，不需要清理
+func (s *chatService) PostSystemMessage(ctx context.Context, roomID uint, content string) (*models.RoomMessage, error) {
+	return s.create(ctx, &models.RoomMessage{
+		RoomID:  roomID,
+		Type:    models.RoomMessageSystem,
+		Content: content,
+	})
+}
+
+// EditMessage 只能编辑自己的消息，代码片段保持不变；编辑不会重新发送提及通知
+func (s *chatService) EditMessage(ctx context.Context, roomID, userID, messageID uint, content string) (*models.RoomMessage, error) {
+	message, err := s.findMessage(ctx, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted() {
+		return nil, ErrChatMessageDeleted
+	}
+	if message.UserID == nil || *message.UserID != userID {
+		return nil, ErrChatNotAuthor
+	}
+	// 禁言期间也不能通过编辑发言
+	if _, err := s.checkSpeaker(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	content, err = s.cleanContent(content, message.Snippet != nil)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	message.Content = content
+	message.EditedAt = &now
+	return s.update(ctx, message)
+}
+
+// DeleteMessage 清空内容并标记删除，回复和表情回应保留
+func (s *chatService) DeleteMessage(ctx context.Context, roomID, userID, messageID uint) (*models.RoomMessage, error) {
+	message, err := s.findMessage(ctx, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted() {
+		return nil, ErrChatMessageDeleted
+	}
+
+	// 作者本人，或者房间管理员及以上
+	if message.UserID == nil || *message.UserID != userID {
+		member, err := s.roomRepo.GetMember(ctx, roomID, userID)
+		if err != nil || !member.HasRole(models.RoomRoleAdmin) {
+			return nil, ErrChatForbidden
+		}
+	}
+
+	now := time.Now()
+	message.Content = ""
+	message.Snippet = nil
+	message.DeletedAt = &now
+	message.DeletedBy = &userID
+	return s.update(ctx, message)
+}
+
+// ToggleReaction 同一用户对同一条消息的同一个表情只计一次，再次回应即取消
+func (s *chatService) ToggleReaction(ctx context.Context, roomID, userID, messageID uint, emoji string) ([]models.ReactionSummary, error) {
+	if !validEmoji(emoji) {
+		return nil, ErrChatInvalidEmoji
+	}
+	message, err := s.findMessage(ctx, roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsDeleted() {
+		return nil, ErrChatMessageDeleted
+	}
+
+	_, err = s.messageRepo.ToggleReaction(ctx, &models.RoomMessageReaction{
+		RoomID:    roomID,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+	})
+	if err != nil {
+		logger.Error("保存表情回应失败", zap.Uint("message_id", messageID), zap.Error(err))
+		return nil, ErrChatSendFailed
+	}
+
+	reactions, err := s.messageRepo.ListReactions(ctx, []uint{messageID})
+	if err != nil {
+		logger.Error("读取表情回应失败", zap.Uint("message_id", messageID), zap.Error(err))
+		return nil, ErrChatSendFailed
+	}
+	return summarizeReactions(reactions)[messageID], nil
+}
+
+// ListMessages 返回 before 之前的一页消息，带回复数和表情回应汇总
+func (s *chatService) ListMessages(ctx context.Context, uuid string, userID uint, query *ListRoomMessagesQuery) (*RoomMessagePage, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+
+	limit := query.Limit
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	// 多取一条判断是否还有更早的消息
+	filter := &repository.RoomMessageFilter{BeforeID: query.Before, ThreadID: query.ThreadID}
+	messages, err := s.messageRepo.List(ctx, room.ID, filter, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if err := s.decorate(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	// 数据库按 ID 倒序取，返回给客户端时按时间正序
+	slices.Reverse(messages)
+	return &RoomMessagePage{
+		Messages: messages,
+		HasMore:  hasMore,
+	}, nil
+}
+
+// cleanContent 校验长度、清理 Markdown 并过滤敏感词，allowEmpty 为真时允许清理后为空
+func (s *chatService) cleanContent(content string, allowEmpty bool) (string, error) {
+	// 按清理前的内容计算，避免超长消息先做一遍清理
+	if utf8.RuneCountInString(content) > s.maxLength {
+		return "", ErrChatMessageTooLong
+	}
+	content = markdown.Sanitize(content)
+	if content == "" && !allowEmpty {
+		return "", ErrChatMessageEmpty
+	}
+
+	switch s.cfg.WordFilter.Mode {
+	case config.WordFilterReject:
+		if s.filter.Contains(content) {
+			return "", ErrChatBlockedWord
+		}
+	case config.WordFilterMask:
+		content = s.filter.Mask(content)
+	}
+	return content, nil
+}
+
+// checkSpeaker 校验发言人仍是房间成员且没有被禁言
+// 连接建立后成员可能被移出或禁言，所以每次发言都重新读取
+func (s *chatService) checkSpeaker(ctx context.Context, roomID, userID uint) (*models.RoomMember, error) {
+	member, err := s.roomRepo.GetMember(ctx, roomID, userID)
+	if err != nil {
+		return nil, ErrNotRoomMember
+	}
+	if member.IsMuted(time.Now()) {
+		return nil, fmt.Errorf("%w，%s 后可以发言", ErrChatMuted, member.MutedUntil.Local().Format("01-02 15:04"))
+	}
+	return member, nil
+}
+
+// checkRate 每个成员在每个房间单独限流，管理员及以上不受限制
+// Redis 不可用时放行，不因为限流影响正常聊天
+func (s *chatService) checkRate(ctx context.Context, member *models.RoomMember) error {
+	limit, window := s.cfg.RateLimitMessages, time.Duration(s.cfg.RateLimitWindowSeconds)*time.Second
+	if s.limiter == nil || limit <= 0 || window <= 0 || member.HasRole(models.RoomRoleAdmin) {
+		return nil
+	}
+
+	key := fmt.Sprintf("chat:%d:%d", member.RoomID, member.UserID)
+	ok, retryAfter, err := s.limiter.Allow(ctx, key, limit, window)
+	if err != nil {
+		logger.Warn("聊天限流检查失败", zap.Uint("room_id", member.RoomID), zap.Error(err))
+		return nil
+	}
+	if !ok {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		return fmt.Errorf("%w，请 %d 秒后再试", ErrChatRateLimited, max(seconds, 1))
+	}
+	return nil
+}
+
+// findMessage 查找房间内的消息，其他房间的消息视为不存在
+func (s *chatService) findMessage(ctx context.Context, roomID, messageID uint) (*models.RoomMessage, error) {
+	message, err := s.messageRepo.FindByID(ctx, messageID)
+	if errors.Is(err, repository.ErrRoomMessageNotFound) {
+		return nil, ErrChatMessageNotFound
+	}
+	if err != nil {
+		logger.Error("读取聊天消息失败", zap.Uint("message_id", messageID), zap.Error(err))
+		return nil, ErrChatSendFailed
+	}
+	if message.RoomID != roomID {
+		return nil, ErrChatMessageNotFound
+	}
+	return message, nil
+}
+
+// create 写库后重新读取，带上发送者的用户名和头像，数据库错误统一转换为 ErrChatSendFailed
+func (s *chatService) create(ctx context.Context, message *models.RoomMessage) (*models.RoomMessage, error) {
+	if err := s.messageRepo.Create(ctx, message); err != nil {
+		logger.Error("保存聊天消息失败", zap.Uint("room_id", message.RoomID), zap.Error(err))
+		return nil, ErrChatSendFailed
+	}
+	if message.UserID == nil {
+		return message, nil
+	}
+
+	saved, err := s.messageRepo.FindByID(ctx, message.ID)
+	if err != nil {
+		// 消息已保存，只是缺少发送者信息，客户端可以按 user_id 补全
+		logger.Warn("读取聊天消息失败", zap.Uint("message_id", message.ID), zap.Error(err))
+		return message, nil
+	}
+	return saved, nil
+}
+
+// update 保存编辑或删除，返回带回复数和表情回应的完整消息，客户端直接替换本地的那条消息
+func (s *chatService) update(ctx context.Context, message *models.RoomMessage) (*models.RoomMessage, error) {
+	if err := s.messageRepo.Update(ctx, message); err != nil {
+		logger.Error("更新聊天消息失败", zap.Uint("message_id", message.ID), zap.Error(err))
+		return nil, ErrChatSendFailed
+	}
+	if err := s.decorate(ctx, []*models.RoomMessage{message}); err != nil {
+		logger.Warn("读取消息回复和表情回应失败", zap.Uint("message_id", message.ID), zap.Error(err))
+	}
+	return message, nil
+}
+
+// decorate 填充回复数（只有根消息有回复）和表情回应汇总
+func (s *chatService) decorate(ctx context.Context, messages []*models.RoomMessage) error {
+	ids := make([]uint, 0, len(messages))
+	var roots []uint
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		if message.ReplyToID == nil {
+			roots = append(roots, message.ID)
+		}
+	}
+
+	counts, err := s.messageRepo.CountReplies(ctx, roots)
+	if err != nil {
+		return err
+	}
+	reactions, err := s.messageRepo.ListReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	summaries := summarizeReactions(reactions)
+	for _, message := range messages {
+		message.ReplyCount = counts[message.ID]
+		message.Reactions = summaries[message.ID]
+	}
+	return nil
+}
+
+// notifyMentions 给消息中 @ 到的房间成员发送通知，不通知自己
+// 通知是旁路数据，失败只记录日志，不影响消息发送
+func (s *chatService) notifyMentions(ctx context.Context, message *models.RoomMessage) {
+	if s.notificationRepo == nil || message.UserID == nil {
+		return
+	}
+	usernames := parseMentions(message.Content)
+	if len(usernames) == 0 {
+		return
+	}
+
+	members, err := s.roomRepo.GetMembers(ctx, message.RoomID)
+	if err != nil {
+		logger.Warn("读取房间成员失败，跳过提及通知", zap.Uint("room_id", message.RoomID), zap.Error(err))
+		return
+	}
+
+	data := models.JSONMap{"excerpt": excerpt(message.Content, mentionExcerptLength)}
+	if room, err := s.roomRepo.FindByID(ctx, message.RoomID); err == nil {
+		data["room_uuid"] = room.UUID
+		data["room_name"] = room.Name
+	}
+
+	var notifications []*models.Notification
+	for _, member := range members {
+		if member.UserID == *message.UserID || !slices.Contains(usernames, member.Users.Username) {
+			continue
+		}
+		notifications = append(notifications, &models.Notification{
+			UserID:    member.UserID,
+			ActorID:   message.UserID,
+			Type:      models.NotificationMention,
+			RoomID:    &message.RoomID,
+			MessageID: &message.ID,
+			Data:      data,
+		})
+	}
+	if err := s.notificationRepo.CreateBatch(ctx, notifications); err != nil {
+		logger.Warn("写入提及通知失败", zap.Uint("message_id", message.ID), zap.Error(err))
+	}
+}
+
+// parseMentions 提取 @ 到的用户名（去重），代码中的 @（例如 Java 注解）不算
+func parseMentions(content string) []string {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(stripCode(content), -1) {
+		// 句末的标点不属于用户名
+		name := strings.TrimRight(match[1], ".-")
+		if name != "" && !slices.Contains(usernames, name) {
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames
+}
+
+// stripCode 去掉代码块和行内代码
+func stripCode(content string) string {
+	var b strings.Builder
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		parts := strings.Split(line, "`")
+		for i := 0; i < len(parts); i += 2 {
+			b.WriteString(parts[i])
+			b.WriteByte(' ')
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// extractSnippet 按行号从文档中截取代码片段
+func extractSnippet(req *models.CodeSnippet, source string) (*models.CodeSnippet, error) {
+	lines := strings.Split(source, "\n")
+	if req.StartLine < 1 || req.EndLine < req.StartLine || req.EndLine > len(lines) {
+		return nil, ErrChatInvalidSnippet
+	}
+	if req.EndLine-req.StartLine+1 > maxSnippetLines {
+		return nil, fmt.Errorf("%w：最多引用 %d 行", ErrChatSnippetTooLong, maxSnippetLines)
+	}
+
+	language := strings.TrimSpace(req.Language)
+	if utf8.RuneCountInString(language) > 20 {
+		language = ""
+	}
+	return &models.CodeSnippet{
+		Language:  language,
+		StartLine: req.StartLine,
+		EndLine:   req.EndLine,
+		Code:      strings.Join(lines[req.StartLine-1:req.EndLine], "\n"),
+	}, nil
+}
+
+// summarizeReactions 按消息和表情聚合，表情按第一次被回应的时间排序
+func summarizeReactions(reactions []*models.RoomMessageReaction) map[uint][]models.ReactionSummary {
+	summaries := make(map[uint][]models.ReactionSummary)
+	for _, reaction := range reactions {
+		list := summaries[reaction.MessageID]
+		i := slices.IndexFunc(list, func(s models.ReactionSummary) bool { return s.Emoji == reaction.Emoji })
+		if i < 0 {
+			list = append(list, models.ReactionSummary{Emoji: reaction.Emoji})
+			i = len(list) - 1
+		}
+		list[i].Count++
+		list[i].UserIDs = append(list[i].UserIDs, reaction.UserID)
+		summaries[reaction.MessageID] = list
+	}
+	return summaries
+}
+
+// validEmoji 只接受 emoji：不能包含文字、空白和控制字符，ASCII 只允许键帽 emoji 用到的数字、# 和 *
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > 32 || !utf8.ValidString(emoji) {
+		return false
+	}
+	for _, r := range emoji {
+		if r < utf8.RuneSelf && !unicode.IsDigit(r) && r != '#' && r != '*' {
+			return false
+		}
+		if unicode.IsLetter(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// excerpt 截取前 n 个字符
+func excerpt(content string, n int) string {
+	if utf8.RuneCountInString(content) <= n {
+		return content
+	}
+	return string([]rune(content)[:n]) + "…"
+}
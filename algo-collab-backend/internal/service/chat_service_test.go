@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/wordfilter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRoomMessageRepository 模拟聊天消息仓库
+type MockRoomMessageRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoomMessageRepository) Create(ctx context.Context, message *models.RoomMessage) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockRoomMessageRepository) FindByID(ctx context.Context, id uint) (*models.RoomMessage, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMessage), args.Error(1)
+}
+
+func (m *MockRoomMessageRepository) Update(ctx context.Context, message *models.RoomMessage) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockRoomMessageRepository) List(ctx context.Context, roomID uint, filter *repository.RoomMessageFilter, limit int) ([]*models.RoomMessage, error) {
+	args := m.Called(ctx, roomID, filter, limit)
+	return args.Get(0).([]*models.RoomMessage), args.Error(1)
+}
+
+func (m *MockRoomMessageRepository) CountReplies(ctx context.Context, messageIDs []uint) (map[uint]int, error) {
+	args := m.Called(ctx, messageIDs)
+	return args.Get(0).(map[uint]int), args.Error(1)
+}
+
+func (m *MockRoomMessageRepository) ToggleReaction(ctx context.Context, reaction *models.RoomMessageReaction) (bool, error) {
+	args := m.Called(ctx, reaction)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomMessageRepository) ListReactions(ctx context.Context, messageIDs []uint) ([]*models.RoomMessageReaction, error) {
+	args := m.Called(ctx, messageIDs)
+	return args.Get(0).([]*models.RoomMessageReaction), args.Error(1)
+}
+
+// MockNotificationRepository 模拟通知仓库
+type MockNotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationRepository) CreateBatch(ctx context.Context, notifications []*models.Notification) error {
+	args := m.Called(ctx, notifications)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepository) List(ctx context.Context, userID uint, unreadOnly bool, limit, offset int) ([]*models.Notification, int64, error) {
+	args := m.Called(ctx, userID, unreadOnly, limit, offset)
+	return args.Get(0).([]*models.Notification), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockNotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkRead(ctx context.Context, userID uint, ids []uint) error {
+	args := m.Called(ctx, userID, ids)
+	return args.Error(0)
+}
+
+// newSpeakerRoomRepo 发言人是房间的普通成员
+func newSpeakerRoomRepo() *MockRoomRepository {
+	roomRepo := new(MockRoomRepository)
+	roomRepo.On("GetMember", mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.RoomMember{Role: models.RoomRoleMember}, nil).Maybe()
+	return roomRepo
+}
+
+// fakeLimiter 每个 key 最多允许 limit 次
+type fakeLimiter struct {
+	counts map[string]int
+}
+
+func (l *fakeLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	l.counts[key]++
+	if l.counts[key] > limit {
+		return false, window, nil
+	}
+	return true, 0, nil
+}
+
+func TestChatService_PostMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		createErr   error
+		wantContent string
+		wantErr     error
+	}{
+		{name: "清理 HTML", content: "**O(n)** <script>x</script>", wantContent: "**O(n)** x"},
+		{name: "清理后为空", content: "<b></b>  ", wantErr: ErrChatMessageEmpty},
+		{name: "超出长度", content: strings.Repeat("长", 31), wantErr: ErrChatMessageTooLong},
+		{name: "数据库错误不暴露细节", content: "hi", createErr: errors.New("connection refused"), wantErr: ErrChatSendFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageRepo := new(MockRoomMessageRepository)
+			messageRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.RoomMessage")).
+				Run(func(args mock.Arguments) { args.Get(1).(*models.RoomMessage).ID = 9 }).
+				Return(tt.createErr).Maybe()
+			messageRepo.On("FindByID", mock.Anything, uint(9)).Return(nil, errors.New("not found")).Maybe()
+			svc := NewChatService(messageRepo, newSpeakerRoomRepo(), nil, nil, nil, &config.ChatConfig{MaxLength: 30})
+
+			message, err := svc.PostMessage(context.Background(), 1, 7, &PostChatMessageRequest{Content: tt.content})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, message.Content)
+			assert.Equal(t, models.RoomMessageText, message.Type)
+			assert.Equal(t, uint(7), *message.UserID)
+		})
+	}
+}
+
+func TestChatService_ListMessages(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1"}
+
+	t.Run("非成员不能查看", func(t *testing.T) {
+		roomRepo := new(MockRoomRepository)
+		roomRepo.On("FindByUUID", mock.Anything, "room-1").Return(room, nil)
+		roomRepo.On("IsMember", mock.Anything, uint(1), uint(7)).Return(false, nil)
+		svc := NewChatService(new(MockRoomMessageRepository), roomRepo, nil, nil, nil, &config.ChatConfig{})
+
+		_, err := svc.ListMessages(context.Background(), "room-1", 7, &ListRoomMessagesQuery{})
+		assert.ErrorIs(t, err, ErrNotRoomMember)
+	})
+
+	t.Run("按时间正序返回并判断是否还有更早的消息", func(t *testing.T) {
+		roomRepo := new(MockRoomRepository)
+		roomRepo.On("FindByUUID", mock.Anything, "room-1").Return(room, nil)
+		roomRepo.On("IsMember", mock.Anything, uint(1), uint(7)).Return(true, nil)
+		messageRepo := new(MockRoomMessageRepository)
+		messageRepo.On("List", mock.Anything, uint(1), &repository.RoomMessageFilter{BeforeID: 10}, 3).Return([]*models.RoomMessage{
+			{ID: 9}, {ID: 8}, {ID: 7},
+		}, nil)
+		messageRepo.On("CountReplies", mock.Anything, []uint{9, 8}).Return(map[uint]int{8: 2}, nil)
+		messageRepo.On("ListReactions", mock.Anything, []uint{9, 8}).Return([]*models.RoomMessageReaction{
+			{MessageID: 9, UserID: 1, Emoji: "👍"},
+			{MessageID: 9, UserID: 2, Emoji: "🎉"},
+			{MessageID: 9, UserID: 3, Emoji: "👍"},
+		}, nil)
+		svc := NewChatService(messageRepo, roomRepo, nil, nil, nil, &config.ChatConfig{})
+
+		page, err := svc.ListMessages(context.Background(), "room-1", 7, &ListRoomMessagesQuery{Before: 10, Limit: 2})
+		require.NoError(t, err)
+		assert.True(t, page.HasMore)
+		require.Len(t, page.Messages, 2)
+		assert.Equal(t, uint(8), page.Messages[0].ID)
+		assert.Equal(t, 2, page.Messages[0].ReplyCount)
+		assert.Equal(t, uint(9), page.Messages[1].ID)
+		assert.Equal(t, []models.ReactionSummary{
+			{Emoji: "👍", Count: 2, UserIDs: []uint{1, 3}},
+			{Emoji: "🎉", Count: 1, UserIDs: []uint{2}},
+		}, page.Messages[1].Reactions)
+	})
+}
+
+func TestChatService_PostMessageWithSnippetAndReply(t *testing.T) {
+	source := "func main() {\n\tfmt.Println(1)\n}"
+	replyTo := uint(3)
+
+	tests := []struct {
+		name        string
+		req         *PostChatMessageRequest
+		wantSnippet *models.CodeSnippet
+		wantReplyTo *uint
+		wantErr     error
+	}{
+		{
+			name:        "截取代码片段，可以不带说明",
+			req:         &PostChatMessageRequest{Snippet: &models.CodeSnippet{Language: "go", StartLine: 2, EndLine: 3, Code: "伪造的代码"}, Source: source},
+			wantSnippet: &models.CodeSnippet{Language: "go", StartLine: 2, EndLine: 3, Code: "\tfmt.Println(1)\n}"},
+		},
+		{
+			name:    "行号超出范围",
+			req:     &PostChatMessageRequest{Snippet: &models.CodeSnippet{StartLine: 2, EndLine: 4}, Source: source},
+			wantErr: ErrChatInvalidSnippet,
+		},
+		{
+			name:    "起止行颠倒",
+			req:     &PostChatMessageRequest{Snippet: &models.CodeSnippet{StartLine: 3, EndLine: 2}, Source: source},
+			wantErr: ErrChatInvalidSnippet,
+		},
+		{
+			name:        "回复一条回复时挂到根消息下",
+			req:         &PostChatMessageRequest{Content: "同意", ReplyToID: 5},
+			wantReplyTo: &replyTo,
+		},
+		{
+			name:    "不能回复其他房间的消息",
+			req:     &PostChatMessageRequest{Content: "同意", ReplyToID: 6},
+			wantErr: ErrChatMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageRepo := new(MockRoomMessageRepository)
+			messageRepo.On("FindByID", mock.Anything, uint(5)).Return(&models.RoomMessage{ID: 5, RoomID: 1, ReplyToID: &replyTo}, nil).Maybe()
+			messageRepo.On("FindByID", mock.Anything, uint(6)).Return(&models.RoomMessage{ID: 6, RoomID: 2}, nil).Maybe()
+			messageRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.RoomMessage")).Return(nil).Maybe()
+			messageRepo.On("FindByID", mock.Anything, uint(0)).Return(nil, errors.New("not found")).Maybe()
+			svc := NewChatService(messageRepo, newSpeakerRoomRepo(), nil, nil, nil, &config.ChatConfig{})
+
+			message, err := svc.PostMessage(context.Background(), 1, 7, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSnippet, message.Snippet)
+			assert.Equal(t, tt.wantReplyTo, message.ReplyToID)
+			if tt.wantSnippet != nil {
+				assert.Equal(t, models.RoomMessageCode, message.Type)
+			}
+		})
+	}
+}
+
+func TestChatService_PostMessageNotifiesMentions(t *testing.T) {
+	members := []*models.RoomMember{
+		{UserID: 7, Users: models.User{Username: "alice"}},
+		{UserID: 8, Users: models.User{Username: "bob"}},
+		{UserID: 9, Users: models.User{Username: "carol"}},
+	}
+	roomRepo := newSpeakerRoomRepo()
+	roomRepo.On("GetMembers", mock.Anything, uint(1)).Return(members, nil)
+	roomRepo.On("FindByID", mock.Anything, uint(1)).Return(&models.Room{UUID: "room-1", Name: "二分"}, nil)
+	messageRepo := new(MockRoomMessageRepository)
+	messageRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.RoomMessage")).
+		Run(func(args mock.Arguments) { args.Get(1).(*models.RoomMessage).ID = 9 }).
+		Return(nil)
+	messageRepo.On("FindByID", mock.Anything, uint(9)).Return(nil, errors.New("not found"))
+	notificationRepo := new(MockNotificationRepository)
+	notificationRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(notifications []*models.Notification) bool {
+		return len(notifications) == 1 &&
+			notifications[0].UserID == 8 &&
+			notifications[0].Type == models.NotificationMention &&
+			*notifications[0].MessageID == 9 &&
+			notifications[0].Data["room_uuid"] == "room-1"
+	})).Return(nil)
+	svc := NewChatService(messageRepo, roomRepo, notificationRepo, nil, nil, &config.ChatConfig{})
+
+	// 自己、不存在的用户和代码中的 @ 都不通知
+	_, err := svc.PostMessage(context.Background(), 1, 7, &PostChatMessageRequest{
+		Content: "@bob @alice @nobody 看下 `@carol` 这里",
+	})
+	require.NoError(t, err)
+	notificationRepo.AssertExpectations(t)
+}
+
+func TestChatService_EditAndDelete(t *testing.T) {
+	author := uint(7)
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name    string
+		action  string
+		userID  uint
+		message *models.RoomMessage
+		role    string
+		wantErr error
+	}{
+		{name: "作者编辑", action: "edit", userID: 7, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}},
+		{name: "不能编辑别人的消息", action: "edit", userID: 8, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}, wantErr: ErrChatNotAuthor},
+		{name: "不能编辑系统消息", action: "edit", userID: 8, message: &models.RoomMessage{ID: 5, RoomID: 1, Type: models.RoomMessageSystem}, wantErr: ErrChatNotAuthor},
+		{name: "不能编辑已删除的消息", action: "edit", userID: 7, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author, DeletedAt: &deletedAt}, wantErr: ErrChatMessageDeleted},
+		{name: "作者删除", action: "delete", userID: 7, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}},
+		{name: "管理员删除别人的消息", action: "delete", userID: 8, role: models.RoomRoleAdmin, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}},
+		{name: "普通成员不能删除别人的消息", action: "delete", userID: 8, role: models.RoomRoleMember, message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}, wantErr: ErrChatForbidden},
+		{name: "其他房间的消息", action: "delete", userID: 7, message: &models.RoomMessage{ID: 5, RoomID: 2, UserID: &author}, wantErr: ErrChatMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageRepo := new(MockRoomMessageRepository)
+			messageRepo.On("FindByID", mock.Anything, uint(5)).Return(tt.message, nil)
+			messageRepo.On("Update", mock.Anything, tt.message).Return(nil).Maybe()
+			messageRepo.On("CountReplies", mock.Anything, mock.Anything).Return(map[uint]int{}, nil).Maybe()
+			messageRepo.On("ListReactions", mock.Anything, mock.Anything).Return([]*models.RoomMessageReaction{}, nil).Maybe()
+			roomRepo := new(MockRoomRepository)
+			roomRepo.On("GetMember", mock.Anything, uint(1), tt.userID).Return(&models.RoomMember{Role: tt.role}, nil).Maybe()
+			svc := NewChatService(messageRepo, roomRepo, nil, nil, nil, &config.ChatConfig{})
+
+			var message *models.RoomMessage
+			var err error
+			if tt.action == "edit" {
+				message, err = svc.EditMessage(context.Background(), 1, tt.userID, 5, "改过的 <b>内容</b>")
+			} else {
+				message, err = svc.DeleteMessage(context.Background(), 1, tt.userID, 5)
+			}
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				messageRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			if tt.action == "edit" {
+				assert.Equal(t, "改过的 内容", message.Content)
+				assert.NotNil(t, message.EditedAt)
+			} else {
+				assert.Empty(t, message.Content)
+				assert.True(t, message.IsDeleted())
+				assert.Equal(t, tt.userID, *message.DeletedBy)
+			}
+		})
+	}
+}
+
+func TestChatService_Moderation(t *testing.T) {
+	mutedUntil := time.Now().Add(time.Hour)
+	filter := wordfilter.New([]string{"刷单", "spam"})
+	rateLimit := config.ChatConfig{RateLimitMessages: 2, RateLimitWindowSeconds: 10}
+
+	tests := []struct {
+		name        string
+		member      *models.RoomMember
+		cfg         config.ChatConfig
+		sends       int // 在同一个限流窗口内发送的次数，检查最后一次的结果
+		content     string
+		wantContent string
+		wantErr     error
+	}{
+		{name: "禁言中不能发言", member: &models.RoomMember{Role: models.RoomRoleMember, MutedUntil: &mutedUntil}, content: "hi", wantErr: ErrChatMuted},
+		{name: "禁言已过期", member: &models.RoomMember{Role: models.RoomRoleMember, MutedUntil: &time.Time{}}, content: "hi", wantContent: "hi"},
+		{name: "不再是成员", content: "hi", wantErr: ErrNotRoomMember},
+		{name: "窗口内未超出", member: &models.RoomMember{Role: models.RoomRoleMember}, cfg: rateLimit, sends: 2, content: "hi", wantContent: "hi"},
+		{name: "超出限流", member: &models.RoomMember{Role: models.RoomRoleMember}, cfg: rateLimit, sends: 3, content: "hi", wantErr: ErrChatRateLimited},
+		{name: "管理员不限流", member: &models.RoomMember{Role: models.RoomRoleAdmin}, cfg: rateLimit, sends: 3, content: "hi", wantContent: "hi"},
+		{
+			name:        "敏感词替换为星号",
+			member:      &models.RoomMember{Role: models.RoomRoleMember},
+			cfg:         config.ChatConfig{WordFilter: config.WordFilterConfig{Mode: config.WordFilterMask}},
+			content:     "接刷单，不是 spam",
+			wantContent: "接**，不是 ****",
+		},
+		{
+			name:    "包含敏感词时拒绝",
+			member:  &models.RoomMember{Role: models.RoomRoleMember},
+			cfg:     config.ChatConfig{WordFilter: config.WordFilterConfig{Mode: config.WordFilterReject}},
+			content: "接刷单",
+			wantErr: ErrChatBlockedWord,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomRepo := new(MockRoomRepository)
+			if tt.member != nil {
+				roomRepo.On("GetMember", mock.Anything, uint(1), uint(7)).Return(tt.member, nil)
+			} else {
+				roomRepo.On("GetMember", mock.Anything, uint(1), uint(7)).Return(nil, errors.New("not found"))
+			}
+			messageRepo := new(MockRoomMessageRepository)
+			messageRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.RoomMessage")).Return(nil).Maybe()
+			messageRepo.On("FindByID", mock.Anything, uint(0)).Return(nil, errors.New("not found")).Maybe()
+			limiter := &fakeLimiter{counts: make(map[string]int)}
+			svc := NewChatService(messageRepo, roomRepo, nil, limiter, filter, &tt.cfg)
+
+			var message *models.RoomMessage
+			var err error
+			for i := 0; i < max(tt.sends, 1); i++ {
+				message, err = svc.PostMessage(context.Background(), 1, 7, &PostChatMessageRequest{Content: tt.content})
+			}
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, message.Content)
+		})
+	}
+}
+
+func TestValidEmoji(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  bool
+	}{
+		{"👍", true},
+		{"👨‍💻", true},
+		{"1️⃣", true},
+		{"", false},
+		{"ok", false},
+		{"好", false},
+		{"👍 ", false},
+		{strings.Repeat("👍", 9), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.emoji, func(t *testing.T) {
+			assert.Equal(t, tt.want, validEmoji(tt.emoji))
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/markdown"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCommentThreadNotFound 评论不存在
+	ErrCommentThreadNotFound = repository.ErrCommentThreadNotFound
+	ErrCommentEmpty          = errors.New("评论不能为空")
+	ErrCommentTooLong        = errors.New("评论过长")
+	ErrCommentAnchor         = errors.New("评论的代码位置无效")
+)
+
+const (
+	maxCommentLength = 2000
+	// maxCommentQuoteLength 评论引用的代码最多保存的字符数
+	maxCommentQuoteLength = 2000
+	// commentExcerptLength 房间事件中评论摘要的长度
+	commentExcerptLength = 100
+)
+
+// CommentEditor 解析评论锚点并推送评论变化，由 realtime.Hub 实现
+type CommentEditor interface {
+	// ResolveAnchors 把 Y.Text name 中的相对位置解析为当前下标，无法解析的为 -1
+	ResolveAnchors(ctx context.Context, room *models.Room, name string, anchors [][]byte) ([]int, error)
+	// BroadcastComment 把评论的最新状态推送给所有在线成员
+	BroadcastComment(ctx context.Context, room *models.Room, thread *models.RoomCommentThread) error
+}
+
+// CommentService 代码评论：成员选中文件中的一段代码发起评论，其他成员回复，讨论完后标记为已解决
+// 评论和回复保存在数据库，锚点随协作文档一起变化，每次修改都推送给在线成员并写入房间事件
+type CommentService interface {
+	ListThreads(ctx context.Context, uuid string, userID uint, query *ListCommentThreadsQuery) ([]*models.RoomCommentThread, error)
+	CreateThread(ctx context.Context, uuid string, userID uint, req *CreateCommentThreadRequest) (*models.RoomCommentThread, error)
+	Reply(ctx context.Context, uuid string, userID, threadID uint, req *ReplyCommentRequest) (*models.RoomCommentThread, error)
+	// SetResolved 标记评论已解决或重新打开
+	SetResolved(ctx context.Context, uuid string, userID, threadID uint, resolved bool) (*models.RoomCommentThread, error)
+}
+
+type commentService struct {
+	roomRepo    repository.RoomRepository
+	fileRepo    repository.RoomFileRepository
+	commentRepo repository.RoomCommentRepository
+	eventRepo   repository.RoomEventRepository
+	editor      CommentEditor
+}
+
+// ListCommentThreadsQuery 评论列表筛选：FileID 为 0 时返回所有文件；Status 为 open、resolved 或空（全部）
+type ListCommentThreadsQuery struct {
+	FileID uint   `form:"file_id"`
+	Status string `form:"status" binding:"omitempty,oneof=open resolved"`
+}
+
+// CreateCommentThreadRequest 发起评论
+// AnchorStart/AnchorEnd 是选区两端在文件 Y.Text 中的相对位置（Y.encodeRelativePosition，JSON 中为 base64）
+type CreateCommentThreadRequest struct {
+	FileID      uint   `json:"file_id" binding:"required"`
+	AnchorStart []byte `json:"anchor_start" binding:"required"`
+	AnchorEnd   []byte `json:"anchor_end" binding:"required"`
+	Quote       string `json:"quote"`
+	Content     string `json:"content" binding:"required"`
+}
+
+// ReplyCommentRequest 回复评论
+type ReplyCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+func NewCommentService(
+	roomRepo repository.RoomRepository,
+	fileRepo repository.RoomFileRepository,
+	commentRepo repository.RoomCommentRepository,
+	eventRepo repository.RoomEventRepository,
+	editor CommentEditor,
+) CommentService {
+	return &commentService{
+		roomRepo:    roomRepo,
+		fileRepo:    fileRepo,
+		commentRepo: commentRepo,
+		eventRepo:   eventRepo,
+		editor:      editor,
+	}
+}
+
+// ListThreads 返回的评论带有锚点的当前位置，已删除文件上的评论不再返回
+func (s *commentService) ListThreads(ctx context.Context, uuid string, userID uint, query *ListCommentThreadsQuery) ([]*models.RoomCommentThread, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.CommentThreadFilter{FileID: query.FileID}
+	if query.Status != "" {
+		resolved := query.Status == "resolved"
+		filter.Resolved = &resolved
+	}
+	threads, err := s.commentRepo.ListThreads(ctx, room.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.fileRepo.ListByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	textNames := make(map[uint]string, len(files))
+	for _, file := range files {
+		textNames[file.ID] = file.TextName
+	}
+	visible := threads[:0]
+	for _, thread := range threads {
+		if _, ok := textNames[thread.FileID]; ok {
+			visible = append(visible, thread)
+		}
+	}
+	if err := s.resolveRanges(ctx, room, visible, textNames); err != nil {
+		return nil, err
+	}
+	return visible, nil
+}
+
+// CreateThread 锚点必须是文件中的有效位置；发起评论的成员刚输入的内容可能还没有同步到服务端，
+// 这时锚点暂时无法解析，仍然保存评论，位置在之后查询时再解析
+func (s *commentService) CreateThread(ctx context.Context, uuid string, userID uint, req *CreateCommentThreadRequest) (*models.RoomCommentThread, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.fileRepo.FindByID(ctx, req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.RoomID != room.ID {
+		return nil, ErrRoomFileNotFound
+	}
+	for _, anchor := range [][]byte{req.AnchorStart, req.AnchorEnd} {
+		if _, err := yjs.DecodeRelativePosition(anchor); err != nil {
+			return nil, ErrCommentAnchor
+		}
+	}
+	content, err := cleanComment(req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &models.RoomCommentThread{
+		RoomID:      room.ID,
+		FileID:      file.ID,
+		AnchorStart: req.AnchorStart,
+		AnchorEnd:   req.AnchorEnd,
+		Quote:       excerpt(req.Quote, maxCommentQuoteLength),
+		CreatedBy:   userID,
+	}
+	first := &models.RoomComment{UserID: userID, Content: content}
+	if err := s.commentRepo.CreateThread(ctx, thread, first); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventCommentCreate, models.JSONMap{
+		"thread_id": thread.ID,
+		"file_id":   file.ID,
+		"path":      file.Path,
+		"excerpt":   excerpt(content, commentExcerptLength),
+	})
+	return s.reloadAndBroadcast(ctx, room, thread.ID)
+}
+
+func (s *commentService) Reply(ctx context.Context, uuid string, userID, threadID uint, req *ReplyCommentRequest) (*models.RoomCommentThread, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.findThread(ctx, room, threadID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := cleanComment(req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.RoomComment{RoomID: room.ID, ThreadID: thread.ID, UserID: userID, Content: content}
+	if err := s.commentRepo.AddComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventCommentReply, models.JSONMap{
+		"thread_id":  thread.ID,
+		"comment_id": comment.ID,
+		"excerpt":    excerpt(content, commentExcerptLength),
+	})
+	return s.reloadAndBroadcast(ctx, room, thread.ID)
+}
+
+// SetResolved 任何成员都可以解决或重新打开评论，状态没有变化时直接返回
+func (s *commentService) SetResolved(ctx context.Context, uuid string, userID, threadID uint, resolved bool) (*models.RoomCommentThread, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.findThread(ctx, room, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread.Resolved == resolved {
+		if err := s.resolveRange(ctx, room, thread); err != nil {
+			return nil, err
+		}
+		return thread, nil
+	}
+
+	var resolvedBy *uint
+	eventType := models.RoomEventCommentReopen
+	if resolved {
+		resolvedBy, eventType = &userID, models.RoomEventCommentResolve
+	}
+	if err := s.commentRepo.SetResolved(ctx, thread.ID, resolvedBy); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, eventType, models.JSONMap{
+		"thread_id": thread.ID,
+	})
+	return s.reloadAndBroadcast(ctx, room, thread.ID)
+}
+
+// reloadAndBroadcast 重新加载评论并推送给在线成员
+// 推送失败时在线成员刷新后仍能看到最新的评论，不影响本次修改
+func (s *commentService) reloadAndBroadcast(ctx context.Context, room *models.Room, threadID uint) (*models.RoomCommentThread, error) {
+	thread, err := s.commentRepo.FindThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resolveRange(ctx, room, thread); err != nil {
+		return nil, err
+	}
+	if err := s.editor.BroadcastComment(ctx, room, thread); err != nil {
+		logger.Warn("推送评论失败",
+			zap.String("room_uuid", room.UUID),
+			zap.Uint("thread_id", thread.ID),
+			zap.Error(err))
+	}
+	return thread, nil
+}
+
+func (s *commentService) resolveRange(ctx context.Context, room *models.Room, thread *models.RoomCommentThread) error {
+	file, err := s.fileRepo.FindByID(ctx, thread.FileID)
+	if errors.Is(err, repository.ErrRoomFileNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.resolveRanges(ctx, room, []*models.RoomCommentThread{thread}, map[uint]string{file.ID: file.TextName})
+}
+
+// resolveRanges 按文件批量解析锚点，textNames 为文件 ID 到 Y.Text 名称的映射
+func (s *commentService) resolveRanges(ctx context.Context, room *models.Room, threads []*models.RoomCommentThread, textNames map[uint]string) error {
+	byText := make(map[string][]*models.RoomCommentThread)
+	for _, thread := range threads {
+		name := textNames[thread.FileID]
+		byText[name] = append(byText[name], thread)
+	}
+
+	for name, group := range byText {
+		anchors := make([][]byte, 0, 2*len(group))
+		for _, thread := range group {
+			anchors = append(anchors, thread.AnchorStart, thread.AnchorEnd)
+		}
+		positions, err := s.editor.ResolveAnchors(ctx, room, name, anchors)
+		if err != nil {
+			return err
+		}
+		for i, thread := range group {
+			start, end := positions[2*i], positions[2*i+1]
+			if start < 0 || end < 0 {
+				continue
+			}
+			// 选区中的代码全部被删除后，两端可能交错
+			if end < start {
+				end = start
+			}
+			thread.Range = &models.CommentRange{Start: start, End: end}
+		}
+	}
+	return nil
+}
+
+// findThread 评论必须属于该房间
+func (s *commentService) findThread(ctx context.Context, room *models.Room, threadID uint) (*models.RoomCommentThread, error) {
+	thread, err := s.commentRepo.FindThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread.RoomID != room.ID {
+		return nil, ErrCommentThreadNotFound
+	}
+	return thread, nil
+}
+
+func (s *commentService) findRoomAsMember(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return room, nil
+}
+
+// findWritableRoom 成员都可以评论，归档房间只读
+func (s *commentService) findWritableRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
+
+// cleanComment 评论内容和聊天一样支持 Markdown，保存前清理
+func cleanComment(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", ErrCommentEmpty
+	}
+	if utf8.RuneCountInString(content) > maxCommentLength {
+		return "", ErrCommentTooLong
+	}
+	return markdown.Sanitize(content), nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCommentRepo 内存中的评论
+type memoryCommentRepo struct {
+	threads  map[uint]*models.RoomCommentThread
+	comments []*models.RoomComment
+}
+
+func (r *memoryCommentRepo) CreateThread(_ context.Context, thread *models.RoomCommentThread, first *models.RoomComment) error {
+	thread.ID = uint(len(r.threads) + 1)
+	copied := *thread
+	r.threads[thread.ID] = &copied
+	first.RoomID, first.ThreadID = thread.RoomID, thread.ID
+	r.comments = append(r.comments, first)
+	return nil
+}
+
+func (r *memoryCommentRepo) AddComment(_ context.Context, comment *models.RoomComment) error {
+	comment.ID = uint(len(r.comments) + 1)
+	r.comments = append(r.comments, comment)
+	return nil
+}
+
+func (r *memoryCommentRepo) FindThread(_ context.Context, id uint) (*models.RoomCommentThread, error) {
+	thread, ok := r.threads[id]
+	if !ok {
+		return nil, repository.ErrCommentThreadNotFound
+	}
+	copied := *thread
+	for _, comment := range r.comments {
+		if comment.ThreadID == id {
+			copied.Comments = append(copied.Comments, comment)
+		}
+	}
+	return &copied, nil
+}
+
+func (r *memoryCommentRepo) ListThreads(ctx context.Context, roomID uint, filter repository.CommentThreadFilter) ([]*models.RoomCommentThread, error) {
+	var threads []*models.RoomCommentThread
+	for id := uint(1); id <= uint(len(r.threads)); id++ {
+		thread, _ := r.FindThread(ctx, id)
+		if thread.RoomID != roomID ||
+			(filter.FileID != 0 && thread.FileID != filter.FileID) ||
+			(filter.Resolved != nil && thread.Resolved != *filter.Resolved) {
+			continue
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func (r *memoryCommentRepo) SetResolved(_ context.Context, id uint, resolvedBy *uint) error {
+	r.threads[id].Resolved, r.threads[id].ResolvedBy = resolvedBy != nil, resolvedBy
+	return nil
+}
+
+// fakeCommentEditor 把 relativeItem 的时钟作为当前位置，0xff 表示无法解析
+type fakeCommentEditor struct {
+	broadcasts []*models.RoomCommentThread
+}
+
+func (e *fakeCommentEditor) ResolveAnchors(_ context.Context, _ *models.Room, _ string, anchors [][]byte) ([]int, error) {
+	positions := make([]int, len(anchors))
+	for i, anchor := range anchors {
+		positions[i] = int(anchor[2])
+		if anchor[2] == 0xff {
+			positions[i] = -1
+		}
+	}
+	return positions, nil
+}
+
+func (e *fakeCommentEditor) BroadcastComment(_ context.Context, _ *models.Room, thread *models.RoomCommentThread) error {
+	e.broadcasts = append(e.broadcasts, thread)
+	return nil
+}
+
+// relativeItem 指向客户端 1 第 clock 个字符的相对位置编码
+func relativeItem(clock byte) []byte {
+	return []byte{0, 1, clock, 0}
+}
+
+func TestCommentService_ThreadLifecycle(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Language: "python", Status: models.RoomStatusActive}
+	files := newMemoryFileRepo()
+	main := &models.RoomFile{RoomID: room.ID, Path: "main.py", TextName: models.MainTextName, IsMain: true}
+	helper := &models.RoomFile{RoomID: room.ID, Path: "helper.py", TextName: "file:helper"}
+	require.NoError(t, files.Create(context.Background(), main))
+	require.NoError(t, files.Create(context.Background(), helper))
+	comments := &memoryCommentRepo{threads: make(map[uint]*models.RoomCommentThread)}
+	editor := &fakeCommentEditor{}
+	svc := NewCommentService(newVersionRoomRepo(room), files, comments, nil, editor)
+	ctx := context.Background()
+
+	// 1. 发起评论：锚点解析为当前位置，内容经过清理
+	thread, err := svc.CreateThread(ctx, "room-1", 1, &CreateCommentThreadRequest{
+		FileID:      main.ID,
+		AnchorStart: relativeItem(4),
+		AnchorEnd:   relativeItem(9),
+		Quote:       "x = 1",
+		Content:     "  为什么不用 **常量**？<script>alert(1)</script> ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.CommentRange{Start: 4, End: 9}, thread.Range)
+	require.Len(t, thread.Comments, 1)
+	assert.NotContains(t, thread.Comments[0].Content, "<script>")
+	require.Len(t, editor.broadcasts, 1)
+
+	// 2. 回复、解决、重新打开，每次都推送
+	thread, err = svc.Reply(ctx, "room-1", 1, thread.ID, &ReplyCommentRequest{Content: "已修改"})
+	require.NoError(t, err)
+	assert.Len(t, thread.Comments, 2)
+	thread, err = svc.SetResolved(ctx, "room-1", 1, thread.ID, true)
+	require.NoError(t, err)
+	assert.True(t, thread.Resolved)
+	assert.Len(t, editor.broadcasts, 3)
+	// 状态没有变化时不再推送
+	_, err = svc.SetResolved(ctx, "room-1", 1, thread.ID, true)
+	require.NoError(t, err)
+	assert.Len(t, editor.broadcasts, 3)
+
+	// 3. 筛选，锚点无法解析时没有位置
+	_, err = svc.CreateThread(ctx, "room-1", 1, &CreateCommentThreadRequest{
+		FileID: helper.ID, AnchorStart: relativeItem(0xff), AnchorEnd: relativeItem(2), Content: "未同步",
+	})
+	require.NoError(t, err)
+	open, err := svc.ListThreads(ctx, "room-1", 1, &ListCommentThreadsQuery{Status: "open"})
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Nil(t, open[0].Range)
+	all, err := svc.ListThreads(ctx, "room-1", 1, &ListCommentThreadsQuery{})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	// 4. 文件删除后其评论不再返回
+	require.NoError(t, files.Delete(ctx, helper.ID))
+	all, err = svc.ListThreads(ctx, "room-1", 1, &ListCommentThreadsQuery{})
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestCommentService_Validation(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: models.RoomStatusActive}
+	files := newMemoryFileRepo()
+	other := &models.RoomFile{RoomID: 2, Path: "main.py", TextName: models.MainTextName}
+	file := &models.RoomFile{RoomID: room.ID, Path: "main.py", TextName: models.MainTextName}
+	require.NoError(t, files.Create(context.Background(), other))
+	require.NoError(t, files.Create(context.Background(), file))
+	comments := &memoryCommentRepo{threads: make(map[uint]*models.RoomCommentThread)}
+	svc := NewCommentService(newVersionRoomRepo(room), files, comments, nil, &fakeCommentEditor{})
+
+	valid := CreateCommentThreadRequest{FileID: file.ID, AnchorStart: relativeItem(0), AnchorEnd: relativeItem(1), Content: "ok"}
+	tests := []struct {
+		name    string
+		userID  uint
+		modify  func(req *CreateCommentThreadRequest)
+		wantErr error
+	}{
+		{name: "非成员", userID: 2, modify: func(*CreateCommentThreadRequest) {}, wantErr: ErrNotRoomMember},
+		{name: "其他房间的文件", userID: 1, modify: func(req *CreateCommentThreadRequest) { req.FileID = other.ID }, wantErr: ErrRoomFileNotFound},
+		{name: "无效的锚点", userID: 1, modify: func(req *CreateCommentThreadRequest) { req.AnchorEnd = []byte{2} }, wantErr: ErrCommentAnchor},
+		{name: "空评论", userID: 1, modify: func(req *CreateCommentThreadRequest) { req.Content = "  " }, wantErr: ErrCommentEmpty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := svc.CreateThread(context.Background(), "room-1", tt.userID, &req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := svc.Reply(context.Background(), "room-1", 1, 99, &ReplyCommentRequest{Content: "?"})
+	assert.ErrorIs(t, err, ErrCommentThreadNotFound)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrRoomLockNotFound 锁定区域不存在
+	ErrRoomLockNotFound = repository.ErrRoomLockNotFound
+	ErrRoomLockAnchor   = errors.New("锁定区域的位置无效")
+	ErrTooManyRoomLocks = errors.New("锁定区域数量超出限制")
+	// ErrRoomRegionLocked 管理员以下的成员修改了锁定区域
+	ErrRoomRegionLocked = errors.New("该区域已锁定，不能修改")
+)
+
+// maxRoomLocks 每个房间最多的锁定区域数，每次编辑都要检查所有区域
+const maxRoomLocks = 20
+
+// LockEditor 把锁定区域同步给协作房间，由 realtime.Hub 实现
+type LockEditor interface {
+	// SetLocks 更新房间的锁定区域并推送给所有在线成员
+	SetLocks(ctx context.Context, room *models.Room, locks []*models.RoomLock) error
+}
+
+// LockService 锁定区域：面试官把题目描述、测试代码等区域设为只读，候选人只能编辑作答区域
+// 管理员及以上的成员可以管理锁定区域，也不受锁定限制；编辑由协作服务检查，见 realtime 包
+type LockService interface {
+	ListLocks(ctx context.Context, uuid string, userID uint) ([]*models.RoomLock, error)
+	CreateLock(ctx context.Context, uuid string, userID uint, req *CreateRoomLockRequest) (*models.RoomLock, error)
+	DeleteLock(ctx context.Context, uuid string, userID, lockID uint) error
+}
+
+type lockService struct {
+	roomRepo  repository.RoomRepository
+	fileRepo  repository.RoomFileRepository
+	lockRepo  repository.RoomLockRepository
+	eventRepo repository.RoomEventRepository
+	editor    LockEditor
+}
+
+// CreateRoomLockRequest 锁定文件中的一段代码
+// AnchorStart 指向区域第一个字符（assoc 0），AnchorEnd 指向最后一个字符（assoc -1），
+// 都是 Y.encodeRelativePosition 的结果，JSON 中为 base64
+type CreateRoomLockRequest struct {
+	FileID      uint   `json:"file_id" binding:"required"`
+	AnchorStart []byte `json:"anchor_start" binding:"required"`
+	AnchorEnd   []byte `json:"anchor_end" binding:"required"`
+	Label       string `json:"label" binding:"max=100"`
+}
+
+func NewLockService(
+	roomRepo repository.RoomRepository,
+	fileRepo repository.RoomFileRepository,
+	lockRepo repository.RoomLockRepository,
+	eventRepo repository.RoomEventRepository,
+	editor LockEditor,
+) LockService {
+	return &lockService{
+		roomRepo:  roomRepo,
+		fileRepo:  fileRepo,
+		lockRepo:  lockRepo,
+		eventRepo: eventRepo,
+		editor:    editor,
+	}
+}
+
+func (s *lockService) ListLocks(ctx context.Context, uuid string, userID uint) ([]*models.RoomLock, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return s.lockRepo.ListByRoom(ctx, room.ID)
+}
+
+func (s *lockService) CreateLock(ctx context.Context, uuid string, userID uint, req *CreateRoomLockRequest) (*models.RoomLock, error) {
+	room, err := s.findRoomAsAdmin(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.fileRepo.FindByID(ctx, req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.RoomID != room.ID {
+		return nil, ErrRoomFileNotFound
+	}
+	for _, anchor := range [][]byte{req.AnchorStart, req.AnchorEnd} {
+		if _, err := yjs.DecodeRelativePosition(anchor); err != nil {
+			return nil, ErrRoomLockAnchor
+		}
+	}
+	count, err := s.lockRepo.CountByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxRoomLocks {
+		return nil, ErrTooManyRoomLocks
+	}
+
+	lock := &models.RoomLock{
+		RoomID:      room.ID,
+		FileID:      file.ID,
+		TextName:    file.TextName,
+		AnchorStart: req.AnchorStart,
+		AnchorEnd:   req.AnchorEnd,
+		Label:       strings.TrimSpace(req.Label),
+		CreatedBy:   userID,
+	}
+	if err := s.lockRepo.Create(ctx, lock); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventLockCreate, models.JSONMap{
+		"lock_id": lock.ID,
+		"file_id": file.ID,
+		"path":    file.Path,
+		"label":   lock.Label,
+	})
+	if err := s.sync(ctx, room); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+func (s *lockService) DeleteLock(ctx context.Context, uuid string, userID, lockID uint) error {
+	room, err := s.findRoomAsAdmin(ctx, uuid, userID)
+	if err != nil {
+		return err
+	}
+	lock, err := s.lockRepo.FindByID(ctx, lockID)
+	if err != nil {
+		return err
+	}
+	if lock.RoomID != room.ID {
+		return ErrRoomLockNotFound
+	}
+
+	if err := s.lockRepo.Delete(ctx, lock.ID); err != nil {
+		return err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventLockDelete, models.JSONMap{
+		"lock_id": lock.ID,
+		"label":   lock.Label,
+	})
+	return s.sync(ctx, room)
+}
+
+// sync 把最新的锁定区域同步给协作房间
+// 和推送文件树不同，同步失败时在线成员的编辑不受新的锁定限制，必须返回错误
+func (s *lockService) sync(ctx context.Context, room *models.Room) error {
+	locks, err := s.lockRepo.ListByRoom(ctx, room.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.editor.SetLocks(ctx, room, locks); err != nil {
+		logger.Warn("同步锁定区域失败", zap.String("room_uuid", room.UUID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// findRoomAsAdmin 管理员及以上可以管理锁定区域，归档房间只读
+func (s *lockService) findRoomAsAdmin(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, ErrNotRoomMember
+	}
+	if !member.HasRole(models.RoomRoleAdmin) {
+		return nil, ErrRoomForbidden
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
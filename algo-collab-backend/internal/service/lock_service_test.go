@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLockRepo 内存中的锁定区域
+type memoryLockRepo struct {
+	locks  []*models.RoomLock
+	nextID uint
+}
+
+func (r *memoryLockRepo) Create(_ context.Context, lock *models.RoomLock) error {
+	r.nextID++
+	lock.ID = r.nextID
+	r.locks = append(r.locks, lock)
+	return nil
+}
+
+func (r *memoryLockRepo) FindByID(_ context.Context, id uint) (*models.RoomLock, error) {
+	for _, lock := range r.locks {
+		if lock.ID == id {
+			return lock, nil
+		}
+	}
+	return nil, repository.ErrRoomLockNotFound
+}
+
+func (r *memoryLockRepo) ListByRoom(_ context.Context, roomID uint) ([]*models.RoomLock, error) {
+	var locks []*models.RoomLock
+	for _, lock := range r.locks {
+		if lock.RoomID == roomID {
+			locks = append(locks, lock)
+		}
+	}
+	return locks, nil
+}
+
+func (r *memoryLockRepo) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	locks, _ := r.ListByRoom(ctx, roomID)
+	return int64(len(locks)), nil
+}
+
+func (r *memoryLockRepo) Delete(_ context.Context, id uint) error {
+	for i, lock := range r.locks {
+		if lock.ID == id {
+			r.locks = append(r.locks[:i], r.locks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// fakeLockEditor 记录同步给协作房间的锁定区域
+type fakeLockEditor struct {
+	synced [][]*models.RoomLock
+	err    error
+}
+
+func (e *fakeLockEditor) SetLocks(_ context.Context, _ *models.Room, locks []*models.RoomLock) error {
+	e.synced = append(e.synced, locks)
+	return e.err
+}
+
+func newLockFixture(status string) (LockService, *memoryFileRepo, *fakeLockEditor) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: status}
+	roomRepo := newVersionRoomRepo(room)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(1)).Return(&models.RoomMember{Role: models.RoomRoleOwner}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(3)).Return(&models.RoomMember{Role: models.RoomRoleMember}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, mock.Anything).Return(nil, errors.New("not found"))
+
+	files := newMemoryFileRepo()
+	_ = files.Create(context.Background(), &models.RoomFile{RoomID: room.ID, Path: "main.py", TextName: models.MainTextName})
+	_ = files.Create(context.Background(), &models.RoomFile{RoomID: 2, Path: "main.py", TextName: models.MainTextName})
+	editor := &fakeLockEditor{}
+	return NewLockService(roomRepo, files, &memoryLockRepo{}, nil, editor), files, editor
+}
+
+func TestLockService_CreateAndDelete(t *testing.T) {
+	svc, _, editor := newLockFixture(models.RoomStatusActive)
+	ctx := context.Background()
+
+	lock, err := svc.CreateLock(ctx, "room-1", 1, &CreateRoomLockRequest{
+		FileID: 1, AnchorStart: relativeItem(0), AnchorEnd: relativeItem(9), Label: " 题目描述 ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.MainTextName, lock.TextName)
+	assert.Equal(t, "题目描述", lock.Label)
+	require.Len(t, editor.synced, 1)
+	assert.Len(t, editor.synced[0], 1)
+
+	locks, err := svc.ListLocks(ctx, "room-1", 1)
+	require.NoError(t, err)
+	assert.Len(t, locks, 1)
+
+	require.NoError(t, svc.DeleteLock(ctx, "room-1", 1, lock.ID))
+	assert.Len(t, editor.synced, 2)
+	assert.Empty(t, editor.synced[1])
+	assert.ErrorIs(t, svc.DeleteLock(ctx, "room-1", 1, lock.ID), ErrRoomLockNotFound)
+}
+
+func TestLockService_Permissions(t *testing.T) {
+	valid := CreateRoomLockRequest{FileID: 1, AnchorStart: relativeItem(0), AnchorEnd: relativeItem(1)}
+	tests := []struct {
+		name    string
+		status  string
+		userID  uint
+		modify  func(req *CreateRoomLockRequest)
+		wantErr error
+	}{
+		{name: "普通成员不能锁定", status: models.RoomStatusActive, userID: 3, wantErr: ErrRoomForbidden},
+		{name: "非成员", status: models.RoomStatusActive, userID: 2, wantErr: ErrNotRoomMember},
+		{name: "归档房间只读", status: models.RoomStatusArchived, userID: 1, wantErr: ErrRoomArchived},
+		{name: "其他房间的文件", status: models.RoomStatusActive, userID: 1, wantErr: ErrRoomFileNotFound,
+			modify: func(req *CreateRoomLockRequest) { req.FileID = 2 }},
+		{name: "无效的锚点", status: models.RoomStatusActive, userID: 1, wantErr: ErrRoomLockAnchor,
+			modify: func(req *CreateRoomLockRequest) { req.AnchorStart = []byte{3} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, editor := newLockFixture(tt.status)
+			req := valid
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			_, err := svc.CreateLock(context.Background(), "room-1", tt.userID, &req)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Empty(t, editor.synced)
+		})
+	}
+}
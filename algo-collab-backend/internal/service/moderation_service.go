@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 举报相关的错误
+var (
+	ErrReportNotFound   = repository.ErrReportNotFound
+	ErrReportDuplicate  = repository.ErrReportDuplicate
+	ErrReportOwnMessage = errors.New("不能举报自己的消息")
+	ErrReportHandled    = errors.New("该举报已处理")
+)
+
+// ModerationService 房间聊天管理：禁言和举报
+// 权限沿用房间角色：管理员及以上可以禁言比自己角色低的成员、处理举报
+type ModerationService interface {
+	MuteMember(ctx context.Context, uuid string, operatorID, targetID uint, req *MuteMemberRequest) (*models.RoomMember, error)
+	UnmuteMember(ctx context.Context, uuid string, operatorID, targetID uint) error
+	// ReportMessage 成员举报一条消息，进入审核队列
+	ReportMessage(ctx context.Context, uuid string, reporterID, messageID uint, req *ReportMessageRequest) (*models.RoomMessageReport, error)
+	// ListReports 审核队列（房间管理员）
+	ListReports(ctx context.Context, uuid string, userID uint, query *ListReportsQuery) (*MessageReportPage, error)
+	// HandleReport 处理举报（房间管理员）
+	HandleReport(ctx context.Context, uuid string, userID, reportID uint, req *HandleReportRequest) (*models.RoomMessageReport, error)
+}
+
+type moderationService struct {
+	roomRepo    repository.RoomRepository
+	messageRepo repository.RoomMessageRepository
+	reportRepo  repository.RoomMessageReportRepository
+	eventRepo   repository.RoomEventRepository
+}
+
+// MuteMemberRequest 禁言成员，最长 30 天
+type MuteMemberRequest struct {
+	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1,max=43200"`
+	Reason          string `json:"reason" binding:"max=200"`
+}
+
+// ReportMessageRequest 举报消息
+type ReportMessageRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ListReportsQuery 审核队列查询参数，默认只返回待处理的举报
+type ListReportsQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending resolved dismissed all"`
+}
+
+// HandleReportRequest 处理举报：resolved 表示已处理（删除消息、禁言等另行操作），dismissed 表示驳回
+type HandleReportRequest struct {
+	Status string `json:"status" binding:"required,oneof=resolved dismissed"`
+}
+
+// MessageReportPage 举报分页结果
+type MessageReportPage struct {
+	Reports  []*models.RoomMessageReport `json:"reports"`
+	Total    int64                       `json:"total"`
+	Page     int                         `json:"page"`
+	PageSize int                         `json:"page_size"`
+}
+
+func NewModerationService(
+	roomRepo repository.RoomRepository,
+	messageRepo repository.RoomMessageRepository,
+	reportRepo repository.RoomMessageReportRepository,
+	eventRepo repository.RoomEventRepository,
+) ModerationService {
+	return &moderationService{
+		roomRepo:    roomRepo,
+		messageRepo: messageRepo,
+		reportRepo:  reportRepo,
+		eventRepo:   eventRepo,
+	}
+}
+
+// MuteMember 禁言成员，重复禁言以最新的时长为准
+func (s *moderationService) MuteMember(ctx context.Context, uuid string, operatorID, targetID uint, req *MuteMemberRequest) (*models.RoomMember, error) {
+	room, target, err := s.findManageableMember(ctx, uuid, operatorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+	if err := s.roomRepo.SetMutedUntil(ctx, room.ID, targetID, &until); err != nil {
+		return nil, err
+	}
+	target.MutedUntil = &until
+
+	logger.Info("成员被禁言",
+		zap.String("room_uuid", uuid),
+		zap.Uint("operator_id", operatorID),
+		zap.Uint("target_id", targetID),
+		zap.Int("duration_minutes", req.DurationMinutes))
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &operatorID, &targetID, models.RoomEventMute, models.JSONMap{
+		"duration_minutes": req.DurationMinutes,
+		"reason":           req.Reason,
+	})
+	return target, nil
+}
+
+// UnmuteMember 解除禁言
+func (s *moderationService) UnmuteMember(ctx context.Context, uuid string, operatorID, targetID uint) error {
+	room, _, err := s.findManageableMember(ctx, uuid, operatorID, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.roomRepo.SetMutedUntil(ctx, room.ID, targetID, nil); err != nil {
+		return err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &operatorID, &targetID, models.RoomEventUnmute, nil)
+	return nil
+}
+
+// ReportMessage 不能举报系统消息、已删除的消息和自己的消息
+func (s *moderationService) ReportMessage(ctx context.Context, uuid string, reporterID, messageID uint, req *ReportMessageRequest) (*models.RoomMessageReport, error) {
+	// 1. 举报人必须是房间成员
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, reporterID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+
+	// 2. 校验被举报的消息
+	message, err := s.messageRepo.FindByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.RoomID != room.ID || message.UserID == nil {
+		return nil, ErrChatMessageNotFound
+	}
+	if message.IsDeleted() {
+		return nil, ErrChatMessageDeleted
+	}
+	if *message.UserID == reporterID {
+		return nil, ErrReportOwnMessage
+	}
+
+	// 3. 写入审核队列
+	report := &models.RoomMessageReport{
+		RoomID:     room.ID,
+		MessageID:  messageID,
+		ReporterID: reporterID,
+		Reason:     req.Reason,
+		Status:     models.ReportStatusPending,
+	}
+	if err := s.reportRepo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+
+	logger.Info("聊天消息被举报",
+		zap.String("room_uuid", uuid),
+		zap.Uint("message_id", messageID),
+		zap.Uint("reporter_id", reporterID))
+	return report, nil
+}
+
+// ListReports 按时间倒序分页返回举报
+func (s *moderationService) ListReports(ctx context.Context, uuid string, userID uint, query *ListReportsQuery) (*MessageReportPage, error) {
+	room, _, err := s.findRoomAsAdmin(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	status := query.Status
+	switch status {
+	case "":
+		status = models.ReportStatusPending
+	case "all":
+		status = ""
+	}
+
+	reports, total, err := s.reportRepo.List(ctx, room.ID, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageReportPage{
+		Reports:  reports,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// HandleReport 每条举报只能处理一次
+func (s *moderationService) HandleReport(ctx context.Context, uuid string, userID, reportID uint, req *HandleReportRequest) (*models.RoomMessageReport, error) {
+	room, _, err := s.findRoomAsAdmin(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.reportRepo.FindByID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.RoomID != room.ID {
+		return nil, ErrReportNotFound
+	}
+	if report.Status != models.ReportStatusPending {
+		return nil, ErrReportHandled
+	}
+
+	now := time.Now()
+	report.Status = req.Status
+	report.HandledBy = &userID
+	report.HandledAt = &now
+	if err := s.reportRepo.UpdateStatus(ctx, report); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventReportHandled, models.JSONMap{
+		"report_id":  report.ID,
+		"message_id": report.MessageID,
+		"status":     report.Status,
+	})
+	return report, nil
+}
+
+// findRoomAsAdmin 查找房间并校验操作人是管理员及以上
+func (s *moderationService) findRoomAsAdmin(ctx context.Context, uuid string, userID uint) (*models.Room, *models.RoomMember, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, nil, ErrNotRoomMember
+	}
+	if !member.HasRole(models.RoomRoleAdmin) {
+		return nil, nil, ErrRoomForbidden
+	}
+	return room, member, nil
+}
+
+// findManageableMember 和踢人的规则一致：管理员及以上，只能操作角色比自己低的成员
+func (s *moderationService) findManageableMember(ctx context.Context, uuid string, operatorID, targetID uint) (*models.Room, *models.RoomMember, error) {
+	room, operator, err := s.findRoomAsAdmin(ctx, uuid, operatorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := s.roomRepo.GetMember(ctx, room.ID, targetID)
+	if err != nil {
+		return nil, nil, ErrNotRoomMember
+	}
+	if target.HasRole(operator.Role) {
+		return nil, nil, ErrRoomForbidden
+	}
+	return room, target, nil
+}
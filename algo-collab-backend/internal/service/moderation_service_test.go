@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRoomMessageReportRepository 模拟举报仓库
+type MockRoomMessageReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoomMessageReportRepository) Create(ctx context.Context, report *models.RoomMessageReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockRoomMessageReportRepository) FindByID(ctx context.Context, id uint) (*models.RoomMessageReport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMessageReport), args.Error(1)
+}
+
+func (m *MockRoomMessageReportRepository) List(ctx context.Context, roomID uint, status string, limit, offset int) ([]*models.RoomMessageReport, int64, error) {
+	args := m.Called(ctx, roomID, status, limit, offset)
+	return args.Get(0).([]*models.RoomMessageReport), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRoomMessageReportRepository) UpdateStatus(ctx context.Context, report *models.RoomMessageReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+var moderationRoom = &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1"}
+
+func TestModerationService_MuteMember(t *testing.T) {
+	tests := []struct {
+		name         string
+		operatorRole string
+		targetRole   string
+		wantErr      error
+	}{
+		{name: "房主禁言管理员", operatorRole: models.RoomRoleOwner, targetRole: models.RoomRoleAdmin},
+		{name: "管理员禁言成员", operatorRole: models.RoomRoleAdmin, targetRole: models.RoomRoleMember},
+		{name: "管理员不能禁言管理员", operatorRole: models.RoomRoleAdmin, targetRole: models.RoomRoleAdmin, wantErr: ErrRoomForbidden},
+		{name: "普通成员不能禁言", operatorRole: models.RoomRoleMember, targetRole: models.RoomRoleMember, wantErr: ErrRoomForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomRepo := new(MockRoomRepository)
+			roomRepo.On("FindByUUID", mock.Anything, "room-1").Return(moderationRoom, nil)
+			roomRepo.On("GetMember", mock.Anything, uint(1), uint(7)).Return(&models.RoomMember{UserID: 7, Role: tt.operatorRole}, nil)
+			roomRepo.On("GetMember", mock.Anything, uint(1), uint(8)).Return(&models.RoomMember{UserID: 8, Role: tt.targetRole}, nil)
+			roomRepo.On("SetMutedUntil", mock.Anything, uint(1), uint(8), mock.AnythingOfType("*time.Time")).Return(nil).Maybe()
+			svc := NewModerationService(roomRepo, nil, nil, nil)
+
+			member, err := svc.MuteMember(context.Background(), "room-1", 7, 8, &MuteMemberRequest{DurationMinutes: 30})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				roomRepo.AssertNotCalled(t, "SetMutedUntil", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, member.IsMuted(time.Now()))
+			assert.False(t, member.IsMuted(time.Now().Add(31*time.Minute)))
+		})
+	}
+}
+
+func TestModerationService_ReportMessage(t *testing.T) {
+	author, reporter := uint(8), uint(7)
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name    string
+		message *models.RoomMessage
+		wantErr error
+	}{
+		{name: "举报他人的消息", message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author}},
+		{name: "不能举报自己的消息", message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &reporter}, wantErr: ErrReportOwnMessage},
+		{name: "不能举报系统消息", message: &models.RoomMessage{ID: 5, RoomID: 1}, wantErr: ErrChatMessageNotFound},
+		{name: "不能举报其他房间的消息", message: &models.RoomMessage{ID: 5, RoomID: 2, UserID: &author}, wantErr: ErrChatMessageNotFound},
+		{name: "不能举报已删除的消息", message: &models.RoomMessage{ID: 5, RoomID: 1, UserID: &author, DeletedAt: &deletedAt}, wantErr: ErrChatMessageDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomRepo := new(MockRoomRepository)
+			roomRepo.On("FindByUUID", mock.Anything, "room-1").Return(moderationRoom, nil)
+			roomRepo.On("IsMember", mock.Anything, uint(1), reporter).Return(true, nil)
+			messageRepo := new(MockRoomMessageRepository)
+			messageRepo.On("FindByID", mock.Anything, uint(5)).Return(tt.message, nil)
+			reportRepo := new(MockRoomMessageReportRepository)
+			reportRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.RoomMessageReport")).Return(nil).Maybe()
+			svc := NewModerationService(roomRepo, messageRepo, reportRepo, nil)
+
+			report, err := svc.ReportMessage(context.Background(), "room-1", reporter, 5, &ReportMessageRequest{Reason: "广告"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				reportRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, models.ReportStatusPending, report.Status)
+			assert.Equal(t, "广告", report.Reason)
+		})
+	}
+}
+
+func TestModerationService_HandleReport(t *testing.T) {
+	roomRepo := new(MockRoomRepository)
+	roomRepo.On("FindByUUID", mock.Anything, "room-1").Return(moderationRoom, nil)
+	roomRepo.On("GetMember", mock.Anything, uint(1), uint(7)).Return(&models.RoomMember{Role: models.RoomRoleAdmin}, nil)
+	reportRepo := new(MockRoomMessageReportRepository)
+	reportRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.RoomMessageReport{ID: 3, RoomID: 1, Status: models.ReportStatusPending}, nil).Once()
+	reportRepo.On("FindByID", mock.Anything, uint(3)).Return(&models.RoomMessageReport{ID: 3, RoomID: 1, Status: models.ReportStatusDismissed}, nil)
+	reportRepo.On("UpdateStatus", mock.Anything, mock.AnythingOfType("*models.RoomMessageReport")).Return(nil)
+	svc := NewModerationService(roomRepo, nil, reportRepo, nil)
+
+	report, err := svc.HandleReport(context.Background(), "room-1", 7, 3, &HandleReportRequest{Status: models.ReportStatusDismissed})
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportStatusDismissed, report.Status)
+	assert.Equal(t, uint(7), *report.HandledBy)
+
+	// 已处理的举报不能再次处理
+	_, err = svc.HandleReport(context.Background(), "room-1", 7, 3, &HandleReportRequest{Status: models.ReportStatusResolved})
+	assert.ErrorIs(t, err, ErrReportHandled)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+)
+
+// NotificationService 站内通知（目前只有聊天中的 @ 提及）
+type NotificationService interface {
+	ListNotifications(ctx context.Context, userID uint, query *ListNotificationsQuery) (*NotificationPage, error)
+	MarkRead(ctx context.Context, userID uint, req *MarkNotificationsReadRequest) error
+}
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+}
+
+// ListNotificationsQuery 通知列表查询参数
+type ListNotificationsQuery struct {
+	Page       int  `form:"page"`
+	PageSize   int  `form:"page_size"`
+	UnreadOnly bool `form:"unread_only"`
+}
+
+// MarkNotificationsReadRequest 标记已读，IDs 为空表示全部标记为已读
+type MarkNotificationsReadRequest struct {
+	IDs []uint `json:"ids" binding:"max=100"`
+}
+
+// NotificationPage 通知分页结果，UnreadCount 是全部未读数，用于显示角标
+type NotificationPage struct {
+	Notifications []*models.Notification `json:"notifications"`
+	Total         int64                  `json:"total"`
+	UnreadCount   int64                  `json:"unread_count"`
+	Page          int                    `json:"page"`
+	PageSize      int                    `json:"page_size"`
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+	}
+}
+
+// ListNotifications 按时间倒序分页返回当前用户的通知
+func (s *notificationService) ListNotifications(ctx context.Context, userID uint, query *ListNotificationsQuery) (*NotificationPage, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	notifications, total, err := s.notificationRepo.List(ctx, userID, query.UnreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationPage{
+		Notifications: notifications,
+		Total:         total,
+		UnreadCount:   unread,
+		Page:          page,
+		PageSize:      pageSize,
+	}, nil
+}
+
+// MarkRead 只会修改当前用户自己的通知
+func (s *notificationService) MarkRead(ctx context.Context, userID uint, req *MarkNotificationsReadRequest) error {
+	return s.notificationRepo.MarkRead(ctx, userID, req.IDs)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrOrganizationNotFound  = repository.ErrOrganizationNotFound
+	ErrOrganizationForbidden = errors.New("不是该组织成员或权限不足")
+)
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, userID uint, req *CreateOrganizationRequest) (*models.Organization, error)
+	AddMember(ctx context.Context, operatorID uint, orgUUID string, req *AddOrganizationMemberRequest) (*models.OrganizationMember, error)
+	SetDefaultTemplate(ctx context.Context, operatorID uint, orgUUID string, req *SetDefaultTemplateRequest) (*models.Organization, error)
+	CreateRoom(ctx context.Context, userID uint, orgUUID string, req *CreateOrganizationRoomRequest) (*models.Room, error)
+}
+
+type organizationService struct {
+	orgRepo      repository.OrganizationRepository
+	templateRepo repository.TemplateRepository
+	roomService  RoomService
+}
+
+// 请求结构体
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type AddOrganizationMemberRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+// SetDefaultTemplateRequest 设置组织默认模板，TemplateUUID 为空表示取消
+type SetDefaultTemplateRequest struct {
+	TemplateUUID string `json:"template_uuid"`
+	Enforce      bool   `json:"enforce"`
+}
+
+// CreateOrganizationRoomRequest 组织下创建房间
+// 组织有默认模板时所有字段都可以省略
+type CreateOrganizationRoomRequest struct {
+	Name           string `json:"name" binding:"max=100"`
+	Description    string `json:"description"`
+	Language       string `json:"language" binding:"omitempty,oneof=python javascript go java cpp"`
+	MaxMembers     int    `json:"max_members" binding:"omitempty,min=1,max=100"`
+	IsPublic       *bool  `json:"is_public"`
+	Password       string `json:"password" binding:"omitempty,min=4,max=32"`
+	ProblemTitle   string `json:"problem_title" binding:"max=200"`
+	ProblemContent string `json:"problem_content"`
+	StarterCode    string `json:"starter_code"`
+}
+
+// OrganizationRoomRequest 把普通建房请求转换为组织建房请求，
+// 携带 organization_uuid 的 POST /rooms 由此转交 OrganizationService.CreateRoom
+func OrganizationRoomRequest(req *CreateRoomRequest) *CreateOrganizationRoomRequest {
+	return &CreateOrganizationRoomRequest{
+		Name:           req.Name,
+		Description:    req.Description,
+		Language:       req.Language,
+		MaxMembers:     req.MaxMembers,
+		IsPublic:       req.IsPublic,
+		Password:       req.Password,
+		ProblemTitle:   req.ProblemTitle,
+		ProblemContent: req.ProblemContent,
+		StarterCode:    req.StarterCode,
+	}
+}
+
+func (r *CreateOrganizationRoomRequest) toRoomRequest() *CreateRoomRequest {
+	return &CreateRoomRequest{
+		Name:           r.Name,
+		Description:    r.Description,
+		Language:       r.Language,
+		MaxMembers:     r.MaxMembers,
+		IsPublic:       r.IsPublic,
+		Password:       r.Password,
+		ProblemTitle:   r.ProblemTitle,
+		ProblemContent: r.ProblemContent,
+		StarterCode:    r.StarterCode,
+	}
+}
+
+func NewOrganizationService(
+	orgRepo repository.OrganizationRepository,
+	templateRepo repository.TemplateRepository,
+	roomService RoomService,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:      orgRepo,
+		templateRepo: templateRepo,
+		roomService:  roomService,
+	}
+}
+
+// CreateOrganization 创建组织
+func (s *organizationService) CreateOrganization(ctx context.Context, userID uint, req *CreateOrganizationRequest) (*models.Organization, error) {
+	org := &models.Organization{
+		Name:    req.Name,
+		OwnerID: userID,
+	}
+	if err := s.orgRepo.Create(ctx, org); err != nil {
+		logger.Error("创建组织失败", zap.Error(err))
+		return nil, errors.New("创建组织失败，请稍后重试")
+	}
+	return org, nil
+}
+
+// AddMember 组织管理员添加成员
+func (s *organizationService) AddMember(ctx context.Context, operatorID uint, orgUUID string, req *AddOrganizationMemberRequest) (*models.OrganizationMember, error) {
+	org, err := s.findManagedOrganization(ctx, operatorID, orgUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         req.UserID,
+		Role:           req.Role,
+	}
+	if member.Role == "" {
+		member.Role = models.OrgRoleMember
+	}
+	if err := s.orgRepo.AddMember(ctx, member); err != nil {
+		return nil, errors.New("添加成员失败，用户可能已在组织中")
+	}
+	return member, nil
+}
+
+// SetDefaultTemplate 设置组织默认模板
+// Enforce 为 true 时，组织内创建房间一律使用该模板的配置
+func (s *organizationService) SetDefaultTemplate(ctx context.Context, operatorID uint, orgUUID string, req *SetDefaultTemplateRequest) (*models.Organization, error) {
+	org, err := s.findManagedOrganization(ctx, operatorID, orgUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TemplateUUID == "" {
+		org.DefaultTemplateID = nil
+		org.EnforceTemplate = false
+	} else {
+		template, err := s.templateRepo.FindByUUID(ctx, req.TemplateUUID)
+		if err != nil {
+			return nil, err
+		}
+		// 只能使用本组织的模板或公开模板
+		ownedByOrg := template.OrganizationID != nil && *template.OrganizationID == org.ID
+		if !ownedByOrg && template.Visibility != models.TemplateVisibilityPublic {
+			return nil, ErrTemplateForbidden
+		}
+		org.DefaultTemplateID = &template.ID
+		org.EnforceTemplate = req.Enforce
+	}
+
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		return nil, err
+	}
+
+	logger.Info("组织默认模板已更新",
+		zap.String("org_uuid", org.UUID),
+		zap.Bool("enforce", org.EnforceTemplate))
+	return org, nil
+}
+
+// CreateRoom 在组织下创建房间
+// 组织设置了默认模板时以模板为基础；强制模板时只保留房间名和密码，其余配置一律来自模板
+func (s *organizationService) CreateRoom(ctx context.Context, userID uint, orgUUID string, req *CreateOrganizationRoomRequest) (*models.Room, error) {
+	org, err := s.orgRepo.FindByUUID(ctx, orgUUID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.orgRepo.GetMember(ctx, org.ID, userID); err != nil {
+		return nil, ErrOrganizationForbidden
+	}
+
+	roomReq := req.toRoomRequest()
+	if org.DefaultTemplateID != nil {
+		template, err := s.templateRepo.FindByID(ctx, *org.DefaultTemplateID)
+		switch {
+		case errors.Is(err, ErrTemplateNotFound):
+			// 默认模板已被删除：按未设置默认模板处理
+			logger.Warn("组织默认模板不存在", zap.String("org_uuid", org.UUID))
+		case err != nil:
+			return nil, err
+		default:
+			roomReq = mergeTemplateDefaults(RoomRequestFromTemplate(template), roomReq, org.EnforceTemplate)
+		}
+	}
+	if roomReq.Name == "" || roomReq.Language == "" {
+		return nil, errors.New("房间名称和编程语言不能为空")
+	}
+	roomReq.OrganizationID = &org.ID
+
+	return s.roomService.CreateRoom(ctx, userID, roomReq)
+}
+
+// findManagedOrganization 查找组织并确认操作者是 owner/admin
+func (s *organizationService) findManagedOrganization(ctx context.Context, operatorID uint, orgUUID string) (*models.Organization, error) {
+	org, err := s.orgRepo.FindByUUID(ctx, orgUUID)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.orgRepo.GetMember(ctx, org.ID, operatorID)
+	if err != nil || !member.CanManage() {
+		return nil, ErrOrganizationForbidden
+	}
+	return org, nil
+}
+
+// mergeTemplateDefaults 合并模板和用户请求
+// enforce=false 时用户填写的字段优先，未填写的使用模板值
+func mergeTemplateDefaults(base, req *CreateRoomRequest, enforce bool) *CreateRoomRequest {
+	if req.Name != "" {
+		base.Name = req.Name
+	}
+	base.Password = req.Password
+	if enforce {
+		return base
+	}
+
+	if req.Description != "" {
+		base.Description = req.Description
+	}
+	if req.Language != "" {
+		base.Language = req.Language
+	}
+	if req.MaxMembers > 0 {
+		base.MaxMembers = req.MaxMembers
+	}
+	if req.IsPublic != nil {
+		base.IsPublic = req.IsPublic
+	}
+	if req.ProblemTitle != "" {
+		base.ProblemTitle = req.ProblemTitle
+		base.ProblemContent = req.ProblemContent
+	}
+	if req.StarterCode != "" {
+		base.StarterCode = req.StarterCode
+	}
+	return base
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOrganizationFixture 组织 org-1 以一个 go 模板为默认模板
+func newOrganizationFixture(t *testing.T, enforce bool) (OrganizationService, *memoryOrgRepo, *models.RoomTemplate) {
+	templateSvc, templates, orgs, roomRepo := newTemplateFixture(t)
+	template, err := templateSvc.CreateTemplate(context.Background(), 1, &SaveTemplateRequest{
+		Name: "周赛模板", Language: "go", MaxMembers: 4,
+		ProblemTitle: "两数之和", StarterCode: "package main",
+		Visibility: models.TemplateVisibilityTeam, OrganizationUUID: "org-1",
+	})
+	require.NoError(t, err)
+
+	svc := NewOrganizationService(orgs, templates, NewRoomService(roomRepo, nil, nil, nil))
+	_, err = svc.SetDefaultTemplate(context.Background(), 1, "org-1", &SetDefaultTemplateRequest{TemplateUUID: template.UUID, Enforce: enforce})
+	require.NoError(t, err)
+	return svc, orgs, template
+}
+
+func TestOrganizationService_SetDefaultTemplate(t *testing.T) {
+	svc, orgs, template := newOrganizationFixture(t, true)
+	ctx := context.Background()
+
+	org := orgs.orgs[0]
+	require.NotNil(t, org.DefaultTemplateID)
+	assert.Equal(t, template.ID, *org.DefaultTemplateID)
+	assert.True(t, org.EnforceTemplate)
+
+	// 普通成员不能修改组织设置
+	_, err := svc.SetDefaultTemplate(ctx, 2, "org-1", &SetDefaultTemplateRequest{})
+	assert.ErrorIs(t, err, ErrOrganizationForbidden)
+
+	// 空模板表示取消默认模板
+	_, err = svc.SetDefaultTemplate(ctx, 1, "org-1", &SetDefaultTemplateRequest{Enforce: true})
+	require.NoError(t, err)
+	assert.Nil(t, org.DefaultTemplateID)
+	assert.False(t, org.EnforceTemplate)
+}
+
+func TestOrganizationService_CreateRoomWithDefaults(t *testing.T) {
+	svc, _, template := newOrganizationFixture(t, false)
+	ctx := context.Background()
+
+	// 未填写的字段使用模板值，填写的字段优先
+	room, err := svc.CreateRoom(ctx, 2, "org-1", &CreateOrganizationRoomRequest{Name: "练习", Language: "python", MaxMembers: 8})
+	require.NoError(t, err)
+	assert.Equal(t, "练习", room.Name)
+	assert.Equal(t, "python", room.Language)
+	assert.Equal(t, 8, room.MaxMembers)
+	assert.Equal(t, "两数之和", room.ProblemTitle)
+	assert.Equal(t, "package main", room.StarterCode)
+	require.NotNil(t, room.OrganizationID)
+	assert.Equal(t, uint(1), *room.OrganizationID)
+	require.NotNil(t, room.TemplateID)
+	assert.Equal(t, template.ID, *room.TemplateID)
+
+	// 非组织成员不能在组织下创建房间
+	_, err = svc.CreateRoom(ctx, 3, "org-1", &CreateOrganizationRoomRequest{Name: "外部"})
+	assert.ErrorIs(t, err, ErrOrganizationForbidden)
+}
+
+func TestOrganizationService_CreateRoomEnforced(t *testing.T) {
+	svc, _, _ := newOrganizationFixture(t, true)
+
+	// 强制模板时只保留房间名，其余配置来自模板
+	room, err := svc.CreateRoom(context.Background(), 2, "org-1", &CreateOrganizationRoomRequest{
+		Name: "练习", Language: "python", MaxMembers: 8, ProblemTitle: "自选题", StarterCode: "print()",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "练习", room.Name)
+	assert.Equal(t, "go", room.Language)
+	assert.Equal(t, 4, room.MaxMembers)
+	assert.Equal(t, "两数之和", room.ProblemTitle)
+	assert.Equal(t, "package main", room.StarterCode)
+}
+
+func TestOrganizationService_CreateRoomAfterTemplateDeleted(t *testing.T) {
+	svc, orgs, template := newOrganizationFixture(t, true)
+	ctx := context.Background()
+
+	// 默认模板被删除后组织仍然可以建房
+	require.NoError(t, orgs.ClearDefaultTemplate(ctx, template.ID))
+	room, err := svc.CreateRoom(ctx, 2, "org-1", &CreateOrganizationRoomRequest{Name: "练习", Language: "python"})
+	require.NoError(t, err)
+	assert.Equal(t, "python", room.Language)
+	assert.Nil(t, room.TemplateID)
+
+	// 没有默认模板时名称和语言必填
+	_, err = svc.CreateRoom(ctx, 2, "org-1", &CreateOrganizationRoomRequest{Name: "练习"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"go.uber.org/zap"
+)
+
+// ErrRoomSessionNotFound 回放不存在或分享链接已失效
+var ErrRoomSessionNotFound = repository.ErrRoomSessionNotFound
+
+// 回放流中的帧类型
+const (
+	ReplayFrameState     = "state"     // 跳转位置的完整状态：文档、awareness、之前的聊天和终端消息
+	ReplayFrameUpdate    = "update"    // 文档增量
+	ReplayFrameAwareness = "awareness" // awareness 更新
+	ReplayFrameChat      = "chat"      // 一条聊天消息
+	ReplayFrameTerminal  = "terminal"  // 一条共享终端的输出或状态
+	ReplayFrameEnd       = "end"       // 回放结束
+)
+
+const (
+	// replayPageSize 每次从数据库读取的事件数
+	replayPageSize = 500
+	// replayMaxChat 跳转时最多带上之前的多少条聊天
+	replayMaxChat = 200
+	// replayMaxTerminal 跳转时最多带上之前的多少条终端消息，用来恢复终端屏幕
+	replayMaxTerminal = 200
+	// replayIdleGap 跳过空闲时，事件之间最多等待的会话时间
+	replayIdleGap = 2 * time.Second
+)
+
+// ReplayService 协作过程回放：房间的文档更新、awareness、聊天和共享终端由 realtime 按会话记录，
+// 这里负责查询、分享和按速度回放
+type ReplayService interface {
+	ListSessions(ctx context.Context, uuid string, userID uint, query *ListSessionsQuery) (*RoomSessionPage, error)
+	GetSession(ctx context.Context, uuid string, userID, sessionID uint) (*models.RoomSession, error)
+	// ShareSession 生成分享链接（房间管理员），已分享过时返回原链接
+	ShareSession(ctx context.Context, uuid string, userID, sessionID uint) (*models.RoomSession, error)
+	// UnshareSession 使分享链接失效（房间管理员）
+	UnshareSession(ctx context.Context, uuid string, userID, sessionID uint) error
+	// SharedSession 通过分享链接查看回放，不需要是房间成员
+	SharedSession(ctx context.Context, token string) (*SharedReplay, error)
+	// Play 从 opts.FromMS 开始回放：先发送该时刻的完整状态，再按速度依次发送之后的事件，
+	// 直到结束、ctx 取消或 emit 返回错误
+	Play(ctx context.Context, session *models.RoomSession, opts *ReplayOptions, emit func(*ReplayFrame) error) error
+}
+
+type replayService struct {
+	roomRepo    repository.RoomRepository
+	sessionRepo repository.RoomSessionRepository
+}
+
+// ListSessionsQuery 会话列表的分页参数
+type ListSessionsQuery struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+// RoomSessionPage 会话分页结果
+type RoomSessionPage struct {
+	Sessions []*models.RoomSession `json:"sessions"`
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"page_size"`
+}
+
+// SharedReplay 分享链接对应的回放
+type SharedReplay struct {
+	Session  *models.RoomSession `json:"session"`
+	RoomName string              `json:"room_name"`
+}
+
+// ReplayOptions 回放参数：调整速度或跳转时客户端用新的参数重新打开回放
+type ReplayOptions struct {
+	FromMS int64   `form:"from" binding:"min=0"`
+	Speed  float64 `form:"speed" binding:"omitempty,min=0.25,max=16"`
+	// SkipIdle 跳过没有操作的空闲时间
+	SkipIdle bool `form:"skip_idle"`
+}
+
+// ReplayFrame 回放流中的一帧，OffsetMS 为相对会话开始的毫秒数
+type ReplayFrame struct {
+	Type     string `json:"type"`
+	OffsetMS int64  `json:"t"`
+	// Update state 帧为完整文档，update 帧为文档增量（Yjs 更新，JSON 中为 base64）
+	Update []byte `json:"update,omitempty"`
+	// Awareness state 帧为所有人的状态，awareness 帧为状态变化
+	Awareness []byte `json:"awareness,omitempty"`
+	// Chat state 帧为之前的聊天，chat 帧为一条聊天，格式和协作连接上的聊天消息一致
+	Chat []json.RawMessage `json:"chat,omitempty"`
+	// Terminal state 帧为之前的终端消息，terminal 帧为一条，格式和协作连接上的终端消息一致，
+	// 客户端收到终端关闭或重新开启的状态时清空屏幕
+	Terminal []json.RawMessage `json:"terminal,omitempty"`
+}
+
+func NewReplayService(roomRepo repository.RoomRepository, sessionRepo repository.RoomSessionRepository) ReplayService {
+	return &replayService{
+		roomRepo:    roomRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// ListSessions 按开始时间倒序分页返回会话
+func (s *replayService) ListSessions(ctx context.Context, uuid string, userID uint, query *ListSessionsQuery) (*RoomSessionPage, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	sessions, total, err := s.sessionRepo.List(ctx, room.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &RoomSessionPage{
+		Sessions: sessions,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+func (s *replayService) GetSession(ctx context.Context, uuid string, userID, sessionID uint) (*models.RoomSession, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.findSession(ctx, room, sessionID)
+}
+
+func (s *replayService) ShareSession(ctx context.Context, roomUUID string, userID, sessionID uint) (*models.RoomSession, error) {
+	session, err := s.findSessionAsAdmin(ctx, roomUUID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.ShareToken != nil {
+		return session, nil
+	}
+
+	token := uuid.NewString()
+	if err := s.sessionRepo.SetShare(ctx, session.ID, &token, &userID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session.ShareToken, session.SharedBy, session.SharedAt = &token, &userID, &now
+
+	logger.Info("回放已分享",
+		zap.String("room_uuid", roomUUID),
+		zap.Uint("session_id", sessionID),
+		zap.Uint("user_id", userID))
+	return session, nil
+}
+
+func (s *replayService) UnshareSession(ctx context.Context, uuid string, userID, sessionID uint) error {
+	session, err := s.findSessionAsAdmin(ctx, uuid, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.ShareToken == nil {
+		return nil
+	}
+	return s.sessionRepo.SetShare(ctx, session.ID, nil, nil)
+}
+
+func (s *replayService) SharedSession(ctx context.Context, token string) (*SharedReplay, error) {
+	session, err := s.sessionRepo.FindByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	room, err := s.roomRepo.FindByID(ctx, session.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedReplay{Session: session, RoomName: room.Name}, nil
+}
+
+func (s *replayService) Play(ctx context.Context, session *models.RoomSession, opts *ReplayOptions, emit func(*ReplayFrame) error) error {
+	from := min(max(opts.FromMS, 0), session.DurationMS)
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	// 1. 从跳转位置之前最近的关键帧开始，重放到跳转位置
+	state, cursor, err := s.seek(ctx, session, from)
+	if err != nil {
+		return err
+	}
+	if err := emit(state); err != nil {
+		return err
+	}
+
+	// 2. 按事件之间的间隔依次发送
+	deadline := time.Now()
+	last := from
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		events, err := s.sessionRepo.ListEvents(ctx, session.ID, "", cursor, replayPageSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			cursor = repository.SessionEventCursor{OffsetMS: event.OffsetMS, ID: event.ID}
+			frame := replayFrame(event)
+			if frame == nil {
+				continue
+			}
+
+			gap := time.Duration(event.OffsetMS-last) * time.Millisecond
+			if opts.SkipIdle && gap > replayIdleGap {
+				gap = replayIdleGap
+			}
+			last = max(last, event.OffsetMS)
+			if gap > 0 {
+				deadline = deadline.Add(time.Duration(float64(gap) / speed))
+				timer.Reset(time.Until(deadline))
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+			if err := emit(frame); err != nil {
+				return err
+			}
+		}
+		if len(events) < replayPageSize {
+			return emit(&ReplayFrame{Type: ReplayFrameEnd, OffsetMS: max(last, session.DurationMS)})
+		}
+	}
+}
+
+// seek 计算 offsetMS 时刻的完整状态，返回状态帧和之后的事件游标
+func (s *replayService) seek(ctx context.Context, session *models.RoomSession, offsetMS int64) (*ReplayFrame, repository.SessionEventCursor, error) {
+	doc := yjs.NewDoc(yjs.Options{})
+	awareness := make(map[uint64]yjs.AwarenessEntry)
+
+	cursor := repository.SessionStart
+	keyframe, err := s.sessionRepo.LatestKeyframe(ctx, session.ID, offsetMS)
+	if err != nil {
+		return nil, cursor, err
+	}
+	if keyframe != nil {
+		applyReplayUpdate(doc, keyframe)
+		cursor = repository.SessionEventCursor{OffsetMS: keyframe.OffsetMS, ID: keyframe.ID}
+	}
+
+	// 1. 文档和 awareness：重放关键帧之后、跳转位置之前的事件
+	for done := false; !done; {
+		events, err := s.sessionRepo.ListEvents(ctx, session.ID, "", cursor, replayPageSize)
+		if err != nil {
+			return nil, cursor, err
+		}
+		for _, event := range events {
+			if event.OffsetMS > offsetMS {
+				done = true
+				break
+			}
+			cursor = repository.SessionEventCursor{OffsetMS: event.OffsetMS, ID: event.ID}
+			switch event.Kind {
+			case models.SessionEventKeyframe, models.SessionEventUpdate:
+				applyReplayUpdate(doc, event)
+			case models.SessionEventAwareness:
+				mergeAwareness(awareness, event.Data)
+			}
+		}
+		done = done || len(events) < replayPageSize
+	}
+
+	// 2. 聊天和终端：会话开始到跳转位置之间的最后几条
+	chat, err := s.recentEvents(ctx, session.ID, models.SessionEventChat, offsetMS, replayMaxChat)
+	if err != nil {
+		return nil, cursor, err
+	}
+	terminal, err := s.recentEvents(ctx, session.ID, models.SessionEventTerminal, offsetMS, replayMaxTerminal)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	entries := make([]yjs.AwarenessEntry, 0, len(awareness))
+	for _, entry := range awareness {
+		entries = append(entries, entry)
+	}
+	return &ReplayFrame{
+		Type:      ReplayFrameState,
+		OffsetMS:  offsetMS,
+		Update:    doc.EncodeStateAsUpdate(nil),
+		Awareness: yjs.EncodeAwarenessUpdate(entries),
+		Chat:      chat,
+		Terminal:  terminal,
+	}, cursor, nil
+}
+
+// recentEvents 会话开始到 offsetMS 之间 kind 类型的最后 limit 条事件
+func (s *replayService) recentEvents(ctx context.Context, sessionID uint, kind string, offsetMS int64, limit int) ([]json.RawMessage, error) {
+	var data []json.RawMessage
+	for cursor, done := repository.SessionStart, false; !done; {
+		events, err := s.sessionRepo.ListEvents(ctx, sessionID, kind, cursor, replayPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.OffsetMS > offsetMS {
+				done = true
+				break
+			}
+			cursor = repository.SessionEventCursor{OffsetMS: event.OffsetMS, ID: event.ID}
+			data = append(data, json.RawMessage(event.Data))
+		}
+		done = done || len(events) < replayPageSize
+	}
+	if len(data) > limit {
+		data = data[len(data)-limit:]
+	}
+	return data, nil
+}
+
+// replayFrame 事件对应的回放帧，关键帧不需要发送（客户端的文档已经包含）
+func replayFrame(event *models.RoomSessionEvent) *ReplayFrame {
+	frame := &ReplayFrame{OffsetMS: event.OffsetMS}
+	switch event.Kind {
+	case models.SessionEventUpdate:
+		frame.Type, frame.Update = ReplayFrameUpdate, event.Data
+	case models.SessionEventAwareness:
+		frame.Type, frame.Awareness = ReplayFrameAwareness, event.Data
+	case models.SessionEventChat:
+		frame.Type, frame.Chat = ReplayFrameChat, []json.RawMessage{event.Data}
+	case models.SessionEventTerminal:
+		frame.Type, frame.Terminal = ReplayFrameTerminal, []json.RawMessage{event.Data}
+	default:
+		return nil
+	}
+	return frame
+}
+
+func applyReplayUpdate(doc *yjs.Doc, event *models.RoomSessionEvent) {
+	if err := doc.ApplyUpdate(event.Data); err != nil {
+		logger.Warn("跳过损坏的回放事件", zap.Uint("event_id", event.ID), zap.Error(err))
+	}
+}
+
+// mergeAwareness 合入 awareness 更新，clock 较旧的忽略，离开的删除
+func mergeAwareness(states map[uint64]yjs.AwarenessEntry, update []byte) {
+	entries, err := yjs.DecodeAwarenessUpdate(update)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if current, ok := states[entry.ClientID]; ok && current.Clock > entry.Clock {
+			continue
+		}
+		if entry.IsRemoved() {
+			delete(states, entry.ClientID)
+			continue
+		}
+		states[entry.ClientID] = entry
+	}
+}
+
+func (s *replayService) findRoomAsMember(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return room, nil
+}
+
+// findSessionAsAdmin 分享回放需要管理员及以上，例如面试官分享给候选人
+func (s *replayService) findSessionAsAdmin(ctx context.Context, uuid string, userID, sessionID uint) (*models.RoomSession, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, ErrNotRoomMember
+	}
+	if !member.HasRole(models.RoomRoleAdmin) {
+		return nil, ErrRoomForbidden
+	}
+	return s.findSession(ctx, room, sessionID)
+}
+
+// findSession 会话必须属于该房间
+func (s *replayService) findSession(ctx context.Context, room *models.Room, sessionID uint) (*models.RoomSession, error) {
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RoomID != room.ID {
+		return nil, ErrRoomSessionNotFound
+	}
+	return session, nil
+}
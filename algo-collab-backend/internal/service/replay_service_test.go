@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/yjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayTextName 协作编辑器使用的 Y.Text 名称
+const replayTextName = "monaco"
+
+// fakeSessionRepo 内存中的回放会话，只实现回放用到的查询
+type fakeSessionRepo struct {
+	repository.RoomSessionRepository
+	events []*models.RoomSessionEvent
+}
+
+func (r *fakeSessionRepo) add(kind string, offsetMS int64, data []byte) {
+	r.events = append(r.events, &models.RoomSessionEvent{
+		ID:        uint(len(r.events) + 1),
+		SessionID: 1,
+		Kind:      kind,
+		OffsetMS:  offsetMS,
+		Data:      data,
+	})
+	sort.SliceStable(r.events, func(i, j int) bool { return r.events[i].OffsetMS < r.events[j].OffsetMS })
+}
+
+func (r *fakeSessionRepo) LatestKeyframe(_ context.Context, _ uint, offsetMS int64) (*models.RoomSessionEvent, error) {
+	var latest *models.RoomSessionEvent
+	for _, event := range r.events {
+		if event.Kind == models.SessionEventKeyframe && event.OffsetMS <= offsetMS {
+			latest = event
+		}
+	}
+	return latest, nil
+}
+
+func (r *fakeSessionRepo) ListEvents(_ context.Context, _ uint, kind string, after repository.SessionEventCursor, limit int) ([]*models.RoomSessionEvent, error) {
+	var events []*models.RoomSessionEvent
+	for _, event := range r.events {
+		if event.OffsetMS < after.OffsetMS || (event.OffsetMS == after.OffsetMS && event.ID <= after.ID) {
+			continue
+		}
+		if kind != "" && event.Kind != kind {
+			continue
+		}
+		if events = append(events, event); len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// newReplayFixture 一个会话：0ms 空文档关键帧，随后每 100ms 输入一个字符，300ms 处一条终端输出，500ms 处一条聊天
+func newReplayFixture(t *testing.T) (*fakeSessionRepo, *models.RoomSession) {
+	t.Helper()
+	repo := &fakeSessionRepo{}
+	doc := yjs.NewDoc(yjs.Options{})
+	repo.add(models.SessionEventKeyframe, 0, doc.EncodeStateAsUpdate(nil))
+	for i, ch := range "hello" {
+		repo.add(models.SessionEventUpdate, int64(i+1)*100, doc.GetText(replayTextName).Insert(uint64(i), string(ch)))
+	}
+	repo.add(models.SessionEventTerminal, 300, []byte(`{"type":"output","data":"JCBscwo="}`))
+	repo.add(models.SessionEventChat, 500, []byte(`{"type":"message","content":"done"}`))
+	return repo, &models.RoomSession{ID: 1, DurationMS: 500}
+}
+
+func replayText(t *testing.T, frames ...*ReplayFrame) string {
+	t.Helper()
+	doc := yjs.NewDoc(yjs.Options{})
+	for _, frame := range frames {
+		if len(frame.Update) > 0 {
+			require.NoError(t, doc.ApplyUpdate(frame.Update))
+		}
+	}
+	return doc.GetText(replayTextName).String()
+}
+
+func TestReplayService_PlaySeek(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         int64
+		wantState    string
+		wantChat     int
+		wantTerminal int
+		wantRest     int // state 之后、end 之前的帧数
+	}{
+		{name: "从头播放", from: 0, wantState: "", wantRest: 7},
+		{name: "跳转到中间", from: 250, wantState: "he", wantRest: 5},
+		{name: "跳转到结尾带上聊天和终端", from: 500, wantState: "hello", wantChat: 1, wantTerminal: 1},
+		{name: "超出时长按结尾处理", from: 9000, wantState: "hello", wantChat: 1, wantTerminal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, session := newReplayFixture(t)
+			svc := NewReplayService(nil, repo)
+
+			var frames []*ReplayFrame
+			err := svc.Play(context.Background(), session, &ReplayOptions{FromMS: tt.from, Speed: 16},
+				func(frame *ReplayFrame) error {
+					frames = append(frames, frame)
+					return nil
+				})
+			require.NoError(t, err)
+
+			require.GreaterOrEqual(t, len(frames), 2)
+			state, end := frames[0], frames[len(frames)-1]
+			assert.Equal(t, ReplayFrameState, state.Type)
+			assert.Equal(t, tt.wantState, replayText(t, state))
+			assert.Len(t, state.Chat, tt.wantChat)
+			assert.Len(t, state.Terminal, tt.wantTerminal)
+			assert.Equal(t, ReplayFrameEnd, end.Type)
+			assert.Equal(t, int64(500), end.OffsetMS)
+			assert.Len(t, frames[1:len(frames)-1], tt.wantRest)
+			assert.Equal(t, "hello", replayText(t, frames...))
+		})
+	}
+}
+
+func TestReplayService_PlayPacing(t *testing.T) {
+	repo, session := newReplayFixture(t)
+	svc := NewReplayService(nil, repo)
+
+	start := time.Now()
+	err := svc.Play(context.Background(), session, &ReplayOptions{Speed: 4}, func(*ReplayFrame) error { return nil })
+	require.NoError(t, err)
+
+	// 500ms 的会话 4 倍速播放约 125ms
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+	assert.Less(t, elapsed, 400*time.Millisecond)
+}
+
+func TestReplayService_PlayCancel(t *testing.T) {
+	repo, session := newReplayFixture(t)
+	svc := NewReplayService(nil, repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := svc.Play(ctx, session, &ReplayOptions{}, func(*ReplayFrame) error {
+		if count++; count == 2 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, count)
+}
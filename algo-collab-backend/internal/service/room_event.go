@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ListRoomEventsQuery 房间审计日志查询参数
+type ListRoomEventsQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Types    string `form:"type"` // 逗号分隔，例如 join,leave
+	ActorID  uint   `form:"actor_id"`
+}
+
+// RoomEventPage 审计日志分页结果
+type RoomEventPage struct {
+	Events   []*models.RoomEvent `json:"events"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+// recordRoomEvent 写入一条房间事件
+// 审计日志是旁路数据，写入失败只记录日志，不影响主流程
+func recordRoomEvent(ctx context.Context, repo repository.RoomEventRepository, roomID uint, actorID, targetID *uint, eventType string, data models.JSONMap) {
+	if repo == nil {
+		return
+	}
+
+	event := &models.RoomEvent{
+		RoomID:       roomID,
+		ActorID:      actorID,
+		TargetUserID: targetID,
+		Type:         eventType,
+		Data:         data,
+	}
+	if err := repo.Create(ctx, event); err != nil {
+		logger.Warn("写入房间事件失败",
+			zap.Uint("room_id", roomID),
+			zap.String("type", eventType),
+			zap.Error(err))
+	}
+}
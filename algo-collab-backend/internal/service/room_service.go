@@ -0,0 +1,607 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrRoomFull      = repository.ErrRoomFull
+	ErrRoomNotFound  = repository.ErrRoomNotFound
+	ErrRoomPassword  = errors.New("房间密码错误")
+	ErrNotRoomMember = errors.New("不是房间成员")
+	ErrRoomArchived  = errors.New("房间已归档，只读")
+	ErrRoomForbidden = errors.New("没有权限执行该操作")
+	ErrOwnerLeave    = errors.New("房主不能退出房间")
+)
+
+type RoomService interface {
+	CreateRoom(ctx context.Context, creatorID uint, req *CreateRoomRequest) (*models.Room, error)
+	GetRoom(ctx context.Context, uuid string) (*models.Room, error)
+	ListRooms(ctx context.Context, query *ListRoomsQuery) ([]*models.Room, error)
+	JoinRoom(ctx context.Context, uuid string, userID uint, password string) (*models.RoomMember, error)
+	LeaveRoom(ctx context.Context, uuid string, userID uint) error
+	GetMembers(ctx context.Context, uuid string) ([]*models.RoomMember, error)
+	// CheckMember 返回房间和成员信息，操作者不是成员时返回 ErrNotRoomMember
+	CheckMember(ctx context.Context, uuid string, userID uint) (*models.Room, *models.RoomMember, error)
+	UpdateMetadata(ctx context.Context, uuid string, userID uint, req *UpdateRoomMetadataRequest) (*models.Room, error)
+	UpdateProblem(ctx context.Context, uuid string, userID uint, req *UpdateRoomProblemRequest) (*models.Room, error)
+
+	// 成员管理
+
+	UpdateMemberRole(ctx context.Context, uuid string, operatorID, targetID uint, role string) error
+	KickMember(ctx context.Context, uuid string, operatorID, targetID uint) error
+
+	// 审计日志
+
+	ListEvents(ctx context.Context, uuid string, userID uint, query *ListRoomEventsQuery) (*RoomEventPage, error)
+
+	// 活跃度与归档
+
+	RecordActivity(ctx context.Context, uuid string, userID uint) error
+	ArchiveRoom(ctx context.Context, uuid string, userID uint) error
+	UnarchiveRoom(ctx context.Context, uuid string, userID uint) error
+}
+
+// MemberNotifier 把成员和房间状态的变化同步给协作房间中的在线连接，由 realtime.Hub 实现
+type MemberNotifier interface {
+	// DisconnectMember 断开成员在房间内的所有协作连接
+	DisconnectMember(ctx context.Context, room *models.Room, userID uint) error
+	// UpdateMemberRole 更新成员在线连接上的角色，降级的管理员立即失去管理员权限
+	UpdateMemberRole(ctx context.Context, room *models.Room, userID uint, role string) error
+	// CloseRoom 断开房间内的所有协作连接，归档或恢复后重连的连接按新状态决定是否只读
+	CloseRoom(ctx context.Context, room *models.Room) error
+}
+
+type roomService struct {
+	roomRepo  repository.RoomRepository
+	tagRepo   repository.TagRepository
+	eventRepo repository.RoomEventRepository
+	members   MemberNotifier
+}
+
+// 请求结构体
+
+type CreateRoomRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	Language    string `json:"language" binding:"required,oneof=python javascript go java cpp"`
+	MaxMembers  int    `json:"max_members" binding:"omitempty,min=1,max=100"`
+	IsPublic    *bool  `json:"is_public"`
+	Password    string `json:"password" binding:"omitempty,min=4,max=32"`
+
+	ProblemTitle   string `json:"problem_title" binding:"max=200"`
+	ProblemContent string `json:"problem_content"`
+	StarterCode    string `json:"starter_code"`
+
+	// OrganizationUUID 在组织下创建房间，控制器会把请求转交 OrganizationService.CreateRoom，
+	// 由其校验组织成员身份并应用默认/强制模板
+	OrganizationUUID string `json:"organization_uuid"`
+
+	// 由模板/组织流程填充，不接受客户端传入
+	OrganizationID *uint `json:"-"`
+	TemplateID     *uint `json:"-"`
+}
+
+type JoinRoomRequest struct {
+	Password string `json:"password"`
+}
+
+// ListRoomsQuery 房间列表查询参数
+type ListRoomsQuery struct {
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+	Difficulty string `form:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	Topic      string `form:"topic"`
+	Tags       string `form:"tags"` // 逗号分隔，需同时满足
+}
+
+// UpdateRoomMetadataRequest 修改房间分类信息（房间管理员）
+type UpdateRoomMetadataRequest struct {
+	Difficulty string   `json:"difficulty" binding:"omitempty,oneof=easy medium hard"`
+	Topic      string   `json:"topic"`
+	Tags       []string `json:"tags" binding:"max=10"`
+}
+
+// UpdateRoomProblemRequest 切换房间题目（房间管理员）
+type UpdateRoomProblemRequest struct {
+	ProblemTitle   string `json:"problem_title" binding:"required,max=200"`
+	ProblemContent string `json:"problem_content"`
+	StarterCode    string `json:"starter_code"`
+}
+
+// UpdateMemberRoleRequest 修改成员角色（房主），不能通过该接口转让房主
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+// NewRoomService members 为空时成员变化不会同步给在线连接
+func NewRoomService(roomRepo repository.RoomRepository, tagRepo repository.TagRepository, eventRepo repository.RoomEventRepository, members MemberNotifier) RoomService {
+	return &roomService{
+		roomRepo:  roomRepo,
+		tagRepo:   tagRepo,
+		eventRepo: eventRepo,
+		members:   members,
+	}
+}
+
+// CreateRoom 创建房间，创建者在同一事务中成为房主并占用一个席位
+func (s *roomService) CreateRoom(ctx context.Context, creatorID uint, req *CreateRoomRequest) (*models.Room, error) {
+	room := &models.Room{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatorID:   creatorID,
+		Language:    req.Language,
+		MaxMembers:  10,
+		IsPublic:    true,
+		Status:      models.RoomStatusActive,
+
+		OrganizationID: req.OrganizationID,
+		TemplateID:     req.TemplateID,
+		ProblemTitle:   req.ProblemTitle,
+		ProblemContent: req.ProblemContent,
+		StarterCode:    req.StarterCode,
+	}
+	if req.MaxMembers > 0 {
+		room.MaxMembers = req.MaxMembers
+	}
+	if req.IsPublic != nil {
+		room.IsPublic = *req.IsPublic
+	}
+	if req.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		room.Password = string(hashed)
+	}
+
+	if err := s.roomRepo.Create(ctx, room); err != nil {
+		logger.Error("创建房间失败", zap.Error(err))
+		return nil, errors.New("创建房间失败，请稍后重试")
+	}
+
+	return room, nil
+}
+
+// GetRoom 获取房间详情
+func (s *roomService) GetRoom(ctx context.Context, uuid string) (*models.Room, error) {
+	return s.roomRepo.FindByUUID(ctx, uuid)
+}
+
+// ListRooms 分页获取公开的活跃房间，支持按难度、主题、标签筛选
+func (s *roomService) ListRooms(ctx context.Context, query *ListRoomsQuery) ([]*models.Room, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.RoomFilter{
+		Difficulty: query.Difficulty,
+		Topic:      query.Topic,
+		Tags:       splitTags(query.Tags),
+	}
+	return s.roomRepo.FindActiveRooms(ctx, filter, pageSize, (page-1)*pageSize)
+}
+
+// JoinRoom 加入房间
+// 人数上限由 ReserveSeat 在数据库事务中保证，这里不再做"先查人数再插入"的检查
+func (s *roomService) JoinRoom(ctx context.Context, uuid string, userID uint, password string) (*models.RoomMember, error) {
+	// 1. 查找房间
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+
+	// 2. 校验密码（已经是成员的用户重新进入不需要密码）
+	if room.Password != "" {
+		isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember && bcrypt.CompareHashAndPassword([]byte(room.Password), []byte(password)) != nil {
+			return nil, ErrRoomPassword
+		}
+	}
+
+	// 3. 原子占座
+	member, err := s.roomRepo.ReserveSeat(ctx, room.ID, userID, models.RoomRoleMember)
+	if err != nil {
+		if !errors.Is(err, ErrRoomFull) {
+			logger.Error("加入房间失败", zap.String("room_uuid", uuid), zap.Uint("user_id", userID), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	logger.Info("用户加入房间",
+		zap.String("room_uuid", uuid),
+		zap.Uint("user_id", userID),
+	)
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventJoin, nil)
+	return member, nil
+}
+
+// LeaveRoom 退出房间（软删除成员记录，释放席位）
+func (s *roomService) LeaveRoom(ctx context.Context, uuid string, userID uint) error {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return ErrNotRoomMember
+	}
+	if member.Role == models.RoomRoleOwner {
+		return ErrOwnerLeave
+	}
+
+	if err := s.roomRepo.RemoveMember(ctx, room.ID, userID); err != nil {
+		return err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventLeave, nil)
+	return nil
+}
+
+// GetMembers 获取房间成员列表
+func (s *roomService) GetMembers(ctx context.Context, uuid string) ([]*models.RoomMember, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return s.roomRepo.GetMembers(ctx, room.ID)
+}
+
+// CheckMember 确认用户是房间的有效成员（协作连接鉴权使用）
+func (s *roomService) CheckMember(ctx context.Context, uuid string, userID uint) (*models.Room, *models.RoomMember, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, nil, ErrNotRoomMember
+	}
+	return room, member, nil
+}
+
+// UpdateMetadata 修改房间难度、主题和标签
+// 主题和标签都必须来自共享标签体系，主题只能使用 topic 分类的标签
+func (s *roomService) UpdateMetadata(ctx context.Context, uuid string, userID uint, req *UpdateRoomMetadataRequest) (*models.Room, error) {
+	// 1. 权限：房间管理员及以上
+	room, err := s.findRoomWithRole(ctx, uuid, userID, models.RoomRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+
+	// 2. 校验主题
+	if req.Topic != "" {
+		topic, err := s.tagRepo.FindByName(ctx, req.Topic)
+		if err != nil || topic.Category != models.TagCategoryTopic {
+			return nil, fmt.Errorf("未知的主题: %s", req.Topic)
+		}
+	}
+
+	// 3. 校验标签
+	tags, err := resolveTags(ctx, s.tagRepo, req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 保存
+	room.Difficulty = req.Difficulty
+	room.Topic = req.Topic
+	if err := s.roomRepo.Update(ctx, room); err != nil {
+		return nil, err
+	}
+	if err := s.roomRepo.ReplaceTags(ctx, room, tags); err != nil {
+		return nil, err
+	}
+	room.Tags = make([]models.Tag, 0, len(tags))
+	for _, tag := range tags {
+		room.Tags = append(room.Tags, *tag)
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventSettingsChange, models.JSONMap{
+		"difficulty": req.Difficulty,
+		"topic":      req.Topic,
+		"tags":       req.Tags,
+	})
+	return room, nil
+}
+
+// UpdateProblem 切换房间题目
+// 只修改房间上的题目信息，编辑器里已有的代码不会被 StarterCode 覆盖
+func (s *roomService) UpdateProblem(ctx context.Context, uuid string, userID uint, req *UpdateRoomProblemRequest) (*models.Room, error) {
+	room, err := s.findRoomWithRole(ctx, uuid, userID, models.RoomRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+
+	previous := room.ProblemTitle
+	room.ProblemTitle = req.ProblemTitle
+	room.ProblemContent = req.ProblemContent
+	room.StarterCode = req.StarterCode
+	if err := s.roomRepo.Update(ctx, room); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventProblemSwitch, models.JSONMap{
+		"from": previous,
+		"to":   req.ProblemTitle,
+	})
+	return room, nil
+}
+
+// UpdateMemberRole 房主修改成员角色（admin/member）
+func (s *roomService) UpdateMemberRole(ctx context.Context, uuid string, operatorID, targetID uint, role string) error {
+	if role != models.RoomRoleAdmin && role != models.RoomRoleMember {
+		return fmt.Errorf("无效的角色: %s", role)
+	}
+
+	room, err := s.findOwnedRoom(ctx, uuid, operatorID)
+	if err != nil {
+		return err
+	}
+	target, err := s.roomRepo.GetMember(ctx, room.ID, targetID)
+	if err != nil {
+		return ErrNotRoomMember
+	}
+	if target.Role == models.RoomRoleOwner {
+		return ErrRoomForbidden
+	}
+	if target.Role == role {
+		return nil
+	}
+
+	if err := s.roomRepo.UpdateMemberRole(ctx, room.ID, targetID, role); err != nil {
+		return err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &operatorID, &targetID, models.RoomEventRoleChange, models.JSONMap{
+		"from": target.Role,
+		"to":   role,
+	})
+
+	// 角色已经保存，同步失败只记录日志，成员重连后按新角色连接
+	if s.members != nil {
+		if err := s.members.UpdateMemberRole(ctx, room, targetID, role); err != nil {
+			logger.Warn("同步成员角色失败", zap.String("room_uuid", uuid), zap.Uint("target_id", targetID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// KickMember 把成员移出房间，只能移出角色比自己低的成员
+func (s *roomService) KickMember(ctx context.Context, uuid string, operatorID, targetID uint) error {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	operator, err := s.roomRepo.GetMember(ctx, room.ID, operatorID)
+	if err != nil {
+		return ErrNotRoomMember
+	}
+	target, err := s.roomRepo.GetMember(ctx, room.ID, targetID)
+	if err != nil {
+		return ErrNotRoomMember
+	}
+	if !operator.HasRole(models.RoomRoleAdmin) || target.HasRole(operator.Role) {
+		return ErrRoomForbidden
+	}
+
+	if err := s.roomRepo.RemoveMember(ctx, room.ID, targetID); err != nil {
+		return err
+	}
+
+	logger.Info("成员被移出房间",
+		zap.String("room_uuid", uuid),
+		zap.Uint("operator_id", operatorID),
+		zap.Uint("target_id", targetID))
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &operatorID, &targetID, models.RoomEventKick, nil)
+
+	// 已经移出房间，断开连接失败只记录日志，成员重连时会因不是成员被拒绝
+	if s.members != nil {
+		if err := s.members.DisconnectMember(ctx, room, targetID); err != nil {
+			logger.Warn("断开被移出成员的连接失败", zap.String("room_uuid", uuid), zap.Uint("target_id", targetID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// ListEvents 分页查询房间审计日志（房间管理员）
+func (s *roomService) ListEvents(ctx context.Context, uuid string, userID uint, query *ListRoomEventsQuery) (*RoomEventPage, error) {
+	room, err := s.findRoomWithRole(ctx, uuid, userID, models.RoomRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.RoomEventFilter{
+		Types:   splitTags(query.Types),
+		ActorID: query.ActorID,
+	}
+	events, total, err := s.eventRepo.List(ctx, room.ID, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoomEventPage{
+		Events:   events,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// RecordActivity 记录成员在房间内的活动，刷新 LastActiveAt
+// 只有成员可以刷新，否则任何登录用户都能让别人的房间一直不被归档
+func (s *roomService) RecordActivity(ctx context.Context, uuid string, userID uint) error {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotRoomMember
+	}
+	if room.IsArchived() {
+		return ErrRoomArchived
+	}
+	return s.roomRepo.TouchActivity(ctx, room.ID, userID)
+}
+
+// ArchiveRoom 房主手动归档房间
+func (s *roomService) ArchiveRoom(ctx context.Context, uuid string, userID uint) error {
+	room, err := s.findOwnedRoom(ctx, uuid, userID)
+	if err != nil {
+		return err
+	}
+	if room.IsArchived() {
+		return nil
+	}
+
+	now := time.Now()
+	room.Status = models.RoomStatusArchived
+	room.ArchivedAt = &now
+	if err := s.roomRepo.Update(ctx, room); err != nil {
+		return err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventSettingsChange, models.JSONMap{
+		"status": models.RoomStatusArchived,
+	})
+	s.closeRoom(ctx, room)
+	return nil
+}
+
+// UnarchiveRoom 房主恢复已归档的房间
+func (s *roomService) UnarchiveRoom(ctx context.Context, uuid string, userID uint) error {
+	room, err := s.findOwnedRoom(ctx, uuid, userID)
+	if err != nil {
+		return err
+	}
+	if !room.IsArchived() {
+		return nil
+	}
+
+	// 重置活跃时间，避免刚恢复就被清理任务再次归档
+	room.Status = models.RoomStatusActive
+	room.ArchivedAt = nil
+	room.LastActiveAt = time.Now()
+	if err := s.roomRepo.Update(ctx, room); err != nil {
+		return err
+	}
+
+	logger.Info("房间已恢复", zap.String("room_uuid", uuid), zap.Uint("user_id", userID))
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventSettingsChange, models.JSONMap{
+		"status": models.RoomStatusActive,
+	})
+	s.closeRoom(ctx, room)
+	return nil
+}
+
+// closeRoom 归档状态变化后断开在线连接，重连后按新状态决定是否只读
+// 状态已经保存，断开失败只记录日志，清理任务会断开仍连接在已归档房间上的连接
+func (s *roomService) closeRoom(ctx context.Context, room *models.Room) {
+	if s.members == nil {
+		return
+	}
+	if err := s.members.CloseRoom(ctx, room); err != nil {
+		logger.Warn("断开房间连接失败", zap.String("room_uuid", room.UUID), zap.Error(err))
+	}
+}
+
+// findOwnedRoom 查找房间并确认操作者是房主
+func (s *roomService) findOwnedRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	return s.findRoomWithRole(ctx, uuid, userID, models.RoomRoleOwner)
+}
+
+// findRoomWithRole 查找房间并确认操作者角色不低于 role
+func (s *roomService) findRoomWithRole(ctx context.Context, uuid string, userID uint, role string) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, ErrNotRoomMember
+	}
+	if !member.HasRole(role) {
+		return nil, ErrRoomForbidden
+	}
+	return room, nil
+}
+
+// splitTags 解析逗号分隔的标签参数
+func splitTags(raw string) []string {
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// resolveTags 把标签名转换成标签记录，任何一个不在标签体系中都返回错误
+func resolveTags(ctx context.Context, tagRepo repository.TagRepository, names []string) ([]*models.Tag, error) {
+	unique := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	tags, err := tagRepo.FindByNames(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) != len(unique) {
+		found := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			found[tag.Name] = true
+		}
+		for _, name := range unique {
+			if !found[name] {
+				return nil, fmt.Errorf("未知的标签: %s", name)
+			}
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,549 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockRoomRepository 模拟房间仓库
+type MockRoomRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoomRepository) Create(ctx context.Context, room *models.Room) error {
+	args := m.Called(ctx, room)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) FindByID(ctx context.Context, id uint) (*models.Room, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) FindByUUID(ctx context.Context, uuid string) (*models.Room, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) FindActiveRooms(ctx context.Context, filter *repository.RoomFilter, limit, offset int) ([]*models.Room, error) {
+	args := m.Called(ctx, filter, limit, offset)
+	return args.Get(0).([]*models.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) Update(ctx context.Context, room *models.Room) error {
+	args := m.Called(ctx, room)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ReplaceTags(ctx context.Context, room *models.Room, tags []*models.Tag) error {
+	args := m.Called(ctx, room, tags)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) AddMember(ctx context.Context, member *models.RoomMember) error {
+	args := m.Called(ctx, member)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) RemoveMember(ctx context.Context, roomID, userID uint) error {
+	args := m.Called(ctx, roomID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) GetMembers(ctx context.Context, roomID uint) ([]*models.RoomMember, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).([]*models.RoomMember), args.Error(1)
+}
+
+func (m *MockRoomRepository) GetMemberCount(ctx context.Context, roomID uint) (int64, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoomRepository) IsMember(ctx context.Context, roomID, userID uint) (bool, error) {
+	args := m.Called(ctx, roomID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) GetMember(ctx context.Context, roomID, userID uint) (*models.RoomMember, error) {
+	args := m.Called(ctx, roomID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMember), args.Error(1)
+}
+
+func (m *MockRoomRepository) UpdateMemberRole(ctx context.Context, roomID, userID uint, role string) error {
+	args := m.Called(ctx, roomID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) SetMutedUntil(ctx context.Context, roomID, userID uint, until *time.Time) error {
+	args := m.Called(ctx, roomID, userID, until)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ReserveSeat(ctx context.Context, roomID, userID uint, role string) (*models.RoomMember, error) {
+	args := m.Called(ctx, roomID, userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RoomMember), args.Error(1)
+}
+
+func (m *MockRoomRepository) TouchActivity(ctx context.Context, roomID, userID uint) error {
+	args := m.Called(ctx, roomID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ArchiveIdleRooms(ctx context.Context, inactiveBefore time.Time) (int64, error) {
+	args := m.Called(ctx, inactiveBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoomRepository) FindPurgeableRooms(ctx context.Context, archivedBefore time.Time, limit int) ([]*models.Room, error) {
+	args := m.Called(ctx, archivedBefore, limit)
+	return args.Get(0).([]*models.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) PurgeRoom(ctx context.Context, roomID uint) error {
+	args := m.Called(ctx, roomID)
+	return args.Error(0)
+}
+
+func TestRoomService_JoinRoom(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("room-pass"), bcrypt.DefaultCost)
+	publicRoom := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "public-room", MaxMembers: 2}
+	privateRoom := &models.Room{BaseModel: models.BaseModel{ID: 2}, UUID: "private-room", MaxMembers: 2, Password: string(hashedPassword)}
+	member := &models.RoomMember{RoomID: 1, UserID: 7, Role: models.RoomRoleMember}
+
+	tests := []struct {
+		name      string
+		uuid      string
+		password  string
+		mockSetup func(*MockRoomRepository)
+		wantErr   error
+	}{
+		{
+			name: "成功加入",
+			uuid: "public-room",
+			mockSetup: func(m *MockRoomRepository) {
+				m.On("FindByUUID", mock.Anything, "public-room").Return(publicRoom, nil)
+				m.On("ReserveSeat", mock.Anything, uint(1), uint(7), models.RoomRoleMember).Return(member, nil)
+			},
+		},
+		{
+			name: "房间已满",
+			uuid: "public-room",
+			mockSetup: func(m *MockRoomRepository) {
+				m.On("FindByUUID", mock.Anything, "public-room").Return(publicRoom, nil)
+				m.On("ReserveSeat", mock.Anything, uint(1), uint(7), models.RoomRoleMember).Return(nil, ErrRoomFull)
+			},
+			wantErr: ErrRoomFull,
+		},
+		{
+			name: "房间不存在",
+			uuid: "missing",
+			mockSetup: func(m *MockRoomRepository) {
+				m.On("FindByUUID", mock.Anything, "missing").Return(nil, ErrRoomNotFound)
+			},
+			wantErr: ErrRoomNotFound,
+		},
+		{
+			name:     "密码错误",
+			uuid:     "private-room",
+			password: "wrong",
+			mockSetup: func(m *MockRoomRepository) {
+				m.On("FindByUUID", mock.Anything, "private-room").Return(privateRoom, nil)
+				m.On("IsMember", mock.Anything, uint(2), uint(7)).Return(false, nil)
+			},
+			wantErr: ErrRoomPassword,
+		},
+		{
+			name:     "已是成员重新进入不校验密码",
+			uuid:     "private-room",
+			password: "",
+			mockSetup: func(m *MockRoomRepository) {
+				m.On("FindByUUID", mock.Anything, "private-room").Return(privateRoom, nil)
+				m.On("IsMember", mock.Anything, uint(2), uint(7)).Return(true, nil)
+				m.On("ReserveSeat", mock.Anything, uint(2), uint(7), models.RoomRoleMember).Return(member, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRoomRepository)
+			tt.mockSetup(mockRepo)
+
+			service := NewRoomService(mockRepo, nil, nil, nil)
+			got, err := service.JoinRoom(context.Background(), tt.uuid, 7, tt.password)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRoom_IsFull(t *testing.T) {
+	room := &models.Room{MaxMembers: 3}
+	assert.False(t, room.IsFull(2))
+	assert.True(t, room.IsFull(3))
+	assert.True(t, room.IsFull(4))
+
+	// MaxMembers <= 0 视为不限人数
+	unlimited := &models.Room{MaxMembers: 0}
+	assert.False(t, unlimited.IsFull(1000))
+}
+
+// fakeMemberNotifier 记录被断开连接的成员和房间
+type fakeMemberNotifier struct {
+	disconnected []uint
+	roles        map[uint]string
+	closed       []string
+}
+
+func (n *fakeMemberNotifier) DisconnectMember(_ context.Context, _ *models.Room, userID uint) error {
+	n.disconnected = append(n.disconnected, userID)
+	return nil
+}
+
+func (n *fakeMemberNotifier) UpdateMemberRole(_ context.Context, _ *models.Room, userID uint, role string) error {
+	if n.roles == nil {
+		n.roles = make(map[uint]string)
+	}
+	n.roles[userID] = role
+	return nil
+}
+
+func (n *fakeMemberNotifier) CloseRoom(_ context.Context, room *models.Room) error {
+	n.closed = append(n.closed, room.UUID)
+	return nil
+}
+
+func TestRoomService_KickMember(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room"}
+	owner := &models.RoomMember{RoomID: 1, UserID: 1, Role: models.RoomRoleOwner}
+	admin := &models.RoomMember{RoomID: 1, UserID: 2, Role: models.RoomRoleAdmin}
+	member := &models.RoomMember{RoomID: 1, UserID: 3, Role: models.RoomRoleMember}
+
+	tests := []struct {
+		name       string
+		operator   *models.RoomMember
+		target     *models.RoomMember
+		wantRemove bool
+		wantErr    error
+	}{
+		{name: "房主移出管理员", operator: owner, target: admin, wantRemove: true},
+		{name: "管理员移出成员", operator: admin, target: member, wantRemove: true},
+		{name: "管理员不能移出房主", operator: admin, target: owner, wantErr: ErrRoomForbidden},
+		{name: "成员不能移出成员", operator: member, target: &models.RoomMember{RoomID: 1, UserID: 4, Role: models.RoomRoleMember}, wantErr: ErrRoomForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRoomRepository)
+			mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+			mockRepo.On("GetMember", mock.Anything, uint(1), tt.operator.UserID).Return(tt.operator, nil)
+			mockRepo.On("GetMember", mock.Anything, uint(1), tt.target.UserID).Return(tt.target, nil)
+			if tt.wantRemove {
+				mockRepo.On("RemoveMember", mock.Anything, uint(1), tt.target.UserID).Return(nil)
+			}
+
+			members := &fakeMemberNotifier{}
+			service := NewRoomService(mockRepo, nil, nil, members)
+			err := service.KickMember(context.Background(), "room", tt.operator.UserID, tt.target.UserID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, members.disconnected)
+			} else {
+				assert.NoError(t, err)
+				// 被移出的成员的协作连接同时断开
+				assert.Equal(t, []uint{tt.target.UserID}, members.disconnected)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRoomService_UpdateMemberRole(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room"}
+	owner := &models.RoomMember{RoomID: 1, UserID: 1, Role: models.RoomRoleOwner}
+	admin := &models.RoomMember{RoomID: 1, UserID: 2, Role: models.RoomRoleAdmin}
+
+	mockRepo := new(MockRoomRepository)
+	mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+	mockRepo.On("GetMember", mock.Anything, uint(1), uint(1)).Return(owner, nil)
+	mockRepo.On("GetMember", mock.Anything, uint(1), uint(2)).Return(admin, nil)
+	mockRepo.On("UpdateMemberRole", mock.Anything, uint(1), uint(2), models.RoomRoleMember).Return(nil)
+
+	members := &fakeMemberNotifier{}
+	service := NewRoomService(mockRepo, nil, nil, members)
+
+	// 降级立即同步到在线连接，管理员权限（例如不受锁定区域限制）随之失效
+	assert.NoError(t, service.UpdateMemberRole(context.Background(), "room", 1, 2, models.RoomRoleMember))
+	assert.Equal(t, map[uint]string{2: models.RoomRoleMember}, members.roles)
+
+	// 只有房主可以修改角色
+	assert.ErrorIs(t, service.UpdateMemberRole(context.Background(), "room", 2, 1, models.RoomRoleMember), ErrRoomForbidden)
+	assert.Len(t, members.roles, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRoomService_ArchiveRoomClosesConnections(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room", Status: models.RoomStatusActive}
+	owner := &models.RoomMember{RoomID: 1, UserID: 1, Role: models.RoomRoleOwner}
+
+	mockRepo := new(MockRoomRepository)
+	mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+	mockRepo.On("GetMember", mock.Anything, uint(1), uint(1)).Return(owner, nil)
+	mockRepo.On("Update", mock.Anything, room).Return(nil)
+
+	members := &fakeMemberNotifier{}
+	service := NewRoomService(mockRepo, nil, nil, members)
+
+	// 归档和恢复后都断开在线连接，重连时按新状态决定是否只读
+	assert.NoError(t, service.ArchiveRoom(context.Background(), "room", 1))
+	assert.True(t, room.IsArchived())
+	assert.Equal(t, []string{"room"}, members.closed)
+	assert.NoError(t, service.UnarchiveRoom(context.Background(), "room", 1))
+	assert.False(t, room.IsArchived())
+	assert.Equal(t, []string{"room", "room"}, members.closed)
+
+	// 状态没有变化时不断开
+	assert.NoError(t, service.UnarchiveRoom(context.Background(), "room", 1))
+	assert.Len(t, members.closed, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRoomService_CreateRoom(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	// 房间和房主席位由 Create 在同一事务中写入，不再单独占座
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Room")).Return(nil)
+
+	service := NewRoomService(mockRepo, nil, nil, nil)
+	room, err := service.CreateRoom(context.Background(), 7, &CreateRoomRequest{Name: "二分查找", Language: "go"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), room.CreatorID)
+	assert.Equal(t, models.RoomStatusActive, room.Status)
+	mockRepo.AssertNotCalled(t, "ReserveSeat", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRoomService_OwnerCannotLeave(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room"}
+	mockRepo := new(MockRoomRepository)
+	mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+	mockRepo.On("GetMember", mock.Anything, uint(1), uint(1)).Return(&models.RoomMember{RoomID: 1, UserID: 1, Role: models.RoomRoleOwner}, nil)
+
+	service := NewRoomService(mockRepo, nil, nil, nil)
+	assert.ErrorIs(t, service.LeaveRoom(context.Background(), "room", 1), ErrOwnerLeave)
+	mockRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoomService_RecordActivity(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room", Status: models.RoomStatusActive}
+
+	mockRepo := new(MockRoomRepository)
+	mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+	mockRepo.On("IsMember", mock.Anything, uint(1), uint(7)).Return(true, nil)
+	mockRepo.On("IsMember", mock.Anything, uint(1), uint(8)).Return(false, nil)
+	mockRepo.On("TouchActivity", mock.Anything, uint(1), uint(7)).Return(nil)
+
+	service := NewRoomService(mockRepo, nil, nil, nil)
+	assert.NoError(t, service.RecordActivity(context.Background(), "room", 7))
+	// 非成员不能让房间保持活跃
+	assert.ErrorIs(t, service.RecordActivity(context.Background(), "room", 8), ErrNotRoomMember)
+	mockRepo.AssertNotCalled(t, "TouchActivity", mock.Anything, uint(1), uint(8))
+	mockRepo.AssertExpectations(t)
+}
+
+// memoryTagRepo 内存中的共享标签体系
+type memoryTagRepo struct {
+	tags  []*models.Tag
+	usage map[string]int64
+	// listed 记录最近一次 ListWithUsage 的参数
+	listed []interface{}
+}
+
+func newMemoryTagRepo() *memoryTagRepo {
+	repo := &memoryTagRepo{usage: make(map[string]int64)}
+	for _, tag := range []*models.Tag{
+		{Name: "图论", Category: models.TagCategoryTopic},
+		{Name: "动态规划", Category: models.TagCategoryTopic},
+		{Name: "bfs", Category: models.TagCategoryTechnique},
+		{Name: "双指针", Category: models.TagCategoryTechnique},
+	} {
+		_ = repo.Create(context.Background(), tag)
+	}
+	return repo
+}
+
+func (r *memoryTagRepo) Create(_ context.Context, tag *models.Tag) error {
+	for _, existing := range r.tags {
+		if existing.Name == tag.Name {
+			return errors.New("duplicate")
+		}
+	}
+	tag.ID = uint(len(r.tags) + 1)
+	r.tags = append(r.tags, tag)
+	return nil
+}
+
+func (r *memoryTagRepo) FindByNames(ctx context.Context, names []string) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	for _, name := range names {
+		if tag, err := r.FindByName(ctx, name); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *memoryTagRepo) FindByName(_ context.Context, name string) (*models.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *memoryTagRepo) ListWithUsage(_ context.Context, prefix, category string, limit int) ([]*models.TagUsage, error) {
+	r.listed = []interface{}{prefix, category, limit}
+	var result []*models.TagUsage
+	for _, tag := range r.tags {
+		if strings.HasPrefix(tag.Name, prefix) && (category == "" || tag.Category == category) {
+			result = append(result, &models.TagUsage{Tag: *tag, UsageCount: r.usage[tag.Name]})
+		}
+	}
+	return result, nil
+}
+
+func TestRoomService_ListRoomsFilter(t *testing.T) {
+	mockRepo := new(MockRoomRepository)
+	want := &repository.RoomFilter{Difficulty: "hard", Topic: "图论", Tags: []string{"bfs", "双指针"}}
+	mockRepo.On("FindActiveRooms", mock.Anything, want, 10, 10).Return([]*models.Room{{Name: "最短路"}}, nil)
+
+	service := NewRoomService(mockRepo, nil, nil, nil)
+	rooms, err := service.ListRooms(context.Background(), &ListRoomsQuery{
+		Page: 2, PageSize: 10, Difficulty: "hard", Topic: "图论", Tags: " bfs, ,双指针 ",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRoomService_UpdateMetadata(t *testing.T) {
+	newFixture := func(role string, status string) (*MockRoomRepository, RoomService) {
+		room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room", Status: status}
+		mockRepo := new(MockRoomRepository)
+		mockRepo.On("FindByUUID", mock.Anything, "room").Return(room, nil)
+		mockRepo.On("GetMember", mock.Anything, uint(1), uint(7)).Return(&models.RoomMember{RoomID: 1, UserID: 7, Role: role}, nil)
+		mockRepo.On("Update", mock.Anything, room).Return(nil).Maybe()
+		mockRepo.On("ReplaceTags", mock.Anything, room, mock.Anything).Return(nil).Maybe()
+		return mockRepo, NewRoomService(mockRepo, newMemoryTagRepo(), nil, nil)
+	}
+	ctx := context.Background()
+
+	t.Run("管理员修改难度主题和标签", func(t *testing.T) {
+		mockRepo, service := newFixture(models.RoomRoleAdmin, models.RoomStatusActive)
+		room, err := service.UpdateMetadata(ctx, "room", 7, &UpdateRoomMetadataRequest{
+			Difficulty: "medium", Topic: "图论", Tags: []string{"bfs", " bfs ", "双指针"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "medium", room.Difficulty)
+		assert.Equal(t, "图论", room.Topic)
+
+		// 重复标签去重后只保存一次
+		var names []string
+		for _, tag := range room.Tags {
+			names = append(names, tag.Name)
+		}
+		assert.Equal(t, []string{"bfs", "双指针"}, names)
+		mockRepo.AssertCalled(t, "ReplaceTags", mock.Anything, room, mock.MatchedBy(func(tags []*models.Tag) bool {
+			return len(tags) == 2
+		}))
+	})
+
+	t.Run("普通成员不能修改", func(t *testing.T) {
+		mockRepo, service := newFixture(models.RoomRoleMember, models.RoomStatusActive)
+		_, err := service.UpdateMetadata(ctx, "room", 7, &UpdateRoomMetadataRequest{Difficulty: "easy"})
+		assert.ErrorIs(t, err, ErrRoomForbidden)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("已归档房间不能修改", func(t *testing.T) {
+		_, service := newFixture(models.RoomRoleOwner, models.RoomStatusArchived)
+		_, err := service.UpdateMetadata(ctx, "room", 7, &UpdateRoomMetadataRequest{Difficulty: "easy"})
+		assert.ErrorIs(t, err, ErrRoomArchived)
+	})
+
+	invalid := []struct {
+		name string
+		req  *UpdateRoomMetadataRequest
+	}{
+		{name: "未知标签", req: &UpdateRoomMetadataRequest{Tags: []string{"bfs", "贪心"}}},
+		{name: "未知主题", req: &UpdateRoomMetadataRequest{Topic: "数论"}},
+		{name: "技巧标签不能作为主题", req: &UpdateRoomMetadataRequest{Topic: "双指针"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo, service := newFixture(models.RoomRoleOwner, models.RoomStatusActive)
+			_, err := service.UpdateMetadata(ctx, "room", 7, tt.req)
+			assert.Error(t, err)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "ReplaceTags", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTagService_ListTags(t *testing.T) {
+	tagRepo := newMemoryTagRepo()
+	tagRepo.usage["图论"] = 3
+	service := NewTagService(tagRepo)
+
+	tags, err := service.ListTags(context.Background(), &ListTagsQuery{Prefix: " 图", Limit: 500})
+	assert.NoError(t, err)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, "图论", tags[0].Name)
+		assert.Equal(t, int64(3), tags[0].UsageCount)
+	}
+	// 前缀去空白，超出范围的 limit 回落到默认值
+	assert.Equal(t, []interface{}{"图", "", 20}, tagRepo.listed)
+
+	_, err = service.CreateTag(context.Background(), &CreateTagRequest{Name: "图论"})
+	assert.Error(t, err)
+	tag, err := service.CreateTag(context.Background(), &CreateTagRequest{Name: " 贪心 "})
+	assert.NoError(t, err)
+	assert.Equal(t, "贪心", tag.Name)
+	assert.Equal(t, models.TagCategoryOther, tag.Category)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+)
+
+type TagService interface {
+	ListTags(ctx context.Context, query *ListTagsQuery) ([]*models.TagUsage, error)
+	CreateTag(ctx context.Context, req *CreateTagRequest) (*models.Tag, error)
+}
+
+type tagService struct {
+	tagRepo repository.TagRepository
+}
+
+// ListTagsQuery 标签查询参数（自动补全）
+type ListTagsQuery struct {
+	Prefix   string `form:"prefix"`
+	Category string `form:"category" binding:"omitempty,oneof=topic technique other"`
+	Limit    int    `form:"limit"`
+}
+
+// CreateTagRequest 新增标签（管理员）
+type CreateTagRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Category string `json:"category" binding:"omitempty,oneof=topic technique other"`
+}
+
+func NewTagService(tagRepo repository.TagRepository) TagService {
+	return &tagService{
+		tagRepo: tagRepo,
+	}
+}
+
+// ListTags 按使用次数倒序返回标签
+func (s *tagService) ListTags(ctx context.Context, query *ListTagsQuery) ([]*models.TagUsage, error) {
+	limit := query.Limit
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return s.tagRepo.ListWithUsage(ctx, strings.TrimSpace(query.Prefix), query.Category, limit)
+}
+
+// CreateTag 向标签体系中新增标签
+func (s *tagService) CreateTag(ctx context.Context, req *CreateTagRequest) (*models.Tag, error) {
+	tag := &models.Tag{
+		Name:     strings.TrimSpace(req.Name),
+		Category: req.Category,
+	}
+	if tag.Category == "" {
+		tag.Category = models.TagCategoryOther
+	}
+	if err := s.tagRepo.Create(ctx, tag); err != nil {
+		return nil, errors.New("标签已存在")
+	}
+	return tag, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTemplateNotFound  = repository.ErrTemplateNotFound
+	ErrTemplateForbidden = errors.New("无权使用该模板")
+)
+
+type TemplateService interface {
+	CreateTemplate(ctx context.Context, userID uint, req *SaveTemplateRequest) (*models.RoomTemplate, error)
+	SaveRoomAsTemplate(ctx context.Context, userID uint, roomUUID string, req *SaveTemplateRequest) (*models.RoomTemplate, error)
+	ListTemplates(ctx context.Context, userID uint, page, pageSize int) ([]*models.RoomTemplate, error)
+	GetTemplate(ctx context.Context, userID uint, uuid string) (*models.RoomTemplate, error)
+	DeleteTemplate(ctx context.Context, userID uint, uuid string) error
+	CreateRoomFromTemplate(ctx context.Context, userID uint, templateUUID string, req *CreateRoomFromTemplateRequest) (*models.Room, error)
+}
+
+type templateService struct {
+	templateRepo repository.TemplateRepository
+	orgRepo      repository.OrganizationRepository
+	roomRepo     repository.RoomRepository
+	roomService  RoomService
+}
+
+// 请求结构体
+
+// SaveTemplateRequest 创建模板 / 把房间另存为模板
+// 另存为模板时房间配置字段来自房间本身，请求中的配置字段会被忽略
+type SaveTemplateRequest struct {
+	Name             string `json:"name" binding:"required,max=100"`
+	Description      string `json:"description"`
+	Visibility       string `json:"visibility" binding:"omitempty,oneof=private team public"`
+	OrganizationUUID string `json:"organization_uuid"`
+
+	Language       string `json:"language" binding:"omitempty,oneof=python javascript go java cpp"`
+	MaxMembers     int    `json:"max_members" binding:"omitempty,min=1,max=100"`
+	IsPublic       *bool  `json:"is_public"`
+	ProblemTitle   string `json:"problem_title" binding:"max=200"`
+	ProblemContent string `json:"problem_content"`
+	StarterCode    string `json:"starter_code"`
+}
+
+// CreateRoomFromTemplateRequest 从模板创建房间时可以覆盖的字段
+type CreateRoomFromTemplateRequest struct {
+	Name     string `json:"name" binding:"max=100"`
+	Password string `json:"password" binding:"omitempty,min=4,max=32"`
+}
+
+func NewTemplateService(
+	templateRepo repository.TemplateRepository,
+	orgRepo repository.OrganizationRepository,
+	roomRepo repository.RoomRepository,
+	roomService RoomService,
+) TemplateService {
+	return &templateService{
+		templateRepo: templateRepo,
+		orgRepo:      orgRepo,
+		roomRepo:     roomRepo,
+		roomService:  roomService,
+	}
+}
+
+// CreateTemplate 直接创建模板
+func (s *templateService) CreateTemplate(ctx context.Context, userID uint, req *SaveTemplateRequest) (*models.RoomTemplate, error) {
+	if req.Language == "" {
+		return nil, errors.New("模板必须指定编程语言")
+	}
+
+	template := &models.RoomTemplate{
+		Name:           req.Name,
+		Description:    req.Description,
+		OwnerID:        userID,
+		Language:       req.Language,
+		MaxMembers:     10,
+		IsPublic:       true,
+		ProblemTitle:   req.ProblemTitle,
+		ProblemContent: req.ProblemContent,
+		StarterCode:    req.StarterCode,
+	}
+	if req.MaxMembers > 0 {
+		template.MaxMembers = req.MaxMembers
+	}
+	if req.IsPublic != nil {
+		template.IsPublic = *req.IsPublic
+	}
+
+	return s.saveTemplate(ctx, userID, template, req)
+}
+
+// SaveRoomAsTemplate 把已有房间的配置另存为模板
+func (s *templateService) SaveRoomAsTemplate(ctx context.Context, userID uint, roomUUID string, req *SaveTemplateRequest) (*models.RoomTemplate, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, roomUUID)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+
+	template := &models.RoomTemplate{
+		Name:           req.Name,
+		Description:    req.Description,
+		OwnerID:        userID,
+		Language:       room.Language,
+		MaxMembers:     room.MaxMembers,
+		IsPublic:       room.IsPublic,
+		ProblemTitle:   room.ProblemTitle,
+		ProblemContent: room.ProblemContent,
+		StarterCode:    room.StarterCode,
+	}
+
+	return s.saveTemplate(ctx, userID, template, req)
+}
+
+// saveTemplate 处理可见范围并落库
+func (s *templateService) saveTemplate(ctx context.Context, userID uint, template *models.RoomTemplate, req *SaveTemplateRequest) (*models.RoomTemplate, error) {
+	template.Visibility = req.Visibility
+	if template.Visibility == "" {
+		template.Visibility = models.TemplateVisibilityPrivate
+	}
+
+	// team 可见必须属于某个组织，且创建者是该组织成员
+	if req.OrganizationUUID != "" {
+		org, err := s.orgRepo.FindByUUID(ctx, req.OrganizationUUID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.orgRepo.GetMember(ctx, org.ID, userID); err != nil {
+			return nil, ErrOrganizationForbidden
+		}
+		template.OrganizationID = &org.ID
+	}
+	if template.Visibility == models.TemplateVisibilityTeam && template.OrganizationID == nil {
+		return nil, errors.New("团队模板必须指定所属组织")
+	}
+
+	if err := s.templateRepo.Create(ctx, template); err != nil {
+		logger.Error("保存模板失败", zap.Error(err))
+		return nil, errors.New("保存模板失败，请稍后重试")
+	}
+
+	logger.Info("模板已保存",
+		zap.String("template_uuid", template.UUID),
+		zap.String("visibility", template.Visibility),
+		zap.Uint("owner_id", userID))
+	return template, nil
+}
+
+// ListTemplates 列出用户可见的模板
+func (s *templateService) ListTemplates(ctx context.Context, userID uint, page, pageSize int) ([]*models.RoomTemplate, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	orgIDs, err := s.orgRepo.FindOrganizationIDsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.templateRepo.FindVisible(ctx, userID, orgIDs, pageSize, (page-1)*pageSize)
+}
+
+// GetTemplate 获取模板详情（校验可见性）
+func (s *templateService) GetTemplate(ctx context.Context, userID uint, uuid string) (*models.RoomTemplate, error) {
+	template, err := s.templateRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if !s.canView(ctx, userID, template) {
+		return nil, ErrTemplateForbidden
+	}
+	return template, nil
+}
+
+// DeleteTemplate 删除模板（仅创建者）
+// 仍被组织用作默认模板时，先取消这些组织的默认模板设置，避免组织建房失败
+func (s *templateService) DeleteTemplate(ctx context.Context, userID uint, uuid string) error {
+	template, err := s.templateRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if template.OwnerID != userID {
+		return ErrTemplateForbidden
+	}
+	if err := s.orgRepo.ClearDefaultTemplate(ctx, template.ID); err != nil {
+		return err
+	}
+	return s.templateRepo.Delete(ctx, template.ID)
+}
+
+// CreateRoomFromTemplate 一键从模板创建房间
+func (s *templateService) CreateRoomFromTemplate(ctx context.Context, userID uint, templateUUID string, req *CreateRoomFromTemplateRequest) (*models.Room, error) {
+	template, err := s.GetTemplate(ctx, userID, templateUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomReq := RoomRequestFromTemplate(template)
+	if req.Name != "" {
+		roomReq.Name = req.Name
+	}
+	roomReq.Password = req.Password
+
+	return s.roomService.CreateRoom(ctx, userID, roomReq)
+}
+
+// canView 模板可见性：创建者 / 公开 / 同组织成员
+func (s *templateService) canView(ctx context.Context, userID uint, template *models.RoomTemplate) bool {
+	switch {
+	case template.OwnerID == userID:
+		return true
+	case template.Visibility == models.TemplateVisibilityPublic:
+		return true
+	case template.Visibility == models.TemplateVisibilityTeam && template.OrganizationID != nil:
+		_, err := s.orgRepo.GetMember(ctx, *template.OrganizationID, userID)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// RoomRequestFromTemplate 把模板配置转换成创建房间请求
+func RoomRequestFromTemplate(template *models.RoomTemplate) *CreateRoomRequest {
+	isPublic := template.IsPublic
+	return &CreateRoomRequest{
+		Name:           template.Name,
+		Description:    template.Description,
+		Language:       template.Language,
+		MaxMembers:     template.MaxMembers,
+		IsPublic:       &isPublic,
+		ProblemTitle:   template.ProblemTitle,
+		ProblemContent: template.ProblemContent,
+		StarterCode:    template.StarterCode,
+		TemplateID:     &template.ID,
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryTemplateRepo 内存中的房间模板
+type memoryTemplateRepo struct {
+	templates []*models.RoomTemplate
+	nextID    uint
+}
+
+func (r *memoryTemplateRepo) Create(_ context.Context, template *models.RoomTemplate) error {
+	r.nextID++
+	template.ID = r.nextID
+	if template.UUID == "" {
+		template.UUID = fmt.Sprintf("template-%d", r.nextID)
+	}
+	r.templates = append(r.templates, template)
+	return nil
+}
+
+func (r *memoryTemplateRepo) FindByID(_ context.Context, id uint) (*models.RoomTemplate, error) {
+	for _, template := range r.templates {
+		if template.ID == id {
+			return template, nil
+		}
+	}
+	return nil, repository.ErrTemplateNotFound
+}
+
+func (r *memoryTemplateRepo) FindByUUID(_ context.Context, uuid string) (*models.RoomTemplate, error) {
+	for _, template := range r.templates {
+		if template.UUID == uuid {
+			return template, nil
+		}
+	}
+	return nil, repository.ErrTemplateNotFound
+}
+
+func (r *memoryTemplateRepo) FindVisible(_ context.Context, userID uint, orgIDs []uint, limit, offset int) ([]*models.RoomTemplate, error) {
+	var visible []*models.RoomTemplate
+	for _, template := range r.templates {
+		switch {
+		case template.OwnerID == userID,
+			template.Visibility == models.TemplateVisibilityPublic:
+			visible = append(visible, template)
+		case template.Visibility == models.TemplateVisibilityTeam && template.OrganizationID != nil:
+			for _, id := range orgIDs {
+				if id == *template.OrganizationID {
+					visible = append(visible, template)
+					break
+				}
+			}
+		}
+	}
+	return visible, nil
+}
+
+func (r *memoryTemplateRepo) Update(_ context.Context, _ *models.RoomTemplate) error {
+	return nil
+}
+
+func (r *memoryTemplateRepo) Delete(_ context.Context, id uint) error {
+	for i, template := range r.templates {
+		if template.ID == id {
+			r.templates = append(r.templates[:i], r.templates[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// memoryOrgRepo 内存中的组织及成员
+type memoryOrgRepo struct {
+	orgs    []*models.Organization
+	members []*models.OrganizationMember
+}
+
+func (r *memoryOrgRepo) Create(_ context.Context, org *models.Organization) error {
+	org.ID = uint(len(r.orgs) + 1)
+	if org.UUID == "" {
+		org.UUID = fmt.Sprintf("org-%d", org.ID)
+	}
+	r.orgs = append(r.orgs, org)
+	r.members = append(r.members, &models.OrganizationMember{OrganizationID: org.ID, UserID: org.OwnerID, Role: models.OrgRoleOwner})
+	return nil
+}
+
+func (r *memoryOrgRepo) FindByID(_ context.Context, id uint) (*models.Organization, error) {
+	for _, org := range r.orgs {
+		if org.ID == id {
+			return org, nil
+		}
+	}
+	return nil, repository.ErrOrganizationNotFound
+}
+
+func (r *memoryOrgRepo) FindByUUID(_ context.Context, uuid string) (*models.Organization, error) {
+	for _, org := range r.orgs {
+		if org.UUID == uuid {
+			return org, nil
+		}
+	}
+	return nil, repository.ErrOrganizationNotFound
+}
+
+func (r *memoryOrgRepo) Update(_ context.Context, _ *models.Organization) error {
+	return nil
+}
+
+func (r *memoryOrgRepo) ClearDefaultTemplate(_ context.Context, templateID uint) error {
+	for _, org := range r.orgs {
+		if org.DefaultTemplateID != nil && *org.DefaultTemplateID == templateID {
+			org.DefaultTemplateID = nil
+			org.EnforceTemplate = false
+		}
+	}
+	return nil
+}
+
+func (r *memoryOrgRepo) AddMember(_ context.Context, member *models.OrganizationMember) error {
+	r.members = append(r.members, member)
+	return nil
+}
+
+func (r *memoryOrgRepo) GetMember(_ context.Context, orgID, userID uint) (*models.OrganizationMember, error) {
+	for _, member := range r.members {
+		if member.OrganizationID == orgID && member.UserID == userID {
+			return member, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryOrgRepo) FindOrganizationIDsByUser(_ context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	for _, member := range r.members {
+		if member.UserID == userID {
+			ids = append(ids, member.OrganizationID)
+		}
+	}
+	return ids, nil
+}
+
+// newTemplateFixture 用户 1 创建组织 org-1，用户 2 是组织成员，用户 3 是外部用户
+func newTemplateFixture(t *testing.T) (TemplateService, *memoryTemplateRepo, *memoryOrgRepo, *MockRoomRepository) {
+	orgs := &memoryOrgRepo{}
+	require.NoError(t, orgs.Create(context.Background(), &models.Organization{Name: "算法组", OwnerID: 1}))
+	require.NoError(t, orgs.AddMember(context.Background(), &models.OrganizationMember{OrganizationID: 1, UserID: 2, Role: models.OrgRoleMember}))
+
+	templates := &memoryTemplateRepo{}
+	roomRepo := new(MockRoomRepository)
+	roomRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Room")).Return(nil)
+	return NewTemplateService(templates, orgs, roomRepo, NewRoomService(roomRepo, nil, nil, nil)), templates, orgs, roomRepo
+}
+
+func TestTemplateService_CRUD(t *testing.T) {
+	svc, templates, _, _ := newTemplateFixture(t)
+	ctx := context.Background()
+
+	_, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "缺语言"})
+	assert.Error(t, err)
+
+	template, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "动态规划", Language: "go", MaxMembers: 4})
+	require.NoError(t, err)
+	assert.Equal(t, models.TemplateVisibilityPrivate, template.Visibility)
+	assert.Equal(t, 4, template.MaxMembers)
+	assert.True(t, template.IsPublic)
+
+	got, err := svc.GetTemplate(ctx, 1, template.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, template.ID, got.ID)
+
+	room, err := svc.CreateRoomFromTemplate(ctx, 1, template.UUID, &CreateRoomFromTemplateRequest{Name: "周赛"})
+	require.NoError(t, err)
+	assert.Equal(t, "周赛", room.Name)
+	assert.Equal(t, "go", room.Language)
+	require.NotNil(t, room.TemplateID)
+	assert.Equal(t, template.ID, *room.TemplateID)
+
+	// 只有创建者可以删除
+	assert.ErrorIs(t, svc.DeleteTemplate(ctx, 2, template.UUID), ErrTemplateForbidden)
+	require.NoError(t, svc.DeleteTemplate(ctx, 1, template.UUID))
+	assert.Empty(t, templates.templates)
+	_, err = svc.GetTemplate(ctx, 1, template.UUID)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateService_Visibility(t *testing.T) {
+	svc, _, _, _ := newTemplateFixture(t)
+	ctx := context.Background()
+
+	private, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "私有", Language: "go"})
+	require.NoError(t, err)
+	team, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "团队", Language: "go", Visibility: models.TemplateVisibilityTeam, OrganizationUUID: "org-1"})
+	require.NoError(t, err)
+	public, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "公开", Language: "go", Visibility: models.TemplateVisibilityPublic})
+	require.NoError(t, err)
+
+	// 团队模板必须属于组织，且只能由组织成员创建
+	_, err = svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "无组织", Language: "go", Visibility: models.TemplateVisibilityTeam})
+	assert.Error(t, err)
+	_, err = svc.CreateTemplate(ctx, 3, &SaveTemplateRequest{Name: "外部", Language: "go", Visibility: models.TemplateVisibilityTeam, OrganizationUUID: "org-1"})
+	assert.ErrorIs(t, err, ErrOrganizationForbidden)
+
+	cases := []struct {
+		userID  uint
+		visible []string
+	}{
+		{userID: 1, visible: []string{private.UUID, team.UUID, public.UUID}},
+		{userID: 2, visible: []string{team.UUID, public.UUID}},
+		{userID: 3, visible: []string{public.UUID}},
+	}
+	for _, tc := range cases {
+		list, err := svc.ListTemplates(ctx, tc.userID, 1, 20)
+		require.NoError(t, err)
+		var uuids []string
+		for _, template := range list {
+			uuids = append(uuids, template.UUID)
+		}
+		assert.ElementsMatch(t, tc.visible, uuids, "user %d", tc.userID)
+
+		for _, template := range []*models.RoomTemplate{private, team, public} {
+			_, err := svc.GetTemplate(ctx, tc.userID, template.UUID)
+			if slices.Contains(tc.visible, template.UUID) {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrTemplateForbidden)
+			}
+		}
+	}
+
+	// 看不到的模板也不能用来创建房间
+	_, err = svc.CreateRoomFromTemplate(ctx, 3, private.UUID, &CreateRoomFromTemplateRequest{})
+	assert.ErrorIs(t, err, ErrTemplateForbidden)
+}
+
+func TestTemplateService_DeleteClearsOrganizationDefault(t *testing.T) {
+	svc, _, orgs, _ := newTemplateFixture(t)
+	ctx := context.Background()
+
+	template, err := svc.CreateTemplate(ctx, 1, &SaveTemplateRequest{Name: "默认", Language: "go", Visibility: models.TemplateVisibilityTeam, OrganizationUUID: "org-1"})
+	require.NoError(t, err)
+	orgs.orgs[0].DefaultTemplateID = &template.ID
+	orgs.orgs[0].EnforceTemplate = true
+
+	require.NoError(t, svc.DeleteTemplate(ctx, 1, template.UUID))
+	assert.Nil(t, orgs.orgs[0].DefaultTemplateID)
+	assert.False(t, orgs.orgs[0].EnforceTemplate)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/textdiff"
+	"go.uber.org/zap"
+)
+
+// ErrDocumentVersionNotFound 历史版本不存在
+var ErrDocumentVersionNotFound = repository.ErrDocumentVersionNotFound
+
+// diffContextLines 差异中每处修改前后保留的上下文行数
+const diffContextLines = 3
+
+// DocumentEditor 读取和修改房间的实时文档，由 realtime.Hub 实现
+type DocumentEditor interface {
+	// CodeText 房间当前的代码
+	CodeText(ctx context.Context, room *models.Room) (string, error)
+	// CodeAuthorship 房间当前的代码，以及每段代码由哪个 Yjs 客户端写入
+	CodeAuthorship(ctx context.Context, room *models.Room) (string, models.AuthorRuns, error)
+	// ReplaceCode 把代码修改为 text，作为一次编辑下发给所有在线成员
+	// 只有管理员可以恢复版本，不检查锁定区域
+	ReplaceCode(ctx context.Context, room *models.Room, text string) error
+}
+
+// VersionService 房间代码的历史版本：定期自动保存（见 realtime），成员也可以创建命名检查点
+// 版本只覆盖房间主文件，工作区的其他文件没有历史版本
+type VersionService interface {
+	ListVersions(ctx context.Context, uuid string, userID uint, query *ListVersionsQuery) (*DocumentVersionPage, error)
+	GetVersion(ctx context.Context, uuid string, userID, versionID uint) (*models.DocumentVersion, error)
+	// CreateCheckpoint 把当前代码保存为命名检查点
+	CreateCheckpoint(ctx context.Context, uuid string, userID uint, req *CreateCheckpointRequest) (*models.DocumentVersion, error)
+	// DiffVersion 对比历史版本和另一个版本（默认为当前代码）
+	DiffVersion(ctx context.Context, uuid string, userID, versionID uint, query *DiffVersionQuery) (*VersionDiff, error)
+	// RestoreVersion 恢复历史版本（房间管理员及以上），只改写主文件，返回恢复前自动保存的版本，可以用它撤销恢复
+	RestoreVersion(ctx context.Context, uuid string, userID, versionID uint) (*models.DocumentVersion, error)
+	// Blame 每一行代码的作者和每位作者的贡献占比，versionID 为 0 时统计当前代码
+	Blame(ctx context.Context, uuid string, userID, versionID uint) (*DocumentBlame, error)
+}
+
+type versionService struct {
+	roomRepo    repository.RoomRepository
+	versionRepo repository.DocumentVersionRepository
+	authorRepo  repository.DocumentAuthorRepository
+	eventRepo   repository.RoomEventRepository
+	editor      DocumentEditor
+}
+
+// ListVersionsQuery 版本列表的分页参数
+type ListVersionsQuery struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+// CreateCheckpointRequest 创建命名检查点，例如 "优化之前"
+type CreateCheckpointRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// DiffVersionQuery 对比的目标版本，为 0 时和当前代码对比
+type DiffVersionQuery struct {
+	Against uint `form:"against"`
+}
+
+// DocumentVersionPage 版本分页结果，不包含版本内容
+type DocumentVersionPage struct {
+	Versions []*models.DocumentVersion `json:"versions"`
+	Total    int64                     `json:"total"`
+	Page     int                       `json:"page"`
+	PageSize int                       `json:"page_size"`
+}
+
+// VersionDiff 从 From 到 To 的差异，To 为空表示当前代码
+type VersionDiff struct {
+	From  *models.DocumentVersion `json:"from"`
+	To    *models.DocumentVersion `json:"to"`
+	Stats textdiff.Stats          `json:"stats"`
+	Hunks []textdiff.Hunk         `json:"hunks"`
+}
+
+func NewVersionService(
+	roomRepo repository.RoomRepository,
+	versionRepo repository.DocumentVersionRepository,
+	authorRepo repository.DocumentAuthorRepository,
+	eventRepo repository.RoomEventRepository,
+	editor DocumentEditor,
+) VersionService {
+	return &versionService{
+		roomRepo:    roomRepo,
+		versionRepo: versionRepo,
+		authorRepo:  authorRepo,
+		eventRepo:   eventRepo,
+		editor:      editor,
+	}
+}
+
+// ListVersions 按时间倒序分页返回版本
+func (s *versionService) ListVersions(ctx context.Context, uuid string, userID uint, query *ListVersionsQuery) (*DocumentVersionPage, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	versions, total, err := s.versionRepo.List(ctx, room.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentVersionPage{
+		Versions: versions,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// GetVersion 返回版本及其内容
+func (s *versionService) GetVersion(ctx context.Context, uuid string, userID, versionID uint) (*models.DocumentVersion, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.findVersion(ctx, room, versionID)
+}
+
+// CreateCheckpoint 归档房间只读，不能创建检查点
+func (s *versionService) CreateCheckpoint(ctx context.Context, uuid string, userID uint, req *CreateCheckpointRequest) (*models.DocumentVersion, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.saveCurrent(ctx, room, userID, models.DocumentVersionCheckpoint, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventSnapshot, models.JSONMap{
+		"version_id": version.ID,
+		"name":       version.Name,
+	})
+	return version, nil
+}
+
+func (s *versionService) DiffVersion(ctx context.Context, uuid string, userID, versionID uint, query *DiffVersionQuery) (*VersionDiff, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.findVersion(ctx, room, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *models.DocumentVersion
+	var target string
+	if query.Against == 0 {
+		if target, err = s.editor.CodeText(ctx, room); err != nil {
+			return nil, err
+		}
+	} else {
+		if to, err = s.findVersion(ctx, room, query.Against); err != nil {
+			return nil, err
+		}
+		target = to.Content
+	}
+
+	lines := textdiff.Lines(from.Content, target)
+	return &VersionDiff{
+		From:  withoutContent(from),
+		To:    withoutContent(to),
+		Stats: textdiff.Summarize(lines),
+		Hunks: textdiff.Hunks(lines, diffContextLines),
+	}, nil
+}
+
+// RestoreVersion 恢复前先保存当前代码，再把代码修改为历史版本的内容
+// 恢复是一次普通的编辑，在线成员立即看到，也可以继续用编辑器撤销
+func (s *versionService) RestoreVersion(ctx context.Context, uuid string, userID, versionID uint) (*models.DocumentVersion, error) {
+	room, err := s.findRestorableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.findVersion(ctx, room, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 保存当前代码
+	backup, err := s.saveCurrent(ctx, room, userID, models.DocumentVersionRestore, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 修改实时文档
+	if err := s.editor.ReplaceCode(ctx, room, version.Content); err != nil {
+		return nil, err
+	}
+
+	logger.Info("恢复历史版本",
+		zap.String("room_uuid", uuid),
+		zap.Uint("user_id", userID),
+		zap.Uint("version_id", versionID))
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventRestore, models.JSONMap{
+		"version_id": versionID,
+		"backup_id":  backup.ID,
+	})
+	return backup, nil
+}
+
+// Blame 作者信息随版本一起保存，统计时再对应到用户
+func (s *versionService) Blame(ctx context.Context, uuid string, userID, versionID uint) (*DocumentBlame, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *models.DocumentVersion
+	var content string
+	var runs models.AuthorRuns
+	if versionID == 0 {
+		if content, runs, err = s.editor.CodeAuthorship(ctx, room); err != nil {
+			return nil, err
+		}
+	} else {
+		if version, err = s.findVersion(ctx, room, versionID); err != nil {
+			return nil, err
+		}
+		content, runs = version.Content, version.Authorship
+	}
+
+	authors, err := s.authorRepo.ListByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	blame := buildBlame(content, runs, authors)
+	blame.Version = withoutContent(version)
+	return blame, nil
+}
+
+// saveCurrent 把当前代码及其作者信息保存为一个版本
+func (s *versionService) saveCurrent(ctx context.Context, room *models.Room, userID uint, kind, name string) (*models.DocumentVersion, error) {
+	text, authorship, err := s.editor.CodeAuthorship(ctx, room)
+	if err != nil {
+		return nil, err
+	}
+	version := &models.DocumentVersion{
+		RoomID:     room.ID,
+		Kind:       kind,
+		Name:       name,
+		TextName:   models.MainTextName,
+		Content:    text,
+		Size:       len(text),
+		Authorship: authorship,
+		CreatedBy:  &userID,
+	}
+	if err := s.versionRepo.Create(ctx, version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// findVersion 版本必须属于该房间
+func (s *versionService) findVersion(ctx context.Context, room *models.Room, versionID uint) (*models.DocumentVersion, error) {
+	version, err := s.versionRepo.FindByID(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if version.RoomID != room.ID {
+		return nil, ErrDocumentVersionNotFound
+	}
+	return version, nil
+}
+
+func (s *versionService) findRoomAsMember(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return room, nil
+}
+
+// findWritableRoom 修改代码和编辑器的权限一致：成员即可，归档房间只读
+func (s *versionService) findWritableRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
+
+// findRestorableRoom 恢复会整体覆盖所有人的代码，只有管理员及以上可以执行，归档房间只读
+func (s *versionService) findRestorableRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, ErrNotRoomMember
+	}
+	if !member.HasRole(models.RoomRoleAdmin) {
+		return nil, ErrRoomForbidden
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
+
+// withoutContent 差异中已经包含内容，版本信息不再重复返回
+func withoutContent(version *models.DocumentVersion) *models.DocumentVersion {
+	if version == nil {
+		return nil
+	}
+	copied := *version
+	copied.Content = ""
+	return &copied
+}
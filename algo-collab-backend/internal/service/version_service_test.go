@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/pkg/textdiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDocumentVersionRepository 模拟历史版本仓库
+type MockDocumentVersionRepository struct {
+	mock.Mock
+}
+
+func (m *MockDocumentVersionRepository) Create(ctx context.Context, version *models.DocumentVersion) error {
+	args := m.Called(ctx, version)
+	return args.Error(0)
+}
+
+func (m *MockDocumentVersionRepository) FindByID(ctx context.Context, id uint) (*models.DocumentVersion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentVersion), args.Error(1)
+}
+
+func (m *MockDocumentVersionRepository) List(ctx context.Context, roomID uint, limit, offset int) ([]*models.DocumentVersion, int64, error) {
+	args := m.Called(ctx, roomID, limit, offset)
+	return args.Get(0).([]*models.DocumentVersion), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDocumentVersionRepository) Latest(ctx context.Context, roomID uint, kind string) (*models.DocumentVersion, error) {
+	args := m.Called(ctx, roomID, kind)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DocumentVersion), args.Error(1)
+}
+
+func (m *MockDocumentVersionRepository) PruneAuto(ctx context.Context, roomID uint, keep int) error {
+	args := m.Called(ctx, roomID, keep)
+	return args.Error(0)
+}
+
+// fakeEditor 内存中的实时文档，runs 为当前代码的作者信息
+type fakeEditor struct {
+	text string
+	runs models.AuthorRuns
+}
+
+func (e *fakeEditor) CodeText(context.Context, *models.Room) (string, error) {
+	return e.text, nil
+}
+
+func (e *fakeEditor) CodeAuthorship(context.Context, *models.Room) (string, models.AuthorRuns, error) {
+	return e.text, e.runs, nil
+}
+
+func (e *fakeEditor) ReplaceCode(_ context.Context, _ *models.Room, text string) error {
+	e.text = text
+	return nil
+}
+
+// fakeAuthorRepo 固定的文档作者
+type fakeAuthorRepo struct {
+	authors []*models.DocumentAuthor
+}
+
+func (r *fakeAuthorRepo) Claim(context.Context, uint, uint64, uint) error {
+	return nil
+}
+
+func (r *fakeAuthorRepo) ListByRoom(context.Context, uint) ([]*models.DocumentAuthor, error) {
+	return r.authors, nil
+}
+
+func newVersionRoomRepo(room *models.Room) *MockRoomRepository {
+	roomRepo := new(MockRoomRepository)
+	roomRepo.On("FindByUUID", mock.Anything, room.UUID).Return(room, nil)
+	roomRepo.On("IsMember", mock.Anything, room.ID, uint(1)).Return(true, nil)
+	roomRepo.On("IsMember", mock.Anything, room.ID, mock.Anything).Return(false, nil)
+	return roomRepo
+}
+
+func TestVersionService_CreateCheckpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		userID  uint
+		wantErr error
+	}{
+		{name: "成员创建检查点", status: models.RoomStatusActive, userID: 1},
+		{name: "非成员", status: models.RoomStatusActive, userID: 2, wantErr: ErrNotRoomMember},
+		{name: "归档房间只读", status: models.RoomStatusArchived, userID: 1, wantErr: ErrRoomArchived},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: tt.status}
+			versionRepo := new(MockDocumentVersionRepository)
+			versionRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			svc := NewVersionService(newVersionRoomRepo(room), versionRepo, nil, nil, &fakeEditor{text: "return 0"})
+
+			version, err := svc.CreateCheckpoint(context.Background(), "room-1", tt.userID, &CreateCheckpointRequest{Name: "优化之前"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				versionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, models.DocumentVersionCheckpoint, version.Kind)
+			assert.Equal(t, "优化之前", version.Name)
+			assert.Equal(t, "return 0", version.Content)
+			assert.Equal(t, uint(1), *version.CreatedBy)
+		})
+	}
+}
+
+func TestVersionService_DiffVersion(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: models.RoomStatusActive}
+	versionRepo := new(MockDocumentVersionRepository)
+	versionRepo.On("FindByID", mock.Anything, uint(10)).Return(&models.DocumentVersion{ID: 10, RoomID: 1, Content: "a\nb\nc"}, nil)
+	versionRepo.On("FindByID", mock.Anything, uint(11)).Return(&models.DocumentVersion{ID: 11, RoomID: 1, Content: "a\nc"}, nil)
+	versionRepo.On("FindByID", mock.Anything, uint(20)).Return(&models.DocumentVersion{ID: 20, RoomID: 2}, nil)
+	svc := NewVersionService(newVersionRoomRepo(room), versionRepo, nil, nil, &fakeEditor{text: "a\nB\nc\nd"})
+
+	// 默认和当前代码对比
+	diff, err := svc.DiffVersion(context.Background(), "room-1", 1, 10, &DiffVersionQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, textdiff.Stats{Additions: 2, Deletions: 1}, diff.Stats)
+	assert.Nil(t, diff.To)
+	assert.Empty(t, diff.From.Content)
+	require.Len(t, diff.Hunks, 1)
+
+	diff, err = svc.DiffVersion(context.Background(), "room-1", 1, 10, &DiffVersionQuery{Against: 11})
+	require.NoError(t, err)
+	assert.Equal(t, textdiff.Stats{Deletions: 1}, diff.Stats)
+	assert.Equal(t, uint(11), diff.To.ID)
+
+	// 其他房间的版本
+	_, err = svc.DiffVersion(context.Background(), "room-1", 1, 20, &DiffVersionQuery{})
+	assert.ErrorIs(t, err, ErrDocumentVersionNotFound)
+}
+
+func TestVersionService_RestoreVersion(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: models.RoomStatusActive}
+	versionRepo := new(MockDocumentVersionRepository)
+	versionRepo.On("FindByID", mock.Anything, uint(10)).Return(&models.DocumentVersion{ID: 10, RoomID: 1, Content: "old"}, nil)
+	versionRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	editor := &fakeEditor{text: "broken"}
+	roomRepo := newVersionRoomRepo(room)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(1)).Return(&models.RoomMember{Role: models.RoomRoleAdmin}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(3)).Return(&models.RoomMember{Role: models.RoomRoleMember}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, mock.Anything).Return(nil, errors.New("not found"))
+	svc := NewVersionService(roomRepo, versionRepo, nil, nil, editor)
+
+	// 普通成员和非成员不能恢复
+	_, err := svc.RestoreVersion(context.Background(), "room-1", 3, 10)
+	assert.ErrorIs(t, err, ErrRoomForbidden)
+	_, err = svc.RestoreVersion(context.Background(), "room-1", 2, 10)
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+	assert.Equal(t, "broken", editor.text)
+	versionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	backup, err := svc.RestoreVersion(context.Background(), "room-1", 1, 10)
+	require.NoError(t, err)
+	// 恢复前的主文件被保存下来，实时文档改为历史版本
+	assert.Equal(t, models.DocumentVersionRestore, backup.Kind)
+	assert.Equal(t, models.MainTextName, backup.TextName)
+	assert.Equal(t, "broken", backup.Content)
+	assert.Equal(t, "old", editor.text)
+}
+
+func TestVersionService_Blame(t *testing.T) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: models.RoomStatusActive}
+	// 客户端 11、12 属于用户 1，13 属于用户 2，99 没有作者（服务端写入）
+	authors := &fakeAuthorRepo{authors: []*models.DocumentAuthor{
+		{ClientID: 11, UserID: 1},
+		{ClientID: 12, UserID: 1},
+		{ClientID: 13, UserID: 2},
+	}}
+	editor := &fakeEditor{
+		// "def f():" 由 99 写入，"\n    return " 由 11 写入，"x+y" 由 13 写入，"\n" 由 12 写入
+		text: "def f():\n    return x+y\n",
+		runs: models.AuthorRuns{{Client: 99, Length: 8}, {Client: 11, Length: 12}, {Client: 13, Length: 3}, {Client: 12, Length: 1}},
+	}
+	versionRepo := new(MockDocumentVersionRepository)
+	versionRepo.On("FindByID", mock.Anything, uint(10)).Return(&models.DocumentVersion{ID: 10, RoomID: 1, Content: "ab\nc"}, nil)
+	svc := NewVersionService(newVersionRoomRepo(room), versionRepo, authors, nil, editor)
+
+	blame, err := svc.Blame(context.Background(), "room-1", 1, 0)
+	require.NoError(t, err)
+	assert.Nil(t, blame.Version)
+	assert.Equal(t, 16, blame.TotalChars) // 空白不计入
+
+	require.Len(t, blame.Lines, 3)
+	assert.Nil(t, blame.Lines[0].UserID)
+	require.NotNil(t, blame.Lines[1].UserID)
+	assert.Equal(t, uint(1), *blame.Lines[1].UserID) // return 6 个字符多于 x+y
+	require.Len(t, blame.Lines[1].Authors, 2)
+	assert.Equal(t, 3, blame.Lines[1].Authors[1].Chars)
+	assert.Empty(t, blame.Lines[2].Authors)
+
+	require.Len(t, blame.Authors, 3)
+	assert.Nil(t, blame.Authors[0].UserID)
+	assert.Equal(t, 7, blame.Authors[0].Chars)
+	assert.Equal(t, uint(1), *blame.Authors[1].UserID)
+	assert.InDelta(t, 37.5, blame.Authors[1].Percent, 0.001)
+	assert.Equal(t, uint(2), *blame.Authors[2].UserID)
+
+	// 没有作者信息的旧版本全部视为没有作者
+	blame, err = svc.Blame(context.Background(), "room-1", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint(10), blame.Version.ID)
+	require.Len(t, blame.Authors, 1)
+	assert.Nil(t, blame.Authors[0].UserID)
+	assert.InDelta(t, 100, blame.Authors[0].Percent, 0.001)
+
+	// 非成员
+	_, err = svc.Blame(context.Background(), "room-1", 2, 0)
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+}
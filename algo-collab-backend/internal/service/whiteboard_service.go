@@ -0,0 +1,369 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"github.com/is-Xiaoen/algo-collab/pkg/whiteboard"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrWhiteboardNotFound 白板不存在
+	ErrWhiteboardNotFound = repository.ErrWhiteboardNotFound
+	ErrWhiteboardTitle    = errors.New("白板标题无效")
+	ErrTooManyWhiteboards = errors.New("白板数量超出限制")
+	ErrWhiteboardFormat   = errors.New("不支持的导出格式")
+)
+
+const (
+	// defaultMaxWhiteboards 每个房间默认最多的白板数
+	defaultMaxWhiteboards = 10
+	maxWhiteboardTitle    = 100
+	// defaultWhiteboardTitle 没有填写标题时使用的标题
+	defaultWhiteboardTitle = "白板"
+)
+
+// 白板导出格式
+const (
+	WhiteboardFormatSVG = "svg"
+	WhiteboardFormatPNG = "png"
+)
+
+// WhiteboardEditor 读取白板文档、断开已删除白板的连接并推送白板列表，由 realtime.Hub 实现
+type WhiteboardEditor interface {
+	// WhiteboardShapes 读取白板文档中的所有图形
+	WhiteboardShapes(ctx context.Context, room *models.Room, boardID uint) (map[string]any, error)
+	// CloseWhiteboard 断开白板的所有连接，丢弃内存中的文档
+	CloseWhiteboard(ctx context.Context, room *models.Room, boardID uint) error
+	// BroadcastWhiteboards 把新的白板列表推送给所有在线成员
+	BroadcastWhiteboards(ctx context.Context, room *models.Room, boards []*models.RoomWhiteboard) error
+}
+
+// WhiteboardService 房间的协作白板：白板列表保存在数据库，每块白板的图形是一份独立的协作文档，
+// 和代码一样实时同步。白板可以关联到评论讨论，也可以导出为 SVG 或 PNG
+type WhiteboardService interface {
+	ListWhiteboards(ctx context.Context, uuid string, userID uint) ([]*models.RoomWhiteboard, error)
+	CreateWhiteboard(ctx context.Context, uuid string, userID uint, req *CreateWhiteboardRequest) (*models.RoomWhiteboard, error)
+	// UpdateWhiteboard 修改标题或关联的讨论，协作中的图形不受影响
+	UpdateWhiteboard(ctx context.Context, uuid string, userID, boardID uint, req *UpdateWhiteboardRequest) (*models.RoomWhiteboard, error)
+	// DeleteWhiteboard 删除白板及其文档
+	DeleteWhiteboard(ctx context.Context, uuid string, userID, boardID uint) error
+	// ExportWhiteboard 把白板当前的图形导出为 format（svg 或 png）
+	ExportWhiteboard(ctx context.Context, uuid string, userID, boardID uint, format string) (*WhiteboardExport, error)
+	// FindWhiteboard 查找房间中的白板，建立白板协作连接前调用，成员身份由调用方校验
+	FindWhiteboard(ctx context.Context, room *models.Room, boardID uint) (*models.RoomWhiteboard, error)
+}
+
+type whiteboardService struct {
+	roomRepo    repository.RoomRepository
+	boardRepo   repository.RoomWhiteboardRepository
+	docRepo     repository.WhiteboardDocumentRepository
+	commentRepo repository.RoomCommentRepository
+	eventRepo   repository.RoomEventRepository
+	editor      WhiteboardEditor
+	cfg         *config.WhiteboardConfig
+}
+
+// CreateWhiteboardRequest 新建白板，ThreadID 不为空时关联到该评论讨论
+type CreateWhiteboardRequest struct {
+	Title    string `json:"title"`
+	ThreadID *uint  `json:"thread_id"`
+}
+
+// UpdateWhiteboardRequest 修改白板，为空的字段不修改；ThreadID 为 0 表示取消关联
+type UpdateWhiteboardRequest struct {
+	Title    *string `json:"title"`
+	ThreadID *uint   `json:"thread_id"`
+}
+
+// WhiteboardExport 导出的白板文件
+type WhiteboardExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+func NewWhiteboardService(
+	roomRepo repository.RoomRepository,
+	boardRepo repository.RoomWhiteboardRepository,
+	docRepo repository.WhiteboardDocumentRepository,
+	commentRepo repository.RoomCommentRepository,
+	eventRepo repository.RoomEventRepository,
+	editor WhiteboardEditor,
+	cfg *config.WhiteboardConfig,
+) WhiteboardService {
+	return &whiteboardService{
+		roomRepo:    roomRepo,
+		boardRepo:   boardRepo,
+		docRepo:     docRepo,
+		commentRepo: commentRepo,
+		eventRepo:   eventRepo,
+		editor:      editor,
+		cfg:         cfg,
+	}
+}
+
+func (s *whiteboardService) ListWhiteboards(ctx context.Context, uuid string, userID uint) ([]*models.RoomWhiteboard, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.boardRepo.ListByRoom(ctx, room.ID)
+}
+
+// CreateWhiteboard 白板文档在第一次连接时创建
+func (s *whiteboardService) CreateWhiteboard(ctx context.Context, roomUUID string, userID uint, req *CreateWhiteboardRequest) (*models.RoomWhiteboard, error) {
+	room, err := s.findWritableRoom(ctx, roomUUID, userID)
+	if err != nil {
+		return nil, err
+	}
+	title, err := cleanWhiteboardTitle(req.Title)
+	if err != nil {
+		return nil, err
+	}
+	if req.ThreadID != nil {
+		if err := s.checkThread(ctx, room, *req.ThreadID); err != nil {
+			return nil, err
+		}
+	}
+
+	count, err := s.boardRepo.CountByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.maxBoards()) {
+		return nil, ErrTooManyWhiteboards
+	}
+
+	board := &models.RoomWhiteboard{
+		RoomID:    room.ID,
+		Title:     title,
+		ThreadID:  req.ThreadID,
+		CreatedBy: &userID,
+	}
+	if err := s.boardRepo.Create(ctx, board); err != nil {
+		return nil, err
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventBoardCreate, models.JSONMap{
+		"whiteboard_id": board.ID,
+		"title":         board.Title,
+	})
+	s.broadcast(ctx, room)
+	return board, nil
+}
+
+func (s *whiteboardService) UpdateWhiteboard(ctx context.Context, uuid string, userID, boardID uint, req *UpdateWhiteboardRequest) (*models.RoomWhiteboard, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.findBoard(ctx, room, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	title, threadID := board.Title, board.ThreadID
+	if req.Title != nil {
+		if title, err = cleanWhiteboardTitle(*req.Title); err != nil {
+			return nil, err
+		}
+	}
+	if req.ThreadID != nil {
+		threadID = nil
+		if *req.ThreadID != 0 {
+			if err := s.checkThread(ctx, room, *req.ThreadID); err != nil {
+				return nil, err
+			}
+			threadID = req.ThreadID
+		}
+	}
+
+	if err := s.boardRepo.Update(ctx, board.ID, title, threadID); err != nil {
+		return nil, err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventBoardUpdate, models.JSONMap{
+		"whiteboard_id": board.ID,
+		"title":         title,
+		"thread_id":     threadID,
+	})
+	board.Title, board.ThreadID = title, threadID
+	s.broadcast(ctx, room)
+	return board, nil
+}
+
+// DeleteWhiteboard 先删除白板，之后的连接找不到白板；再断开已有的连接并丢弃内存中的文档，
+// 最后删除文档，回收连接时不会把文档重新写回
+func (s *whiteboardService) DeleteWhiteboard(ctx context.Context, uuid string, userID, boardID uint) error {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return err
+	}
+	board, err := s.findBoard(ctx, room, boardID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.boardRepo.Delete(ctx, board.ID); err != nil {
+		return err
+	}
+	if err := s.editor.CloseWhiteboard(ctx, room, board.ID); err != nil {
+		logger.Warn("断开已删除白板的连接失败",
+			zap.String("room_uuid", uuid),
+			zap.Uint("whiteboard_id", board.ID),
+			zap.Error(err))
+	}
+	if err := s.docRepo.Delete(ctx, board.ID); err != nil {
+		logger.Warn("删除白板文档失败",
+			zap.String("room_uuid", uuid),
+			zap.Uint("whiteboard_id", board.ID),
+			zap.Error(err))
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventBoardDelete, models.JSONMap{
+		"whiteboard_id": board.ID,
+		"title":         board.Title,
+	})
+	s.broadcast(ctx, room)
+	return nil
+}
+
+// ExportWhiteboard 成员都可以导出，归档房间也可以
+func (s *whiteboardService) ExportWhiteboard(ctx context.Context, uuid string, userID, boardID uint, format string) (*WhiteboardExport, error) {
+	if format != WhiteboardFormatSVG && format != WhiteboardFormatPNG {
+		return nil, ErrWhiteboardFormat
+	}
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.findBoard(ctx, room, boardID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.editor.WhiteboardShapes(ctx, room, board.ID)
+	if err != nil {
+		return nil, err
+	}
+	shapes := whiteboard.ParseShapes(entries)
+
+	var buf bytes.Buffer
+	export := &WhiteboardExport{Filename: fmt.Sprintf("whiteboard-%d.%s", board.ID, format)}
+	if format == WhiteboardFormatSVG {
+		export.ContentType = "image/svg+xml"
+		err = whiteboard.RenderSVG(&buf, shapes)
+	} else {
+		export.ContentType = "image/png"
+		err = whiteboard.RenderPNG(&buf, shapes, s.exportMaxSize())
+	}
+	if err != nil {
+		return nil, err
+	}
+	export.Data = buf.Bytes()
+	return export, nil
+}
+
+func (s *whiteboardService) FindWhiteboard(ctx context.Context, room *models.Room, boardID uint) (*models.RoomWhiteboard, error) {
+	return s.findBoard(ctx, room, boardID)
+}
+
+// broadcast 推送最新的白板列表，失败时在线成员刷新后仍能看到正确的列表，不影响本次修改
+func (s *whiteboardService) broadcast(ctx context.Context, room *models.Room) {
+	boards, err := s.boardRepo.ListByRoom(ctx, room.ID)
+	if err == nil {
+		err = s.editor.BroadcastWhiteboards(ctx, room, boards)
+	}
+	if err != nil {
+		logger.Warn("推送白板列表失败", zap.String("room_uuid", room.UUID), zap.Error(err))
+	}
+}
+
+// checkThread 关联的讨论必须属于该房间
+func (s *whiteboardService) checkThread(ctx context.Context, room *models.Room, threadID uint) error {
+	thread, err := s.commentRepo.FindThread(ctx, threadID)
+	if err != nil {
+		return err
+	}
+	if thread.RoomID != room.ID {
+		return ErrCommentThreadNotFound
+	}
+	return nil
+}
+
+// findBoard 白板必须属于该房间
+func (s *whiteboardService) findBoard(ctx context.Context, room *models.Room, boardID uint) (*models.RoomWhiteboard, error) {
+	board, err := s.boardRepo.FindByID(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if board.RoomID != room.ID {
+		return nil, ErrWhiteboardNotFound
+	}
+	return board, nil
+}
+
+func (s *whiteboardService) maxBoards() int {
+	if s.cfg == nil || s.cfg.MaxBoards <= 0 {
+		return defaultMaxWhiteboards
+	}
+	return s.cfg.MaxBoards
+}
+
+func (s *whiteboardService) exportMaxSize() int {
+	if s.cfg == nil || s.cfg.ExportMaxSize <= 0 {
+		return whiteboard.DefaultMaxPNGSize
+	}
+	return s.cfg.ExportMaxSize
+}
+
+func (s *whiteboardService) findRoomAsMember(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return room, nil
+}
+
+// findWritableRoom 修改白板和编辑器的权限一致：成员即可，归档房间只读
+func (s *whiteboardService) findWritableRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
+
+// cleanWhiteboardTitle 去掉首尾空白，为空时使用默认标题
+func cleanWhiteboardTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return defaultWhiteboardTitle, nil
+	}
+	if utf8.RuneCountInString(title) > maxWhiteboardTitle {
+		return "", ErrWhiteboardTitle
+	}
+	for _, r := range title {
+		if r < 0x20 || r == 0x7f {
+			return "", ErrWhiteboardTitle
+		}
+	}
+	return title, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWhiteboardRepo 内存中的白板列表
+type memoryWhiteboardRepo struct {
+	boards map[uint]*models.RoomWhiteboard
+	nextID uint
+}
+
+func (r *memoryWhiteboardRepo) Create(_ context.Context, board *models.RoomWhiteboard) error {
+	r.nextID++
+	board.ID = r.nextID
+	copied := *board
+	r.boards[board.ID] = &copied
+	return nil
+}
+
+func (r *memoryWhiteboardRepo) FindByID(_ context.Context, id uint) (*models.RoomWhiteboard, error) {
+	board, ok := r.boards[id]
+	if !ok {
+		return nil, repository.ErrWhiteboardNotFound
+	}
+	copied := *board
+	return &copied, nil
+}
+
+func (r *memoryWhiteboardRepo) ListByRoom(_ context.Context, roomID uint) ([]*models.RoomWhiteboard, error) {
+	var boards []*models.RoomWhiteboard
+	for id := uint(1); id <= r.nextID; id++ {
+		if board, ok := r.boards[id]; ok && board.RoomID == roomID {
+			copied := *board
+			boards = append(boards, &copied)
+		}
+	}
+	return boards, nil
+}
+
+func (r *memoryWhiteboardRepo) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	boards, _ := r.ListByRoom(ctx, roomID)
+	return int64(len(boards)), nil
+}
+
+func (r *memoryWhiteboardRepo) Update(_ context.Context, id uint, title string, threadID *uint) error {
+	r.boards[id].Title, r.boards[id].ThreadID = title, threadID
+	return nil
+}
+
+func (r *memoryWhiteboardRepo) Delete(_ context.Context, id uint) error {
+	delete(r.boards, id)
+	return nil
+}
+
+// memoryWhiteboardDocRepo 只记录删除过的白板文档
+type memoryWhiteboardDocRepo struct {
+	deleted []uint
+}
+
+func (r *memoryWhiteboardDocRepo) Load(context.Context, uint) (*models.WhiteboardDocument, []*models.WhiteboardUpdate, error) {
+	return nil, nil, nil
+}
+
+func (r *memoryWhiteboardDocRepo) AppendUpdate(context.Context, uint, uint, []byte) (uint, error) {
+	return 0, nil
+}
+
+func (r *memoryWhiteboardDocRepo) Compact(_ context.Context, _, _ uint, _ []byte, uptoID uint, _ [][]byte) (uint, error) {
+	return uptoID, nil
+}
+
+func (r *memoryWhiteboardDocRepo) Delete(_ context.Context, boardID uint) error {
+	r.deleted = append(r.deleted, boardID)
+	return nil
+}
+
+// fakeWhiteboardEditor 内存中各块白板的图形，记录断开过的白板和推送过的白板列表
+type fakeWhiteboardEditor struct {
+	shapes     map[uint]map[string]any
+	closed     []uint
+	broadcasts [][]*models.RoomWhiteboard
+}
+
+func (e *fakeWhiteboardEditor) WhiteboardShapes(_ context.Context, _ *models.Room, boardID uint) (map[string]any, error) {
+	return e.shapes[boardID], nil
+}
+
+func (e *fakeWhiteboardEditor) CloseWhiteboard(_ context.Context, _ *models.Room, boardID uint) error {
+	e.closed = append(e.closed, boardID)
+	return nil
+}
+
+func (e *fakeWhiteboardEditor) BroadcastWhiteboards(_ context.Context, _ *models.Room, boards []*models.RoomWhiteboard) error {
+	e.broadcasts = append(e.broadcasts, boards)
+	return nil
+}
+
+func newWhiteboardFixture(maxBoards int) (WhiteboardService, *memoryCommentRepo, *fakeWhiteboardEditor, *memoryWhiteboardDocRepo) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Status: models.RoomStatusActive}
+	comments := &memoryCommentRepo{threads: make(map[uint]*models.RoomCommentThread)}
+	editor := &fakeWhiteboardEditor{shapes: make(map[uint]map[string]any)}
+	boards := &memoryWhiteboardRepo{boards: make(map[uint]*models.RoomWhiteboard)}
+	docs := &memoryWhiteboardDocRepo{}
+	svc := NewWhiteboardService(newVersionRoomRepo(room), boards, docs, comments, nil, editor, &config.WhiteboardConfig{MaxBoards: maxBoards})
+	return svc, comments, editor, docs
+}
+
+func TestWhiteboardService_Lifecycle(t *testing.T) {
+	svc, comments, editor, docs := newWhiteboardFixture(0)
+	ctx := context.Background()
+	require.NoError(t, comments.CreateThread(ctx, &models.RoomCommentThread{RoomID: 1}, &models.RoomComment{Content: "为什么是 O(n)？"}))
+	require.NoError(t, comments.CreateThread(ctx, &models.RoomCommentThread{RoomID: 2}, &models.RoomComment{Content: "其他房间"}))
+
+	// 1. 新建：没有标题时使用默认标题
+	board, err := svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{Title: "  "})
+	require.NoError(t, err)
+	assert.Equal(t, defaultWhiteboardTitle, board.Title)
+	assert.Nil(t, board.ThreadID)
+	require.Len(t, editor.broadcasts, 1)
+
+	_, err = svc.CreateWhiteboard(ctx, "room-1", 2, &CreateWhiteboardRequest{})
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+
+	// 2. 关联到讨论，只能关联本房间的讨论；ThreadID 为 0 时取消关联
+	threadID, otherRoomThread := uint(1), uint(2)
+	updated, err := svc.UpdateWhiteboard(ctx, "room-1", 1, board.ID, &UpdateWhiteboardRequest{ThreadID: &threadID})
+	require.NoError(t, err)
+	require.NotNil(t, updated.ThreadID)
+	assert.Equal(t, threadID, *updated.ThreadID)
+	assert.Equal(t, defaultWhiteboardTitle, updated.Title)
+
+	_, err = svc.UpdateWhiteboard(ctx, "room-1", 1, board.ID, &UpdateWhiteboardRequest{ThreadID: &otherRoomThread})
+	assert.ErrorIs(t, err, ErrCommentThreadNotFound)
+
+	title, detach := "BFS 层序", uint(0)
+	updated, err = svc.UpdateWhiteboard(ctx, "room-1", 1, board.ID, &UpdateWhiteboardRequest{Title: &title, ThreadID: &detach})
+	require.NoError(t, err)
+	assert.Equal(t, "BFS 层序", updated.Title)
+	assert.Nil(t, updated.ThreadID)
+
+	// 3. 删除时断开白板的连接并删除白板文档
+	require.NoError(t, svc.DeleteWhiteboard(ctx, "room-1", 1, board.ID))
+	assert.Equal(t, []uint{board.ID}, editor.closed)
+	assert.Equal(t, []uint{board.ID}, docs.deleted)
+	_, err = svc.FindWhiteboard(ctx, &models.Room{BaseModel: models.BaseModel{ID: 1}}, board.ID)
+	assert.ErrorIs(t, err, ErrWhiteboardNotFound)
+	boards, err := svc.ListWhiteboards(ctx, "room-1", 1)
+	require.NoError(t, err)
+	assert.Empty(t, boards)
+	assert.Len(t, editor.broadcasts, 4)
+}
+
+func TestWhiteboardService_Export(t *testing.T) {
+	svc, _, editor, _ := newWhiteboardFixture(0)
+	ctx := context.Background()
+	board, err := svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{Title: "DP 表"})
+	require.NoError(t, err)
+	editor.shapes[board.ID] = map[string]any{
+		"t": map[string]any{"type": "text", "x": int64(0), "y": int64(0), "text": "dp[i][j]"},
+	}
+
+	svg, err := svc.ExportWhiteboard(ctx, "room-1", 1, board.ID, WhiteboardFormatSVG)
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", svg.ContentType)
+	assert.Equal(t, "whiteboard-1.svg", svg.Filename)
+	assert.Contains(t, string(svg.Data), "dp[i][j]")
+
+	png, err := svc.ExportWhiteboard(ctx, "room-1", 1, board.ID, WhiteboardFormatPNG)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", png.ContentType)
+	assert.Equal(t, []byte("\x89PNG"), png.Data[:4])
+
+	_, err = svc.ExportWhiteboard(ctx, "room-1", 1, board.ID, "pdf")
+	assert.ErrorIs(t, err, ErrWhiteboardFormat)
+	_, err = svc.ExportWhiteboard(ctx, "room-1", 1, 99, WhiteboardFormatSVG)
+	assert.ErrorIs(t, err, ErrWhiteboardNotFound)
+}
+
+func TestWhiteboardService_Limits(t *testing.T) {
+	svc, _, _, _ := newWhiteboardFixture(1)
+	ctx := context.Background()
+
+	_, err := svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{Title: strings.Repeat("图", maxWhiteboardTitle+1)})
+	assert.ErrorIs(t, err, ErrWhiteboardTitle)
+	_, err = svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{Title: "a\nb"})
+	assert.ErrorIs(t, err, ErrWhiteboardTitle)
+
+	_, err = svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{})
+	require.NoError(t, err)
+	_, err = svc.CreateWhiteboard(ctx, "room-1", 1, &CreateWhiteboardRequest{})
+	assert.ErrorIs(t, err, ErrTooManyWhiteboards)
+}
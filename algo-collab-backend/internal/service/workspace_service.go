@@ -0,0 +1,511 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/is-Xiaoen/algo-collab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrRoomFileNotFound 文件不存在
+	ErrRoomFileNotFound = repository.ErrRoomFileNotFound
+	ErrRoomFileExists   = errors.New("该路径已存在文件")
+	// ErrRoomFileConflict 路径的上级目录是已有的文件，或路径是已有文件所在的目录
+	ErrRoomFileConflict = errors.New("路径与已有的文件或目录冲突")
+	ErrRoomFilePath     = errors.New("文件路径无效")
+	ErrRoomFileMain     = errors.New("主文件不能删除")
+	ErrTooManyRoomFiles = errors.New("文件数量超出限制")
+)
+
+const (
+	// defaultMaxRoomFiles 每个房间默认最多的文件数（包括主文件）
+	defaultMaxRoomFiles = 50
+	// maxFilePathLength 文件路径的最大长度（字符）
+	maxFilePathLength = 255
+	// maxFilePathDepth 文件路径最多的层级
+	maxFilePathDepth = 8
+	// fileTextPrefix 工作区文件对应的 Y.Text 名称前缀，主文件为 models.MainTextName
+	fileTextPrefix = "file:"
+)
+
+// languageByExtension 由扩展名推断文件语言，名称和 Monaco 的语言 ID 一致
+var languageByExtension = map[string]string{
+	".py":   "python",
+	".js":   "javascript",
+	".mjs":  "javascript",
+	".ts":   "typescript",
+	".go":   "go",
+	".java": "java",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".hpp":  "cpp",
+	".rs":   "rust",
+	".json": "json",
+	".md":   "markdown",
+	".sql":  "sql",
+	".sh":   "shell",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// mainFileByLanguage 房间语言对应的主文件名
+var mainFileByLanguage = map[string]string{
+	"python":     "main.py",
+	"javascript": "main.js",
+	"go":         "main.go",
+	"java":       "Main.java",
+}
+
+// DocumentReader 读取房间协作文档的纯文本视图，由 realtime.Hub 实现
+// 执行、搜索、导出、查重等需要代码字符串的功能都通过它读取，不需要自己解码 Yjs 状态
+type DocumentReader interface {
+	// FileTexts 读取 Y.Text 名称为 names 的文件内容
+	FileTexts(ctx context.Context, room *models.Room, names []string) (map[string]string, error)
+}
+
+// WorkspaceEditor 读写房间协作文档中的文件内容并推送文件树，由 realtime.Hub 实现
+type WorkspaceEditor interface {
+	DocumentReader
+	// ReplaceFileText 以角色为 role 的成员身份把文件内容修改为 text，作为一次编辑下发给所有在线成员
+	// 管理员以下的成员修改到锁定区域时不做任何修改，返回 ErrRoomRegionLocked
+	ReplaceFileText(ctx context.Context, room *models.Room, name, text, role string) error
+	// BroadcastFiles 把新的文件列表推送给所有在线成员
+	BroadcastFiles(ctx context.Context, room *models.Room, files []*models.RoomFile) error
+}
+
+// WorkspaceService 房间的多文件工作区：文件树保存在数据库，文件内容是协作文档中各自的 Y.Text
+// 原有的代码（Y.Text "monaco"）作为主文件，第一次访问工作区时自动加入文件树
+type WorkspaceService interface {
+	ListFiles(ctx context.Context, uuid string, userID uint) ([]*models.RoomFile, error)
+	CreateFile(ctx context.Context, uuid string, userID uint, req *CreateRoomFileRequest) (*models.RoomFile, error)
+	// UpdateFile 重命名或移动文件，协作中的内容不受影响
+	UpdateFile(ctx context.Context, uuid string, userID, fileID uint, req *UpdateRoomFileRequest) (*models.RoomFile, error)
+	// DeleteFile 删除文件并清空其内容，主文件不能删除
+	DeleteFile(ctx context.Context, uuid string, userID, fileID uint) error
+	// FileContent 按路径读取一个文件的当前内容
+	FileContent(ctx context.Context, uuid string, userID uint, filePath string) (*WorkspaceFile, error)
+	// Workspace 所有文件及其当前内容，供执行代码时使用
+	Workspace(ctx context.Context, uuid string, userID uint) (*Workspace, error)
+	// Snapshot 和 Workspace 相同，但不校验成员身份，供已经校验过的内部调用（例如语言服务器）使用
+	Snapshot(ctx context.Context, room *models.Room) (*Workspace, error)
+}
+
+type workspaceService struct {
+	roomRepo  repository.RoomRepository
+	fileRepo  repository.RoomFileRepository
+	eventRepo repository.RoomEventRepository
+	editor    WorkspaceEditor
+	cfg       *config.WorkspaceConfig
+}
+
+// CreateRoomFileRequest 新建文件，Content 为初始内容
+type CreateRoomFileRequest struct {
+	Path    string `json:"path" binding:"required,max=255"`
+	Content string `json:"content"`
+}
+
+// UpdateRoomFileRequest 重命名或移动文件，Path 为新的完整路径
+type UpdateRoomFileRequest struct {
+	Path string `json:"path" binding:"required,max=255"`
+}
+
+// Workspace 工作区快照，Entry 为主文件路径
+type Workspace struct {
+	Entry string           `json:"entry"`
+	Files []*WorkspaceFile `json:"files"`
+}
+
+// WorkspaceFile 工作区中的一个文件及其内容
+type WorkspaceFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+func NewWorkspaceService(
+	roomRepo repository.RoomRepository,
+	fileRepo repository.RoomFileRepository,
+	eventRepo repository.RoomEventRepository,
+	editor WorkspaceEditor,
+	cfg *config.WorkspaceConfig,
+) WorkspaceService {
+	return &workspaceService{
+		roomRepo:  roomRepo,
+		fileRepo:  fileRepo,
+		eventRepo: eventRepo,
+		editor:    editor,
+		cfg:       cfg,
+	}
+}
+
+func (s *workspaceService) ListFiles(ctx context.Context, uuid string, userID uint) ([]*models.RoomFile, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listFiles(ctx, room)
+}
+
+// CreateFile 新文件使用新的 Y.Text，名称随机生成，不会和删除过的文件冲突
+func (s *workspaceService) CreateFile(ctx context.Context, roomUUID string, userID uint, req *CreateRoomFileRequest) (*models.RoomFile, error) {
+	room, member, err := s.findWritableMember(ctx, roomUUID, userID)
+	if err != nil {
+		return nil, err
+	}
+	filePath, err := cleanFilePath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 数量和路径检查，主文件也计入数量
+	if _, err := s.listFiles(ctx, room); err != nil {
+		return nil, err
+	}
+	count, err := s.fileRepo.CountByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.maxFiles()) {
+		return nil, ErrTooManyRoomFiles
+	}
+	if err := s.checkPathFree(ctx, room.ID, filePath, 0); err != nil {
+		return nil, err
+	}
+
+	// 2. 创建文件，写入初始内容
+	file := &models.RoomFile{
+		RoomID:    room.ID,
+		Path:      filePath,
+		Language:  languageForPath(filePath),
+		TextName:  fileTextPrefix + uuid.NewString(),
+		CreatedBy: &userID,
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		return nil, err
+	}
+	if req.Content != "" {
+		if err := s.editor.ReplaceFileText(ctx, room, file.TextName, req.Content, member.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventFileCreate, models.JSONMap{
+		"file_id": file.ID,
+		"path":    file.Path,
+	})
+	s.broadcast(ctx, room)
+	return file, nil
+}
+
+func (s *workspaceService) UpdateFile(ctx context.Context, uuid string, userID, fileID uint, req *UpdateRoomFileRequest) (*models.RoomFile, error) {
+	room, err := s.findWritableRoom(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.findFile(ctx, room, fileID)
+	if err != nil {
+		return nil, err
+	}
+	filePath, err := cleanFilePath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if filePath == file.Path {
+		return file, nil
+	}
+	if err := s.checkPathFree(ctx, room.ID, filePath, file.ID); err != nil {
+		return nil, err
+	}
+
+	language := languageForPath(filePath)
+	if err := s.fileRepo.UpdatePath(ctx, file.ID, filePath, language); err != nil {
+		return nil, err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventFileMove, models.JSONMap{
+		"file_id": file.ID,
+		"from":    file.Path,
+		"to":      filePath,
+	})
+	file.Path, file.Language = filePath, language
+	s.broadcast(ctx, room)
+	return file, nil
+}
+
+// DeleteFile Y.Text 无法从文档中移除，清空内容以免已删除的文件占用文档大小
+// 清空内容和编辑一样受锁定区域限制，包含锁定区域的文件只有管理员可以删除
+func (s *workspaceService) DeleteFile(ctx context.Context, uuid string, userID, fileID uint) error {
+	room, member, err := s.findWritableMember(ctx, uuid, userID)
+	if err != nil {
+		return err
+	}
+	file, err := s.findFile(ctx, room, fileID)
+	if err != nil {
+		return err
+	}
+	if file.IsMain {
+		return ErrRoomFileMain
+	}
+
+	if err := s.editor.ReplaceFileText(ctx, room, file.TextName, "", member.Role); err != nil {
+		if errors.Is(err, ErrRoomRegionLocked) {
+			return err
+		}
+		logger.Warn("清空已删除文件的内容失败",
+			zap.String("room_uuid", uuid),
+			zap.Uint("file_id", file.ID),
+			zap.Error(err))
+	}
+	if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+		return err
+	}
+	recordRoomEvent(ctx, s.eventRepo, room.ID, &userID, nil, models.RoomEventFileDelete, models.JSONMap{
+		"file_id": file.ID,
+		"path":    file.Path,
+	})
+	s.broadcast(ctx, room)
+	return nil
+}
+
+func (s *workspaceService) FileContent(ctx context.Context, uuid string, userID uint, filePath string) (*WorkspaceFile, error) {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return nil, ErrRoomFileNotFound
+	}
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.listFiles(ctx, room)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.Path != filePath {
+			continue
+		}
+		texts, err := s.editor.FileTexts(ctx, room, []string{file.TextName})
+		if err != nil {
+			return nil, err
+		}
+		return &WorkspaceFile{
+			Path:     file.Path,
+			Language: file.Language,
+			Content:  texts[file.TextName],
+		}, nil
+	}
+	return nil, ErrRoomFileNotFound
+}
+
+func (s *workspaceService) Workspace(ctx context.Context, uuid string, userID uint) (*Workspace, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Snapshot(ctx, room)
+}
+
+func (s *workspaceService) Snapshot(ctx context.Context, room *models.Room) (*Workspace, error) {
+	files, err := s.listFiles(ctx, room)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.TextName)
+	}
+	texts, err := s.editor.FileTexts(ctx, room, names)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &Workspace{Files: make([]*WorkspaceFile, 0, len(files))}
+	for _, file := range files {
+		if file.IsMain {
+			workspace.Entry = file.Path
+		}
+		workspace.Files = append(workspace.Files, &WorkspaceFile{
+			Path:     file.Path,
+			Language: file.Language,
+			Content:  texts[file.TextName],
+		})
+	}
+	return workspace, nil
+}
+
+// listFiles 返回房间的文件，第一次访问时把原有代码作为主文件加入文件树
+func (s *workspaceService) listFiles(ctx context.Context, room *models.Room) ([]*models.RoomFile, error) {
+	files, err := s.fileRepo.ListByRoom(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsMain {
+			return files, nil
+		}
+	}
+
+	main := &models.RoomFile{
+		RoomID:   room.ID,
+		Path:     mainFilePath(room.Language),
+		TextName: models.MainTextName,
+		IsMain:   true,
+	}
+	main.Language = languageForPath(main.Path)
+	if exists, err := s.fileRepo.ExistsPath(ctx, room.ID, main.Path); err != nil {
+		return nil, err
+	} else if exists {
+		// 同名的普通文件已经占用了默认路径
+		main.Path = "_" + main.Path
+	}
+	if err := s.fileRepo.EnsureMain(ctx, main); err != nil {
+		return nil, err
+	}
+	return s.fileRepo.ListByRoom(ctx, room.ID)
+}
+
+// broadcast 推送最新的文件列表，失败时在线成员刷新后仍能看到正确的文件树，不影响本次修改
+func (s *workspaceService) broadcast(ctx context.Context, room *models.Room) {
+	files, err := s.fileRepo.ListByRoom(ctx, room.ID)
+	if err == nil {
+		err = s.editor.BroadcastFiles(ctx, room, files)
+	}
+	if err != nil {
+		logger.Warn("推送文件树失败", zap.String("room_uuid", room.UUID), zap.Error(err))
+	}
+}
+
+// checkPathFree 路径不能和已有文件相同，也不能让文件和目录重名：
+// 上级目录不能是已有的文件（有 a.py 时不能创建 a.py/b.py），也不能是已有文件所在的目录（有 src/a.py 时不能创建 src）。
+// exceptID 为正在重命名的文件，它原来的路径不算冲突
+func (s *workspaceService) checkPathFree(ctx context.Context, roomID uint, filePath string, exceptID uint) error {
+	files, err := s.fileRepo.ListByRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.ID == exceptID {
+			continue
+		}
+		if file.Path == filePath {
+			return ErrRoomFileExists
+		}
+		if strings.HasPrefix(filePath, file.Path+"/") || strings.HasPrefix(file.Path, filePath+"/") {
+			return ErrRoomFileConflict
+		}
+	}
+	return nil
+}
+
+func (s *workspaceService) maxFiles() int {
+	if s.cfg == nil || s.cfg.MaxFiles <= 0 {
+		return defaultMaxRoomFiles
+	}
+	return s.cfg.MaxFiles
+}
+
+// findFile 文件必须属于该房间
+func (s *workspaceService) findFile(ctx context.Context, room *models.Room, fileID uint) (*models.RoomFile, error) {
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.RoomID != room.ID {
+		return nil, ErrRoomFileNotFound
+	}
+	return file, nil
+}
+
+func (s *workspaceService) findRoomAsMember(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.roomRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotRoomMember
+	}
+	return room, nil
+}
+
+// findWritableRoom 修改文件树和编辑器的权限一致：成员即可，归档房间只读
+func (s *workspaceService) findWritableRoom(ctx context.Context, uuid string, userID uint) (*models.Room, error) {
+	room, err := s.findRoomAsMember(ctx, uuid, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsArchived() {
+		return nil, ErrRoomArchived
+	}
+	return room, nil
+}
+
+// findWritableMember 同 findWritableRoom，同时返回成员信息，修改文件内容时按成员角色检查锁定区域
+func (s *workspaceService) findWritableMember(ctx context.Context, uuid string, userID uint) (*models.Room, *models.RoomMember, error) {
+	room, err := s.roomRepo.FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.roomRepo.GetMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, nil, ErrNotRoomMember
+	}
+	if room.IsArchived() {
+		return nil, nil, ErrRoomArchived
+	}
+	return room, member, nil
+}
+
+// cleanFilePath 规范化文件路径：使用 / 分隔的相对路径，不能包含 . 和 .. 等特殊的段
+func cleanFilePath(p string) (string, error) {
+	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
+	p = strings.Trim(p, "/")
+	if p == "" || utf8.RuneCountInString(p) > maxFilePathLength {
+		return "", ErrRoomFilePath
+	}
+	segments := strings.Split(p, "/")
+	if len(segments) > maxFilePathDepth {
+		return "", ErrRoomFilePath
+	}
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.TrimSpace(segment) != segment {
+			return "", ErrRoomFilePath
+		}
+		for _, r := range segment {
+			if r < 0x20 || r == 0x7f {
+				return "", ErrRoomFilePath
+			}
+		}
+	}
+	return p, nil
+}
+
+// languageForPath 由扩展名推断语言，无法识别时为 plaintext（例如测试输入 input.txt）
+func languageForPath(p string) string {
+	if language, ok := languageByExtension[strings.ToLower(path.Ext(p))]; ok {
+		return language
+	}
+	return "plaintext"
+}
+
+func mainFilePath(language string) string {
+	if name, ok := mainFileByLanguage[language]; ok {
+		return name
+	}
+	return "main.txt"
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/is-Xiaoen/algo-collab/internal/config"
+	"github.com/is-Xiaoen/algo-collab/internal/models"
+	"github.com/is-Xiaoen/algo-collab/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryFileRepo 内存中的文件树
+type memoryFileRepo struct {
+	files  map[uint]*models.RoomFile
+	nextID uint
+}
+
+func newMemoryFileRepo() *memoryFileRepo {
+	return &memoryFileRepo{files: make(map[uint]*models.RoomFile)}
+}
+
+func (r *memoryFileRepo) Create(_ context.Context, file *models.RoomFile) error {
+	r.nextID++
+	file.ID = r.nextID
+	copied := *file
+	r.files[file.ID] = &copied
+	return nil
+}
+
+func (r *memoryFileRepo) EnsureMain(ctx context.Context, file *models.RoomFile) error {
+	for _, existing := range r.files {
+		if existing.RoomID == file.RoomID && existing.TextName == file.TextName {
+			return nil
+		}
+	}
+	return r.Create(ctx, file)
+}
+
+func (r *memoryFileRepo) FindByID(_ context.Context, id uint) (*models.RoomFile, error) {
+	file, ok := r.files[id]
+	if !ok {
+		return nil, repository.ErrRoomFileNotFound
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (r *memoryFileRepo) ListByRoom(_ context.Context, roomID uint) ([]*models.RoomFile, error) {
+	var files []*models.RoomFile
+	for _, file := range r.files {
+		if file.RoomID == roomID {
+			copied := *file
+			files = append(files, &copied)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (r *memoryFileRepo) CountByRoom(ctx context.Context, roomID uint) (int64, error) {
+	files, _ := r.ListByRoom(ctx, roomID)
+	return int64(len(files)), nil
+}
+
+func (r *memoryFileRepo) ExistsPath(_ context.Context, roomID uint, path string) (bool, error) {
+	for _, file := range r.files {
+		if file.RoomID == roomID && file.Path == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryFileRepo) UpdatePath(_ context.Context, id uint, path, language string) error {
+	r.files[id].Path, r.files[id].Language = path, language
+	return nil
+}
+
+func (r *memoryFileRepo) Delete(_ context.Context, id uint) error {
+	delete(r.files, id)
+	return nil
+}
+
+// fakeWorkspaceEditor 内存中的各个 Y.Text，记录推送过的文件列表
+// locked 中的 Y.Text 包含锁定区域，管理员以下的成员不能修改
+type fakeWorkspaceEditor struct {
+	texts      map[string]string
+	locked     map[string]bool
+	broadcasts [][]*models.RoomFile
+}
+
+func (e *fakeWorkspaceEditor) FileTexts(_ context.Context, _ *models.Room, names []string) (map[string]string, error) {
+	texts := make(map[string]string, len(names))
+	for _, name := range names {
+		texts[name] = e.texts[name]
+	}
+	return texts, nil
+}
+
+func (e *fakeWorkspaceEditor) ReplaceFileText(_ context.Context, _ *models.Room, name, text, role string) error {
+	if e.locked[name] && !models.RoomRoleAtLeast(role, models.RoomRoleAdmin) {
+		return ErrRoomRegionLocked
+	}
+	e.texts[name] = text
+	return nil
+}
+
+func (e *fakeWorkspaceEditor) BroadcastFiles(_ context.Context, _ *models.Room, files []*models.RoomFile) error {
+	e.broadcasts = append(e.broadcasts, files)
+	return nil
+}
+
+func newWorkspaceFixture(maxFiles int) (WorkspaceService, *memoryFileRepo, *fakeWorkspaceEditor) {
+	room := &models.Room{BaseModel: models.BaseModel{ID: 1}, UUID: "room-1", Language: "python", Status: models.RoomStatusActive}
+	files := newMemoryFileRepo()
+	editor := &fakeWorkspaceEditor{texts: map[string]string{models.MainTextName: "print(read())"}, locked: map[string]bool{}}
+	roomRepo := newVersionRoomRepo(room)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(1)).Return(&models.RoomMember{UserID: 1, Role: models.RoomRoleMember}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, uint(3)).Return(&models.RoomMember{UserID: 3, Role: models.RoomRoleAdmin}, nil)
+	roomRepo.On("GetMember", mock.Anything, room.ID, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	svc := NewWorkspaceService(roomRepo, files, nil, editor, &config.WorkspaceConfig{MaxFiles: maxFiles})
+	return svc, files, editor
+}
+
+func TestWorkspaceService_MainFile(t *testing.T) {
+	svc, _, _ := newWorkspaceFixture(0)
+
+	files, err := svc.ListFiles(context.Background(), "room-1", 1)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "main.py", files[0].Path)
+	assert.Equal(t, "python", files[0].Language)
+	assert.Equal(t, models.MainTextName, files[0].TextName)
+	assert.True(t, files[0].IsMain)
+
+	// 再次访问不会重复创建
+	files, err = svc.ListFiles(context.Background(), "room-1", 1)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// 主文件不能删除
+	assert.ErrorIs(t, svc.DeleteFile(context.Background(), "room-1", 1, files[0].ID), ErrRoomFileMain)
+
+	_, err = svc.ListFiles(context.Background(), "room-1", 2)
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+}
+
+func TestWorkspaceService_FileLifecycle(t *testing.T) {
+	svc, _, editor := newWorkspaceFixture(0)
+	ctx := context.Background()
+
+	// 1. 新建：推断语言、写入初始内容、推送文件树
+	file, err := svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "/tests\\input.txt", Content: "1 2"})
+	require.NoError(t, err)
+	assert.Equal(t, "tests/input.txt", file.Path)
+	assert.Equal(t, "plaintext", file.Language)
+	assert.Equal(t, "1 2", editor.texts[file.TextName])
+	require.Len(t, editor.broadcasts, 1)
+	assert.Len(t, editor.broadcasts[0], 2)
+
+	_, err = svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "tests/input.txt"})
+	assert.ErrorIs(t, err, ErrRoomFileExists)
+	// 文件和目录不能重名：已有文件不能作为目录，已有文件所在的目录不能作为文件
+	_, err = svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "main.py/util.py"})
+	assert.ErrorIs(t, err, ErrRoomFileConflict)
+	_, err = svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "tests"})
+	assert.ErrorIs(t, err, ErrRoomFileConflict)
+	_, err = svc.UpdateFile(ctx, "room-1", 1, file.ID, &UpdateRoomFileRequest{Path: "main.py/input.txt"})
+	assert.ErrorIs(t, err, ErrRoomFileConflict)
+
+	// 2. 重命名并移动：Y.Text 不变，语言重新推断
+	moved, err := svc.UpdateFile(ctx, "room-1", 1, file.ID, &UpdateRoomFileRequest{Path: "lib/helper.py"})
+	require.NoError(t, err)
+	assert.Equal(t, "python", moved.Language)
+	assert.Equal(t, file.TextName, moved.TextName)
+
+	// 3. 工作区快照包含所有文件的内容
+	workspace, err := svc.Workspace(ctx, "room-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "main.py", workspace.Entry)
+	assert.Equal(t, []*WorkspaceFile{
+		{Path: "lib/helper.py", Language: "python", Content: "1 2"},
+		{Path: "main.py", Language: "python", Content: "print(read())"},
+	}, workspace.Files)
+
+	// 4. 删除时清空内容
+	require.NoError(t, svc.DeleteFile(ctx, "room-1", 1, file.ID))
+	assert.Empty(t, editor.texts[file.TextName])
+	files, err := svc.ListFiles(ctx, "room-1", 1)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Len(t, editor.broadcasts, 3)
+}
+
+func TestWorkspaceService_DeleteLockedFile(t *testing.T) {
+	svc, files, editor := newWorkspaceFixture(0)
+	ctx := context.Background()
+
+	file, err := svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "solution.py", Content: "def f(): pass"})
+	require.NoError(t, err)
+	editor.locked[file.TextName] = true
+
+	// 清空内容会修改锁定区域：普通成员不能删除，文件和内容都保留
+	assert.ErrorIs(t, svc.DeleteFile(ctx, "room-1", 1, file.ID), ErrRoomRegionLocked)
+	assert.Contains(t, files.files, file.ID)
+	assert.Equal(t, "def f(): pass", editor.texts[file.TextName])
+
+	_, err = svc.CreateFile(ctx, "room-1", 2, &CreateRoomFileRequest{Path: "other.py"})
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+
+	// 管理员不受锁定区域限制
+	require.NoError(t, svc.DeleteFile(ctx, "room-1", 3, file.ID))
+	assert.NotContains(t, files.files, file.ID)
+	assert.Empty(t, editor.texts[file.TextName])
+}
+
+func TestWorkspaceService_Limits(t *testing.T) {
+	svc, _, _ := newWorkspaceFixture(2)
+	ctx := context.Background()
+
+	for _, p := range []string{"", "a/../b.py", "a//b.py", "./a.py", "dir /a.py", "a/b/c/d/e/f/g/h/i.py", "bad\x00name"} {
+		_, err := svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: p})
+		assert.ErrorIs(t, err, ErrRoomFilePath, p)
+	}
+
+	_, err := svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "notes.md"})
+	require.NoError(t, err)
+	// 主文件也计入数量
+	_, err = svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "more.md"})
+	assert.ErrorIs(t, err, ErrTooManyRoomFiles)
+}
+
+func TestWorkspaceService_FileContent(t *testing.T) {
+	svc, _, _ := newWorkspaceFixture(0)
+	ctx := context.Background()
+
+	_, err := svc.CreateFile(ctx, "room-1", 1, &CreateRoomFileRequest{Path: "src/utils.py", Content: "def f(): pass"})
+	require.NoError(t, err)
+
+	file, err := svc.FileContent(ctx, "room-1", 1, "/src/utils.py")
+	require.NoError(t, err)
+	assert.Equal(t, &WorkspaceFile{Path: "src/utils.py", Language: "python", Content: "def f(): pass"}, file)
+
+	// 第一次访问时主文件也可以按路径读取
+	file, err = svc.FileContent(ctx, "room-1", 1, "main.py")
+	require.NoError(t, err)
+	assert.Equal(t, "print(read())", file.Content)
+
+	for _, p := range []string{"src", "missing.py", "../main.py"} {
+		_, err = svc.FileContent(ctx, "room-1", 1, p)
+		assert.ErrorIs(t, err, ErrRoomFileNotFound, p)
+	}
+	_, err = svc.FileContent(ctx, "room-1", 2, "main.py")
+	assert.ErrorIs(t, err, ErrNotRoomMember)
+}
@@ -0,0 +1,230 @@
+// Package markdown 处理用户提交的 Markdown 文本
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// htmlTagPattern 原始 HTML 标签和注释
+	htmlTagPattern = regexp.MustCompile(`(?is)<!--.*?-->|</?[a-z][a-z0-9-]*(?:\s[^<>]*)?/?>`)
+	// linkStartPattern 链接、自动链接和引用定义中链接地址的开始位置
+	linkStartPattern = regexp.MustCompile(`(?m)(?:\]\(\s*<?|<|^\s*\[[^\]]+\]:\s*<?)`)
+	// entityPattern HTML 实体，渲染器会在链接地址中解码
+	entityPattern = regexp.MustCompile(`^&(?:#[0-9]{1,7};?|#[xX][0-9a-fA-F]{1,6};?|[a-zA-Z][a-zA-Z0-9]{1,31};)`)
+	// autolinkPattern 自动链接，尖括号不需要转义
+	autolinkPattern = regexp.MustCompile(`^<(?:[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*|[^\s<>@]+@[^\s<>]+)>`)
+	// unsafeSchemes 链接中不允许的协议
+	unsafeSchemes = []string{"javascript", "vbscript", "data", "file"}
+)
+
+// Sanitize 清理 Markdown 文本，结果可以交给任意 Markdown 渲染器
+//
+//  1. 去掉控制字符（保留换行和制表符），统一换行符
+//  2. 代码块和行内代码原样保留，算法讨论里的 a < b、&& 不会被转义
+//  3. 其余部分删除原始 HTML 标签，删除后剩下的（嵌套拼接出的、没有闭合的）标签开头用反斜杠转义，
+//     并把 javascript: 等危险协议的链接改为无效链接，判断协议前先解码实体、去掉空白
+//
+// 不会把 < > & 转义为 HTML 实体：渲染器负责转义文本，这里转义会导致显示出 &lt;。
+// 反斜杠转义是 Markdown 语法，渲染后仍然显示为 <
+func Sanitize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, text)
+
+	var b strings.Builder
+	var prose []string
+	flushProse := func() {
+		if len(prose) > 0 {
+			b.WriteString(sanitizeProse(strings.Join(prose, "")))
+			prose = prose[:0]
+		}
+	}
+
+	fence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		marker := fenceMarker(line)
+		switch {
+		case fence == "" && marker != "":
+			flushProse()
+			fence = marker
+			b.WriteString(line)
+		case fence != "":
+			b.WriteString(line)
+			// 结束标记至少和开始标记一样长
+			if marker != "" && strings.HasPrefix(marker, fence) && strings.TrimSpace(line) == marker {
+				fence = ""
+			}
+		default:
+			prose = append(prose, line)
+		}
+	}
+	flushProse()
+	return strings.TrimSpace(b.String())
+}
+
+// fenceMarker 返回代码围栏行的标记（``` 或 ~~~ 及更长），不是围栏时返回空
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	ch := trimmed[0]
+	if ch != '`' && ch != '~' {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// sanitizeProse 清理围栏代码块以外的文本，行内代码原样保留
+func sanitizeProse(text string) string {
+	var b strings.Builder
+	for text != "" {
+		start := strings.IndexByte(text, '`')
+		if start < 0 {
+			b.WriteString(cleanText(text))
+			break
+		}
+		b.WriteString(cleanText(text[:start]))
+
+		// 行内代码以同样长度的反引号结束，没有闭合时按普通文本处理
+		n := start
+		for n < len(text) && text[n] == '`' {
+			n++
+		}
+		ticks := text[start:n]
+		end := indexTicks(text[n:], len(ticks))
+		if end < 0 {
+			b.WriteString(ticks)
+			text = text[n:]
+			continue
+		}
+		b.WriteString(text[start : n+end+len(ticks)])
+		text = text[n+end+len(ticks):]
+	}
+	return b.String()
+}
+
+// indexTicks 找到恰好 count 个连续反引号的位置
+func indexTicks(text string, count int) int {
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && text[j] == '`' {
+			j++
+		}
+		if j-i == count {
+			return i
+		}
+		i = j
+	}
+	return -1
+}
+
+func cleanText(text string) string {
+	// 删除标签后两侧可能拼成新的标签，如 <scr<script>ipt>，重复到不再变化
+	for {
+		stripped := htmlTagPattern.ReplaceAllString(text, "")
+		if stripped == text {
+			break
+		}
+		text = stripped
+	}
+	return escapeTags(neutralizeLinks(text))
+}
+
+// neutralizeLinks 把危险协议的链接地址改为 #，保留协议之后的内容
+func neutralizeLinks(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range linkStartPattern.FindAllStringIndex(text, -1) {
+		if loc[0] < last {
+			continue
+		}
+		start := loc[1]
+		end, ok := unsafeScheme(text[start:])
+		if !ok {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString("#")
+		last = start + end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// unsafeScheme 链接地址是否以危险协议开头，返回协议（包括冒号）在原文中的长度
+// 浏览器会忽略协议中的空白和控制字符，渲染器会解码实体和反斜杠转义，比较前都先去掉
+func unsafeScheme(dest string) (int, bool) {
+	var scheme strings.Builder
+	for i := 0; i < len(dest) && scheme.Len() <= 32; {
+		chunk := dest[i : i+1]
+		if m := entityPattern.FindString(dest[i:]); m != "" {
+			chunk = m
+		}
+		i += len(chunk)
+
+		for _, r := range html.UnescapeString(chunk) {
+			switch {
+			case r == ':':
+				name := scheme.String()
+				for _, unsafe := range unsafeSchemes {
+					if name == unsafe {
+						return i, true
+					}
+				}
+				return 0, false
+			case r == '\\' || unicode.IsSpace(r) || unicode.IsControl(r):
+			case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '.' || r == '-'):
+				scheme.WriteRune(unicode.ToLower(r))
+			default:
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// escapeTags 转义可能开始 HTML 的 <（后面是字母、/、!、?），自动链接和已经转义的除外
+func escapeTags(text string) string {
+	var b strings.Builder
+	backslashes := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '<' && backslashes%2 == 0 && i+1 < len(text) && startsTag(text[i+1]) && !autolinkPattern.MatchString(text[i:]) {
+			b.WriteByte('\\')
+		}
+		if c == '\\' {
+			backslashes++
+		} else {
+			backslashes = 0
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func startsTag(c byte) bool {
+	return c == '/' || c == '!' || c == '?' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,42 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "普通文本", input: "用哈希表，时间复杂度 O(n)", want: "用哈希表，时间复杂度 O(n)"},
+		{name: "比较运算符不转义", input: "当 a < b && b > c 时", want: "当 a < b && b > c 时"},
+		{name: "删除 HTML 标签", input: `你好<script>alert(1)</script><img src=x onerror="alert(1)">`, want: "你好alert(1)"},
+		{name: "删除跨行标签和注释", input: "a<div\nonclick=\"x\">b</div><!-- c -->", want: "ab"},
+		{name: "行内代码保留", input: "用 `<vector>` 头文件", want: "用 `<vector>` 头文件"},
+		{name: "未闭合的反引号", input: "`<b>x", want: "`x"},
+		{name: "代码块保留", input: "看这里：\n```cpp\n#include <vector>\n```\n<b>完</b>", want: "看这里：\n```cpp\n#include <vector>\n```\n完"},
+		{name: "危险链接", input: "[点我](javascript:alert(1))", want: "[点我](#alert(1))"},
+		{name: "危险自动链接", input: "<JavaScript:alert(1)>", want: "<#alert(1)>"},
+		{name: "危险引用定义", input: "[a]\n\n[a]: javascript:alert(1)", want: "[a]\n\n[a]: #alert(1)"},
+		{name: "嵌套标签删除后重新拼成标签", input: "<scr<script>ipt>alert(1)</script>", want: "alert(1)"},
+		{name: "没有闭合的标签", input: "<img src=x onerror=alert(1)//", want: "\\<img src=x onerror=alert(1)//"},
+		{name: "已经转义的尖括号", input: `\<b x \\<b x`, want: `\<b x \\\<b x`},
+		{name: "实体编码的危险协议", input: "[x](javascript&#58;alert(1))", want: "[x](#alert(1))"},
+		{name: "实体编码的协议名", input: "[x](&#x6A;ava&Tab;script&colon;alert(1))", want: "[x](#alert(1))"},
+		{name: "协议中夹带空白", input: "[x](java\tscript:alert(1)) [y]( data :x)", want: "[x](#alert(1)) [y]( #x)"},
+		{name: "协议中夹带反斜杠转义", input: "[x](javascript\\:alert(1))", want: "[x](#alert(1))"},
+		{name: "正常自动链接", input: "<https://leetcode.cn> <a@b.cn>", want: "<https://leetcode.cn> <a@b.cn>"},
+		{name: "正常链接", input: "[题解](https://leetcode.cn/problems/two-sum)", want: "[题解](https://leetcode.cn/problems/two-sum)"},
+		{name: "控制字符和换行", input: "a\x00b\r\nc\t", want: "ab\nc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Sanitize(tt.input))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package ratelimit 基于 Redis 的限流，多个节点共享计数
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter 固定窗口限流
+type Limiter interface {
+	// Allow 记录一次请求，window 内超过 limit 次时返回 false 和距离窗口结束的时间
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+const keyPrefix = "ratelimit:"
+
+// incrScript 计数加一，第一次计数时设置过期时间，返回计数和剩余毫秒数
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+type redisLimiter struct {
+	rdb *redis.Client
+}
+
+// NewRedisLimiter 创建基于 Redis INCR 的 Limiter
+func NewRedisLimiter(rdb *redis.Client) Limiter {
+	return &redisLimiter{rdb: rdb}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	result, err := incrScript.Run(ctx, l.rdb, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	count, ttl := result[0], time.Duration(result[1])*time.Millisecond
+	if count > int64(limit) {
+		return false, ttl, nil
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	limiter := NewRedisLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := limiter.Allow(ctx, "chat:1:7", 3, 10*time.Second)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	// 超出后返回剩余等待时间，其他 key 不受影响
+	ok, retryAfter, err := limiter.Allow(ctx, "chat:1:7", 3, 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Greater(t, retryAfter, time.Duration(0))
+	assert.LessOrEqual(t, retryAfter, 10*time.Second)
+
+	ok, _, err = limiter.Allow(ctx, "chat:1:8", 3, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 窗口结束后重新计数
+	mr.FastForward(10 * time.Second)
+	ok, _, err = limiter.Allow(ctx, "chat:1:7", 3, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,246 @@
+// Package textdiff 按行比较两段文本，用于展示代码历史版本之间的差异
+package textdiff
+
+import "strings"
+
+// Op 行的变化类型
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// maxEdits 超过这个编辑距离不再寻找最短差异，中间部分整体按删除 + 插入处理
+// Myers 算法回溯需要保存 O(D²) 的状态，限制编辑距离避免大文件占用过多内存
+const maxEdits = 2000
+
+// Line 差异中的一行，行号从 1 开始，插入的行没有旧行号，删除的行没有新行号
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Hunk 一段连续的修改及其上下文，和 unified diff 的 @@ 块一致
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Stats 增删的行数
+type Stats struct {
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+// Lines 返回从 a 到 b 的逐行差异
+func Lines(a, b string) []Line {
+	oldLines, newLines := splitLines(a), splitLines(b)
+
+	// 先去掉相同的开头和结尾，通常只剩下很小的一段需要比较
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(oldLines)+len(newLines))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, OpEqual)
+	}
+	ops = append(ops, myers(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, OpEqual)
+	}
+
+	result := make([]Line, 0, len(ops))
+	oldIndex, newIndex := 0, 0
+	for _, op := range ops {
+		switch op {
+		case OpEqual:
+			result = append(result, Line{Op: op, Text: oldLines[oldIndex], OldLine: oldIndex + 1, NewLine: newIndex + 1})
+			oldIndex++
+			newIndex++
+		case OpDelete:
+			result = append(result, Line{Op: op, Text: oldLines[oldIndex], OldLine: oldIndex + 1})
+			oldIndex++
+		case OpInsert:
+			result = append(result, Line{Op: op, Text: newLines[newIndex], NewLine: newIndex + 1})
+			newIndex++
+		}
+	}
+	return result
+}
+
+// Summarize 统计增删的行数
+func Summarize(lines []Line) Stats {
+	var stats Stats
+	for _, line := range lines {
+		switch line.Op {
+		case OpInsert:
+			stats.Additions++
+		case OpDelete:
+			stats.Deletions++
+		}
+	}
+	return stats
+}
+
+// Hunks 把差异分成带 context 行上下文的修改块，没有修改时返回空
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Op == OpEqual {
+			i++
+			continue
+		}
+
+		// 向前带上上下文，向后一直延伸到连续 2*context 行没有修改为止
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == OpEqual && next-end < 2*context {
+				next++
+			}
+			if next < len(lines) && lines[next].Op != OpEqual {
+				end = next
+				continue
+			}
+			end = min(end+context, len(lines))
+			break
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []Line) Hunk {
+	hunk := Hunk{Lines: lines}
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			if hunk.OldStart == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+		if line.Op != OpDelete {
+			if hunk.NewStart == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+	}
+	return hunk
+}
+
+// splitLines 按换行拆分，结尾的换行不产生空行
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	return strings.Split(s, "\n")
+}
+
+// myers 求 a 到 b 的最短编辑序列（Myers O(ND) 算法）
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	// 行数差是编辑距离的下界
+	if n == 0 || m == 0 || n-m > maxEdits || m-n > maxEdits {
+		return replaceAll(n, m)
+	}
+
+	// v[k] 为对角线 k 上能到达的最远 x，trace[d] 保存第 d 轮开始前对角线 [-d, d] 的状态
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	for d := 0; d <= min(n+m, maxEdits); d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return replaceAll(n, m)
+}
+
+func backtrack(trace [][]int, n, m int) []Op {
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		// trace[d] 保存的是第 d-1 轮结束后对角线 [-d, d] 的状态
+		at := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, OpEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, OpInsert)
+			y--
+		} else {
+			ops = append(ops, OpDelete)
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, OpEqual)
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func replaceAll(n, m int) []Op {
+	ops := make([]Op, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, OpDelete)
+	}
+	for i := 0; i < m; i++ {
+		ops = append(ops, OpInsert)
+	}
+	return ops
+}
@@ -0,0 +1,97 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// render 按 unified diff 的格式输出，便于断言
+func render(lines []Line) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		switch line.Op {
+		case OpEqual:
+			sb.WriteString(" ")
+		case OpInsert:
+			sb.WriteString("+")
+		case OpDelete:
+			sb.WriteString("-")
+		}
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "相同", a: "a\nb\n", b: "a\nb", want: " a\n b\n"},
+		{name: "从空文本", a: "", b: "a\nb", want: "+a\n+b\n"},
+		{name: "清空", a: "a\nb", b: "", want: "-a\n-b\n"},
+		{name: "修改一行", a: "a\nb\nc", b: "a\nB\nc", want: " a\n-b\n+B\n c\n"},
+		{name: "插入和删除", a: "a\nb\nc\nd", b: "b\nc\nx\nd", want: "-a\n b\n c\n+x\n d\n"},
+		{
+			name: "最短差异",
+			a:    "for i in range(n):\n    if a[i] == t:\n        return i\nreturn -1",
+			b:    "seen = {}\nfor i in range(n):\n    if t - a[i] in seen:\n        return i\nreturn -1",
+			want: "+seen = {}\n for i in range(n):\n-    if a[i] == t:\n+    if t - a[i] in seen:\n         return i\n return -1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(Lines(tt.a, tt.b)))
+		})
+	}
+}
+
+func TestLines_LineNumbers(t *testing.T) {
+	lines := Lines("a\nb\nc", "a\nc\nd")
+	assert.Equal(t, []Line{
+		{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+		{Op: OpDelete, Text: "b", OldLine: 2},
+		{Op: OpEqual, Text: "c", OldLine: 3, NewLine: 2},
+		{Op: OpInsert, Text: "d", NewLine: 3},
+	}, lines)
+	assert.Equal(t, Stats{Additions: 1, Deletions: 1}, Summarize(lines))
+}
+
+func TestHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		a = append(a, string(rune('a'+i)))
+	}
+	b = append(b, a...)
+	b[2] = "C"
+	b[15] = "P"
+
+	hunks := Hunks(Lines(strings.Join(a, "\n"), strings.Join(b, "\n")), 2)
+	assert.Len(t, hunks, 2)
+	assert.Equal(t, Hunk{OldStart: 1, OldLines: 5, NewStart: 1, NewLines: 5}, Hunk{
+		OldStart: hunks[0].OldStart, OldLines: hunks[0].OldLines, NewStart: hunks[0].NewStart, NewLines: hunks[0].NewLines,
+	})
+	assert.Equal(t, 14, hunks[1].OldStart)
+	assert.Equal(t, 5, hunks[1].OldLines)
+
+	// 两处修改之间的相同行不超过 2*context 时合并为一块
+	b[6] = "G"
+	assert.Len(t, Hunks(Lines(strings.Join(a, "\n"), strings.Join(b, "\n")), 2), 2)
+	assert.Len(t, Hunks(Lines(strings.Join(a, "\n"), strings.Join(b, "\n")), 4), 1)
+
+	assert.Empty(t, Hunks(Lines("a", "a"), 3))
+}
+
+func TestLines_FallsBackBeyondMaxEdits(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxEdits+10; i++ {
+		a = append(a, "x")
+		b = append(b, "y")
+	}
+	stats := Summarize(Lines(strings.Join(a, "\n"), strings.Join(b, "\n")))
+	assert.Equal(t, Stats{Additions: maxEdits + 10, Deletions: maxEdits + 10}, stats)
+}
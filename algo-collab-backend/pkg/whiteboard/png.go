@@ -0,0 +1,206 @@
+package whiteboard
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// DefaultMaxPNGSize 导出 PNG 时较长一边的默认上限（像素），超出时整体缩小
+const DefaultMaxPNGSize = 4096
+
+// RenderPNG 把图形导出为 PNG，画布和 RenderSVG 一致，较长一边超过 maxSize 时整体缩小
+// 服务端没有字体，文本使用内置的点阵字体绘制，只支持 ASCII，其他字符画成方框；需要准确的文本请导出 SVG
+func RenderPNG(w io.Writer, shapes []*Shape, maxSize int) error {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPNGSize
+	}
+	x, y, width, height := canvas(shapes)
+	scale := min(1, float64(maxSize)/max(width, height))
+
+	r := &raster{
+		img:   image.NewRGBA(image.Rect(0, 0, max(1, int(math.Ceil(width*scale))), max(1, int(math.Ceil(height*scale))))),
+		ox:    x,
+		oy:    y,
+		scale: scale,
+	}
+	for i := range r.img.Pix {
+		r.img.Pix[i] = 0xff
+	}
+	for _, s := range shapes {
+		r.draw(s)
+	}
+	return png.Encode(w, r.img)
+}
+
+// raster 简单的软件光栅化：按像素中心到图形边缘的距离计算覆盖率，边缘做抗锯齿
+type raster struct {
+	img    *image.RGBA
+	ox, oy float64
+	scale  float64
+}
+
+// px 白板坐标转换为像素坐标
+func (r *raster) px(x, y float64) (float64, float64) {
+	return (x - r.ox) * r.scale, (y - r.oy) * r.scale
+}
+
+func (r *raster) draw(s *Shape) {
+	stroke, _ := parseColor(s.Stroke)
+	fill, hasFill := parseColor(s.Fill)
+	width := max(s.StrokeWidth*r.scale, 1)
+
+	switch s.Type {
+	case ShapeRect:
+		x0, y0 := r.px(s.X, s.Y)
+		x1, y1 := r.px(s.X+s.W, s.Y+s.H)
+		if hasFill {
+			r.fillRect(x0, y0, x1, y1, fill)
+		}
+		r.segment(x0, y0, x1, y0, width, stroke)
+		r.segment(x1, y0, x1, y1, width, stroke)
+		r.segment(x1, y1, x0, y1, width, stroke)
+		r.segment(x0, y1, x0, y0, width, stroke)
+	case ShapeEllipse:
+		cx, cy := r.px(s.X+s.W/2, s.Y+s.H/2)
+		r.ellipse(cx, cy, s.W/2*r.scale, s.H/2*r.scale, width, stroke, fill, hasFill)
+	case ShapeLine, ShapeArrow:
+		x0, y0 := r.px(s.Points[0], s.Points[1])
+		x1, y1 := r.px(s.Points[2], s.Points[3])
+		r.segment(x0, y0, x1, y1, width, stroke)
+		if s.Type == ShapeArrow {
+			ax, ay, bx, by := arrowHead(s.Points[0], s.Points[1], s.Points[2], s.Points[3], s.StrokeWidth)
+			ax, ay = r.px(ax, ay)
+			bx, by = r.px(bx, by)
+			r.segment(ax, ay, x1, y1, width, stroke)
+			r.segment(bx, by, x1, y1, width, stroke)
+		}
+	case ShapeFreehand:
+		px, py := r.px(s.Points[0], s.Points[1])
+		if len(s.Points) == 2 {
+			r.segment(px, py, px, py, width, stroke)
+		}
+		for i := 2; i+1 < len(s.Points); i += 2 {
+			x, y := r.px(s.Points[i], s.Points[i+1])
+			r.segment(px, py, x, y, width, stroke)
+			px, py = x, y
+		}
+	case ShapeText:
+		c := stroke
+		if hasFill {
+			c = fill
+		}
+		r.text(s, c)
+	}
+}
+
+// blend 以 alpha 的不透明度把颜色叠加到像素上
+func (r *raster) blend(x, y int, c rgb, alpha float64) {
+	if alpha <= 0 || !(image.Point{X: x, Y: y}).In(r.img.Rect) {
+		return
+	}
+	alpha = min(alpha, 1)
+	old := r.img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha + 0.5)
+	}
+	r.img.SetRGBA(x, y, color.RGBA{R: mix(old.R, c.r), G: mix(old.G, c.g), B: mix(old.B, c.b), A: 0xff})
+}
+
+// pixelRange 覆盖 [lo, hi] 的像素下标范围，限制在画布内
+func (r *raster) pixelRange(lo, hi float64, size int) (int, int) {
+	return max(0, int(math.Floor(lo))), min(size-1, int(math.Ceil(hi)))
+}
+
+func (r *raster) fillRect(x0, y0, x1, y1 float64, c rgb) {
+	b := r.img.Rect
+	xs, xe := r.pixelRange(x0, x1, b.Dx())
+	ys, ye := r.pixelRange(y0, y1, b.Dy())
+	for py := ys; py <= ye; py++ {
+		cy := float64(py) + 0.5
+		covY := min(cy+0.5, y1) - max(cy-0.5, y0)
+		for px := xs; px <= xe; px++ {
+			cx := float64(px) + 0.5
+			covX := min(cx+0.5, x1) - max(cx-0.5, x0)
+			r.blend(px, py, c, min(covX, 1)*min(covY, 1))
+		}
+	}
+}
+
+// segment 画一条线段，两端为圆头
+func (r *raster) segment(x0, y0, x1, y1, width float64, c rgb) {
+	half := width / 2
+	b := r.img.Rect
+	xs, xe := r.pixelRange(min(x0, x1)-half-1, max(x0, x1)+half+1, b.Dx())
+	ys, ye := r.pixelRange(min(y0, y1)-half-1, max(y0, y1)+half+1, b.Dy())
+	dx, dy := x1-x0, y1-y0
+	length2 := dx*dx + dy*dy
+	for py := ys; py <= ye; py++ {
+		cy := float64(py) + 0.5
+		for px := xs; px <= xe; px++ {
+			cx := float64(px) + 0.5
+			t := 0.0
+			if length2 > 0 {
+				t = math.Max(0, math.Min(1, ((cx-x0)*dx+(cy-y0)*dy)/length2))
+			}
+			d := math.Hypot(cx-(x0+t*dx), cy-(y0+t*dy))
+			r.blend(px, py, c, half+0.5-d)
+		}
+	}
+}
+
+// ellipse 填充并描边椭圆，像素到椭圆边的距离用一阶近似 |f| / |∇f| 估算
+func (r *raster) ellipse(cx, cy, rx, ry, width float64, stroke, fill rgb, hasFill bool) {
+	rx, ry = max(rx, 0.5), max(ry, 0.5)
+	half := width / 2
+	b := r.img.Rect
+	xs, xe := r.pixelRange(cx-rx-half-1, cx+rx+half+1, b.Dx())
+	ys, ye := r.pixelRange(cy-ry-half-1, cy+ry+half+1, b.Dy())
+	for py := ys; py <= ye; py++ {
+		dy := float64(py) + 0.5 - cy
+		for px := xs; px <= xe; px++ {
+			dx := float64(px) + 0.5 - cx
+			k := math.Hypot(dx/rx, dy/ry)
+			var d float64 // 到椭圆边的有向距离，内部为负
+			if k == 0 {
+				d = -min(rx, ry)
+			} else {
+				grad := math.Hypot(dx/(rx*rx), dy/(ry*ry)) / k
+				d = (k - 1) / grad
+			}
+			if hasFill {
+				r.blend(px, py, fill, 0.5-d)
+			}
+			r.blend(px, py, stroke, half+0.5-math.Abs(d))
+		}
+	}
+}
+
+// text 用 5x7 点阵字体绘制文本，每个字符占 6 列（含 1 列间距），字符宽度和 RenderSVG 估算的一致
+func (r *raster) text(s *Shape, c rgb) {
+	unit := s.FontSize * charWidthRatio / glyphAdvance
+	for i, line := range s.lines() {
+		top := s.Y + s.FontSize*(float64(i)*lineHeightRatio+0.2)
+		for j, ch := range []rune(line) {
+			left := s.X + float64(j)*s.FontSize*charWidthRatio
+			glyph, ok := glyphFor(ch)
+			for col := 0; col < glyphWidth; col++ {
+				for row := 0; row < glyphHeight; row++ {
+					on := glyph[col]&(1<<row) != 0
+					if !ok {
+						// 不支持的字符画成方框
+						on = col == 0 || col == glyphWidth-1 || row == 0 || row == glyphHeight-1
+					}
+					if !on {
+						continue
+					}
+					x0, y0 := r.px(left+float64(col)*unit, top+float64(row)*unit)
+					x1, y1 := r.px(left+float64(col+1)*unit, top+float64(row+1)*unit)
+					r.fillRect(x0, y0, x1, y1, c)
+				}
+			}
+		}
+	}
+}
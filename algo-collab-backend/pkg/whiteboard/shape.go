@@ -0,0 +1,233 @@
+// Package whiteboard 解析协作白板中的图形并导出为 SVG 或 PNG
+//
+// 白板是协作文档中的一个 Y.Map：键为图形 ID，值为图形的普通对象（见 Shape），
+// 每次修改图形时前端整体写入新的对象，并发修改同一个图形时后写入的生效。
+package whiteboard
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 图形类型
+const (
+	ShapeRect     = "rect"
+	ShapeEllipse  = "ellipse"
+	ShapeLine     = "line"
+	ShapeArrow    = "arrow"
+	ShapeFreehand = "freehand" // 手绘笔迹
+	ShapeText     = "text"
+)
+
+const (
+	defaultStroke      = "#1e1e1e"
+	defaultStrokeWidth = 2
+	defaultFontSize    = 16
+	maxStrokeWidth     = 64
+	maxFontSize        = 256
+	// maxCoordinate 坐标的绝对值上限，超出的图形忽略，避免导出时画布过大
+	maxCoordinate = 1e6
+	// maxPoints 单个图形最多的坐标数（x、y 各算一个）
+	maxPoints = 20000
+)
+
+// Shape 白板上的一个图形
+// rect、ellipse、text 使用 X、Y、W、H 表示外框（text 的 W、H 可以为 0）；
+// line、arrow 使用 Points 中的起点和终点，freehand 使用 Points 中的所有点，依次为 x0, y0, x1, y1...
+type Shape struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	X           float64   `json:"x"`
+	Y           float64   `json:"y"`
+	W           float64   `json:"w"`
+	H           float64   `json:"h"`
+	Points      []float64 `json:"points,omitempty"`
+	Text        string    `json:"text,omitempty"`
+	FontSize    float64   `json:"font_size,omitempty"`
+	Stroke      string    `json:"stroke,omitempty"`
+	Fill        string    `json:"fill,omitempty"`
+	StrokeWidth float64   `json:"stroke_width,omitempty"`
+	// Z 叠放顺序，大的在上层
+	Z float64 `json:"z"`
+}
+
+// ParseShapes 把 Y.Map 的内容解析为图形，按叠放顺序排列
+// 无法识别或坐标无效的图形忽略，颜色无效时使用默认值
+func ParseShapes(entries map[string]any) []*Shape {
+	shapes := make([]*Shape, 0, len(entries))
+	for id, value := range entries {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		shape := &Shape{}
+		if err := json.Unmarshal(data, shape); err != nil {
+			continue
+		}
+		shape.ID = id
+		if shape.normalize() {
+			shapes = append(shapes, shape)
+		}
+	}
+	sort.Slice(shapes, func(i, j int) bool {
+		if shapes[i].Z != shapes[j].Z {
+			return shapes[i].Z < shapes[j].Z
+		}
+		return shapes[i].ID < shapes[j].ID
+	})
+	return shapes
+}
+
+// normalize 校验并补全默认值，返回图形是否有效
+func (s *Shape) normalize() bool {
+	for _, v := range []float64{s.X, s.Y, s.W, s.H, s.Z} {
+		if !validCoordinate(v) {
+			return false
+		}
+	}
+	if len(s.Points) > maxPoints || len(s.Points)%2 != 0 {
+		return false
+	}
+	for _, v := range s.Points {
+		if !validCoordinate(v) {
+			return false
+		}
+	}
+	// 从右下往左上拖出的外框宽高为负数
+	if s.W < 0 {
+		s.X, s.W = s.X+s.W, -s.W
+	}
+	if s.H < 0 {
+		s.Y, s.H = s.Y+s.H, -s.H
+	}
+
+	switch s.Type {
+	case ShapeRect, ShapeEllipse:
+	case ShapeLine, ShapeArrow:
+		if len(s.Points) < 4 {
+			return false
+		}
+		s.Points = s.Points[:4]
+	case ShapeFreehand:
+		if len(s.Points) < 2 {
+			return false
+		}
+	case ShapeText:
+		if strings.TrimSpace(s.Text) == "" {
+			return false
+		}
+		if s.FontSize <= 0 {
+			s.FontSize = defaultFontSize
+		}
+		s.FontSize = min(s.FontSize, maxFontSize)
+	default:
+		return false
+	}
+
+	if _, ok := parseColor(s.Stroke); !ok {
+		s.Stroke = defaultStroke
+	}
+	if _, ok := parseColor(s.Fill); !ok {
+		s.Fill = ""
+	}
+	if s.StrokeWidth <= 0 {
+		s.StrokeWidth = defaultStrokeWidth
+	}
+	s.StrokeWidth = min(s.StrokeWidth, maxStrokeWidth)
+	return true
+}
+
+func validCoordinate(v float64) bool {
+	return !math.IsNaN(v) && math.Abs(v) <= maxCoordinate
+}
+
+// lines 文本按换行拆分
+func (s *Shape) lines() []string {
+	return strings.Split(strings.ReplaceAll(s.Text, "\r\n", "\n"), "\n")
+}
+
+// bounds 图形的外框（包括线宽）
+func (s *Shape) bounds() (minX, minY, maxX, maxY float64) {
+	switch s.Type {
+	case ShapeLine, ShapeArrow, ShapeFreehand:
+		minX, minY = math.Inf(1), math.Inf(1)
+		maxX, maxY = math.Inf(-1), math.Inf(-1)
+		for i := 0; i+1 < len(s.Points); i += 2 {
+			minX, maxX = min(minX, s.Points[i]), max(maxX, s.Points[i])
+			minY, maxY = min(minY, s.Points[i+1]), max(maxY, s.Points[i+1])
+		}
+		pad := s.StrokeWidth / 2
+		if s.Type == ShapeArrow {
+			pad += arrowHeadLength(s.StrokeWidth)
+		}
+		return minX - pad, minY - pad, maxX + pad, maxY + pad
+	case ShapeText:
+		lines := s.lines()
+		width := s.W
+		for _, line := range lines {
+			width = max(width, float64(len([]rune(line)))*s.FontSize*charWidthRatio)
+		}
+		height := max(s.H, float64(len(lines))*s.FontSize*lineHeightRatio)
+		return s.X, s.Y, s.X + width, s.Y + height
+	default:
+		pad := s.StrokeWidth / 2
+		return s.X - pad, s.Y - pad, s.X + s.W + pad, s.Y + s.H + pad
+	}
+}
+
+// Bounds 所有图形的外框，没有图形时为空
+func Bounds(shapes []*Shape) (minX, minY, maxX, maxY float64, ok bool) {
+	for i, shape := range shapes {
+		x0, y0, x1, y1 := shape.bounds()
+		if i == 0 {
+			minX, minY, maxX, maxY = x0, y0, x1, y1
+			continue
+		}
+		minX, minY = min(minX, x0), min(minY, y0)
+		maxX, maxY = max(maxX, x1), max(maxY, y1)
+	}
+	return minX, minY, maxX, maxY, len(shapes) > 0
+}
+
+const (
+	// charWidthRatio、lineHeightRatio 估算文本宽高时每个字符的宽度和行高（相对字号）
+	charWidthRatio  = 0.6
+	lineHeightRatio = 1.25
+)
+
+// arrowHeadLength 箭头的长度，随线宽变化
+func arrowHeadLength(strokeWidth float64) float64 {
+	return 8 + strokeWidth*3
+}
+
+// arrowHead 箭头两翼的端点，箭头指向 (x1, y1)
+func arrowHead(x0, y0, x1, y1, strokeWidth float64) (ax, ay, bx, by float64) {
+	angle := math.Atan2(y1-y0, x1-x0)
+	length := arrowHeadLength(strokeWidth)
+	const spread = math.Pi / 7
+	ax, ay = x1-length*math.Cos(angle-spread), y1-length*math.Sin(angle-spread)
+	bx, by = x1-length*math.Cos(angle+spread), y1-length*math.Sin(angle+spread)
+	return ax, ay, bx, by
+}
+
+// rgb 颜色分量
+type rgb struct{ r, g, b uint8 }
+
+// parseColor 解析 #rgb 或 #rrggbb 格式的颜色，其他格式不支持
+func parseColor(s string) (rgb, bool) {
+	if !strings.HasPrefix(s, "#") || (len(s) != 4 && len(s) != 7) {
+		return rgb{}, false
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rgb{}, false
+	}
+	return rgb{uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
@@ -0,0 +1,98 @@
+package whiteboard
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// exportPadding 导出时画布四周留白
+const exportPadding = 20
+
+// emptySize 没有图形时导出的画布大小
+const emptySize = 100
+
+// canvas 导出的画布：原点和大小，坐标与白板一致
+func canvas(shapes []*Shape) (x, y, w, h float64) {
+	minX, minY, maxX, maxY, ok := Bounds(shapes)
+	if !ok {
+		return 0, 0, emptySize, emptySize
+	}
+	return minX - exportPadding, minY - exportPadding,
+		maxX - minX + 2*exportPadding, maxY - minY + 2*exportPadding
+}
+
+// RenderSVG 把图形导出为 SVG，画布为所有图形的外框加上留白
+func RenderSVG(w io.Writer, shapes []*Shape) error {
+	bw := bufio.NewWriter(w)
+	x, y, width, height := canvas(shapes)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="%s %s %s %s">`,
+		num(width), num(height), num(x), num(y), num(width), num(height))
+	bw.WriteString("\n")
+	fmt.Fprintf(bw, `<rect x="%s" y="%s" width="%s" height="%s" fill="#ffffff"/>`, num(x), num(y), num(width), num(height))
+	bw.WriteString("\n")
+
+	for _, s := range shapes {
+		stroke := fmt.Sprintf(`stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`, s.Stroke, num(s.StrokeWidth))
+		fill := "none"
+		if s.Fill != "" {
+			fill = s.Fill
+		}
+		switch s.Type {
+		case ShapeRect:
+			fmt.Fprintf(bw, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" %s/>`,
+				num(s.X), num(s.Y), num(s.W), num(s.H), fill, stroke)
+		case ShapeEllipse:
+			fmt.Fprintf(bw, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s" fill="%s" %s/>`,
+				num(s.X+s.W/2), num(s.Y+s.H/2), num(s.W/2), num(s.H/2), fill, stroke)
+		case ShapeLine:
+			fmt.Fprintf(bw, `<line x1="%s" y1="%s" x2="%s" y2="%s" %s/>`,
+				num(s.Points[0]), num(s.Points[1]), num(s.Points[2]), num(s.Points[3]), stroke)
+		case ShapeArrow:
+			x0, y0, x1, y1 := s.Points[0], s.Points[1], s.Points[2], s.Points[3]
+			ax, ay, bx, by := arrowHead(x0, y0, x1, y1, s.StrokeWidth)
+			fmt.Fprintf(bw, `<path d="M%s %sL%s %sM%s %sL%s %sL%s %s" fill="none" %s/>`,
+				num(x0), num(y0), num(x1), num(y1),
+				num(ax), num(ay), num(x1), num(y1), num(bx), num(by), stroke)
+		case ShapeFreehand:
+			points := make([]string, 0, len(s.Points)/2)
+			for i := 0; i+1 < len(s.Points); i += 2 {
+				points = append(points, num(s.Points[i])+","+num(s.Points[i+1]))
+			}
+			if len(points) == 1 {
+				// 只有一个点的笔迹画成一个圆点
+				points = append(points, points[0])
+			}
+			fmt.Fprintf(bw, `<polyline points="%s" fill="none" %s/>`, strings.Join(points, " "), stroke)
+		case ShapeText:
+			color := s.Stroke
+			if s.Fill != "" {
+				color = s.Fill
+			}
+			fmt.Fprintf(bw, `<text x="%s" y="%s" font-family="sans-serif" font-size="%s" fill="%s">`,
+				num(s.X), num(s.Y), num(s.FontSize), color)
+			for i, line := range s.lines() {
+				fmt.Fprintf(bw, `<tspan x="%s" y="%s">`, num(s.X), num(s.Y+s.FontSize*(float64(i)*lineHeightRatio+1)))
+				_ = xml.EscapeText(bw, []byte(line))
+				bw.WriteString("</tspan>")
+			}
+			bw.WriteString("</text>")
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// num 格式化坐标，最多保留两位小数
+func num(v float64) string {
+	v = math.Round(v*100) / 100
+	if v == 0 {
+		v = 0 // 避免输出 -0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
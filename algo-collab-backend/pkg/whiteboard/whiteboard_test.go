@@ -0,0 +1,92 @@
+package whiteboard
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testShapes() map[string]any {
+	return map[string]any{
+		"r": map[string]any{"type": "rect", "x": int64(0), "y": int64(0), "w": int64(100), "h": int64(50), "fill": "#ff0000", "z": int64(1)},
+		"a": map[string]any{"type": "arrow", "points": []any{int64(0), int64(100), 200.5, int64(100)}, "stroke": "#00f", "z": int64(2)},
+		"t": map[string]any{"type": "text", "x": int64(10), "y": int64(120), "text": "dp[i] < 3 & 树", "z": int64(3)},
+		"f": map[string]any{"type": "freehand", "points": []any{int64(5), int64(5), int64(10), int64(12), int64(20), int64(8)}},
+		// 无效的图形
+		"bad-type":   map[string]any{"type": "star"},
+		"bad-points": map[string]any{"type": "line", "points": []any{int64(1), int64(2)}},
+		"bad-value":  "not a shape",
+		"bad-text":   map[string]any{"type": "text", "text": "  "},
+	}
+}
+
+func TestParseShapes(t *testing.T) {
+	shapes := ParseShapes(testShapes())
+	ids := make([]string, 0, len(shapes))
+	for _, s := range shapes {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{"f", "r", "a", "t"}, ids)
+
+	assert.Equal(t, "#1e1e1e", shapes[1].Stroke)
+	assert.Equal(t, float64(defaultStrokeWidth), shapes[1].StrokeWidth)
+	assert.Equal(t, float64(defaultFontSize), shapes[3].FontSize)
+
+	// 负的宽高表示反向拖出的外框
+	flipped := ParseShapes(map[string]any{"e": map[string]any{"type": "ellipse", "x": int64(10), "y": int64(10), "w": int64(-4), "h": int64(-6), "fill": "red"}})
+	require.Len(t, flipped, 1)
+	assert.Equal(t, Shape{ID: "e", Type: ShapeEllipse, X: 6, Y: 4, W: 4, H: 6, Stroke: defaultStroke, StrokeWidth: defaultStrokeWidth}, *flipped[0])
+}
+
+func TestRenderSVG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, RenderSVG(&buf, ParseShapes(testShapes())))
+	svg := buf.String()
+
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg"`))
+	assert.Contains(t, svg, `<rect x="0" y="0" width="100" height="50" fill="#ff0000"`)
+	assert.Contains(t, svg, `<path d="M0 100L200.5 100M`)
+	assert.Contains(t, svg, `<polyline points="5,5 10,12 20,8"`)
+	// 文本需要转义
+	assert.Contains(t, svg, `dp[i] &lt; 3 &amp; 树</tspan>`)
+	assert.Less(t, strings.Index(svg, "<polyline"), strings.Index(svg, "<rect x=\"0\""))
+}
+
+func TestRenderPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, RenderPNG(&buf, ParseShapes(testShapes()), 0))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+
+	// 画布左上角是 (-35, -21)：箭头和矩形的外框（含线宽和箭头）减去留白
+	assert.Equal(t, 271, img.Bounds().Dx())
+	r, g, b, _ := img.At(35+50, 21+25).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b}, "矩形内部为填充色")
+	r, g, b, _ = img.At(2, 2).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b}, "空白处为白色")
+	r, g, b, _ = img.At(35+150, 21+100).RGBA()
+	assert.Equal(t, []uint32{0, 0, 0xffff}, []uint32{r, g, b}, "箭头线段")
+
+	// 超过上限时整体缩小
+	buf.Reset()
+	require.NoError(t, RenderPNG(&buf, ParseShapes(testShapes()), 100))
+	img, err = png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+
+	buf.Reset()
+	require.NoError(t, RenderPNG(&buf, nil, 0))
+	img, err = png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, emptySize, img.Bounds().Dx())
+}
+
+func TestFontTable(t *testing.T) {
+	assert.Len(t, font5x7, '~'-' '+1)
+	_, ok := glyphFor('树')
+	assert.False(t, ok)
+}
@@ -0,0 +1,138 @@
+// Package wordfilter 敏感词过滤
+package wordfilter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Filter 敏感词过滤器，不区分大小写
+//
+// 中文词按子串匹配；英文（以字母或数字开头/结尾）要求词边界，
+// 避免把 "class" 中的 "ass" 这类正常单词误判。
+// 零值和 nil 都可以使用，表示不过滤。
+type Filter struct {
+	// words 按首字符分组的小写敏感词，从长到短排列，优先匹配较长的词
+	words map[rune][][]rune
+}
+
+// New 创建过滤器，忽略空词和重复的词
+func New(words []string) *Filter {
+	f := &Filter{words: make(map[rune][][]rune)}
+	seen := make(map[string]bool)
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		runes := []rune(word)
+		f.words[runes[0]] = append(f.words[runes[0]], runes)
+	}
+	for _, list := range f.words {
+		slices.SortStableFunc(list, func(a, b []rune) int { return len(b) - len(a) })
+	}
+	return f
+}
+
+// LoadFiles 读取词表文件：每行一个词，空行和 # 开头的行忽略
+func LoadFiles(paths ...string) ([]string, error) {
+	var words []string
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取词表失败: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				words = append(words, line)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取词表失败: %w", err)
+		}
+	}
+	return words, nil
+}
+
+// Contains 文本中是否包含敏感词
+func (f *Filter) Contains(text string) bool {
+	found := false
+	f.scan(text, func(int, int) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// Mask 把敏感词的每个字符替换为 *
+func (f *Filter) Mask(text string) string {
+	runes := []rune(text)
+	masked := false
+	f.scan(text, func(start, end int) bool {
+		for i := start; i < end; i++ {
+			runes[i] = '*'
+		}
+		masked = true
+		return true
+	})
+	if !masked {
+		return text
+	}
+	return string(runes)
+}
+
+// scan 从左到右查找不重叠的敏感词，对每个匹配调用 fn(起始, 结束) 的字符下标，fn 返回 false 时停止
+func (f *Filter) scan(text string, fn func(start, end int) bool) {
+	if f == nil || len(f.words) == 0 {
+		return
+	}
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	for i := 0; i < len(lower); {
+		n := f.matchAt(lower, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		if !fn(i, i+n) {
+			return
+		}
+		i += n
+	}
+}
+
+// matchAt 返回从 i 开始匹配到的最长敏感词的长度，没有匹配返回 0
+func (f *Filter) matchAt(text []rune, i int) int {
+	for _, word := range f.words[text[i]] {
+		end := i + len(word)
+		if end > len(text) || !slices.Equal(text[i:end], word) {
+			continue
+		}
+		if isWordChar(word[0]) && i > 0 && isWordChar(text[i-1]) {
+			continue
+		}
+		if isWordChar(word[len(word)-1]) && end < len(text) && isWordChar(text[end]) {
+			continue
+		}
+		return len(word)
+	}
+	return 0
+}
+
+// isWordChar 英文单词的组成字符，中文没有词边界
+func isWordChar(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
@@ -0,0 +1,55 @@
+package wordfilter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	filter := New([]string{"刷单", "加微信", "微信", "spam", "free money", " ", "SPAM"})
+
+	tests := []struct {
+		name     string
+		input    string
+		contains bool
+		masked   string
+	}{
+		{name: "正常文本", input: "用双指针，O(n) 就够了", contains: false, masked: "用双指针，O(n) 就够了"},
+		{name: "中文子串", input: "需要刷单的私聊", contains: true, masked: "需要**的私聊"},
+		{name: "优先匹配较长的词", input: "有事加微信", contains: true, masked: "有事***"},
+		{name: "英文不区分大小写", input: "This is SPAM!", contains: true, masked: "This is ****!"},
+		{name: "英文要求词边界", input: "spammer and antispam", contains: false, masked: "spammer and antispam"},
+		{name: "英文词组", input: "get Free Money now", contains: true, masked: "get ********** now"},
+		{name: "中英混排", input: "发spam广告", contains: true, masked: "发****广告"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.contains, filter.Contains(tt.input))
+			assert.Equal(t, tt.masked, filter.Mask(tt.input))
+		})
+	}
+}
+
+func TestFilter_Nil(t *testing.T) {
+	var filter *Filter
+	assert.False(t, filter.Contains("spam"))
+	assert.Equal(t, "spam", filter.Mask("spam"))
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "words.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# 注释\n刷单\n\n  spam  \n"), 0o644))
+
+	words, err := LoadFiles(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"刷单", "spam"}, words)
+
+	_, err = LoadFiles(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
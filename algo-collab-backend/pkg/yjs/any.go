@@ -0,0 +1,187 @@
+package yjs
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// lib0 any 的类型标记
+const (
+	anyUndefined = 127
+	anyNull      = 126
+	anyInteger   = 125
+	anyFloat32   = 124
+	anyFloat64   = 123
+	anyBigInt    = 122
+	anyFalse     = 121
+	anyTrue      = 120
+	anyString    = 119
+	anyObject    = 118
+	anyArray     = 117
+	anyBinary    = 116
+)
+
+// maxAnyInteger lib0 用 varint 编码的整数范围（BITS31），超出的按浮点数编码
+const maxAnyInteger = 1<<31 - 1
+
+// DecodeAny 解析一个 lib0 any 值（Y.Map/Y.Array 中的普通值）
+// 对应的 Go 类型：nil、bool、int64（整数）、float64、string、[]byte、[]any、map[string]any
+func DecodeAny(data []byte) (any, error) {
+	return readAny(NewDecoder(data), 0)
+}
+
+func readAny(d *Decoder, depth int) (any, error) {
+	if depth > maxAnyDepth {
+		return nil, errInvalidAny
+	}
+	t, err := d.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case anyUndefined, anyNull:
+		return nil, nil
+	case anyFalse:
+		return false, nil
+	case anyTrue:
+		return true, nil
+	case anyInteger:
+		return d.ReadVarInt()
+	case anyFloat32:
+		raw, err := d.ReadRaw(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case anyFloat64:
+		raw, err := d.ReadRaw(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	case anyBigInt:
+		raw, err := d.ReadRaw(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(binary.BigEndian.Uint64(raw)), nil
+	case anyString:
+		return readLenientString(d)
+	case anyBinary:
+		data, err := d.ReadVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), data...), nil
+	case anyObject:
+		n, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]any, min(n, uint64(len(d.Remaining()))))
+		for i := uint64(0); i < n; i++ {
+			key, err := readLenientString(d)
+			if err != nil {
+				return nil, err
+			}
+			if obj[key], err = readAny(d, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case anyArray:
+		n, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]any, 0, min(n, uint64(len(d.Remaining()))))
+		for i := uint64(0); i < n; i++ {
+			v, err := readAny(d, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	default:
+		return nil, errInvalidAny
+	}
+}
+
+// EncodeAny 按 lib0 的规则编码一个值，支持的类型同 DecodeAny，另外接受 int、float32 和 []float64
+// 无法编码的值写为 undefined
+func EncodeAny(v any) []byte {
+	e := NewEncoder()
+	writeAny(e, v)
+	return e.Bytes()
+}
+
+func writeAny(e *Encoder, v any) {
+	switch v := v.(type) {
+	case nil:
+		e.WriteUint8(anyNull)
+	case bool:
+		if v {
+			e.WriteUint8(anyTrue)
+		} else {
+			e.WriteUint8(anyFalse)
+		}
+	case int:
+		writeAnyNumber(e, float64(v))
+	case int64:
+		writeAnyNumber(e, float64(v))
+	case float32:
+		writeAnyNumber(e, float64(v))
+	case float64:
+		writeAnyNumber(e, v)
+	case string:
+		e.WriteUint8(anyString)
+		e.WriteVarString(v)
+	case []byte:
+		e.WriteUint8(anyBinary)
+		e.WriteVarBytes(v)
+	case []any:
+		e.WriteUint8(anyArray)
+		e.WriteVarUint(uint64(len(v)))
+		for _, item := range v {
+			writeAny(e, item)
+		}
+	case []float64:
+		e.WriteUint8(anyArray)
+		e.WriteVarUint(uint64(len(v)))
+		for _, item := range v {
+			writeAnyNumber(e, item)
+		}
+	case map[string]any:
+		// 按键排序，同样的值总是得到同样的编码
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.WriteUint8(anyObject)
+		e.WriteVarUint(uint64(len(keys)))
+		for _, key := range keys {
+			e.WriteVarString(key)
+			writeAny(e, v[key])
+		}
+	default:
+		e.WriteUint8(anyUndefined)
+	}
+}
+
+// writeAnyNumber 和 lib0 一致：31 位以内的整数用 varint，能无损表示的用 float32，其余用 float64
+func writeAnyNumber(e *Encoder, v float64) {
+	switch {
+	case v == math.Trunc(v) && math.Abs(v) <= maxAnyInteger:
+		e.WriteUint8(anyInteger)
+		e.WriteVarInt(int64(v))
+	case float64(float32(v)) == v:
+		e.WriteUint8(anyFloat32)
+		e.WriteRaw(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v))))
+	default:
+		e.WriteUint8(anyFloat64)
+		e.WriteRaw(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	}
+}
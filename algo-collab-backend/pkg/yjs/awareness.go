@@ -0,0 +1,60 @@
+package yjs
+
+import "encoding/json"
+
+// AwarenessEntry awareness 更新中的一项
+// State 是客户端本地状态的 JSON；"null" 表示该客户端已离开
+type AwarenessEntry struct {
+	ClientID uint64
+	Clock    uint64
+	State    json.RawMessage
+}
+
+// IsRemoved 该项是否表示客户端离开
+func (a *AwarenessEntry) IsRemoved() bool {
+	return len(a.State) == 0 || string(a.State) == "null"
+}
+
+// DecodeAwarenessUpdate 解析 awareness 更新
+// 格式：条目数，然后每项依次为 clientID、clock、state（JSON 字符串）
+func DecodeAwarenessUpdate(update []byte) ([]AwarenessEntry, error) {
+	d := NewDecoder(update)
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AwarenessEntry, 0, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		var entry AwarenessEntry
+		if entry.ClientID, err = d.ReadVarUint(); err != nil {
+			return nil, err
+		}
+		if entry.Clock, err = d.ReadVarUint(); err != nil {
+			return nil, err
+		}
+		state, err := d.ReadVarString()
+		if err != nil {
+			return nil, err
+		}
+		entry.State = json.RawMessage(state)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// EncodeAwarenessUpdate 编码 awareness 更新
+func EncodeAwarenessUpdate(entries []AwarenessEntry) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(uint64(len(entries)))
+	for _, entry := range entries {
+		e.WriteVarUint(entry.ClientID)
+		e.WriteVarUint(entry.Clock)
+		if len(entry.State) == 0 {
+			e.WriteVarString("null")
+		} else {
+			e.WriteVarString(string(entry.State))
+		}
+	}
+	return e.Bytes()
+}
@@ -0,0 +1,386 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Item 内容类型编号（info 字节的低 5 位）
+const (
+	contentRefGC      = 0
+	contentRefDeleted = 1
+	contentRefJSON    = 2
+	contentRefBinary  = 3
+	contentRefString  = 4
+	contentRefEmbed   = 5
+	contentRefFormat  = 6
+	contentRefType    = 7
+	contentRefAny     = 8
+	contentRefDoc     = 9
+	structRefSkip     = 10
+)
+
+// 共享类型编号
+const (
+	TypeRefArray       = 0
+	TypeRefMap         = 1
+	TypeRefText        = 2
+	TypeRefXMLElement  = 3
+	TypeRefXMLFragment = 4
+	TypeRefXMLHook     = 5
+	TypeRefXMLText     = 6
+)
+
+// maxAnyDepth 解析 lib0 any 时允许的最大嵌套深度
+const maxAnyDepth = 100
+
+var errInvalidAny = errors.New("yjs: 无法解析的 any 值")
+
+// content Item 携带的内容
+//
+// 服务端不需要理解 JSON/any 等内容的含义，只保留原始编码，
+// 拆分和合并时按元素边界处理即可。
+type content interface {
+	ref() uint8
+	length() uint64
+	countable() bool
+	// splice 在 offset 处拆分，返回右半部分，自身保留左半部分
+	splice(offset uint64) content
+	// mergeWith 把右侧相邻的同类内容合并进来
+	mergeWith(right content) bool
+	write(e *Encoder, offset uint64)
+}
+
+// contentDeleted 已被垃圾回收的内容，只保留长度
+type contentDeleted struct {
+	len uint64
+}
+
+func (c *contentDeleted) ref() uint8      { return contentRefDeleted }
+func (c *contentDeleted) length() uint64  { return c.len }
+func (c *contentDeleted) countable() bool { return false }
+func (c *contentDeleted) splice(offset uint64) content {
+	right := &contentDeleted{len: c.len - offset}
+	c.len = offset
+	return right
+}
+func (c *contentDeleted) mergeWith(right content) bool {
+	c.len += right.(*contentDeleted).len
+	return true
+}
+func (c *contentDeleted) write(e *Encoder, offset uint64) {
+	e.WriteVarUint(c.len - offset)
+}
+
+// contentJSON 旧版本 Yjs 的 JSON 数组内容，每个元素是一个 JSON 字符串
+type contentJSON struct {
+	arr []string
+}
+
+func (c *contentJSON) ref() uint8      { return contentRefJSON }
+func (c *contentJSON) length() uint64  { return uint64(len(c.arr)) }
+func (c *contentJSON) countable() bool { return true }
+func (c *contentJSON) splice(offset uint64) content {
+	right := &contentJSON{arr: append([]string(nil), c.arr[offset:]...)}
+	c.arr = c.arr[:offset:offset]
+	return right
+}
+func (c *contentJSON) mergeWith(right content) bool {
+	c.arr = append(c.arr, right.(*contentJSON).arr...)
+	return true
+}
+func (c *contentJSON) write(e *Encoder, offset uint64) {
+	e.WriteVarUint(uint64(len(c.arr)) - offset)
+	for _, s := range c.arr[offset:] {
+		e.WriteVarString(s)
+	}
+}
+
+// contentBinary 二进制内容
+type contentBinary struct {
+	data []byte
+}
+
+func (c *contentBinary) ref() uint8                 { return contentRefBinary }
+func (c *contentBinary) length() uint64             { return 1 }
+func (c *contentBinary) countable() bool            { return true }
+func (c *contentBinary) splice(uint64) content      { panic("yjs: binary 内容不可拆分") }
+func (c *contentBinary) mergeWith(content) bool     { return false }
+func (c *contentBinary) write(e *Encoder, _ uint64) { e.WriteVarBytes(c.data) }
+
+// contentString 文本内容，以 UTF-16 存储，长度和位置都以 UTF-16 码元计算（与 JavaScript 一致）
+type contentString struct {
+	str []uint16
+}
+
+func newContentString(s string) *contentString {
+	return &contentString{str: utf16.Encode([]rune(s))}
+}
+
+func (c *contentString) ref() uint8      { return contentRefString }
+func (c *contentString) length() uint64  { return uint64(len(c.str)) }
+func (c *contentString) countable() bool { return true }
+func (c *contentString) splice(offset uint64) content {
+	right := &contentString{str: append([]uint16(nil), c.str[offset:]...)}
+	c.str = c.str[:offset:offset]
+	// 拆开了代理对时两边都替换成 U+FFFD，与 Yjs 行为一致
+	if last := c.str[offset-1]; last >= 0xd800 && last <= 0xdbff {
+		c.str[offset-1] = 0xfffd
+		right.str[0] = 0xfffd
+	}
+	return right
+}
+func (c *contentString) mergeWith(right content) bool {
+	c.str = append(c.str, right.(*contentString).str...)
+	return true
+}
+func (c *contentString) write(e *Encoder, offset uint64) {
+	e.WriteVarString(string(utf16.Decode(c.str[offset:])))
+}
+
+// contentEmbed 富文本中的嵌入对象（JSON）
+type contentEmbed struct {
+	raw string
+}
+
+func (c *contentEmbed) ref() uint8                 { return contentRefEmbed }
+func (c *contentEmbed) length() uint64             { return 1 }
+func (c *contentEmbed) countable() bool            { return true }
+func (c *contentEmbed) splice(uint64) content      { panic("yjs: embed 内容不可拆分") }
+func (c *contentEmbed) mergeWith(content) bool     { return false }
+func (c *contentEmbed) write(e *Encoder, _ uint64) { e.WriteVarString(c.raw) }
+
+// contentFormat 富文本格式标记，不占位置
+type contentFormat struct {
+	key   string
+	value string // JSON
+}
+
+func (c *contentFormat) ref() uint8             { return contentRefFormat }
+func (c *contentFormat) length() uint64         { return 1 }
+func (c *contentFormat) countable() bool        { return false }
+func (c *contentFormat) splice(uint64) content  { panic("yjs: format 内容不可拆分") }
+func (c *contentFormat) mergeWith(content) bool { return false }
+func (c *contentFormat) write(e *Encoder, _ uint64) {
+	e.WriteVarString(c.key)
+	e.WriteVarString(c.value)
+}
+
+// contentType 嵌套的共享类型
+type contentType struct {
+	typ *Type
+}
+
+func (c *contentType) ref() uint8             { return contentRefType }
+func (c *contentType) length() uint64         { return 1 }
+func (c *contentType) countable() bool        { return true }
+func (c *contentType) splice(uint64) content  { panic("yjs: type 内容不可拆分") }
+func (c *contentType) mergeWith(content) bool { return false }
+func (c *contentType) write(e *Encoder, _ uint64) {
+	e.WriteVarUint(c.typ.ref)
+	if c.typ.ref == TypeRefXMLElement || c.typ.ref == TypeRefXMLHook {
+		e.WriteVarString(c.typ.key)
+	}
+}
+
+// contentAny 数组/Map 中的普通值，每个元素保留 lib0 any 的原始编码
+type contentAny struct {
+	arr [][]byte
+}
+
+func (c *contentAny) ref() uint8      { return contentRefAny }
+func (c *contentAny) length() uint64  { return uint64(len(c.arr)) }
+func (c *contentAny) countable() bool { return true }
+func (c *contentAny) splice(offset uint64) content {
+	right := &contentAny{arr: append([][]byte(nil), c.arr[offset:]...)}
+	c.arr = c.arr[:offset:offset]
+	return right
+}
+func (c *contentAny) mergeWith(right content) bool {
+	c.arr = append(c.arr, right.(*contentAny).arr...)
+	return true
+}
+func (c *contentAny) write(e *Encoder, offset uint64) {
+	e.WriteVarUint(uint64(len(c.arr)) - offset)
+	for _, raw := range c.arr[offset:] {
+		e.WriteRaw(raw)
+	}
+}
+
+// contentDoc 子文档引用
+type contentDoc struct {
+	guid string
+	opts []byte // lib0 any 原始编码
+}
+
+func (c *contentDoc) ref() uint8             { return contentRefDoc }
+func (c *contentDoc) length() uint64         { return 1 }
+func (c *contentDoc) countable() bool        { return true }
+func (c *contentDoc) splice(uint64) content  { panic("yjs: doc 内容不可拆分") }
+func (c *contentDoc) mergeWith(content) bool { return false }
+func (c *contentDoc) write(e *Encoder, _ uint64) {
+	e.WriteVarString(c.guid)
+	e.WriteRaw(c.opts)
+}
+
+// readContent 按 info 中的内容类型读取 Item 内容
+func readContent(d *Decoder, doc *Doc, info uint8) (content, error) {
+	switch info & 0x1f {
+	case contentRefDeleted:
+		n, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &contentDeleted{len: n}, nil
+	case contentRefJSON:
+		n, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]string, 0, min(n, uint64(len(d.Remaining()))))
+		for i := uint64(0); i < n; i++ {
+			s, err := readLenientString(d)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, s)
+		}
+		return &contentJSON{arr: arr}, nil
+	case contentRefBinary:
+		data, err := d.ReadVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		return &contentBinary{data: append([]byte(nil), data...)}, nil
+	case contentRefString:
+		s, err := readLenientString(d)
+		if err != nil {
+			return nil, err
+		}
+		return newContentString(s), nil
+	case contentRefEmbed:
+		s, err := readLenientString(d)
+		if err != nil {
+			return nil, err
+		}
+		return &contentEmbed{raw: s}, nil
+	case contentRefFormat:
+		key, err := readLenientString(d)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readLenientString(d)
+		if err != nil {
+			return nil, err
+		}
+		return &contentFormat{key: key, value: value}, nil
+	case contentRefType:
+		ref, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		if ref > TypeRefXMLText {
+			return nil, fmt.Errorf("yjs: 未知的共享类型 %d", ref)
+		}
+		typ := newType(doc, ref)
+		if ref == TypeRefXMLElement || ref == TypeRefXMLHook {
+			if typ.key, err = readLenientString(d); err != nil {
+				return nil, err
+			}
+		}
+		return &contentType{typ: typ}, nil
+	case contentRefAny:
+		n, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([][]byte, 0, min(n, uint64(len(d.Remaining()))))
+		for i := uint64(0); i < n; i++ {
+			raw, err := readAnyRaw(d)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, raw)
+		}
+		return &contentAny{arr: arr}, nil
+	case contentRefDoc:
+		guid, err := readLenientString(d)
+		if err != nil {
+			return nil, err
+		}
+		opts, err := readAnyRaw(d)
+		if err != nil {
+			return nil, err
+		}
+		return &contentDoc{guid: guid, opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("yjs: 未知的内容类型 %d", info&0x1f)
+	}
+}
+
+// readLenientString 读取字符串，非法 UTF-8 替换为 U+FFFD（与浏览器 TextDecoder 一致）
+func readLenientString(d *Decoder) (string, error) {
+	data, err := d.ReadVarBytes()
+	if err != nil {
+		return "", err
+	}
+	return string([]rune(string(data))), nil
+}
+
+// readAnyRaw 读取一个 lib0 any 值，返回它的原始编码（拷贝）
+func readAnyRaw(d *Decoder) ([]byte, error) {
+	start := d.pos
+	if err := skipAny(d, 0); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), d.buf[start:d.pos]...), nil
+}
+
+func skipAny(d *Decoder, depth int) error {
+	if depth > maxAnyDepth {
+		return errInvalidAny
+	}
+	t, err := d.ReadUint8()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case 127, 126, 121, 120: // undefined, null, false, true
+		return nil
+	case 125: // 整数
+		_, err = d.ReadVarInt()
+	case 124: // float32
+		_, err = d.ReadRaw(4)
+	case 123, 122: // float64, bigint64
+		_, err = d.ReadRaw(8)
+	case 119, 116: // 字符串, Uint8Array
+		_, err = d.ReadVarBytes()
+	case 118: // 对象
+		var n uint64
+		if n, err = d.ReadVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err = d.ReadVarBytes(); err != nil {
+				return err
+			}
+			if err = skipAny(d, depth+1); err != nil {
+				return err
+			}
+		}
+	case 117: // 数组
+		var n uint64
+		if n, err = d.ReadVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err = skipAny(d, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return errInvalidAny
+	}
+	return err
+}
@@ -0,0 +1,619 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+// ErrInvalidUpdate 更新无法解析或与文档状态矛盾
+var ErrInvalidUpdate = errors.New("yjs: 无效的更新")
+
+// Type 共享类型（Y.Text / Y.Array / Y.Map 等），服务端不区分具体类型
+type Type struct {
+	doc     *Doc
+	item    *Item  // 嵌套类型所在的 Item，根类型为空
+	name    string // 根类型的名字
+	ref     uint64
+	key     string // XmlElement 的标签名 / XmlHook 的名字
+	start   *Item
+	mapping map[string]*Item
+	length  uint64
+}
+
+func newType(doc *Doc, ref uint64) *Type {
+	return &Type{doc: doc, ref: ref, mapping: make(map[string]*Item)}
+}
+
+// Length 可见内容的长度（已删除的不计入）
+func (t *Type) Length() uint64 {
+	return t.length
+}
+
+// Options 文档选项
+type Options struct {
+	// GC 是否回收已删除内容：开启后删除的文本只保留长度，文档更小，但无法再还原历史内容
+	GC bool
+	// ClientID 服务端本地编辑使用的客户端 ID，为 0 时随机生成
+	ClientID uint64
+}
+
+// Doc 服务端的 Yjs 文档
+//
+// Doc 不是并发安全的，调用方需要自行加锁。
+type Doc struct {
+	clientID uint64
+	gc       bool
+	store    *structStore
+	share    map[string]*Type
+
+	// pending 依赖尚未到达、没能完全应用的原始更新，依赖到达后会重新应用
+	pending [][]byte
+	// watched 需要记录修改的根文本及尚未取走的修改，见 WatchText
+	watched map[string][]TextDelta
+}
+
+// NewDoc 创建空文档
+func NewDoc(opts Options) *Doc {
+	d := &Doc{
+		clientID: opts.ClientID,
+		gc:       opts.GC,
+		store:    newStructStore(),
+		share:    make(map[string]*Type),
+		watched:  make(map[string][]TextDelta),
+	}
+	if d.clientID == 0 {
+		d.clientID = randomClientID()
+	}
+	return d
+}
+
+// randomClientID 与 Yjs 一样使用 32 位随机数
+func randomClientID() uint64 {
+	return uint64(rand.Uint32())
+}
+
+// ClientID 服务端本地编辑使用的客户端 ID
+func (d *Doc) ClientID() uint64 {
+	return d.clientID
+}
+
+// getType 获取（或创建）根类型
+func (d *Doc) getType(name string) *Type {
+	t, ok := d.share[name]
+	if !ok {
+		t = newType(d, TypeRefText)
+		t.name = name
+		d.share[name] = t
+	}
+	return t
+}
+
+// RootNames 文档中已存在的根类型名字（按字典序）
+func (d *Doc) RootNames() []string {
+	names := make([]string, 0, len(d.share))
+	for name := range d.share {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StateVector 文档当前的状态向量
+func (d *Doc) StateVector() StateVector {
+	return d.store.stateVector()
+}
+
+// HasPending 是否有等待依赖的更新
+func (d *Doc) HasPending() bool {
+	return len(d.pending) > 0
+}
+
+// PendingUpdates 等待依赖的原始更新，持久化时需要和快照一起保存
+func (d *Doc) PendingUpdates() [][]byte {
+	return d.pending
+}
+
+// ApplyUpdate 应用一条 Yjs v1 更新，重复应用同一条更新是安全的
+func (d *Doc) ApplyUpdate(update []byte) error {
+	before := d.store.totalClock()
+	pending, err := d.applyUpdate(update)
+	if err != nil {
+		return err
+	}
+	if pending {
+		d.pending = append(d.pending, append([]byte(nil), update...))
+	}
+	// 只有文档状态前进了，等待中的更新才可能变得可用
+	if len(d.pending) > 0 && d.store.totalClock() != before {
+		d.retryPending()
+	}
+	return nil
+}
+
+// UpdateHasStructs 更新中是否包含新的结构（插入），只有删除集时返回 false
+func UpdateHasStructs(update []byte) bool {
+	n, err := NewDecoder(update).ReadVarUint()
+	return err == nil && n > 0
+}
+
+// UpdateClients 更新中包含结构（插入）的客户端，只有删除集时为空
+func UpdateClients(update []byte) ([]uint64, error) {
+	refs, _, err := NewDoc(Options{}).decodeUpdate(update)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+	clients := make([]uint64, 0, len(refs))
+	for client := range refs {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] < clients[j] })
+	return clients, nil
+}
+
+// ClientState 文档中已集成的 client 的时钟，即状态向量中的一项
+func (d *Doc) ClientState(client uint64) uint64 {
+	return d.store.getState(client)
+}
+
+// retryPending 重新应用等待中的更新，直到没有新的进展
+func (d *Doc) retryPending() {
+	for progress := true; progress && len(d.pending) > 0; {
+		progress = false
+		remaining := d.pending[:0]
+		for _, update := range d.pending {
+			pending, err := d.applyUpdate(update)
+			if err == nil && !pending {
+				progress = true
+				continue
+			}
+			if err == nil {
+				remaining = append(remaining, update)
+			}
+		}
+		d.pending = remaining
+	}
+}
+
+// applyUpdate 解码并集成更新，返回是否还有部分内容因缺少依赖没能应用
+func (d *Doc) applyUpdate(update []byte) (pending bool, err error) {
+	refs, ds, err := d.decodeUpdate(update)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+
+	defer func() {
+		// 结构之间互相矛盾的恶意数据会让集成过程越界，统一转换成错误
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidUpdate, r)
+		}
+	}()
+
+	tx := d.newTransaction()
+	pendingStructs := tx.integrateStructs(refs)
+	pendingDeletes := tx.applyDeleteSet(ds)
+	tx.cleanup()
+	return pendingStructs || pendingDeletes, nil
+}
+
+// EncodeStateAsUpdate 编码对方（状态向量为 sv）缺少的全部内容，sv 为空时编码整个文档
+// 结果不包含等待依赖的更新，需要完整状态时配合 PendingUpdates 使用
+func (d *Doc) EncodeStateAsUpdate(sv StateVector) []byte {
+	e := NewEncoder()
+
+	// 1. 结构：对方缺少的部分
+	clients := make(map[uint64]uint64)
+	for client := range d.store.clients {
+		state := d.store.getState(client)
+		if known := sv[client]; known < state {
+			clients[client] = known
+		}
+	}
+	e.WriteVarUint(uint64(len(clients)))
+	for _, client := range sortedClientsDesc(clients) {
+		d.store.writeStructs(e, client, clients[client])
+	}
+
+	// 2. 删除集：总是完整写入
+	ds := d.store.deleteSet()
+	ds.sortAndMerge()
+	ds.write(e)
+	return e.Bytes()
+}
+
+// decodeUpdate 解析更新中的结构和删除集，结构此时还没有集成
+func (d *Doc) decodeUpdate(update []byte) (map[uint64]*structRefs, deleteSet, error) {
+	dec := NewDecoder(update)
+	numClients, err := dec.ReadVarUint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := make(map[uint64]*structRefs)
+	for i := uint64(0); i < numClients; i++ {
+		numStructs, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		clock, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		list := make([]Struct, 0, min(numStructs, uint64(len(dec.Remaining()))))
+		for j := uint64(0); j < numStructs; j++ {
+			st, err := d.readStruct(dec, ID{Client: client, Clock: clock})
+			if err != nil {
+				return nil, nil, err
+			}
+			if st.Len() == 0 {
+				return nil, nil, errors.New("长度为 0 的结构")
+			}
+			list = append(list, st)
+			clock += st.Len()
+		}
+		if len(list) > 0 {
+			refs[client] = &structRefs{refs: list}
+		}
+	}
+
+	ds, err := readDeleteSet(dec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return refs, ds, nil
+}
+
+func (d *Doc) readStruct(dec *Decoder, id ID) (Struct, error) {
+	info, err := dec.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch info & 0x1f {
+	case contentRefGC:
+		length, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &GC{id: id, length: length}, nil
+	case structRefSkip:
+		length, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &skip{id: id, length: length}, nil
+	}
+
+	it := &Item{id: id}
+	if info&0x80 != 0 {
+		origin, err := readID(dec)
+		if err != nil {
+			return nil, err
+		}
+		it.origin = &origin
+	}
+	if info&0x40 != 0 {
+		rightOrigin, err := readID(dec)
+		if err != nil {
+			return nil, err
+		}
+		it.rightOrigin = &rightOrigin
+	}
+	if info&0xc0 == 0 {
+		// 没有 origin 时才编码父类型
+		isRoot, err := dec.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		if isRoot == 1 {
+			name, err := readLenientString(dec)
+			if err != nil {
+				return nil, err
+			}
+			it.parent = d.getType(name)
+		} else {
+			parentID, err := readID(dec)
+			if err != nil {
+				return nil, err
+			}
+			it.parentID = &parentID
+		}
+		if info&0x20 != 0 {
+			sub, err := readLenientString(dec)
+			if err != nil {
+				return nil, err
+			}
+			it.parentSub = &sub
+		}
+	}
+
+	if it.content, err = readContent(dec, d, info); err != nil {
+		return nil, err
+	}
+	it.length = it.content.length()
+	return it, nil
+}
+
+// structRefs 某个客户端待集成的结构及读取位置
+type structRefs struct {
+	i    int
+	refs []Struct
+}
+
+// transaction 一次更新的上下文
+type transaction struct {
+	doc          *Doc
+	beforeState  StateVector
+	deleteSet    deleteSet
+	mergeStructs []Struct
+}
+
+func (d *Doc) newTransaction() *transaction {
+	return &transaction{
+		doc:         d,
+		beforeState: d.store.stateVector(),
+		deleteSet:   make(deleteSet),
+	}
+}
+
+// getItemCleanStart 返回从 id 开始的结构，必要时拆分
+func (tx *transaction) getItemCleanStart(id ID) Struct {
+	store := tx.doc.store
+	structs := store.clients[id.Client]
+	index := findIndex(structs, id.Clock)
+	st := structs[index]
+	if it, ok := st.(*Item); ok && it.id.Clock < id.Clock {
+		right := splitItem(tx, it, id.Clock-it.id.Clock)
+		store.insertAt(id.Client, index+1, right)
+		return right
+	}
+	return st
+}
+
+// getItemCleanEnd 返回以 id 结束的结构，必要时拆分
+func (tx *transaction) getItemCleanEnd(id ID) Struct {
+	store := tx.doc.store
+	structs := store.clients[id.Client]
+	index := findIndex(structs, id.Clock)
+	st := structs[index]
+	if it, ok := st.(*Item); ok && id.Clock != it.id.Clock+it.length-1 {
+		right := splitItem(tx, it, id.Clock-it.id.Clock+1)
+		store.insertAt(id.Client, index+1, right)
+	}
+	return st
+}
+
+// integrateStructs 按依赖顺序集成结构（移植自 Yjs 的 integrateStructs）
+// 返回是否有结构因为缺少依赖没能集成
+func (tx *transaction) integrateStructs(clientRefs map[uint64]*structRefs) bool {
+	store := tx.doc.store
+
+	clients := make([]uint64, 0, len(clientRefs))
+	for client := range clientRefs {
+		clients = append(clients, client)
+	}
+	if len(clients) == 0 {
+		return false
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] < clients[j] })
+
+	nextTarget := func() *structRefs {
+		for len(clients) > 0 {
+			target := clientRefs[clients[len(clients)-1]]
+			if target.i < len(target.refs) {
+				return target
+			}
+			clients = clients[:len(clients)-1]
+		}
+		return nil
+	}
+
+	current := nextTarget()
+	if current == nil {
+		return false
+	}
+
+	var stack []Struct
+	hasRest := false
+	state := make(map[uint64]uint64)
+
+	// 栈中的结构（以及它们所属客户端后续的结构）本次都无法集成
+	moveStackToRest := func() {
+		for _, st := range stack {
+			client := st.ID().Client
+			if refs, ok := clientRefs[client]; ok {
+				refs.i = 0
+				refs.refs = nil
+				delete(clientRefs, client)
+			}
+			for i, c := range clients {
+				if c == client {
+					clients = append(clients[:i], clients[i+1:]...)
+					break
+				}
+			}
+		}
+		hasRest = true
+		stack = stack[:0]
+	}
+
+	head := current.refs[current.i]
+	current.i++
+	for {
+		if _, isSkip := head.(*skip); !isSkip {
+			id := head.ID()
+			localClock, ok := state[id.Client]
+			if !ok {
+				localClock = store.getState(id.Client)
+				state[id.Client] = localClock
+			}
+
+			if localClock < id.Clock {
+				// 同一客户端前面的结构还没到
+				stack = append(stack, head)
+				moveStackToRest()
+			} else {
+				offset := localClock - id.Clock
+				missing, isMissing := uint64(0), false
+				if it, ok := head.(*Item); ok {
+					missing, isMissing = it.getMissing(tx)
+				}
+
+				if isMissing {
+					stack = append(stack, head)
+					refs := clientRefs[missing]
+					if refs == nil || refs.i == len(refs.refs) {
+						moveStackToRest()
+					} else {
+						// 先集成缺少的依赖
+						head = refs.refs[refs.i]
+						refs.i++
+						continue
+					}
+				} else if offset < head.Len() {
+					length := head.Len()
+					switch st := head.(type) {
+					case *Item:
+						st.integrate(tx, offset)
+					case *GC:
+						st.integrate(tx, offset)
+					}
+					state[id.Client] = id.Clock + length
+				}
+			}
+		}
+
+		switch {
+		case len(stack) > 0:
+			head = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		case current != nil && current.i < len(current.refs):
+			head = current.refs[current.i]
+			current.i++
+		default:
+			if current = nextTarget(); current == nil {
+				return hasRest
+			}
+			head = current.refs[current.i]
+			current.i++
+		}
+	}
+}
+
+// applyDeleteSet 应用删除集，返回是否有删除因为对应结构还没到而没能应用
+func (tx *transaction) applyDeleteSet(ds deleteSet) bool {
+	store := tx.doc.store
+	hasRest := false
+
+	for client, ranges := range ds {
+		state := store.getState(client)
+		for _, r := range ranges {
+			clock, end := r.clock, r.clock+r.length
+			if clock >= state {
+				hasRest = true
+				continue
+			}
+			if state < end {
+				hasRest = true
+			}
+
+			structs := store.clients[client]
+			index := findIndex(structs, clock)
+			if st := structs[index]; !st.Deleted() && st.ID().Clock < clock {
+				it := st.(*Item)
+				store.insertAt(client, index+1, splitItem(tx, it, clock-it.id.Clock))
+				index++
+			}
+			for structs = store.clients[client]; index < len(structs); structs = store.clients[client] {
+				st := structs[index]
+				index++
+				if st.ID().Clock >= end {
+					break
+				}
+				it, ok := st.(*Item)
+				if !ok || it.deleted {
+					continue
+				}
+				if end < it.id.Clock+it.length {
+					store.insertAt(client, index, splitItem(tx, it, end-it.id.Clock))
+				}
+				it.delete(tx)
+			}
+		}
+	}
+	return hasRest
+}
+
+// cleanup 事务结束：回收已删除内容，合并相邻结构
+func (tx *transaction) cleanup() {
+	store := tx.doc.store
+	ds := tx.deleteSet
+	ds.sortAndMerge()
+
+	// 1. 垃圾回收
+	if tx.doc.gc {
+		for client, ranges := range ds {
+			structs := store.clients[client]
+			for i := len(ranges) - 1; i >= 0; i-- {
+				r := ranges[i]
+				end := r.clock + r.length
+				for si := findIndex(structs, r.clock); si < len(structs); si++ {
+					st := structs[si]
+					if st.ID().Clock >= end {
+						break
+					}
+					if it, ok := st.(*Item); ok && it.deleted && !it.keep {
+						it.gc(store, false)
+					}
+				}
+			}
+		}
+	}
+
+	// 2. 合并删除区间内的结构
+	for client, ranges := range ds {
+		for i := len(ranges) - 1; i >= 0; i-- {
+			r := ranges[i]
+			structs := store.clients[client]
+			si := min(len(structs)-1, 1+findIndex(structs, r.clock+r.length-1))
+			for si > 0 && store.clients[client][si].ID().Clock >= r.clock {
+				si -= 1 + store.tryMergeWithLefts(client, si)
+			}
+		}
+	}
+
+	// 3. 合并本次新增的结构
+	for client := range store.clients {
+		before := tx.beforeState[client]
+		if before == store.getState(client) {
+			continue
+		}
+		structs := store.clients[client]
+		first := 1
+		if before > 0 {
+			first = max(findIndex(structs, before), 1)
+		}
+		for i := len(store.clients[client]) - 1; i >= first; {
+			i -= 1 + store.tryMergeWithLefts(client, i)
+		}
+	}
+
+	// 4. 合并拆分产生的结构
+	for i := len(tx.mergeStructs) - 1; i >= 0; i-- {
+		id := tx.mergeStructs[i].ID()
+		structs := store.clients[id.Client]
+		pos := findIndex(structs, id.Clock)
+		if pos+1 < len(structs) && store.tryMergeWithLefts(id.Client, pos+1) > 1 {
+			continue
+		}
+		if pos > 0 {
+			store.tryMergeWithLefts(id.Client, pos)
+		}
+	}
+}
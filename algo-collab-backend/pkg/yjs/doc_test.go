@@ -0,0 +1,258 @@
+package yjs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textItem 构造一条只插入一段文本的更新
+// origin/rightOrigin 为空时父类型是根类型 name
+func textItem(client, clock uint64, origin, rightOrigin *ID, name, text string) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(1) // 1 个客户端
+	e.WriteVarUint(1) // 1 个结构
+	e.WriteVarUint(client)
+	e.WriteVarUint(clock)
+
+	info := uint8(contentRefString)
+	if origin != nil {
+		info |= 0x80
+	}
+	if rightOrigin != nil {
+		info |= 0x40
+	}
+	e.WriteUint8(info)
+	if origin != nil {
+		writeID(e, *origin)
+	}
+	if rightOrigin != nil {
+		writeID(e, *rightOrigin)
+	}
+	if origin == nil && rightOrigin == nil {
+		e.WriteVarUint(1)
+		e.WriteVarString(name)
+	}
+	e.WriteVarString(text)
+	e.WriteVarUint(0) // 空删除集
+	return e.Bytes()
+}
+
+func deleteUpdate(client, clock, length uint64) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(0)
+	e.WriteVarUint(1)
+	e.WriteVarUint(client)
+	e.WriteVarUint(1)
+	e.WriteVarUint(clock)
+	e.WriteVarUint(length)
+	return e.Bytes()
+}
+
+func TestDoc_ApplyUpdate(t *testing.T) {
+	doc := NewDoc(Options{GC: true})
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "monaco", "abc")))
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")))
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 2, 1)))
+
+	assert.Equal(t, "aXb", doc.GetText("monaco").String())
+	assert.Equal(t, uint64(3), doc.GetText("monaco").Length())
+	assert.Equal(t, StateVector{1: 3, 2: 1}, doc.StateVector())
+
+	// 重复应用不影响结果
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")))
+	assert.Equal(t, "aXb", doc.GetText("monaco").String())
+}
+
+func TestDoc_ConcurrentInsertsConverge(t *testing.T) {
+	base := textItem(1, 0, nil, nil, "t", "abc")
+	fromY := textItem(2, 0, &ID{1, 2}, nil, "", "Y")
+	fromZ := textItem(3, 0, &ID{1, 2}, nil, "", "Z")
+
+	for _, order := range [][][]byte{{base, fromY, fromZ}, {base, fromZ, fromY}} {
+		doc := NewDoc(Options{})
+		for _, update := range order {
+			require.NoError(t, doc.ApplyUpdate(update))
+		}
+		// origin 相同时客户端 ID 小的在左边
+		assert.Equal(t, "abcYZ", doc.GetText("t").String())
+	}
+}
+
+func TestDoc_PendingUpdates(t *testing.T) {
+	doc := NewDoc(Options{})
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")))
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 2, 1)))
+	assert.True(t, doc.HasPending())
+	assert.Equal(t, "", doc.GetText("t").String())
+
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "t", "abc")))
+	assert.False(t, doc.HasPending())
+	assert.Equal(t, "aXb", doc.GetText("t").String())
+}
+
+func TestDoc_TakeTextDeltas(t *testing.T) {
+	doc := NewDoc(Options{})
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "a", "abc")))
+	assert.Empty(t, doc.TakeTextDeltas())
+
+	// 只记录被跟踪的文本，远端更新和本地编辑都记录，下标按修改发生时计算
+	doc.WatchText("a")
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")))
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 2, 1)))
+	require.NoError(t, doc.ApplyUpdate(textItem(3, 0, nil, nil, "b", "xyz")))
+	doc.GetText("a").Insert(0, "😀")
+	assert.Equal(t, map[string][]TextDelta{
+		"a": {{Index: 1, Insert: "X"}, {Index: 3, Delete: 1}, {Index: 0, Insert: "😀"}},
+	}, doc.TakeTextDeltas())
+	assert.Empty(t, doc.TakeTextDeltas())
+
+	// 重复应用没有变化的更新不记录
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")))
+	assert.Empty(t, doc.TakeTextDeltas())
+
+	doc.UnwatchText("a")
+	doc.GetText("a").Delete(0, 1)
+	assert.Empty(t, doc.TakeTextDeltas())
+}
+
+func TestDoc_EncodeStateAsUpdate(t *testing.T) {
+	for _, gc := range []bool{true, false} {
+		doc := NewDoc(Options{GC: gc})
+		require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "t", "hello world")))
+		require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 5, 6)))
+		require.NoError(t, doc.ApplyUpdate(textItem(2, 0, &ID{1, 4}, &ID{1, 5}, "", "!")))
+
+		// 完整状态
+		restored := NewDoc(Options{GC: gc})
+		require.NoError(t, restored.ApplyUpdate(doc.EncodeStateAsUpdate(nil)))
+		assert.Equal(t, "hello!", restored.GetText("t").String())
+		assert.Equal(t, doc.StateVector(), restored.StateVector())
+
+		// 差量：对方已经有 client 1 的全部内容
+		partial := NewDoc(Options{GC: gc})
+		require.NoError(t, partial.ApplyUpdate(textItem(1, 0, nil, nil, "t", "hello world")))
+		diff := doc.EncodeStateAsUpdate(partial.StateVector())
+		require.NoError(t, partial.ApplyUpdate(diff))
+		assert.Equal(t, "hello!", partial.GetText("t").String())
+	}
+}
+
+func TestDoc_GCDropsDeletedContent(t *testing.T) {
+	withGC := NewDoc(Options{GC: true})
+	withoutGC := NewDoc(Options{GC: false})
+	for _, doc := range []*Doc{withGC, withoutGC} {
+		require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "t", "some long text that will be deleted")))
+		require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 0, 35)))
+	}
+
+	assert.Less(t, len(withGC.EncodeStateAsUpdate(nil)), len(withoutGC.EncodeStateAsUpdate(nil)))
+}
+
+func TestText_LocalEdits(t *testing.T) {
+	server := NewDoc(Options{ClientID: 100})
+	client := NewDoc(Options{ClientID: 200})
+
+	apply := func(update []byte) {
+		require.NoError(t, client.ApplyUpdate(update))
+	}
+
+	text := server.GetText("t")
+	apply(text.Insert(0, "hello"))
+	apply(text.Insert(5, " world"))
+	apply(text.Insert(5, ","))
+	apply(text.Delete(0, 1))
+	apply(text.Insert(0, "H"))
+
+	assert.Equal(t, "Hello, world", text.String())
+	assert.Equal(t, "Hello, world", client.GetText("t").String())
+}
+
+func TestText_UTF16Positions(t *testing.T) {
+	doc := NewDoc(Options{})
+	text := doc.GetText("t")
+	text.Insert(0, "a😀b")
+	assert.Equal(t, uint64(4), text.Length())
+
+	text.Delete(3, 1)
+	assert.Equal(t, "a😀", text.String())
+
+	text.Insert(0, "xy")
+	assert.Equal(t, "ya😀", text.Slice(1, 5))
+	assert.Equal(t, "😀", text.Slice(3, 10))
+	assert.Empty(t, text.Slice(5, 5))
+}
+
+func TestDoc_RejectsMalformedUpdate(t *testing.T) {
+	doc := NewDoc(Options{})
+	update := textItem(1, 0, nil, nil, "t", "abc")
+	assert.ErrorIs(t, doc.ApplyUpdate(update[:len(update)-2]), ErrInvalidUpdate)
+	assert.Equal(t, "", doc.GetText("t").String())
+}
+
+func TestText_RunsAndUpdateClients(t *testing.T) {
+	doc := NewDoc(Options{})
+	insertX := textItem(2, 0, &ID{1, 0}, &ID{1, 1}, "", "X")
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "t", "abc")))
+	require.NoError(t, doc.ApplyUpdate(insertX))
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 2, 1)))
+
+	// 删除的字符不出现，同一客户端被拆开的内容分成两段
+	assert.Equal(t, []TextRun{{Client: 1, Text: "a"}, {Client: 2, Text: "X"}, {Client: 1, Text: "b"}}, doc.GetText("t").Runs())
+
+	clients, err := UpdateClients(insertX)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, clients)
+	clients, err = UpdateClients(deleteUpdate(1, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, clients)
+	assert.Equal(t, uint64(3), doc.ClientState(1))
+}
+
+func TestText_RelativePosition(t *testing.T) {
+	doc := NewDoc(Options{GC: true})
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "monaco", "hello world")))
+	text := doc.GetText("monaco")
+
+	// 1. 编码和 Yjs 一致
+	pos := text.RelativePosition(6, 0)
+	assert.Equal(t, []byte{0, 1, 6, 0}, pos.Encode())
+	end := text.RelativePosition(text.Length(), 0)
+	assert.Equal(t, append([]byte{1, 6}, append([]byte("monaco"), 0)...), end.Encode())
+	decoded, err := DecodeRelativePosition(pos.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, pos, decoded)
+	_, err = DecodeRelativePosition([]byte{2, 1, 0})
+	assert.ErrorIs(t, err, ErrInvalidPosition)
+
+	// 2. 前面插入内容后仍然指向 "world"
+	require.NoError(t, doc.ApplyUpdate(textItem(2, 0, nil, &ID{1, 0}, "", "XX")))
+	index, ok := text.Resolve(pos)
+	require.True(t, ok)
+	assert.Equal(t, uint64(8), index)
+	index, ok = text.Resolve(end)
+	require.True(t, ok)
+	assert.Equal(t, uint64(13), index)
+
+	// 3. 指向的字符被删除后落在原来的位置
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 6, 1)))
+	index, ok = text.Resolve(pos)
+	require.True(t, ok)
+	assert.Equal(t, uint64(8), index)
+	assert.Equal(t, "XXhello orld", text.String())
+
+	// 4. 左关联的位置
+	before := text.RelativePosition(2, -1)
+	assert.Equal(t, &ID{2, 1}, before.Item)
+	index, _ = text.Resolve(before)
+	assert.Equal(t, uint64(2), index)
+
+	// 5. 尚未同步的字符、其他文本中的位置都无法解析
+	_, ok = text.Resolve(&RelativePosition{Item: &ID{3, 0}})
+	assert.False(t, ok)
+	_, ok = doc.GetText("other").Resolve(pos)
+	assert.False(t, ok)
+	_, ok = doc.GetText("other").Resolve(end)
+	assert.False(t, ok)
+}
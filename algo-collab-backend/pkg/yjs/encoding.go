@@ -0,0 +1,214 @@
+// Package yjs 实现与 Yjs / y-protocols 兼容的二进制编码
+//
+// 整数使用 lib0 的变长编码（每字节低 7 位存数据，最高位表示是否还有后续字节），
+// 字节数组和字符串以长度前缀 + 内容的形式写入。
+package yjs
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnexpectedEOF 数据在读取完成前结束
+	ErrUnexpectedEOF = errors.New("yjs: 数据不完整")
+	// ErrVarIntOverflow 变长整数超出 64 位
+	ErrVarIntOverflow = errors.New("yjs: 变长整数溢出")
+	// ErrInvalidUTF8 字符串不是合法的 UTF-8
+	ErrInvalidUTF8 = errors.New("yjs: 字符串不是合法的 UTF-8")
+)
+
+// Encoder 顺序写入的编码器
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder 创建编码器
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Bytes 返回已写入的数据
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Len 已写入的字节数
+func (e *Encoder) Len() int {
+	return len(e.buf)
+}
+
+// WriteUint8 写入单个字节
+func (e *Encoder) WriteUint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+// WriteVarUint 写入无符号变长整数
+func (e *Encoder) WriteVarUint(v uint64) {
+	for v > 0x7f {
+		e.buf = append(e.buf, byte(v&0x7f)|0x80)
+		v >>= 7
+	}
+	e.buf = append(e.buf, byte(v))
+}
+
+// WriteVarInt 写入有符号变长整数
+// 第一个字节：最高位为继续位，次高位为符号位，剩余 6 位存数据
+func (e *Encoder) WriteVarInt(v int64) {
+	negative := v < 0
+	var n uint64
+	if negative {
+		n = uint64(-v)
+	} else {
+		n = uint64(v)
+	}
+
+	first := byte(n & 0x3f)
+	if negative {
+		first |= 0x40
+	}
+	n >>= 6
+	if n > 0 {
+		first |= 0x80
+	}
+	e.buf = append(e.buf, first)
+
+	for n > 0 {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n > 0 {
+			b |= 0x80
+		}
+		e.buf = append(e.buf, b)
+	}
+}
+
+// WriteVarBytes 写入长度前缀的字节数组
+func (e *Encoder) WriteVarBytes(data []byte) {
+	e.WriteVarUint(uint64(len(data)))
+	e.buf = append(e.buf, data...)
+}
+
+// WriteVarString 写入长度前缀的 UTF-8 字符串
+func (e *Encoder) WriteVarString(s string) {
+	e.WriteVarUint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// WriteRaw 直接写入字节，不带长度前缀
+func (e *Encoder) WriteRaw(data []byte) {
+	e.buf = append(e.buf, data...)
+}
+
+// Decoder 顺序读取的解码器
+type Decoder struct {
+	buf []byte
+	pos int
+}
+
+// NewDecoder 创建解码器
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{buf: data}
+}
+
+// HasContent 是否还有未读取的数据
+func (d *Decoder) HasContent() bool {
+	return d.pos < len(d.buf)
+}
+
+// Remaining 返回剩余未读取的数据（不拷贝）
+func (d *Decoder) Remaining() []byte {
+	return d.buf[d.pos:]
+}
+
+// ReadUint8 读取单个字节
+func (d *Decoder) ReadUint8() (uint8, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	v := d.buf[d.pos]
+	d.pos++
+	return v, nil
+}
+
+// ReadVarUint 读取无符号变长整数
+func (d *Decoder) ReadVarUint() (uint64, error) {
+	var v uint64
+	var shift uint
+	for {
+		if d.pos >= len(d.buf) {
+			return 0, ErrUnexpectedEOF
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		if shift >= 64 || (shift == 63 && b > 1) {
+			return 0, ErrVarIntOverflow
+		}
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+		shift += 7
+	}
+}
+
+// ReadVarInt 读取有符号变长整数
+func (d *Decoder) ReadVarInt() (int64, error) {
+	first, err := d.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+	v := uint64(first & 0x3f)
+	negative := first&0x40 != 0
+	shift := uint(6)
+
+	more := first&0x80 != 0
+	for more {
+		b, err := d.ReadUint8()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= 64 {
+			return 0, ErrVarIntOverflow
+		}
+		v |= uint64(b&0x7f) << shift
+		shift += 7
+		more = b&0x80 != 0
+	}
+
+	if negative {
+		return -int64(v), nil
+	}
+	return int64(v), nil
+}
+
+// ReadVarBytes 读取长度前缀的字节数组（返回的切片引用原数据）
+func (d *Decoder) ReadVarBytes() ([]byte, error) {
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+	return d.ReadRaw(n)
+}
+
+// ReadVarString 读取长度前缀的 UTF-8 字符串
+func (d *Decoder) ReadVarString() (string, error) {
+	data, err := d.ReadVarBytes()
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", ErrInvalidUTF8
+	}
+	return string(data), nil
+}
+
+// ReadRaw 读取 n 个字节（返回的切片引用原数据）
+func (d *Decoder) ReadRaw(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, ErrUnexpectedEOF
+	}
+	data := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return data, nil
+}
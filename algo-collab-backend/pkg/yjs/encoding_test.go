@@ -0,0 +1,81 @@
+package yjs
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarUint(t *testing.T) {
+	tests := []struct {
+		value uint64
+		want  []byte
+	}{
+		{0, []byte{0}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{300, []byte{0xac, 0x02}},
+		{math.MaxUint32, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	}
+
+	for _, tt := range tests {
+		e := NewEncoder()
+		e.WriteVarUint(tt.value)
+		assert.Equal(t, tt.want, e.Bytes())
+
+		got, err := NewDecoder(tt.want).ReadVarUint()
+		require.NoError(t, err)
+		assert.Equal(t, tt.value, got)
+	}
+}
+
+func TestVarInt(t *testing.T) {
+	for _, v := range []int64{0, 1, -1, 63, 64, -64, 1 << 20, -(1 << 40)} {
+		e := NewEncoder()
+		e.WriteVarInt(v)
+		got, err := NewDecoder(e.Bytes()).ReadVarInt()
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+}
+
+func TestDecoder_Truncated(t *testing.T) {
+	_, err := NewDecoder([]byte{0x80}).ReadVarUint()
+	assert.ErrorIs(t, err, ErrUnexpectedEOF)
+
+	_, err = NewDecoder([]byte{5, 'a'}).ReadVarString()
+	assert.ErrorIs(t, err, ErrUnexpectedEOF)
+}
+
+func TestParseMessage(t *testing.T) {
+	msg, err := ParseMessage(EncodeUpdate([]byte{1, 2, 3}))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(MessageSync), msg.Type)
+	assert.Equal(t, uint64(SyncUpdate), msg.SubType)
+	assert.Equal(t, []byte{1, 2, 3}, msg.Payload)
+
+	msg, err = ParseMessage(EncodeSyncStep1(EmptyStateVector))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(SyncStep1), msg.SubType)
+
+	_, err = ParseMessage([]byte{42})
+	assert.ErrorIs(t, err, ErrUnknownMessage)
+}
+
+func TestAwarenessUpdate_RoundTrip(t *testing.T) {
+	entries := []AwarenessEntry{
+		{ClientID: 12345, Clock: 3, State: json.RawMessage(`{"user":{"name":"alice"}}`)},
+		{ClientID: 7, Clock: 1},
+	}
+
+	decoded, err := DecodeAwarenessUpdate(EncodeAwarenessUpdate(entries))
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, uint64(12345), decoded[0].ClientID)
+	assert.JSONEq(t, `{"user":{"name":"alice"}}`, string(decoded[0].State))
+	assert.False(t, decoded[0].IsRemoved())
+	assert.True(t, decoded[1].IsRemoved())
+}
@@ -0,0 +1,148 @@
+package yjs
+
+import "sort"
+
+// ID 唯一标识一个结构：由哪个客户端在它的第几个时钟创建
+type ID struct {
+	Client uint64
+	Clock  uint64
+}
+
+// idEqual 两个可空 ID 是否相同（都为空也算相同）
+func idEqual(a, b *ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// StateVector 每个客户端已知的下一个时钟
+type StateVector map[uint64]uint64
+
+// Encode 编码状态向量
+func (sv StateVector) Encode() []byte {
+	e := NewEncoder()
+	clients := sortedClientsDesc(sv)
+	e.WriteVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		e.WriteVarUint(client)
+		e.WriteVarUint(sv[client])
+	}
+	return e.Bytes()
+}
+
+// DecodeStateVector 解析状态向量，空数据视为空状态向量
+func DecodeStateVector(data []byte) (StateVector, error) {
+	sv := make(StateVector)
+	if len(data) == 0 {
+		return sv, nil
+	}
+
+	d := NewDecoder(data)
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		client, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		clock, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		sv[client] = clock
+	}
+	return sv, nil
+}
+
+// deleteRange 删除集中的一段连续时钟
+type deleteRange struct {
+	clock  uint64
+	length uint64
+}
+
+// deleteSet 按客户端分组的删除区间
+type deleteSet map[uint64][]deleteRange
+
+func (ds deleteSet) add(client, clock, length uint64) {
+	ds[client] = append(ds[client], deleteRange{clock: clock, length: length})
+}
+
+// sortAndMerge 排序并合并重叠或相邻的区间
+func (ds deleteSet) sortAndMerge() {
+	for client, ranges := range ds {
+		if len(ranges) == 0 {
+			delete(ds, client)
+			continue
+		}
+		sort.Slice(ranges, func(i, j int) bool { return ranges[i].clock < ranges[j].clock })
+		merged := ranges[:1]
+		for _, r := range ranges[1:] {
+			last := &merged[len(merged)-1]
+			if last.clock+last.length >= r.clock {
+				if end := r.clock + r.length; end > last.clock+last.length {
+					last.length = end - last.clock
+				}
+			} else {
+				merged = append(merged, r)
+			}
+		}
+		ds[client] = merged
+	}
+}
+
+func (ds deleteSet) write(e *Encoder) {
+	clients := sortedClientsDesc(ds)
+	e.WriteVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		ranges := ds[client]
+		e.WriteVarUint(client)
+		e.WriteVarUint(uint64(len(ranges)))
+		for _, r := range ranges {
+			e.WriteVarUint(r.clock)
+			e.WriteVarUint(r.length)
+		}
+	}
+}
+
+func readDeleteSet(d *Decoder) (deleteSet, error) {
+	ds := make(deleteSet)
+	n, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		client, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		count, err := d.ReadVarUint()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < count; j++ {
+			clock, err := d.ReadVarUint()
+			if err != nil {
+				return nil, err
+			}
+			length, err := d.ReadVarUint()
+			if err != nil {
+				return nil, err
+			}
+			ds.add(client, clock, length)
+		}
+	}
+	return ds, nil
+}
+
+// sortedClientsDesc 按客户端 ID 从大到小排序（与 Yjs 的编码顺序一致）
+func sortedClientsDesc[V any](m map[uint64]V) []uint64 {
+	clients := make([]uint64, 0, len(m))
+	for client := range m {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] > clients[j] })
+	return clients
+}
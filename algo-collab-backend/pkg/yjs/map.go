@@ -0,0 +1,142 @@
+package yjs
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Map 根级 Y.Map 的只读视图和服务端编辑入口
+// 只支持普通值（lib0 any），值为嵌套共享类型的键读取时忽略
+type Map struct {
+	doc *Doc
+	typ *Type
+}
+
+// GetMap 获取根类型 name 对应的 Map（不存在时创建空 Map）
+func (d *Doc) GetMap(name string) *Map {
+	return &Map{doc: d, typ: d.getType(name)}
+}
+
+// Keys 当前存在的键（按字典序）
+func (m *Map) Keys() []string {
+	keys := make([]string, 0, len(m.typ.mapping))
+	for key, it := range m.typ.mapping {
+		if !it.deleted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get 读取 key 对应的值，类型见 DecodeAny
+func (m *Map) Get(key string) (any, bool) {
+	it, ok := m.typ.mapping[key]
+	if !ok || it.deleted {
+		return nil, false
+	}
+	return itemValue(it)
+}
+
+// Entries 所有键值对，无法解析的值忽略
+func (m *Map) Entries() map[string]any {
+	entries := make(map[string]any, len(m.typ.mapping))
+	for key, it := range m.typ.mapping {
+		if it.deleted {
+			continue
+		}
+		if v, ok := itemValue(it); ok {
+			entries[key] = v
+		}
+	}
+	return entries
+}
+
+// Len 当前存在的键的数量
+func (m *Map) Len() int {
+	n := 0
+	for _, it := range m.typ.mapping {
+		if !it.deleted {
+			n++
+		}
+	}
+	return n
+}
+
+// itemValue Map 中一个值的 Item 的内容（Y.Map 每次 set 写入一个长度为 1 的 Item）
+func itemValue(it *Item) (any, bool) {
+	switch c := it.content.(type) {
+	case *contentAny:
+		if len(c.arr) == 0 {
+			return nil, false
+		}
+		v, err := DecodeAny(c.arr[len(c.arr)-1])
+		return v, err == nil
+	case *contentJSON:
+		// 旧版本 Yjs 写入的 JSON 内容
+		if len(c.arr) == 0 {
+			return nil, false
+		}
+		var v any
+		if c.arr[len(c.arr)-1] == "undefined" {
+			return nil, true
+		}
+		err := json.Unmarshal([]byte(c.arr[len(c.arr)-1]), &v)
+		return v, err == nil
+	case *contentBinary:
+		return c.data, true
+	default:
+		return nil, false
+	}
+}
+
+// Set 以服务端身份设置 key 的值，返回需要广播给客户端的更新
+func (m *Map) Set(key string, value any) []byte {
+	return m.doc.transact(func(tx *transaction) {
+		tx.setMapItem(m.typ, key, &contentAny{arr: [][]byte{EncodeAny(value)}})
+	})
+}
+
+// Delete 以服务端身份删除 key，不存在时返回 nil
+func (m *Map) Delete(key string) []byte {
+	it, ok := m.typ.mapping[key]
+	if !ok || it.deleted {
+		return nil
+	}
+	return m.doc.transact(func(tx *transaction) {
+		it.delete(tx)
+	})
+}
+
+// Clear 以服务端身份删除所有键，Map 为空时返回 nil
+func (m *Map) Clear() []byte {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m.doc.transact(func(tx *transaction) {
+		for _, it := range m.typ.mapping {
+			if !it.deleted {
+				it.delete(tx)
+			}
+		}
+	})
+}
+
+// setMapItem 在 Map 的 key 上写入新值，和 Y.Map.set 一样以当前值为左侧，集成时覆盖旧值
+func (tx *transaction) setMapItem(parent *Type, key string, c content) {
+	doc := tx.doc
+	left := parent.mapping[key]
+	it := &Item{
+		id:        ID{Client: doc.clientID, Clock: doc.store.getState(doc.clientID)},
+		length:    c.length(),
+		left:      left,
+		parent:    parent,
+		parentSub: &key,
+		content:   c,
+	}
+	if left != nil {
+		last := left.lastID()
+		it.origin = &last
+	}
+	it.integrate(tx, 0)
+}
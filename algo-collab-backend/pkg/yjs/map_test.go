@@ -0,0 +1,69 @@
+package yjs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeAny(t *testing.T) {
+	// 和 lib0 encoding.writeAny 的输出一致
+	assert.Equal(t, []byte{anyObject, 1, 1, 'a', anyInteger, 1}, EncodeAny(map[string]any{"a": 1}))
+	assert.Equal(t, []byte{anyArray, 2, anyTrue, anyNull}, EncodeAny([]any{true, nil}))
+	assert.Equal(t, []byte{anyFloat32, 0x3f, 0xc0, 0, 0}, EncodeAny(1.5))
+
+	value := map[string]any{
+		"type":   "rect",
+		"x":      int64(-12),
+		"w":      0.1,
+		"points": []any{int64(1), 2.5},
+		"data":   []byte{1, 2},
+		"locked": false,
+	}
+	got, err := DecodeAny(EncodeAny(value))
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	_, err = DecodeAny([]byte{anyString, 5, 'a'})
+	assert.Error(t, err)
+}
+
+func TestMap_SetDeleteSync(t *testing.T) {
+	a := NewDoc(Options{ClientID: 1})
+	b := NewDoc(Options{})
+
+	require.NoError(t, b.ApplyUpdate(a.GetMap("board").Set("s1", map[string]any{"type": "rect"})))
+	require.NoError(t, b.ApplyUpdate(a.GetMap("board").Set("s2", "text")))
+	require.NoError(t, b.ApplyUpdate(a.GetMap("board").Set("s1", map[string]any{"type": "ellipse"})))
+
+	board := b.GetMap("board")
+	assert.Equal(t, []string{"s1", "s2"}, board.Keys())
+	v, ok := board.Get("s1")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"type": "ellipse"}, v)
+
+	require.NoError(t, b.ApplyUpdate(a.GetMap("board").Delete("s2")))
+	assert.Equal(t, map[string]any{"s1": map[string]any{"type": "ellipse"}}, board.Entries())
+	assert.Nil(t, a.GetMap("board").Delete("missing"))
+
+	require.NoError(t, b.ApplyUpdate(a.GetMap("board").Clear()))
+	assert.Equal(t, 0, board.Len())
+	assert.Nil(t, a.GetMap("board").Clear())
+}
+
+func TestMap_ConcurrentSetConverges(t *testing.T) {
+	a := NewDoc(Options{ClientID: 1})
+	b := NewDoc(Options{ClientID: 2})
+
+	ua := a.GetMap("board").Set("s1", "from a")
+	ub := b.GetMap("board").Set("s1", "from b")
+	require.NoError(t, a.ApplyUpdate(ub))
+	require.NoError(t, b.ApplyUpdate(ua))
+
+	va, _ := a.GetMap("board").Get("s1")
+	vb, _ := b.GetMap("board").Get("s1")
+	// 和 Yjs 一样，clientID 较大的一方写入的值生效
+	assert.Equal(t, "from b", va)
+	assert.Equal(t, va, vb)
+}
@@ -0,0 +1,138 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPosition 相对位置无法解析
+var ErrInvalidPosition = errors.New("yjs: 无效的相对位置")
+
+// 相对位置的编码格式，和 Y.encodeRelativePosition 一致
+const (
+	relativePositionItem  = 0 // 指向某个字符
+	relativePositionTName = 1 // 指向根类型的末尾（或开头）
+	relativePositionType  = 2 // 指向嵌套类型，服务端不支持
+)
+
+// RelativePosition 文本中的相对位置：记录位置右侧字符的 ID，而不是下标，
+// 其他人在前面插入或删除内容后仍然指向同一处。
+// Item 为空时表示文本末尾（Assoc >= 0）或开头（Assoc < 0）
+type RelativePosition struct {
+	Item  *ID
+	TName string
+	// Assoc 为负数时位置关联到左侧的字符，和 Yjs 一致
+	Assoc int64
+}
+
+// Encode 编码为 Y.decodeRelativePosition 可以读取的格式
+func (p *RelativePosition) Encode() []byte {
+	e := NewEncoder()
+	if p.Item != nil {
+		e.WriteVarUint(relativePositionItem)
+		e.WriteVarUint(p.Item.Client)
+		e.WriteVarUint(p.Item.Clock)
+	} else {
+		e.WriteVarUint(relativePositionTName)
+		e.WriteVarString(p.TName)
+	}
+	e.WriteVarInt(p.Assoc)
+	return e.Bytes()
+}
+
+// DecodeRelativePosition 解析 Y.encodeRelativePosition 编码的相对位置
+func DecodeRelativePosition(data []byte) (*RelativePosition, error) {
+	d := NewDecoder(data)
+	kind, err := d.ReadVarUint()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+	}
+
+	pos := &RelativePosition{}
+	switch kind {
+	case relativePositionItem:
+		client, err := d.ReadVarUint()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+		clock, err := d.ReadVarUint()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+		pos.Item = &ID{Client: client, Clock: clock}
+	case relativePositionTName:
+		if pos.TName, err = d.ReadVarString(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的类型 %d", ErrInvalidPosition, kind)
+	}
+	// 旧版本的 Yjs 不编码 assoc
+	if d.HasContent() {
+		if pos.Assoc, err = d.ReadVarInt(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+		}
+	}
+	return pos, nil
+}
+
+// RelativePosition 把下标 index 转为相对位置，和 Y.createRelativePositionFromTypeIndex 一致
+func (t *Text) RelativePosition(index uint64, assoc int64) *RelativePosition {
+	if assoc < 0 {
+		if index == 0 {
+			return &RelativePosition{TName: t.typ.name, Assoc: assoc}
+		}
+		index--
+	}
+	for it := t.typ.start; it != nil; it = it.right {
+		if !it.deleted && it.countable() {
+			if index < it.length {
+				return &RelativePosition{Item: &ID{Client: it.id.Client, Clock: it.id.Clock + index}, Assoc: assoc}
+			}
+			index -= it.length
+		}
+		if it.right == nil && assoc < 0 {
+			last := it.lastID()
+			return &RelativePosition{Item: &last, Assoc: assoc}
+		}
+	}
+	return &RelativePosition{TName: t.typ.name, Assoc: assoc}
+}
+
+// Resolve 相对位置当前的下标，和 Y.createAbsolutePositionFromRelativePosition 一致
+// 位置右侧的字符被删除后，位置落在被删除内容原来所在的地方。
+// 位置不属于这段文本、或引用的字符还没有同步到文档时返回 false
+func (t *Text) Resolve(pos *RelativePosition) (uint64, bool) {
+	if pos.Item == nil {
+		if pos.TName != t.typ.name {
+			return 0, false
+		}
+		if pos.Assoc >= 0 {
+			return t.typ.length, true
+		}
+		return 0, true
+	}
+
+	store := t.doc.store
+	if pos.Item.Clock >= store.getState(pos.Item.Client) {
+		return 0, false
+	}
+	it, ok := store.find(*pos.Item).(*Item)
+	if !ok || it.parent != t.typ {
+		return 0, false
+	}
+
+	var index uint64
+	if !it.deleted && it.countable() {
+		index = pos.Item.Clock - it.id.Clock
+		if pos.Assoc < 0 {
+			index++
+		}
+	}
+	for n := it.left; n != nil; n = n.left {
+		if !n.deleted && n.countable() {
+			index += n.length
+		}
+	}
+	return index, true
+}
@@ -0,0 +1,245 @@
+package yjs
+
+// TextEdit 更新在根文本中的一处修改，下标是合入更新之前的 UTF-16 下标
+// 删除时为 [From, To)；插入时内容落在 From 和 To 之间：
+// 通常 From == To，与其他人的并发插入冲突时由 YATA 排序，只能确定在 [From, To] 之内
+type TextEdit struct {
+	Insert   bool
+	From, To uint64
+}
+
+// PreviewTextEdits 不修改文档，列出 update 合入后会在根文本 name 中删除和插入的位置
+// 只解析更新本身，删除按删除集定位、插入按 origin/rightOrigin 定位，不需要复制文档。
+// 更新依赖的结构还没有同步到文档时 ok 为 false，此时无法确定修改位置
+func (d *Doc) PreviewTextEdits(update []byte, name string) (edits []TextEdit, ok bool, err error) {
+	typ := d.share[name]
+	known := make(map[string]struct{}, len(d.share))
+	for n := range d.share {
+		known[n] = struct{}{}
+	}
+	refs, ds, err := d.decodeUpdate(update)
+	// 解码时会创建更新里出现的根类型，预览不应该留下它们
+	for n, t := range d.share {
+		if _, ok := known[n]; !ok && t.start == nil {
+			delete(d.share, n)
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	p := &editPreview{
+		store: d.store,
+		typ:   typ,
+		refs:  refs,
+		gaps:  make(map[*Item]*insertGap),
+		ok:    true,
+	}
+	p.previewInserts()
+	p.previewDeletes(ds)
+	return p.edits, p.ok, nil
+}
+
+// insertGap 新 Item 在合入前文本中的位置范围，in 为 false 表示不属于目标文本
+type insertGap struct {
+	lo, hi uint64
+	in     bool
+}
+
+type editPreview struct {
+	store *structStore
+	typ   *Type
+	refs  map[uint64]*structRefs
+	// gaps 已经计算过的新 Item，值为 nil 表示正在计算（用来发现循环引用）
+	gaps  map[*Item]*insertGap
+	edits []TextEdit
+	ok    bool
+}
+
+func (p *editPreview) previewInserts() {
+	for client, refs := range p.refs {
+		state := p.store.getState(client)
+		if refs.refs[0].ID().Clock > state {
+			// 同一客户端前面的结构还没到
+			p.ok = false
+			continue
+		}
+		for _, st := range refs.refs {
+			if st.ID().Clock+st.Len() <= state {
+				continue
+			}
+			switch st := st.(type) {
+			case *skip:
+				p.ok = false
+			case *Item:
+				gap, ok := p.gap(st)
+				if !ok {
+					p.ok = false
+					continue
+				}
+				if gap.in && st.countable() {
+					p.edits = append(p.edits, TextEdit{Insert: true, From: gap.lo, To: gap.hi})
+				}
+			}
+		}
+	}
+}
+
+func (p *editPreview) previewDeletes(ds deleteSet) {
+	for client, ranges := range ds {
+		state := p.store.getState(client)
+		structs := p.store.clients[client]
+		for _, r := range ranges {
+			end := r.clock + r.length
+			if end > state && !p.covers(client, max(r.clock, state), end) {
+				p.ok = false
+			}
+			if p.typ == nil || r.clock >= state {
+				// 同一条更新里插入又删除的内容不影响合入前的文本
+				continue
+			}
+			for i := findIndex(structs, r.clock); i < len(structs); i++ {
+				it, ok := structs[i].(*Item)
+				if !ok {
+					continue
+				}
+				if it.id.Clock >= end {
+					break
+				}
+				if it.deleted || !it.countable() || !p.inText(it) {
+					continue
+				}
+				index := indexBefore(it)
+				p.edits = append(p.edits, TextEdit{
+					From: index + max(r.clock, it.id.Clock) - it.id.Clock,
+					To:   index + min(end, it.id.Clock+it.length) - it.id.Clock,
+				})
+			}
+		}
+	}
+}
+
+// covers 更新中是否包含客户端 [clock, end) 的结构
+func (p *editPreview) covers(client, clock, end uint64) bool {
+	refs := p.refs[client]
+	if refs == nil {
+		return false
+	}
+	first, last := refs.refs[0], refs.refs[len(refs.refs)-1]
+	return first.ID().Clock <= clock && last.ID().Clock+last.Len() >= end
+}
+
+// gap 计算新 Item 的插入位置：在 origin 右侧、rightOrigin 左侧
+// origin/rightOrigin 也可能是同一条更新里的新 Item，此时取它们的位置
+func (p *editPreview) gap(it *Item) (*insertGap, bool) {
+	if gap, seen := p.gaps[it]; seen {
+		return gap, gap != nil
+	}
+	p.gaps[it] = nil
+
+	origin := it.origin
+	if state := p.store.getState(it.id.Client); it.id.Clock < state {
+		// 前半部分本地已经有了，剩余部分紧接在它后面
+		origin = &ID{Client: it.id.Client, Clock: state - 1}
+	}
+
+	gap := &insertGap{}
+	inLeft, inRight := false, false
+	if origin != nil {
+		lo, in, ok := p.position(*origin, true)
+		if !ok {
+			return nil, false
+		}
+		gap.lo, inLeft = lo, in
+	}
+	if it.rightOrigin != nil {
+		hi, in, ok := p.position(*it.rightOrigin, false)
+		if !ok {
+			return nil, false
+		}
+		gap.hi, inRight = hi, in
+	}
+	switch {
+	case origin == nil && it.rightOrigin == nil:
+		gap.in = p.typ != nil && it.parent == p.typ && it.parentSub == nil
+		if gap.in {
+			gap.hi = p.typ.length
+		}
+	case !inLeft && !inRight:
+	case !inLeft:
+		gap.lo, gap.in = 0, true
+	case !inRight:
+		gap.hi, gap.in = p.typ.length, true
+	default:
+		gap.in = true
+	}
+	if gap.hi < gap.lo {
+		gap.lo, gap.hi = gap.hi, gap.lo
+	}
+	p.gaps[it] = gap
+	return gap, true
+}
+
+// position 字符 id 右侧（after 为 true）或左侧的位置，以及字符是否属于目标文本
+func (p *editPreview) position(id ID, after bool) (uint64, bool, bool) {
+	if id.Clock >= p.store.getState(id.Client) {
+		it := p.newItem(id)
+		if it == nil {
+			return 0, false, false
+		}
+		gap, ok := p.gap(it)
+		if !ok {
+			return 0, false, false
+		}
+		if after {
+			return gap.lo, gap.in, true
+		}
+		return gap.hi, gap.in, true
+	}
+
+	it, ok := p.store.find(id).(*Item)
+	if !ok || !p.inText(it) {
+		return 0, false, true
+	}
+	index := indexBefore(it)
+	if !it.deleted && it.countable() {
+		index += id.Clock - it.id.Clock
+		if after {
+			index++
+		}
+	}
+	return index, true, true
+}
+
+// newItem 更新中包含 id 的 Item
+func (p *editPreview) newItem(id ID) *Item {
+	refs := p.refs[id.Client]
+	if refs == nil {
+		return nil
+	}
+	for _, st := range refs.refs {
+		if id.Clock < st.ID().Clock+st.Len() {
+			if st.ID().Clock > id.Clock {
+				return nil
+			}
+			it, _ := st.(*Item)
+			return it
+		}
+	}
+	return nil
+}
+
+func (p *editPreview) inText(it *Item) bool {
+	return p.typ != nil && it.parent == p.typ && it.parentSub == nil
+}
+
+// indexBefore it 左侧可见内容的长度
+func indexBefore(it *Item) uint64 {
+	var index uint64
+	for n := it.left; n != nil; n = n.left {
+		if !n.deleted && n.countable() {
+			index += n.length
+		}
+	}
+	return index
+}
@@ -0,0 +1,68 @@
+package yjs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoc_PreviewTextEdits(t *testing.T) {
+	doc := NewDoc(Options{GC: true})
+	require.NoError(t, doc.ApplyUpdate(textItem(1, 0, nil, nil, "t", "hello world")))
+	require.NoError(t, doc.ApplyUpdate(deleteUpdate(1, 0, 1)))
+	before := doc.EncodeStateAsUpdate(nil)
+
+	preview := func(update []byte, name string) ([]TextEdit, bool) {
+		edits, ok, err := doc.PreviewTextEdits(update, name)
+		require.NoError(t, err)
+		return edits, ok
+	}
+
+	// 1. 插入：位置按合入前的可见文本计算，已删除的字符不计
+	edits, ok := preview(textItem(2, 0, &ID{1, 4}, &ID{1, 5}, "", "X"), "t")
+	assert.True(t, ok)
+	assert.Equal(t, []TextEdit{{Insert: true, From: 4, To: 4}}, edits)
+	edits, _ = preview(textItem(2, 0, nil, &ID{1, 1}, "", "X"), "t")
+	assert.Equal(t, []TextEdit{{Insert: true, From: 0, To: 0}}, edits)
+	edits, _ = preview(textItem(2, 0, &ID{1, 10}, nil, "", "X"), "t")
+	assert.Equal(t, []TextEdit{{Insert: true, From: 10, To: 10}}, edits)
+
+	// 2. 删除
+	edits, ok = preview(deleteUpdate(1, 2, 3), "t")
+	assert.True(t, ok)
+	assert.Equal(t, []TextEdit{{From: 1, To: 4}}, edits)
+
+	// 3. 客户端本地编辑生成的多段更新，origin 指向同一条更新里的新内容
+	client := NewDoc(Options{ClientID: 3})
+	require.NoError(t, client.ApplyUpdate(before))
+	text := client.GetText("t")
+	sv := client.StateVector()
+	text.Insert(5, "ab")
+	text.Insert(6, "c")
+	text.Delete(0, 2)
+	edits, ok = preview(client.EncodeStateAsUpdate(sv), "t")
+	assert.True(t, ok)
+	assert.ElementsMatch(t, []TextEdit{
+		{Insert: true, From: 5, To: 5},
+		{Insert: true, From: 5, To: 5},
+		{Insert: true, From: 5, To: 5},
+		{From: 0, To: 2},
+	}, edits)
+
+	// 4. 其他文本的修改不列出，预览不创建新的根类型
+	edits, ok = preview(textItem(4, 0, nil, nil, "other", "x"), "t")
+	assert.True(t, ok)
+	assert.Empty(t, edits)
+	assert.Equal(t, []string{"t"}, doc.RootNames())
+
+	// 5. 依赖还没到
+	_, ok = preview(textItem(2, 0, &ID{5, 0}, nil, "", "X"), "t")
+	assert.False(t, ok)
+	_, ok = preview(deleteUpdate(1, 20, 1), "t")
+	assert.False(t, ok)
+
+	// 预览不修改文档
+	assert.Equal(t, "ello world", doc.GetText("t").String())
+	assert.Equal(t, before, doc.EncodeStateAsUpdate(nil))
+}
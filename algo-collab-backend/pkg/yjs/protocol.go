@@ -0,0 +1,115 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// y-websocket 消息类型（消息的第一个变长整数）
+const (
+	MessageSync           = 0
+	MessageAwareness      = 1
+	MessageAuth           = 2
+	MessageQueryAwareness = 3
+)
+
+// sync 子消息类型
+const (
+	SyncStep1  = 0 // 携带发送方的状态向量，请求对方缺少的数据
+	SyncStep2  = 1 // 对 SyncStep1 的回复，携带对方缺少的更新
+	SyncUpdate = 2 // 增量更新
+)
+
+// auth 子消息类型
+const (
+	AuthPermissionDenied = 0
+)
+
+// ErrUnknownMessage 无法识别的消息类型
+var ErrUnknownMessage = errors.New("yjs: 未知的消息类型")
+
+// EmptyUpdate 不包含任何结构和删除的更新（0 个客户端结构 + 空删除集）
+var EmptyUpdate = []byte{0, 0}
+
+// EmptyStateVector 空状态向量，表示"我什么都没有"
+var EmptyStateVector = []byte{0}
+
+// Message 解析后的 y-websocket 消息
+type Message struct {
+	Type    uint64
+	SubType uint64 // 仅 sync 消息有效
+	Payload []byte // sync: 状态向量或更新；awareness: awareness 更新
+}
+
+// ParseMessage 解析一条 y-websocket 二进制消息
+func ParseMessage(data []byte) (*Message, error) {
+	d := NewDecoder(data)
+	msgType, err := d.ReadVarUint()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Type: msgType}
+	switch msgType {
+	case MessageSync:
+		if msg.SubType, err = d.ReadVarUint(); err != nil {
+			return nil, err
+		}
+		if msg.SubType > SyncUpdate {
+			return nil, fmt.Errorf("%w: sync/%d", ErrUnknownMessage, msg.SubType)
+		}
+		if msg.Payload, err = d.ReadVarBytes(); err != nil {
+			return nil, err
+		}
+	case MessageAwareness:
+		if msg.Payload, err = d.ReadVarBytes(); err != nil {
+			return nil, err
+		}
+	case MessageQueryAwareness:
+	case MessageAuth:
+		msg.Payload = d.Remaining()
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownMessage, msgType)
+	}
+	return msg, nil
+}
+
+// EncodeSyncStep1 编码 SyncStep1 消息
+func EncodeSyncStep1(stateVector []byte) []byte {
+	return encodeSync(SyncStep1, stateVector)
+}
+
+// EncodeSyncStep2 编码 SyncStep2 消息
+func EncodeSyncStep2(update []byte) []byte {
+	return encodeSync(SyncStep2, update)
+}
+
+// EncodeUpdate 编码增量更新消息
+func EncodeUpdate(update []byte) []byte {
+	return encodeSync(SyncUpdate, update)
+}
+
+func encodeSync(subType uint64, payload []byte) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(MessageSync)
+	e.WriteVarUint(subType)
+	e.WriteVarBytes(payload)
+	return e.Bytes()
+}
+
+// EncodeAwarenessMessage 把 awareness 更新包装成消息
+func EncodeAwarenessMessage(update []byte) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(MessageAwareness)
+	e.WriteVarBytes(update)
+	return e.Bytes()
+}
+
+// EncodePermissionDenied 编码鉴权失败消息，客户端收到后会关闭连接
+func EncodePermissionDenied(reason string) []byte {
+	e := NewEncoder()
+	e.WriteVarUint(MessageAuth)
+	e.WriteVarUint(AuthPermissionDenied)
+	e.WriteVarString(reason)
+	return e.Bytes()
+}
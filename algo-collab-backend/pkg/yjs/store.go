@@ -0,0 +1,167 @@
+package yjs
+
+import (
+	"fmt"
+	"sort"
+)
+
+// structStore 按客户端保存所有结构，每个客户端的结构按时钟连续排列
+type structStore struct {
+	clients map[uint64][]Struct
+}
+
+func newStructStore() *structStore {
+	return &structStore{clients: make(map[uint64][]Struct)}
+}
+
+// getState 客户端的下一个时钟（即已知结构的总长度）
+func (s *structStore) getState(client uint64) uint64 {
+	structs := s.clients[client]
+	if len(structs) == 0 {
+		return 0
+	}
+	last := structs[len(structs)-1]
+	return last.ID().Clock + last.Len()
+}
+
+// totalClock 所有客户端时钟之和，用来快速判断文档是否有新结构
+func (s *structStore) totalClock() uint64 {
+	var total uint64
+	for client := range s.clients {
+		total += s.getState(client)
+	}
+	return total
+}
+
+func (s *structStore) stateVector() StateVector {
+	sv := make(StateVector, len(s.clients))
+	for client := range s.clients {
+		sv[client] = s.getState(client)
+	}
+	return sv
+}
+
+func (s *structStore) addStruct(st Struct) {
+	id := st.ID()
+	if state := s.getState(id.Client); state != id.Clock {
+		panic(fmt.Sprintf("yjs: 结构时钟不连续 client=%d state=%d clock=%d", id.Client, state, id.Clock))
+	}
+	s.clients[id.Client] = append(s.clients[id.Client], st)
+}
+
+// findIndex 二分查找包含 clock 的结构下标
+func findIndex(structs []Struct, clock uint64) int {
+	i := sort.Search(len(structs), func(i int) bool {
+		st := structs[i]
+		return st.ID().Clock+st.Len() > clock
+	})
+	if i == len(structs) || structs[i].ID().Clock > clock {
+		panic(fmt.Sprintf("yjs: 找不到时钟 %d 对应的结构", clock))
+	}
+	return i
+}
+
+// find 查找包含 id 的结构
+func (s *structStore) find(id ID) Struct {
+	structs := s.clients[id.Client]
+	return structs[findIndex(structs, id.Clock)]
+}
+
+func (s *structStore) replaceStruct(old Struct, replacement Struct) {
+	id := old.ID()
+	structs := s.clients[id.Client]
+	structs[findIndex(structs, id.Clock)] = replacement
+}
+
+func (s *structStore) insertAt(client uint64, index int, st Struct) {
+	structs := s.clients[client]
+	structs = append(structs, nil)
+	copy(structs[index+1:], structs[index:])
+	structs[index] = st
+	s.clients[client] = structs
+}
+
+// tryMergeWithLefts 尝试把 pos 处的结构和左侧结构依次合并，返回合并掉的个数
+func (s *structStore) tryMergeWithLefts(client uint64, pos int) int {
+	structs := s.clients[client]
+	i := pos
+	for i > 0 {
+		left, right := structs[i-1], structs[i]
+		if left.Deleted() != right.Deleted() || !mergeStructs(left, right) {
+			break
+		}
+		if r, ok := right.(*Item); ok && r.parentSub != nil && r.parent.mapping[*r.parentSub] == r {
+			r.parent.mapping[*r.parentSub] = left.(*Item)
+		}
+		i--
+	}
+
+	merged := pos - i
+	if merged > 0 {
+		structs = append(structs[:pos+1-merged], structs[pos+1:]...)
+		s.clients[client] = structs
+	}
+	return merged
+}
+
+func mergeStructs(left, right Struct) bool {
+	switch l := left.(type) {
+	case *GC:
+		if r, ok := right.(*GC); ok {
+			l.length += r.length
+			return true
+		}
+	case *Item:
+		if r, ok := right.(*Item); ok {
+			return l.mergeWith(r)
+		}
+	}
+	return false
+}
+
+// deleteSet 从文档中所有已删除的结构生成删除集
+func (s *structStore) deleteSet() deleteSet {
+	ds := make(deleteSet)
+	for client, structs := range s.clients {
+		for i := 0; i < len(structs); i++ {
+			if !structs[i].Deleted() {
+				continue
+			}
+			clock, length := structs[i].ID().Clock, structs[i].Len()
+			for i+1 < len(structs) && structs[i+1].Deleted() {
+				i++
+				length += structs[i].Len()
+			}
+			ds.add(client, clock, length)
+		}
+	}
+	return ds
+}
+
+// writeStructs 写入某个客户端从 clock 开始的所有结构
+func (s *structStore) writeStructs(e *Encoder, client, clock uint64) {
+	structs := s.clients[client]
+	if first := structs[0].ID().Clock; clock < first {
+		clock = first
+	}
+	start := findIndex(structs, clock)
+
+	e.WriteVarUint(uint64(len(structs) - start))
+	e.WriteVarUint(client)
+	e.WriteVarUint(clock)
+
+	firstStruct := structs[start]
+	writeStruct(e, firstStruct, clock-firstStruct.ID().Clock)
+	for _, st := range structs[start+1:] {
+		writeStruct(e, st, 0)
+	}
+}
+
+func writeStruct(e *Encoder, st Struct, offset uint64) {
+	switch v := st.(type) {
+	case *Item:
+		v.write(e, offset)
+	case *GC:
+		v.write(e, offset)
+	}
+}
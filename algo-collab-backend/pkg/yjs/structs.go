@@ -0,0 +1,413 @@
+package yjs
+
+// Struct 文档中的一个结构：Item（有内容）、GC（已回收的区间）或 skip（合并更新中的空洞）
+type Struct interface {
+	ID() ID
+	Len() uint64
+	Deleted() bool
+}
+
+// GC 已被垃圾回收的连续区间，只保留 ID 和长度
+type GC struct {
+	id     ID
+	length uint64
+}
+
+func (g *GC) ID() ID        { return g.id }
+func (g *GC) Len() uint64   { return g.length }
+func (g *GC) Deleted() bool { return true }
+
+func (g *GC) integrate(tx *transaction, offset uint64) {
+	if offset > 0 {
+		g.id.Clock += offset
+		g.length -= offset
+	}
+	tx.doc.store.addStruct(g)
+}
+
+func (g *GC) write(e *Encoder, offset uint64) {
+	e.WriteUint8(contentRefGC)
+	e.WriteVarUint(g.length - offset)
+}
+
+// skip 合并后的更新中用来占位的空洞，不会被写入文档
+type skip struct {
+	id     ID
+	length uint64
+}
+
+func (s *skip) ID() ID        { return s.id }
+func (s *skip) Len() uint64   { return s.length }
+func (s *skip) Deleted() bool { return true }
+
+// Item 带内容的结构，同一个父类型下的 Item 组成双向链表
+type Item struct {
+	id     ID
+	length uint64
+
+	left, right *Item
+	// origin 插入时左侧的字符（的最后一个 ID），rightOrigin 插入时右侧的字符
+	origin      *ID
+	rightOrigin *ID
+
+	parent *Type
+	// 解码时父类型可能是一个尚未集成的 Item，集成前用 parentID 暂存
+	parentID  *ID
+	parentSub *string // Map 的键，序列类型为空
+
+	content content
+	deleted bool
+	keep    bool
+}
+
+func (it *Item) ID() ID        { return it.id }
+func (it *Item) Len() uint64   { return it.length }
+func (it *Item) Deleted() bool { return it.deleted }
+
+func (it *Item) lastID() ID {
+	return ID{Client: it.id.Client, Clock: it.id.Clock + it.length - 1}
+}
+
+func (it *Item) countable() bool {
+	return it.content.countable()
+}
+
+// getMissing 检查集成所依赖的结构是否都已存在，缺少时返回缺少的客户端
+// 依赖齐全时把 origin/rightOrigin 解析为链表中的左右邻居，并确定父类型
+func (it *Item) getMissing(tx *transaction) (uint64, bool) {
+	// 同一客户端的依赖一定在它之前，时钟也必然小于当前状态，所以统一按状态判断
+	store := tx.doc.store
+	if it.origin != nil && it.origin.Clock >= store.getState(it.origin.Client) {
+		return it.origin.Client, true
+	}
+	if it.rightOrigin != nil && it.rightOrigin.Clock >= store.getState(it.rightOrigin.Client) {
+		return it.rightOrigin.Client, true
+	}
+	if it.parentID != nil && it.parentID.Clock >= store.getState(it.parentID.Client) {
+		return it.parentID.Client, true
+	}
+
+	var left, right Struct
+	if it.origin != nil {
+		left = tx.getItemCleanEnd(*it.origin)
+		last := structLastID(left)
+		it.origin = &last
+	}
+	if it.rightOrigin != nil {
+		right = tx.getItemCleanStart(*it.rightOrigin)
+		id := right.ID()
+		it.rightOrigin = &id
+	}
+
+	_, leftGC := left.(*GC)
+	_, rightGC := right.(*GC)
+	switch {
+	case leftGC || rightGC:
+		it.parent = nil
+	case it.parent == nil && it.parentID == nil:
+		// 有 origin 的 Item 不编码父类型，从邻居继承
+		if l, ok := left.(*Item); ok {
+			it.parent, it.parentSub = l.parent, l.parentSub
+		}
+		if r, ok := right.(*Item); ok {
+			it.parent, it.parentSub = r.parent, r.parentSub
+		}
+	case it.parentID != nil:
+		it.parent = nil
+		if parentItem, ok := store.find(*it.parentID).(*Item); ok {
+			if ct, ok := parentItem.content.(*contentType); ok {
+				it.parent = ct.typ
+			}
+		}
+		it.parentID = nil
+	}
+
+	it.left, _ = left.(*Item)
+	it.right, _ = right.(*Item)
+	return 0, false
+}
+
+// integrate 按 YATA 算法把 Item 插入父类型的链表
+// offset > 0 表示前 offset 个时钟本地已经有了，只集成剩余部分
+func (it *Item) integrate(tx *transaction, offset uint64) {
+	store := tx.doc.store
+	if offset > 0 {
+		it.id.Clock += offset
+		left := tx.getItemCleanEnd(ID{Client: it.id.Client, Clock: it.id.Clock - 1})
+		last := structLastID(left)
+		it.origin = &last
+		if l, ok := left.(*Item); ok {
+			it.left = l
+		} else {
+			it.left = nil
+			it.parent = nil
+		}
+		it.content = it.content.splice(offset)
+		it.length -= offset
+	}
+
+	if it.parent == nil {
+		gc := &GC{id: it.id, length: it.length}
+		gc.integrate(tx, 0)
+		return
+	}
+	parent := it.parent
+
+	// 1. 解决并发插入冲突，确定真正的左邻居
+	if (it.left == nil && (it.right == nil || it.right.left != nil)) || (it.left != nil && it.left.right != it.right) {
+		left := it.left
+		var o *Item
+		switch {
+		case left != nil:
+			o = left.right
+		case it.parentSub != nil:
+			o = parent.mapping[*it.parentSub]
+			for o != nil && o.left != nil {
+				o = o.left
+			}
+		default:
+			o = parent.start
+		}
+
+		conflicting := make(map[*Item]bool)
+		beforeOrigin := make(map[*Item]bool)
+		for o != nil && o != it.right {
+			beforeOrigin[o] = true
+			conflicting[o] = true
+			if idEqual(it.origin, o.origin) {
+				if o.id.Client < it.id.Client {
+					left = o
+					clear(conflicting)
+				} else if idEqual(it.rightOrigin, o.rightOrigin) {
+					break
+				}
+			} else if o.origin != nil {
+				originItem, ok := store.find(*o.origin).(*Item)
+				if !ok || !beforeOrigin[originItem] {
+					break
+				}
+				if !conflicting[originItem] {
+					left = o
+					clear(conflicting)
+				}
+			} else {
+				break
+			}
+			o = o.right
+		}
+		it.left = left
+	}
+
+	// 2. 接入链表
+	if it.left != nil {
+		it.right = it.left.right
+		it.left.right = it
+	} else {
+		var r *Item
+		if it.parentSub != nil {
+			r = parent.mapping[*it.parentSub]
+			for r != nil && r.left != nil {
+				r = r.left
+			}
+		} else {
+			r = parent.start
+			parent.start = it
+		}
+		it.right = r
+	}
+	if it.right != nil {
+		it.right.left = it
+	} else if it.parentSub != nil {
+		// Map 中最右侧的值生效，旧值被覆盖即删除
+		parent.mapping[*it.parentSub] = it
+		if it.left != nil {
+			it.left.delete(tx)
+		}
+	}
+	if it.parentSub == nil && it.countable() && !it.deleted {
+		parent.length += it.length
+	}
+	store.addStruct(it)
+	if !it.deleted {
+		tx.doc.recordText(it, true)
+	}
+
+	// 3. 内容相关的处理
+	switch c := it.content.(type) {
+	case *contentDeleted:
+		tx.deleteSet.add(it.id.Client, it.id.Clock, c.len)
+		it.deleted = true
+	case *contentType:
+		c.typ.item = it
+	}
+
+	// 父类型已删除，或者 Map 中已经有更新的值
+	if (parent.item != nil && parent.item.deleted) || (it.parentSub != nil && it.right != nil) {
+		it.delete(tx)
+	}
+}
+
+// delete 标记删除，嵌套类型会级联删除其内容
+func (it *Item) delete(tx *transaction) {
+	if it.deleted {
+		return
+	}
+	if it.countable() && it.parentSub == nil {
+		it.parent.length -= it.length
+	}
+	tx.doc.recordText(it, false)
+	it.deleted = true
+	tx.deleteSet.add(it.id.Client, it.id.Clock, it.length)
+
+	if ct, ok := it.content.(*contentType); ok {
+		for child := ct.typ.start; child != nil; child = child.right {
+			if !child.deleted {
+				child.delete(tx)
+			} else if child.id.Clock < tx.beforeState[child.id.Client] {
+				tx.mergeStructs = append(tx.mergeStructs, child)
+			}
+		}
+		for _, child := range ct.typ.mapping {
+			if !child.deleted {
+				child.delete(tx)
+			} else if child.id.Clock < tx.beforeState[child.id.Client] {
+				tx.mergeStructs = append(tx.mergeStructs, child)
+			}
+		}
+	}
+}
+
+// gc 回收已删除 Item 的内容；父类型也被回收时整个 Item 替换为 GC
+func (it *Item) gc(store *structStore, parentGCd bool) {
+	if ct, ok := it.content.(*contentType); ok {
+		for child := ct.typ.start; child != nil; child = child.right {
+			child.gc(store, true)
+		}
+		ct.typ.start = nil
+		for _, child := range ct.typ.mapping {
+			for ; child != nil; child = child.left {
+				child.gc(store, true)
+			}
+		}
+		ct.typ.mapping = make(map[string]*Item)
+	}
+
+	if parentGCd {
+		store.replaceStruct(it, &GC{id: it.id, length: it.length})
+	} else {
+		it.content = &contentDeleted{len: it.length}
+	}
+}
+
+// mergeWith 合并右侧紧邻且由同一次连续输入产生的 Item
+func (it *Item) mergeWith(right *Item) bool {
+	last := it.lastID()
+	if idEqual(right.origin, &last) &&
+		it.right == right &&
+		idEqual(it.rightOrigin, right.rightOrigin) &&
+		it.id.Client == right.id.Client &&
+		it.id.Clock+it.length == right.id.Clock &&
+		it.deleted == right.deleted &&
+		it.content.ref() == right.content.ref() &&
+		it.content.mergeWith(right.content) {
+		if right.keep {
+			it.keep = true
+		}
+		it.right = right.right
+		if it.right != nil {
+			it.right.left = it
+		}
+		it.length += right.length
+		return true
+	}
+	return false
+}
+
+func (it *Item) write(e *Encoder, offset uint64) {
+	origin := it.origin
+	if offset > 0 {
+		origin = &ID{Client: it.id.Client, Clock: it.id.Clock + offset - 1}
+	}
+
+	info := it.content.ref() & 0x1f
+	if origin != nil {
+		info |= 0x80
+	}
+	if it.rightOrigin != nil {
+		info |= 0x40
+	}
+	if it.parentSub != nil {
+		info |= 0x20
+	}
+	e.WriteUint8(info)
+
+	if origin != nil {
+		writeID(e, *origin)
+	}
+	if it.rightOrigin != nil {
+		writeID(e, *it.rightOrigin)
+	}
+	if origin == nil && it.rightOrigin == nil {
+		if it.parent.item == nil {
+			e.WriteVarUint(1)
+			e.WriteVarString(it.parent.name)
+		} else {
+			e.WriteVarUint(0)
+			writeID(e, it.parent.item.id)
+		}
+		if it.parentSub != nil {
+			e.WriteVarString(*it.parentSub)
+		}
+	}
+	it.content.write(e, offset)
+}
+
+// splitItem 在 diff 处把 Item 拆成两个，返回右半部分
+func splitItem(tx *transaction, left *Item, diff uint64) *Item {
+	client, clock := left.id.Client, left.id.Clock
+	right := &Item{
+		id:          ID{Client: client, Clock: clock + diff},
+		length:      left.length - diff,
+		left:        left,
+		origin:      &ID{Client: client, Clock: clock + diff - 1},
+		right:       left.right,
+		rightOrigin: left.rightOrigin,
+		parent:      left.parent,
+		parentSub:   left.parentSub,
+		content:     left.content.splice(diff),
+		deleted:     left.deleted,
+		keep:        left.keep,
+	}
+	left.right = right
+	if right.right != nil {
+		right.right.left = right
+	}
+	tx.mergeStructs = append(tx.mergeStructs, right)
+	if right.parentSub != nil && right.right == nil {
+		right.parent.mapping[*right.parentSub] = right
+	}
+	left.length = diff
+	return right
+}
+
+func structLastID(s Struct) ID {
+	id := s.ID()
+	return ID{Client: id.Client, Clock: id.Clock + s.Len() - 1}
+}
+
+func writeID(e *Encoder, id ID) {
+	e.WriteVarUint(id.Client)
+	e.WriteVarUint(id.Clock)
+}
+
+func readID(d *Decoder) (ID, error) {
+	client, err := d.ReadVarUint()
+	if err != nil {
+		return ID{}, err
+	}
+	clock, err := d.ReadVarUint()
+	if err != nil {
+		return ID{}, err
+	}
+	return ID{Client: client, Clock: clock}, nil
+}
@@ -0,0 +1,243 @@
+package yjs
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Text 根级 Y.Text 的只读视图和服务端编辑入口
+// 位置和长度以 UTF-16 码元计算，与前端 Monaco/Yjs 一致
+type Text struct {
+	doc *Doc
+	typ *Type
+}
+
+// GetText 获取根类型 name 对应的文本（不存在时创建空文本）
+func (d *Doc) GetText(name string) *Text {
+	return &Text{doc: d, typ: d.getType(name)}
+}
+
+// String 当前可见的文本内容
+func (t *Text) String() string {
+	var sb strings.Builder
+	for it := t.typ.start; it != nil; it = it.right {
+		if it.deleted {
+			continue
+		}
+		if c, ok := it.content.(*contentString); ok {
+			sb.WriteString(string(utf16.Decode(c.str)))
+		}
+	}
+	return sb.String()
+}
+
+// TextRun 同一个客户端连续插入的一段可见文本
+type TextRun struct {
+	Client uint64
+	Text   string
+}
+
+// Runs 按客户端切分的可见文本，相邻的同一客户端的内容合并为一段
+// 用于统计每个字符由哪个客户端写入
+func (t *Text) Runs() []TextRun {
+	var runs []TextRun
+	for it := t.typ.start; it != nil; it = it.right {
+		if it.deleted {
+			continue
+		}
+		c, ok := it.content.(*contentString)
+		if !ok {
+			continue
+		}
+		s := string(utf16.Decode(c.str))
+		if n := len(runs); n > 0 && runs[n-1].Client == it.id.Client {
+			runs[n-1].Text += s
+			continue
+		}
+		runs = append(runs, TextRun{Client: it.id.Client, Text: s})
+	}
+	return runs
+}
+
+// Slice 可见文本中 [start, end) 的部分，位置以 UTF-16 码元计算
+func (t *Text) Slice(start, end uint64) string {
+	var buf []uint16
+	var index uint64
+	for it := t.typ.start; it != nil && index < end; it = it.right {
+		if it.deleted || !it.countable() {
+			continue
+		}
+		lo, hi := index, index+it.length
+		index = hi
+		c, ok := it.content.(*contentString)
+		if !ok || hi <= start {
+			continue
+		}
+		buf = append(buf, c.str[max(start, lo)-lo:min(end, hi)-lo]...)
+	}
+	return string(utf16.Decode(buf))
+}
+
+// Length 可见文本长度（UTF-16 码元）
+func (t *Text) Length() uint64 {
+	return t.typ.length
+}
+
+// Insert 以服务端身份在 index 处插入文本，返回需要广播给客户端的更新
+func (t *Text) Insert(index uint64, s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return t.doc.transact(func(tx *transaction) {
+		left, right := tx.findPosition(t.typ, index)
+		tx.insertItem(t.typ, left, right, newContentString(s))
+	})
+}
+
+// Delete 以服务端身份删除 [index, index+length)，返回需要广播给客户端的更新
+func (t *Text) Delete(index, length uint64) []byte {
+	if length == 0 {
+		return nil
+	}
+	return t.doc.transact(func(tx *transaction) {
+		_, right := tx.findPosition(t.typ, index)
+		for ; length > 0 && right != nil; right = right.right {
+			if right.deleted || !right.countable() {
+				continue
+			}
+			if length < right.length {
+				tx.getItemCleanStart(ID{Client: right.id.Client, Clock: right.id.Clock + length})
+			}
+			length -= right.length
+			right.delete(tx)
+		}
+	})
+}
+
+// TextDelta 根文本的一次修改：在 Index 处插入 Insert，或删除 Delete 个码元
+// Index 是修改发生时的 UTF-16 下标，只统计字符串内容，和 String 的结果一致
+type TextDelta struct {
+	Index  uint64
+	Insert string
+	Delete uint64
+}
+
+// WatchText 开始记录根文本 name 的修改（远端更新和本地编辑），调用方据此增量更新自己缓存的文本
+func (d *Doc) WatchText(name string) {
+	if _, ok := d.watched[name]; !ok {
+		d.watched[name] = nil
+	}
+}
+
+// UnwatchText 停止记录根文本 name 的修改，丢弃尚未取走的记录
+func (d *Doc) UnwatchText(name string) {
+	delete(d.watched, name)
+}
+
+// TakeTextDeltas 返回被记录的根文本上次调用之后的修改（按发生顺序），并清空记录
+// 没有修改的文本不出现在结果中
+func (d *Doc) TakeTextDeltas() map[string][]TextDelta {
+	var taken map[string][]TextDelta
+	for name, deltas := range d.watched {
+		if len(deltas) == 0 {
+			continue
+		}
+		if taken == nil {
+			taken = make(map[string][]TextDelta)
+		}
+		taken[name] = deltas
+		d.watched[name] = nil
+	}
+	return taken
+}
+
+// recordText 记录被跟踪的根文本中插入或删除了 it，在 it 接入链表之后、标记删除之前调用
+func (d *Doc) recordText(it *Item, insert bool) {
+	parent := it.parent
+	if parent == nil || parent.item != nil || it.parentSub != nil {
+		return
+	}
+	deltas, ok := d.watched[parent.name]
+	if !ok {
+		return
+	}
+	c, ok := it.content.(*contentString)
+	if !ok {
+		return
+	}
+
+	delta := TextDelta{}
+	for n := it.left; n != nil; n = n.left {
+		if _, isString := n.content.(*contentString); isString && !n.deleted {
+			delta.Index += n.length
+		}
+	}
+	if insert {
+		delta.Insert = string(utf16.Decode(c.str))
+	} else {
+		delta.Delete = it.length
+	}
+	d.watched[parent.name] = append(deltas, delta)
+}
+
+// findPosition 找到可见位置 index 左右两侧的 Item，必要时拆分
+func (tx *transaction) findPosition(typ *Type, index uint64) (left, right *Item) {
+	right = typ.start
+	for right != nil && index > 0 {
+		if !right.deleted && right.countable() {
+			if index < right.length {
+				tx.getItemCleanStart(ID{Client: right.id.Client, Clock: right.id.Clock + index})
+			}
+			index -= right.length
+		}
+		left, right = right, right.right
+	}
+	// 跳过紧跟着的已删除内容，和 Yjs 插入文本时的行为一致
+	for right != nil && right.deleted {
+		left, right = right, right.right
+	}
+	return left, right
+}
+
+// insertItem 在 left 和 right 之间插入本地 Item
+func (tx *transaction) insertItem(parent *Type, left, right *Item, c content) {
+	doc := tx.doc
+	it := &Item{
+		id:      ID{Client: doc.clientID, Clock: doc.store.getState(doc.clientID)},
+		length:  c.length(),
+		left:    left,
+		right:   right,
+		parent:  parent,
+		content: c,
+	}
+	if left != nil {
+		last := left.lastID()
+		it.origin = &last
+	}
+	if right != nil {
+		id := right.id
+		it.rightOrigin = &id
+	}
+	it.integrate(tx, 0)
+}
+
+// transact 执行本地编辑并编码为更新：本次新增的结构 + 本次的删除集
+func (d *Doc) transact(fn func(tx *transaction)) []byte {
+	tx := d.newTransaction()
+	fn(tx)
+	tx.cleanup()
+
+	e := NewEncoder()
+	clients := make(map[uint64]uint64)
+	for client := range d.store.clients {
+		if before := tx.beforeState[client]; before < d.store.getState(client) {
+			clients[client] = before
+		}
+	}
+	e.WriteVarUint(uint64(len(clients)))
+	for _, client := range sortedClientsDesc(clients) {
+		d.store.writeStructs(e, client, clients[client])
+	}
+	tx.deleteSet.write(e)
+	return e.Bytes()
+}
@@ -0,0 +1,61 @@
+// 聊天消息复用协作 WebSocket：y-websocket 消息类型 100，消息体是 JSON 字符串
+import type { IChatMessage, ICodeSnippet, IReactionSummary } from '../../../services/chat/types';
+
+export const MESSAGE_CHAT = 100;
+
+export interface ChatFrame {
+  // send/edit/delete/react 由客户端发送；message/updated/reaction/error 由服务端推送
+  type: 'send' | 'edit' | 'delete' | 'react' | 'message' | 'updated' | 'reaction' | 'error';
+  nonce?: string;
+  content?: string;
+  message_id?: number;
+  reply_to_id?: number;
+  snippet?: ICodeSnippet;
+  emoji?: string;
+  message?: IChatMessage;
+  reactions?: IReactionSummary[];
+  error?: string;
+}
+
+function writeVarUint(out: number[], num: number): void {
+  while (num > 0x7f) {
+    out.push(0x80 | (num & 0x7f));
+    num = Math.floor(num / 128);
+  }
+  out.push(num);
+}
+
+export function encodeChatFrame(frame: ChatFrame): Uint8Array {
+  return encodeJSONFrame(MESSAGE_CHAT, frame);
+}
+
+// 编码扩展消息：消息类型 + JSON 消息体，信令消息（rtcProtocol）也使用这种格式
+export function encodeJSONFrame(messageType: number, frame: unknown): Uint8Array {
+  const payload = new TextEncoder().encode(JSON.stringify(frame));
+  const header: number[] = [];
+  writeVarUint(header, messageType);
+  writeVarUint(header, payload.length);
+  const buf = new Uint8Array(header.length + payload.length);
+  buf.set(header);
+  buf.set(payload, header.length);
+  return buf;
+}
+
+// 从 y-websocket 的 decoder 中读取消息体（消息类型已被读取）
+export function readChatFrame(decoder: { arr: Uint8Array; pos: number }): ChatFrame {
+  return readJSONFrame<ChatFrame>(decoder);
+}
+
+export function readJSONFrame<T>(decoder: { arr: Uint8Array; pos: number }): T {
+  let len = 0;
+  let mult = 1;
+  for (;;) {
+    const byte = decoder.arr[decoder.pos++];
+    len += (byte & 0x7f) * mult;
+    mult *= 128;
+    if (byte < 0x80) break;
+  }
+  const payload = decoder.arr.subarray(decoder.pos, decoder.pos + len);
+  decoder.pos += len;
+  return JSON.parse(new TextDecoder().decode(payload)) as T;
+}
@@ -3,6 +3,8 @@ import * as Y from 'yjs';
 import { WebsocketProvider } from 'y-websocket';
 import { MonacoBinding } from 'y-monaco';
 import { editor } from 'monaco-editor';
+import tokenManager from '../../../utils/tokenManager';
+import { MESSAGE_CHAT, encodeChatFrame, readChatFrame, type ChatFrame } from './chatProtocol';
 
 function generateId(): string {
   return `${Date.now()}-${Math.random().toString(36).substring(2, 11)}`;
@@ -23,6 +25,7 @@ export class CollaborationManager {
   private awareness: any;
   private roomId: string;
   private userId: string;
+  private chatListeners = new Set<(frame: ChatFrame) => void>();
 
   constructor(options: CollaborationOptions) {
     this.roomId = options.roomId;
@@ -38,6 +41,7 @@ export class CollaborationManager {
         params: {
           userId: options.userId,
           username: options.username,
+          token: tokenManager.getAccessToken() ?? '',
         }
       }
     );
@@ -50,6 +54,15 @@ export class CollaborationManager {
       },
     })
     this.setupEventListeners();
+    this.setupChat();
+  }
+
+  //聊天消息：服务端回送的消息带有发送时的 nonce
+  private setupChat(): void {
+    (this.provider as any).messageHandlers[MESSAGE_CHAT] = (_encoder: unknown, decoder: any) => {
+      const frame = readChatFrame(decoder);
+      this.chatListeners.forEach((listener) => listener(frame));
+    };
   }
 
   //绑定到Monaco编译器
@@ -138,22 +151,29 @@ export class CollaborationManager {
       })
       .filter(Boolean);
   }
-  sendMessage(message: string): void {
-    const yMessage = this.ydoc.getArray('messages');
-    yMessage.push([{
-      id: generateId(),
-      userId: this.userId,
-      message,
-      timestamp: Date.now()
-    }])
+  //发送聊天消息（可以带代码片段或回复某条消息），返回 nonce；未连接时返回 null
+  sendMessage(content: string, options: Pick<ChatFrame, 'reply_to_id' | 'snippet'> = {}): string | null {
+    return this.sendChatFrame({ type: 'send', content, ...options });
   }
 
-  onMessage(callback: (messages: any[]) => void): void {
-    const yMessage = this.ydoc.getArray('messages');
-    callback(yMessage.toArray());
-    yMessage.observe(() => {
-      callback(yMessage.toArray());
-    })
+  //编辑、删除、表情回应，结果通过 onChat 推送
+  sendChatFrame(frame: Omit<ChatFrame, 'nonce'>): string | null {
+    const ws = this.provider.ws;
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+      return null;
+    }
+    const nonce = generateId();
+    ws.send(encodeChatFrame({ ...frame, nonce }));
+    return nonce;
+  }
+
+  onChat(listener: (frame: ChatFrame) => void): () => void {
+    this.chatListeners.add(listener);
+    return () => this.chatListeners.delete(listener);
+  }
+
+  getUserId(): string {
+    return this.userId;
   }
 
   getContent(): string {
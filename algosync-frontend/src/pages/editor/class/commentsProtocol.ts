@@ -0,0 +1,9 @@
+// 代码评论消息复用协作 WebSocket：消息类型 105，只由服务端推送
+import type { ICommentsFrame } from '../../../services/comment/types';
+import { readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_COMMENTS = 105;
+
+export function readCommentsFrame(decoder: { arr: Uint8Array; pos: number }): ICommentsFrame {
+  return readJSONFrame<ICommentsFrame>(decoder);
+}
@@ -0,0 +1,9 @@
+// 文件树消息复用协作 WebSocket：消息类型 104，只由服务端推送
+import type { IFilesFrame } from '../../../services/workspace/types';
+import { readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_FILES = 104;
+
+export function readFilesFrame(decoder: { arr: Uint8Array; pos: number }): IFilesFrame {
+  return readJSONFrame<IFilesFrame>(decoder);
+}
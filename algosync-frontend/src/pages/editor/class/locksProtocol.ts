@@ -0,0 +1,9 @@
+// 锁定区域消息复用协作 WebSocket：消息类型 106，只由服务端推送
+import type { ILocksFrame } from '../../../services/lock/types';
+import { readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_LOCKS = 106;
+
+export function readLocksFrame(decoder: { arr: Uint8Array; pos: number }): ILocksFrame {
+  return readJSONFrame<ILocksFrame>(decoder);
+}
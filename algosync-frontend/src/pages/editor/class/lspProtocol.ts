@@ -0,0 +1,24 @@
+// 语言服务器网关：每条 WebSocket 消息是一条 JSON-RPC 消息（不带 Content-Length 头）
+// 文件内容由网关根据协作文档同步给语言服务器，客户端发送的 didOpen/didChange/didClose 会被忽略
+import tokenManager from '../../../utils/tokenManager';
+
+// 工作区在语言服务器中的根目录，房间文件 src/main.go 对应 file:///workspace/src/main.go
+export const WORKSPACE_ROOT_URI = 'file:///workspace';
+
+// 与服务端转义规则一致：保留路径中的 +、@ 等字符，只转义空格、#、? 等
+export const documentURI = (path: string) =>
+  `${WORKSPACE_ROOT_URI}/${encodeURI(path).replace(/[#?]/g, encodeURIComponent)}`;
+
+export const documentPath = (uri: string) =>
+  uri.startsWith(`${WORKSPACE_ROOT_URI}/`)
+    ? decodeURIComponent(uri.slice(WORKSPACE_ROOT_URI.length + 1))
+    : null;
+
+// language 为空时使用房间语言；不支持的语言或网关未开启时连接会被拒绝（HTTP 400/503）
+export const languageServerURL = (roomUUID: string, language?: string) => {
+  const params = new URLSearchParams({ token: tokenManager.getAccessToken() ?? '' });
+  if (language) {
+    params.set('language', language);
+  }
+  return `${import.meta.env.VITE_WS_URL}/lsp/${encodeURIComponent(roomUUID)}?${params}`;
+};
@@ -0,0 +1,51 @@
+// 演示者 / 跟随模式复用协作 WebSocket：控制消息类型 102，视图消息类型 103，消息体都是 JSON 字符串
+// 服务端保证同一时间最多一个演示者，只转发演示者的视图
+import { encodeJSONFrame, readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_PRESENTER = 102;
+export const MESSAGE_PRESENTER_VIEW = 103;
+
+export interface IPresenter {
+  user_id: number;
+  username: string;
+  role: string;
+  since: string;
+}
+
+export interface PresenterFrame {
+  // take/release/handover 由客户端发送；state/error 由服务端推送，presenter 为 null 表示没有演示者
+  type: 'take' | 'release' | 'handover' | 'state' | 'error';
+  user_id?: number;
+  presenter?: IPresenter | null;
+  error?: string;
+}
+
+export interface IPresenterPosition {
+  line: number;
+  column: number;
+}
+
+export interface PresenterView {
+  user_id?: number; // 由服务端填写
+  file: string;
+  start_line: number;
+  end_line: number;
+  cursor?: IPresenterPosition;
+  selection?: { start: IPresenterPosition; end: IPresenterPosition };
+}
+
+export function encodePresenterFrame(frame: PresenterFrame): Uint8Array {
+  return encodeJSONFrame(MESSAGE_PRESENTER, frame);
+}
+
+export function encodePresenterView(view: PresenterView): Uint8Array {
+  return encodeJSONFrame(MESSAGE_PRESENTER_VIEW, view);
+}
+
+export function readPresenterFrame(decoder: { arr: Uint8Array; pos: number }): PresenterFrame {
+  return readJSONFrame<PresenterFrame>(decoder);
+}
+
+export function readPresenterView(decoder: { arr: Uint8Array; pos: number }): PresenterView {
+  return readJSONFrame<PresenterView>(decoder);
+}
@@ -0,0 +1,38 @@
+// 语音和屏幕共享的信令复用协作 WebSocket：消息类型 101，消息体是 JSON 字符串
+// 新加入语音频道的一方收到 welcome 后向每个已有参与者发送 offer
+import { encodeJSONFrame, readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_RTC = 101;
+
+export interface IVoiceParticipant {
+  peer_id: string;
+  user_id: number;
+  username: string;
+  muted: boolean;
+  sharing: boolean;
+  joined_at: string;
+}
+
+export interface RTCFrame {
+  // join/leave/state 由客户端发送；snapshot/welcome/joined/updated/left/error 由服务端推送；signal 双向
+  type: 'join' | 'leave' | 'state' | 'signal' | 'snapshot' | 'welcome' | 'joined' | 'updated' | 'left' | 'error';
+  muted?: boolean;
+  sharing?: boolean;
+  from?: string;
+  to?: string;
+  signal_type?: 'offer' | 'answer' | 'candidate';
+  payload?: RTCSessionDescriptionInit | RTCIceCandidateInit;
+  peer_id?: string;
+  participant?: IVoiceParticipant;
+  participants?: IVoiceParticipant[];
+  ice_servers?: RTCIceServer[];
+  error?: string;
+}
+
+export function encodeRTCFrame(frame: RTCFrame): Uint8Array {
+  return encodeJSONFrame(MESSAGE_RTC, frame);
+}
+
+export function readRTCFrame(decoder: { arr: Uint8Array; pos: number }): RTCFrame {
+  return readJSONFrame<RTCFrame>(decoder);
+}
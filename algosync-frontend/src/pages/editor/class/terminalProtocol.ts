@@ -0,0 +1,56 @@
+// 共享终端复用协作 WebSocket：控制消息类型 107，输出类型 108（发送队列满时可能被丢弃）
+import { encodeJSONFrame, readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_TERMINAL = 107;
+export const MESSAGE_TERMINAL_OUTPUT = 108;
+
+// owner：只有开启者可以输入；everyone：所有可编辑的成员；grant：开启者和被授权的成员
+export type TerminalMode = 'owner' | 'everyone' | 'grant';
+
+export interface ITerminalSize {
+  cols: number;
+  rows: number;
+}
+
+export interface ITerminalState {
+  owner_id: number;
+  owner_name: string;
+  mode: TerminalMode;
+  writers: number[] | null;
+  requests: number[] | null;
+  size: ITerminalSize;
+  started_at: string;
+}
+
+export interface TerminalFrame {
+  // start/input/resize/mode/request/grant/revoke/stop 由客户端发送；state/output/error 由服务端推送
+  type:
+    | 'start'
+    | 'input'
+    | 'resize'
+    | 'mode'
+    | 'request'
+    | 'grant'
+    | 'revoke'
+    | 'stop'
+    | 'state'
+    | 'output'
+    | 'error';
+  mode?: TerminalMode;
+  size?: ITerminalSize;
+  // input/output 的内容，base64
+  data?: string;
+  user_id?: number;
+  // state 帧中为空表示终端已关闭，reason 为关闭原因：stopped/exited/idle/closed
+  terminal?: ITerminalState;
+  reason?: string;
+  error?: string;
+}
+
+export function encodeTerminalFrame(frame: TerminalFrame): Uint8Array {
+  return encodeJSONFrame(MESSAGE_TERMINAL, frame);
+}
+
+export function readTerminalFrame(decoder: { arr: Uint8Array; pos: number }): TerminalFrame {
+  return readJSONFrame<TerminalFrame>(decoder);
+}
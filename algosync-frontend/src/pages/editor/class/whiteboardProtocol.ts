@@ -0,0 +1,17 @@
+// 白板列表消息复用协作 WebSocket：消息类型 109，只由服务端推送
+import type { IWhiteboardsFrame } from '../../../services/whiteboard/types';
+import { readJSONFrame } from './chatProtocol';
+
+export const MESSAGE_WHITEBOARDS = 109;
+
+// 白板文档中保存图形的 Y.Map 名称
+export const WHITEBOARD_MAP_NAME = 'shapes';
+
+// 白板文档的 y-websocket 房间名，和代码一样连接 `${VITE_WS_URL}/collaboration`
+export function whiteboardRoomName(roomUUID: string, boardID: number): string {
+  return `${roomUUID}/whiteboards/${boardID}`;
+}
+
+export function readWhiteboardsFrame(decoder: { arr: Uint8Array; pos: number }): IWhiteboardsFrame {
+  return readJSONFrame<IWhiteboardsFrame>(decoder);
+}
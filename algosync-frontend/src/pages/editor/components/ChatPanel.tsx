@@ -1,56 +1,22 @@
 import React, { useState, useRef, useEffect } from 'react';
-
-interface ChatMessage {
-  id: string;
-  userId: string;
-  username: string;
-  content: string;
-  timestamp: Date;
-  type: 'message' | 'system';
-}
+import { useCollaborationStore } from '../../../stores/collaborationStore';
+import { useAuthStore } from '../../../stores/authStore';
 
 interface ChatPanelProps {
   roomId: string;
 }
 
 const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
-  const [messages, setMessages] = useState<ChatMessage[]>([]);
+  const { messages, hasMoreMessages, sendMessage, loadMessages, manager, onlineUsers } = useCollaborationStore();
+  const currentUserId = useAuthStore((state) => state.user?.id);
   const [newMessage, setNewMessage] = useState('');
-  const [isConnected, setIsConnected] = useState(false);
+  const isConnected = Boolean(manager);
   const messagesEndRef = useRef<HTMLDivElement>(null);
-  const currentUser = { id: 'user1', username: '当前用户' }; // 实际应该从 auth store 获取
 
-  // 模拟初始消息
+  // 加载最近的聊天记录
   useEffect(() => {
-    const initialMessages: ChatMessage[] = [
-      {
-        id: '1',
-        userId: 'system',
-        username: '系统',
-        content: `欢迎加入房间 ${roomId}！大家可以在这里讨论解题思路。`,
-        timestamp: new Date(Date.now() - 300000),
-        type: 'system'
-      },
-      {
-        id: '2',
-        userId: 'user2',
-        username: 'Alice',
-        content: '这题可以用哈希表来解决，时间复杂度是O(n)',
-        timestamp: new Date(Date.now() - 120000),
-        type: 'message'
-      },
-      {
-        id: '3',
-        userId: 'user3',
-        username: 'Bob',
-        content: '同意，用两个指针的方法也可以',
-        timestamp: new Date(Date.now() - 60000),
-        type: 'message'
-      }
-    ];
-    setMessages(initialMessages);
-    setIsConnected(true);
-  }, [roomId]);
+    loadMessages(roomId).catch((error) => console.error('加载聊天记录失败', error));
+  }, [roomId, loadMessages]);
 
   // 自动滚动到底部
   useEffect(() => {
@@ -59,21 +25,8 @@ const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
 
   const handleSendMessage = () => {
     if (!newMessage.trim()) return;
-
-    const message: ChatMessage = {
-      id: Date.now().toString(),
-      userId: currentUser.id,
-      username: currentUser.username,
-      content: newMessage.trim(),
-      timestamp: new Date(),
-      type: 'message'
-    };
-
-    setMessages(prev => [...prev, message]);
+    sendMessage(newMessage.trim());
     setNewMessage('');
-
-    // TODO(human): 实现实时聊天功能
-    // 这里应该通过 WebSocket 发送消息到服务器
   };
 
   const handleKeyPress = (e: React.KeyboardEvent) => {
@@ -83,8 +36,8 @@ const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
     }
   };
 
-  const formatTime = (timestamp: Date) => {
-    return timestamp.toLocaleTimeString('zh-CN', {
+  const formatTime = (timestamp?: string) => {
+    return (timestamp ? new Date(timestamp) : new Date()).toLocaleTimeString('zh-CN', {
       hour: '2-digit',
       minute: '2-digit'
     });
@@ -104,14 +57,27 @@ const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
           </div>
         </div>
         <div className="text-xs text-gray-500 mt-1">
-          房间: {roomId} • 3人在线
+          房间: {roomId} • {onlineUsers.length + 1}人在线
         </div>
       </div>
 
       {/* 消息列表 */}
       <div className="flex-1 overflow-y-auto p-4 space-y-3">
-        {messages.map((message) => (
-          <div key={message.id} className={`${
+        {hasMoreMessages && (
+          <div className="text-center">
+            <button
+              onClick={() => loadMessages(roomId)}
+              className="text-xs text-blue-500 hover:underline"
+            >
+              加载更早的消息
+            </button>
+          </div>
+        )}
+        {messages.map((message) => {
+          // 待确认的消息没有 user_id，一定是自己发的
+          const isMine = message.user_id === undefined || message.user_id === currentUserId;
+          return (
+          <div key={message.key} className={`${
             message.type === 'system' ? 'text-center' : ''
           }`}>
             {message.type === 'system' ? (
@@ -120,31 +86,32 @@ const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
               </div>
             ) : (
               <div className={`flex ${
-                message.userId === currentUser.id ? 'justify-end' : 'justify-start'
+                isMine ? 'justify-end' : 'justify-start'
               }`}>
                 <div className={`max-w-xs lg:max-w-md px-3 py-2 rounded-lg ${
-                  message.userId === currentUser.id
+                  isMine
                     ? 'bg-blue-500 text-white'
                     : 'bg-gray-100 text-gray-900'
                 }`}>
-                  {message.userId !== currentUser.id && (
+                  {!isMine && (
                     <div className="text-xs font-medium mb-1 text-gray-600">
-                      {message.username}
+                      {message.user?.username}
                     </div>
                   )}
                   <div className="text-sm whitespace-pre-wrap">
                     {message.content}
                   </div>
                   <div className={`text-xs mt-1 ${
-                    message.userId === currentUser.id ? 'text-blue-100' : 'text-gray-500'
+                    isMine ? 'text-blue-100' : 'text-gray-500'
                   }`}>
-                    {formatTime(message.timestamp)}
+                    {message.failed ? `发送失败：${message.failed}` : message.pending ? '发送中...' : formatTime(message.created_at)}
                   </div>
                 </div>
               </div>
             )}
           </div>
-        ))}
+          );
+        })}
         <div ref={messagesEndRef} />
       </div>
 
@@ -176,12 +143,12 @@ const ChatPanel: React.FC<ChatPanelProps> = ({ roomId }) => {
         
         {/* 在线用户列表 */}
         <div className="mt-3 pt-3 border-t">
-          <div className="text-xs text-gray-500 mb-2">在线用户 (3)</div>
+          <div className="text-xs text-gray-500 mb-2">在线用户 ({onlineUsers.length})</div>
           <div className="flex flex-wrap gap-2">
-            {['Alice', 'Bob', '当前用户'].map((user) => (
-              <div key={user} className="flex items-center space-x-1 bg-white px-2 py-1 rounded-md text-xs">
-                <div className="w-2 h-2 bg-green-500 rounded-full"></div>
-                <span className="text-gray-700">{user}</span>
+            {onlineUsers.map((user) => (
+              <div key={user.id} className="flex items-center space-x-1 bg-white px-2 py-1 rounded-md text-xs">
+                <div className="w-2 h-2 rounded-full" style={{ backgroundColor: user.color }}></div>
+                <span className="text-gray-700">{user.name}</span>
               </div>
             ))}
           </div>
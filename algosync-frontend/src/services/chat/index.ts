@@ -0,0 +1,28 @@
+import request from '../../utils/request';
+import type { IChatMessagePage } from './types';
+
+//聊天相关api（发送消息走协作 WebSocket）
+class ChatService {
+  // 聊天记录，before 为当前最早一条消息的 id
+  async listMessages(roomId: string, before?: number, limit = 50): Promise<IChatMessagePage> {
+    const response = await request.get(`/v1/rooms/${roomId}/messages`, {
+      params: { before, limit },
+    });
+    return response.data as unknown as IChatMessagePage;
+  }
+
+  // 某条消息下的回复
+  async listReplies(roomId: string, threadId: number, before?: number, limit = 50): Promise<IChatMessagePage> {
+    const response = await request.get(`/v1/rooms/${roomId}/messages`, {
+      params: { thread_id: threadId, before, limit },
+    });
+    return response.data as unknown as IChatMessagePage;
+  }
+
+  // 举报消息，进入房间管理员的审核队列
+  async reportMessage(roomId: string, messageId: number, reason: string): Promise<void> {
+    await request.post(`/v1/rooms/${roomId}/messages/${messageId}/report`, { reason });
+  }
+}
+
+export default new ChatService();
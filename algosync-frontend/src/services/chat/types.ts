@@ -0,0 +1,46 @@
+// 聊天消息发送者
+export interface IChatAuthor {
+  id: number;
+  uuid: string;
+  username: string;
+  avatar: string;
+}
+
+// 引用的代码片段，代码由服务端从房间文档中截取
+export interface ICodeSnippet {
+  language: string;
+  start_line: number;
+  end_line: number;
+  code?: string;
+}
+
+// 消息上某个表情的回应汇总
+export interface IReactionSummary {
+  emoji: string;
+  count: number;
+  user_ids: number[];
+}
+
+// 聊天消息
+export interface IChatMessage {
+  id: number;
+  room_id: number;
+  user_id: number | null; // 为空表示系统消息
+  type: 'text' | 'code' | 'system';
+  content: string;
+  snippet?: ICodeSnippet;
+  reply_to_id: number | null; // 回复只挂在根消息下
+  reply_count: number;
+  reactions: IReactionSummary[] | null;
+  created_at: string;
+  edited_at: string | null;
+  deleted_at: string | null;
+  deleted_by: number | null;
+  user?: IChatAuthor;
+}
+
+// 聊天记录分页，按时间正序
+export interface IChatMessagePage {
+  messages: IChatMessage[];
+  has_more: boolean;
+}
@@ -0,0 +1,35 @@
+import request from '../../utils/request';
+import type { ICreateCommentThread, IRoomCommentThread } from './types';
+
+//代码评论相关api
+class CommentService {
+  // 评论列表，不传 fileId 时返回所有文件的评论
+  async listThreads(roomId: string, fileId?: number, status?: 'open' | 'resolved'): Promise<IRoomCommentThread[]> {
+    const response = await request.get(`/v1/rooms/${roomId}/comments`, {
+      params: { file_id: fileId, status },
+    });
+    return response.data as unknown as IRoomCommentThread[];
+  }
+
+  async createThread(roomId: string, data: ICreateCommentThread): Promise<IRoomCommentThread> {
+    const response = await request.post(`/v1/rooms/${roomId}/comments`, data);
+    return response.data as unknown as IRoomCommentThread;
+  }
+
+  async reply(roomId: string, threadId: number, content: string): Promise<IRoomCommentThread> {
+    const response = await request.post(`/v1/rooms/${roomId}/comments/${threadId}/replies`, { content });
+    return response.data as unknown as IRoomCommentThread;
+  }
+
+  async resolve(roomId: string, threadId: number): Promise<IRoomCommentThread> {
+    const response = await request.post(`/v1/rooms/${roomId}/comments/${threadId}/resolve`);
+    return response.data as unknown as IRoomCommentThread;
+  }
+
+  async reopen(roomId: string, threadId: number): Promise<IRoomCommentThread> {
+    const response = await request.post(`/v1/rooms/${roomId}/comments/${threadId}/reopen`);
+    return response.data as unknown as IRoomCommentThread;
+  }
+}
+
+export default new CommentService();
@@ -0,0 +1,47 @@
+import type { IChatAuthor } from '../chat/types';
+
+// 评论中的一条回复，第一条是发起评论时的内容
+export interface IRoomComment {
+  id: number;
+  room_id: number;
+  thread_id: number;
+  user_id: number;
+  content: string; // Markdown，已在服务端清理
+  created_at: string;
+  user?: IChatAuthor;
+}
+
+// 挂在文件中一段代码上的评论
+// anchor_start/anchor_end 是 Y.encodeRelativePosition 的结果（base64），
+// 用 Y.decodeRelativePosition + Y.createAbsolutePositionFromRelativePosition 在本地解析
+export interface IRoomCommentThread {
+  id: number;
+  room_id: number;
+  file_id: number;
+  anchor_start: string;
+  anchor_end: string;
+  quote: string; // 创建时选中的代码
+  resolved: boolean;
+  resolved_by: number | null;
+  resolved_at: string | null;
+  created_by: number;
+  created_at: string;
+  updated_at: string;
+  // 服务端解析的当前位置（UTF-16 偏移），锚点尚未同步到服务端时为空
+  range: { start: number; end: number } | null;
+  comments: IRoomComment[];
+}
+
+export interface ICreateCommentThread {
+  file_id: number;
+  anchor_start: string;
+  anchor_end: string;
+  quote?: string;
+  content: string;
+}
+
+// 协作连接上的评论消息（类型 105），评论新建、回复、解决、重新打开后推送给所有在线成员
+export interface ICommentsFrame {
+  type: 'thread';
+  thread: IRoomCommentThread;
+}
@@ -0,0 +1,22 @@
+import request from '../../utils/request';
+import type { ICreateRoomLock, IRoomLock } from './types';
+
+//锁定区域相关api
+class LockService {
+  async listLocks(roomId: string): Promise<IRoomLock[]> {
+    const response = await request.get(`/v1/rooms/${roomId}/locks`);
+    return response.data as unknown as IRoomLock[];
+  }
+
+  // 锁定一段代码（管理员及以上）
+  async createLock(roomId: string, data: ICreateRoomLock): Promise<IRoomLock> {
+    const response = await request.post(`/v1/rooms/${roomId}/locks`, data);
+    return response.data as unknown as IRoomLock;
+  }
+
+  async deleteLock(roomId: string, lockId: number): Promise<void> {
+    await request.delete(`/v1/rooms/${roomId}/locks/${lockId}`);
+  }
+}
+
+export default new LockService();
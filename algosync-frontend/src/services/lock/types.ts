@@ -0,0 +1,31 @@
+// 房间文件中的只读区域，管理员及以上的成员不受限制
+// anchor_start 指向区域第一个字符（assoc 0），anchor_end 指向最后一个字符（assoc -1），
+// 都是 Y.encodeRelativePosition 的结果（base64）
+export interface IRoomLock {
+  id: number;
+  room_id: number;
+  file_id: number;
+  text_name: string;
+  anchor_start: string;
+  anchor_end: string;
+  label: string;
+  created_by: number;
+  created_at: string;
+}
+
+export interface ICreateRoomLock {
+  file_id: number;
+  anchor_start: string;
+  anchor_end: string;
+  label?: string;
+}
+
+// 协作连接上的锁定区域消息（类型 106）
+// locks：锁定区域变化后的完整列表；rejected：本连接的编辑被拒绝，需要丢弃本地文档重新连接
+export interface ILocksFrame {
+  type: 'locks' | 'rejected';
+  locks?: IRoomLock[];
+  lock_id?: number; // 为空表示之前的编辑已被拒绝，本次编辑未检查
+  label?: string;
+  error?: string;
+}
@@ -0,0 +1,59 @@
+import request from '../../utils/request';
+import tokenManager from '../../utils/tokenManager';
+import type { IReplayOptions, IRoomSession, IRoomSessionPage, ISharedReplay } from './types';
+
+//协作过程回放相关api
+class ReplayService {
+  async listSessions(roomId: string, page = 1, pageSize = 20): Promise<IRoomSessionPage> {
+    const response = await request.get(`/v1/rooms/${roomId}/sessions`, {
+      params: { page, page_size: pageSize },
+    });
+    return response.data as unknown as IRoomSessionPage;
+  }
+
+  async getSession(roomId: string, sessionId: number): Promise<IRoomSession> {
+    const response = await request.get(`/v1/rooms/${roomId}/sessions/${sessionId}`);
+    return response.data as unknown as IRoomSession;
+  }
+
+  // 生成分享链接（房间管理员），已分享过时返回原链接
+  async shareSession(roomId: string, sessionId: number): Promise<IRoomSession> {
+    const response = await request.post(`/v1/rooms/${roomId}/sessions/${sessionId}/share`);
+    return response.data as unknown as IRoomSession;
+  }
+
+  async unshareSession(roomId: string, sessionId: number): Promise<void> {
+    await request.delete(`/v1/rooms/${roomId}/sessions/${sessionId}/share`);
+  }
+
+  // 通过分享链接查看回放，不需要登录
+  async getShared(token: string): Promise<ISharedReplay> {
+    const response = await request.get(`/v1/replays/${token}`);
+    return response.data as unknown as ISharedReplay;
+  }
+
+  // 回放流地址，用 EventSource 打开；EventSource 不能带请求头，通过 ?token= 鉴权
+  streamURL(roomId: string, sessionId: number, options: IReplayOptions = {}): string {
+    return this.buildURL(`/v1/rooms/${roomId}/sessions/${sessionId}/stream`, {
+      ...options,
+      token: tokenManager.getAccessToken() ?? undefined,
+    });
+  }
+
+  sharedStreamURL(token: string, options: IReplayOptions = {}): string {
+    return this.buildURL(`/v1/replays/${token}/stream`, options);
+  }
+
+  private buildURL(path: string, params: Record<string, string | number | boolean | undefined>): string {
+    const query = new URLSearchParams();
+    Object.entries(params).forEach(([key, value]) => {
+      if (value !== undefined) {
+        query.set(key, String(value));
+      }
+    });
+    const search = query.toString();
+    return `${request.defaults.baseURL ?? ''}${path}${search ? `?${search}` : ''}`;
+  }
+}
+
+export default new ReplayService();
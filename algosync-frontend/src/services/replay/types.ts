@@ -0,0 +1,42 @@
+// 房间的一次协作过程，超过一段时间没有活动后，下一次活动开始新的会话
+export interface IRoomSession {
+  id: number;
+  room_id: number;
+  started_at: string;
+  last_event_at: string;
+  duration_ms: number;
+  event_count: number;
+  share_token?: string; // 分享后才有
+  shared_by?: number;
+  shared_at?: string;
+}
+
+export interface IRoomSessionPage {
+  sessions: IRoomSession[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+// 分享链接对应的回放
+export interface ISharedReplay {
+  session: IRoomSession;
+  room_name: string;
+}
+
+// 回放参数，调整速度或跳转时重新打开回放
+export interface IReplayOptions {
+  from?: number; // 跳转位置（毫秒）
+  speed?: number; // 0.25 ~ 16，默认 1
+  skip_idle?: boolean; // 跳过没有操作的空闲时间
+}
+
+// 回放流中的一帧（SSE 事件名为 type），t 为相对会话开始的毫秒数
+// update、awareness 为 base64 编码的 Yjs 更新，state 帧为跳转位置的完整状态
+export interface IReplayFrame {
+  type: 'state' | 'update' | 'awareness' | 'chat' | 'end';
+  t: number;
+  update?: string;
+  awareness?: string;
+  chat?: unknown[]; // 格式和协作连接上的聊天消息一致
+}
@@ -0,0 +1,48 @@
+import request from '../../utils/request';
+import type { IDocumentBlame, IDocumentVersion, IDocumentVersionPage, IVersionDiff } from './types';
+
+//代码历史版本相关api
+class VersionService {
+  async listVersions(roomId: string, page = 1, pageSize = 20): Promise<IDocumentVersionPage> {
+    const response = await request.get(`/v1/rooms/${roomId}/versions`, {
+      params: { page, page_size: pageSize },
+    });
+    return response.data as unknown as IDocumentVersionPage;
+  }
+
+  async getVersion(roomId: string, versionId: number): Promise<IDocumentVersion> {
+    const response = await request.get(`/v1/rooms/${roomId}/versions/${versionId}`);
+    return response.data as unknown as IDocumentVersion;
+  }
+
+  // 把当前代码保存为命名检查点
+  async createCheckpoint(roomId: string, name: string): Promise<IDocumentVersion> {
+    const response = await request.post(`/v1/rooms/${roomId}/versions`, { name });
+    return response.data as unknown as IDocumentVersion;
+  }
+
+  // 对比两个版本，不传 against 时和当前代码对比
+  async diffVersion(roomId: string, versionId: number, against?: number): Promise<IVersionDiff> {
+    const response = await request.get(`/v1/rooms/${roomId}/versions/${versionId}/diff`, {
+      params: { against },
+    });
+    return response.data as unknown as IVersionDiff;
+  }
+
+  // 每一行代码的作者和贡献占比，不传 versionId 时统计当前代码
+  async blame(roomId: string, versionId?: number): Promise<IDocumentBlame> {
+    const url = versionId
+      ? `/v1/rooms/${roomId}/versions/${versionId}/blame`
+      : `/v1/rooms/${roomId}/blame`;
+    const response = await request.get(url);
+    return response.data as unknown as IDocumentBlame;
+  }
+
+  // 恢复历史版本（房间管理员及以上），只改写主文件，返回恢复前自动保存的版本
+  async restoreVersion(roomId: string, versionId: number): Promise<IDocumentVersion> {
+    const response = await request.post(`/v1/rooms/${roomId}/versions/${versionId}/restore`);
+    return response.data as unknown as IDocumentVersion;
+  }
+}
+
+export default new VersionService();
@@ -0,0 +1,71 @@
+import type { IChatAuthor } from '../chat/types';
+
+// 历史版本：自动保存、命名检查点、恢复前自动保存的代码
+// 版本只包含房间主文件，工作区的其他文件没有历史版本
+export interface IDocumentVersion {
+  id: number;
+  room_id: number;
+  kind: 'auto' | 'checkpoint' | 'restore';
+  name: string;
+  text_name: string; // 版本对应的 Y.Text，目前固定为主文件 'monaco'
+  content?: string; // 列表中不返回内容
+  size: number;
+  created_by: number | null; // 为空表示自动保存
+  created_at: string;
+  creator?: IChatAuthor;
+}
+
+export interface IDocumentVersionPage {
+  versions: IDocumentVersion[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+// 差异中的一行，插入的行没有旧行号，删除的行没有新行号
+export interface IDiffLine {
+  op: 'equal' | 'insert' | 'delete';
+  text: string;
+  old_line?: number;
+  new_line?: number;
+}
+
+export interface IDiffHunk {
+  old_start: number;
+  old_lines: number;
+  new_start: number;
+  new_lines: number;
+  lines: IDiffLine[];
+}
+
+// 从 from 到 to 的差异，to 为空表示当前代码
+export interface IVersionDiff {
+  from: IDocumentVersion;
+  to: IDocumentVersion | null;
+  stats: { additions: number; deletions: number };
+  hunks: IDiffHunk[] | null;
+}
+
+// 一位作者写入的字符数（不含空白），user_id 为空表示没有作者：初始代码、恢复的版本等服务端写入的内容
+export interface IBlameAuthor {
+  user_id: number | null;
+  user?: IChatAuthor;
+  chars: number;
+  percent: number;
+}
+
+// 一行代码的作者，user_id 为写入字符最多的作者，空行没有作者
+export interface IBlameLine {
+  line: number;
+  user_id: number | null;
+  authors: { user_id: number | null; chars: number }[];
+}
+
+// 代码每一行的作者和贡献占比，version 为空表示当前代码
+export interface IDocumentBlame {
+  version: IDocumentVersion | null;
+  content: string;
+  lines: IBlameLine[];
+  authors: IBlameAuthor[] | null;
+  total_chars: number;
+}
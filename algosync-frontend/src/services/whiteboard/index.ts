@@ -0,0 +1,35 @@
+import request from '../../utils/request';
+import type { ICreateWhiteboard, IUpdateWhiteboard, IWhiteboard, WhiteboardExportFormat } from './types';
+
+//协作白板相关api
+class WhiteboardService {
+  async listWhiteboards(roomId: string): Promise<IWhiteboard[]> {
+    const response = await request.get(`/v1/rooms/${roomId}/whiteboards`);
+    return response.data as unknown as IWhiteboard[];
+  }
+
+  async createWhiteboard(roomId: string, data: ICreateWhiteboard): Promise<IWhiteboard> {
+    const response = await request.post(`/v1/rooms/${roomId}/whiteboards`, data);
+    return response.data as unknown as IWhiteboard;
+  }
+
+  async updateWhiteboard(roomId: string, boardId: number, data: IUpdateWhiteboard): Promise<IWhiteboard> {
+    const response = await request.put(`/v1/rooms/${roomId}/whiteboards/${boardId}`, data);
+    return response.data as unknown as IWhiteboard;
+  }
+
+  async deleteWhiteboard(roomId: string, boardId: number): Promise<void> {
+    await request.delete(`/v1/rooms/${roomId}/whiteboards/${boardId}`);
+  }
+
+  // 导出白板，PNG 中的文本只支持 ASCII，需要准确的文本请导出 SVG
+  async exportWhiteboard(roomId: string, boardId: number, format: WhiteboardExportFormat = 'svg'): Promise<Blob> {
+    const response = await request.get(`/v1/rooms/${roomId}/whiteboards/${boardId}/export`, {
+      params: { format },
+      responseType: 'blob',
+    });
+    return response as unknown as Blob;
+  }
+}
+
+export default new WhiteboardService();
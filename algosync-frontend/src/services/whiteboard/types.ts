@@ -0,0 +1,52 @@
+// 房间中的一块协作白板
+// 每块白板是一份独立的 Yjs 文档，用单独的 WebsocketProvider 连接，房间名见 whiteboardRoomName；
+// 图形保存在 ydoc.getMap(WHITEBOARD_MAP_NAME) 中：键为图形 ID，值为 IWhiteboardShape，
+// 修改图形时整体写入新的对象（map.set(id, {...shape})），和代码一样同步和持久化
+export interface IWhiteboard {
+  id: number;
+  room_id: number;
+  title: string;
+  thread_id: number | null; // 关联的讨论（评论），没有关联时为空
+  created_by: number | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export type WhiteboardShapeType = 'rect' | 'ellipse' | 'line' | 'arrow' | 'freehand' | 'text';
+
+// 白板上的一个图形
+// rect、ellipse、text 使用 x、y、w、h 表示外框；line、arrow 使用 points 中的起点和终点，
+// freehand 使用 points 中的所有点，依次为 x0, y0, x1, y1...
+export interface IWhiteboardShape {
+  type: WhiteboardShapeType;
+  x: number;
+  y: number;
+  w: number;
+  h: number;
+  points?: number[];
+  text?: string;
+  font_size?: number;
+  stroke?: string; // #rgb 或 #rrggbb
+  fill?: string;
+  stroke_width?: number;
+  z: number; // 叠放顺序，大的在上层
+}
+
+export interface ICreateWhiteboard {
+  title?: string;
+  thread_id?: number;
+}
+
+// thread_id 为 0 时取消关联
+export interface IUpdateWhiteboard {
+  title?: string;
+  thread_id?: number;
+}
+
+export type WhiteboardExportFormat = 'svg' | 'png';
+
+// 协作连接上的白板消息（类型 109），白板新建、修改、删除后推送完整的白板列表
+export interface IWhiteboardsFrame {
+  type: 'list';
+  whiteboards: IWhiteboard[];
+}
@@ -0,0 +1,40 @@
+import request from '../../utils/request';
+import type { IRoomFile, IWorkspace, IWorkspaceFile } from './types';
+
+//房间多文件工作区相关api
+class WorkspaceService {
+  async listFiles(roomId: string): Promise<IRoomFile[]> {
+    const response = await request.get(`/v1/rooms/${roomId}/files`);
+    return response.data as unknown as IRoomFile[];
+  }
+
+  // 新建文件，path 可以包含目录，例如 src/utils.py
+  async createFile(roomId: string, path: string, content = ''): Promise<IRoomFile> {
+    const response = await request.post(`/v1/rooms/${roomId}/files`, { path, content });
+    return response.data as unknown as IRoomFile;
+  }
+
+  // 重命名或移动文件，内容和编辑历史不变
+  async updateFile(roomId: string, fileId: number, path: string): Promise<IRoomFile> {
+    const response = await request.put(`/v1/rooms/${roomId}/files/${fileId}`, { path });
+    return response.data as unknown as IRoomFile;
+  }
+
+  async deleteFile(roomId: string, fileId: number): Promise<void> {
+    await request.delete(`/v1/rooms/${roomId}/files/${fileId}`);
+  }
+
+  // 按路径读取一个文件的当前内容，路径中的 / 编码后作为一个路径段
+  async getFileContent(roomId: string, path: string): Promise<IWorkspaceFile> {
+    const response = await request.get(`/v1/rooms/${roomId}/files/${encodeURIComponent(path)}/content`);
+    return response.data as unknown as IWorkspaceFile;
+  }
+
+  // 所有文件及其当前内容
+  async getWorkspace(roomId: string): Promise<IWorkspace> {
+    const response = await request.get(`/v1/rooms/${roomId}/workspace`);
+    return response.data as unknown as IWorkspace;
+  }
+}
+
+export default new WorkspaceService();
@@ -0,0 +1,31 @@
+// 房间工作区中的一个文件，内容保存在同一个协作文档中名为 text_name 的 Y.Text
+export interface IRoomFile {
+  id: number;
+  room_id: number;
+  path: string; // 例如 src/utils.py
+  language: string;
+  text_name: string;
+  is_main: boolean; // 主文件即原来的单文件代码（monaco），不能删除
+  created_by: number | null;
+  created_at: string;
+  updated_at: string;
+}
+
+// 工作区中的一个文件及其当前内容
+export interface IWorkspaceFile {
+  path: string;
+  language: string;
+  content: string;
+}
+
+// 整个工作区的快照，entry 为主文件路径
+export interface IWorkspace {
+  entry: string;
+  files: IWorkspaceFile[];
+}
+
+// 协作连接上的文件树消息（类型 104），文件增删改后推送给所有在线成员
+export interface IFilesFrame {
+  type: 'tree';
+  files: IRoomFile[];
+}
@@ -1,5 +1,7 @@
 import {create } from 'zustand';
 import { CollaborationManager } from '../pages/editor/class/collaboration';
+import chatService from '../services/chat';
+import type { IChatMessage, ICodeSnippet } from '../services/chat/types';
 
 interface CollaborationUser{
   id:string;
@@ -17,11 +19,12 @@ interface CollaborationUser{
   }
 }
 
-interface ChatMessage {
-  id: string;
-  userId: string;
+// 聊天消息：pending 表示已发送、等待服务端确认，failed 为发送失败的原因
+export interface ChatMessage extends Partial<IChatMessage> {
+  key: string;
   content: string;
-  timestamp: number;
+  pending?: boolean;
+  failed?: string;
 }
 
 interface CollaborationState{
@@ -30,8 +33,13 @@ interface CollaborationState{
   isSynced:boolean;
   onlineUsers:CollaborationUser[];
   messages:ChatMessage[];
+  hasMoreMessages:boolean;
   initCollaboration:(option:any)=>void;
-  sendMessage:(message:string)=>void;
+  sendMessage:(message:string, options?:{reply_to_id?:number; snippet?:ICodeSnippet})=>void;
+  editMessage:(messageId:number, content:string)=>void;
+  deleteMessage:(messageId:number)=>void;
+  toggleReaction:(messageId:number, emoji:string)=>void;
+  loadMessages:(roomId:string)=>Promise<void>;
   // updateCursor:(position:any)=>void;
   // updateSelection:(selection:any)=>void;
   destroy:()=>void;
@@ -43,6 +51,7 @@ export const useCollaborationStore = create<CollaborationState>((set,get)=>({
   isSynced:false,
   onlineUsers:[],
   messages:[],
+  hasMoreMessages:false,
 
   initCollaboration:(options)=>{
     const manager = new CollaborationManager(options);
@@ -52,16 +61,63 @@ export const useCollaborationStore = create<CollaborationState>((set,get)=>({
       set({onlineUsers:users})
     }, (1000));
 
-    manager.onMessage((messages)=>{
-      set({messages});
+    manager.onChat((frame)=>{
+      const {messages} = get();
+      if (frame.type === 'message' && frame.message) {
+        const message = frame.message;
+        // 自己发送的消息替换本地的待确认消息，其他人的消息追加到末尾
+        const confirmed = {...message, key: String(message.id)};
+        const index = frame.nonce ? messages.findIndex((m) => m.key === frame.nonce) : -1;
+        if (index >= 0) {
+          set({messages: messages.map((m, i) => (i === index ? confirmed : m))});
+        } else if (!messages.some((m) => m.id === message.id)) {
+          set({messages: [...messages, confirmed]});
+        }
+      } else if (frame.type === 'updated' && frame.message) {
+        // 编辑或删除：整条替换
+        const updated = frame.message;
+        set({messages: messages.map((m) => (m.id === updated.id ? {...updated, key: m.key} : m))});
+      } else if (frame.type === 'reaction' && frame.message_id) {
+        const reactions = frame.reactions ?? [];
+        set({messages: messages.map((m) => (m.id === frame.message_id ? {...m, reactions} : m))});
+      } else if (frame.type === 'error' && frame.nonce) {
+        set({messages: messages.map((m) => (m.key === frame.nonce ? {...m, pending: false, failed: frame.error} : m))});
+      }
     })
 
     set({manager,isConnected:true});
   },
 
-  sendMessage:(message)=>{
-    const {manager} = get();
-    manager?.sendMessage(message);
+  sendMessage:(message, options)=>{
+    const {manager, messages} = get();
+    const nonce = manager?.sendMessage(message, options);
+    if (!nonce) return;
+    set({messages: [...messages, {key: nonce, content: message, type: options?.snippet ? 'code' : 'text', pending: true}]});
+  },
+
+  editMessage:(messageId, content)=>{
+    get().manager?.sendChatFrame({type: 'edit', message_id: messageId, content});
+  },
+
+  deleteMessage:(messageId)=>{
+    get().manager?.sendChatFrame({type: 'delete', message_id: messageId});
+  },
+
+  toggleReaction:(messageId, emoji)=>{
+    get().manager?.sendChatFrame({type: 'react', message_id: messageId, emoji});
+  },
+
+  // 加载更早的聊天记录，首次调用加载最新的一页
+  loadMessages:async(roomId)=>{
+    const {messages} = get();
+    const oldest = messages.find((m) => m.id !== undefined)?.id;
+    const page = await chatService.listMessages(roomId, oldest);
+    const loaded = page.messages.map((m) => ({...m, key: String(m.id)}));
+    const known = new Set(get().messages.map((m) => m.id));
+    set({
+      messages: [...loaded.filter((m) => !known.has(m.id)), ...get().messages],
+      hasMoreMessages: page.has_more,
+    });
   },
 
   // updateCursor:(position)=>{
@@ -87,7 +143,8 @@ export const useCollaborationStore = create<CollaborationState>((set,get)=>({
       manager:null,
       isConnected:false,
       onlineUsers:[],
-      messages:[]
+      messages:[],
+      hasMoreMessages:false
     })
   }
 }))
\ No newline at end of file